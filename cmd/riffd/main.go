@@ -0,0 +1,7 @@
+package main
+
+import "github.com/tessro/riff/internal/cli"
+
+func main() {
+	cli.ExecuteDaemon()
+}
@@ -0,0 +1,422 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/core/coretest"
+	rifferrors "github.com/tessro/riff/internal/errors"
+	"github.com/tessro/riff/internal/sonos"
+	"github.com/tessro/riff/internal/tail"
+)
+
+const testToken = "s3cret"
+
+// fakeBackends serves a fixed set of players by device name.
+type fakeBackends struct {
+	devices []core.Device
+	players map[string]*coretest.Player
+}
+
+func newFakeBackends() *fakeBackends {
+	b := &fakeBackends{
+		devices: []core.Device{
+			{ID: "abc123", Name: "MacBook Pro", Platform: core.PlatformSpotify},
+			{ID: "RINCON_1", Name: "Kitchen", Platform: core.PlatformSonos},
+		},
+		players: map[string]*coretest.Player{},
+	}
+	b.players["MacBook Pro"] = coretest.NewPlayer(&b.devices[0], core.CapQueueRead|core.CapQueueAdd|core.CapSeek)
+	b.players["Kitchen"] = coretest.NewPlayer(&b.devices[1], core.CapQueueRead)
+	return b
+}
+
+func (b *fakeBackends) Devices(ctx context.Context) ([]core.Device, error) {
+	return b.devices, nil
+}
+
+func (b *fakeBackends) PlayerFor(ctx context.Context, target string) (core.Player, *core.Device, error) {
+	if target == "" {
+		target = "MacBook Pro"
+	}
+	p, ok := b.players[target]
+	if !ok {
+		return nil, nil, fmt.Errorf("'%s': %w", target, rifferrors.ErrDeviceNotFound)
+	}
+	return p, p.State().Device, nil
+}
+
+func (b *fakeBackends) Search(ctx context.Context, query string, kinds []core.SearchKind, limit int) ([]core.SearchResult, error) {
+	return []core.SearchResult{{URI: "spotify:track:1", Kind: kinds[0], Title: query, Platform: core.PlatformSpotify}}, nil
+}
+
+// fakeGroups records group changes.
+type fakeGroups struct {
+	joined map[string]string
+}
+
+func (g *fakeGroups) Groups(ctx context.Context) ([]sonos.Group, error) {
+	kitchen := &sonos.Device{UUID: "RINCON_1", Name: "Kitchen"}
+	return []sonos.Group{{ID: "g1", Name: "Kitchen", Coordinator: kitchen, Members: []*sonos.Device{kitchen}}}, nil
+}
+
+func (g *fakeGroups) Join(ctx context.Context, speaker, target string) error {
+	if target != "Kitchen" {
+		return fmt.Errorf("group '%s': %w", target, rifferrors.ErrDeviceNotFound)
+	}
+	g.joined[speaker] = target
+	return nil
+}
+
+func (g *fakeGroups) Leave(ctx context.Context, speaker string) (string, error) {
+	group := g.joined[speaker]
+	delete(g.joined, speaker)
+	return group, nil
+}
+
+// startServer serves a Server over httptest with the test token.
+func startServer(t *testing.T, groups Groups) (*httptest.Server, *fakeBackends) {
+	t.Helper()
+	b := newFakeBackends()
+	s := NewServer(b, groups, testToken, "test", 10*time.Millisecond)
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return ts, b
+}
+
+// do sends an authenticated request and decodes a JSON response into out.
+func do(t *testing.T, ts *httptest.Server, method, path string, body, out any) int {
+	t.Helper()
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = strings.NewReader(string(data))
+	}
+	req, err := http.NewRequest(method, ts.URL+path, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: failed to decode response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestAuth(t *testing.T) {
+	ts, _ := startServer(t, nil)
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		want   int
+	}{
+		{"no token", "/v1/devices", "", http.StatusUnauthorized},
+		{"wrong token", "/v1/devices", "Bearer nope", http.StatusUnauthorized},
+		{"bearer header", "/v1/devices", "Bearer " + testToken, http.StatusOK},
+		{"query parameter", "/v1/devices?access_token=" + testToken, "", http.StatusOK},
+		{"public openapi", "/v1/openapi.json", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", ts.URL+tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlayerEndpoints(t *testing.T) {
+	ts, b := startServer(t, nil)
+	mac := b.players["MacBook Pro"]
+
+	var devices DevicesResponse
+	if code := do(t, ts, "GET", "/v1/devices", nil, &devices); code != http.StatusOK || len(devices.Devices) != 2 {
+		t.Errorf("GET /v1/devices = %d, %+v", code, devices)
+	}
+
+	commands := []struct {
+		path string
+		body any
+	}{
+		{"/v1/player/play", nil},
+		{"/v1/player/volume", VolumeRequest{Percent: 35}},
+		{"/v1/player/seek", SeekRequest{PositionMs: 90000}},
+		{"/v1/queue", QueueAddRequest{URI: "spotify:track:1"}},
+	}
+	for _, c := range commands {
+		if code := do(t, ts, "POST", c.path, c.body, nil); code != http.StatusNoContent {
+			t.Errorf("POST %s = %d, want 204", c.path, code)
+		}
+	}
+
+	var state core.PlaybackState
+	if code := do(t, ts, "GET", "/v1/status", nil, &state); code != http.StatusOK {
+		t.Fatalf("GET /v1/status = %d", code)
+	}
+	if !state.IsPlaying || state.Volume != 35 || state.Progress != 90*time.Second || state.Device.Name != "MacBook Pro" {
+		t.Errorf("status = %+v", state)
+	}
+
+	var q core.Queue
+	if code := do(t, ts, "GET", "/v1/queue", nil, &q); code != http.StatusOK || q.Len() != 1 {
+		t.Errorf("GET /v1/queue = %d, %+v", code, q)
+	}
+	if q := mac.Queue(); len(q) != 1 {
+		t.Errorf("queue = %v, want one track", q)
+	}
+
+	var search SearchResponse
+	if code := do(t, ts, "GET", "/v1/search?q=song&type=album", nil, &search); code != http.StatusOK ||
+		len(search.Results) != 1 || search.Results[0].Kind != core.SearchKindAlbum {
+		t.Errorf("GET /v1/search = %d, %+v", code, search)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ts, _ := startServer(t, nil)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		want   int
+	}{
+		{"unknown device", "GET", "/v1/status?device=Bathroom", nil, http.StatusNotFound},
+		{"unsupported seek", "POST", "/v1/player/seek", SeekRequest{Device: "Kitchen", PositionMs: 1}, http.StatusNotImplemented},
+		{"unsupported queue edit", "DELETE", "/v1/queue/1", nil, http.StatusNotImplemented},
+		{"bad volume", "POST", "/v1/player/volume", VolumeRequest{Percent: 150}, http.StatusBadRequest},
+		{"bad position", "DELETE", "/v1/queue/first", nil, http.StatusBadRequest},
+		{"unknown field", "POST", "/v1/player/pause", map[string]string{"room": "Kitchen"}, http.StatusBadRequest},
+		{"missing query", "GET", "/v1/search", nil, http.StatusBadRequest},
+		{"no groups", "GET", "/v1/groups", nil, http.StatusNotImplemented},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp errorResponse
+			if code := do(t, ts, tt.method, tt.path, tt.body, &resp); code != tt.want {
+				t.Errorf("status = %d, want %d (%s)", code, tt.want, resp.Error)
+			}
+			if resp.Error == "" {
+				t.Error("error message is empty")
+			}
+		})
+	}
+
+	var resp errorResponse
+	do(t, ts, "POST", "/v1/player/seek", SeekRequest{Device: "Kitchen"}, &resp)
+	if resp.Suggestion == "" {
+		t.Errorf("unsupported error has no suggestion: %+v", resp)
+	}
+}
+
+func TestGroups(t *testing.T) {
+	groups := &fakeGroups{joined: map[string]string{}}
+	ts, _ := startServer(t, groups)
+
+	var list GroupsResponse
+	if code := do(t, ts, "GET", "/v1/groups", nil, &list); code != http.StatusOK || len(list.Groups) != 1 {
+		t.Errorf("GET /v1/groups = %d, %+v", code, list)
+	}
+
+	if code := do(t, ts, "POST", "/v1/groups/join", JoinRequest{Speaker: "Den", Group: "Kitchen"}, nil); code != http.StatusNoContent {
+		t.Errorf("join = %d, want 204", code)
+	}
+	if groups.joined["Den"] != "Kitchen" {
+		t.Errorf("joined = %v", groups.joined)
+	}
+	if code := do(t, ts, "POST", "/v1/groups/join", JoinRequest{Speaker: "Den", Group: "Attic"}, nil); code != http.StatusNotFound {
+		t.Errorf("join unknown group = %d, want 404", code)
+	}
+
+	var leave LeaveResponse
+	if code := do(t, ts, "POST", "/v1/groups/leave", LeaveRequest{Speaker: "Den"}, &leave); code != http.StatusOK || leave.Group != "Kitchen" {
+		t.Errorf("leave = %d, %+v", code, leave)
+	}
+}
+
+// changeVolume bumps the player's volume until ctx is done, so a watcher
+// started at any point sees a change.
+func changeVolume(ctx context.Context, p *coretest.Player) {
+	for i := 1; ; i++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(20 * time.Millisecond):
+			_ = p.Volume(ctx, i%100)
+		}
+	}
+}
+
+func TestEventsSSE(t *testing.T) {
+	ts, b := startServer(t, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"/v1/events?device=Kitchen&access_token="+testToken, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	go changeVolume(ctx, b.players["Kitchen"])
+
+	scanner := bufio.NewScanner(resp.Body)
+	var name string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if v, ok := strings.CutPrefix(line, "event: "); ok {
+			name = v
+		}
+		if v, ok := strings.CutPrefix(line, "data: "); ok {
+			var e struct {
+				Type    string             `json:"type"`
+				Current core.PlaybackState `json:"current"`
+			}
+			if err := json.Unmarshal([]byte(v), &e); err != nil {
+				t.Fatalf("bad event data %q: %v", v, err)
+			}
+			if name != "volume_change" || e.Type != name || e.Current.Device.Name != "Kitchen" {
+				t.Errorf("event %q = %+v", name, e)
+			}
+			return
+		}
+	}
+	t.Fatalf("stream ended without an event: %v", scanner.Err())
+}
+
+func TestEventsWebSocket(t *testing.T) {
+	ts, b := startServer(t, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Unauthenticated upgrades are refused
+	if _, _, err := websocket.Dial(ctx, ts.URL+"/v1/events", nil); err == nil {
+		t.Error("Dial() without token succeeded")
+	}
+
+	conn, _, err := websocket.Dial(ctx, ts.URL+"/v1/events", &websocket.DialOptions{
+		HTTPHeader: http.Header{"Authorization": {"Bearer " + testToken}},
+	})
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.CloseNow()
+
+	go changeVolume(ctx, b.players["MacBook Pro"])
+
+	var e struct {
+		Type    string             `json:"type"`
+		Current core.PlaybackState `json:"current"`
+	}
+	if err := wsjson.Read(ctx, conn, &e); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if e.Type != tail.EventVolumeChange.String() || e.Current.Device.Name != "MacBook Pro" {
+		t.Errorf("event = %+v", e)
+	}
+}
+
+func TestEventsWebSocketOrigin(t *testing.T) {
+	s := NewServer(newFakeBackends(), nil, testToken, "test", 10*time.Millisecond)
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dial := func(origin string) error {
+		conn, _, err := websocket.Dial(ctx, ts.URL+"/v1/events?access_token="+testToken, &websocket.DialOptions{
+			HTTPHeader: http.Header{"Origin": {origin}},
+		})
+		if err == nil {
+			conn.CloseNow()
+		}
+		return err
+	}
+
+	// A page elsewhere can't use a leaked token
+	if err := dial("https://evil.example"); err == nil {
+		t.Error("Dial() from another origin succeeded")
+	}
+	if err := dial(ts.URL); err != nil {
+		t.Errorf("Dial() from the same origin error = %v", err)
+	}
+
+	s.SetOriginPatterns([]string{"*.example"})
+	if err := dial("https://dash.example"); err != nil {
+		t.Errorf("Dial() from an allowed origin error = %v", err)
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	ts, _ := startServer(t, nil)
+
+	var doc struct {
+		OpenAPI string                               `json:"openapi"`
+		Paths   map[string]map[string]map[string]any `json:"paths"`
+		Comps   struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if code := do(t, ts, "GET", "/v1/openapi.json", nil, &doc); code != http.StatusOK {
+		t.Fatalf("GET /v1/openapi.json = %d", code)
+	}
+	if doc.OpenAPI != "3.1.0" {
+		t.Errorf("openapi = %q", doc.OpenAPI)
+	}
+
+	// Every route is documented
+	s := NewServer(newFakeBackends(), nil, "", "test", 0)
+	for _, rt := range s.routes {
+		op, ok := doc.Paths[rt.path][strings.ToLower(rt.method)]
+		if !ok {
+			t.Errorf("%s %s missing from document", rt.method, rt.path)
+			continue
+		}
+		if op["summary"] != rt.summary {
+			t.Errorf("%s %s summary = %v", rt.method, rt.path, op["summary"])
+		}
+	}
+
+	for _, name := range []string{"Device", "SonosDevice", "PlaybackState", "VolumeRequest", "Event"} {
+		if _, ok := doc.Comps.Schemas[name]; !ok {
+			t.Errorf("schema %s missing", name)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/tail"
+)
+
+// hub shares one tail.Watcher per device among every stream subscribed to
+// it, and stops the watcher when the last subscriber leaves.
+type hub struct {
+	interval time.Duration
+
+	mu    sync.Mutex
+	feeds map[string]*feed
+}
+
+// feed is a running watcher and its subscribers.
+type feed struct {
+	cancel context.CancelFunc
+	subs   map[chan tail.Event]struct{}
+}
+
+func newHub(interval time.Duration) *hub {
+	return &hub{interval: interval, feeds: make(map[string]*feed)}
+}
+
+// subscribe returns a channel of events for player, keyed by key, and a
+// function that unsubscribes it. The channel is closed if the watcher
+// stops on its own.
+func (h *hub) subscribe(key string, player core.Player) (<-chan tail.Event, func()) {
+	ch := make(chan tail.Event, 16)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	f, ok := h.feeds[key]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		f = &feed{cancel: cancel, subs: make(map[chan tail.Event]struct{})}
+		h.feeds[key] = f
+		w := tail.NewWatcher(player, h.interval)
+		go func() { _ = w.Start(ctx) }()
+		go h.fanOut(key, f, w.Events())
+	}
+	f.subs[ch] = struct{}{}
+
+	return ch, func() { h.unsubscribe(key, f, ch) }
+}
+
+// fanOut copies events to every subscriber of f, dropping events for
+// subscribers that have fallen behind.
+func (h *hub) fanOut(key string, f *feed, events <-chan tail.Event) {
+	for e := range events {
+		h.mu.Lock()
+		for sub := range f.subs {
+			select {
+			case sub <- e:
+			default:
+			}
+		}
+		h.mu.Unlock()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range f.subs {
+		close(sub)
+	}
+	f.subs = nil
+	if h.feeds[key] == f {
+		delete(h.feeds, key)
+	}
+}
+
+func (h *hub) unsubscribe(key string, f *feed, ch chan tail.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := f.subs[ch]; !ok {
+		return
+	}
+	delete(f.subs, ch)
+	if len(f.subs) == 0 {
+		f.cancel()
+		if h.feeds[key] == f {
+			delete(h.feeds, key)
+		}
+	}
+}
+
+// streamEvents serves playback events over a WebSocket when the client asks
+// for an upgrade, and as server-sent events otherwise.
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) error {
+	p, d, err := s.backends.PlayerFor(r.Context(), r.URL.Query().Get("device"))
+	if err != nil {
+		return err
+	}
+	key := ""
+	if d != nil {
+		key = string(d.Platform) + ":" + d.ID
+	}
+	events, unsubscribe := s.events.subscribe(key, p)
+	defer unsubscribe()
+
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		streamWebSocket(w, r, events, s.originPatterns)
+	} else {
+		streamSSE(w, r, events)
+	}
+	return nil
+}
+
+// streamWebSocket sends each event as a JSON text message. Browsers let any
+// page open a WebSocket, so pages from other origins are refused unless
+// they match originPatterns; otherwise a page that learned the token could
+// pass it as access_token.
+func streamWebSocket(w http.ResponseWriter, r *http.Request, events <-chan tail.Event, originPatterns []string) {
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: originPatterns})
+	if err != nil {
+		return
+	}
+	defer conn.CloseNow()
+
+	// Clients only listen; CloseRead cancels ctx when they hang up
+	ctx := conn.CloseRead(r.Context())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				_ = conn.Close(websocket.StatusGoingAway, "watcher stopped")
+				return
+			}
+			if err := wsjson.Write(ctx, conn, e); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// streamSSE writes each event as a server-sent event named by its type.
+func streamSSE(w http.ResponseWriter, r *http.Request, events <-chan tail.Event) {
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
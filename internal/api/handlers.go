@@ -0,0 +1,399 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/tessro/riff/internal/core"
+	rifferrors "github.com/tessro/riff/internal/errors"
+	"github.com/tessro/riff/internal/sonos"
+	"github.com/tessro/riff/internal/tail"
+)
+
+// route describes one endpoint. The same table drives the mux and the
+// OpenAPI document, so the two can't drift apart.
+type route struct {
+	method   string
+	path     string
+	summary  string
+	query    []param
+	body     any // request body type, or nil
+	response any // response body type, or nil for 204 No Content
+	public   bool
+	handler  handlerFunc
+	stream   streamFunc // replaces handler for WebSocket and event streams
+}
+
+// param is a query parameter.
+type param struct {
+	name        string
+	kind        string // OpenAPI type: string, integer or boolean
+	description string
+	required    bool
+}
+
+var deviceParam = param{name: "device", kind: "string", description: "Target device ID or name; defaults to whatever is playing"}
+
+// DeviceRequest targets a device. An empty device selects whatever is playing.
+type DeviceRequest struct {
+	Device string `json:"device,omitempty"`
+}
+
+// PlayRequest resumes playback, or plays a stream URL when URI is set.
+type PlayRequest struct {
+	Device string `json:"device,omitempty"`
+	URI    string `json:"uri,omitempty"`
+}
+
+// SeekRequest seeks to an absolute position.
+type SeekRequest struct {
+	Device     string `json:"device,omitempty"`
+	PositionMs int    `json:"position_ms"`
+}
+
+// VolumeRequest sets the device volume, or its group's volume when Group is
+// set.
+type VolumeRequest struct {
+	Device  string `json:"device,omitempty"`
+	Percent int    `json:"percent"`
+	Group   bool   `json:"group,omitempty"`
+}
+
+// QueueAddRequest adds a track to the queue.
+type QueueAddRequest struct {
+	Device string `json:"device,omitempty"`
+	URI    string `json:"uri"`
+}
+
+// JoinRequest adds a speaker to a group, named by the group or any member.
+type JoinRequest struct {
+	Speaker string `json:"speaker"`
+	Group   string `json:"group"`
+}
+
+// LeaveRequest makes a speaker standalone.
+type LeaveRequest struct {
+	Speaker string `json:"speaker"`
+}
+
+// DevicesResponse lists devices on every platform.
+type DevicesResponse struct {
+	Devices []core.Device `json:"devices"`
+}
+
+// GroupsResponse lists Sonos speaker groups.
+type GroupsResponse struct {
+	Groups []sonos.Group `json:"groups"`
+}
+
+// LeaveResponse names the group a speaker left.
+type LeaveResponse struct {
+	Group string `json:"group"`
+}
+
+// SearchResponse lists search results.
+type SearchResponse struct {
+	Results []core.SearchResult `json:"results"`
+}
+
+func (s *Server) buildRoutes() []route {
+	return []route{
+		{method: "GET", path: "/v1/openapi.json", summary: "OpenAPI description of this API", public: true, response: map[string]any{}, handler: s.openAPI},
+		{method: "GET", path: "/v1/devices", summary: "List devices", response: DevicesResponse{}, handler: s.devices},
+		{method: "GET", path: "/v1/status", summary: "Current playback state", query: []param{deviceParam}, response: core.PlaybackState{}, handler: s.status},
+		{method: "POST", path: "/v1/player/play", summary: "Resume playback, or play a stream URL", body: PlayRequest{}, handler: s.play},
+		{method: "POST", path: "/v1/player/pause", summary: "Pause playback", body: DeviceRequest{}, handler: s.command(core.Player.Pause)},
+		{method: "POST", path: "/v1/player/next", summary: "Skip to the next track", body: DeviceRequest{}, handler: s.command(core.Player.Next)},
+		{method: "POST", path: "/v1/player/prev", summary: "Go to the previous track", body: DeviceRequest{}, handler: s.command(core.Player.Prev)},
+		{method: "POST", path: "/v1/player/seek", summary: "Seek within the current track", body: SeekRequest{}, handler: s.seek},
+		{method: "POST", path: "/v1/player/volume", summary: "Set the device or group volume", body: VolumeRequest{}, handler: s.volume},
+		{method: "GET", path: "/v1/queue", summary: "Show the playback queue", query: []param{deviceParam}, response: core.Queue{}, handler: s.queue},
+		{method: "POST", path: "/v1/queue", summary: "Add a track to the queue", body: QueueAddRequest{}, handler: s.queueAdd},
+		{method: "DELETE", path: "/v1/queue", summary: "Clear the queue", query: []param{deviceParam}, handler: s.queueClear},
+		{method: "DELETE", path: "/v1/queue/{position}", summary: "Remove the track at a 1-based queue position", query: []param{deviceParam}, handler: s.queueRemove},
+		{method: "GET", path: "/v1/groups", summary: "List Sonos speaker groups", response: GroupsResponse{}, handler: s.listGroups},
+		{method: "POST", path: "/v1/groups/join", summary: "Add a speaker to a group", body: JoinRequest{}, handler: s.joinGroup},
+		{method: "POST", path: "/v1/groups/leave", summary: "Remove a speaker from its group", body: LeaveRequest{}, response: LeaveResponse{}, handler: s.leaveGroup},
+		{method: "GET", path: "/v1/search", summary: "Search for playable items", query: []param{
+			{name: "q", kind: "string", description: "Search query", required: true},
+			{name: "type", kind: "string", description: "Comma-separated kinds: track, album, artist, playlist (default: track)"},
+			{name: "limit", kind: "integer", description: "Maximum results per backend (default: 10)"},
+		}, response: SearchResponse{}, handler: s.search},
+		{method: "GET", path: "/v1/events", summary: "Stream playback events over WebSocket, or as server-sent events", query: []param{deviceParam}, response: tail.Event{}, stream: s.streamEvents},
+	}
+}
+
+// decode reads a JSON request body into v. An empty body leaves v unchanged.
+func decode(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && err != io.EOF {
+		return badRequestf("invalid request body: %v", err)
+	}
+	return nil
+}
+
+func (s *Server) player(r *http.Request, device string) (core.Player, error) {
+	p, _, err := s.backends.PlayerFor(r.Context(), device)
+	return p, err
+}
+
+func (s *Server) devices(r *http.Request) (any, error) {
+	devices, err := s.backends.Devices(r.Context())
+	if err != nil && len(devices) == 0 {
+		return nil, err
+	}
+	if devices == nil {
+		devices = []core.Device{}
+	}
+	return DevicesResponse{Devices: devices}, nil
+}
+
+func (s *Server) status(r *http.Request) (any, error) {
+	p, err := s.player(r, r.URL.Query().Get("device"))
+	if err != nil {
+		return nil, err
+	}
+	state, err := p.GetState(r.Context())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get playback state: %w", err)
+	}
+	return state, nil
+}
+
+func (s *Server) play(r *http.Request) (any, error) {
+	var req PlayRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	p, err := s.player(r, req.Device)
+	if err != nil {
+		return nil, err
+	}
+	if req.URI == "" {
+		return nil, p.Play(r.Context())
+	}
+	if err := core.Require(p, core.CapStream); err != nil {
+		return nil, err
+	}
+	return nil, p.(core.StreamPlayer).PlayURI(r.Context(), req.URI)
+}
+
+// command returns a handler that runs op on the requested device.
+func (s *Server) command(op func(core.Player, context.Context) error) handlerFunc {
+	return func(r *http.Request) (any, error) {
+		var req DeviceRequest
+		if err := decode(r, &req); err != nil {
+			return nil, err
+		}
+		p, err := s.player(r, req.Device)
+		if err != nil {
+			return nil, err
+		}
+		return nil, op(p, r.Context())
+	}
+}
+
+func (s *Server) seek(r *http.Request) (any, error) {
+	var req SeekRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	if req.PositionMs < 0 {
+		return nil, badRequestf("position_ms must be non-negative")
+	}
+	p, err := s.player(r, req.Device)
+	if err != nil {
+		return nil, err
+	}
+	if err := core.Require(p, core.CapSeek); err != nil {
+		return nil, err
+	}
+	return nil, p.Seek(r.Context(), req.PositionMs)
+}
+
+func (s *Server) volume(r *http.Request) (any, error) {
+	var req VolumeRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	if req.Percent < 0 || req.Percent > 100 {
+		return nil, badRequestf("percent must be between 0 and 100")
+	}
+	p, err := s.player(r, req.Device)
+	if err != nil {
+		return nil, err
+	}
+	if !req.Group {
+		return nil, p.Volume(r.Context(), req.Percent)
+	}
+	if err := core.Require(p, core.CapGroupVolume); err != nil {
+		return nil, err
+	}
+	return nil, p.(core.GroupVolumeController).SetGroupVolume(r.Context(), req.Percent)
+}
+
+func (s *Server) queue(r *http.Request) (any, error) {
+	p, err := s.player(r, r.URL.Query().Get("device"))
+	if err != nil {
+		return nil, err
+	}
+	if err := core.Require(p, core.CapQueueRead); err != nil {
+		return nil, err
+	}
+	q, err := p.GetQueue(r.Context())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue: %w", err)
+	}
+	return q, nil
+}
+
+func (s *Server) queueAdd(r *http.Request) (any, error) {
+	var req QueueAddRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	if req.URI == "" {
+		return nil, badRequestf("uri is required")
+	}
+	p, err := s.player(r, req.Device)
+	if err != nil {
+		return nil, err
+	}
+	if err := core.Require(p, core.CapQueueAdd); err != nil {
+		return nil, err
+	}
+	return nil, p.AddToQueue(r.Context(), req.URI)
+}
+
+func (s *Server) queueEditor(r *http.Request) (core.QueueEditor, error) {
+	p, err := s.player(r, r.URL.Query().Get("device"))
+	if err != nil {
+		return nil, err
+	}
+	if err := core.Require(p, core.CapQueueEdit); err != nil {
+		return nil, err
+	}
+	return p.(core.QueueEditor), nil
+}
+
+func (s *Server) queueClear(r *http.Request) (any, error) {
+	q, err := s.queueEditor(r)
+	if err != nil {
+		return nil, err
+	}
+	return nil, q.ClearQueue(r.Context())
+}
+
+func (s *Server) queueRemove(r *http.Request) (any, error) {
+	position, err := strconv.Atoi(r.PathValue("position"))
+	if err != nil || position < 1 {
+		return nil, badRequestf("invalid queue position: %s", r.PathValue("position"))
+	}
+	q, err := s.queueEditor(r)
+	if err != nil {
+		return nil, err
+	}
+	return nil, q.RemoveFromQueue(r.Context(), position)
+}
+
+// errNoGroups is returned by the group endpoints when no Sonos backend is
+// available.
+var errNoGroups = rifferrors.Unsupported("speaker grouping", "this server", "Grouping is only available for Sonos speakers")
+
+func (s *Server) requireGroups() error {
+	if s.groups == nil {
+		return errNoGroups
+	}
+	return nil
+}
+
+func (s *Server) listGroups(r *http.Request) (any, error) {
+	if err := s.requireGroups(); err != nil {
+		return nil, err
+	}
+	groups, err := s.groups.Groups(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	if groups == nil {
+		groups = []sonos.Group{}
+	}
+	return GroupsResponse{Groups: groups}, nil
+}
+
+func (s *Server) joinGroup(r *http.Request) (any, error) {
+	var req JoinRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	if req.Speaker == "" || req.Group == "" {
+		return nil, badRequestf("speaker and group are required")
+	}
+	if err := s.requireGroups(); err != nil {
+		return nil, err
+	}
+	return nil, s.groups.Join(r.Context(), req.Speaker, req.Group)
+}
+
+func (s *Server) leaveGroup(r *http.Request) (any, error) {
+	var req LeaveRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	if req.Speaker == "" {
+		return nil, badRequestf("speaker is required")
+	}
+	if err := s.requireGroups(); err != nil {
+		return nil, err
+	}
+	group, err := s.groups.Leave(r.Context(), req.Speaker)
+	if err != nil {
+		return nil, err
+	}
+	return LeaveResponse{Group: group}, nil
+}
+
+func (s *Server) search(r *http.Request) (any, error) {
+	query := r.URL.Query()
+	q := query.Get("q")
+	if q == "" {
+		return nil, badRequestf("q is required")
+	}
+
+	kinds := []core.SearchKind{core.SearchKindTrack}
+	if v := query.Get("type"); v != "" {
+		kinds = nil
+		for _, k := range strings.Split(v, ",") {
+			switch kind := core.SearchKind(strings.TrimSpace(k)); kind {
+			case core.SearchKindTrack, core.SearchKindAlbum, core.SearchKindArtist, core.SearchKindPlaylist:
+				kinds = append(kinds, kind)
+			default:
+				return nil, badRequestf("invalid search type: %s", k)
+			}
+		}
+	}
+
+	limit := 10
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 50 {
+			return nil, badRequestf("limit must be between 1 and 50")
+		}
+		limit = n
+	}
+
+	results, err := s.backends.Search(r.Context(), q, kinds, limit)
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+	if results == nil {
+		results = []core.SearchResult{}
+	}
+	return SearchResponse{Results: results}, nil
+}
@@ -0,0 +1,249 @@
+package api
+
+import (
+	"encoding"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	durationType      = reflect.TypeFor[time.Duration]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+func (s *Server) openAPI(r *http.Request) (any, error) {
+	return s.OpenAPI(), nil
+}
+
+// OpenAPI returns an OpenAPI 3.1 document describing every route, with
+// schemas generated from the request and response types.
+func (s *Server) OpenAPI() map[string]any {
+	g := &schemaGen{schemas: map[string]any{}, names: map[reflect.Type]string{}}
+	errorRef := g.schema(reflect.TypeFor[errorResponse]())
+
+	paths := map[string]any{}
+	for _, rt := range s.routes {
+		op := map[string]any{
+			"operationId": operationID(rt),
+			"summary":     rt.summary,
+		}
+		if rt.public {
+			op["security"] = []any{}
+		}
+
+		var params []any
+		for _, name := range pathParams(rt.path) {
+			params = append(params, map[string]any{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "integer"},
+			})
+		}
+		for _, p := range rt.query {
+			params = append(params, map[string]any{
+				"name":        p.name,
+				"in":          "query",
+				"description": p.description,
+				"required":    p.required,
+				"schema":      map[string]any{"type": p.kind},
+			})
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+
+		if rt.body != nil {
+			op["requestBody"] = map[string]any{
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": g.schema(reflect.TypeOf(rt.body))},
+				},
+			}
+		}
+
+		responses := map[string]any{
+			"default": map[string]any{
+				"description": "Error",
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": errorRef},
+				},
+			},
+		}
+		switch {
+		case rt.stream != nil:
+			event := g.schema(reflect.TypeOf(rt.response))
+			responses["101"] = map[string]any{"description": "WebSocket; each text message is an event"}
+			responses["200"] = map[string]any{
+				"description": "Server-sent events named by event type",
+				"content": map[string]any{
+					"text/event-stream": map[string]any{"schema": event},
+				},
+			}
+		case rt.response == nil:
+			responses["204"] = map[string]any{"description": "Done"}
+		default:
+			responses["200"] = map[string]any{
+				"description": "OK",
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": g.schema(reflect.TypeOf(rt.response))},
+				},
+			}
+		}
+		op["responses"] = responses
+
+		item, _ := paths[rt.path].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[rt.path] = item
+		}
+		item[strings.ToLower(rt.method)] = op
+	}
+
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":   "riff",
+			"version": s.version,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": g.schemas,
+			"securitySchemes": map[string]any{
+				"bearer": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []any{map[string]any{"bearer": []any{}}},
+	}
+}
+
+// operationID derives an operation ID such as "postPlayerVolume" from a
+// route.
+func operationID(rt route) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(rt.method))
+	for _, part := range strings.FieldsFunc(strings.TrimPrefix(rt.path, "/v1/"), func(r rune) bool {
+		return r == '/' || r == '.' || r == '_' || r == '{' || r == '}'
+	}) {
+		if part == "json" {
+			continue
+		}
+		b.WriteString(capitalize(part))
+	}
+	return b.String()
+}
+
+// pathParams returns the names of the {wildcards} in a route path.
+func pathParams(p string) []string {
+	var names []string
+	for _, seg := range strings.Split(p, "/") {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			names = append(names, strings.TrimSuffix(seg[1:], "}"))
+		}
+	}
+	return names
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// schemaGen builds JSON Schemas for Go types, collecting named structs as
+// components.
+type schemaGen struct {
+	schemas map[string]any
+	names   map[reflect.Type]string
+}
+
+// schema returns the schema for t, or a reference to its component.
+func (g *schemaGen) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == durationType:
+		return map[string]any{"type": "integer", "description": "Duration in nanoseconds"}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return map[string]any{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + g.component(t)}
+	default:
+		return map[string]any{}
+	}
+}
+
+// component registers a named struct and returns its component name.
+// Types from different packages that share a name, such as core.Device and
+// sonos.Device, are told apart by a package prefix.
+func (g *schemaGen) component(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, taken := g.schemas[name]; taken {
+		name = capitalize(path.Base(t.PkgPath())) + name
+	}
+	g.names[t] = name
+	g.schemas[name] = map[string]any{} // placeholder for recursive types
+	g.schemas[name] = g.object(t)
+	return name
+}
+
+// object builds an object schema from a struct's JSON fields.
+func (g *schemaGen) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = g.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]any{"type": "object", "properties": props}
+	if required != nil {
+		schema["required"] = required
+	}
+	return schema
+}
@@ -0,0 +1,194 @@
+// Package api serves riff's playback controls over HTTP, with playback
+// events streamed over WebSocket or server-sent events.
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+	rifferrors "github.com/tessro/riff/internal/errors"
+	"github.com/tessro/riff/internal/sonos"
+)
+
+// Backends resolves devices and players. The CLI's backend registry, direct
+// or served by riffd, satisfies it.
+type Backends interface {
+	core.DeviceResolver
+	Search(ctx context.Context, query string, kinds []core.SearchKind, limit int) ([]core.SearchResult, error)
+}
+
+// Groups manages Sonos speaker groups.
+type Groups interface {
+	Groups(ctx context.Context) ([]sonos.Group, error)
+	Join(ctx context.Context, speaker, target string) error
+	Leave(ctx context.Context, speaker string) (string, error)
+}
+
+// Ensure sonos.Backend implements Groups
+var _ Groups = (*sonos.Backend)(nil)
+
+// Server is the HTTP API.
+type Server struct {
+	backends Backends
+	groups   Groups
+	token    string
+	version  string
+	events   *hub
+	routes   []route
+
+	originPatterns []string
+}
+
+// NewServer creates an API server. groups may be nil, in which case the
+// group endpoints report that grouping is unsupported. Requests must carry
+// token as a bearer token; an empty token disables authentication.
+// interval is how often event streams poll the player.
+func NewServer(backends Backends, groups Groups, token, version string, interval time.Duration) *Server {
+	s := &Server{
+		backends: backends,
+		groups:   groups,
+		token:    token,
+		version:  version,
+		events:   newHub(interval),
+	}
+	s.routes = s.buildRoutes()
+	return s
+}
+
+// SetOriginPatterns sets the host patterns, like "dash.example.com" or
+// "*.lan", of the web pages allowed to open event WebSockets. Without any,
+// only pages served from the API's own host may.
+func (s *Server) SetOriginPatterns(patterns []string) {
+	s.originPatterns = patterns
+}
+
+// Handler returns the HTTP handler serving every route.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	for _, rt := range s.routes {
+		var h http.Handler
+		if rt.stream != nil {
+			h = s.handleStream(rt.stream)
+		} else {
+			h = s.handle(rt.handler)
+		}
+		if !rt.public {
+			h = s.authenticate(h)
+		}
+		mux.Handle(rt.method+" "+rt.path, h)
+	}
+	return mux
+}
+
+// authenticate rejects requests without the server's bearer token. Browsers
+// can't set headers on WebSocket or EventSource requests, so the token is
+// also accepted as the access_token query parameter.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token := r.URL.Query().Get("access_token")
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			scheme, value, _ := strings.Cut(auth, " ")
+			if strings.EqualFold(scheme, "Bearer") {
+				token = value
+			}
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="riff"`)
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing or invalid bearer token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handlerFunc is an HTTP handler that returns the response body, or nil for
+// 204 No Content.
+type handlerFunc func(r *http.Request) (any, error)
+
+// handle adapts h, encoding its result or error as JSON.
+func (s *Server) handle(h handlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result, err := h(r)
+		switch {
+		case err != nil:
+			writeError(w, err)
+		case result == nil:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeJSON(w, http.StatusOK, result)
+		}
+	})
+}
+
+// streamFunc is an HTTP handler that streams its response. It returns an
+// error only if it fails before writing anything.
+type streamFunc func(w http.ResponseWriter, r *http.Request) error
+
+// handleStream adapts h, encoding an early error as JSON.
+func (s *Server) handleStream(h streamFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := h(w, r); err != nil {
+			writeError(w, err)
+		}
+	})
+}
+
+// errorResponse is the body of every error response.
+type errorResponse struct {
+	Error      string `json:"error"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// badRequest is an error in the client's request.
+type badRequest struct {
+	msg string
+}
+
+func (e *badRequest) Error() string {
+	return e.msg
+}
+
+func badRequestf(format string, args ...any) error {
+	return &badRequest{msg: fmt.Sprintf(format, args...)}
+}
+
+// statusFor maps err to an HTTP status code.
+func statusFor(err error) int {
+	var br *badRequest
+	switch {
+	case errors.As(err, &br):
+		return http.StatusBadRequest
+	case errors.Is(err, rifferrors.ErrDeviceNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, rifferrors.ErrUnsupported):
+		return http.StatusNotImplemented
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, statusFor(err), errorResponse{
+		Error:      err.Error(),
+		Suggestion: rifferrors.GetSuggestion(err),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
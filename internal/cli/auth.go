@@ -1,16 +1,22 @@
 package cli
 
 import (
+	"bufio"
 	"context"
-	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
 	"os"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/tessro/riff/internal/browser"
+	"github.com/tessro/riff/internal/config"
 	"github.com/tessro/riff/internal/spotify/auth"
 	"github.com/tessro/riff/internal/spotify/client"
+	"golang.org/x/term"
 )
 
 var authCmd = &cobra.Command{
@@ -22,8 +28,18 @@ var authCmd = &cobra.Command{
 var authLoginCmd = &cobra.Command{
 	Use:   "login",
 	Short: "Authenticate with Spotify",
-	Long:  `Opens a browser to authenticate with Spotify using OAuth PKCE flow.`,
-	RunE:  runAuthLogin,
+	Long: `Opens a browser to authenticate with Spotify using OAuth PKCE flow.
+
+On a headless machine, use --no-browser to print the authorization URL
+instead. Open it on any device, approve access, then paste the URL you are
+redirected to (or just the code parameter) back into the terminal.
+
+To finish the login through an SSH tunnel instead, bind the callback server
+with --listen and forward the redirect URI's port:
+
+  ssh -L 8888:127.0.0.1:8888 pi
+  riff auth login --no-browser --listen 127.0.0.1:8888`,
+	RunE: runAuthLogin,
 }
 
 var authLogoutCmd = &cobra.Command{
@@ -40,38 +56,207 @@ var authStatusCmd = &cobra.Command{
 	RunE:  runAuthStatus,
 }
 
+var authMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Move stored credentials between token stores",
+	Long: `Copies the stored Spotify token from one token store backend to another,
+then removes it from the source.
+
+The destination defaults to the backend selected by spotify.token_store.backend.
+
+Examples:
+  riff auth migrate --from file                  # Move to the configured backend
+  riff auth migrate --from file --to encrypted
+  riff auth migrate --from encrypted --to command --keep`,
+	RunE: runAuthMigrate,
+}
+
+var (
+	loginNoBrowser bool
+	loginListen    string
+)
+
+var (
+	migrateFrom string
+	migrateTo   string
+	migrateKeep bool
+)
+
 func init() {
+	authLoginCmd.Flags().BoolVar(&loginNoBrowser, "no-browser", false, "Print the authorization URL and read the redirect URL from stdin")
+	authLoginCmd.Flags().StringVar(&loginListen, "listen", "", "Address for the callback server, e.g. 127.0.0.1:8888 (default: redirect URI port)")
+
+	authMigrateCmd.Flags().StringVar(&migrateFrom, "from", "", "Source backend (file, encrypted, command)")
+	authMigrateCmd.Flags().StringVar(&migrateTo, "to", "", "Destination backend (default: configured backend)")
+	authMigrateCmd.Flags().BoolVar(&migrateKeep, "keep", false, "Keep the token in the source store")
+	_ = authMigrateCmd.MarkFlagRequired("from")
+
 	authCmd.AddCommand(authLoginCmd)
 	authCmd.AddCommand(authLogoutCmd)
 	authCmd.AddCommand(authStatusCmd)
+	authCmd.AddCommand(authMigrateCmd)
 	rootCmd.AddCommand(authCmd)
+
+	registerOutput(authLoginCmd, authLoginResult{})
+	registerOutput(authLogoutCmd, actionResult{})
+	registerOutput(authStatusCmd, authStatusResult{})
+	registerOutput(authMigrateCmd, authMigrateResult{})
 }
 
-func runAuthLogin(cmd *cobra.Command, args []string) error {
-	if cfg.Spotify.ClientID == "" {
-		return fmt.Errorf("spotify.client_id not configured. Set it in ~/.riffrc or via RIFF_SPOTIFY_CLIENT_ID")
+// authLoginResult is the output of riff auth login.
+type authLoginResult struct {
+	Status      string `json:"status"`
+	UserID      string `json:"user_id"`
+	DisplayName string `json:"display_name"`
+	Email       string `json:"email"`
+	Product     string `json:"product"`
+}
+
+// authStatusResult is the output of riff auth status. The account fields
+// are set when Spotify could be reached.
+type authStatusResult struct {
+	Authenticated bool      `json:"authenticated"`
+	Expired       bool      `json:"expired"`
+	UserID        string    `json:"user_id,omitempty"`
+	DisplayName   string    `json:"display_name,omitempty"`
+	Email         string    `json:"email,omitempty"`
+	Product       string    `json:"product,omitempty"`
+	ExpiresAt     time.Time `json:"expires_at,omitzero"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// authMigrateResult is the output of riff auth migrate.
+type authMigrateResult struct {
+	Status string `json:"status"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Kept   bool   `json:"kept"`
+}
+
+// newTokenStore opens the token store selected in the config.
+func newTokenStore() (auth.TokenStore, error) {
+	store, err := openTokenStore(cfg.Spotify.TokenStore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize token storage: %w", err)
 	}
+	return store, nil
+}
 
-	// Generate PKCE parameters
-	pkce, err := auth.NewPKCE()
+// spotifyHTTPClient builds the HTTP client described by [spotify.http].
+func spotifyHTTPClient() (*http.Client, error) {
+	hc := cfg.Spotify.HTTP
+	httpClient, err := client.NewHTTPClient(client.HTTPConfig{
+		Proxy:          hc.Proxy,
+		CAFile:         hc.CAFile,
+		Timeout:        time.Duration(hc.Timeout) * time.Second,
+		ConnectTimeout: time.Duration(hc.ConnectTimeout) * time.Second,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to generate PKCE: %w", err)
+		return nil, fmt.Errorf("invalid spotify.http config: %w", err)
 	}
+	return httpClient, nil
+}
 
-	// Start callback server
-	callbackServer, err := auth.NewCallbackServer(8888)
+// newSpotifyClient creates a Spotify client for the configured endpoints.
+func newSpotifyClient(storage auth.TokenStore) (*client.Client, error) {
+	opts, err := spotifyClientOptions()
 	if err != nil {
-		return fmt.Errorf("failed to start callback server: %w", err)
+		return nil, err
 	}
-	callbackServer.Start()
-	defer func() { _ = callbackServer.Shutdown(context.Background()) }()
+	return client.New(cfg.Spotify.ClientID, storage, opts...), nil
+}
 
-	// Build auth URL
-	config := auth.NewConfig(cfg.Spotify.ClientID)
-	if cfg.Spotify.RedirectURI != "" {
-		config.RedirectURI = cfg.Spotify.RedirectURI
+// spotifyClientOptions returns the client options for [spotify.http].
+func spotifyClientOptions() ([]client.Option, error) {
+	httpClient, err := spotifyHTTPClient()
+	if err != nil {
+		return nil, err
 	}
-	authURL := config.BuildAuthURL(pkce)
+	return []client.Option{
+		client.WithHTTPClient(httpClient),
+		client.WithBaseURL(cfg.Spotify.HTTP.APIURL),
+		client.WithAccountsURL(cfg.Spotify.HTTP.AccountsURL),
+	}, nil
+}
+
+// openTokenStore builds a token store from its configuration.
+func openTokenStore(sc config.TokenStoreConfig) (auth.TokenStore, error) {
+	switch sc.Backend {
+	case "", "file":
+		return auth.NewTokenStorage(sc.Path)
+	case "encrypted":
+		if sc.KeyFile != "" {
+			return auth.NewKeyFileTokenStorage(sc.Path, sc.KeyFile)
+		}
+		passphrase, err := readTokenPassphrase(sc.PassphraseEnv)
+		if err != nil {
+			return nil, err
+		}
+		return auth.NewEncryptedTokenStorage(sc.Path, passphrase)
+	case "command":
+		return auth.NewCommandTokenStorage(sc.Command)
+	default:
+		return nil, fmt.Errorf("unknown token store backend: %s", sc.Backend)
+	}
+}
+
+// readTokenPassphrase reads the encryption passphrase from the environment,
+// prompting for it when running interactively.
+func readTokenPassphrase(envVar string) (string, error) {
+	if envVar != "" {
+		if v := os.Getenv(envVar); v != "" {
+			return v, nil
+		}
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("encrypted token store needs a passphrase: set %s or configure spotify.token_store.key_file", envVar)
+	}
+
+	fmt.Fprint(os.Stderr, "Token store passphrase: ")
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if len(passphrase) == 0 {
+		return "", fmt.Errorf("passphrase must not be empty")
+	}
+	return string(passphrase), nil
+}
+
+// callbackAddr returns the address the login callback server should bind to:
+// --listen, then spotify.callback_addr, then the redirect URI's port on all
+// interfaces.
+func callbackAddr(redirectURI string) (string, error) {
+	if loginListen != "" {
+		return loginListen, nil
+	}
+	if cfg.Spotify.CallbackAddr != "" {
+		return cfg.Spotify.CallbackAddr, nil
+	}
+
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return "", fmt.Errorf("invalid redirect URI: %w", err)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "8888"
+	}
+	return ":" + port, nil
+}
+
+// waitForBrowserCallback opens the authorize URL in a browser and waits for
+// Spotify to redirect back to the local callback server.
+func waitForBrowserCallback(ctx context.Context, authURL, redirectURI string, pkce *auth.PKCE) (auth.CallbackResult, error) {
+	// Start callback server
+	callbackServer, err := startCallbackServer(redirectURI)
+	if err != nil {
+		return auth.CallbackResult{}, err
+	}
+	defer func() { _ = callbackServer.Shutdown(context.Background()) }()
 
 	// Open browser
 	fmt.Println("Opening browser for Spotify authentication...")
@@ -82,34 +267,153 @@ func runAuthLogin(cmd *cobra.Command, args []string) error {
 
 	// Wait for callback
 	fmt.Println("Waiting for authentication...")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
-
 	result, err := callbackServer.Wait(ctx)
 	if err != nil {
-		return fmt.Errorf("authentication timed out: %w", err)
+		return auth.CallbackResult{}, fmt.Errorf("authentication timed out: %w", err)
 	}
 
-	if result.Error != "" {
-		return fmt.Errorf("authentication failed: %s", result.Error)
+	// Verify state
+	if err := result.Validate(pkce, false); err != nil {
+		return auth.CallbackResult{}, err
 	}
+	return result, nil
+}
 
-	// Verify state
-	if result.State != pkce.State {
-		return fmt.Errorf("state mismatch: possible CSRF attack")
+// waitForPastedCallback prints the authorize URL and reads back the redirect
+// URL (or bare code) the user pastes after authorizing on another machine.
+// With --listen, the callback server runs as well, so a redirect arriving
+// through an SSH tunnel completes the login without pasting anything.
+func waitForPastedCallback(ctx context.Context, authURL, redirectURI string, pkce *auth.PKCE) (auth.CallbackResult, error) {
+	var callbackResult <-chan auth.CallbackResult
+	if loginListen != "" {
+		callbackServer, err := startCallbackServer(redirectURI)
+		if err != nil {
+			return auth.CallbackResult{}, err
+		}
+		defer func() { _ = callbackServer.Shutdown(context.Background()) }()
+
+		ch := make(chan auth.CallbackResult, 1)
+		go func() {
+			if result, err := callbackServer.Wait(ctx); err == nil {
+				ch <- result
+			}
+		}()
+		callbackResult = ch
+	}
+
+	fmt.Printf("Open this URL in a browser on any machine:\n\n%s\n\n", authURL)
+	fmt.Println("After approving access, the browser is redirected to a page that may fail to load.")
+	fmt.Print("Paste the full URL from the address bar (or just the code): ")
+
+	type pasted struct {
+		line string
+		err  error
+	}
+	input := make(chan pasted, 1)
+	go func() {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err == io.EOF && line != "" {
+			err = nil
+		}
+		input <- pasted{line, err}
+	}()
+
+	var result auth.CallbackResult
+	select {
+	case <-ctx.Done():
+		fmt.Println()
+		return auth.CallbackResult{}, fmt.Errorf("authentication timed out: %w", ctx.Err())
+	case result = <-callbackResult:
+		fmt.Println()
+		if err := result.Validate(pkce, false); err != nil {
+			return auth.CallbackResult{}, err
+		}
+	case in := <-input:
+		if in.err != nil {
+			return auth.CallbackResult{}, fmt.Errorf("failed to read redirect URL: %w", in.err)
+		}
+		var bareCode bool
+		var err error
+		result, bareCode, err = auth.ParseCallbackInput(in.line)
+		if err != nil {
+			return auth.CallbackResult{}, err
+		}
+		// A bare code has no state to compare; the PKCE verifier still
+		// ties it to this login attempt. A pasted URL must carry the state.
+		if err := result.Validate(pkce, bareCode); err != nil {
+			return auth.CallbackResult{}, err
+		}
+	}
+
+	return result, nil
+}
+
+// startCallbackServer starts the login callback server on the configured address.
+func startCallbackServer(redirectURI string) (*auth.CallbackServer, error) {
+	addr, err := callbackAddr(redirectURI)
+	if err != nil {
+		return nil, err
+	}
+
+	callbackServer, err := auth.NewCallbackServerAddr(addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start callback server: %w", err)
+	}
+	callbackServer.Start()
+
+	slog.Debug("callback server listening", "addr", callbackServer.Addr())
+	return callbackServer, nil
+}
+
+func runAuthLogin(cmd *cobra.Command, args []string) error {
+	if cfg.Spotify.ClientID == "" {
+		return fmt.Errorf("spotify.client_id not configured. Set it in ~/.riffrc or via RIFF_SPOTIFY_CLIENT_ID")
+	}
+
+	// Generate PKCE parameters
+	pkce, err := auth.NewPKCE()
+	if err != nil {
+		return fmt.Errorf("failed to generate PKCE: %w", err)
+	}
+
+	// Build auth URL
+	config := auth.NewConfig(cfg.Spotify.ClientID)
+	if cfg.Spotify.RedirectURI != "" {
+		config.RedirectURI = cfg.Spotify.RedirectURI
+	}
+	config.AccountsURL = cfg.Spotify.HTTP.AccountsURL
+
+	httpClient, err := spotifyHTTPClient()
+	if err != nil {
+		return err
+	}
+	authURL := config.BuildAuthURL(pkce)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var result auth.CallbackResult
+	if loginNoBrowser {
+		result, err = waitForPastedCallback(ctx, authURL, config.RedirectURI, pkce)
+	} else {
+		result, err = waitForBrowserCallback(ctx, authURL, config.RedirectURI, pkce)
+	}
+	if err != nil {
+		return err
 	}
 
 	// Exchange code for tokens
 	fmt.Println("Exchanging code for tokens...")
-	token, err := auth.ExchangeCode(ctx, cfg.Spotify.ClientID, result.Code, config.RedirectURI, pkce.Verifier)
+	token, err := auth.ExchangeCode(ctx, cfg.Spotify.ClientID, result.Code, config.RedirectURI, pkce.Verifier,
+		auth.WithAccountsURL(config.AccountsURL), auth.WithHTTPClient(httpClient))
 	if err != nil {
 		return fmt.Errorf("failed to exchange code: %w", err)
 	}
 
 	// Store token
-	storage, err := auth.NewTokenStorage("")
+	storage, err := newTokenStore()
 	if err != nil {
-		return fmt.Errorf("failed to initialize token storage: %w", err)
+		return err
 	}
 
 	if err := storage.Save(token); err != nil {
@@ -117,11 +421,9 @@ func runAuthLogin(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get user info to confirm success
-	spotifyClient := client.New(cfg.Spotify.ClientID, storage)
-	if Verbose() {
-		spotifyClient.SetVerbose(true, func(format string, args ...interface{}) {
-			fmt.Fprintf(os.Stderr, format+"\n", args...)
-		})
+	spotifyClient, err := newSpotifyClient(storage)
+	if err != nil {
+		return err
 	}
 	if err := spotifyClient.LoadToken(); err != nil {
 		return fmt.Errorf("failed to load token: %w", err)
@@ -133,54 +435,41 @@ func runAuthLogin(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	if JSONOutput() {
-		output := map[string]interface{}{
-			"status":       "authenticated",
-			"user_id":      user.ID,
-			"display_name": user.DisplayName,
-			"email":        user.Email,
-			"product":      user.Product,
-		}
-		_ = json.NewEncoder(os.Stdout).Encode(output)
-	} else {
-		fmt.Printf("Successfully authenticated as %s (%s)\n", user.DisplayName, user.Email)
-	}
-
-	return nil
+	return renderf(authLoginResult{
+		Status:      "authenticated",
+		UserID:      user.ID,
+		DisplayName: user.DisplayName,
+		Email:       user.Email,
+		Product:     user.Product,
+	}, "Successfully authenticated as %s (%s)", user.DisplayName, user.Email)
 }
 
 func runAuthLogout(cmd *cobra.Command, args []string) error {
-	storage, err := auth.NewTokenStorage("")
+	storage, err := newTokenStore()
 	if err != nil {
-		return fmt.Errorf("failed to initialize token storage: %w", err)
+		return err
 	}
 
-	if !storage.Exists() {
-		if JSONOutput() {
-			_ = json.NewEncoder(os.Stdout).Encode(map[string]string{"status": "not_authenticated"})
-		} else {
-			fmt.Println("Not authenticated with Spotify.")
-		}
-		return nil
+	token, err := storage.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load token: %w", err)
 	}
 
-	if err := storage.Delete(); err != nil {
-		return fmt.Errorf("failed to delete token: %w", err)
+	if token == nil {
+		return renderf(actionResult{Status: "not_authenticated"}, "Not authenticated with Spotify.")
 	}
 
-	if JSONOutput() {
-		_ = json.NewEncoder(os.Stdout).Encode(map[string]string{"status": "logged_out"})
-	} else {
-		fmt.Println("Logged out of Spotify.")
+	if err := storage.Delete(); err != nil {
+		return fmt.Errorf("failed to delete token: %w", err)
 	}
 
-	return nil
+	return renderf(actionResult{Status: "logged_out"}, "Logged out of Spotify.")
 }
 
 func runAuthStatus(cmd *cobra.Command, args []string) error {
-	storage, err := auth.NewTokenStorage("")
+	storage, err := newTokenStore()
 	if err != nil {
-		return fmt.Errorf("failed to initialize token storage: %w", err)
+		return err
 	}
 
 	token, err := storage.Load()
@@ -189,40 +478,29 @@ func runAuthStatus(cmd *cobra.Command, args []string) error {
 	}
 
 	if token == nil {
-		if JSONOutput() {
-			_ = json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
-				"authenticated": false,
-			})
-		} else {
+		return render(authStatusResult{}, func() error {
 			fmt.Println("Not authenticated with Spotify.")
 			fmt.Println("Run 'riff auth login' to authenticate.")
-		}
-		return nil
+			return nil
+		})
 	}
 
 	// Try to get user info
 	if cfg.Spotify.ClientID == "" {
-		if JSONOutput() {
-			_ = json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
-				"authenticated": true,
-				"expired":       token.IsExpired(),
-				"expires_at":    token.ExpiresAt,
-			})
-		} else {
-			if token.IsExpired() {
-				fmt.Println("Authenticated but token expired.")
-			} else {
-				fmt.Println("Authenticated with Spotify.")
-			}
+		text := "Authenticated with Spotify."
+		if token.IsExpired() {
+			text = "Authenticated but token expired."
 		}
-		return nil
+		return renderf(authStatusResult{
+			Authenticated: true,
+			Expired:       token.IsExpired(),
+			ExpiresAt:     token.ExpiresAt,
+		}, "%s", text)
 	}
 
-	spotifyClient := client.New(cfg.Spotify.ClientID, storage)
-	if Verbose() {
-		spotifyClient.SetVerbose(true, func(format string, args ...interface{}) {
-			fmt.Fprintf(os.Stderr, format+"\n", args...)
-		})
+	spotifyClient, err := newSpotifyClient(storage)
+	if err != nil {
+		return err
 	}
 	if err := spotifyClient.LoadToken(); err != nil {
 		return fmt.Errorf("failed to load token: %w", err)
@@ -231,34 +509,94 @@ func runAuthStatus(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 	user, err := spotifyClient.GetCurrentUser(ctx)
 	if err != nil {
-		if JSONOutput() {
-			_ = json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
-				"authenticated": true,
-				"expired":       true,
-				"error":         err.Error(),
-			})
-		} else {
+		return render(authStatusResult{Authenticated: true, Expired: true, Error: err.Error()}, func() error {
 			fmt.Printf("Token may be expired or invalid: %v\n", err)
 			fmt.Println("Run 'riff auth login' to re-authenticate.")
-		}
-		return nil
+			return nil
+		})
 	}
 
-	if JSONOutput() {
-		_ = json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
-			"authenticated": true,
-			"expired":       false,
-			"user_id":       user.ID,
-			"display_name":  user.DisplayName,
-			"email":         user.Email,
-			"product":       user.Product,
-			"expires_at":    token.ExpiresAt,
-		})
-	} else {
+	return render(authStatusResult{
+		Authenticated: true,
+		UserID:        user.ID,
+		DisplayName:   user.DisplayName,
+		Email:         user.Email,
+		Product:       user.Product,
+		ExpiresAt:     token.ExpiresAt,
+	}, func() error {
 		fmt.Printf("Authenticated as: %s (%s)\n", user.DisplayName, user.Email)
 		fmt.Printf("Account type: %s\n", user.Product)
 		fmt.Printf("Token expires: %s\n", token.ExpiresAt.Format(time.RFC3339))
+		return nil
+	})
+}
+
+func runAuthMigrate(cmd *cobra.Command, args []string) error {
+	configured := cfg.Spotify.TokenStore
+	if configured.Backend == "" {
+		configured.Backend = "file"
+	}
+
+	to := migrateTo
+	if to == "" {
+		to = configured.Backend
+	}
+	if migrateFrom == to {
+		return fmt.Errorf("source and destination are both %q", to)
+	}
+
+	// The configured path only applies to the configured backend; the other
+	// side of the migration uses its default location.
+	storeConfig := func(backend string) config.TokenStoreConfig {
+		sc := configured
+		if backend != configured.Backend {
+			sc.Backend = backend
+			sc.Path = ""
+		}
+		return sc
+	}
+
+	src, err := openTokenStore(storeConfig(migrateFrom))
+	if err != nil {
+		return fmt.Errorf("failed to open %s store: %w", migrateFrom, err)
+	}
+	dst, err := openTokenStore(storeConfig(to))
+	if err != nil {
+		return fmt.Errorf("failed to open %s store: %w", to, err)
+	}
+
+	token, err := src.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load token from %s store: %w", migrateFrom, err)
+	}
+	if token == nil {
+		return fmt.Errorf("no token found in %s store", migrateFrom)
 	}
 
-	return nil
+	if err := dst.Save(token); err != nil {
+		return fmt.Errorf("failed to save token to %s store: %w", to, err)
+	}
+
+	// Read it back before touching the source
+	saved, err := dst.Load()
+	if err != nil {
+		return fmt.Errorf("failed to verify %s store: %w", to, err)
+	}
+	if saved == nil || saved.RefreshToken != token.RefreshToken {
+		return fmt.Errorf("token read back from %s store does not match; source left untouched", to)
+	}
+
+	if !migrateKeep {
+		if err := src.Delete(); err != nil {
+			return fmt.Errorf("token migrated, but failed to remove it from %s store: %w", migrateFrom, err)
+		}
+	}
+
+	return render(authMigrateResult{Status: "migrated", From: migrateFrom, To: to, Kept: migrateKeep}, func() error {
+		fmt.Printf("Migrated Spotify token from %s store to %s store.\n", migrateFrom, to)
+		if to != configured.Backend {
+			fmt.Printf("Set spotify.token_store.backend = %q to use it.\n", to)
+		}
+		return nil
+	})
 }
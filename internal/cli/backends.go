@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/daemon"
+	"github.com/tessro/riff/internal/mpd"
+	"github.com/tessro/riff/internal/sonos"
+	"github.com/tessro/riff/internal/spotify/client"
+	"github.com/tessro/riff/internal/spotify/player"
+	"github.com/tessro/riff/internal/upnp"
+)
+
+// backends is the registry of playback backends for a single command run.
+type backends struct {
+	*core.Registry
+
+	// spotifyErr records why Spotify was left out, for error messages.
+	spotifyErr error
+
+	// remote is set when the backends are served by riffd.
+	remote *daemon.Client
+}
+
+// newBackends returns riffd's backends when it is running, and otherwise
+// builds them directly.
+func newBackends() *backends {
+	if b := newDaemonBackends(); b != nil {
+		return b
+	}
+	return newDirectBackends()
+}
+
+// newDirectBackends registers Spotify (when authenticated) ahead of Sonos,
+// then any configured MPD hosts and finally generic UPnP renderers.
+func newDirectBackends() *backends {
+	b := &backends{Registry: core.NewRegistry()}
+
+	spotifyClient, err := getSpotifyClient()
+	if err != nil {
+		b.spotifyErr = err
+		slog.Debug("spotify unavailable", "error", err)
+	} else {
+		b.Register(player.NewBackend(spotifyClient))
+	}
+
+	b.Register(sonos.NewBackend(sonos.NewClient(), cfg.Sonos.DefaultRoom))
+	b.Register(newMPDBackend())
+	b.Register(upnp.NewBackend(0))
+	return b
+}
+
+// newBackendsWithSpotify builds a registry around an existing Spotify client.
+func newBackendsWithSpotify(c *client.Client) *backends {
+	return &backends{Registry: core.NewRegistry(
+		player.NewBackend(c),
+		sonos.NewBackend(sonos.NewClient(), cfg.Sonos.DefaultRoom),
+		newMPDBackend(),
+		upnp.NewBackend(0),
+	)}
+}
+
+// newMPDBackend returns a backend for the configured MPD hosts, or nil when
+// there are none.
+func newMPDBackend() core.Backend {
+	if len(cfg.MPD.Hosts) == 0 {
+		return nil
+	}
+	timeout := time.Duration(cfg.MPD.Timeout) * time.Second
+	hosts := make([]mpd.Host, len(cfg.MPD.Hosts))
+	for i, h := range cfg.MPD.Hosts {
+		hosts[i] = mpd.Host{
+			Name:   h.Name,
+			Client: mpd.NewClient(h.Address, h.Password, timeout),
+		}
+	}
+	return mpd.NewBackend(hosts...)
+}
+
+// PlayerFor resolves target to a player, explaining a missing Spotify
+// login when nothing else could be found.
+func (b *backends) PlayerFor(ctx context.Context, target string) (core.Player, *core.Device, error) {
+	if b.remote != nil {
+		return b.remote.PlayerFor(ctx, target)
+	}
+	p, d, err := b.Registry.PlayerFor(ctx, target)
+	if err != nil && b.spotifyErr != nil {
+		return nil, nil, fmt.Errorf("%w (spotify: %v)", err, b.spotifyErr)
+	}
+	return p, d, err
+}
+
+// Spotify returns the Spotify backend, or an error explaining its absence.
+func (b *backends) Spotify() (*player.Backend, error) {
+	if sb, ok := b.Backend(core.PlatformSpotify).(*player.Backend); ok {
+		return sb, nil
+	}
+	if b.spotifyErr != nil {
+		return nil, b.spotifyErr
+	}
+	return nil, fmt.Errorf("spotify not configured")
+}
+
+// Sonos returns the Sonos backend.
+func (b *backends) Sonos() *sonos.Backend {
+	sb, _ := b.Backend(core.PlatformSonos).(*sonos.Backend)
+	return sb
+}
+
+// resolvePlayer returns the player for a --to target, or the default player
+// when target is empty.
+func resolvePlayer(ctx context.Context, target string) (core.Player, *core.Device, error) {
+	return newBackends().PlayerFor(ctx, target)
+}
+
+// platformOf returns the platform label for a player.
+func platformOf(p core.Player) core.Platform {
+	switch p := p.(type) {
+	case *sonos.Player:
+		return core.PlatformSonos
+	case *mpd.Player:
+		return core.PlatformMPD
+	case *upnp.Player:
+		return core.PlatformUPnP
+	case *daemon.Player:
+		return p.Platform()
+	default:
+		return core.PlatformSpotify
+	}
+}
+
+// addTargetFlags adds --to and its older alias --device/-d, both bound to target.
+func addTargetFlags(cmd *cobra.Command, target *string) {
+	cmd.Flags().StringVar(target, "to", "", "Target device name or ID (Spotify, Sonos, MPD or UPnP)")
+	cmd.Flags().StringVarP(target, "device", "d", "", "Alias for --to")
+	_ = cmd.RegisterFlagCompletionFunc("to", completeDevices)
+	_ = cmd.RegisterFlagCompletionFunc("device", completeDevices)
+}
+
+// getSpotifyClient returns an authenticated Spotify client.
+func getSpotifyClient() (*client.Client, error) {
+	if cfg.Spotify.ClientID == "" {
+		return nil, fmt.Errorf("spotify not configured")
+	}
+
+	storage, err := newTokenStore()
+	if err != nil {
+		return nil, err
+	}
+
+	spotifyClient, err := newSpotifyClient(storage)
+	if err != nil {
+		return nil, err
+	}
+	if err := spotifyClient.LoadToken(); err != nil {
+		return nil, fmt.Errorf("failed to load token: %w", err)
+	}
+
+	if !spotifyClient.HasToken() {
+		return nil, fmt.Errorf("not authenticated. Run 'riff auth login' first")
+	}
+
+	return spotifyClient, nil
+}
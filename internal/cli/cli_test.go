@@ -0,0 +1,961 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/core/coretest"
+	"github.com/tessro/riff/internal/daemon"
+	rifferrors "github.com/tessro/riff/internal/errors"
+	"github.com/tessro/riff/internal/mpd"
+	"github.com/tessro/riff/internal/mpd/mpdtest"
+	"github.com/tessro/riff/internal/spotify/auth"
+	"github.com/tessro/riff/internal/spotify/client"
+	"github.com/tessro/riff/internal/spotify/spotifytest"
+	"github.com/tessro/riff/internal/upnp"
+	"github.com/tessro/riff/internal/upnp/upnptest"
+)
+
+// setupRiff writes a config pointing riff at srv, with a valid token in a
+// temporary token store, and returns the config path.
+func setupRiff(t *testing.T, srv *spotifytest.Server) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	tokenPath := filepath.Join(dir, "token.json")
+	storage, err := auth.NewTokenStorage(tokenPath)
+	if err != nil {
+		t.Fatalf("NewTokenStorage() error = %v", err)
+	}
+	if err := storage.Save(srv.Token()); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Seed empty Sonos and renderer caches so commands never wait on SSDP
+	t.Setenv("XDG_CACHE_HOME", dir)
+	// Keep a riffd running on this machine, and its sleep timers, out of
+	// the way
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+	t.Setenv("XDG_STATE_HOME", dir)
+	if err := os.MkdirAll(filepath.Join(dir, "riff"), 0755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	now := time.Now().Format(time.RFC3339)
+	caches := map[string]string{
+		"sonos-devices.json":  fmt.Sprintf(`{"cached_at": %q, "devices": []}`, now),
+		"upnp-renderers.json": fmt.Sprintf(`{"cached_at": %q, "renderers": []}`, now),
+	}
+	for name, cache := range caches {
+		if err := os.WriteFile(filepath.Join(dir, "riff", name), []byte(cache), 0644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	configPath := filepath.Join(dir, "config.toml")
+	config := fmt.Sprintf(`[spotify]
+client_id = %q
+
+[spotify.token_store]
+backend = "file"
+path = %q
+
+[spotify.http]
+api_url = %q
+accounts_url = %q
+`, spotifytest.ClientID, tokenPath, srv.APIURL(), srv.AccountsURL())
+	if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	return configPath
+}
+
+// runRiff executes the root command with args and returns what it wrote to stdout.
+func runRiff(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe() error = %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		_, _ = io.Copy(&buf, r)
+		out <- buf.String()
+	}()
+
+	// Flags bind to package variables that outlive a single run
+	resetFlags(rootCmd)
+
+	rootCmd.SetArgs(args)
+	runErr := rootCmd.Execute()
+
+	_ = w.Close()
+	return <-out, runErr
+}
+
+// resetFlags restores every flag set by a previous run to its default.
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if f.Changed {
+			_ = f.Value.Set(f.DefValue)
+			f.Changed = false
+		}
+	}
+	cmd.PersistentFlags().VisitAll(reset)
+	cmd.Flags().VisitAll(reset)
+	for _, c := range cmd.Commands() {
+		resetFlags(c)
+	}
+}
+
+func TestAuthStatusEndToEnd(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+
+	configPath := setupRiff(t, srv)
+
+	out, err := runRiff(t, "--config", configPath, "--json", "auth", "status")
+	if err != nil {
+		t.Fatalf("riff auth status error = %v", err)
+	}
+
+	var status map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &status); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+	if status["user_id"] != "testuser" {
+		t.Errorf("user_id = %v, want %q", status["user_id"], "testuser")
+	}
+}
+
+func TestQueueEndToEnd(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+
+	configPath := setupRiff(t, srv)
+
+	// Start playback so there is an active device and a queue
+	if err := srv.Client().Play(t.Context(), "device-computer", nil); err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+
+	if _, err := runRiff(t, "--config", configPath, "queue", "add", "--uri", "spotify:track:morning-songs-3", "x"); err != nil {
+		t.Fatalf("riff queue add error = %v", err)
+	}
+
+	queue := srv.Queue()
+	if len(queue) != 1 || queue[0].Name != "Commute" {
+		t.Errorf("server queue = %+v, want [Commute]", queue)
+	}
+
+	out, err := runRiff(t, "--config", configPath, "--json", "queue")
+	if err != nil {
+		t.Fatalf("riff queue error = %v", err)
+	}
+
+	var result struct {
+		Queue []struct {
+			Title string `json:"title"`
+		} `json:"queue"`
+	}
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+	if len(result.Queue) < 2 || result.Queue[1].Title != "Commute" {
+		t.Errorf("queue = %+v, want Commute after the current track", result.Queue)
+	}
+}
+
+func TestSeekEndToEnd(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+
+	configPath := setupRiff(t, srv)
+
+	if err := srv.Client().Play(t.Context(), "device-computer", nil); err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+
+	if _, err := runRiff(t, "--config", configPath, "seek", "--to", "Computer", "1:05"); err != nil {
+		t.Fatalf("riff seek error = %v", err)
+	}
+	if got := srv.State().ProgressMS; got != 65000 {
+		t.Errorf("progress = %dms, want 65000", got)
+	}
+
+	if _, err := runRiff(t, "--config", configPath, "seek", "--", "-5"); err != nil {
+		t.Fatalf("riff seek -- -5 error = %v", err)
+	}
+	if got := srv.State().ProgressMS; got != 60000 {
+		t.Errorf("progress = %dms, want 60000", got)
+	}
+}
+
+func TestQueueRemoveUnsupportedOnSpotify(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+
+	configPath := setupRiff(t, srv)
+
+	if err := srv.Client().Play(t.Context(), "device-computer", nil); err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+
+	_, err := runRiff(t, "--config", configPath, "queue", "remove", "1")
+	if !errors.Is(err, rifferrors.ErrUnsupported) {
+		t.Fatalf("riff queue remove error = %v, want ErrUnsupported", err)
+	}
+	if rifferrors.GetSuggestion(err) == "" {
+		t.Error("expected a suggestion for the unsupported operation")
+	}
+}
+
+func TestMPDEndToEnd(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+	mpdSrv := mpdtest.NewServer()
+	defer mpdSrv.Close()
+
+	configPath := setupRiff(t, srv)
+	f, err := os.OpenFile(configPath, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	fmt.Fprintf(f, "\n[[mpd.hosts]]\nname = \"Office\"\naddress = %q\n", mpdSrv.Addr())
+	_ = f.Close()
+
+	out, err := runRiff(t, "--config", configPath, "--json", "devices")
+	if err != nil {
+		t.Fatalf("riff devices error = %v", err)
+	}
+	var devices []struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		Platform string `json:"platform"`
+	}
+	if err := json.Unmarshal([]byte(out), &devices); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+	var mpdDevices []string
+	for _, d := range devices {
+		if d.Platform == "mpd" {
+			mpdDevices = append(mpdDevices, d.Name)
+		}
+	}
+	if len(mpdDevices) != 2 || mpdDevices[0] != "Office (Speakers)" {
+		t.Errorf("mpd devices = %v, want Office outputs", mpdDevices)
+	}
+
+	for _, song := range mpdtest.Library[:2] {
+		if _, err := runRiff(t, "--config", configPath, "queue", "add", "--to", "Office", "--uri", song.File); err != nil {
+			t.Fatalf("riff queue add error = %v", err)
+		}
+	}
+	if _, err := runRiff(t, "--config", configPath, "queue", "move", "--to", "Office", "2", "1"); err != nil {
+		t.Fatalf("riff queue move error = %v", err)
+	}
+
+	queue := mpdSrv.Queue()
+	if len(queue) != 2 || queue[0] != mpdtest.Library[1].File {
+		t.Errorf("mpd queue = %v, want %s first", queue, mpdtest.Library[1].File)
+	}
+
+	const stream = "http://radio.example/live.mp3"
+	if _, err := runRiff(t, "--config", configPath, "play", "--stream", stream, "--to", "Office"); err != nil {
+		t.Fatalf("riff play --stream error = %v", err)
+	}
+	st := mpdSrv.Status()
+	if queue := mpdSrv.Queue(); st.State != "play" || st.Current != 2 || queue[2] != stream {
+		t.Errorf("after play --stream: status = %+v, queue = %v", st, queue)
+	}
+}
+
+func TestDaemonEndToEnd(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+	mpdSrv := mpdtest.NewServer()
+	defer mpdSrv.Close()
+
+	configPath := setupRiff(t, srv)
+
+	// The daemon knows an MPD host the CLI's config doesn't, so seeing it
+	// proves a command went through the daemon
+	registry := core.NewRegistry(mpd.NewBackend(mpd.Host{
+		Name:   "Office",
+		Client: mpd.NewClient(mpdSrv.Addr(), "", 5*time.Second),
+	}))
+	ln, err := daemon.Listen(daemon.SocketPath())
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() { served <- daemon.NewServer(registry, "test").Serve(ctx, ln) }()
+
+	mpdDevices := func(args ...string) []string {
+		t.Helper()
+		out, err := runRiff(t, append([]string{"--config", configPath, "--json", "devices"}, args...)...)
+		if err != nil {
+			t.Fatalf("riff devices error = %v", err)
+		}
+		var devices []struct {
+			Name     string `json:"name"`
+			Platform string `json:"platform"`
+		}
+		if err := json.Unmarshal([]byte(out), &devices); err != nil {
+			t.Fatalf("invalid JSON output %q: %v", out, err)
+		}
+		var names []string
+		for _, d := range devices {
+			if d.Platform == "mpd" {
+				names = append(names, d.Name)
+			}
+		}
+		return names
+	}
+
+	if names := mpdDevices(); len(names) != 2 {
+		t.Errorf("mpd devices via riffd = %v, want Office outputs", names)
+	}
+	if names := mpdDevices("--no-daemon"); len(names) != 0 {
+		t.Errorf("mpd devices with --no-daemon = %v, want none", names)
+	}
+
+	song := mpdtest.Library[0].File
+	if _, err := runRiff(t, "--config", configPath, "queue", "add", "--to", "Office", "--uri", song); err != nil {
+		t.Fatalf("riff queue add error = %v", err)
+	}
+	if queue := mpdSrv.Queue(); len(queue) != 1 || queue[0] != song {
+		t.Errorf("mpd queue = %v, want %s", queue, song)
+	}
+
+	// Capability checks still apply to remote players
+	_, err = runRiff(t, "--config", configPath, "volume", "--group", "--to", "Office", "50")
+	if !errors.Is(err, rifferrors.ErrUnsupported) {
+		t.Errorf("riff volume --group error = %v, want ErrUnsupported", err)
+	}
+
+	out, err := runRiff(t, "--config", configPath, "--json", "daemon", "status")
+	if err != nil {
+		t.Fatalf("riff daemon status error = %v", err)
+	}
+	var status map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &status); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+	if status["running"] != true || status["version"] != "test" {
+		t.Errorf("daemon status = %v", status)
+	}
+
+	if _, err := runRiff(t, "--config", configPath, "daemon", "stop"); err != nil {
+		t.Fatalf("riff daemon stop error = %v", err)
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("daemon did not stop")
+	}
+}
+
+// seedUPnPCache replaces the empty discovery cache named file with items
+// built from the description at each location.
+func seedUPnPCache[T any](t *testing.T, file, key string, build func(*upnp.Description, string) T, locations ...string) {
+	t.Helper()
+	items := make([]T, len(locations))
+	for i, loc := range locations {
+		desc, err := upnp.FetchDescription(context.Background(), http.DefaultClient, loc)
+		if err != nil {
+			t.Fatalf("FetchDescription() error = %v", err)
+		}
+		items[i] = build(desc, loc)
+	}
+	data, err := json.Marshal(map[string]any{"cached_at": time.Now(), key: items})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(upnp.CacheDir(), file), data, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+}
+
+func TestMediaEndToEnd(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+	nas := upnptest.NewMediaServer("NAS")
+	defer nas.Close()
+	tv := upnptest.NewRenderer("Living Room TV")
+	defer tv.Close()
+	mpdSrv := mpdtest.NewServer()
+	defer mpdSrv.Close()
+
+	configPath := setupRiff(t, srv)
+	f, err := os.OpenFile(configPath, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	fmt.Fprintf(f, "\n[[mpd.hosts]]\nname = \"Office\"\naddress = %q\n", mpdSrv.Addr())
+	_ = f.Close()
+	seedUPnPCache(t, "upnp-servers.json", "servers", upnp.NewMediaServer, nas.Location())
+	seedUPnPCache(t, "upnp-renderers.json", "renderers", upnp.NewRenderer, tv.Location())
+
+	out, err := runRiff(t, "--config", configPath, "--json", "media", "browse", upnptest.AlbumID)
+	if err != nil {
+		t.Fatalf("riff media browse error = %v", err)
+	}
+	var browse struct {
+		Server string `json:"server"`
+		Total  int    `json:"total"`
+		Items  []struct {
+			ID    string `json:"id"`
+			Title string `json:"title"`
+			URL   string `json:"url"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(out), &browse); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+	if browse.Server != "NAS" || browse.Total != 3 || browse.Items[0].Title != "Neon" {
+		t.Errorf("media browse = %+v, want the Night Drive tracks on NAS", browse)
+	}
+
+	out, err = runRiff(t, "--config", configPath, "--json", "media", "search", "overpass")
+	if err != nil {
+		t.Fatalf("riff media search error = %v", err)
+	}
+	if err := json.Unmarshal([]byte(out), &browse); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+	if len(browse.Items) != 1 || browse.Items[0].ID != upnptest.Tracks[1].ID {
+		t.Fatalf("media search = %+v, want Overpass", browse.Items)
+	}
+
+	if _, err := runRiff(t, "--config", configPath, "media", "play", browse.Items[0].ID, "--to", "Living Room TV"); err != nil {
+		t.Fatalf("riff media play error = %v", err)
+	}
+	st := tv.State()
+	if st.Transport != "PLAYING" || st.URI != nas.URL(upnptest.Tracks[1]) || !strings.Contains(st.Metadata, "<dc:title>Overpass</dc:title>") {
+		t.Errorf("renderer state = %+v, want Overpass playing with metadata", st)
+	}
+
+	if _, err := runRiff(t, "--config", configPath, "media", "play", upnptest.AlbumID, "--to", "Office"); err == nil || rifferrors.GetSuggestion(err) == "" {
+		t.Errorf("media play <folder> error = %v, want a suggestion to use --queue", err)
+	}
+	if _, err := runRiff(t, "--config", configPath, "media", "play", upnptest.AlbumID, "--to", "Office", "--queue"); err != nil {
+		t.Fatalf("riff media play --queue error = %v", err)
+	}
+	if queue := mpdSrv.Queue(); len(queue) != 3 || queue[0] != nas.URL(upnptest.Tracks[0]) {
+		t.Errorf("mpd queue = %v, want the album's resource URLs", queue)
+	}
+}
+
+func TestRulesCheckEndToEnd(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+
+	configPath := setupRiff(t, srv)
+	f, err := os.OpenFile(configPath, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	fmt.Fprint(f, `
+[[rules]]
+name = "split patio"
+events = ["pause"]
+device = "Kitchen"
+for = "10m"
+actions = [{ do = "ungroup", target = "Patio" }]
+`)
+	_ = f.Close()
+
+	// Record events the way riff tail --json does
+	kitchen := &core.Device{ID: "RINCON_1", Name: "Kitchen", Platform: core.PlatformSonos}
+	start := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	var events bytes.Buffer
+	enc := json.NewEncoder(&events)
+	for i, typ := range []string{"pause", "resume", "pause", "resume"} {
+		var e struct {
+			Type      string              `json:"type"`
+			Timestamp time.Time           `json:"timestamp"`
+			Current   *core.PlaybackState `json:"current"`
+		}
+		e.Type = typ
+		e.Timestamp = start.Add(time.Duration(i) * 15 * time.Minute)
+		if i == 1 {
+			e.Timestamp = start.Add(5 * time.Minute)
+		}
+		e.Current = &core.PlaybackState{Device: kitchen, IsPlaying: typ == "resume"}
+		_ = enc.Encode(e)
+	}
+	eventsPath := filepath.Join(t.TempDir(), "events.jsonl")
+	if err := os.WriteFile(eventsPath, events.Bytes(), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	out, err := runRiff(t, "--config", configPath, "--json", "rules", "check", eventsPath)
+	if err != nil {
+		t.Fatalf("riff rules check error = %v", err)
+	}
+	var result struct {
+		Events  int `json:"events"`
+		Firings []struct {
+			Rule    string    `json:"rule"`
+			At      time.Time `json:"at"`
+			Actions []struct {
+				Do     string `json:"do"`
+				Target string `json:"target"`
+			} `json:"actions"`
+		} `json:"firings"`
+	}
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+	// The first pause is cut short; the second lasts 15 minutes
+	if result.Events != 4 || len(result.Firings) != 1 {
+		t.Fatalf("result = %+v, want one firing from 4 events", result)
+	}
+	fired := result.Firings[0]
+	if !fired.At.Equal(start.Add(40*time.Minute)) || fired.Actions[0].Do != "ungroup" || fired.Actions[0].Target != "Patio" {
+		t.Errorf("firing = %+v, want ungroup Patio at 12:40", fired)
+	}
+}
+
+func TestFadeOutEndToEnd(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+
+	configPath := setupRiff(t, srv)
+
+	if err := srv.Client().Play(t.Context(), "device-computer", nil); err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+
+	if _, err := runRiff(t, "--config", configPath, "fade-out", "2s", "--then", "pause", "--to", "Computer"); err != nil {
+		t.Fatalf("riff fade-out error = %v", err)
+	}
+
+	// Spotify steps at most once a second: 50 -> 25 -> 0, then back to 50
+	var volumes []string
+	for _, r := range srv.Requests() {
+		if r.Path == "/me/player/volume" {
+			volumes = append(volumes, r.Query["volume_percent"])
+		}
+	}
+	if got := strings.Join(volumes, " "); got != "25 0 50" {
+		t.Errorf("volume requests = %q, want \"25 0 50\"", got)
+	}
+	if state := srv.State(); state.IsPlaying || *state.Device.VolumePercent != 50 {
+		t.Errorf("after fade-out: playing = %v, volume = %d; want paused at 50", state.IsPlaying, *state.Device.VolumePercent)
+	}
+
+	// Fades claim the volume in the state dir, not the discovery cache
+	if info, err := os.Stat(filepath.Join(os.Getenv("XDG_STATE_HOME"), "riff", "fades")); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("fade claim dir: %v, %v; want a 0700 directory", info, err)
+	}
+
+	if _, err := runRiff(t, "--config", configPath, "fade-out", "2s", "--then", "stop"); err == nil {
+		t.Error("riff fade-out accepted --then stop")
+	}
+}
+
+// groupPlayer is a Sonos-like player that may coordinate a group.
+type groupPlayer struct {
+	*coretest.Player
+	grouped     bool
+	groupVolume int
+	lookups     int
+}
+
+func (g *groupPlayer) GroupVolume(ctx context.Context) (int, error) { return g.groupVolume, nil }
+
+func (g *groupPlayer) SetGroupVolume(ctx context.Context, percent int) error {
+	g.groupVolume = percent
+	return g.Record(fmt.Sprintf("group volume %d", percent))
+}
+
+func (g *groupPlayer) CoordinatesGroup(ctx context.Context) (bool, error) {
+	g.lookups++
+	return g.grouped, nil
+}
+
+func TestVolumeScope(t *testing.T) {
+	sonos := &core.Device{ID: "RINCON_1", Name: "Kitchen", Platform: core.PlatformSonos}
+	mpd := &core.Device{ID: "mpd:Office", Name: "Office", Platform: core.PlatformMPD}
+	tests := []struct {
+		name        string
+		device      *core.Device
+		grouped     bool
+		scope       scope
+		want        int
+		wantLookups int
+	}{
+		{"coordinator", sonos, true, scopeAuto, 40, 1},
+		{"coordinator with --room", sonos, true, scopeRoom, 25, 0},
+		{"alone", sonos, false, scopeAuto, 25, 1},
+		{"alone with --group", sonos, false, scopeGroup, 40, 0},
+		{"not sonos", mpd, true, scopeAuto, 25, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &groupPlayer{Player: coretest.NewPlayer(tt.device, core.CapGroupVolume), grouped: tt.grouped, groupVolume: 40}
+			p.Update(func(s *core.PlaybackState) { s.Volume = 25 })
+			state := p.State()
+
+			_, got, err := currentVolumeOf(t.Context(), p, &state, tt.scope)
+			if err != nil {
+				t.Fatalf("currentVolumeOf() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("currentVolumeOf() volume = %d, want %d", got, tt.want)
+			}
+			if p.lookups != tt.wantLookups {
+				t.Errorf("CoordinatesGroup called %d times, want %d", p.lookups, tt.wantLookups)
+			}
+		})
+	}
+}
+
+func TestVolumeKey(t *testing.T) {
+	device := &core.Device{ID: "RINCON_1", Name: "Kitchen", Platform: core.PlatformSonos}
+	p := coretest.NewPlayer(nil, 0)
+	if got := volumeKey(p, nil, &core.PlaybackState{Device: device}); got != "RINCON_1" {
+		t.Errorf("volumeKey() = %q, want the active device's ID", got)
+	}
+	if got := volumeKey(p, nil, &core.PlaybackState{}); got != string(core.PlatformSpotify) {
+		t.Errorf("volumeKey() = %q without a device, want the platform", got)
+	}
+}
+
+func TestSleepEndToEnd(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+
+	configPath := setupRiff(t, srv)
+
+	if err := srv.Client().Play(t.Context(), "device-computer", nil); err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+
+	if _, err := runRiff(t, "--config", configPath, "sleep", "tonight"); err == nil {
+		t.Error("riff sleep accepted an invalid timer")
+	}
+
+	out, err := runRiff(t, "--config", configPath, "sleep", "1s", "--wait", "--to", "Computer")
+	if err != nil {
+		t.Fatalf("riff sleep error = %v", err)
+	}
+	if !strings.Contains(out, "Pausing Test Computer in 0:01") {
+		t.Errorf("riff sleep output = %q", out)
+	}
+	if srv.State().IsPlaying {
+		t.Error("still playing after the sleep timer went off")
+	}
+
+	out, err = runRiff(t, "--config", configPath, "sleep")
+	if err != nil {
+		t.Fatalf("riff sleep error = %v", err)
+	}
+	if !strings.Contains(out, "No sleep timers") {
+		t.Errorf("timer still listed after firing: %q", out)
+	}
+}
+
+func TestSceneEndToEnd(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+
+	configPath := setupRiff(t, srv)
+	f, err := os.OpenFile(configPath, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`
+[scenes.focus]
+source = "playlist:Test Mix"
+shuffle = true
+
+[[scenes.focus.rooms]]
+name = "Test Computer"
+volume = 30
+`)
+	_ = f.Close()
+
+	out, err := runRiff(t, "--config", configPath, "scene", "apply", "focus")
+	if err != nil {
+		t.Fatalf("riff scene apply error = %v", err)
+	}
+	if !strings.Contains(out, "Applied focus on Test Computer") {
+		t.Errorf("riff scene apply output = %q", out)
+	}
+	state := srv.State()
+	if !state.IsPlaying || state.Device.ID != "device-computer" ||
+		state.Device.VolumePercent == nil || *state.Device.VolumePercent != 30 || !state.ShuffleState {
+		t.Errorf("state after apply = %+v", state)
+	}
+	if state.Context == nil || state.Context.URI != "spotify:playlist:playlist-mix" {
+		t.Errorf("playing %+v, want the Test Mix playlist", state.Context)
+	}
+
+	if _, err := runRiff(t, "--config", configPath, "scene", "apply", "nope"); err == nil {
+		t.Error("riff scene apply accepted an unknown scene")
+	}
+
+	// Capture keeps the source and reads back the current settings
+	if err := srv.Client().SetVolume(t.Context(), 45, "device-computer"); err != nil {
+		t.Fatalf("SetVolume() error = %v", err)
+	}
+	if _, err := runRiff(t, "--config", configPath, "scene", "capture", "focus"); err != nil {
+		t.Fatalf("riff scene capture error = %v", err)
+	}
+	out, err = runRiff(t, "--config", configPath, "--json", "scene", "list")
+	if err != nil {
+		t.Fatalf("riff scene list error = %v", err)
+	}
+	var listed struct {
+		Scenes map[string]struct {
+			Source  string `json:"source"`
+			Shuffle bool   `json:"shuffle"`
+			Rooms   []struct {
+				Name   string `json:"name"`
+				Volume int    `json:"volume"`
+			} `json:"rooms"`
+		} `json:"scenes"`
+	}
+	if err := json.Unmarshal([]byte(out), &listed); err != nil {
+		t.Fatalf("riff scene list output = %q: %v", out, err)
+	}
+	focus := listed.Scenes["focus"]
+	if focus.Source != "playlist:Test Mix" || !focus.Shuffle || len(focus.Rooms) != 1 || focus.Rooms[0].Volume != 45 {
+		t.Errorf("captured scene = %+v", focus)
+	}
+}
+
+func TestHandoffEndToEnd(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+	configPath := setupRiff(t, srv)
+
+	c := srv.Client()
+	err := c.Play(t.Context(), "device-computer", &client.PlayOptions{
+		ContextURI: "spotify:playlist:playlist-mix",
+		Offset:     &client.PlayOffset{Position: 1},
+	})
+	if err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+	if err := c.SetVolume(t.Context(), 65, "device-computer"); err != nil {
+		t.Fatalf("SetVolume() error = %v", err)
+	}
+	srv.Advance(30 * time.Second)
+	before := srv.State()
+
+	out, err := runRiff(t, "--config", configPath, "handoff", "--to", "Test Speaker")
+	if err != nil {
+		t.Fatalf("riff handoff error = %v", err)
+	}
+	if !strings.Contains(out, "Moved playback from Test Computer to Test Speaker") {
+		t.Errorf("riff handoff output = %q", out)
+	}
+
+	// The playlist carries on from the same track and position
+	state := srv.State()
+	if !state.IsPlaying || state.Device.ID != "device-speaker" {
+		t.Fatalf("state after handoff = %+v", state)
+	}
+	if state.Context == nil || state.Context.URI != "spotify:playlist:playlist-mix" {
+		t.Errorf("playing %+v, want the Test Mix playlist", state.Context)
+	}
+	if state.Item.URI != before.Item.URI || state.ProgressMS < 30000 {
+		t.Errorf("playing %s at %dms, want %s at 30000ms", state.Item.URI, state.ProgressMS, before.Item.URI)
+	}
+	if state.Device.VolumePercent == nil || *state.Device.VolumePercent != 65 {
+		t.Errorf("speaker volume = %v, want 65", state.Device.VolumePercent)
+	}
+
+	if _, err := runRiff(t, "--config", configPath, "handoff", "--to", "Test Speaker"); err == nil {
+		t.Error("riff handoff to the device already playing succeeded")
+	}
+}
+
+func TestLogFile(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+
+	configPath := setupRiff(t, srv)
+	logPath := filepath.Join(t.TempDir(), "riff.log")
+	f, err := os.OpenFile(configPath, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = fmt.Fprintf(f, "\n[log]\nlevel = \"debug\"\nformat = \"json\"\nfile = %q\n", logPath)
+	_ = f.Close()
+
+	for range 2 {
+		if _, err := runRiff(t, "--config", configPath, "--no-daemon", "devices"); err != nil {
+			t.Fatalf("riff devices error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), srv.Token().AccessToken) {
+		t.Error("log file contains the access token")
+	}
+	ids := map[string]bool{}
+	sawRequest := false
+	for line := range strings.Lines(string(data)) {
+		var record struct {
+			Msg       string `json:"msg"`
+			RequestID string `json:"request_id"`
+			Command   string `json:"command"`
+		}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		if record.RequestID == "" || record.Command != "riff devices" {
+			t.Errorf("log line %q lacks the request ID or command", line)
+		}
+		ids[record.RequestID] = true
+		sawRequest = sawRequest || record.Msg == "spotify request"
+	}
+	if len(ids) != 2 || !sawRequest {
+		t.Errorf("log has %d request IDs, spotify requests %v; want 2 and true", len(ids), sawRequest)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+
+	configPath := setupRiff(t, srv)
+	f, err := os.OpenFile(configPath, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("\n[[mpd.hosts]]\nname = \"Office\"\naddress = \"localhost:6600\"\n")
+	_ = f.Close()
+
+	// A cached room whose speaker is switched off: completions must not
+	// wait on it
+	cache := fmt.Sprintf(`{"cached_at": %q, "devices": [{"ip": "127.0.0.1", "port": 1, "uuid": "RINCON_1", "name": "Kitchen"}]}`,
+		time.Now().Add(-time.Hour).Format(time.RFC3339))
+	if err := os.WriteFile(filepath.Join(os.Getenv("XDG_CACHE_HOME"), "riff", "sonos-devices.json"), []byte(cache), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	complete := func(args ...string) []string {
+		t.Helper()
+		out, err := runRiff(t, append([]string{"__complete", "--config", configPath}, args...)...)
+		if err != nil {
+			t.Fatalf("riff __complete %v error = %v", args, err)
+		}
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		if last := lines[len(lines)-1]; last != ":4" {
+			t.Errorf("riff __complete %v directive = %q, want :4 (no files)", args, last)
+		}
+		return lines[:len(lines)-1]
+	}
+
+	got := complete("pause", "--to", "")
+	want := []string{"Test Computer\tSpotify Computer", "Test Speaker\tSpotify Speaker", "Kitchen\tSonos room", "Office\tMPD host"}
+	if !slices.Equal(got, want) {
+		t.Errorf("devices = %q, want %q", got, want)
+	}
+	if got := complete("handoff", "--to", "test s"); !slices.Equal(got, want[1:2]) {
+		t.Errorf("devices matching 'test s' = %q", got)
+	}
+
+	// Spotify devices come from the cache until it expires
+	srv.SetDevices(srv.Devices()[0])
+	if got := complete("pause", "--to", "Test"); len(got) != 2 {
+		t.Errorf("devices after one went away = %q, want the cached two", got)
+	}
+
+	if got := complete("group", "add", "--to", ""); !slices.Equal(got, []string{"Kitchen\tSonos room"}) {
+		t.Errorf("groups = %q, want the cached rooms", got)
+	}
+	if got := complete("play", "--playlist", ""); !slices.Equal(got, []string{"Test Mix\tPlaylist"}) {
+		t.Errorf("playlists = %q", got)
+	}
+}
+
+func TestOutputFormats(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+
+	configPath := setupRiff(t, srv)
+	devices := func(format string) string {
+		t.Helper()
+		out, err := runRiff(t, "--config", configPath, "--output", format, "devices")
+		if err != nil {
+			t.Fatalf("riff devices --output %s error = %v", format, err)
+		}
+		return out
+	}
+
+	csv := devices("csv")
+	lines := strings.Split(strings.TrimSpace(csv), "\n")
+	if len(lines) != 3 || lines[0] != "id,name,type,platform,is_active,volume" {
+		t.Fatalf("csv = %q, want a header and two devices", csv)
+	}
+	if !strings.Contains(lines[1], ",Test Computer,") {
+		t.Errorf("csv row = %q, want Test Computer", lines[1])
+	}
+
+	if got := devices("template={{.name}} ({{upper .platform}})"); got != "Test Computer (SPOTIFY)\nTest Speaker (SPOTIFY)\n" {
+		t.Errorf("template = %q", got)
+	}
+
+	// ndjson is one JSON object per device
+	for line := range strings.Lines(devices("ndjson")) {
+		var d deviceInfo
+		if err := json.Unmarshal([]byte(line), &d); err != nil || d.Name == "" {
+			t.Errorf("ndjson line %q: %v", line, err)
+		}
+	}
+
+	if got := devices("yaml"); !strings.HasPrefix(got, "- id: ") || !strings.Contains(got, "  name: Test Speaker\n") {
+		t.Errorf("yaml = %q", got)
+	}
+
+	// The schema lists the same names as the csv header
+	out, err := runRiff(t, "--output", "template={{.name}}", "schema", "devices")
+	if err != nil {
+		t.Fatalf("riff schema devices error = %v", err)
+	}
+	if got := strings.Join(strings.Fields(out), ","); got != lines[0] {
+		t.Errorf("schema fields = %q, want %q", got, lines[0])
+	}
+
+	if _, err := runRiff(t, "--json", "--output", "csv", "version"); err == nil {
+		t.Error("--json --output csv: want an error")
+	}
+	if _, err := runRiff(t, "--output", "xml", "version"); err == nil {
+		t.Error("--output xml: want an error")
+	}
+}
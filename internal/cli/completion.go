@@ -0,0 +1,226 @@
+package cli
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tessro/riff/internal/sonos"
+	"github.com/tessro/riff/internal/upnp"
+)
+
+// completionTimeout bounds how long a completion waits on Spotify or a
+// Sonos speaker. Shells run riff on every tab press, so completions never
+// search the network for devices; Sonos rooms and UPnP renderers come from
+// the discovery caches.
+const completionTimeout = 1500 * time.Millisecond
+
+// How long names fetched from Spotify are reused before asking again.
+const (
+	spotifyDevicesTTL = time.Minute
+	playlistsTTL      = time.Hour
+)
+
+// completionCache is the on-disk format of a completion cache file.
+type completionCache struct {
+	CachedAt time.Time `json:"cached_at"`
+	Items    []string  `json:"items"`
+}
+
+// completions collects candidates matching what has been typed so far,
+// each with a description for shells that show them.
+type completions struct {
+	prefix string
+	seen   map[string]bool
+	items  []string
+}
+
+func newCompletions(toComplete string) *completions {
+	return &completions{prefix: strings.ToLower(toComplete), seen: make(map[string]bool)}
+}
+
+// add adds item ("name\tdescription") unless it doesn't match or a
+// candidate with the same name was already added.
+func (c *completions) add(item string) {
+	name, _, _ := strings.Cut(item, "\t")
+	key := strings.ToLower(name)
+	if name == "" || c.seen[key] || !strings.HasPrefix(key, c.prefix) {
+		return
+	}
+	c.seen[key] = true
+	c.items = append(c.items, item)
+}
+
+func (c *completions) result() ([]string, cobra.ShellCompDirective) {
+	return c.items, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completionConfig loads the config for a completion, which runs without
+// the root command's PersistentPreRunE. It reports whether one loaded.
+func completionConfig() bool {
+	return initConfig() == nil && cfg != nil
+}
+
+// completeDevices completes --to: Spotify Connect devices, Sonos rooms,
+// UPnP renderers and the MPD hosts named in the config.
+func completeDevices(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	c := newCompletions(toComplete)
+	if !completionConfig() {
+		return c.result()
+	}
+	for _, item := range spotifyDeviceCompletions(cmd.Context()) {
+		c.add(item)
+	}
+	for _, s := range sonos.CachedSpeakers() {
+		c.add(s.Name + "\tSonos room")
+	}
+	for _, r := range upnp.CachedRenderers() {
+		c.add(r.Name + "\tUPnP renderer")
+	}
+	for _, h := range cfg.MPD.Hosts {
+		c.add(cmp.Or(h.Name, h.Address) + "\tMPD host")
+	}
+	return c.result()
+}
+
+// completeRooms completes the first argument with Sonos room names.
+func completeRooms(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	c := newCompletions(toComplete)
+	if len(args) > 0 {
+		return c.result()
+	}
+	for _, s := range sonos.CachedSpeakers() {
+		c.add(s.Name + "\tSonos room")
+	}
+	return c.result()
+}
+
+// completeCoordinators completes Sonos groups by their coordinators, asking
+// a cached speaker for the current topology. If none answers in time, it
+// offers every room.
+func completeCoordinators(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	speakers := sonos.CachedSpeakers()
+	if len(speakers) == 0 {
+		return newCompletions(toComplete).result()
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), completionTimeout)
+	defer cancel()
+	groups, err := sonos.NewClient().ListGroups(ctx, speakers[0])
+	if err != nil {
+		return completeRooms(cmd, nil, toComplete)
+	}
+	c := newCompletions(toComplete)
+	for _, g := range groups {
+		if g.Coordinator == nil {
+			continue
+		}
+		desc := "Sonos group"
+		if len(g.Members) > 1 {
+			names := make([]string, 0, len(g.Members))
+			for _, m := range g.Members {
+				names = append(names, m.Name)
+			}
+			desc += " (" + strings.Join(names, ", ") + ")"
+		}
+		c.add(g.Coordinator.Name + "\t" + desc)
+	}
+	return c.result()
+}
+
+// completeScenes completes the first argument with configured scene names.
+func completeScenes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	c := newCompletions(toComplete)
+	if len(args) > 0 || !completionConfig() {
+		return c.result()
+	}
+	names := make([]string, 0, len(cfg.Scenes))
+	for name := range cfg.Scenes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		c.add(name + "\tScene")
+	}
+	return c.result()
+}
+
+// completePlaylists completes the user's saved Spotify playlists.
+func completePlaylists(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	c := newCompletions(toComplete)
+	if !completionConfig() {
+		return c.result()
+	}
+	items := cachedCompletions(cmd.Context(), "playlists", playlistsTTL, func(ctx context.Context) ([]string, error) {
+		sc, err := getSpotifyClient()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := sc.GetMyPlaylists(ctx, 50, 0)
+		if err != nil {
+			return nil, err
+		}
+		items := make([]string, len(resp.Items))
+		for i, p := range resp.Items {
+			items[i] = p.Name + "\tPlaylist"
+			if owner := cmp.Or(p.Owner.DisplayName, p.Owner.ID); owner != "" {
+				items[i] += " by " + owner
+			}
+		}
+		return items, nil
+	})
+	for _, item := range items {
+		c.add(item)
+	}
+	return c.result()
+}
+
+// spotifyDeviceCompletions returns the user's Spotify Connect devices.
+func spotifyDeviceCompletions(ctx context.Context) []string {
+	return cachedCompletions(ctx, "spotify-devices", spotifyDevicesTTL, func(ctx context.Context) ([]string, error) {
+		sc, err := getSpotifyClient()
+		if err != nil {
+			return nil, err
+		}
+		devices, err := sc.GetDevices(ctx)
+		if err != nil {
+			return nil, err
+		}
+		items := make([]string, len(devices))
+		for i, d := range devices {
+			items[i] = d.Name + "\tSpotify " + d.Type
+		}
+		return items, nil
+	})
+}
+
+// cachedCompletions returns the candidates saved under name when they are
+// younger than ttl. Otherwise it fetches them within completionTimeout and
+// saves them, falling back to the stale ones if that fails.
+func cachedCompletions(ctx context.Context, name string, ttl time.Duration, fetch func(context.Context) ([]string, error)) []string {
+	path := filepath.Join(upnp.CacheDir(), "completion-"+name+".json")
+	var cache completionCache
+	if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &cache) == nil &&
+		time.Since(cache.CachedAt) < ttl {
+		return cache.Items
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, completionTimeout)
+	defer cancel()
+	items, err := fetch(ctx)
+	if err != nil {
+		return cache.Items
+	}
+	if data, err := json.Marshal(completionCache{CachedAt: time.Now(), Items: items}); err == nil {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			_ = os.WriteFile(path, data, 0644)
+		}
+	}
+	return items
+}
@@ -2,7 +2,6 @@ package cli
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
@@ -13,8 +12,6 @@ import (
 	"github.com/charmbracelet/huh"
 	"github.com/spf13/cobra"
 	"github.com/tessro/riff/internal/config"
-	"github.com/tessro/riff/internal/spotify/auth"
-	"github.com/tessro/riff/internal/spotify/client"
 )
 
 var configCmd = &cobra.Command{
@@ -77,17 +74,33 @@ func init() {
 	configCmd.AddCommand(configSetCmd)
 	configCmd.AddCommand(configSetDeviceCmd)
 	rootCmd.AddCommand(configCmd)
+
+	registerOutput(configShowCmd, config.Config{})
+	registerOutput(configInitCmd, configInitResult{})
+	registerOutput(configSetCmd, configSetResult{})
+	registerOutput(configSetDeviceCmd, configSetResult{})
 }
 
-func runConfigShow(cmd *cobra.Command, args []string) error {
-	if JSONOutput() {
-		return json.NewEncoder(os.Stdout).Encode(cfg)
-	}
+// configInitResult is the output of riff config init.
+type configInitResult struct {
+	Status string `json:"status"`
+	Path   string `json:"path"`
+}
 
-	// Pretty print as TOML
-	encoder := toml.NewEncoder(os.Stdout)
-	encoder.Indent = "  "
-	return encoder.Encode(cfg)
+// configSetResult is the output of riff config set.
+type configSetResult struct {
+	Status string `json:"status"`
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	return render(cfg, func() error {
+		// Pretty print as TOML
+		encoder := toml.NewEncoder(os.Stdout)
+		encoder.Indent = "  "
+		return encoder.Encode(cfg)
+	})
 }
 
 func runConfigEdit(cmd *cobra.Command, args []string) error {
@@ -161,19 +174,13 @@ func runConfigInit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to write config: %w", err)
 	}
 
-	if JSONOutput() {
-		_ = json.NewEncoder(os.Stdout).Encode(map[string]string{
-			"status": "created",
-			"path":   configPath,
-		})
-	} else {
+	return render(configInitResult{Status: "created", Path: configPath}, func() error {
 		fmt.Printf("Created config file: %s\n", configPath)
 		fmt.Println("\nNext steps:")
 		fmt.Println("  1. Set your Spotify client ID in the config file or via RIFF_SPOTIFY_CLIENT_ID")
 		fmt.Println("  2. Run 'riff auth login' to authenticate with Spotify")
-	}
-
-	return nil
+		return nil
+	})
 }
 
 func getConfigPath() string {
@@ -200,16 +207,9 @@ func runConfigSet(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("config file not found at %s. Run 'riff config init' first", configPath)
 	}
 
-	// Read the current config file as raw TOML
-	data, err := os.ReadFile(configPath)
+	rawConfig, err := readRawConfig(configPath)
 	if err != nil {
-		return fmt.Errorf("failed to read config: %w", err)
-	}
-
-	// Parse and update based on key
-	var rawConfig map[string]interface{}
-	if _, err := toml.Decode(string(data), &rawConfig); err != nil {
-		return fmt.Errorf("failed to parse config: %w", err)
+		return err
 	}
 
 	// Parse the key (e.g., "defaults.device" -> ["defaults", "device"])
@@ -249,8 +249,30 @@ func runConfigSet(cmd *cobra.Command, args []string) error {
 
 	sectionMap[field] = typedValue
 
-	// Write back to file
-	f, err := os.Create(configPath)
+	if err := writeRawConfig(configPath, rawConfig); err != nil {
+		return err
+	}
+
+	return renderf(configSetResult{Status: "updated", Key: key, Value: value}, "Set %s = %s", key, value)
+}
+
+// readRawConfig reads the config file as raw TOML, so it can be edited and
+// written back without dropping keys riff doesn't know.
+func readRawConfig(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	var rawConfig map[string]interface{}
+	if _, err := toml.Decode(string(data), &rawConfig); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	return rawConfig, nil
+}
+
+// writeRawConfig writes raw TOML back to the config file.
+func writeRawConfig(path string, rawConfig map[string]interface{}) error {
+	f, err := os.Create(path)
 	if err != nil {
 		return fmt.Errorf("failed to write config: %w", err)
 	}
@@ -266,17 +288,6 @@ func runConfigSet(cmd *cobra.Command, args []string) error {
 	if err := encoder.Encode(rawConfig); err != nil {
 		return fmt.Errorf("failed to write config: %w", err)
 	}
-
-	if JSONOutput() {
-		_ = json.NewEncoder(os.Stdout).Encode(map[string]string{
-			"status": "updated",
-			"key":    key,
-			"value":  value,
-		})
-	} else {
-		fmt.Printf("Set %s = %s\n", key, value)
-	}
-
 	return nil
 }
 
@@ -287,12 +298,15 @@ func runConfigSetDevice(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("spotify not configured")
 	}
 
-	storage, err := auth.NewTokenStorage("")
+	storage, err := newTokenStore()
 	if err != nil {
-		return fmt.Errorf("failed to initialize token storage: %w", err)
+		return err
 	}
 
-	spotifyClient := client.New(cfg.Spotify.ClientID, storage)
+	spotifyClient, err := newSpotifyClient(storage)
+	if err != nil {
+		return err
+	}
 	if err := spotifyClient.LoadToken(); err != nil {
 		return fmt.Errorf("failed to load token: %w", err)
 	}
@@ -2,15 +2,16 @@ package cli
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/tessro/riff/internal/core"
-	"github.com/tessro/riff/internal/sonos"
-	"github.com/tessro/riff/internal/spotify/player"
 )
 
 var controlDevice string
@@ -52,8 +53,12 @@ var restartCmd = &cobra.Command{
 }
 
 var (
-	volumeUp   bool
-	volumeDown bool
+	volumeUp    bool
+	volumeDown  bool
+	volumeGroup bool
+	volumeRoom  bool
+	volumeFade  time.Duration
+	volumeCurve string
 )
 
 var volumeCmd = &cobra.Command{
@@ -64,33 +69,119 @@ var volumeCmd = &cobra.Command{
 Examples:
   riff volume 50      # Set volume to 50%
   riff volume --up    # Increase volume by 10%
-  riff volume --down  # Decrease volume by 10%`,
+  riff volume --down  # Decrease volume by 10%
+  riff volume 30 --to Kitchen          # The whole group, if Kitchen leads one
+  riff volume 30 --room --to Kitchen   # Just the Kitchen speaker
+  riff volume 20 --fade 30s            # Fade to 20% over 30 seconds
+
+Targeting the coordinator of a Sonos group sets the volume of the whole
+group, keeping the rooms' levels relative to each other; --room sets only
+that speaker. --group sets the whole group from any of its rooms.
+
+A newer volume command on the same device cancels a fade in progress.`,
 	RunE: runVolume,
 }
 
+var shuffleCmd = &cobra.Command{
+	Use:       "shuffle [on|off]",
+	Short:     "Turn shuffle on or off",
+	Long:      `Turn shuffle on or off. Without an argument, toggles the current setting.`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"on", "off"},
+	RunE:      runShuffle,
+}
+
+var repeatCmd = &cobra.Command{
+	Use:   "repeat [off|track|context]",
+	Short: "Set the repeat mode",
+	Long: `Set the repeat mode. Without an argument, cycles off → context → track.
+
+Modes:
+  off      Play through once
+  context  Repeat the album or playlist
+  track    Repeat the current track`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"off", "track", "context"},
+	RunE:      runRepeat,
+}
+
+var seekCmd = &cobra.Command{
+	Use:   "seek <position>",
+	Short: "Seek within the current track",
+	Long: `Seek to a position in the current track.
+
+Positions are seconds or [h:]mm:ss. A leading + or - seeks relative
+to the current position.
+
+Examples:
+  riff seek 1:30     # Jump to 1m30s
+  riff seek 90       # Same as above
+  riff seek +15      # Skip ahead 15 seconds
+  riff seek -- -10   # Go back 10 seconds`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSeek,
+}
+
 func init() {
-	// Add device flag to all control commands
-	pauseCmd.Flags().StringVarP(&controlDevice, "device", "d", "", "Target device")
-	resumeCmd.Flags().StringVarP(&controlDevice, "device", "d", "", "Target device")
-	nextCmd.Flags().StringVarP(&controlDevice, "device", "d", "", "Target device")
-	prevCmd.Flags().StringVarP(&controlDevice, "device", "d", "", "Target device")
-	restartCmd.Flags().StringVarP(&controlDevice, "device", "d", "", "Target device")
-	volumeCmd.Flags().StringVarP(&controlDevice, "device", "d", "", "Target device")
+	// Add target flags to all control commands
+	for _, c := range []*cobra.Command{pauseCmd, resumeCmd, nextCmd, prevCmd, restartCmd, seekCmd, volumeCmd, shuffleCmd, repeatCmd} {
+		addTargetFlags(c, &controlDevice)
+	}
 	volumeCmd.Flags().BoolVar(&volumeUp, "up", false, "Increase volume by 10%")
 	volumeCmd.Flags().BoolVar(&volumeDown, "down", false, "Decrease volume by 10%")
+	volumeCmd.Flags().BoolVar(&volumeGroup, "group", false, "Set the volume of the device's whole group")
+	volumeCmd.Flags().BoolVar(&volumeRoom, "room", false, "Set only this speaker's volume, even if it leads a group")
+	volumeCmd.MarkFlagsMutuallyExclusive("group", "room")
+	volumeCmd.Flags().DurationVar(&volumeFade, "fade", 0, "Fade to the new volume over this long")
+	volumeCmd.Flags().StringVar(&volumeCurve, "curve", "", "Fade curve: linear or log (default: [defaults] fade_curve)")
 
 	rootCmd.AddCommand(pauseCmd)
 	rootCmd.AddCommand(resumeCmd)
 	rootCmd.AddCommand(nextCmd)
 	rootCmd.AddCommand(prevCmd)
 	rootCmd.AddCommand(restartCmd)
+	rootCmd.AddCommand(seekCmd)
 	rootCmd.AddCommand(volumeCmd)
+	rootCmd.AddCommand(shuffleCmd)
+	rootCmd.AddCommand(repeatCmd)
+
+	for _, cmd := range []*cobra.Command{pauseCmd, resumeCmd, nextCmd, prevCmd, restartCmd} {
+		registerOutput(cmd, actionResult{})
+	}
+	registerOutput(seekCmd, seekResult{})
+	registerOutput(volumeCmd, volumeResult{})
+	registerOutput(shuffleCmd, shuffleResult{})
+	registerOutput(repeatCmd, repeatResult{})
+}
+
+// seekResult is the output of riff seek.
+type seekResult struct {
+	Status     string `json:"status"`
+	PositionMS int64  `json:"position_ms"`
+}
+
+// volumeResult is the output of riff volume. Previous is set when the
+// volume changed.
+type volumeResult struct {
+	Volume   int    `json:"volume"`
+	Previous *int   `json:"previous,omitempty"`
+	Platform string `json:"platform"`
+}
+
+// shuffleResult is the output of riff shuffle.
+type shuffleResult struct {
+	Shuffle bool `json:"shuffle"`
+}
+
+// repeatResult is the output of riff repeat.
+type repeatResult struct {
+	Repeat string `json:"repeat"`
 }
 
 func runPause(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
-	p, err := getSpotifyPlayer(ctx)
+	p, _, err := resolvePlayer(ctx, controlDevice)
 	if err != nil {
 		return err
 	}
@@ -99,19 +190,13 @@ func runPause(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to pause: %w", err)
 	}
 
-	if JSONOutput() {
-		_ = json.NewEncoder(os.Stdout).Encode(map[string]string{"status": "paused"})
-	} else {
-		fmt.Println("⏸ Paused")
-	}
-
-	return nil
+	return renderf(actionResult{Status: "paused"}, "⏸ Paused")
 }
 
 func runResume(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
-	p, err := getSpotifyPlayer(ctx)
+	p, _, err := resolvePlayer(ctx, controlDevice)
 	if err != nil {
 		return err
 	}
@@ -120,19 +205,13 @@ func runResume(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to resume: %w", err)
 	}
 
-	if JSONOutput() {
-		_ = json.NewEncoder(os.Stdout).Encode(map[string]string{"status": "playing"})
-	} else {
-		fmt.Println("▶ Resumed")
-	}
-
-	return nil
+	return renderf(actionResult{Status: "playing"}, "▶ Resumed")
 }
 
 func runNext(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
-	p, err := getSpotifyPlayer(ctx)
+	p, _, err := resolvePlayer(ctx, controlDevice)
 	if err != nil {
 		return err
 	}
@@ -141,19 +220,13 @@ func runNext(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to skip: %w", err)
 	}
 
-	if JSONOutput() {
-		_ = json.NewEncoder(os.Stdout).Encode(map[string]string{"status": "skipped"})
-	} else {
-		fmt.Println("⏭ Skipped to next track")
-	}
-
-	return nil
+	return renderf(actionResult{Status: "skipped"}, "⏭ Skipped to next track")
 }
 
 func runPrev(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
-	p, err := getSpotifyPlayer(ctx)
+	p, _, err := resolvePlayer(ctx, controlDevice)
 	if err != nil {
 		return err
 	}
@@ -162,38 +235,101 @@ func runPrev(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to go back: %w", err)
 	}
 
-	if JSONOutput() {
-		_ = json.NewEncoder(os.Stdout).Encode(map[string]string{"status": "previous"})
-	} else {
-		fmt.Println("⏮ Previous track")
-	}
-
-	return nil
+	return renderf(actionResult{Status: "previous"}, "⏮ Previous track")
 }
 
 func runRestart(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
-	p, err := getSpotifyPlayer(ctx)
+	p, _, err := resolvePlayer(ctx, controlDevice)
 	if err != nil {
 		return err
 	}
+	if err := core.Require(p, core.CapSeek); err != nil {
+		return err
+	}
 
 	if err := p.Seek(ctx, 0); err != nil {
 		return fmt.Errorf("failed to restart: %w", err)
 	}
 
-	if JSONOutput() {
-		_ = json.NewEncoder(os.Stdout).Encode(map[string]string{"status": "restarted"})
-	} else {
-		fmt.Println("⏪ Restarted track")
+	return renderf(actionResult{Status: "restarted"}, "⏪ Restarted track")
+}
+
+func runSeek(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	position, relative, err := parseSeekPosition(args[0])
+	if err != nil {
+		return err
+	}
+
+	p, _, err := resolvePlayer(ctx, controlDevice)
+	if err != nil {
+		return err
+	}
+	if err := core.Require(p, core.CapSeek); err != nil {
+		return err
 	}
 
-	return nil
+	target := position
+	if relative {
+		state, err := p.GetState(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get playback state: %w", err)
+		}
+		target = state.Progress + position
+		if target < 0 {
+			target = 0
+		}
+		if state.Track != nil && state.Track.Duration > 0 && target > state.Track.Duration {
+			target = state.Track.Duration
+		}
+	}
+
+	if err := p.Seek(ctx, int(target.Milliseconds())); err != nil {
+		return fmt.Errorf("failed to seek: %w", err)
+	}
+
+	return renderf(seekResult{Status: "seeked", PositionMS: target.Milliseconds()},
+		"⏩ Seeked to %s", formatDuration(target))
+}
+
+// parseSeekPosition parses "90", "1:30", "1:02:03", "+15" or "-10".
+// relative is true when the position has an explicit sign.
+func parseSeekPosition(s string) (position time.Duration, relative bool, err error) {
+	sign := time.Duration(1)
+	value := s
+	switch {
+	case strings.HasPrefix(s, "+"):
+		relative, value = true, s[1:]
+	case strings.HasPrefix(s, "-"):
+		relative, value, sign = true, s[1:], -1
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) > 3 {
+		return 0, false, fmt.Errorf("invalid position: %s", s)
+	}
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, false, fmt.Errorf("invalid position: %s", s)
+		}
+		position = position*60 + time.Duration(n)*time.Second
+	}
+
+	return sign * position, relative, nil
 }
 
 func runVolume(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
+	if volumeFade > 0 {
+		// Let Ctrl-C stop a fade where it is
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+	}
 
 	// Determine target volume from args/flags
 	var targetVolume *int
@@ -212,55 +348,70 @@ func runVolume(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Try to find active playback - check Sonos first since it's local
-	sonosPlayer, sonosState := getActiveSonosPlayer(ctx)
-
-	// If Sonos is playing, control it
-	if sonosPlayer != nil && sonosState != nil && sonosState.IsPlaying {
-		return runVolumeOnPlayer(ctx, sonosPlayer, sonosState.Volume, targetVolume, "sonos")
+	p, d, err := resolvePlayer(ctx, controlDevice)
+	if err != nil {
+		return err
 	}
-
-	// Otherwise try Spotify
-	spotifyClient, err := getSpotifyClient()
+	state, err := p.GetState(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get playback state: %w", err)
+	}
+	vc, currentVolume, err := currentVolumeOf(ctx, p, state, volumeScope(volumeGroup, volumeRoom))
 	if err != nil {
-		// If no Spotify and we found a Sonos (even if not playing), use that
-		if sonosPlayer != nil && sonosState != nil {
-			return runVolumeOnPlayer(ctx, sonosPlayer, sonosState.Volume, targetVolume, "sonos")
-		}
 		return err
 	}
+	return runVolumeOnPlayer(ctx, vc, volumeKey(p, d, state), currentVolume, targetVolume, string(platformOf(p)))
+}
 
-	p := player.New(spotifyClient)
+// scope selects between a speaker's own volume and its group's.
+type scope int
 
-	if controlDevice != "" {
-		resolved, err := resolveDevice(ctx, spotifyClient, controlDevice)
-		if err != nil {
-			return err
-		}
-		if resolved.Platform == core.PlatformSonos && resolved.SonosDevice != nil {
-			// Use Sonos for this device
-			sonosClient := sonos.NewClient()
-			sp := sonos.NewPlayer(sonosClient, resolved.SonosDevice)
-			state, _ := sp.GetState(ctx)
-			vol := 0
-			if state != nil {
-				vol = state.Volume
-			}
-			return runVolumeOnPlayer(ctx, sp, vol, targetVolume, "sonos")
-		}
-		p.SetDevice(resolved.SpotifyID)
+const (
+	scopeAuto  scope = iota // The group's when the target coordinates one
+	scopeGroup              // --group
+	scopeRoom               // --room
+)
+
+// volumeScope returns the scope chosen by the --group and --room flags.
+func volumeScope(group, room bool) scope {
+	switch {
+	case group:
+		return scopeGroup
+	case room:
+		return scopeRoom
+	default:
+		return scopeAuto
 	}
+}
 
-	state, err := p.GetState(ctx)
-	if err != nil {
-		// If Spotify fails but we have Sonos available, use that
-		if sonosPlayer != nil && sonosState != nil {
-			return runVolumeOnPlayer(ctx, sonosPlayer, sonosState.Volume, targetVolume, "sonos")
+// currentVolumeOf returns what sets p's volume in scope, and the current
+// level. state is p's playback state. scopeAuto uses group volume when p is
+// a Sonos room that coordinates a group of rooms.
+func currentVolumeOf(ctx context.Context, p core.Player, state *core.PlaybackState, sc scope) (volumeController, int, error) {
+	group := sc == scopeGroup
+	if sc == scopeAuto && state != nil && state.Device != nil && state.Device.Platform == core.PlatformSonos {
+		if g, ok := p.(core.GroupVolumeController); ok {
+			// Without the topology, fall back to the speaker's own volume
+			group, _ = g.CoordinatesGroup(ctx)
 		}
-		return fmt.Errorf("failed to get playback state: %w", err)
+	}
+	if group {
+		if err := core.Require(p, core.CapGroupVolume); err != nil {
+			return nil, 0, err
+		}
+		g := p.(core.GroupVolumeController)
+		currentVolume, err := g.GroupVolume(ctx)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get group volume: %w", err)
+		}
+		return groupVolumeSetter{g}, currentVolume, nil
 	}
 
-	return runVolumeOnPlayer(ctx, p, state.Volume, targetVolume, "spotify")
+	currentVolume := 0
+	if state != nil {
+		currentVolume = state.Volume
+	}
+	return p, currentVolume, nil
 }
 
 // volumeController is an interface for volume control across platforms.
@@ -268,18 +419,20 @@ type volumeController interface {
 	Volume(ctx context.Context, percent int) error
 }
 
-func runVolumeOnPlayer(ctx context.Context, p volumeController, currentVolume int, targetVolume *int, platform string) error {
+// groupVolumeSetter adapts a group volume controller to volumeController.
+type groupVolumeSetter struct {
+	core.GroupVolumeController
+}
+
+func (g groupVolumeSetter) Volume(ctx context.Context, percent int) error {
+	return g.SetGroupVolume(ctx, percent)
+}
+
+func runVolumeOnPlayer(ctx context.Context, p volumeController, key string, currentVolume int, targetVolume *int, platform string) error {
 	if targetVolume == nil {
 		// Just show current volume
-		if JSONOutput() {
-			_ = json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
-				"volume":   currentVolume,
-				"platform": platform,
-			})
-		} else {
-			fmt.Printf("🔊 Volume: %d%% (%s)\n", currentVolume, platform)
-		}
-		return nil
+		return renderf(volumeResult{Volume: currentVolume, Platform: platform},
+			"🔊 Volume: %d%% (%s)", currentVolume, platform)
 	}
 
 	// Calculate target if relative
@@ -296,82 +449,91 @@ func runVolumeOnPlayer(ctx context.Context, p volumeController, currentVolume in
 		}
 	}
 
-	if err := p.Volume(ctx, target); err != nil {
+	if err := fadeVolume(ctx, p, key, currentVolume, target, volumeFade, volumeCurve, platform); err != nil {
 		return fmt.Errorf("failed to set volume: %w", err)
 	}
 
-	if JSONOutput() {
-		_ = json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
-			"volume":   target,
-			"previous": currentVolume,
-			"platform": platform,
-		})
-	} else {
-		fmt.Printf("🔊 Volume: %d%% (was %d%%) [%s]\n", target, currentVolume, platform)
-	}
-
-	return nil
+	return renderf(volumeResult{Volume: target, Previous: &currentVolume, Platform: platform},
+		"🔊 Volume: %d%% (was %d%%) [%s]", target, currentVolume, platform)
 }
 
-// getActiveSonosPlayer returns a Sonos player and its state if one is actively playing.
-func getActiveSonosPlayer(ctx context.Context) (*sonos.Player, *core.PlaybackState) {
-	client := sonos.NewClient()
-
-	devices, err := client.Discover(ctx)
-	if err != nil || len(devices) == 0 {
-		return nil, nil
-	}
+func runShuffle(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
 
-	groups, err := client.ListGroups(ctx, devices[0])
+	p, _, err := resolvePlayer(ctx, controlDevice)
 	if err != nil {
-		return nil, nil
+		return err
+	}
+	if err := core.Require(p, core.CapShuffle); err != nil {
+		return err
 	}
 
-	// Find a playing group
-	for _, g := range groups {
-		if g.Coordinator == nil {
-			continue
+	var enable bool
+	if len(args) > 0 {
+		switch args[0] {
+		case "on":
+			enable = true
+		case "off":
+			enable = false
+		default:
+			return fmt.Errorf("invalid shuffle state: %s (must be on or off)", args[0])
 		}
-		player := sonos.NewPlayer(client, g.Coordinator)
-		state, err := player.GetState(ctx)
+	} else {
+		state, err := p.GetState(ctx)
 		if err != nil {
-			continue
-		}
-		if state.IsPlaying || state.Track != nil {
-			return player, state
+			return fmt.Errorf("failed to get playback state: %w", err)
 		}
+		enable = !state.Shuffle
 	}
 
-	// No playing group found, return the first coordinator anyway
-	for _, g := range groups {
-		if g.Coordinator != nil {
-			player := sonos.NewPlayer(client, g.Coordinator)
-			state, _ := player.GetState(ctx)
-			return player, state
-		}
+	if err := p.(core.PlayModeController).Shuffle(ctx, enable); err != nil {
+		return fmt.Errorf("failed to set shuffle: %w", err)
 	}
 
-	return nil, nil
+	text := "➡ Shuffle off"
+	if enable {
+		text = "🔀 Shuffle on"
+	}
+	return renderf(shuffleResult{Shuffle: enable}, "%s", text)
 }
 
-func getSpotifyPlayer(ctx context.Context) (*player.Player, error) {
-	spotifyClient, err := getSpotifyClient()
+func runRepeat(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	p, _, err := resolvePlayer(ctx, controlDevice)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if err := core.Require(p, core.CapRepeat); err != nil {
+		return err
 	}
 
-	p := player.New(spotifyClient)
-
-	if controlDevice != "" {
-		resolved, err := resolveDevice(ctx, spotifyClient, controlDevice)
+	var mode string
+	if len(args) > 0 {
+		mode = args[0]
+		switch mode {
+		case "off", "track", "context":
+		default:
+			return fmt.Errorf("invalid repeat mode: %s (must be off, track, or context)", mode)
+		}
+	} else {
+		state, err := p.GetState(ctx)
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("failed to get playback state: %w", err)
 		}
-		if resolved.Platform != core.PlatformSpotify {
-			return nil, fmt.Errorf("control commands for Sonos devices not yet supported via --device flag")
+		switch state.Repeat {
+		case "context":
+			mode = "track"
+		case "track":
+			mode = "off"
+		default:
+			mode = "context"
 		}
-		p.SetDevice(resolved.SpotifyID)
 	}
 
-	return p, nil
+	if err := p.(core.PlayModeController).Repeat(ctx, mode); err != nil {
+		return fmt.Errorf("failed to set repeat: %w", err)
+	}
+
+	return renderf(repeatResult{Repeat: mode}, "🔁 Repeat: %s", mode)
 }
@@ -0,0 +1,213 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/daemon"
+	"github.com/tessro/riff/internal/sleeptimer"
+)
+
+// daemonTimeout bounds the handshake with riffd before a command falls back
+// to running directly.
+const daemonTimeout = 2 * time.Second
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Manage riffd, the background daemon",
+	Long: `riffd keeps Spotify, Sonos, MPD and UPnP backends, discovery caches and
+clients warm, and serves them on a Unix socket. While it runs, commands such
+as play, pause, volume, queue and devices go through it instead of repeating
+discovery; when it isn't running they work directly as before.
+
+The riffd binary is the same as 'riff daemon run'.
+
+Examples:
+  riff daemon run &
+  riff daemon status
+  riff daemon stop
+  riff --no-daemon devices`,
+}
+
+var daemonRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run the daemon in the foreground",
+	Args:  cobra.NoArgs,
+	RunE:  runDaemonRun,
+}
+
+var daemonStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the daemon is running",
+	Args:  cobra.NoArgs,
+	RunE:  runDaemonStatus,
+}
+
+var daemonStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the daemon",
+	Args:  cobra.NoArgs,
+	RunE:  runDaemonStop,
+}
+
+func init() {
+	addMetricsFlag(daemonRunCmd)
+	daemonCmd.AddCommand(daemonRunCmd)
+	daemonCmd.AddCommand(daemonStatusCmd)
+	daemonCmd.AddCommand(daemonStopCmd)
+	rootCmd.AddCommand(daemonCmd)
+
+	registerOutput(daemonRunCmd, daemonRunResult{})
+	registerOutput(daemonStatusCmd, daemonStatusResult{})
+	registerOutput(daemonStopCmd, actionResult{})
+}
+
+// daemonRunResult is what riff daemon run outputs once it is listening.
+type daemonRunResult struct {
+	Status string `json:"status"`
+	Socket string `json:"socket"`
+	PID    int    `json:"pid"`
+}
+
+// daemonStatusResult is the output of riff daemon status. The daemon's
+// own status is included when it is running.
+type daemonStatusResult struct {
+	Running bool   `json:"running"`
+	Socket  string `json:"socket"`
+	*daemon.Status
+}
+
+// daemonSocket returns the configured socket path, or the default.
+func daemonSocket() string {
+	if cfg != nil && cfg.Daemon.Socket != "" {
+		return cfg.Daemon.Socket
+	}
+	return daemon.SocketPath()
+}
+
+// newDaemonBackends returns backends served by riffd, or nil when it isn't
+// running or has been disabled.
+func newDaemonBackends() *backends {
+	if noDaemon || (cfg != nil && cfg.Daemon.Disabled) {
+		return nil
+	}
+	path := daemonSocket()
+	c, err := daemon.Dial(path)
+	if err != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+	defer cancel()
+	remotes, err := c.Backends(ctx)
+	if err != nil {
+		_ = c.Close()
+		slog.DebugContext(ctx, "riffd unavailable, running directly", "socket", path, "error", err)
+		return nil
+	}
+	slog.DebugContext(ctx, "using riffd", "socket", path)
+	return &backends{Registry: core.NewRegistry(remotes...), remote: c}
+}
+
+func runDaemonRun(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	path := daemonSocket()
+	ln, err := daemon.Listen(path)
+	if err != nil {
+		return err
+	}
+
+	if err := startMetrics(ctx); err != nil {
+		return err
+	}
+
+	b := newDirectBackends()
+	server := daemon.NewServer(b.Registry, Version)
+	if interval := time.Duration(cfg.Daemon.RefreshInterval) * time.Second; interval > 0 {
+		go server.KeepWarm(ctx, interval)
+	}
+	if cfg.Daemon.Schedule {
+		scheduler, err := newScheduler(b)
+		if err != nil {
+			return err
+		}
+		go func() { _ = scheduler.Run(ctx) }()
+	}
+	go func() { _ = newSleepRunner(b, sleeptimer.OwnerDaemon).Run(ctx) }()
+
+	if err := renderf(daemonRunResult{Status: "listening", Socket: path, PID: os.Getpid()},
+		"riffd listening on %s", path); err != nil {
+		return err
+	}
+
+	if err := server.Serve(ctx, ln); err != nil && err != context.Canceled {
+		return err
+	}
+	return nil
+}
+
+func runDaemonStatus(cmd *cobra.Command, args []string) error {
+	path := daemonSocket()
+	c, err := daemon.Dial(path)
+	if err != nil {
+		return renderf(daemonStatusResult{Socket: path}, "riffd is not running")
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), daemonTimeout)
+	defer cancel()
+	status, err := c.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get riffd status: %w", err)
+	}
+
+	return render(daemonStatusResult{Running: true, Socket: path, Status: status}, func() error {
+		platforms := make([]string, len(status.Platforms))
+		for i, p := range status.Platforms {
+			platforms[i] = string(p)
+		}
+		fmt.Printf("riffd is running (pid %d, version %s)\n", status.PID, status.Version)
+		fmt.Printf("  Socket:   %s\n", path)
+		fmt.Printf("  Uptime:   %s\n", time.Since(status.StartedAt).Round(time.Second))
+		fmt.Printf("  Backends: %s\n", strings.Join(platforms, ", "))
+		return nil
+	})
+}
+
+func runDaemonStop(cmd *cobra.Command, args []string) error {
+	c, err := daemon.Dial(daemonSocket())
+	if err != nil {
+		return fmt.Errorf("riffd is not running")
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), daemonTimeout)
+	defer cancel()
+	if err := c.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to stop riffd: %w", err)
+	}
+
+	return renderf(actionResult{Status: "stopped"}, "Stopped riffd")
+}
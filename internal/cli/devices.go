@@ -2,16 +2,12 @@ package cli
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"os"
+	"log/slog"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/tessro/riff/internal/core"
-	"github.com/tessro/riff/internal/sonos"
-	"github.com/tessro/riff/internal/spotify/auth"
-	"github.com/tessro/riff/internal/spotify/client"
-	"github.com/tessro/riff/internal/spotify/player"
 )
 
 var devicesRefresh bool
@@ -19,227 +15,107 @@ var devicesRefresh bool
 var devicesCmd = &cobra.Command{
 	Use:   "devices",
 	Short: "List available playback devices",
-	Long:  `Lists all available playback devices across Spotify and Sonos.`,
-	RunE:  runDevices,
+	Long: `Lists all available playback devices across Spotify, Sonos, any configured
+MPD hosts and UPnP/DLNA renderers such as smart TVs and AV receivers.`,
+	RunE: runDevices,
 }
 
 func init() {
 	devicesCmd.Flags().BoolVarP(&devicesRefresh, "refresh", "r", false, "Force refresh device list")
 	rootCmd.AddCommand(devicesCmd)
+	registerOutput(devicesCmd, []deviceInfo{})
 }
 
 func runDevices(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
-	var allDevices []deviceInfo
-
-	// Get Spotify devices
-	spotifyDevices, err := getSpotifyDevices(ctx)
-	if err != nil {
-		if Verbose() {
-			fmt.Fprintf(os.Stderr, "Spotify error: %v\n", err)
-		}
-	} else {
-		allDevices = append(allDevices, spotifyDevices...)
-	}
-
-	// Get Sonos devices
-	sonosDevices, err := getSonosDevices(ctx)
-	if err != nil {
-		if Verbose() {
-			fmt.Fprintf(os.Stderr, "Sonos error: %v\n", err)
-		}
-	} else {
-		allDevices = append(allDevices, sonosDevices...)
-	}
-
-	if len(allDevices) == 0 {
-		if JSONOutput() {
-			_ = json.NewEncoder(os.Stdout).Encode([]interface{}{})
-		} else {
-			fmt.Println("No devices found")
+	b := newBackends()
+	if devicesRefresh {
+		for _, backend := range b.Backends() {
+			r, ok := backend.(interface{ Refresh(context.Context) error })
+			if !ok {
+				continue
+			}
+			if err := r.Refresh(ctx); err != nil {
+				slog.DebugContext(ctx, "device refresh failed", "platform", backend.Platform(), "error", err)
+			}
 		}
-		return nil
-	}
-
-	if JSONOutput() {
-		return outputDevicesJSON(allDevices)
-	}
-	return outputDevicesTable(allDevices)
-}
-
-type deviceInfo struct {
-	Device   *core.Device
-	Volume   *int
-	Platform string
-}
-
-func getSpotifyDevices(ctx context.Context) ([]deviceInfo, error) {
-	if cfg.Spotify.ClientID == "" {
-		return nil, fmt.Errorf("spotify not configured")
-	}
-
-	storage, err := auth.NewTokenStorage("")
-	if err != nil {
-		return nil, err
-	}
-
-	spotifyClient := client.New(cfg.Spotify.ClientID, storage)
-	if Verbose() {
-		spotifyClient.SetVerbose(true, func(format string, args ...interface{}) {
-			fmt.Fprintf(os.Stderr, format+"\n", args...)
-		})
-	}
-	if err := spotifyClient.LoadToken(); err != nil {
-		return nil, err
-	}
-
-	if !spotifyClient.HasToken() {
-		return nil, fmt.Errorf("not authenticated")
 	}
 
-	p := player.New(spotifyClient)
-	devices, err := p.GetDevices(ctx)
+	devices, err := b.Devices(ctx)
 	if err != nil {
-		return nil, err
+		slog.DebugContext(ctx, "listing devices failed", "error", err)
 	}
 
-	result := make([]deviceInfo, len(devices))
+	allDevices := make([]deviceInfo, len(devices))
 	for i, d := range devices {
-		dev := d // Copy to avoid aliasing
-		result[i] = deviceInfo{
-			Device:   &dev,
-			Platform: "spotify",
+		allDevices[i] = deviceInfo{
+			ID:       d.ID,
+			Name:     d.Name,
+			Type:     d.Type,
+			Platform: d.Platform,
+			IsActive: d.IsActive,
 		}
 	}
 
-	return result, nil
-}
-
-func getSonosDevices(ctx context.Context) ([]deviceInfo, error) {
-	client := sonos.NewClient()
-
-	var devices []*sonos.Device
-	var err error
-	if devicesRefresh {
-		devices, err = client.DiscoverFresh(ctx)
-	} else {
-		devices, err = client.Discover(ctx)
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	if len(devices) == 0 {
-		return nil, nil
-	}
-
-	// Get zone groups to find device names and active status
-	groups, err := client.ListGroups(ctx, devices[0])
-	if err != nil {
-		// Fall back to basic device info
-		result := make([]deviceInfo, len(devices))
-		for i, d := range devices {
-			result[i] = deviceInfo{
-				Device: &core.Device{
-					ID:       d.UUID,
-					Name:     d.Name,
-					Type:     core.DeviceTypeSpeaker,
-					Platform: core.PlatformSonos,
-				},
-				Platform: "sonos",
-			}
-		}
-		return result, nil
-	}
-
-	// Build device info from groups (includes names)
-	var result []deviceInfo
-	for _, g := range groups {
-		for _, m := range g.Members {
-			isCoordinator := g.Coordinator != nil && m.UUID == g.Coordinator.UUID
-			result = append(result, deviceInfo{
-				Device: &core.Device{
-					ID:       m.UUID,
-					Name:     m.Name,
-					Type:     core.DeviceTypeSpeaker,
-					Platform: core.PlatformSonos,
-					IsActive: isCoordinator,
-				},
-				Platform: "sonos",
-			})
+	return render(allDevices, func() error {
+		if len(allDevices) == 0 {
+			fmt.Println("No devices found")
+			return nil
 		}
-	}
-
-	return result, nil
+		return outputDevicesTable(allDevices)
+	})
 }
 
-func outputDevicesJSON(devices []deviceInfo) error {
-	output := make([]map[string]interface{}, 0, len(devices))
-
-	for _, d := range devices {
-		item := map[string]interface{}{
-			"id":        d.Device.ID,
-			"name":      d.Device.Name,
-			"type":      d.Device.Type,
-			"platform":  d.Platform,
-			"is_active": d.Device.IsActive,
-		}
-		if d.Volume != nil {
-			item["volume"] = *d.Volume
-		}
-		output = append(output, item)
-	}
-
-	return json.NewEncoder(os.Stdout).Encode(output)
+// deviceInfo is a device in the output of riff devices.
+type deviceInfo struct {
+	ID       string          `json:"id"`
+	Name     string          `json:"name"`
+	Type     core.DeviceType `json:"type"`
+	Platform core.Platform   `json:"platform"`
+	IsActive bool            `json:"is_active"`
+	Volume   *int            `json:"volume,omitempty"`
 }
 
 func outputDevicesTable(devices []deviceInfo) error {
 	// Group by platform
-	spotify := make([]deviceInfo, 0)
-	sonos := make([]deviceInfo, 0)
-
+	platforms := []core.Platform{core.PlatformSpotify, core.PlatformSonos, core.PlatformMPD, core.PlatformUPnP}
+	groups := make(map[core.Platform][]deviceInfo)
 	for _, d := range devices {
-		switch d.Platform {
-		case "spotify":
-			spotify = append(spotify, d)
-		case "sonos":
-			sonos = append(sonos, d)
-		}
+		groups[d.Platform] = append(groups[d.Platform], d)
 	}
 
-	if len(spotify) > 0 {
-		fmt.Println("[SPOTIFY]")
-		for _, d := range spotify {
-			printDevice(d)
+	printed := false
+	for _, platform := range platforms {
+		group := groups[platform]
+		if len(group) == 0 {
+			continue
 		}
-	}
-
-	if len(sonos) > 0 {
-		if len(spotify) > 0 {
+		if printed {
 			fmt.Println()
 		}
-		fmt.Println("[SONOS]")
-		for _, d := range sonos {
+		fmt.Printf("[%s]\n", strings.ToUpper(string(platform)))
+		for _, d := range group {
 			printDevice(d)
 		}
+		printed = true
 	}
 
 	return nil
 }
 
 func printDevice(d deviceInfo) {
-	icon := getDeviceIcon(d.Device.Type)
+	icon := getDeviceIcon(d.Type)
 	active := ""
-	if d.Device.IsActive {
+	if d.IsActive {
 		active = " ●"
 	}
 
-	fmt.Printf("  %s %s%s\n", icon, d.Device.Name, active)
+	fmt.Printf("  %s %s%s\n", icon, d.Name, active)
 
 	if Verbose() {
-		fmt.Printf("      ID: %s\n", d.Device.ID)
-		fmt.Printf("      Type: %s\n", d.Device.Type)
+		fmt.Printf("      ID: %s\n", d.ID)
+		fmt.Printf("      Type: %s\n", d.Type)
 	}
 }
 
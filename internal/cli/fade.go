@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tessro/riff/internal/config"
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/fade"
+)
+
+var (
+	fadeOutThen  string
+	fadeOutCurve string
+	fadeOutGroup bool
+	fadeOutRoom  bool
+)
+
+var fadeOutCmd = &cobra.Command{
+	Use:   "fade-out <duration>",
+	Short: "Fade the volume out",
+	Long: `Fade the volume to zero over a duration such as 30s or 2m.
+
+With --then pause, playback pauses at the end and the volume goes back to
+where it started, ready for the next time. A newer volume command on the
+same device cancels the fade. Like riff volume, a Sonos group fades as a
+whole when its coordinator is the target, unless --room is given.
+
+Examples:
+  riff fade-out 2m --then pause
+  riff fade-out 30s --to Kitchen --curve log
+  riff fade-out 30s --room --to Kitchen`,
+	Args: cobra.ExactArgs(1),
+	RunE: runFadeOut,
+}
+
+func init() {
+	addTargetFlags(fadeOutCmd, &controlDevice)
+	fadeOutCmd.Flags().StringVar(&fadeOutThen, "then", "", "What to do after fading out: pause")
+	fadeOutCmd.Flags().StringVar(&fadeOutCurve, "curve", "", "Fade curve: linear or log (default: [defaults] fade_curve)")
+	fadeOutCmd.Flags().BoolVar(&fadeOutGroup, "group", false, "Fade the device's whole group")
+	fadeOutCmd.Flags().BoolVar(&fadeOutRoom, "room", false, "Fade only this speaker, even if it leads a group")
+	fadeOutCmd.MarkFlagsMutuallyExclusive("group", "room")
+	rootCmd.AddCommand(fadeOutCmd)
+	registerOutput(fadeOutCmd, fadeOutResult{})
+}
+
+func runFadeOut(cmd *cobra.Command, args []string) error {
+	d, err := time.ParseDuration(args[0])
+	if err != nil || d < 0 {
+		return fmt.Errorf("invalid duration: %s", args[0])
+	}
+	switch fadeOutThen {
+	case "", "pause":
+	default:
+		return fmt.Errorf("invalid --then %q (want pause)", fadeOutThen)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	p, dev, err := resolvePlayer(ctx, controlDevice)
+	if err != nil {
+		return err
+	}
+	state, err := p.GetState(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get playback state: %w", err)
+	}
+	vc, startVolume, err := currentVolumeOf(ctx, p, state, volumeScope(fadeOutGroup, fadeOutRoom))
+	if err != nil {
+		return err
+	}
+	platform := string(platformOf(p))
+
+	if err := fadeVolume(ctx, vc, volumeKey(p, dev, state), startVolume, 0, d, fadeOutCurve, platform); err != nil {
+		return fmt.Errorf("fade-out failed: %w", err)
+	}
+	if fadeOutThen == "pause" {
+		if err := p.Pause(ctx); err != nil {
+			return fmt.Errorf("failed to pause: %w", err)
+		}
+		if err := vc.Volume(ctx, startVolume); err != nil {
+			return fmt.Errorf("failed to restore volume: %w", err)
+		}
+	}
+
+	res := fadeOutResult{Status: "faded_out", Previous: startVolume, Paused: fadeOutThen == "pause", Platform: platform}
+	if res.Paused {
+		return renderf(res, "🔇 Faded out and paused (volume restored to %d%%) [%s]", startVolume, platform)
+	}
+	return renderf(res, "🔇 Faded out (was %d%%) [%s]", startVolume, platform)
+}
+
+// fadeOutResult is the output of riff fade-out.
+type fadeOutResult struct {
+	Status   string `json:"status"`
+	Previous int    `json:"previous"`
+	Paused   bool   `json:"paused"`
+	Platform string `json:"platform"`
+}
+
+// fadeVolume moves p's volume from current to target over d, stepping less
+// often on Spotify. It cancels any older fade on the same device, and is
+// cancelled in turn by a newer one. A zero d sets the volume at once.
+func fadeVolume(ctx context.Context, p volumeController, key string, current, target int, d time.Duration, curve, platform string) error {
+	dir := fadeClaimDir()
+	if d <= 0 {
+		fade.Supersede(dir, key)
+		return p.Volume(ctx, target)
+	}
+
+	if curve == "" && cfg != nil {
+		curve = cfg.Defaults.FadeCurve
+	}
+	c, err := fade.ParseCurve(curve)
+	if err != nil {
+		return err
+	}
+	claim, err := fade.NewClaim(dir, key)
+	if err != nil {
+		return err
+	}
+	defer claim.Release()
+
+	opts := fade.Options{Curve: c, Superseded: claim.Superseded}
+	if platform == string(core.PlatformSpotify) {
+		opts.Interval = fade.SpotifyInterval
+	}
+	return fade.Run(ctx, p, current, target, d, opts)
+}
+
+// fadeClaimDir is where fades, schedule ramps and sleep timers claim a
+// device's volume (see fade.Claim). fade.NewClaim creates it 0700.
+func fadeClaimDir() string {
+	return filepath.Join(config.StateDir(), "fades")
+}
+
+// volumeKey identifies the device whose volume a fade owns: its ID, taken
+// from the playback state when the player was chosen without a target, or
+// the platform when there is no device at all.
+func volumeKey(p core.Player, d *core.Device, state *core.PlaybackState) string {
+	if d != nil && d.ID != "" {
+		return d.ID
+	}
+	if state != nil && state.Device != nil && state.Device.ID != "" {
+		return state.Device.ID
+	}
+	return string(platformOf(p))
+}
@@ -2,10 +2,7 @@ package cli
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"os"
-	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/tessro/riff/internal/sonos"
@@ -48,194 +45,94 @@ var groupRemoveCmd = &cobra.Command{
 func init() {
 	groupAddCmd.Flags().StringVar(&groupTo, "to", "", "Target group coordinator (required)")
 	_ = groupAddCmd.MarkFlagRequired("to")
+	_ = groupAddCmd.RegisterFlagCompletionFunc("to", completeCoordinators)
+	groupAddCmd.ValidArgsFunction = completeRooms
+	groupRemoveCmd.ValidArgsFunction = completeRooms
 
 	groupCmd.AddCommand(groupListCmd)
 	groupCmd.AddCommand(groupAddCmd)
 	groupCmd.AddCommand(groupRemoveCmd)
 	rootCmd.AddCommand(groupCmd)
+
+	registerOutput(groupListCmd, groupListResult{})
+	registerOutput(groupAddCmd, groupResult{})
+	registerOutput(groupRemoveCmd, groupResult{})
 }
 
 func runGroupList(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
-	client := sonos.NewClient()
-
-	// Discover devices
-	devices, err := client.Discover(ctx)
+	groups, err := sonos.NewBackend(sonos.NewClient(), "").Groups(ctx)
 	if err != nil {
-		return fmt.Errorf("discovery failed: %w", err)
-	}
-
-	if len(devices) == 0 {
-		if JSONOutput() {
-			_ = json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
-				"groups": []interface{}{},
-			})
-		} else {
-			fmt.Println("No Sonos devices found")
-		}
-		return nil
+		return err
 	}
 
-	// Get zone groups from first device
-	groups, err := client.ListGroups(ctx, devices[0])
-	if err != nil {
-		return fmt.Errorf("failed to get groups: %w", err)
+	if groups == nil {
+		groups = []sonos.Group{}
 	}
+	return render(groupListResult{Groups: groups}, func() error {
+		if len(groups) == 0 {
+			fmt.Println("No groups found")
+			return nil
+		}
 
-	if JSONOutput() {
-		return json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
-			"groups": groups,
-		})
-	}
-
-	if len(groups) == 0 {
-		fmt.Println("No groups found")
-		return nil
-	}
-
-	for _, g := range groups {
-		if g.Coordinator != nil {
-			fmt.Printf("📻 %s", g.Name)
-			if len(g.Members) > 1 {
-				fmt.Printf(" (group of %d)", len(g.Members))
-			}
-			fmt.Println()
-
-			for _, m := range g.Members {
-				if m.UUID == g.Coordinator.UUID {
-					fmt.Printf("   └─ %s [coordinator]\n", m.Name)
-				} else {
-					fmt.Printf("   └─ %s\n", m.Name)
+		for _, g := range groups {
+			if g.Coordinator != nil {
+				fmt.Printf("📻 %s", g.Name)
+				if len(g.Members) > 1 {
+					fmt.Printf(" (group of %d)", len(g.Members))
+				}
+				fmt.Println()
+
+				for _, m := range g.Members {
+					if m.UUID == g.Coordinator.UUID {
+						fmt.Printf("   └─ %s [coordinator]\n", m.Name)
+					} else {
+						fmt.Printf("   └─ %s\n", m.Name)
+					}
 				}
 			}
 		}
-	}
+		return nil
+	})
+}
 
-	return nil
+// groupListResult is the output of riff group list.
+type groupListResult struct {
+	Groups []sonos.Group `json:"groups"`
 }
 
+func (r groupListResult) records() any { return r.Groups }
+
 func runGroupAdd(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 	speakerName := args[0]
 
-	client := sonos.NewClient()
-
-	// Discover devices
-	devices, err := client.Discover(ctx)
-	if err != nil {
-		return fmt.Errorf("discovery failed: %w", err)
-	}
-
-	if len(devices) == 0 {
-		return fmt.Errorf("no Sonos devices found")
-	}
-
-	// Get zone groups to find devices by name
-	groups, err := client.ListGroups(ctx, devices[0])
-	if err != nil {
-		return fmt.Errorf("failed to get groups: %w", err)
-	}
-
-	// Find the speaker to add
-	var speakerDevice *sonos.Device
-	var targetCoordinatorUUID string
-
-	for _, g := range groups {
-		for _, m := range g.Members {
-			if strings.EqualFold(m.Name, speakerName) {
-				speakerDevice = m
-			}
-			if strings.EqualFold(m.Name, groupTo) || strings.EqualFold(g.Name, groupTo) {
-				if g.Coordinator != nil {
-					targetCoordinatorUUID = g.Coordinator.UUID
-				}
-			}
-		}
-	}
-
-	if speakerDevice == nil {
-		return fmt.Errorf("speaker '%s' not found", speakerName)
-	}
-
-	if targetCoordinatorUUID == "" {
-		return fmt.Errorf("target group '%s' not found", groupTo)
+	if err := sonos.NewBackend(sonos.NewClient(), "").Join(ctx, speakerName, groupTo); err != nil {
+		return err
 	}
 
-	// Add speaker to group
-	if err := client.AddToGroup(ctx, speakerDevice, targetCoordinatorUUID); err != nil {
-		return fmt.Errorf("failed to add speaker to group: %w", err)
-	}
-
-	if JSONOutput() {
-		_ = json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
-			"status":  "added",
-			"speaker": speakerName,
-			"group":   groupTo,
-		})
-	} else {
-		fmt.Printf("Added '%s' to group '%s'\n", speakerName, groupTo)
-	}
-
-	return nil
+	return renderf(groupResult{Status: "added", Speaker: speakerName, Group: groupTo},
+		"Added '%s' to group '%s'", speakerName, groupTo)
 }
 
 func runGroupRemove(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 	speakerName := args[0]
 
-	client := sonos.NewClient()
-
-	// Discover devices
-	devices, err := client.Discover(ctx)
-	if err != nil {
-		return fmt.Errorf("discovery failed: %w", err)
-	}
-
-	if len(devices) == 0 {
-		return fmt.Errorf("no Sonos devices found")
-	}
-
-	// Get zone groups to find device by name
-	groups, err := client.ListGroups(ctx, devices[0])
+	groupName, err := sonos.NewBackend(sonos.NewClient(), "").Leave(ctx, speakerName)
 	if err != nil {
-		return fmt.Errorf("failed to get groups: %w", err)
+		return err
 	}
 
-	// Find the speaker to remove
-	var speakerDevice *sonos.Device
-	var groupName string
-
-	for _, g := range groups {
-		for _, m := range g.Members {
-			if strings.EqualFold(m.Name, speakerName) {
-				speakerDevice = m
-				groupName = g.Name
-				break
-			}
-		}
-		if speakerDevice != nil {
-			break
-		}
-	}
-
-	if speakerDevice == nil {
-		return fmt.Errorf("speaker '%s' not found", speakerName)
-	}
-
-	// Remove from group (make standalone)
-	if err := client.RemoveFromGroup(ctx, speakerDevice); err != nil {
-		return fmt.Errorf("failed to remove speaker from group: %w", err)
-	}
-
-	if JSONOutput() {
-		_ = json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
-			"status":  "removed",
-			"speaker": speakerName,
-		})
-	} else {
-		fmt.Printf("Removed '%s' from group '%s' (now standalone)\n", speakerName, groupName)
-	}
+	return renderf(groupResult{Status: "removed", Speaker: speakerName},
+		"Removed '%s' from group '%s' (now standalone)", speakerName, groupName)
+}
 
-	return nil
+// groupResult is the output of riff group add and remove. Group is the
+// group a speaker was added to.
+type groupResult struct {
+	Status  string `json:"status"`
+	Speaker string `json:"speaker"`
+	Group   string `json:"group,omitempty"`
 }
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/handoff"
+)
+
+var (
+	handoffFrom string
+	handoffTo   string
+)
+
+var handoffCmd = &cobra.Command{
+	Use:   "handoff",
+	Short: "Move what is playing to another device",
+	Long: `Move what is playing to another device, at the same position and volume,
+then pause where it was playing.
+
+From a Spotify Connect device, the album or playlist moves along with the
+track, so a Sonos room carries on through it. From a Sonos room, the
+Spotify tracks left in its queue move. Between Sonos rooms, the target joins
+the source's group and the source leaves, which works for any content.
+
+Without --from, hands off whatever is playing.
+
+Examples:
+  riff handoff --to Kitchen
+  riff handoff --from Kitchen --to "Office Mac"`,
+	Args: cobra.NoArgs,
+	RunE: runHandoff,
+}
+
+func init() {
+	handoffCmd.Flags().StringVar(&handoffFrom, "from", "", "Device to move playback from (default: whatever is playing)")
+	handoffCmd.Flags().StringVar(&handoffTo, "to", "", "Device to move playback to (required)")
+	_ = handoffCmd.MarkFlagRequired("to")
+	_ = handoffCmd.RegisterFlagCompletionFunc("from", completeDevices)
+	_ = handoffCmd.RegisterFlagCompletionFunc("to", completeDevices)
+	rootCmd.AddCommand(handoffCmd)
+	registerOutput(handoffCmd, handoffResult{})
+}
+
+// handoffGroups returns the Sonos backend for regrouping rooms, or nil
+// without one.
+func handoffGroups(b *backends) handoff.Groups {
+	if sb := b.Sonos(); sb != nil {
+		return sb
+	}
+	return nil
+}
+
+func runHandoff(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	b := newDirectBackends()
+	res, err := handoff.Handoff(ctx, b, handoffGroups(b), handoffFrom, handoffTo, handoff.Options{})
+	if err != nil {
+		return err
+	}
+
+	return renderf(handoffResult{Status: "handed_off", From: res.From.Name, To: res.To.Name, Session: res.Session},
+		"🔀 Moved playback from %s to %s", res.From.Name, res.To.Name)
+}
+
+// handoffResult is the output of riff handoff. Session is what moved, unless
+// a Sonos room was regrouped.
+type handoffResult struct {
+	Status  string        `json:"status"`
+	From    string        `json:"from"`
+	To      string        `json:"to"`
+	Session *core.Session `json:"session,omitempty"`
+}
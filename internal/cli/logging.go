@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+	"github.com/tessro/riff/internal/logging"
+)
+
+// logCloser closes the log file opened for the running command.
+var logCloser io.Closer
+
+func init() {
+	cobra.OnFinalize(closeLogging)
+}
+
+// setupLogging installs the default logger for cmd from the [log] config.
+// Every record carries a request ID, so one command's lines can be picked
+// out of a log file that several riff processes share. --verbose logs
+// everything to stderr instead.
+func setupLogging(cmd *cobra.Command) error {
+	opts := logging.Options{
+		Level:      cfg.Log.Level,
+		Format:     cfg.Log.Format,
+		File:       cfg.Log.File,
+		MaxSize:    cfg.Log.MaxSize,
+		MaxBackups: cfg.Log.MaxBackups,
+	}
+	if Verbose() {
+		opts.Level = "debug"
+		opts.File = ""
+	}
+	logger, closer, err := logging.New(opts)
+	if err != nil {
+		return err
+	}
+	logCloser = closer
+	slog.SetDefault(logger.With("request_id", newRequestID(), "command", cmd.CommandPath()))
+	slog.Debug("running command")
+	return nil
+}
+
+// closeLogging closes the log file, if the command opened one.
+func closeLogging() {
+	if logCloser != nil {
+		_ = logCloser.Close()
+		logCloser = nil
+	}
+}
+
+// newRequestID returns a short random ID for one command's log records.
+func newRequestID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
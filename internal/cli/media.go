@@ -0,0 +1,367 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tessro/riff/internal/core"
+	rifferrors "github.com/tessro/riff/internal/errors"
+	"github.com/tessro/riff/internal/upnp"
+)
+
+var (
+	mediaServer      string
+	mediaBrowseLimit int
+	mediaSearchLimit int
+	mediaRefresh     bool
+	mediaTo          string
+	mediaQueue       bool
+)
+
+var mediaCmd = &cobra.Command{
+	Use:   "media",
+	Short: "Browse UPnP/DLNA media servers",
+	Long: `Browse and search UPnP/DLNA media servers, such as a NAS, and play their
+content on a Sonos room, MPD host or UPnP renderer.
+
+Examples:
+  riff media servers
+  riff media browse --server NAS
+  riff media search "night drive"
+  riff media play '1$4$1' --to Kitchen
+  riff media play '1$4' --to Kitchen --queue`,
+}
+
+var mediaServersCmd = &cobra.Command{
+	Use:   "servers",
+	Short: "List media servers",
+	Args:  cobra.NoArgs,
+	RunE:  runMediaServers,
+}
+
+var mediaBrowseCmd = &cobra.Command{
+	Use:   "browse [id]",
+	Short: "List the contents of a folder",
+	Long:  `List the contents of a folder on a media server. Without an ID, lists the top level.`,
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runMediaBrowse,
+}
+
+var mediaSearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search a media server",
+	Long:  `Search a media server's titles, artists and albums.`,
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runMediaSearch,
+}
+
+var mediaPlayCmd = &cobra.Command{
+	Use:   "play <id>",
+	Short: "Play an item on a device",
+	Long: `Play an item from a media server on a Sonos room, MPD host or UPnP renderer.
+With --queue, the item, or every track in a folder, is added to the queue instead.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMediaPlay,
+}
+
+func init() {
+	mediaCmd.PersistentFlags().StringVar(&mediaServer, "server", "", "Media server name or ID (default: the only one found)")
+	mediaServersCmd.Flags().BoolVarP(&mediaRefresh, "refresh", "r", false, "Force refresh server list")
+	mediaBrowseCmd.Flags().IntVarP(&mediaBrowseLimit, "limit", "l", 100, "Maximum number of items")
+	mediaSearchCmd.Flags().IntVarP(&mediaSearchLimit, "limit", "l", 20, "Maximum number of results")
+	addTargetFlags(mediaPlayCmd, &mediaTo)
+	mediaPlayCmd.Flags().BoolVar(&mediaQueue, "queue", false, "Add to the queue instead of playing now")
+
+	mediaCmd.AddCommand(mediaServersCmd)
+	mediaCmd.AddCommand(mediaBrowseCmd)
+	mediaCmd.AddCommand(mediaSearchCmd)
+	mediaCmd.AddCommand(mediaPlayCmd)
+	rootCmd.AddCommand(mediaCmd)
+
+	registerOutput(mediaServersCmd, []mediaServerResult{})
+	registerOutput(mediaBrowseCmd, mediaObjectsResult{})
+	registerOutput(mediaSearchCmd, mediaObjectsResult{})
+	registerOutput(mediaPlayCmd, mediaPlayResult{})
+}
+
+func runMediaServers(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	lib := upnp.NewLibrary(0)
+	if mediaRefresh {
+		if err := lib.Refresh(ctx); err != nil {
+			return fmt.Errorf("discovery failed: %w", err)
+		}
+	}
+	servers, err := lib.Servers(ctx)
+	if err != nil {
+		return fmt.Errorf("discovery failed: %w", err)
+	}
+
+	output := make([]mediaServerResult, len(servers))
+	for i, s := range servers {
+		output[i] = mediaServerResult{ID: s.UUID, Name: s.Name, Manufacturer: s.Manufacturer, Model: s.Model}
+	}
+	return render(output, func() error {
+		if len(servers) == 0 {
+			fmt.Println("No media servers found")
+			return nil
+		}
+		for _, s := range servers {
+			fmt.Printf("🗄  %s\n", s.Name)
+			if Verbose() {
+				fmt.Printf("      ID: %s\n", s.UUID)
+				fmt.Printf("      Model: %s %s\n", s.Manufacturer, s.Model)
+			}
+		}
+		return nil
+	})
+}
+
+// mediaServerResult is a server in the output of riff media servers.
+type mediaServerResult struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Manufacturer string `json:"manufacturer"`
+	Model        string `json:"model"`
+}
+
+func runMediaBrowse(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	lib := upnp.NewLibrary(0)
+	server, err := lib.Server(ctx, mediaServer)
+	if err != nil {
+		return err
+	}
+
+	id := upnp.RootID
+	if len(args) > 0 {
+		id = args[0]
+	}
+	result, err := lib.ContentDirectory().Browse(ctx, server, id, 0, mediaBrowseLimit)
+	if err != nil {
+		return fmt.Errorf("failed to browse %s: %w", server.Name, err)
+	}
+	return outputMediaObjects(server, id, result)
+}
+
+func runMediaSearch(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	query := strings.Join(args, " ")
+
+	lib := upnp.NewLibrary(0)
+	server, err := lib.Server(ctx, mediaServer)
+	if err != nil {
+		return err
+	}
+
+	cd := lib.ContentDirectory()
+	caps, err := cd.SearchCapabilities(ctx, server)
+	if err != nil {
+		return fmt.Errorf("failed to get search capabilities: %w", err)
+	}
+	criteria := upnp.SearchCriteria(query, caps)
+	if criteria == "" {
+		return rifferrors.Unsupported("searching", server.Name, "Use 'riff media browse' to find items instead")
+	}
+
+	result, err := cd.Search(ctx, server, upnp.RootID, criteria, 0, mediaSearchLimit)
+	if err != nil {
+		return fmt.Errorf("search failed: %w", err)
+	}
+	if len(result.Objects) == 0 && !StructuredOutput() {
+		return fmt.Errorf("no results found for '%s'", query)
+	}
+	return outputMediaObjects(server, upnp.RootID, result)
+}
+
+func runMediaPlay(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	lib := upnp.NewLibrary(0)
+	server, err := lib.Server(ctx, mediaServer)
+	if err != nil {
+		return err
+	}
+
+	cd := lib.ContentDirectory()
+	obj, err := cd.Metadata(ctx, server, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to look up '%s': %w", args[0], err)
+	}
+
+	items := []upnp.Object{*obj}
+	if obj.Container {
+		if !mediaQueue {
+			return rifferrors.WithSuggestion(fmt.Errorf("'%s' is a folder", obj.Title),
+				"Browse it with 'riff media browse "+obj.ID+"', or add every track with --queue")
+		}
+		result, err := cd.Browse(ctx, server, obj.ID, 0, 0)
+		if err != nil {
+			return fmt.Errorf("failed to browse '%s': %w", obj.Title, err)
+		}
+		items = items[:0]
+		for _, o := range result.Objects {
+			if !o.Container && o.URL() != "" {
+				items = append(items, o)
+			}
+		}
+		if len(items) == 0 {
+			return fmt.Errorf("no playable tracks in '%s'", obj.Title)
+		}
+	} else if obj.URL() == "" {
+		return fmt.Errorf("'%s' has no playable resource", obj.Title)
+	}
+
+	p, d, err := resolvePlayer(ctx, mediaTo)
+	if err != nil {
+		return err
+	}
+
+	status := "playing"
+	if mediaQueue {
+		status = "queued"
+		for _, o := range items {
+			if err := queueMedia(ctx, p, o); err != nil {
+				return fmt.Errorf("failed to add '%s' to queue: %w", o.Title, err)
+			}
+		}
+	} else if err := playMedia(ctx, p, *obj); err != nil {
+		return fmt.Errorf("failed to play '%s': %w", obj.Title, err)
+	}
+
+	name := mediaTo
+	if d != nil {
+		name = d.Name
+	}
+	res := mediaPlayResult{Status: status, ID: obj.ID, Title: obj.Title, Tracks: len(items), Device: name}
+	return render(res, func() error {
+		switch {
+		case !mediaQueue && name != "":
+			fmt.Printf("▶ Playing %s on %s\n", mediaTitle(*obj), name)
+		case !mediaQueue:
+			fmt.Printf("▶ Playing %s\n", mediaTitle(*obj))
+		case len(items) == 1:
+			fmt.Printf("Added to queue: %s\n", mediaTitle(items[0]))
+		default:
+			fmt.Printf("Added %d tracks from %s to queue\n", len(items), obj.Title)
+		}
+		return nil
+	})
+}
+
+// mediaPlayResult is the output of riff media play.
+type mediaPlayResult struct {
+	Status string `json:"status"`
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Tracks int    `json:"tracks"`
+	Device string `json:"device"`
+}
+
+// playMedia plays o on p, passing its DIDL-Lite description when the
+// player can use it.
+func playMedia(ctx context.Context, p core.Player, o upnp.Object) error {
+	if mp, ok := p.(core.MediaPlayer); ok {
+		return mp.PlayMedia(ctx, o.URL(), upnp.BuildDIDL(o))
+	}
+	if err := core.Require(p, core.CapStream); err != nil {
+		return err
+	}
+	return p.(core.StreamPlayer).PlayURI(ctx, o.URL())
+}
+
+// queueMedia adds o to p's queue, passing its DIDL-Lite description when
+// the player can use it.
+func queueMedia(ctx context.Context, p core.Player, o upnp.Object) error {
+	if mq, ok := p.(core.MediaQueuer); ok {
+		return mq.QueueMedia(ctx, o.URL(), upnp.BuildDIDL(o))
+	}
+	// Only players that fetch URLs themselves can queue a server's resources
+	if err := core.Require(p, core.CapQueueAdd|core.CapStream); err != nil {
+		return err
+	}
+	return p.AddToQueue(ctx, o.URL())
+}
+
+// mediaObjectsResult is the output of riff media browse and search.
+type mediaObjectsResult struct {
+	Server string            `json:"server"`
+	ID     string            `json:"id"`
+	Total  int               `json:"total"`
+	Items  []mediaObjectInfo `json:"items"`
+}
+
+func (r mediaObjectsResult) records() any { return r.Items }
+
+// mediaObjectInfo is a track or folder on a media server. URL and
+// DurationMS are set for playable items.
+type mediaObjectInfo struct {
+	ID         string `json:"id"`
+	ParentID   string `json:"parent_id"`
+	Title      string `json:"title"`
+	Class      string `json:"class"`
+	Container  bool   `json:"container"`
+	Artist     string `json:"artist,omitempty"`
+	Album      string `json:"album,omitempty"`
+	URL        string `json:"url,omitempty"`
+	DurationMS int64  `json:"duration_ms,omitempty"`
+}
+
+func outputMediaObjects(server *upnp.MediaServer, id string, result *upnp.BrowseResult) error {
+	res := mediaObjectsResult{
+		Server: server.Name,
+		ID:     id,
+		Total:  result.TotalMatches,
+		Items:  make([]mediaObjectInfo, len(result.Objects)),
+	}
+	for i, o := range result.Objects {
+		res.Items[i] = mediaObjectInfo{
+			ID:        o.ID,
+			ParentID:  o.ParentID,
+			Title:     o.Title,
+			Class:     o.Class,
+			Container: o.Container,
+			Artist:    o.ArtistName(),
+			Album:     o.Album,
+			URL:       o.URL(),
+		}
+		if res.Items[i].URL != "" {
+			res.Items[i].DurationMS = o.Duration().Milliseconds()
+		}
+	}
+	return render(res, func() error { return outputMediaTable(result) })
+}
+
+func outputMediaTable(result *upnp.BrowseResult) error {
+	if len(result.Objects) == 0 {
+		fmt.Println("Empty")
+		return nil
+	}
+
+	t := NewTable("", "TITLE", "ID")
+	for _, o := range result.Objects {
+		icon := "🎵"
+		if o.Container {
+			icon = "📁"
+		}
+		t.Row(icon, mediaTitle(o), o.ID)
+	}
+	t.Flush()
+
+	if result.TotalMatches > len(result.Objects) {
+		fmt.Printf("\nShowing %d of %d (use --limit for more)\n", len(result.Objects), result.TotalMatches)
+	}
+	return nil
+}
+
+// mediaTitle formats an object as "Title — Artist" when the artist is known.
+func mediaTitle(o upnp.Object) string {
+	if artist := o.ArtistName(); artist != "" && !o.Container {
+		return fmt.Sprintf("%s — %s", o.Title, artist)
+	}
+	return o.Title
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"github.com/tessro/riff/internal/metrics"
+)
+
+// metricsListen is set by --metrics-listen on long-running commands.
+var metricsListen string
+
+// addMetricsFlag adds --metrics-listen to a long-running command.
+func addMetricsFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&metricsListen, "metrics-listen", "", "serve Prometheus metrics on this address (default from [metrics] listen)")
+}
+
+// startMetrics serves /metrics until ctx is done when --metrics-listen or
+// [metrics] listen is set. It binds before returning so a bad address fails
+// the command up front.
+func startMetrics(ctx context.Context) error {
+	addr := metricsListen
+	if addr == "" {
+		addr = cfg.Metrics.Listen
+	}
+	if addr == "" {
+		return nil
+	}
+
+	ln, err := metrics.Listen(addr)
+	if err != nil {
+		return err
+	}
+	go func() { _ = metrics.Serve(ctx, ln) }()
+	return nil
+}
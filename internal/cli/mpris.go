@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/spf13/cobra"
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/mpris"
+	"github.com/tessro/riff/internal/tail"
+)
+
+var (
+	mprisDevice   string
+	mprisInterval time.Duration
+)
+
+var mprisCmd = &cobra.Command{
+	Use:   "mpris",
+	Short: "Expose playback over D-Bus for media keys and desktop widgets",
+	Long: `Register riff on the D-Bus session bus as an MPRIS media player, so media
+keys, GNOME and KDE widgets and playerctl can control the target device.
+
+riff mpris runs in the foreground until interrupted, or until a client asks it
+to quit. Start it from your desktop's autostart or a systemd user unit.
+
+Examples:
+  riff mpris
+  riff mpris --to Kitchen
+  playerctl --player=riff play-pause`,
+	Args: cobra.NoArgs,
+	RunE: runMPRIS,
+}
+
+func init() {
+	addTargetFlags(mprisCmd, &mprisDevice)
+	mprisCmd.Flags().DurationVarP(&mprisInterval, "interval", "i", time.Second, "poll interval")
+	addMetricsFlag(mprisCmd)
+
+	rootCmd.AddCommand(mprisCmd)
+	registerOutput(mprisCmd, mprisResult{})
+}
+
+func runMPRIS(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := startMetrics(ctx); err != nil {
+		return err
+	}
+
+	player, d, err := resolvePlayer(ctx, mprisDevice)
+	if err != nil {
+		return err
+	}
+
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return fmt.Errorf("failed to connect to session bus: %w", err)
+	}
+	defer conn.Close()
+
+	server := mpris.NewServer(conn, player)
+	if err := server.Start(ctx); err != nil {
+		return err
+	}
+	defer server.Close()
+
+	name := mprisDevice
+	if d != nil {
+		name = d.Name
+	}
+	if err := render(mprisResult{
+		Status:   "registered",
+		BusName:  mpris.BusName,
+		Device:   name,
+		Platform: platformOf(player),
+	}, func() error {
+		if name != "" {
+			fmt.Printf("Registered %s for %s (Ctrl+C to stop)\n", mpris.BusName, name)
+		} else {
+			fmt.Printf("Registered %s (Ctrl+C to stop)\n", mpris.BusName)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	watcher := tail.NewWatcher(player, mprisInterval)
+	go func() { _ = watcher.Start(ctx) }()
+
+	if err := server.Run(ctx, watcher.Events()); err != nil && err != context.Canceled {
+		return err
+	}
+	return nil
+}
+
+// mprisResult is what riff mpris outputs once it is registered.
+type mprisResult struct {
+	Status   string        `json:"status"`
+	BusName  string        `json:"bus_name"`
+	Device   string        `json:"device"`
+	Platform core.Platform `json:"platform"`
+}
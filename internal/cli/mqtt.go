@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tessro/riff/internal/mqtt"
+)
+
+var (
+	mqttBroker   string
+	mqttInterval time.Duration
+)
+
+var mqttCmd = &cobra.Command{
+	Use:   "mqtt",
+	Short: "Bridge playback to an MQTT broker for Home Assistant",
+	Long: `Publish each device's playback state to MQTT and accept commands, so home
+automation can see and control every Spotify device, Sonos room, MPD output
+and UPnP renderer.
+
+State is retained under <topic_prefix>/<device>/, and commands are read
+from <topic_prefix>/<device>/set/<command> (play, pause, playpause, next,
+previous, volume, seek, playmedia). <topic_prefix>/status reports online or
+offline, and the broker marks the bridge offline if riff goes away.
+
+Home Assistant discovery configs are published for every device, in the
+format of the MQTT Media Player integration, so each one appears as a
+media_player entity. Configure the broker in the [mqtt] config section.
+Like riffd, riff mqtt keeps its own backends warm and doesn't use the daemon.
+
+Examples:
+  riff mqtt
+  riff mqtt --broker tcp://homeassistant.local:1883
+  mosquitto_pub -t riff/rincon_1/set/volume -m 0.3`,
+	Args: cobra.NoArgs,
+	RunE: runMQTT,
+}
+
+func init() {
+	mqttCmd.Flags().StringVar(&mqttBroker, "broker", "", "broker URL (default from config, tcp://localhost:1883)")
+	mqttCmd.Flags().DurationVarP(&mqttInterval, "interval", "i", time.Second, "poll interval")
+	addMetricsFlag(mqttCmd)
+
+	rootCmd.AddCommand(mqttCmd)
+	registerOutput(mqttCmd, mqttResult{})
+}
+
+func runMQTT(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := startMetrics(ctx); err != nil {
+		return err
+	}
+
+	c := cfg.MQTT
+	if mqttBroker != "" {
+		c.Broker = mqttBroker
+	}
+
+	bridge := mqtt.New(newDirectBackends(), mqtt.Options{
+		Broker:           c.Broker,
+		ClientID:         c.ClientID,
+		Username:         c.Username,
+		Password:         c.Password,
+		TopicPrefix:      c.TopicPrefix,
+		DiscoveryPrefix:  c.DiscoveryPrefix,
+		DisableDiscovery: c.DisableDiscovery,
+		Interval:         mqttInterval,
+		RefreshInterval:  time.Duration(c.RefreshInterval) * time.Second,
+	})
+
+	if err := renderf(mqttResult{Status: "bridging", Broker: c.Broker, Prefix: c.TopicPrefix},
+		"Bridging playback to %s under %s/", c.Broker, c.TopicPrefix); err != nil {
+		return err
+	}
+
+	return bridge.Run(ctx)
+}
+
+// mqttResult is what riff mqtt outputs once it is connected.
+type mqttResult struct {
+	Status string `json:"status"`
+	Broker string `json:"broker"`
+	Prefix string `json:"prefix"`
+}
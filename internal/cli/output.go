@@ -16,6 +16,10 @@ const (
 	OutputMinimal
 	OutputTable
 	OutputJSON
+	OutputNDJSON
+	OutputYAML
+	OutputCSV
+	OutputTemplate
 )
 
 var outputMode = OutputNormal
@@ -27,12 +31,15 @@ func SetOutputMode(mode OutputMode) {
 
 // GetOutputMode returns the current output mode.
 func GetOutputMode() OutputMode {
-	if JSONOutput() {
-		return OutputJSON
-	}
 	return outputMode
 }
 
+// StructuredOutput reports whether output is for programs rather than
+// people, as with --json or --output.
+func StructuredOutput() bool {
+	return outputMode != OutputNormal && outputMode != OutputMinimal
+}
+
 // Table provides a simple table formatter.
 type Table struct {
 	w       *tabwriter.Writer
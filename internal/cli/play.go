@@ -2,16 +2,15 @@ package cli
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"os"
+	"log/slog"
 	"strings"
 
 	"github.com/charmbracelet/huh"
 	"github.com/spf13/cobra"
 	"github.com/tessro/riff/internal/core"
+	rifferrors "github.com/tessro/riff/internal/errors"
 	"github.com/tessro/riff/internal/sonos"
-	"github.com/tessro/riff/internal/spotify/auth"
 	"github.com/tessro/riff/internal/spotify/client"
 	"github.com/tessro/riff/internal/spotify/player"
 )
@@ -20,7 +19,7 @@ import (
 type resolvedDevice struct {
 	Platform    core.Platform
 	SpotifyID   string        // Populated if Platform == PlatformSpotify
-	SonosDevice *sonos.Device // Populated if Platform == PlatformSonos
+	SonosPlayer *sonos.Player // Populated if Platform == PlatformSonos
 	Name        string
 }
 
@@ -31,6 +30,7 @@ var (
 	playArtist   bool
 	playURI      string
 	playShuffle  bool
+	playStream   string
 )
 
 var playCmd = &cobra.Command{
@@ -44,7 +44,8 @@ Examples:
   riff play "bohemian rhapsody" # Search and play a track
   riff play --album "abbey road" # Search and play an album
   riff play --uri spotify:track:xxx # Play specific URI
-  riff play --to "Kitchen"     # Resume on specific device`,
+  riff play --to "Kitchen"     # Resume on specific device
+  riff play --stream https://example.com/radio.mp3 --to "Living Room TV"`,
 	RunE: runPlay,
 }
 
@@ -55,33 +56,34 @@ func init() {
 	playCmd.Flags().BoolVar(&playArtist, "artist", false, "Search for artists")
 	playCmd.Flags().StringVar(&playURI, "uri", "", "Play specific Spotify URI")
 	playCmd.Flags().BoolVar(&playShuffle, "shuffle", false, "Enable shuffle mode")
+	playCmd.Flags().StringVar(&playStream, "stream", "", "Play an HTTP stream URL (Sonos, MPD or UPnP renderers)")
+	playCmd.MarkFlagsMutuallyExclusive("stream", "uri")
+	_ = playCmd.RegisterFlagCompletionFunc("to", completeDevices)
+	playCmd.ValidArgsFunction = completePlayQuery
 	rootCmd.AddCommand(playCmd)
+	registerOutput(playCmd, playResult{})
+}
+
+// completePlayQuery completes saved playlists after --playlist. Other
+// queries are searched, so there is nothing to offer.
+func completePlayQuery(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if playPlaylist && len(args) == 0 {
+		return completePlaylists(cmd, args, toComplete)
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
 }
 
 func runPlay(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
-	if cfg.Spotify.ClientID == "" {
-		return fmt.Errorf("spotify not configured")
+	// Streams go straight to the device and need no Spotify login
+	if playStream != "" {
+		return runPlayStream(ctx)
 	}
 
-	storage, err := auth.NewTokenStorage("")
+	spotifyClient, err := getSpotifyClient()
 	if err != nil {
-		return fmt.Errorf("failed to initialize token storage: %w", err)
-	}
-
-	spotifyClient := client.New(cfg.Spotify.ClientID, storage)
-	if Verbose() {
-		spotifyClient.SetVerbose(true, func(format string, args ...interface{}) {
-			fmt.Fprintf(os.Stderr, format+"\n", args...)
-		})
-	}
-	if err := spotifyClient.LoadToken(); err != nil {
-		return fmt.Errorf("failed to load token: %w", err)
-	}
-
-	if !spotifyClient.HasToken() {
-		return fmt.Errorf("not authenticated. Run 'riff auth login' first")
+		return err
 	}
 
 	// Resolve target device if specified
@@ -109,9 +111,7 @@ func runPlay(cmd *cobra.Command, args []string) error {
 	// Set shuffle if requested
 	if playShuffle {
 		if err := spotifyClient.SetShuffle(ctx, true, ""); err != nil {
-			if Verbose() {
-				fmt.Fprintf(os.Stderr, "Warning: could not enable shuffle: %v\n", err)
-			}
+			slog.WarnContext(ctx, "could not enable shuffle", "error", err)
 		}
 	}
 
@@ -134,26 +134,46 @@ func runPlay(cmd *cobra.Command, args []string) error {
 	return searchAndPlay(ctx, spotifyClient, p, query)
 }
 
+// runPlayStream sends an HTTP stream URL to a device that can fetch it
+// itself.
+func runPlayStream(ctx context.Context) error {
+	if !strings.HasPrefix(playStream, "http://") && !strings.HasPrefix(playStream, "https://") {
+		return fmt.Errorf("invalid stream URL %q: must start with http:// or https://", playStream)
+	}
+
+	p, d, err := resolvePlayer(ctx, playTo)
+	if err != nil {
+		return err
+	}
+	if err := core.Require(p, core.CapStream); err != nil {
+		return err
+	}
+	if err := p.(core.StreamPlayer).PlayURI(ctx, playStream); err != nil {
+		return fmt.Errorf("failed to play stream: %w", err)
+	}
+
+	name := playTo
+	if d != nil {
+		name = d.Name
+	}
+	res := playResult{Status: "playing", URI: playStream, Device: name, Platform: platformOf(p)}
+	if name != "" {
+		return renderf(res, "▶ Streaming %s on %s", playStream, name)
+	}
+	return renderf(res, "▶ Streaming %s", playStream)
+}
+
 // runPlaySonos handles playback to a Sonos device directly.
 func runPlaySonos(ctx context.Context, spotifyClient *client.Client, device *resolvedDevice, args []string) error {
-	sonosClient := sonos.NewClient()
-	sonosPlayer := sonos.NewPlayer(sonosClient, device.SonosDevice)
+	sonosPlayer := device.SonosPlayer
 
 	// Handle URI playback
 	if playURI != "" {
 		if err := sonosPlayer.PlayURI(ctx, playURI); err != nil {
 			return fmt.Errorf("failed to play on Sonos: %w", err)
 		}
-		if JSONOutput() {
-			_ = json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
-				"status": "playing",
-				"uri":    playURI,
-				"device": device.Name,
-			})
-		} else {
-			fmt.Printf("▶ Playing %s on %s (Sonos)\n", playURI, device.Name)
-		}
-		return nil
+		return renderf(playResult{Status: "playing", URI: playURI, Device: device.Name},
+			"▶ Playing %s on %s (Sonos)", playURI, device.Name)
 	}
 
 	query := strings.Join(args, " ")
@@ -162,10 +182,8 @@ func runPlaySonos(ctx context.Context, spotifyClient *client.Client, device *res
 		if err := sonosPlayer.Play(ctx); err != nil {
 			return fmt.Errorf("failed to resume on Sonos: %w", err)
 		}
-		if !JSONOutput() {
-			fmt.Printf("▶ Resumed playback on %s (Sonos)\n", device.Name)
-		}
-		return nil
+		return renderf(playResult{Status: "playing", Device: device.Name},
+			"▶ Resumed playback on %s (Sonos)", device.Name)
 	}
 
 	// Search using Spotify, then play on Sonos
@@ -237,54 +255,26 @@ func searchAndPlaySonos(ctx context.Context, c *client.Client, sonosPlayer *sono
 		return fmt.Errorf("failed to play on Sonos: %w", err)
 	}
 
-	if JSONOutput() {
-		output := map[string]interface{}{
-			"status": "playing",
-			"type":   searchType,
-			"name":   name,
-			"uri":    uri,
-			"device": deviceName,
-		}
-		if artist != "" {
-			output["artist"] = artist
-		}
-		_ = json.NewEncoder(os.Stdout).Encode(output)
-	} else {
-		if artist != "" {
-			fmt.Printf("▶ Playing %s: %s by %s on %s (Sonos)\n", searchType, name, artist, deviceName)
-		} else {
-			fmt.Printf("▶ Playing %s: %s on %s (Sonos)\n", searchType, name, deviceName)
-		}
+	res := playResult{Status: "playing", Type: string(searchType), Name: name, Artist: artist, URI: uri, Device: deviceName}
+	if artist != "" {
+		return renderf(res, "▶ Playing %s: %s by %s on %s (Sonos)", searchType, name, artist, deviceName)
 	}
-
-	return nil
+	return renderf(res, "▶ Playing %s: %s on %s (Sonos)", searchType, name, deviceName)
 }
 
 // playWithFallback attempts to play and falls back to default device on 404
 func playWithFallback(ctx context.Context, c *client.Client, p *player.Player, playFunc func() error, uri, playType string) error {
 	err := playFunc()
 	if err == nil {
-		if playType == "resume" && !JSONOutput() {
-			fmt.Println("▶ Resumed playback")
-		} else if playType == "uri" {
-			if JSONOutput() {
-				_ = json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
-					"status": "playing",
-					"uri":    uri,
-				})
-			} else {
-				fmt.Printf("▶ Playing %s\n", uri)
-			}
+		if playType == "uri" {
+			return renderf(playResult{Status: "playing", URI: uri}, "▶ Playing %s", uri)
 		}
-		return nil
+		return renderf(playResult{Status: "playing"}, "▶ Resumed playback")
 	}
 
 	// Handle 403 for resume - Spotify returns 403 when already playing
 	if playType == "resume" && client.IsAlreadyPlayingError(err) {
-		if !JSONOutput() {
-			fmt.Println("▶ Already playing")
-		}
-		return nil
+		return renderf(playResult{Status: "playing"}, "▶ Already playing")
 	}
 
 	// Check if error is "no active device"
@@ -306,9 +296,7 @@ func playWithFallback(ctx context.Context, c *client.Client, p *player.Player, p
 			return err
 		}
 	} else {
-		if Verbose() {
-			fmt.Fprintf(os.Stderr, "No active device, transferring to default: %s\n", defaultDeviceName)
-		}
+		slog.DebugContext(ctx, "no active device, transferring to default", "device", defaultDeviceName)
 
 		// Resolve default device
 		resolved, err := resolveDevice(ctx, c, defaultDeviceName)
@@ -336,24 +324,11 @@ func playWithFallback(ctx context.Context, c *client.Client, p *player.Player, p
 		return fmt.Errorf("failed to play on default device: %w", err)
 	}
 
-	switch playType {
-	case "resume":
-		if !JSONOutput() {
-			fmt.Printf("▶ Resumed playback on %s\n", deviceName)
-		}
-	case "uri":
-		if JSONOutput() {
-			_ = json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
-				"status": "playing",
-				"uri":    uri,
-				"device": deviceName,
-			})
-		} else {
-			fmt.Printf("▶ Playing %s on %s\n", uri, deviceName)
-		}
+	if playType == "uri" {
+		return renderf(playResult{Status: "playing", URI: uri, Device: deviceName},
+			"▶ Playing %s on %s", uri, deviceName)
 	}
-
-	return nil
+	return renderf(playResult{Status: "playing", Device: deviceName}, "▶ Resumed playback on %s", deviceName)
 }
 
 func playByURIInternal(ctx context.Context, p *player.Player, uri string) error {
@@ -418,8 +393,7 @@ func searchAndPlay(ctx context.Context, c *client.Client, p *player.Player, quer
 func playSearchResultWithFallback(ctx context.Context, c *client.Client, p *player.Player, playFunc func() error, itemType, name, artist, uri string) error {
 	err := playFunc()
 	if err == nil {
-		outputPlayResult(itemType, name, artist, uri)
-		return nil
+		return outputPlayResult(itemType, name, artist, uri, "")
 	}
 
 	// Check if error is "no active device"
@@ -438,9 +412,7 @@ func playSearchResultWithFallback(ctx context.Context, c *client.Client, p *play
 			return err
 		}
 	} else {
-		if Verbose() {
-			fmt.Fprintf(os.Stderr, "No active device, transferring to default: %s\n", defaultDeviceName)
-		}
+		slog.DebugContext(ctx, "no active device, transferring to default", "device", defaultDeviceName)
 
 		// Resolve default device
 		resolved, err := resolveDevice(ctx, c, defaultDeviceName)
@@ -469,141 +441,59 @@ func playSearchResultWithFallback(ctx context.Context, c *client.Client, p *play
 		return fmt.Errorf("failed to play %s on default device: %w", itemType, err)
 	}
 
-	outputPlayResultWithDevice(itemType, name, artist, uri, deviceName)
-	return nil
+	return outputPlayResult(itemType, name, artist, uri, deviceName)
 }
 
-func outputPlayResult(itemType, name, artist, uri string) {
-	if JSONOutput() {
-		output := map[string]interface{}{
-			"status": "playing",
-			"type":   itemType,
-			"name":   name,
-			"uri":    uri,
-		}
-		if artist != "" {
-			output["artist"] = artist
-		}
-		_ = json.NewEncoder(os.Stdout).Encode(output)
-	} else {
-		if artist != "" {
-			fmt.Printf("▶ Playing %s: %s by %s\n", itemType, name, artist)
-		} else {
-			fmt.Printf("▶ Playing %s: %s\n", itemType, name)
-		}
-	}
+// playResult is the output of riff play. Type, Name and Artist describe
+// what a search found; fields that don't apply are left out.
+type playResult struct {
+	Status   string        `json:"status"`
+	Type     string        `json:"type,omitempty"`
+	Name     string        `json:"name,omitempty"`
+	Artist   string        `json:"artist,omitempty"`
+	URI      string        `json:"uri,omitempty"`
+	Device   string        `json:"device,omitempty"`
+	Platform core.Platform `json:"platform,omitempty"`
 }
 
-func outputPlayResultWithDevice(itemType, name, artist, uri, device string) {
-	if JSONOutput() {
-		output := map[string]interface{}{
-			"status": "playing",
-			"type":   itemType,
-			"name":   name,
-			"uri":    uri,
-			"device": device,
-		}
-		if artist != "" {
-			output["artist"] = artist
-		}
-		_ = json.NewEncoder(os.Stdout).Encode(output)
-	} else {
-		if artist != "" {
-			fmt.Printf("▶ Playing %s: %s by %s on %s\n", itemType, name, artist, device)
-		} else {
-			fmt.Printf("▶ Playing %s: %s on %s\n", itemType, name, device)
-		}
+// outputPlayResult reports a search result playing, on device if set.
+func outputPlayResult(itemType, name, artist, uri, device string) error {
+	text := fmt.Sprintf("▶ Playing %s: %s", itemType, name)
+	if artist != "" {
+		text += " by " + artist
 	}
+	if device != "" {
+		text += " on " + device
+	}
+	return renderf(playResult{Status: "playing", Type: itemType, Name: name, Artist: artist, URI: uri, Device: device}, "%s", text)
 }
 
+// resolveDevice resolves a device name or ID across Spotify and Sonos.
 func resolveDevice(ctx context.Context, c *client.Client, nameOrID string) (*resolvedDevice, error) {
-	devices, err := c.GetDevices(ctx)
+	b := newBackendsWithSpotify(c)
+	backend, d, err := b.Resolve(ctx, nameOrID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get devices: %w", err)
-	}
-
-	// First try exact ID match in Spotify
-	for _, d := range devices {
-		if d.ID == nameOrID {
-			return &resolvedDevice{
-				Platform:  core.PlatformSpotify,
-				SpotifyID: d.ID,
-				Name:      d.Name,
-			}, nil
-		}
-	}
-
-	// Then try case-insensitive name match in Spotify
-	nameLower := strings.ToLower(nameOrID)
-	for _, d := range devices {
-		if strings.ToLower(d.Name) == nameLower {
-			return &resolvedDevice{
-				Platform:  core.PlatformSpotify,
-				SpotifyID: d.ID,
-				Name:      d.Name,
-			}, nil
-		}
-	}
-
-	// Try partial name match in Spotify
-	for _, d := range devices {
-		if strings.Contains(strings.ToLower(d.Name), nameLower) {
-			return &resolvedDevice{
-				Platform:  core.PlatformSpotify,
-				SpotifyID: d.ID,
-				Name:      d.Name,
-			}, nil
-		}
-	}
-
-	// Not found in Spotify - try Sonos
-	if sonosDevice := findSonosDevice(ctx, nameOrID); sonosDevice != nil {
-		return &resolvedDevice{
-			Platform:    core.PlatformSonos,
-			SonosDevice: sonosDevice,
-			Name:        sonosDevice.Name,
-		}, nil
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("device '%s' not found", nameOrID)
-}
-
-// findSonosDevice finds a device on the local Sonos network.
-func findSonosDevice(ctx context.Context, nameOrID string) *sonos.Device {
-	sonosClient := sonos.NewClient()
-	sonosDevices, err := sonosClient.Discover(ctx)
-	if err != nil || len(sonosDevices) == 0 {
-		return nil
+	resolved := &resolvedDevice{
+		Platform: d.Platform,
+		Name:     d.Name,
 	}
-
-	// Get zone groups for device names
-	groups, err := sonosClient.ListGroups(ctx, sonosDevices[0])
-	if err != nil {
-		// Fall back to basic device info
-		nameLower := strings.ToLower(nameOrID)
-		for _, d := range sonosDevices {
-			if d.UUID == nameOrID ||
-				strings.ToLower(d.Name) == nameLower ||
-				strings.Contains(strings.ToLower(d.Name), nameLower) {
-				return d
-			}
-		}
-		return nil
-	}
-
-	// Check zone group members
-	nameLower := strings.ToLower(nameOrID)
-	for _, g := range groups {
-		for _, m := range g.Members {
-			if m.UUID == nameOrID ||
-				strings.ToLower(m.Name) == nameLower ||
-				strings.Contains(strings.ToLower(m.Name), nameLower) {
-				return m
-			}
+	switch d.Platform {
+	case core.PlatformSpotify:
+		resolved.SpotifyID = d.ID
+	case core.PlatformSonos:
+		p, err := backend.PlayerFor(ctx, d)
+		if err != nil {
+			return nil, err
 		}
+		resolved.SonosPlayer = p.(*sonos.Player)
+	default:
+		return nil, rifferrors.Unsupported("playing Spotify content", d.Name,
+			"Use 'riff play --stream <url> --to "+d.Name+"' to play a stream URL instead")
 	}
-
-	return nil
+	return resolved, nil
 }
 
 // selectDevice shows an interactive picker for device selection
@@ -2,18 +2,17 @@ package cli
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"os"
 	"strconv"
 
 	"github.com/spf13/cobra"
-	"github.com/tessro/riff/internal/spotify/auth"
-	"github.com/tessro/riff/internal/spotify/client"
-	"github.com/tessro/riff/internal/spotify/player"
+	"github.com/tessro/riff/internal/core"
 )
 
-var queueLimit int
+var (
+	queueLimit  int
+	queueDevice string
+)
 
 var queueCmd = &cobra.Command{
 	Use:   "queue",
@@ -29,16 +28,22 @@ var queueAddCmd = &cobra.Command{
 
 Examples:
   riff queue add "bohemian rhapsody"
-  riff queue add --uri spotify:track:xxx`,
-	Args: cobra.MinimumNArgs(1),
+  riff queue add --uri spotify:track:xxx
+  riff queue add --to Office --uri "Album/01 Track.flac"`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if queueAddURI != "" {
+			return nil
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
 	RunE: runQueueAdd,
 }
 
 var queueRemoveCmd = &cobra.Command{
 	Use:   "remove <index>",
 	Short: "Remove a track from the queue",
-	Long: `Remove a track at the specified position from the queue.
-Note: Spotify API does not support queue removal. This is a placeholder.`,
+	Long: `Remove a track at the specified position (as numbered by 'riff queue').
+Requires a device that can edit its queue, such as a Sonos room.`,
 	Args: cobra.ExactArgs(1),
 	RunE: runQueueRemove,
 }
@@ -47,15 +52,15 @@ var queueClearCmd = &cobra.Command{
 	Use:   "clear",
 	Short: "Clear the queue",
 	Long: `Clear all tracks from the queue.
-Note: Spotify API does not support queue clearing. This is a placeholder.`,
+Requires a device that can edit its queue, such as a Sonos room.`,
 	RunE: runQueueClear,
 }
 
 var queueMoveCmd = &cobra.Command{
 	Use:   "move <from> <to>",
 	Short: "Move a track in the queue",
-	Long: `Move a track from one position to another.
-Note: Spotify API does not support queue reordering. This is a placeholder.`,
+	Long: `Move a track from one position to another (as numbered by 'riff queue').
+Requires a device that can edit its queue, such as a Sonos room.`,
 	Args: cobra.ExactArgs(2),
 	RunE: runQueueMove,
 }
@@ -65,90 +70,110 @@ var queueAddURI string
 func init() {
 	queueCmd.Flags().IntVarP(&queueLimit, "limit", "l", 20, "Maximum number of tracks to show")
 	queueAddCmd.Flags().StringVar(&queueAddURI, "uri", "", "Add specific Spotify URI to queue")
+	for _, c := range []*cobra.Command{queueCmd, queueAddCmd, queueRemoveCmd, queueClearCmd, queueMoveCmd} {
+		addTargetFlags(c, &queueDevice)
+	}
 
 	queueCmd.AddCommand(queueAddCmd)
 	queueCmd.AddCommand(queueRemoveCmd)
 	queueCmd.AddCommand(queueClearCmd)
 	queueCmd.AddCommand(queueMoveCmd)
 	rootCmd.AddCommand(queueCmd)
+
+	registerOutput(queueCmd, queueResult{})
+	registerOutput(queueAddCmd, queueAddResult{})
+	registerOutput(queueRemoveCmd, queueRemoveResult{})
+	registerOutput(queueClearCmd, actionResult{})
+	registerOutput(queueMoveCmd, queueMoveResult{})
 }
 
 func runQueueList(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
-	spotifyClient, err := getSpotifyClient()
+	p, _, err := resolvePlayer(ctx, queueDevice)
 	if err != nil {
 		return err
 	}
+	if err := core.Require(p, core.CapQueueRead); err != nil {
+		return err
+	}
 
-	p := player.New(spotifyClient)
 	queue, err := p.GetQueue(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get queue: %w", err)
 	}
 
-	if queue.IsEmpty() {
-		if JSONOutput() {
-			_ = json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
-				"queue":   []interface{}{},
-				"message": "Queue is empty",
-			})
-		} else {
-			fmt.Println("Queue is empty")
-		}
-		return nil
-	}
-
 	// Apply limit
 	tracks := queue.Tracks
 	if queueLimit > 0 && len(tracks) > queueLimit {
 		tracks = tracks[:queueLimit]
 	}
 
-	if JSONOutput() {
-		output := make([]map[string]interface{}, len(tracks))
+	res := queueResult{Queue: make([]queueTrack, len(tracks)), Total: len(queue.Tracks)}
+	for i, t := range tracks {
+		res.Queue[i] = queueTrack{
+			Position: i,
+			Title:    t.Title,
+			Artist:   t.Artist,
+			Album:    t.Album,
+			Duration: t.Duration.String(),
+			URI:      t.URI,
+		}
+	}
+
+	return render(res, func() error {
+		if queue.IsEmpty() {
+			fmt.Println("Queue is empty")
+			return nil
+		}
+
+		// Table output
+		fmt.Println("Queue:")
 		for i, t := range tracks {
-			output[i] = map[string]interface{}{
-				"position": i,
-				"title":    t.Title,
-				"artist":   t.Artist,
-				"album":    t.Album,
-				"duration": t.Duration.String(),
-				"uri":      t.URI,
+			prefix := "  "
+			if i == queue.CurrentIndex {
+				prefix = "▶ "
 			}
+			fmt.Printf("%s%d. %s — %s (%s)\n", prefix, i+1, t.Title, t.Artist, formatDuration(t.Duration))
 		}
-		return json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
-			"queue": output,
-			"total": len(queue.Tracks),
-		})
-	}
 
-	// Table output
-	fmt.Println("Queue:")
-	for i, t := range tracks {
-		prefix := "  "
-		if i == 0 {
-			prefix = "▶ "
+		if len(queue.Tracks) > queueLimit {
+			fmt.Printf("\n... and %d more tracks\n", len(queue.Tracks)-queueLimit)
 		}
-		fmt.Printf("%s%d. %s — %s (%s)\n", prefix, i+1, t.Title, t.Artist, formatDuration(t.Duration))
-	}
+		return nil
+	})
+}
 
-	if len(queue.Tracks) > queueLimit {
-		fmt.Printf("\n... and %d more tracks\n", len(queue.Tracks)-queueLimit)
-	}
+// queueResult is the output of riff queue. Total counts every track in the
+// queue, beyond --limit.
+type queueResult struct {
+	Queue []queueTrack `json:"queue"`
+	Total int          `json:"total"`
+}
+
+func (r queueResult) records() any { return r.Queue }
 
-	return nil
+// queueTrack is a track in the output of riff queue.
+type queueTrack struct {
+	Position int    `json:"position"`
+	Title    string `json:"title"`
+	Artist   string `json:"artist"`
+	Album    string `json:"album"`
+	Duration string `json:"duration"`
+	URI      string `json:"uri"`
 }
 
 func runQueueAdd(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
-	spotifyClient, err := getSpotifyClient()
+	b := newBackends()
+	p, _, err := b.PlayerFor(ctx, queueDevice)
 	if err != nil {
 		return err
 	}
-
-	p := player.New(spotifyClient)
+	if err := core.Require(p, core.CapQueueAdd); err != nil {
+		return err
+	}
 
 	var uri string
 	var trackName string
@@ -159,93 +184,114 @@ func runQueueAdd(cmd *cobra.Command, args []string) error {
 	} else {
 		// Search for the track
 		query := args[0]
-		results, err := spotifyClient.Search(ctx, client.SearchOptions{
-			Query: query,
-			Types: []client.SearchType{client.SearchTypeTrack},
-			Limit: 1,
-		})
+		results, err := b.Search(ctx, query, []core.SearchKind{core.SearchKindTrack}, 1)
 		if err != nil {
 			return fmt.Errorf("search failed: %w", err)
 		}
 
-		if results.Tracks == nil || len(results.Tracks.Items) == 0 {
+		if len(results) == 0 {
 			return fmt.Errorf("no tracks found for '%s'", query)
 		}
 
-		track := results.Tracks.Items[0]
+		track := results[0]
 		uri = track.URI
-		trackName = fmt.Sprintf("%s by %s", track.Name, track.Artists[0].Name)
+		trackName = fmt.Sprintf("%s by %s", track.Title, track.Subtitle)
 	}
 
 	if err := p.AddToQueue(ctx, uri); err != nil {
 		return fmt.Errorf("failed to add to queue: %w", err)
 	}
 
-	if JSONOutput() {
-		_ = json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
-			"status": "added",
-			"uri":    uri,
-			"name":   trackName,
-		})
-	} else {
-		fmt.Printf("Added to queue: %s\n", trackName)
-	}
+	return renderf(queueAddResult{Status: "added", URI: uri, Name: trackName}, "Added to queue: %s", trackName)
+}
 
-	return nil
+// queueAddResult is the output of riff queue add.
+type queueAddResult struct {
+	Status string `json:"status"`
+	URI    string `json:"uri"`
+	Name   string `json:"name"`
 }
 
 func runQueueRemove(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
 	index, err := strconv.Atoi(args[0])
-	if err != nil {
+	if err != nil || index < 1 {
 		return fmt.Errorf("invalid index: %s", args[0])
 	}
 
-	// Spotify API doesn't support queue removal
-	return fmt.Errorf("queue removal is not supported by Spotify API (requested index: %d)", index)
+	editor, err := getQueueEditor(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := editor.RemoveFromQueue(ctx, index); err != nil {
+		return fmt.Errorf("failed to remove from queue: %w", err)
+	}
+
+	return renderf(queueRemoveResult{Status: "removed", Index: index}, "Removed track %d from queue", index)
+}
+
+// queueRemoveResult is the output of riff queue remove.
+type queueRemoveResult struct {
+	Status string `json:"status"`
+	Index  int    `json:"index"`
 }
 
 func runQueueClear(cmd *cobra.Command, args []string) error {
-	// Spotify API doesn't support queue clearing
-	return fmt.Errorf("queue clearing is not supported by Spotify API")
+	ctx := context.Background()
+
+	editor, err := getQueueEditor(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := editor.ClearQueue(ctx); err != nil {
+		return fmt.Errorf("failed to clear queue: %w", err)
+	}
+
+	return renderf(actionResult{Status: "cleared"}, "Queue cleared")
 }
 
 func runQueueMove(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
 	from, err := strconv.Atoi(args[0])
-	if err != nil {
+	if err != nil || from < 1 {
 		return fmt.Errorf("invalid from index: %s", args[0])
 	}
 	to, err := strconv.Atoi(args[1])
-	if err != nil {
+	if err != nil || to < 1 {
 		return fmt.Errorf("invalid to index: %s", args[1])
 	}
 
-	// Spotify API doesn't support queue reordering
-	return fmt.Errorf("queue reordering is not supported by Spotify API (requested move: %d -> %d)", from, to)
-}
-
-func getSpotifyClient() (*client.Client, error) {
-	if cfg.Spotify.ClientID == "" {
-		return nil, fmt.Errorf("spotify not configured")
-	}
-
-	storage, err := auth.NewTokenStorage("")
+	editor, err := getQueueEditor(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize token storage: %w", err)
+		return err
 	}
 
-	spotifyClient := client.New(cfg.Spotify.ClientID, storage)
-	if Verbose() {
-		spotifyClient.SetVerbose(true, func(format string, args ...interface{}) {
-			fmt.Fprintf(os.Stderr, format+"\n", args...)
-		})
-	}
-	if err := spotifyClient.LoadToken(); err != nil {
-		return nil, fmt.Errorf("failed to load token: %w", err)
+	if err := editor.MoveInQueue(ctx, from, to); err != nil {
+		return fmt.Errorf("failed to move in queue: %w", err)
 	}
 
-	if !spotifyClient.HasToken() {
-		return nil, fmt.Errorf("not authenticated. Run 'riff auth login' first")
-	}
+	return renderf(queueMoveResult{Status: "moved", From: from, To: to}, "Moved track %d to position %d", from, to)
+}
+
+// queueMoveResult is the output of riff queue move.
+type queueMoveResult struct {
+	Status string `json:"status"`
+	From   int    `json:"from"`
+	To     int    `json:"to"`
+}
 
-	return spotifyClient, nil
+// getQueueEditor resolves the target player and checks it can edit its queue.
+func getQueueEditor(ctx context.Context) (core.QueueEditor, error) {
+	p, _, err := resolvePlayer(ctx, queueDevice)
+	if err != nil {
+		return nil, err
+	}
+	if err := core.Require(p, core.CapQueueEdit); err != nil {
+		return nil, err
+	}
+	return p.(core.QueueEditor), nil
 }
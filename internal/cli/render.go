@@ -0,0 +1,381 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// outputFormat is the value of --output, and outputTemplate the parsed
+// template of --output template=....
+var (
+	outputFormat   string
+	outputTemplate *template.Template
+)
+
+// recordLister is implemented by results that wrap their records, such as
+// {"groups": [...]}, so that the record formats (ndjson, csv, table and
+// template) render one record per line rather than the wrapper.
+type recordLister interface {
+	records() any
+}
+
+// parseOutput sets the output mode from --output and --json.
+func parseOutput() error {
+	format, text, hasTemplate := strings.Cut(outputFormat, "=")
+	mode := OutputNormal
+	switch format {
+	case "":
+	case "json":
+		mode = OutputJSON
+	case "ndjson":
+		mode = OutputNDJSON
+	case "yaml":
+		mode = OutputYAML
+	case "csv":
+		mode = OutputCSV
+	case "table":
+		mode = OutputTable
+	case "template":
+		if !hasTemplate {
+			return fmt.Errorf("--output template needs a template, as in --output 'template={{.name}}'")
+		}
+		t, err := template.New("output").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+		if err != nil {
+			return fmt.Errorf("invalid output template: %w", err)
+		}
+		outputTemplate = t
+		mode = OutputTemplate
+	default:
+		return fmt.Errorf("unknown output format %q (want json, ndjson, yaml, csv, table or template=...)", outputFormat)
+	}
+	if jsonOut {
+		if mode != OutputNormal && mode != OutputJSON {
+			return fmt.Errorf("--json conflicts with --output %s", format)
+		}
+		mode = OutputJSON
+	}
+	SetOutputMode(mode)
+	return nil
+}
+
+// templateFuncs are the functions available to --output template=....
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"join": func(list any, sep string) string {
+		items, _ := list.([]any)
+		strs := make([]string, len(items))
+		for i, item := range items {
+			strs[i] = formatField(item)
+		}
+		return strings.Join(strs, sep)
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// actionResult is the output of commands that only report what they did.
+type actionResult struct {
+	Status string `json:"status"`
+}
+
+// render writes a command's result in the requested output format. Without
+// one it calls human, which prints the command's usual text.
+//
+// Field names come from the result's json tags, so every format uses the
+// same names; riff schema lists them. json and yaml render the whole
+// result, the other formats one record per line.
+func render(v any, human func() error) error {
+	switch GetOutputMode() {
+	case OutputNormal, OutputMinimal:
+		return human()
+	case OutputJSON:
+		return json.NewEncoder(os.Stdout).Encode(v)
+	case OutputYAML:
+		return writeYAML(os.Stdout, v)
+	}
+
+	if l, ok := v.(recordLister); ok {
+		v = l.records()
+	}
+	rv := reflect.ValueOf(v)
+	w := newRecordWriter(os.Stdout, reflect.TypeOf(v))
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		for i := range rv.Len() {
+			if err := w.Write(rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+	} else if err := w.Write(v); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// renderf renders v, or prints the formatted line for people.
+func renderf(v any, format string, args ...any) error {
+	return render(v, func() error {
+		fmt.Printf(format+"\n", args...)
+		return nil
+	})
+}
+
+// recordWriter writes records one at a time in a record format, for
+// render and for commands that stream, like riff tail.
+type recordWriter struct {
+	out     io.Writer
+	mode    OutputMode
+	columns []string
+	header  bool
+
+	csv   *csv.Writer
+	table *Table
+}
+
+// newRecordWriter returns a writer for records of type t, or for the
+// elements of t if it is a slice. Its columns are t's fields, or the keys
+// of the first record if t has none.
+func newRecordWriter(out io.Writer, t reflect.Type) *recordWriter {
+	if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		t = t.Elem()
+	}
+	w := &recordWriter{out: out, mode: GetOutputMode()}
+	for _, f := range schemaFields(t) {
+		if !strings.Contains(f.Name, "[]") {
+			w.columns = append(w.columns, f.Name)
+		}
+	}
+	return w
+}
+
+// Write writes one record.
+func (w *recordWriter) Write(v any) error {
+	switch w.mode {
+	case OutputJSON, OutputNDJSON:
+		return json.NewEncoder(w.out).Encode(v)
+	case OutputYAML:
+		if _, err := io.WriteString(w.out, "---\n"); err != nil {
+			return err
+		}
+		return writeYAML(w.out, v)
+	}
+
+	record, err := toGeneric(v)
+	if err != nil {
+		return err
+	}
+	if w.mode == OutputTemplate {
+		if err := outputTemplate.Execute(w.out, record); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w.out, "\n")
+		return err
+	}
+
+	if w.columns == nil {
+		if m, ok := record.(map[string]any); ok {
+			for key := range m {
+				w.columns = append(w.columns, key)
+			}
+			slices.Sort(w.columns)
+		} else {
+			w.columns = []string{"value"}
+		}
+	}
+	row := make([]string, len(w.columns))
+	for i, col := range w.columns {
+		row[i] = formatField(lookupField(record, col))
+	}
+
+	if w.mode == OutputCSV {
+		if w.csv == nil {
+			w.csv = csv.NewWriter(w.out)
+		}
+		if !w.header {
+			w.header = true
+			if err := w.csv.Write(w.columns); err != nil {
+				return err
+			}
+		}
+		return w.csv.Write(row)
+	}
+
+	if w.table == nil {
+		headers := make([]string, len(w.columns))
+		for i, col := range w.columns {
+			headers[i] = strings.ToUpper(col)
+		}
+		w.table = NewTableWriter(w.out, headers...)
+		w.header = true
+	}
+	w.table.Row(row...)
+	return nil
+}
+
+// Flush writes any buffered records. A csv or table with no records still
+// gets its header.
+func (w *recordWriter) Flush() error {
+	switch w.mode {
+	case OutputCSV:
+		if w.csv == nil {
+			w.csv = csv.NewWriter(w.out)
+		}
+		if !w.header && w.columns != nil {
+			w.header = true
+			_ = w.csv.Write(w.columns)
+		}
+		w.csv.Flush()
+		return w.csv.Error()
+	case OutputTable:
+		if w.table == nil && w.columns != nil {
+			headers := make([]string, len(w.columns))
+			for i, col := range w.columns {
+				headers[i] = strings.ToUpper(col)
+			}
+			w.table = NewTableWriter(w.out, headers...)
+		}
+		if w.table != nil {
+			w.table.Flush()
+		}
+	}
+	return nil
+}
+
+// toGeneric converts v to the maps, slices and scalars its JSON decodes
+// to, so that fields are addressed by their published names.
+func toGeneric(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out any
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// lookupField returns the field at a dotted path like "track.title".
+func lookupField(v any, path string) any {
+	for name := range strings.SplitSeq(path, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[name]
+	}
+	return v
+}
+
+// formatField formats a field for a csv or table cell. Lists and objects
+// are written as JSON.
+func formatField(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// writeYAML writes v as YAML, with fields in the same order and under the
+// same names as its JSON.
+func writeYAML(out io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	node, err := yamlNode(dec)
+	if err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(out)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// yaml11Number matches strings that YAML 1.1 reads as numbers.
+var yaml11Number = regexp.MustCompile(`^[-+]?[0-9][0-9_.:]*$`)
+
+// yamlNode reads the next JSON value from dec as a YAML node.
+func yamlNode(dec *json.Decoder) (*yaml.Node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if tok == '{' {
+			node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		for dec.More() {
+			if tok == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)})
+			}
+			child, err := yamlNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		if len(node.Content) == 0 {
+			node.Style = yaml.FlowStyle
+		}
+		return node, nil
+	case string:
+		node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: tok}
+		if yaml11Number.MatchString(tok) {
+			// Keep YAML 1.1 parsers from reading times like 07:00 as numbers
+			node.Style = yaml.DoubleQuotedStyle
+		}
+		return node, nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(tok.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: tok.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: formatField(tok)}, nil
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+}
@@ -1,17 +1,20 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/tessro/riff/internal/config"
+	rifferrors "github.com/tessro/riff/internal/errors"
 )
 
 var (
-	cfgFile string
-	jsonOut bool
-	verbose bool
+	cfgFile  string
+	jsonOut  bool
+	verbose  bool
+	noDaemon bool
 
 	cfg *config.Config
 )
@@ -21,15 +24,25 @@ var rootCmd = &cobra.Command{
 	Short: "Control Spotify and Sonos from the command line",
 	Long:  `Riff is a unified CLI for controlling music playback across Spotify and Sonos devices.`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		return initConfig()
+		if err := parseOutput(); err != nil {
+			return err
+		}
+		if err := initConfig(); err != nil {
+			return err
+		}
+		return setupLogging(cmd)
 	},
 	SilenceUsage: true,
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file (default: ~/.riffrc)")
-	rootCmd.PersistentFlags().BoolVarP(&jsonOut, "json", "j", false, "output as JSON")
-	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().BoolVarP(&jsonOut, "json", "j", false, "output as JSON (same as --output json)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "", "output format: json, ndjson, yaml, csv, table or template='{{...}}'")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output, with debug logs on stderr")
+	_ = rootCmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions(
+		[]string{"json", "ndjson", "yaml", "csv", "table", "template="}, cobra.ShellCompDirectiveNoFileComp|cobra.ShellCompDirectiveNoSpace))
+	rootCmd.PersistentFlags().BoolVar(&noDaemon, "no-daemon", false, "run directly even when riffd is running")
 }
 
 func initConfig() error {
@@ -53,10 +66,21 @@ func initConfig() error {
 // Execute runs the root command.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
+		var riffErr *rifferrors.RiffError
+		if errors.As(err, &riffErr) && riffErr.Suggestion != "" {
+			fmt.Fprintf(os.Stderr, "Suggestion: %s\n", riffErr.Suggestion)
+		}
 		os.Exit(1)
 	}
 }
 
+// ExecuteDaemon runs 'riff daemon run' with the process arguments, for the
+// riffd binary.
+func ExecuteDaemon() {
+	rootCmd.SetArgs(append([]string{"daemon", "run"}, os.Args[1:]...))
+	Execute()
+}
+
 // Config returns the loaded configuration.
 func Config() *config.Config {
 	return cfg
@@ -64,7 +88,7 @@ func Config() *config.Config {
 
 // JSONOutput returns true if JSON output is requested.
 func JSONOutput() bool {
-	return jsonOut
+	return GetOutputMode() == OutputJSON
 }
 
 // Verbose returns true if verbose output is requested.
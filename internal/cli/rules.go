@@ -0,0 +1,276 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tessro/riff/internal/rules"
+	"github.com/tessro/riff/internal/tail"
+)
+
+var rulesInterval time.Duration
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Run automation rules from the config",
+	Long: `Rules react to playback events on any device. Each [[rules]] entry in the
+config sets conditions (event types, device, platform, whether it's playing,
+a time window and weekdays) and the actions to run when they all hold:
+play, pause, next, previous, volume, volume_cap, or Sonos group and ungroup.
+
+A rule with "for" waits that long and only fires if nothing else happens on
+the device meanwhile, e.g. to act when a room has been paused for 10
+minutes.
+
+  [[rules]]
+  name = "Quiet evenings"
+  events = ["resume", "track_change", "volume_change"]
+  device = "Office"
+  platform = "spotify"
+  after = "18:00"
+  actions = [{ do = "volume_cap", volume = 30 }]
+
+  [[rules]]
+  name = "Split the patio"
+  events = ["pause"]
+  device = "Kitchen"
+  for = "10m"
+  actions = [{ do = "ungroup", target = "Patio" }]`,
+}
+
+var rulesRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Watch every device and run matching rules",
+	Long: `Watch every device and run the actions of matching rules until
+interrupted. Like riffd, it keeps its own backends and doesn't use the
+daemon.`,
+	Args: cobra.NoArgs,
+	RunE: runRulesRun,
+}
+
+var rulesCheckCmd = &cobra.Command{
+	Use:   "check [events.jsonl]",
+	Short: "Validate rules and dry-run them against recorded events",
+	Long: `Validate the [[rules]] in the config. Given events recorded with
+'riff tail --json' (or "-" for stdin), also replay them and show which rules
+would fire and what they would do, without touching any device.
+
+Examples:
+  riff tail --json > events.jsonl
+  riff rules check events.jsonl`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runRulesCheck,
+}
+
+func init() {
+	rulesRunCmd.Flags().DurationVarP(&rulesInterval, "interval", "i", time.Second, "poll interval")
+	addMetricsFlag(rulesRunCmd)
+
+	rulesCmd.AddCommand(rulesRunCmd)
+	rulesCmd.AddCommand(rulesCheckCmd)
+	rootCmd.AddCommand(rulesCmd)
+
+	registerOutput(rulesRunCmd, rulesRunResult{})
+	registerOutput(rulesCheckCmd, rulesCheckResult{})
+}
+
+// configRules converts the [[rules]] config entries.
+func configRules() ([]rules.Rule, error) {
+	var rs []rules.Rule
+	for _, c := range cfg.Rules {
+		r := rules.Rule{
+			Name:     c.Name,
+			Events:   c.Events,
+			Device:   c.Device,
+			Platform: c.Platform,
+			After:    c.After,
+			Before:   c.Before,
+			Days:     c.Days,
+			Playing:  c.Playing,
+		}
+		if c.For != "" {
+			d, err := time.ParseDuration(c.For)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: invalid for: %w", c.Name, err)
+			}
+			r.For = d
+		}
+		for _, a := range c.Actions {
+			r.Actions = append(r.Actions, rules.Action{
+				Do:     a.Do,
+				Target: a.Target,
+				Volume: a.Volume,
+				With:   a.With,
+			})
+		}
+		rs = append(rs, r)
+	}
+	return rs, nil
+}
+
+func runRulesRun(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	rs, err := configRules()
+	if err != nil {
+		return err
+	}
+	if len(rs) == 0 {
+		return fmt.Errorf("no rules configured; add [[rules]] to the config")
+	}
+
+	if err := startMetrics(ctx); err != nil {
+		return err
+	}
+
+	b := newDirectBackends()
+	var groups rules.Groups
+	if sb := b.Sonos(); sb != nil {
+		groups = sb
+	}
+	engine, err := rules.New(rs, b, groups, rules.Options{
+		Interval: rulesInterval,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := renderf(rulesRunResult{Status: "running", Rules: len(rs)}, "Running %d rule(s)", len(rs)); err != nil {
+		return err
+	}
+
+	return engine.Run(ctx)
+}
+
+// rulesRunResult is what riff rules run outputs once it has started.
+type rulesRunResult struct {
+	Status string `json:"status"`
+	Rules  int    `json:"rules"`
+}
+
+// rulesCheckResult is the output of riff rules check: the valid rules, and
+// what would fire when events are replayed.
+type rulesCheckResult struct {
+	Valid   bool           `json:"valid"`
+	Rules   []string       `json:"rules"`
+	Events  int            `json:"events"`
+	Firings []rules.Firing `json:"firings"`
+}
+
+func (r rulesCheckResult) records() any { return r.Firings }
+
+func runRulesCheck(cmd *cobra.Command, args []string) error {
+	rs, err := configRules()
+	if err != nil {
+		return err
+	}
+	engine, err := rules.New(rs, nil, nil, rules.Options{})
+	if err != nil {
+		return err
+	}
+
+	res := rulesCheckResult{Valid: true, Rules: make([]string, len(rs)), Firings: []rules.Firing{}}
+	for i, r := range rs {
+		res.Rules[i] = r.Name
+	}
+	if len(args) == 0 {
+		return render(res, func() error { return printRules(rs) })
+	}
+
+	var r io.Reader = os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	events, err := readEvents(r)
+	if err != nil {
+		return err
+	}
+
+	res.Events = len(events)
+	if firings := engine.Check(events); firings != nil {
+		res.Firings = firings
+	}
+	return render(res, func() error {
+		fmt.Printf("Replayed %d event(s)\n", len(events))
+		if len(res.Firings) == 0 {
+			fmt.Println("No rules would fire")
+			return nil
+		}
+		for _, f := range res.Firings {
+			when := f.At.Local().Format("2006-01-02 15:04:05")
+			if f.Pending {
+				when += " (if nothing else happens)"
+			}
+			fmt.Printf("%s  %s  on %s\n", when, f.Rule, f.Event.Type)
+			for _, a := range f.Actions {
+				fmt.Printf("    → %s\n", a)
+			}
+		}
+		return nil
+	})
+}
+
+// printRules lists valid rules.
+func printRules(rs []rules.Rule) error {
+	if len(rs) == 0 {
+		fmt.Println("No rules configured")
+		return nil
+	}
+	fmt.Printf("%d rule(s) OK\n", len(rs))
+	for _, r := range rs {
+		actions := make([]string, len(r.Actions))
+		for i, a := range r.Actions {
+			actions[i] = a.String()
+		}
+		fmt.Printf("  %s: %s\n", r.Name, strings.Join(actions, ", "))
+	}
+	return nil
+}
+
+// readEvents reads events recorded as JSON lines, skipping blank lines.
+func readEvents(r io.Reader) ([]tail.Event, error) {
+	var events []tail.Event
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var e tail.Event
+		if err := json.Unmarshal([]byte(text), &e); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
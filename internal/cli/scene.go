@@ -0,0 +1,410 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/scene"
+	"github.com/tessro/riff/internal/schedule"
+	"github.com/tessro/riff/internal/sonos"
+)
+
+var (
+	sceneRooms  []string
+	sceneSource string
+)
+
+var sceneCmd = &cobra.Command{
+	Use:   "scene",
+	Short: "Apply and capture multi-room scenes",
+	Long: `Scenes are named setups kept in the config as [scenes.<name>]: which
+rooms play, whether they are grouped, each room's volume and tone, what to
+play and the shuffle and repeat modes.
+
+  [scenes.dinner]
+  group = true
+  source = "playlist:Dinner Jazz"
+  shuffle = true
+
+  [[scenes.dinner.rooms]]
+  name = "Dining Room"
+  volume = 25
+
+  [[scenes.dinner.rooms]]
+  name = "Kitchen"
+  volume = 15
+  bass = -2
+
+A source is a Spotify URI, a stream URL, or playlist:<name>, album:<name>
+or favorite:<name> (Sonos Favorites) to look one up.`,
+}
+
+var sceneApplyCmd = &cobra.Command{
+	Use:   "apply <name>",
+	Short: "Apply a scene",
+	Long: `Apply a scene. Rooms are grouped and their volume and tone set
+concurrently, then the source starts on the first room. If any step fails,
+the steps that succeeded are undone.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSceneApply,
+}
+
+var sceneCaptureCmd = &cobra.Command{
+	Use:   "capture <name>",
+	Short: "Save the current setup as a scene",
+	Long: `Save the current grouping, volumes, tone and play mode of some rooms as
+a scene in the config, replacing any scene with the same name.
+
+Without --rooms, captures the rooms of an existing scene with that name, or
+else the rooms grouped with the device that is playing. Stream URLs are
+captured as the source; otherwise an existing scene keeps its source unless
+--source is given.
+
+Examples:
+  riff scene capture dinner --rooms "Dining Room,Kitchen"
+  riff scene capture morning --source favorite:KEXP`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSceneCapture,
+}
+
+var sceneListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configured scenes",
+	Args:  cobra.NoArgs,
+	RunE:  runSceneList,
+}
+
+func init() {
+	sceneCaptureCmd.Flags().StringSliceVar(&sceneRooms, "rooms", nil, "Rooms to capture, comma-separated (first leads the group)")
+	sceneCaptureCmd.Flags().StringVar(&sceneSource, "source", "", "Source to save with the scene")
+	sceneApplyCmd.ValidArgsFunction = completeScenes
+	sceneCaptureCmd.ValidArgsFunction = completeScenes
+
+	sceneCmd.AddCommand(sceneApplyCmd)
+	sceneCmd.AddCommand(sceneCaptureCmd)
+	sceneCmd.AddCommand(sceneListCmd)
+	rootCmd.AddCommand(sceneCmd)
+
+	registerOutput(sceneApplyCmd, sceneApplyResult{})
+	registerOutput(sceneCaptureCmd, sceneCaptureResult{})
+	registerOutput(sceneListCmd, sceneListResult{})
+}
+
+// configScene converts the [scenes.<name>] config entry.
+func configScene(name string) (*scene.Scene, error) {
+	c, ok := cfg.Scenes[name]
+	if !ok {
+		return nil, fmt.Errorf("no scene named %q; add [scenes.%s] to the config or run 'riff scene capture %s'", name, name, name)
+	}
+	s := &scene.Scene{
+		Name:    name,
+		Group:   c.Group,
+		Source:  c.Source,
+		Shuffle: c.Shuffle,
+		Repeat:  c.Repeat,
+	}
+	for _, r := range c.Rooms {
+		s.Rooms = append(s.Rooms, scene.Room{
+			Name:     r.Name,
+			Volume:   r.Volume,
+			Bass:     r.Bass,
+			Treble:   r.Treble,
+			Loudness: r.Loudness,
+		})
+	}
+	return s, nil
+}
+
+// sceneGroups returns the Sonos backend for grouping, or nil without one.
+func sceneGroups(b *backends) scene.Groups {
+	if sb := b.Sonos(); sb != nil {
+		return sb
+	}
+	return nil
+}
+
+// scenePlayer resolves a scene's source once, before anything changes,
+// and returns how to start it on a player. An empty source plays nothing.
+func scenePlayer(ctx context.Context, b *backends, source string) (func(context.Context, core.Player) error, error) {
+	kind, name, _ := strings.Cut(source, ":")
+	switch {
+	case source == "":
+		return nil, nil
+	case kind == "favorite":
+		sb := b.Sonos()
+		if sb == nil {
+			return nil, fmt.Errorf("sonos is not available for %s", source)
+		}
+		favorites, err := sb.Favorites(ctx)
+		if err != nil {
+			return nil, err
+		}
+		i := slices.IndexFunc(favorites, func(f sonos.Favorite) bool { return strings.EqualFold(f.Title, name) })
+		if i < 0 {
+			return nil, fmt.Errorf("no Sonos Favorite named '%s'", name)
+		}
+		favorite := favorites[i]
+		return func(ctx context.Context, p core.Player) error {
+			sp, ok := p.(*sonos.Player)
+			if !ok {
+				return fmt.Errorf("sonos favorites only play on Sonos rooms")
+			}
+			return sp.PlayFavorite(ctx, favorite)
+		}, nil
+	case kind == "playlist" || kind == "album":
+		sb, err := b.Spotify()
+		if err != nil {
+			return nil, err
+		}
+		results, err := sb.Search(ctx, name, []core.SearchKind{core.SearchKind(kind)}, 1)
+		if err != nil {
+			return nil, fmt.Errorf("search failed: %w", err)
+		}
+		if len(results) == 0 {
+			return nil, fmt.Errorf("no %ss found for '%s'", kind, name)
+		}
+		source = results[0].URI
+	}
+	return func(ctx context.Context, p core.Player) error {
+		return schedule.PlayURI(ctx, p, source)
+	}, nil
+}
+
+func runSceneApply(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s, err := configScene(args[0])
+	if err != nil {
+		return err
+	}
+
+	b := newDirectBackends()
+	play, err := scenePlayer(ctx, b, s.Source)
+	if err != nil {
+		return err
+	}
+	err = scene.Apply(ctx, s, b, sceneGroups(b), scene.Options{
+		Play: play,
+	})
+	if err != nil {
+		return err
+	}
+
+	rooms := make([]string, len(s.Rooms))
+	for i, r := range s.Rooms {
+		rooms[i] = r.Name
+	}
+	return renderf(sceneApplyResult{Status: "applied", Scene: s.Name, Rooms: rooms},
+		"🎬 Applied %s on %s", s.Name, strings.Join(rooms, ", "))
+}
+
+// sceneApplyResult is the output of riff scene apply.
+type sceneApplyResult struct {
+	Status string   `json:"status"`
+	Scene  string   `json:"scene"`
+	Rooms  []string `json:"rooms"`
+}
+
+// sceneCaptureResult is the output of riff scene capture. Config is what
+// was saved.
+type sceneCaptureResult struct {
+	Status string    `json:"status"`
+	Scene  string    `json:"scene"`
+	Config sceneInfo `json:"config"`
+}
+
+func runSceneCapture(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	name := args[0]
+	configPath := getConfigPath()
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return fmt.Errorf("config file not found at %s. Run 'riff config init' first", configPath)
+	}
+
+	b := newDirectBackends()
+	groups := sceneGroups(b)
+	rooms := sceneRooms
+	if len(rooms) == 0 {
+		var err error
+		if rooms, err = captureRooms(ctx, b, name); err != nil {
+			return err
+		}
+	}
+
+	s, err := scene.Capture(ctx, name, rooms, b, groups)
+	if err != nil {
+		return err
+	}
+	switch {
+	case sceneSource != "":
+		s.Source = sceneSource
+	case s.Source == "":
+		s.Source = cfg.Scenes[name].Source
+	}
+
+	rawConfig, err := readRawConfig(configPath)
+	if err != nil {
+		return err
+	}
+	scenes, ok := rawConfig["scenes"].(map[string]interface{})
+	if !ok {
+		scenes = make(map[string]interface{})
+		rawConfig["scenes"] = scenes
+	}
+	info := newSceneInfo(s)
+	scenes[name] = info
+	if err := writeRawConfig(configPath, rawConfig); err != nil {
+		return err
+	}
+
+	names := make([]string, len(s.Rooms))
+	for i, r := range s.Rooms {
+		names[i] = r.Name
+	}
+	return renderf(sceneCaptureResult{Status: "captured", Scene: name, Config: info},
+		"🎬 Saved %s: %s", name, strings.Join(names, ", "))
+}
+
+// captureRooms picks the rooms to capture when --rooms isn't given: those
+// of the existing scene, or else the group of the device that is playing.
+func captureRooms(ctx context.Context, b *backends, name string) ([]string, error) {
+	if existing, ok := cfg.Scenes[name]; ok {
+		rooms := make([]string, len(existing.Rooms))
+		for i, r := range existing.Rooms {
+			rooms[i] = r.Name
+		}
+		return rooms, nil
+	}
+
+	_, d, err := b.PlayerFor(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, fmt.Errorf("no device to capture; use --rooms")
+	}
+	if sb := b.Sonos(); sb != nil && d.Platform == core.PlatformSonos {
+		groups, err := sb.Groups(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range groups {
+			if !slices.ContainsFunc(g.Members, func(m *sonos.Device) bool { return m.UUID == d.ID }) {
+				continue
+			}
+			rooms := []string{g.Name}
+			for _, m := range g.Members {
+				if m.Name != g.Name {
+					rooms = append(rooms, m.Name)
+				}
+			}
+			return rooms, nil
+		}
+	}
+	return []string{d.Name}, nil
+}
+
+// sceneInfo is a scene as output, and as saved in its [scenes.<name>]
+// table.
+type sceneInfo struct {
+	Group   bool        `json:"group" toml:"group"`
+	Source  string      `json:"source,omitempty" toml:"source,omitempty"`
+	Shuffle *bool       `json:"shuffle,omitempty" toml:"shuffle,omitempty"`
+	Repeat  string      `json:"repeat,omitempty" toml:"repeat,omitempty"`
+	Rooms   []sceneRoom `json:"rooms" toml:"rooms"`
+}
+
+// sceneRoom is a room of a scene, with the settings it was captured with.
+type sceneRoom struct {
+	Name     string `json:"name" toml:"name"`
+	Volume   *int   `json:"volume,omitempty" toml:"volume,omitempty"`
+	Bass     *int   `json:"bass,omitempty" toml:"bass,omitempty"`
+	Treble   *int   `json:"treble,omitempty" toml:"treble,omitempty"`
+	Loudness *bool  `json:"loudness,omitempty" toml:"loudness,omitempty"`
+}
+
+func newSceneInfo(s *scene.Scene) sceneInfo {
+	info := sceneInfo{
+		Group:   s.Group,
+		Source:  s.Source,
+		Shuffle: s.Shuffle,
+		Repeat:  s.Repeat,
+		Rooms:   make([]sceneRoom, len(s.Rooms)),
+	}
+	for i, r := range s.Rooms {
+		info.Rooms[i] = sceneRoom{Name: r.Name, Volume: r.Volume, Bass: r.Bass, Treble: r.Treble, Loudness: r.Loudness}
+	}
+	return info
+}
+
+// sceneListResult is the output of riff scene list, keyed by scene name.
+type sceneListResult struct {
+	Scenes map[string]sceneInfo `json:"scenes"`
+}
+
+// sceneListItem is a scene as a record, with its name.
+type sceneListItem struct {
+	Name string `json:"name"`
+	sceneInfo
+}
+
+func (r sceneListResult) records() any {
+	items := make([]sceneListItem, 0, len(r.Scenes))
+	for name, info := range r.Scenes {
+		items = append(items, sceneListItem{Name: name, sceneInfo: info})
+	}
+	slices.SortFunc(items, func(a, b sceneListItem) int { return strings.Compare(a.Name, b.Name) })
+	return items
+}
+
+func runSceneList(cmd *cobra.Command, args []string) error {
+	res := sceneListResult{Scenes: make(map[string]sceneInfo, len(cfg.Scenes))}
+	for name := range cfg.Scenes {
+		s, _ := configScene(name)
+		res.Scenes[name] = newSceneInfo(s)
+	}
+	return render(res, printScenes)
+}
+
+// printScenes lists the configured scenes as a table.
+func printScenes() error {
+	if len(cfg.Scenes) == 0 {
+		fmt.Println("No scenes configured")
+		return nil
+	}
+	names := make([]string, 0, len(cfg.Scenes))
+	for name := range cfg.Scenes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	t := NewTable("NAME", "ROOMS", "SOURCE")
+	for _, name := range names {
+		c := cfg.Scenes[name]
+		rooms := make([]string, len(c.Rooms))
+		for i, r := range c.Rooms {
+			rooms[i] = r.Name
+		}
+		sep := ", "
+		if c.Group {
+			sep = " + "
+		}
+		source := c.Source
+		if source == "" {
+			source = "-"
+		}
+		t.Row(name, strings.Join(rooms, sep), source)
+	}
+	t.Flush()
+	return nil
+}
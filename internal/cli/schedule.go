@@ -0,0 +1,311 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/schedule"
+	"github.com/tessro/riff/internal/upnp"
+)
+
+var (
+	scheduleURI    string
+	scheduleTo     string
+	scheduleVolume string
+	scheduleRamp   time.Duration
+	scheduleTZ     string
+	scheduleMissed string
+	scheduleGrace  time.Duration
+)
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Schedule playback and wake-up routines",
+	Long: `Schedule playback on any device, Spotify included, at recurring times.
+
+Schedules are a time of day with optional days ("07:00 weekdays",
+"22:30 fri,sat", "09:00 daily") or a five-field cron expression
+("*/30 6-8 * * 1-5"), read in the local time zone or --tz.
+
+Jobs fire from 'riff schedule run', or from riffd when [daemon] schedule is
+set. Runs more than --grace late, e.g. because the machine was asleep, are
+skipped, or fired once on wake with --missed run.`,
+}
+
+var scheduleAddCmd = &cobra.Command{
+	Use:   "add <schedule>",
+	Short: "Add a scheduled job",
+	Long: `Add a scheduled job.
+
+--volume takes a level, or a range to ramp through over --ramp, starting
+quietly and getting louder.
+
+Examples:
+  riff schedule add "07:00 weekdays" --play spotify:playlist:37i9dQZF1DX0UrRvztWcAU --to Bedroom --volume 10..35 --ramp 10m
+  riff schedule add "0 18 * * *" --play http://radio.example/live.mp3 --to Kitchen --tz Europe/London`,
+	Args: cobra.ExactArgs(1),
+	RunE: runScheduleAdd,
+}
+
+var scheduleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List scheduled jobs and their next runs",
+	Args:  cobra.NoArgs,
+	RunE:  runScheduleList,
+}
+
+var scheduleRemoveCmd = &cobra.Command{
+	Use:   "remove <id>...",
+	Short: "Remove scheduled jobs",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runScheduleRemove,
+}
+
+var scheduleRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Fire scheduled jobs until interrupted",
+	Args:  cobra.NoArgs,
+	RunE:  runScheduleRun,
+}
+
+func init() {
+	scheduleAddCmd.Flags().StringVar(&scheduleURI, "play", "", "URI or stream URL to play (default: resume)")
+	scheduleAddCmd.Flags().StringVar(&scheduleTo, "to", "", "Target device name or ID (default: the default player)")
+	scheduleAddCmd.Flags().StringVar(&scheduleVolume, "volume", "", "volume level, or FROM..TO to ramp")
+	scheduleAddCmd.Flags().DurationVar(&scheduleRamp, "ramp", 0, "how long a volume range takes")
+	scheduleAddCmd.Flags().StringVar(&scheduleTZ, "tz", "", "IANA time zone for the schedule (default: local)")
+	scheduleAddCmd.Flags().StringVar(&scheduleMissed, "missed", schedule.MissedSkip, "what to do about a missed run: skip or run")
+	scheduleAddCmd.Flags().DurationVar(&scheduleGrace, "grace", schedule.DefaultGrace, "how late a run may fire before it counts as missed")
+	_ = scheduleAddCmd.RegisterFlagCompletionFunc("to", completeDevices)
+	addMetricsFlag(scheduleRunCmd)
+
+	scheduleCmd.AddCommand(scheduleAddCmd)
+	scheduleCmd.AddCommand(scheduleListCmd)
+	scheduleCmd.AddCommand(scheduleRemoveCmd)
+	scheduleCmd.AddCommand(scheduleRunCmd)
+	rootCmd.AddCommand(scheduleCmd)
+
+	registerOutput(scheduleAddCmd, scheduleAddResult{})
+	registerOutput(scheduleListCmd, scheduleListResult{})
+	registerOutput(scheduleRemoveCmd, scheduleRemoveResult{})
+	registerOutput(scheduleRunCmd, actionResult{})
+}
+
+// scheduleStore opens the job store in riff's config directory.
+func scheduleStore() (*schedule.Store, error) {
+	path, err := schedule.DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return schedule.NewStore(path), nil
+}
+
+// newScheduler creates a scheduler that plays through b.
+func newScheduler(b core.PlayerResolver) (*schedule.Scheduler, error) {
+	store, err := scheduleStore()
+	if err != nil {
+		return nil, err
+	}
+	return schedule.New(store, b, schedule.Options{
+		StatePath: filepath.Join(upnp.CacheDir(), "schedule-state.json"),
+		ClaimDir:  fadeClaimDir(),
+	}), nil
+}
+
+// parseVolumeRange parses "20" or "10..35".
+func parseVolumeRange(s string) (*schedule.VolumeRange, error) {
+	from, to, isRange := strings.Cut(s, "..")
+	lo, err := strconv.Atoi(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid volume %q", s)
+	}
+	hi := lo
+	if isRange {
+		if hi, err = strconv.Atoi(to); err != nil {
+			return nil, fmt.Errorf("invalid volume %q", s)
+		}
+	}
+	return &schedule.VolumeRange{From: lo, To: hi}, nil
+}
+
+func runScheduleAdd(cmd *cobra.Command, args []string) error {
+	store, err := scheduleStore()
+	if err != nil {
+		return err
+	}
+
+	job := schedule.Job{
+		Spec:   args[0],
+		TZ:     scheduleTZ,
+		URI:    scheduleURI,
+		Target: scheduleTo,
+		Ramp:   scheduleRamp,
+		Missed: scheduleMissed,
+		Grace:  scheduleGrace,
+	}
+	if scheduleVolume != "" {
+		if job.Volume, err = parseVolumeRange(scheduleVolume); err != nil {
+			return err
+		}
+	}
+	if job.Ramp > 0 && (job.Volume == nil || job.Volume.From == job.Volume.To) {
+		return fmt.Errorf("--ramp needs a volume range such as --volume 10..35")
+	}
+
+	job, err = store.Add(job)
+	if err != nil {
+		return err
+	}
+	next := job.Next(time.Now())
+
+	return render(scheduleAddResult{Status: "scheduled", scheduledJob: scheduledJob{Job: job, NextRun: next}}, func() error {
+		fmt.Printf("Scheduled job %d: %s\n", job.ID, describeJob(job))
+		if !next.IsZero() {
+			fmt.Printf("  Next run: %s\n", next.Format("Mon 2006-01-02 15:04 MST"))
+		}
+		return nil
+	})
+}
+
+// scheduledJob is a job with when it next runs, or the zero time if never.
+type scheduledJob struct {
+	Job     schedule.Job `json:"job"`
+	NextRun time.Time    `json:"next_run"`
+}
+
+// scheduleAddResult is the output of riff schedule add.
+type scheduleAddResult struct {
+	Status string `json:"status"`
+	scheduledJob
+}
+
+// scheduleListResult is the output of riff schedule list.
+type scheduleListResult struct {
+	Jobs []scheduledJob `json:"jobs"`
+}
+
+func (r scheduleListResult) records() any { return r.Jobs }
+
+// scheduleRemoveResult is output by riff schedule remove for each job.
+type scheduleRemoveResult struct {
+	Status string `json:"status"`
+	ID     int    `json:"id"`
+}
+
+func runScheduleList(cmd *cobra.Command, args []string) error {
+	store, err := scheduleStore()
+	if err != nil {
+		return err
+	}
+	jobs, err := store.Load()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	res := scheduleListResult{Jobs: make([]scheduledJob, len(jobs))}
+	for i, j := range jobs {
+		res.Jobs[i] = scheduledJob{Job: j, NextRun: j.Next(now)}
+	}
+	return render(res, func() error {
+		if len(jobs) == 0 {
+			fmt.Println("No scheduled jobs")
+			return nil
+		}
+		t := NewTable("ID", "SCHEDULE", "NEXT RUN", "JOB")
+		for _, j := range res.Jobs {
+			next := "never"
+			if !j.NextRun.IsZero() {
+				next = j.NextRun.Format("Mon 01-02 15:04 MST")
+			}
+			t.Row(strconv.Itoa(j.Job.ID), j.Job.Spec, next, describeJob(j.Job))
+		}
+		t.Flush()
+		return nil
+	})
+}
+
+func runScheduleRemove(cmd *cobra.Command, args []string) error {
+	store, err := scheduleStore()
+	if err != nil {
+		return err
+	}
+	for _, arg := range args {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("invalid job ID %q", arg)
+		}
+		if err := store.Remove(id); err != nil {
+			return err
+		}
+		if err := renderf(scheduleRemoveResult{Status: "removed", ID: id}, "Removed job %d", id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runScheduleRun(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := startMetrics(ctx); err != nil {
+		return err
+	}
+
+	scheduler, err := newScheduler(newDirectBackends())
+	if err != nil {
+		return err
+	}
+
+	if err := renderf(actionResult{Status: "running"}, "Running scheduled jobs"); err != nil {
+		return err
+	}
+	return scheduler.Run(ctx)
+}
+
+// describeJob summarizes what a job plays, where and how loud.
+func describeJob(j schedule.Job) string {
+	what := "resume"
+	if j.URI != "" {
+		what = "play " + j.URI
+	}
+	if j.Target != "" {
+		what += " on " + j.Target
+	}
+	if v := j.Volume; v != nil {
+		if v.From == v.To {
+			what += fmt.Sprintf(" at %d%%", v.From)
+		} else {
+			what += fmt.Sprintf(" from %d%% to %d%% over %s", v.From, v.To, j.Ramp)
+		}
+	}
+	if j.TZ != "" {
+		what += " (" + j.TZ + ")"
+	}
+	if j.Missed == schedule.MissedRun {
+		what += ", runs if missed"
+	}
+	return what
+}
@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// outputTypes maps each command with structured output to an example of
+// its result, for riff schema.
+var outputTypes = map[*cobra.Command]any{}
+
+// registerOutput records the type of result cmd renders. example is a
+// zero value of it, such as []deviceResult{}.
+func registerOutput(cmd *cobra.Command, example any) {
+	outputTypes[cmd] = example
+}
+
+// schemaField is a field of a command's output.
+type schemaField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// schemaResult is the output of riff schema <command>.
+type schemaResult struct {
+	Command string        `json:"command"`
+	Type    string        `json:"type"`
+	Fields  []schemaField `json:"fields"`
+}
+
+func (s schemaResult) records() any { return s.Fields }
+
+// schemaCommand is a line of riff schema's list of commands.
+type schemaCommand struct {
+	Command string `json:"command"`
+	Type    string `json:"type"`
+}
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema [command]",
+	Short: "List the fields of a command's structured output",
+	Long: `Lists the fields a command outputs with --json or --output, with their
+names as they appear in JSON, YAML, CSV and table headers. Nested fields
+are joined with dots, and fields of list items are marked with [].
+
+These names are stable: scripts can rely on them even when the normal
+output changes. Without a command, lists the commands that have them.
+
+Examples:
+  riff schema status
+  riff schema group list
+  riff schema --output json devices`,
+	RunE: runSchema,
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+	registerOutput(schemaCmd, schemaResult{})
+}
+
+func runSchema(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		var list []schemaCommand
+		for c, example := range outputTypes {
+			list = append(list, schemaCommand{Command: c.CommandPath(), Type: schemaKind(example)})
+		}
+		slices.SortFunc(list, func(a, b schemaCommand) int { return strings.Compare(a.Command, b.Command) })
+		return render(list, func() error {
+			t := NewTable("COMMAND", "OUTPUT")
+			for _, c := range list {
+				t.Row(c.Command, c.Type)
+			}
+			t.Flush()
+			return nil
+		})
+	}
+
+	target, _, err := rootCmd.Find(args)
+	if err != nil || target == rootCmd {
+		return fmt.Errorf("unknown command %q", strings.Join(args, " "))
+	}
+	example, ok := outputTypes[target]
+	if !ok {
+		return fmt.Errorf("%s has no structured output", target.CommandPath())
+	}
+	t := reflect.TypeOf(example)
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	res := schemaResult{
+		Command: target.CommandPath(),
+		Type:    schemaKind(example),
+		Fields:  schemaFields(t),
+	}
+	return render(res, func() error {
+		fmt.Printf("%s outputs %s\n\n", res.Command, res.Type)
+		t := NewTable("FIELD", "TYPE")
+		for _, f := range res.Fields {
+			t.Row(f.Name, f.Type)
+		}
+		t.Flush()
+		return nil
+	})
+}
+
+// schemaKind describes the shape of a result: an object or a list of them.
+func schemaKind(example any) string {
+	if reflect.TypeOf(example).Kind() == reflect.Slice {
+		return "list of objects"
+	}
+	return "object"
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// schemaFields lists the fields of struct type t under their JSON names,
+// in the order they are output.
+func schemaFields(t reflect.Type) []schemaField {
+	var fields []schemaField
+	appendSchemaFields(&fields, t, "")
+	return fields
+}
+
+func appendSchemaFields(fields *[]schemaField, t reflect.Type, prefix string) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct || isLeaf(t) {
+		return
+	}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			appendSchemaFields(fields, ft, prefix)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		name = prefix + name
+
+		switch {
+		case ft.Kind() == reflect.Struct && !isLeaf(ft):
+			appendSchemaFields(fields, ft, name+".")
+		case (ft.Kind() == reflect.Slice || ft.Kind() == reflect.Array) && ft.Elem().Kind() != reflect.Uint8:
+			elem := ft.Elem()
+			for elem.Kind() == reflect.Pointer {
+				elem = elem.Elem()
+			}
+			if elem.Kind() == reflect.Struct && !isLeaf(elem) {
+				*fields = append(*fields, schemaField{Name: name, Type: "list"})
+				appendSchemaFields(fields, elem, name+"[].")
+			} else {
+				*fields = append(*fields, schemaField{Name: name, Type: "list of " + schemaType(elem)})
+			}
+		case ft.Kind() == reflect.Map && ft.Elem().Kind() == reflect.Struct && !isLeaf(ft.Elem()):
+			*fields = append(*fields, schemaField{Name: name, Type: "map"})
+			appendSchemaFields(fields, ft.Elem(), name+".<key>.")
+		default:
+			*fields = append(*fields, schemaField{Name: name, Type: schemaType(ft)})
+		}
+	}
+}
+
+// isLeaf reports whether t is output as a single value rather than as an
+// object of its fields.
+func isLeaf(t reflect.Type) bool {
+	return t == timeType || t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) ||
+		reflect.PointerTo(t).Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType)
+}
+
+// schemaType names the JSON type of a leaf field.
+func schemaType(t reflect.Type) string {
+	if t == timeType {
+		return "time"
+	}
+	if isLeaf(t) {
+		return "string"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	default:
+		return "any"
+	}
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tessro/riff/internal/api"
+)
+
+var (
+	serveListen   string
+	serveToken    string
+	serveInterval time.Duration
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a REST API for home automation and dashboards",
+	Long: `Serve riff's controls over HTTP: devices, status, playback, volume, queue,
+Sonos groups and search, with playback events streamed over WebSocket or
+server-sent events from /v1/events.
+
+Every request except /v1/openapi.json, which describes the API, needs the
+bearer token from [serve] token in the config or --token. When neither is
+set, a random token is generated and printed at startup.
+
+Like riffd, riff serve keeps its own backends warm, so it doesn't use the
+daemon.
+
+Examples:
+  riff serve
+  riff serve --listen 0.0.0.0:8723 --token "$RIFF_TOKEN"
+  curl -H "Authorization: Bearer $RIFF_TOKEN" localhost:8723/v1/status`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveListen, "listen", "", "address to listen on (default from config, 127.0.0.1:8723)")
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "bearer token clients must send")
+	serveCmd.Flags().DurationVarP(&serveInterval, "interval", "i", time.Second, "event stream poll interval")
+	addMetricsFlag(serveCmd)
+
+	rootCmd.AddCommand(serveCmd)
+	registerOutput(serveCmd, serveResult{})
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	listen := serveListen
+	if listen == "" {
+		listen = cfg.Serve.Listen
+	}
+	token := serveToken
+	if token == "" {
+		token = cfg.Serve.Token
+	}
+	generated := token == ""
+	if generated {
+		buf := make([]byte, 24)
+		if _, err := rand.Read(buf); err != nil {
+			return fmt.Errorf("failed to generate token: %w", err)
+		}
+		token = hex.EncodeToString(buf)
+	}
+
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", listen, err)
+	}
+
+	if err := startMetrics(ctx); err != nil {
+		return err
+	}
+
+	b := newDirectBackends()
+	var groups api.Groups
+	if sb := b.Sonos(); sb != nil {
+		groups = sb
+	}
+	server := api.NewServer(b, groups, token, Version, serveInterval)
+	server.SetOriginPatterns(cfg.Serve.AllowedOrigins)
+	srv := &http.Server{
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	addr := ln.Addr().String()
+	res := serveResult{Status: "listening", URL: "http://" + addr}
+	if generated {
+		res.Token = token
+	}
+	if err := render(res, func() error {
+		fmt.Printf("Serving riff API on http://%s\n", addr)
+		fmt.Printf("  OpenAPI: http://%s/v1/openapi.json\n", addr)
+		if generated {
+			fmt.Printf("  Token:   %s\n", token)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, stop := context.WithTimeout(context.Background(), 5*time.Second)
+	defer stop()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return nil
+}
+
+// serveResult is what riff serve outputs once it is listening. Token is
+// set when one was generated for this run.
+type serveResult struct {
+	Status string `json:"status"`
+	URL    string `json:"url"`
+	Token  string `json:"token,omitempty"`
+}
@@ -0,0 +1,290 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tessro/riff/internal/config"
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/fade"
+	"github.com/tessro/riff/internal/sleeptimer"
+)
+
+var (
+	sleepDevice  string
+	sleepFade    time.Duration
+	sleepCurve   string
+	sleepWait    bool
+	sleepTimerID string
+)
+
+var sleepCmd = &cobra.Command{
+	Use:   "sleep [duration|end-of-track|end-of-album|off]",
+	Short: "Pause playback later",
+	Long: `Set a sleep timer that pauses playback after a while, at the end of the
+current track, or at the end of the current album. It works on every
+device, with an optional fade-out first. Without an argument, lists the
+timers that are set; "off" cancels them.
+
+Timers are run by riffd when it is running, and otherwise by a background
+worker, so the command returns straight away. Setting a new timer on a
+device replaces its old one.
+
+Examples:
+  riff sleep 45m --to Bedroom
+  riff sleep end-of-album --fade 30s
+  riff sleep off --to Bedroom`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runSleep,
+}
+
+var sleepRunCmd = &cobra.Command{
+	Use:    "run",
+	Short:  "Run a sleep timer in the foreground",
+	Args:   cobra.NoArgs,
+	Hidden: true,
+	RunE:   runSleepRun,
+}
+
+func init() {
+	addTargetFlags(sleepCmd, &sleepDevice)
+	sleepCmd.Flags().DurationVar(&sleepFade, "fade", 0, "Fade out over this long before pausing")
+	sleepCmd.Flags().StringVar(&sleepCurve, "curve", "", "Fade curve: linear or log (default: [defaults] fade_curve)")
+	sleepCmd.Flags().BoolVar(&sleepWait, "wait", false, "Run the timer in the foreground instead of in the background")
+	sleepRunCmd.Flags().StringVar(&sleepTimerID, "timer", "", "ID of the timer to run")
+	_ = sleepRunCmd.MarkFlagRequired("timer")
+
+	sleepCmd.AddCommand(sleepRunCmd)
+	rootCmd.AddCommand(sleepCmd)
+	registerOutput(sleepCmd, sleepResult{})
+}
+
+// sleepStore opens the sleep timers in riff's state directory.
+func sleepStore() *sleeptimer.Store {
+	return sleeptimer.NewStore(filepath.Join(config.StateDir(), "sleep-timers.json"))
+}
+
+// newSleepRunner creates a runner for owner's sleep timers that plays
+// through b.
+func newSleepRunner(b core.PlayerResolver, owner string) *sleeptimer.Runner {
+	return sleeptimer.NewRunner(sleepStore(), b, sleeptimer.Options{
+		Owner:    owner,
+		ClaimDir: fadeClaimDir(),
+	})
+}
+
+func runSleep(cmd *cobra.Command, args []string) error {
+	switch {
+	case len(args) == 0:
+		return listSleepTimers()
+	case args[0] == "off":
+		return cancelSleepTimers(sleepDevice)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	curve := sleepCurve
+	if curve == "" {
+		curve = cfg.Defaults.FadeCurve
+	}
+	if _, err := fade.ParseCurve(curve); err != nil {
+		return err
+	}
+
+	var b *backends
+	if !sleepWait {
+		b = newBackends()
+	} else {
+		b = newDirectBackends()
+	}
+	p, d, err := b.PlayerFor(ctx, sleepDevice)
+	if err != nil {
+		return err
+	}
+	state, err := p.GetState(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get playback state: %w", err)
+	}
+	if d != nil {
+		// Pause what was targeted, not whatever the state reports
+		s := *state
+		s.Device = d
+		state = &s
+	}
+
+	timer, err := sleeptimer.New(args[0], state, time.Now())
+	if err != nil {
+		return err
+	}
+	timer.Fade = sleepFade
+	timer.Curve = curve
+	timer.Owner = sleeptimer.OwnerWorker
+	if b.remote != nil {
+		timer.Owner = sleeptimer.OwnerDaemon
+	}
+
+	store := sleepStore()
+	if err := store.Put(*timer); err != nil {
+		return err
+	}
+	if timer.Owner == sleeptimer.OwnerWorker && !sleepWait {
+		if err := startSleepWorker(timer.ID); err != nil {
+			_, _ = store.Remove(timer.ID)
+			return err
+		}
+	}
+
+	info := newSleepTimerInfo(timer, state)
+	if err := renderf(sleepResult{Status: "set", Timer: &info},
+		"💤 Pausing %s %s", timer.Name, timer.Describe(state, time.Now())); err != nil {
+		return err
+	}
+
+	if sleepWait {
+		return newSleepRunner(b, timer.Owner).RunTimer(ctx, timer.ID)
+	}
+	return nil
+}
+
+// startSleepWorker runs the timer with the given ID in a background riff
+// process. The worker logs through the [log] config; set a file there to
+// keep its records, since it has no terminal.
+func startSleepWorker(id string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to start sleep timer: %w", err)
+	}
+	args := []string{"sleep", "run", "--timer", id}
+	if cfgFile != "" {
+		args = append([]string{"--config", cfgFile}, args...)
+	}
+
+	worker := exec.Command(exe, args...)
+	if err := worker.Start(); err != nil {
+		return fmt.Errorf("failed to start sleep timer: %w", err)
+	}
+	return worker.Process.Release()
+}
+
+func runSleepRun(cmd *cobra.Command, args []string) error {
+	// Outlive the terminal that set the timer
+	signal.Ignore(syscall.SIGHUP)
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return newSleepRunner(newDirectBackends(), sleeptimer.OwnerWorker).RunTimer(ctx, sleepTimerID)
+}
+
+func listSleepTimers() error {
+	timers, err := sleepStore().Load()
+	if err != nil {
+		return err
+	}
+
+	res := sleepResult{Timers: make([]sleepTimerInfo, len(timers))}
+	for i := range timers {
+		res.Timers[i] = newSleepTimerInfo(&timers[i], nil)
+	}
+	return render(res, func() error {
+		if len(timers) == 0 {
+			fmt.Println("No sleep timers")
+			return nil
+		}
+		now := time.Now()
+		for _, t := range timers {
+			fmt.Printf("💤 %s: pausing %s\n", t.Name, t.Describe(nil, now))
+		}
+		return nil
+	})
+}
+
+// cancelSleepTimers cancels the timer on the device named or identified by
+// target, or every timer when target is empty.
+func cancelSleepTimers(target string) error {
+	store := sleepStore()
+	timers, err := store.Load()
+	if err != nil {
+		return err
+	}
+
+	cancelled := []string{}
+	for _, t := range timers {
+		if target != "" && t.Device != target && !strings.EqualFold(t.Name, target) {
+			continue
+		}
+		if _, err := store.Remove(t.ID); err != nil {
+			return err
+		}
+		cancelled = append(cancelled, t.Name)
+	}
+
+	var text string
+	switch {
+	case len(cancelled) > 0:
+		text = "Cancelled sleep timer on " + strings.Join(cancelled, ", ")
+	case target != "":
+		text = "No sleep timer on " + target
+	default:
+		text = "No sleep timers"
+	}
+	return renderf(sleepResult{Status: "cancelled", Cancelled: cancelled}, "%s", text)
+}
+
+// sleepResult is the output of riff sleep: the timer set, the timers
+// listed, or the names of the devices whose timers were cancelled.
+type sleepResult struct {
+	Status    string           `json:"status,omitempty"`
+	Timer     *sleepTimerInfo  `json:"timer,omitempty"`
+	Timers    []sleepTimerInfo `json:"timers,omitzero"`
+	Cancelled []string         `json:"cancelled,omitzero"`
+}
+
+func (r sleepResult) records() any {
+	if r.Timer != nil {
+		return []sleepTimerInfo{*r.Timer}
+	}
+	return r.Timers
+}
+
+// sleepTimerInfo describes a sleep timer. RemainingMS is set when the
+// time left is known.
+type sleepTimerInfo struct {
+	Device      string    `json:"device"`
+	DeviceName  string    `json:"device_name"`
+	Mode        string    `json:"mode"`
+	Owner       string    `json:"owner"`
+	Deadline    time.Time `json:"deadline,omitzero"`
+	Fade        string    `json:"fade,omitempty"`
+	RemainingMS *int64    `json:"remaining_ms,omitempty"`
+}
+
+// newSleepTimerInfo describes a timer for output. state may be nil.
+func newSleepTimerInfo(t *sleeptimer.Timer, state *core.PlaybackState) sleepTimerInfo {
+	info := sleepTimerInfo{
+		Device:     t.Device,
+		DeviceName: t.Name,
+		Mode:       t.Mode,
+		Owner:      t.Owner,
+		Deadline:   t.Deadline,
+	}
+	if t.Fade > 0 {
+		info.Fade = t.Fade.String()
+	}
+	if state != nil || t.Mode == sleeptimer.ModeDuration {
+		if left, known := t.Remaining(state, nil, time.Now()); known {
+			ms := left.Milliseconds()
+			info.RemainingMS = &ms
+		}
+	}
+	return info
+}
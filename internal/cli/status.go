@@ -2,17 +2,15 @@ package cli
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"os"
+	"log/slog"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/sleeptimer"
 	"github.com/tessro/riff/internal/sonos"
-	"github.com/tessro/riff/internal/spotify/auth"
-	"github.com/tessro/riff/internal/spotify/client"
 	"github.com/tessro/riff/internal/spotify/player"
 )
 
@@ -34,6 +32,7 @@ func init() {
 	statusCmd.Flags().BoolVar(&statusSonos, "sonos", false, "Show only Sonos status")
 	statusCmd.Flags().StringVarP(&statusDevice, "device", "d", "", "Show status for specific device")
 	rootCmd.AddCommand(statusCmd)
+	registerOutput(statusCmd, []statusInfo{})
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
@@ -48,9 +47,7 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	if showSpotify {
 		state, err := getSpotifyStatus(ctx)
 		if err != nil {
-			if Verbose() {
-				fmt.Fprintf(os.Stderr, "Spotify error: %v\n", err)
-			}
+			slog.DebugContext(ctx, "spotify status failed", "error", err)
 		} else if state != nil {
 			states = append(states, state)
 		}
@@ -59,26 +56,12 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	if showSonos {
 		sonosStates, err := getSonosStatus(ctx)
 		if err != nil {
-			if Verbose() {
-				fmt.Fprintf(os.Stderr, "Sonos error: %v\n", err)
-			}
+			slog.DebugContext(ctx, "sonos status failed", "error", err)
 		} else {
 			states = append(states, sonosStates...)
 		}
 	}
 
-	if len(states) == 0 {
-		if JSONOutput() {
-			_ = json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
-				"playing": false,
-				"message": "No active playback",
-			})
-		} else {
-			fmt.Println("No active playback")
-		}
-		return nil
-	}
-
 	// Filter by device if specified
 	if statusDevice != "" {
 		filtered := make([]*statusResult, 0)
@@ -90,42 +73,43 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		states = filtered
 	}
 
-	if JSONOutput() {
-		return outputStatusJSON(states)
+	// Attach sleep timers
+	if timers, err := sleepStore().Load(); err == nil {
+		for _, s := range states {
+			for i := range timers {
+				if s.Device != nil && timers[i].Device == s.Device.ID {
+					s.Sleep = &timers[i]
+				}
+			}
+		}
+	}
+
+	output := make([]statusInfo, len(states))
+	for i, s := range states {
+		output[i] = newStatusInfo(s)
 	}
-	return outputStatusTable(states)
+	return render(output, func() error {
+		if len(states) == 0 {
+			fmt.Println("No active playback")
+			return nil
+		}
+		return outputStatusTable(states)
+	})
 }
 
 type statusResult struct {
 	Platform string
 	State    *core.PlaybackState
 	Device   *core.Device
+	Sleep    *sleeptimer.Timer
 }
 
 func getSpotifyStatus(ctx context.Context) (*statusResult, error) {
-	if cfg.Spotify.ClientID == "" {
-		return nil, fmt.Errorf("spotify not configured")
-	}
-
-	storage, err := auth.NewTokenStorage("")
+	spotifyClient, err := getSpotifyClient()
 	if err != nil {
 		return nil, err
 	}
 
-	spotifyClient := client.New(cfg.Spotify.ClientID, storage)
-	if Verbose() {
-		spotifyClient.SetVerbose(true, func(format string, args ...interface{}) {
-			fmt.Fprintf(os.Stderr, format+"\n", args...)
-		})
-	}
-	if err := spotifyClient.LoadToken(); err != nil {
-		return nil, err
-	}
-
-	if !spotifyClient.HasToken() {
-		return nil, fmt.Errorf("not authenticated")
-	}
-
 	p := player.New(spotifyClient)
 	state, err := p.GetState(ctx)
 	if err != nil {
@@ -148,15 +132,10 @@ func getSonosStatus(ctx context.Context) ([]*statusResult, error) {
 	}
 
 	if len(devices) == 0 {
-		if Verbose() {
-			fmt.Fprintf(os.Stderr, "Sonos: no devices discovered\n")
-		}
+		slog.DebugContext(ctx, "sonos found no devices")
 		return nil, nil
 	}
-
-	if Verbose() {
-		fmt.Fprintf(os.Stderr, "Sonos: found %d devices\n", len(devices))
-	}
+	slog.DebugContext(ctx, "sonos devices discovered", "count", len(devices))
 
 	// Get zone groups to find coordinators (only coordinators have playback state)
 	groups, err := client.ListGroups(ctx, devices[0])
@@ -164,9 +143,7 @@ func getSonosStatus(ctx context.Context) ([]*statusResult, error) {
 		return nil, err
 	}
 
-	if Verbose() {
-		fmt.Fprintf(os.Stderr, "Sonos: found %d groups\n", len(groups))
-	}
+	slog.DebugContext(ctx, "sonos groups listed", "count", len(groups))
 
 	var results []*statusResult
 	for _, g := range groups {
@@ -174,23 +151,17 @@ func getSonosStatus(ctx context.Context) ([]*statusResult, error) {
 			continue
 		}
 
-		if Verbose() {
-			fmt.Fprintf(os.Stderr, "Sonos: checking group %s (coordinator: %s)\n", g.Name, g.Coordinator.Name)
-		}
+		slog.DebugContext(ctx, "sonos checking group", "group", g.Name, "coordinator", g.Coordinator.Name)
 
 		// Get playback state from coordinator
 		sonosPlayer := sonos.NewPlayer(client, g.Coordinator)
 		state, err := sonosPlayer.GetState(ctx)
 		if err != nil {
-			if Verbose() {
-				fmt.Fprintf(os.Stderr, "Sonos %s error: %v\n", g.Name, err)
-			}
+			slog.DebugContext(ctx, "sonos group status failed", "group", g.Name, "error", err)
 			continue
 		}
 
-		if Verbose() {
-			fmt.Fprintf(os.Stderr, "Sonos %s: isPlaying=%v, track=%v\n", g.Name, state.IsPlaying, state.Track != nil)
-		}
+		slog.DebugContext(ctx, "sonos group status", "group", g.Name, "playing", state.IsPlaying, "has_track", state.Track != nil)
 
 		// Only include if playing or has a track
 		if state.Track != nil || state.IsPlaying {
@@ -205,44 +176,68 @@ func getSonosStatus(ctx context.Context) ([]*statusResult, error) {
 	return results, nil
 }
 
-func outputStatusJSON(states []*statusResult) error {
-	output := make([]map[string]interface{}, 0, len(states))
+// statusInfo is a player in the output of riff status. The track and
+// progress fields are set when the player reports what is playing.
+type statusInfo struct {
+	Platform             string            `json:"platform"`
+	IsPlaying            bool              `json:"is_playing"`
+	Volume               int               `json:"volume"`
+	Track                *statusTrack      `json:"track,omitempty"`
+	Progress             string            `json:"progress,omitempty"`
+	ProgressPercent      *float64          `json:"progress_percent,omitempty"`
+	TrackInfoUnavailable bool              `json:"track_info_unavailable,omitempty"`
+	Device               *statusDeviceInfo `json:"device,omitempty"`
+	SleepTimer           *sleepTimerInfo   `json:"sleep_timer,omitempty"`
+}
 
-	for _, s := range states {
-		item := map[string]interface{}{
-			"platform":   s.Platform,
-			"is_playing": s.State.IsPlaying,
-			"volume":     s.State.Volume,
-		}
+// statusTrack is the track in riff status.
+type statusTrack struct {
+	Title    string `json:"title"`
+	Artist   string `json:"artist"`
+	Album    string `json:"album"`
+	Duration string `json:"duration"`
+	URI      string `json:"uri"`
+}
 
-		if s.State.Track != nil {
-			item["track"] = map[string]interface{}{
-				"title":    s.State.Track.Title,
-				"artist":   s.State.Track.Artist,
-				"album":    s.State.Track.Album,
-				"duration": s.State.Track.Duration.String(),
-				"uri":      s.State.Track.URI,
-			}
-			item["progress"] = s.State.Progress.String()
-			item["progress_percent"] = s.State.ProgressPercent()
-		} else if s.State.IsPlaying {
-			// Playing but no track info available
-			item["track_info_unavailable"] = true
-		}
+// statusDeviceInfo is the device in riff status.
+type statusDeviceInfo struct {
+	ID       string          `json:"id"`
+	Name     string          `json:"name"`
+	Type     core.DeviceType `json:"type"`
+	IsActive bool            `json:"is_active"`
+}
 
-		if s.Device != nil {
-			item["device"] = map[string]interface{}{
-				"id":        s.Device.ID,
-				"name":      s.Device.Name,
-				"type":      s.Device.Type,
-				"is_active": s.Device.IsActive,
-			}
-		}
+func newStatusInfo(s *statusResult) statusInfo {
+	info := statusInfo{
+		Platform:  s.Platform,
+		IsPlaying: s.State.IsPlaying,
+		Volume:    s.State.Volume,
+	}
 
-		output = append(output, item)
+	if t := s.State.Track; t != nil {
+		info.Track = &statusTrack{
+			Title:    t.Title,
+			Artist:   t.Artist,
+			Album:    t.Album,
+			Duration: t.Duration.String(),
+			URI:      t.URI,
+		}
+		info.Progress = s.State.Progress.String()
+		percent := s.State.ProgressPercent()
+		info.ProgressPercent = &percent
+	} else if s.State.IsPlaying {
+		// Playing but no track info available
+		info.TrackInfoUnavailable = true
 	}
 
-	return json.NewEncoder(os.Stdout).Encode(output)
+	if d := s.Device; d != nil {
+		info.Device = &statusDeviceInfo{ID: d.ID, Name: d.Name, Type: d.Type, IsActive: d.IsActive}
+	}
+	if s.Sleep != nil {
+		timer := newSleepTimerInfo(s.Sleep, s.State)
+		info.SleepTimer = &timer
+	}
+	return info
 }
 
 func outputStatusTable(states []*statusResult) error {
@@ -269,6 +264,7 @@ func outputStatusTable(states []*statusResult) error {
 				}
 				fmt.Println()
 			}
+			printSleepTimer(s)
 			continue
 		}
 
@@ -296,11 +292,19 @@ func outputStatusTable(states []*statusResult) error {
 			}
 			fmt.Println()
 		}
+		printSleepTimer(s)
 	}
 
 	return nil
 }
 
+// printSleepTimer shows the countdown of a device's sleep timer, if set.
+func printSleepTimer(s *statusResult) {
+	if s.Sleep != nil {
+		fmt.Printf("    💤 Pausing %s\n", s.Sleep.Describe(s.State, time.Now()))
+	}
+}
+
 func formatProgressBar(percent float64, width int) string {
 	filled := int(percent / 100 * float64(width))
 	if filled > width {
@@ -5,17 +5,16 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
-	"strings"
+	"path/filepath"
+	"reflect"
 	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/tessro/riff/internal/config"
 	"github.com/tessro/riff/internal/core"
-	"github.com/tessro/riff/internal/sonos"
-	"github.com/tessro/riff/internal/spotify/auth"
-	"github.com/tessro/riff/internal/spotify/client"
-	"github.com/tessro/riff/internal/spotify/player"
 	"github.com/tessro/riff/internal/tail"
+	"github.com/tessro/riff/internal/webhook"
 )
 
 var (
@@ -25,6 +24,7 @@ var (
 	tailTimestamp bool
 	tailFormat    string
 	tailInterval  time.Duration
+	tailWebhooks  []string
 )
 
 var tailCmd = &cobra.Command{
@@ -38,34 +38,52 @@ Events tracked:
   - Track skips (song skipped before completion)
   - Pause/Resume
   - Volume changes
-  - Device changes`,
+  - Device changes
+
+Events are also POSTed as JSON to every [[webhooks]] entry in the config
+whose filters match, and to each --webhook URL. Deliveries that still fail
+after retrying are appended to webhooks-dead-letter.jsonl in riff's cache
+directory.
+
+With --json, each event is printed as a JSON line, which 'riff rules check'
+can replay.
+
+Examples:
+  riff tail -t
+  riff tail --json > events.jsonl
+  riff tail --webhook https://dashboard.example.com/hooks/riff`,
 	RunE: runTail,
 }
 
 func init() {
 	tailCmd.Flags().BoolVarP(&tailAll, "all", "a", false, "watch all devices")
-	tailCmd.Flags().StringVarP(&tailDevice, "device", "d", "", "device to watch")
+	addTargetFlags(tailCmd, &tailDevice)
 	tailCmd.Flags().BoolVar(&tailNoEmoji, "no-emoji", false, "disable emoji output")
 	tailCmd.Flags().BoolVarP(&tailTimestamp, "timestamp", "t", false, "show timestamps")
 	tailCmd.Flags().StringVarP(&tailFormat, "format", "f", "", "custom format template")
 	tailCmd.Flags().DurationVarP(&tailInterval, "interval", "i", time.Second, "poll interval")
+	tailCmd.Flags().StringArrayVar(&tailWebhooks, "webhook", nil, "also POST every event to this URL (repeatable)")
+	addMetricsFlag(tailCmd)
 
 	rootCmd.AddCommand(tailCmd)
+	registerOutput(tailCmd, []tail.Event{})
 }
 
 func runTail(cmd *cobra.Command, args []string) error {
-	// Get player (placeholder - would get from config/discovery)
-	player, err := getPlayer()
+	player, _, err := resolvePlayer(cmd.Context(), tailDevice)
 	if err != nil {
 		return fmt.Errorf("get player: %w", err)
 	}
 
 	// Create formatter
-	formatter := tail.NewFormatter(
-		tail.WithEmoji(!tailNoEmoji),
-		tail.WithTimestamp(tailTimestamp),
-		tail.WithTemplate(tailFormat),
-	)
+	printer := &eventPrinter{
+		formatter: tail.NewFormatter(
+			tail.WithEmoji(!tailNoEmoji),
+			tail.WithTimestamp(tailTimestamp),
+			tail.WithTemplate(tailFormat),
+		),
+		records: newRecordWriter(os.Stdout, reflect.TypeFor[tail.Event]()),
+	}
 
 	// Handle Ctrl+C gracefully
 	ctx, cancel := context.WithCancel(cmd.Context())
@@ -79,8 +97,20 @@ func runTail(cmd *cobra.Command, args []string) error {
 		cancel()
 	}()
 
+	if err := startMetrics(ctx); err != nil {
+		return err
+	}
+
+	hooks, err := newWebhookRunner()
+	if err != nil {
+		return err
+	}
+	if hooks != nil {
+		go func() { _ = hooks.Run(ctx) }()
+	}
+
 	// Show recently played tracks and current song on startup
-	showInitialState(ctx, player, formatter)
+	showInitialState(ctx, player, printer)
 
 	// Create watcher
 	watcher := tail.NewWatcher(player, tailInterval)
@@ -98,7 +128,10 @@ func runTail(cmd *cobra.Command, args []string) error {
 			if !ok {
 				return nil
 			}
-			fmt.Println(formatter.Format(event))
+			printer.print(event)
+			if hooks != nil {
+				hooks.Send(event)
+			}
 
 		case err := <-errCh:
 			if err == context.Canceled {
@@ -109,97 +142,86 @@ func runTail(cmd *cobra.Command, args []string) error {
 	}
 }
 
+// newWebhookRunner returns a runner for the configured webhooks plus any
+// --webhook URLs, or nil when there are none.
+func newWebhookRunner() (*webhook.Runner, error) {
+	var hooks []webhook.Hook
+	for _, c := range cfg.Webhooks {
+		hooks = append(hooks, webhook.Hook{
+			URL:         c.URL,
+			Events:      c.Events,
+			Devices:     c.Devices,
+			Secret:      c.Secret,
+			Template:    c.Template,
+			MaxAttempts: c.MaxAttempts,
+			Timeout:     time.Duration(c.Timeout) * time.Second,
+		})
+	}
+	for _, u := range tailWebhooks {
+		hooks = append(hooks, webhook.Hook{URL: u})
+	}
+	if len(hooks) == 0 {
+		return nil, nil
+	}
+
+	return webhook.New(hooks, webhook.Options{
+		DeadLetter: filepath.Join(config.StateDir(), "webhooks-dead-letter.jsonl"),
+	})
+}
+
 // showInitialState displays recently played tracks and current song on startup.
-func showInitialState(ctx context.Context, p core.Player, formatter *tail.Formatter) {
-	// Get recently played tracks (show last 5)
-	history, err := p.GetRecentlyPlayed(ctx, 5)
-	if err == nil && len(history) > 0 {
-		// Print in reverse order (oldest first) so newest is at bottom
-		for i := len(history) - 1; i >= 0; i-- {
-			entry := history[i]
-			if entry.Track != nil {
-				timestamp := ""
-				if tailTimestamp {
-					timestamp = entry.PlayedAt.Local().Format("15:04:05") + " "
-				}
-				emoji := ""
-				if !tailNoEmoji {
-					emoji = "⏪ "
-				}
-				fmt.Printf("%s%s%s — %s\n", timestamp, emoji, entry.Track.Artist, entry.Track.Title)
-			}
-		}
+func showInitialState(ctx context.Context, p core.Player, printer *eventPrinter) {
+	// Get recently played tracks (show last 5) where the device keeps history
+	if !StructuredOutput() && p.Capabilities().Has(core.CapHistory) {
+		showHistory(ctx, p)
 	}
 
 	// Get current state
 	state, err := p.GetState(ctx)
 	if err == nil && state != nil && state.Track != nil {
 		event := tail.Event{
-			Type:    tail.EventTrackChange,
-			Current: state,
+			Type:      tail.EventTrackChange,
+			Timestamp: time.Now(),
+			Current:   state,
 		}
-		fmt.Println(formatter.Format(event))
+		printer.print(event)
 	}
 }
 
-// getPlayer returns a player based on config and flags.
-func getPlayer() (core.Player, error) {
-	ctx := context.Background()
-
-	// Try Spotify first if configured
-	if cfg.Spotify.ClientID != "" {
-		storage, err := auth.NewTokenStorage("")
-		if err == nil {
-			spotifyClient := client.New(cfg.Spotify.ClientID, storage)
-			if err := spotifyClient.LoadToken(); err == nil && spotifyClient.HasToken() {
-				return player.New(spotifyClient), nil
-			}
-		}
-	}
-
-	// Try Sonos discovery
-	sonosClient := sonos.NewClient()
-	devices, err := sonosClient.Discover(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("no player available: spotify not authenticated and sonos discovery failed: %w", err)
-	}
+// eventPrinter prints events as lines of text, or as records with --json
+// or --output.
+type eventPrinter struct {
+	formatter *tail.Formatter
+	records   *recordWriter
+}
 
-	if len(devices) == 0 {
-		return nil, fmt.Errorf("no player available: spotify not authenticated and no sonos devices found")
+func (p *eventPrinter) print(event tail.Event) {
+	if StructuredOutput() {
+		_ = p.records.Write(event)
+		_ = p.records.Flush()
+		return
 	}
+	fmt.Println(p.formatter.Format(event))
+}
 
-	// If specific device requested, find it
-	if tailDevice != "" {
-		groups, err := sonosClient.ListGroups(ctx, devices[0])
-		if err == nil {
-			for _, g := range groups {
-				for _, m := range g.Members {
-					if strings.EqualFold(m.Name, tailDevice) || m.UUID == tailDevice {
-						return sonos.NewPlayer(sonosClient, m), nil
-					}
+// showHistory prints the last few played tracks, oldest first.
+func showHistory(ctx context.Context, p core.Player) {
+	history, err := p.GetRecentlyPlayed(ctx, 5)
+	if err == nil && len(history) > 0 {
+		// Print in reverse order (oldest first) so newest is at bottom
+		for i := len(history) - 1; i >= 0; i-- {
+			entry := history[i]
+			if entry.Track != nil {
+				timestamp := ""
+				if tailTimestamp {
+					timestamp = entry.PlayedAt.Local().Format("15:04:05") + " "
 				}
-			}
-		}
-		return nil, fmt.Errorf("device '%s' not found", tailDevice)
-	}
-
-	// Use default room from config or first device
-	if cfg.Sonos.DefaultRoom != "" {
-		groups, err := sonosClient.ListGroups(ctx, devices[0])
-		if err == nil {
-			for _, g := range groups {
-				if g.Coordinator != nil && strings.EqualFold(g.Coordinator.Name, cfg.Sonos.DefaultRoom) {
-					return sonos.NewPlayer(sonosClient, g.Coordinator), nil
+				emoji := ""
+				if !tailNoEmoji {
+					emoji = "⏪ "
 				}
+				fmt.Printf("%s%s%s — %s\n", timestamp, emoji, entry.Track.Artist, entry.Track.Title)
 			}
 		}
 	}
-
-	// Fall back to first coordinator
-	groups, err := sonosClient.ListGroups(ctx, devices[0])
-	if err == nil && len(groups) > 0 && groups[0].Coordinator != nil {
-		return sonos.NewPlayer(sonosClient, groups[0].Coordinator), nil
-	}
-
-	return sonos.NewPlayer(sonosClient, devices[0]), nil
 }
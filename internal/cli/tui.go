@@ -1,7 +1,7 @@
 package cli
 
 import (
-	"fmt"
+	"context"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -36,14 +36,24 @@ Keyboard shortcuts:
 
 func init() {
 	tuiCmd.Flags().IntVar(&tuiRefresh, "refresh", 1000, "Refresh interval in milliseconds")
+	addMetricsFlag(tuiCmd)
 	rootCmd.AddCommand(tuiCmd)
 }
 
 func runTUI(cmd *cobra.Command, args []string) error {
-	if cfg.Spotify.ClientID == "" {
-		return fmt.Errorf("spotify not configured. Set spotify.client_id in ~/.riffrc")
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	b := newBackends()
+	p, _, err := b.PlayerFor(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	if err := startMetrics(ctx); err != nil {
+		return err
 	}
 
 	refreshRate := time.Duration(tuiRefresh) * time.Millisecond
-	return tui.Run(cfg.Spotify.ClientID, refreshRate, cfg.Defaults.Device)
+	return tui.Run(b.Registry, p, refreshRate, cfg.Defaults.Device, sleepStore())
 }
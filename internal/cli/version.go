@@ -1,7 +1,6 @@
 package cli
 
 import (
-	"encoding/json"
 	"fmt"
 	"runtime"
 
@@ -19,30 +18,38 @@ var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Show version information",
 	Run: func(cmd *cobra.Command, args []string) {
-		if JSONOutput() {
-			info := map[string]string{
-				"version":    Version,
-				"commit":     Commit,
-				"build_date": BuildDate,
-				"go_version": runtime.Version(),
-				"os":         runtime.GOOS,
-				"arch":       runtime.GOARCH,
-			}
-			out, _ := json.MarshalIndent(info, "", "  ")
-			fmt.Println(string(out))
-			return
-		}
-
-		fmt.Printf("riff %s\n", Version)
-		if Verbose() {
-			fmt.Printf("  commit:     %s\n", Commit)
-			fmt.Printf("  built:      %s\n", BuildDate)
-			fmt.Printf("  go version: %s\n", runtime.Version())
-			fmt.Printf("  platform:   %s/%s\n", runtime.GOOS, runtime.GOARCH)
+		info := versionResult{
+			Version:   Version,
+			Commit:    Commit,
+			BuildDate: BuildDate,
+			GoVersion: runtime.Version(),
+			OS:        runtime.GOOS,
+			Arch:      runtime.GOARCH,
 		}
+		_ = render(info, func() error {
+			fmt.Printf("riff %s\n", Version)
+			if Verbose() {
+				fmt.Printf("  commit:     %s\n", Commit)
+				fmt.Printf("  built:      %s\n", BuildDate)
+				fmt.Printf("  go version: %s\n", runtime.Version())
+				fmt.Printf("  platform:   %s/%s\n", runtime.GOOS, runtime.GOARCH)
+			}
+			return nil
+		})
 	},
 }
 
+// versionResult is the output of riff version.
+type versionResult struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
 func init() {
 	rootCmd.AddCommand(versionCmd)
+	registerOutput(versionCmd, versionResult{})
 }
@@ -66,6 +66,18 @@ func findConfigFile() string {
 	return ""
 }
 
+// StateDir returns the directory riff keeps state in that must survive
+// clearing the cache, such as sleep timers: $XDG_STATE_HOME/riff, or
+// ~/.local/state/riff.
+func StateDir() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "riff")
+}
+
 // applyEnvOverrides applies environment variable overrides to the config.
 func applyEnvOverrides(cfg *Config) {
 	// Spotify
@@ -75,6 +87,21 @@ func applyEnvOverrides(cfg *Config) {
 	if v := os.Getenv("RIFF_SPOTIFY_REDIRECT_URI"); v != "" {
 		cfg.Spotify.RedirectURI = v
 	}
+	if v := os.Getenv("RIFF_SPOTIFY_CALLBACK_ADDR"); v != "" {
+		cfg.Spotify.CallbackAddr = v
+	}
+	if v := os.Getenv("RIFF_SPOTIFY_API_URL"); v != "" {
+		cfg.Spotify.HTTP.APIURL = v
+	}
+	if v := os.Getenv("RIFF_SPOTIFY_ACCOUNTS_URL"); v != "" {
+		cfg.Spotify.HTTP.AccountsURL = v
+	}
+	if v := os.Getenv("RIFF_SPOTIFY_PROXY"); v != "" {
+		cfg.Spotify.HTTP.Proxy = v
+	}
+	if v := os.Getenv("RIFF_TOKEN_STORE"); v != "" {
+		cfg.Spotify.TokenStore.Backend = v
+	}
 
 	// Sonos
 	if v := os.Getenv("RIFF_SONOS_DEFAULT_ROOM"); v != "" {
@@ -100,7 +127,28 @@ func applyEnvOverrides(cfg *Config) {
 	if v := os.Getenv("RIFF_LOG_LEVEL"); v != "" {
 		cfg.Log.Level = v
 	}
+	if v := os.Getenv("RIFF_LOG_FORMAT"); v != "" {
+		cfg.Log.Format = v
+	}
 	if v := os.Getenv("RIFF_LOG_FILE"); v != "" {
 		cfg.Log.File = v
 	}
+
+	// Serve
+	if v := os.Getenv("RIFF_SERVE_TOKEN"); v != "" {
+		cfg.Serve.Token = v
+	}
+
+	// MQTT
+	if v := os.Getenv("RIFF_MQTT_BROKER"); v != "" {
+		cfg.MQTT.Broker = v
+	}
+	if v := os.Getenv("RIFF_MQTT_PASSWORD"); v != "" {
+		cfg.MQTT.Password = v
+	}
+
+	// Metrics
+	if v := os.Getenv("RIFF_METRICS_LISTEN"); v != "" {
+		cfg.Metrics.Listen = v
+	}
 }
@@ -5,14 +5,28 @@ func Default() *Config {
 	return &Config{
 		Spotify: SpotifyConfig{
 			RedirectURI: "http://127.0.0.1:8888/callback",
+			TokenStore: TokenStoreConfig{
+				Backend:       "file",
+				PassphraseEnv: "RIFF_TOKEN_PASSPHRASE",
+			},
+			HTTP: SpotifyHTTPConfig{
+				APIURL:         "https://api.spotify.com/v1",
+				AccountsURL:    "https://accounts.spotify.com",
+				Timeout:        30,
+				ConnectTimeout: 10,
+			},
 		},
 		Sonos: SonosConfig{
 			DiscoveryTimeout: 5,
 		},
+		MPD: MPDConfig{
+			Timeout: 5,
+		},
 		Defaults: DefaultsConfig{
-			Volume:  50,
-			Shuffle: false,
-			Repeat:  "off",
+			Volume:    50,
+			Shuffle:   false,
+			Repeat:    "off",
+			FadeCurve: "linear",
 		},
 		Tail: TailConfig{
 			Enabled:  false,
@@ -23,7 +37,23 @@ func Default() *Config {
 			RefreshInterval: 1000,
 		},
 		Log: LogConfig{
-			Level: "info",
+			Level:      "info",
+			Format:     "text",
+			MaxSize:    10,
+			MaxBackups: 3,
+		},
+		Daemon: DaemonConfig{
+			RefreshInterval: 300,
+		},
+		Serve: ServeConfig{
+			Listen: "127.0.0.1:8723",
+		},
+		MQTT: MQTTConfig{
+			Broker:          "tcp://localhost:1883",
+			ClientID:        "riff",
+			TopicPrefix:     "riff",
+			DiscoveryPrefix: "homeassistant",
+			RefreshInterval: 300,
 		},
 	}
 }
@@ -36,12 +66,40 @@ func (c *Config) ApplyDefaults() {
 	if c.Spotify.RedirectURI == "" {
 		c.Spotify.RedirectURI = d.Spotify.RedirectURI
 	}
+	if c.Spotify.TokenStore.Backend == "" {
+		c.Spotify.TokenStore.Backend = d.Spotify.TokenStore.Backend
+	}
+	if c.Spotify.TokenStore.PassphraseEnv == "" {
+		c.Spotify.TokenStore.PassphraseEnv = d.Spotify.TokenStore.PassphraseEnv
+	}
+	if c.Spotify.HTTP.APIURL == "" {
+		c.Spotify.HTTP.APIURL = d.Spotify.HTTP.APIURL
+	}
+	if c.Spotify.HTTP.AccountsURL == "" {
+		c.Spotify.HTTP.AccountsURL = d.Spotify.HTTP.AccountsURL
+	}
+	if c.Spotify.HTTP.Timeout == 0 {
+		c.Spotify.HTTP.Timeout = d.Spotify.HTTP.Timeout
+	}
+	if c.Spotify.HTTP.ConnectTimeout == 0 {
+		c.Spotify.HTTP.ConnectTimeout = d.Spotify.HTTP.ConnectTimeout
+	}
 
 	// Sonos
 	if c.Sonos.DiscoveryTimeout == 0 {
 		c.Sonos.DiscoveryTimeout = d.Sonos.DiscoveryTimeout
 	}
 
+	// MPD
+	if c.MPD.Timeout == 0 {
+		c.MPD.Timeout = d.MPD.Timeout
+	}
+	for i := range c.MPD.Hosts {
+		if c.MPD.Hosts[i].Name == "" {
+			c.MPD.Hosts[i].Name = c.MPD.Hosts[i].Address
+		}
+	}
+
 	// Defaults
 	if c.Defaults.Volume == 0 {
 		c.Defaults.Volume = d.Defaults.Volume
@@ -49,6 +107,9 @@ func (c *Config) ApplyDefaults() {
 	if c.Defaults.Repeat == "" {
 		c.Defaults.Repeat = d.Defaults.Repeat
 	}
+	if c.Defaults.FadeCurve == "" {
+		c.Defaults.FadeCurve = d.Defaults.FadeCurve
+	}
 
 	// Tail
 	if c.Tail.Interval == 0 {
@@ -67,4 +128,40 @@ func (c *Config) ApplyDefaults() {
 	if c.Log.Level == "" {
 		c.Log.Level = d.Log.Level
 	}
+	if c.Log.Format == "" {
+		c.Log.Format = d.Log.Format
+	}
+	if c.Log.MaxSize == 0 {
+		c.Log.MaxSize = d.Log.MaxSize
+	}
+	if c.Log.MaxBackups == 0 {
+		c.Log.MaxBackups = d.Log.MaxBackups
+	}
+
+	// Daemon
+	if c.Daemon.RefreshInterval == 0 {
+		c.Daemon.RefreshInterval = d.Daemon.RefreshInterval
+	}
+
+	// Serve
+	if c.Serve.Listen == "" {
+		c.Serve.Listen = d.Serve.Listen
+	}
+
+	// MQTT
+	if c.MQTT.Broker == "" {
+		c.MQTT.Broker = d.MQTT.Broker
+	}
+	if c.MQTT.ClientID == "" {
+		c.MQTT.ClientID = d.MQTT.ClientID
+	}
+	if c.MQTT.TopicPrefix == "" {
+		c.MQTT.TopicPrefix = d.MQTT.TopicPrefix
+	}
+	if c.MQTT.DiscoveryPrefix == "" {
+		c.MQTT.DiscoveryPrefix = d.MQTT.DiscoveryPrefix
+	}
+	if c.MQTT.RefreshInterval == 0 {
+		c.MQTT.RefreshInterval = d.MQTT.RefreshInterval
+	}
 }
@@ -2,18 +2,62 @@ package config
 
 // Config is the root configuration structure.
 type Config struct {
-	Spotify  SpotifyConfig  `toml:"spotify"`
-	Sonos    SonosConfig    `toml:"sonos"`
-	Defaults DefaultsConfig `toml:"defaults"`
-	Tail     TailConfig     `toml:"tail"`
-	TUI      TUIConfig      `toml:"tui"`
-	Log      LogConfig      `toml:"log"`
+	Spotify  SpotifyConfig          `toml:"spotify"`
+	Sonos    SonosConfig            `toml:"sonos"`
+	MPD      MPDConfig              `toml:"mpd"`
+	Defaults DefaultsConfig         `toml:"defaults"`
+	Tail     TailConfig             `toml:"tail"`
+	TUI      TUIConfig              `toml:"tui"`
+	Log      LogConfig              `toml:"log"`
+	Daemon   DaemonConfig           `toml:"daemon"`
+	Serve    ServeConfig            `toml:"serve"`
+	MQTT     MQTTConfig             `toml:"mqtt"`
+	Metrics  MetricsConfig          `toml:"metrics"`
+	Webhooks []WebhookConfig        `toml:"webhooks"`
+	Rules    []RuleConfig           `toml:"rules"`
+	Scenes   map[string]SceneConfig `toml:"scenes"`
 }
 
 // SpotifyConfig holds Spotify API settings.
 type SpotifyConfig struct {
 	ClientID    string `toml:"client_id"`
 	RedirectURI string `toml:"redirect_uri"`
+	// CallbackAddr is the host:port the login callback server binds to.
+	// Defaults to the port of RedirectURI on all interfaces.
+	CallbackAddr string            `toml:"callback_addr"`
+	TokenStore   TokenStoreConfig  `toml:"token_store"`
+	HTTP         SpotifyHTTPConfig `toml:"http"`
+}
+
+// SpotifyHTTPConfig controls how riff reaches the Spotify services.
+type SpotifyHTTPConfig struct {
+	// APIURL is the Web API base URL (default: https://api.spotify.com/v1).
+	APIURL string `toml:"api_url"`
+	// AccountsURL is the accounts service base URL used for login and
+	// token refresh (default: https://accounts.spotify.com).
+	AccountsURL string `toml:"accounts_url"`
+	// Proxy is an HTTP(S) proxy URL. When empty, HTTPS_PROXY and friends apply.
+	Proxy string `toml:"proxy"`
+	// CAFile is a PEM bundle of extra CA certificates to trust.
+	CAFile string `toml:"ca_file"`
+	// Timeout is the per-request timeout in seconds.
+	Timeout int `toml:"timeout"`
+	// ConnectTimeout is the TCP connect timeout in seconds.
+	ConnectTimeout int `toml:"connect_timeout"`
+}
+
+// TokenStoreConfig selects where Spotify OAuth tokens are persisted.
+type TokenStoreConfig struct {
+	// Backend is one of "file", "encrypted", or "command".
+	Backend string `toml:"backend"`
+	// Path overrides the token file location for the file and encrypted backends.
+	Path string `toml:"path"`
+	// PassphraseEnv names the environment variable holding the encryption passphrase.
+	PassphraseEnv string `toml:"passphrase_env"`
+	// KeyFile is used instead of a passphrase when set.
+	KeyFile string `toml:"key_file"`
+	// Command is the helper invoked as "<command> get|store|erase".
+	Command string `toml:"command"`
 }
 
 // SonosConfig holds Sonos connection settings.
@@ -22,12 +66,30 @@ type SonosConfig struct {
 	DiscoveryTimeout int    `toml:"discovery_timeout"`
 }
 
+// MPDConfig lists the Music Player Daemon servers riff controls.
+type MPDConfig struct {
+	Hosts []MPDHost `toml:"hosts"`
+	// Timeout is the per-command timeout in seconds.
+	Timeout int `toml:"timeout"`
+}
+
+// MPDHost is a single MPD server.
+type MPDHost struct {
+	// Name labels the host in 'riff devices' and --to. Defaults to Address.
+	Name string `toml:"name"`
+	// Address is host:port, or the path of a Unix socket.
+	Address  string `toml:"address"`
+	Password string `toml:"password"`
+}
+
 // DefaultsConfig holds default playback settings.
 type DefaultsConfig struct {
-	Volume   int    `toml:"volume"`
-	Shuffle  bool   `toml:"shuffle"`
-	Repeat   string `toml:"repeat"`
-	Device   string `toml:"device"`
+	Volume  int    `toml:"volume"`
+	Shuffle bool   `toml:"shuffle"`
+	Repeat  string `toml:"repeat"`
+	Device  string `toml:"device"`
+	// FadeCurve shapes volume fades: "linear" or "log".
+	FadeCurve string `toml:"fade_curve"`
 }
 
 // TailConfig holds settings for tail/follow mode.
@@ -44,6 +106,147 @@ type TUIConfig struct {
 
 // LogConfig holds logging settings.
 type LogConfig struct {
-	Level string `toml:"level"`
-	File  string `toml:"file"`
+	Level  string `toml:"level"`
+	Format string `toml:"format"` // text or json
+	File   string `toml:"file"`
+	// MaxSize is how many megabytes File grows to before it is rotated,
+	// keeping MaxBackups old files.
+	MaxSize    int `toml:"max_size"`
+	MaxBackups int `toml:"max_backups"`
+}
+
+// DaemonConfig controls riffd and how the CLI reaches it.
+type DaemonConfig struct {
+	// Socket overrides the Unix socket path (default: $XDG_RUNTIME_DIR/riff/riffd.sock).
+	Socket string `toml:"socket"`
+	// Disabled makes the CLI always run commands directly.
+	Disabled bool `toml:"disabled"`
+	// RefreshInterval is how often, in seconds, riffd refreshes device
+	// discovery in the background.
+	RefreshInterval int `toml:"refresh_interval"`
+	// Schedule makes riffd fire the jobs added with riff schedule add, so
+	// riff schedule run isn't needed.
+	Schedule bool `toml:"schedule"`
+}
+
+// ServeConfig controls the HTTP API started by riff serve.
+type ServeConfig struct {
+	// Listen is the host:port to bind (default: 127.0.0.1:8723).
+	Listen string `toml:"listen"`
+	// Token is the bearer token clients must send. When empty, riff serve
+	// generates one at startup.
+	Token string `toml:"token"`
+	// AllowedOrigins are the hosts of web pages, such as
+	// "dashboard.local:8080" or "*.example.com", that may open event
+	// WebSockets. By default only pages served from the API's own host can.
+	AllowedOrigins []string `toml:"allowed_origins"`
+}
+
+// MQTTConfig connects riff mqtt to a broker.
+type MQTTConfig struct {
+	// Broker is the broker URL (default: tcp://localhost:1883).
+	Broker   string `toml:"broker"`
+	ClientID string `toml:"client_id"`
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+	// TopicPrefix roots the state and command topics (default: riff).
+	TopicPrefix string `toml:"topic_prefix"`
+	// DiscoveryPrefix is Home Assistant's discovery prefix (default:
+	// homeassistant).
+	DiscoveryPrefix  string `toml:"discovery_prefix"`
+	DisableDiscovery bool   `toml:"disable_discovery"`
+	// RefreshInterval is how often, in seconds, the device list is
+	// refreshed.
+	RefreshInterval int `toml:"refresh_interval"`
+}
+
+// MetricsConfig exposes Prometheus metrics from long-running modes.
+type MetricsConfig struct {
+	// Listen is the host:port serving /metrics from long-running modes
+	// such as tail, the TUI and riffd. Empty disables it.
+	Listen string `toml:"listen"`
+}
+
+// WebhookConfig is one [[webhooks]] entry: an endpoint that riff tail
+// POSTs matching playback events to.
+type WebhookConfig struct {
+	URL string `toml:"url"`
+	// Events limits the hook to these event types (track_change,
+	// track_complete, track_skip, pause, resume, volume_change,
+	// device_change). Empty sends every event.
+	Events []string `toml:"events"`
+	// Devices limits the hook to these device names or IDs.
+	Devices []string `toml:"devices"`
+	// Secret signs each body with HMAC-SHA256 in X-Riff-Signature.
+	Secret string `toml:"secret"`
+	// Template is a text/template rendering the JSON body.
+	Template string `toml:"template"`
+	// MaxAttempts bounds delivery attempts per event (default: 3).
+	MaxAttempts int `toml:"max_attempts"`
+	// Timeout is the per-attempt timeout in seconds (default: 10).
+	Timeout int `toml:"timeout"`
+}
+
+// RuleConfig is one [[rules]] entry: an automation run by riff rules run.
+type RuleConfig struct {
+	Name string `toml:"name"`
+	// Events that trigger the rule (track_change, pause, resume, ...).
+	// Empty matches every event.
+	Events []string `toml:"events"`
+	// Device is the name or ID the event must come from.
+	Device string `toml:"device"`
+	// Platform limits the rule to spotify, sonos, mpd or upnp devices.
+	Platform string `toml:"platform"`
+	// After and Before bound the local time of day, as "HH:MM".
+	After  string `toml:"after"`
+	Before string `toml:"before"`
+	// Days limits the rule to these weekdays ("mon" ... "sun").
+	Days []string `toml:"days"`
+	// Playing requires the device to be playing (true) or not (false).
+	Playing *bool `toml:"playing"`
+	// For delays the actions until the device has been quiet this long,
+	// as a duration such as "10m".
+	For     string             `toml:"for"`
+	Actions []RuleActionConfig `toml:"actions"`
+}
+
+// RuleActionConfig is one action of a rule.
+type RuleActionConfig struct {
+	// Do is play, pause, next, previous, volume, volume_cap, group or
+	// ungroup.
+	Do string `toml:"do"`
+	// Target is the device to act on (default: the event's device).
+	Target string `toml:"target"`
+	// Volume is the level for volume and the ceiling for volume_cap.
+	Volume int `toml:"volume"`
+	// With is the coordinator that group joins Target to.
+	With string `toml:"with"`
+}
+
+// SceneConfig is one [scenes.<name>] entry: a multi-room setup applied by
+// riff scene apply.
+type SceneConfig struct {
+	// Rooms are the devices in the scene. The first one leads the group
+	// and is where the source starts.
+	Rooms []SceneRoomConfig `toml:"rooms"`
+	// Group joins every Sonos room to the first one's group.
+	Group bool `toml:"group"`
+	// Source is what to play: a Spotify URI, a stream URL, or
+	// "playlist:<name>", "album:<name>" or "favorite:<name>" to look one up.
+	// Empty leaves playback alone.
+	Source string `toml:"source"`
+	// Shuffle and Repeat (off, track or context) set the play mode when
+	// given.
+	Shuffle *bool  `toml:"shuffle"`
+	Repeat  string `toml:"repeat"`
+}
+
+// SceneRoomConfig is one room of a scene. Unset fields are left alone.
+type SceneRoomConfig struct {
+	Name   string `toml:"name"`
+	Volume *int   `toml:"volume"`
+	// Bass, Treble (-10 to 10) and Loudness adjust Sonos tone controls.
+	Bass     *int  `toml:"bass"`
+	Treble   *int  `toml:"treble"`
+	Loudness *bool `toml:"loudness"`
 }
@@ -3,7 +3,10 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/url"
+	"strings"
+	"time"
 )
 
 // Validate checks the configuration for errors.
@@ -16,6 +19,9 @@ func (c *Config) Validate() error {
 	if err := c.Sonos.Validate(); err != nil {
 		errs = append(errs, fmt.Errorf("sonos: %w", err))
 	}
+	if err := c.MPD.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("mpd: %w", err))
+	}
 	if err := c.Defaults.Validate(); err != nil {
 		errs = append(errs, fmt.Errorf("defaults: %w", err))
 	}
@@ -28,6 +34,33 @@ func (c *Config) Validate() error {
 	if err := c.Log.Validate(); err != nil {
 		errs = append(errs, fmt.Errorf("log: %w", err))
 	}
+	if err := c.Daemon.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("daemon: %w", err))
+	}
+	if err := c.Serve.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("serve: %w", err))
+	}
+	if err := c.MQTT.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("mqtt: %w", err))
+	}
+	if err := c.Metrics.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("metrics: %w", err))
+	}
+	for i := range c.Rules {
+		if err := c.Rules[i].Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("rules[%d]: %w", i, err))
+		}
+	}
+	for name, scene := range c.Scenes {
+		if err := scene.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("scenes.%s: %w", name, err))
+		}
+	}
+	for i := range c.Webhooks {
+		if err := c.Webhooks[i].Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("webhooks[%d]: %w", i, err))
+		}
+	}
 
 	return errors.Join(errs...)
 }
@@ -39,6 +72,59 @@ func (c *SpotifyConfig) Validate() error {
 			return fmt.Errorf("invalid redirect_uri: %w", err)
 		}
 	}
+	if c.CallbackAddr != "" {
+		if _, _, err := net.SplitHostPort(c.CallbackAddr); err != nil {
+			return fmt.Errorf("invalid callback_addr: %w", err)
+		}
+	}
+	if err := c.TokenStore.Validate(); err != nil {
+		return fmt.Errorf("token_store: %w", err)
+	}
+	if err := c.HTTP.Validate(); err != nil {
+		return fmt.Errorf("http: %w", err)
+	}
+	return nil
+}
+
+// Validate checks SpotifyHTTPConfig for errors.
+func (c *SpotifyHTTPConfig) Validate() error {
+	for _, u := range []struct{ name, value string }{
+		{"api_url", c.APIURL},
+		{"accounts_url", c.AccountsURL},
+		{"proxy", c.Proxy},
+	} {
+		if u.value == "" {
+			continue
+		}
+		parsed, err := url.Parse(u.value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", u.name, err)
+		}
+		if parsed.Scheme == "" || parsed.Host == "" {
+			return fmt.Errorf("invalid %s: %q must be an absolute URL", u.name, u.value)
+		}
+	}
+	if c.Timeout < 0 {
+		return errors.New("timeout must be non-negative")
+	}
+	if c.ConnectTimeout < 0 {
+		return errors.New("connect_timeout must be non-negative")
+	}
+	return nil
+}
+
+// Validate checks TokenStoreConfig for errors.
+func (c *TokenStoreConfig) Validate() error {
+	switch c.Backend {
+	case "", "file", "encrypted":
+		// valid
+	case "command":
+		if c.Command == "" {
+			return errors.New("command is required for the command backend")
+		}
+	default:
+		return fmt.Errorf("invalid backend: %s (must be file, encrypted, or command)", c.Backend)
+	}
 	return nil
 }
 
@@ -50,6 +136,30 @@ func (c *SonosConfig) Validate() error {
 	return nil
 }
 
+// Validate checks MPDConfig for errors.
+func (c *MPDConfig) Validate() error {
+	if c.Timeout < 0 {
+		return errors.New("timeout must be non-negative")
+	}
+	seen := make(map[string]bool)
+	for i, h := range c.Hosts {
+		if h.Address == "" {
+			return fmt.Errorf("hosts[%d]: address is required", i)
+		}
+		if !strings.HasPrefix(h.Address, "/") {
+			if _, _, err := net.SplitHostPort(h.Address); err != nil {
+				return fmt.Errorf("hosts[%d]: invalid address: %w", i, err)
+			}
+		}
+		name := strings.ToLower(h.Name)
+		if seen[name] {
+			return fmt.Errorf("hosts[%d]: duplicate name %q", i, h.Name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
 // Validate checks DefaultsConfig for errors.
 func (c *DefaultsConfig) Validate() error {
 	if c.Volume < 0 || c.Volume > 100 {
@@ -61,6 +171,12 @@ func (c *DefaultsConfig) Validate() error {
 	default:
 		return fmt.Errorf("invalid repeat mode: %s (must be off, track, or context)", c.Repeat)
 	}
+	switch c.FadeCurve {
+	case "", "linear", "log":
+		// valid
+	default:
+		return fmt.Errorf("invalid fade curve: %s (must be linear or log)", c.FadeCurve)
+	}
 	return nil
 }
 
@@ -94,5 +210,136 @@ func (c *LogConfig) Validate() error {
 	default:
 		return fmt.Errorf("invalid log level: %s (must be debug, info, warn, or error)", c.Level)
 	}
+	switch c.Format {
+	case "", "text", "json":
+		// valid
+	default:
+		return fmt.Errorf("invalid log format: %s (must be text or json)", c.Format)
+	}
+	if c.MaxSize < 0 {
+		return errors.New("max_size must be non-negative")
+	}
+	if c.MaxBackups < 0 {
+		return errors.New("max_backups must be non-negative")
+	}
+	return nil
+}
+
+// Validate checks DaemonConfig for errors.
+func (c *DaemonConfig) Validate() error {
+	if c.RefreshInterval < 0 {
+		return errors.New("refresh_interval must be non-negative")
+	}
+	return nil
+}
+
+// Validate checks ServeConfig for errors.
+func (c *ServeConfig) Validate() error {
+	if c.Listen != "" {
+		if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+			return fmt.Errorf("invalid listen address: %w", err)
+		}
+	}
+	return nil
+}
+
+// Validate checks MQTTConfig for errors.
+func (c *MQTTConfig) Validate() error {
+	if c.Broker != "" {
+		u, err := url.Parse(c.Broker)
+		if err != nil {
+			return fmt.Errorf("invalid broker URL: %w", err)
+		}
+		switch u.Scheme {
+		case "tcp", "mqtt", "ssl", "tls", "mqtts", "ws", "wss":
+			// valid
+		default:
+			return fmt.Errorf("invalid broker URL: %s (scheme must be tcp, ssl, ws or wss)", c.Broker)
+		}
+	}
+	if strings.ContainsAny(c.TopicPrefix, "+#") || strings.ContainsAny(c.DiscoveryPrefix, "+#") {
+		return errors.New("topic prefixes must not contain wildcards")
+	}
+	if c.RefreshInterval < 0 {
+		return errors.New("refresh_interval must be non-negative")
+	}
+	return nil
+}
+
+// Validate checks MetricsConfig for errors.
+func (c *MetricsConfig) Validate() error {
+	if c.Listen != "" {
+		if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+			return fmt.Errorf("invalid listen address: %w", err)
+		}
+	}
+	return nil
+}
+
+// Validate checks WebhookConfig for errors.
+func (c *WebhookConfig) Validate() error {
+	u, err := url.Parse(c.URL)
+	if err != nil || c.URL == "" {
+		return fmt.Errorf("invalid url: %q", c.URL)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid url: %s (scheme must be http or https)", c.URL)
+	}
+	if c.MaxAttempts < 0 {
+		return errors.New("max_attempts must be non-negative")
+	}
+	if c.Timeout < 0 {
+		return errors.New("timeout must be non-negative")
+	}
+	return nil
+}
+
+// Validate checks RuleConfig for errors. Conditions and actions are
+// checked in full when the rules are loaded.
+func (c *RuleConfig) Validate() error {
+	if c.Name == "" {
+		return errors.New("missing name")
+	}
+	if c.For != "" {
+		if d, err := time.ParseDuration(c.For); err != nil || d < 0 {
+			return fmt.Errorf("invalid for: %q", c.For)
+		}
+	}
+	if len(c.Actions) == 0 {
+		return errors.New("no actions")
+	}
+	return nil
+}
+
+// Validate checks SceneConfig for errors.
+func (c *SceneConfig) Validate() error {
+	if len(c.Rooms) == 0 {
+		return errors.New("no rooms")
+	}
+	seen := make(map[string]bool)
+	for i, r := range c.Rooms {
+		if r.Name == "" {
+			return fmt.Errorf("rooms[%d]: missing name", i)
+		}
+		if seen[strings.ToLower(r.Name)] {
+			return fmt.Errorf("rooms[%d]: %s is listed twice", i, r.Name)
+		}
+		seen[strings.ToLower(r.Name)] = true
+		if r.Volume != nil && (*r.Volume < 0 || *r.Volume > 100) {
+			return fmt.Errorf("rooms[%d]: volume must be between 0 and 100", i)
+		}
+		if (r.Bass != nil && (*r.Bass < -10 || *r.Bass > 10)) || (r.Treble != nil && (*r.Treble < -10 || *r.Treble > 10)) {
+			return fmt.Errorf("rooms[%d]: bass and treble must be between -10 and 10", i)
+		}
+	}
+	switch c.Repeat {
+	case "", "off", "track", "context":
+		// valid
+	default:
+		return fmt.Errorf("invalid repeat mode: %s (must be off, track, or context)", c.Repeat)
+	}
+	if kind, name, ok := strings.Cut(c.Source, ":"); ok && name == "" {
+		return fmt.Errorf("invalid source %q: missing %s name", c.Source, kind)
+	}
 	return nil
 }
@@ -0,0 +1,226 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	rifferrors "github.com/tessro/riff/internal/errors"
+)
+
+// SearchKind indicates the kind of item to search for.
+type SearchKind string
+
+const (
+	SearchKindTrack    SearchKind = "track"
+	SearchKindAlbum    SearchKind = "album"
+	SearchKindArtist   SearchKind = "artist"
+	SearchKindPlaylist SearchKind = "playlist"
+)
+
+// SearchResult is a playable item returned by a backend search.
+type SearchResult struct {
+	URI      string     `json:"uri"`
+	Kind     SearchKind `json:"kind"`
+	Title    string     `json:"title"`
+	Subtitle string     `json:"subtitle"`
+	Platform Platform   `json:"platform"`
+}
+
+// Backend is a playback platform that owns a set of devices.
+type Backend interface {
+	// Platform returns the platform this backend serves.
+	Platform() Platform
+
+	// Devices lists the devices currently known to the backend.
+	Devices(ctx context.Context) ([]Device, error)
+
+	// PlayerFor returns a player bound to device. A nil device selects the
+	// backend's default target, such as Spotify's active device.
+	PlayerFor(ctx context.Context, device *Device) (Player, error)
+
+	// Search finds playable items of the given kinds.
+	Search(ctx context.Context, query string, kinds []SearchKind, limit int) ([]SearchResult, error)
+}
+
+// PlayerResolver opens the player for a device target, or the default
+// player when the target is empty. Registry satisfies it, as does the CLI's
+// registry served by riffd.
+type PlayerResolver interface {
+	PlayerFor(ctx context.Context, target string) (Player, *Device, error)
+}
+
+// DeviceResolver is a PlayerResolver that can also list every device, for
+// code that watches them all.
+type DeviceResolver interface {
+	PlayerResolver
+	Devices(ctx context.Context) ([]Device, error)
+}
+
+// Ensure Registry implements DeviceResolver
+var _ DeviceResolver = (*Registry)(nil)
+
+// Registry resolves device targets across all registered backends.
+type Registry struct {
+	backends []Backend
+}
+
+// NewRegistry creates a registry with the given backends, in priority order.
+func NewRegistry(backends ...Backend) *Registry {
+	r := &Registry{}
+	for _, b := range backends {
+		r.Register(b)
+	}
+	return r
+}
+
+// Register adds a backend. Earlier backends win ties during resolution.
+func (r *Registry) Register(b Backend) {
+	if b != nil {
+		r.backends = append(r.backends, b)
+	}
+}
+
+// Backends returns the registered backends in priority order.
+func (r *Registry) Backends() []Backend {
+	return r.backends
+}
+
+// Backend returns the backend for platform, or nil if none is registered.
+func (r *Registry) Backend(platform Platform) Backend {
+	for _, b := range r.backends {
+		if b.Platform() == platform {
+			return b
+		}
+	}
+	return nil
+}
+
+// Devices lists devices from every backend. Backends that fail are skipped;
+// their errors are joined and returned alongside whatever was found.
+func (r *Registry) Devices(ctx context.Context) ([]Device, error) {
+	var devices []Device
+	var errs []error
+	for _, b := range r.backends {
+		ds, err := b.Devices(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", b.Platform(), err))
+			continue
+		}
+		devices = append(devices, ds...)
+	}
+	return devices, errors.Join(errs...)
+}
+
+// Resolve finds the device matching target by exact ID, then exact name
+// (case-insensitive), then name substring. Each rule is tried across all
+// backends before falling back to the next, so an exact Sonos room name beats
+// a partial Spotify match.
+func (r *Registry) Resolve(ctx context.Context, target string) (Backend, *Device, error) {
+	type candidate struct {
+		backend Backend
+		device  Device
+	}
+
+	var candidates []candidate
+	var errs []error
+	for _, b := range r.backends {
+		ds, err := b.Devices(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", b.Platform(), err))
+			continue
+		}
+		for _, d := range ds {
+			candidates = append(candidates, candidate{backend: b, device: d})
+		}
+	}
+
+	targetLower := strings.ToLower(target)
+	matchers := []func(d Device) bool{
+		func(d Device) bool { return d.ID == target },
+		func(d Device) bool { return strings.ToLower(d.Name) == targetLower },
+		func(d Device) bool { return strings.Contains(strings.ToLower(d.Name), targetLower) },
+	}
+	for _, match := range matchers {
+		for _, c := range candidates {
+			if match(c.device) {
+				d := c.device
+				return c.backend, &d, nil
+			}
+		}
+	}
+
+	err := fmt.Errorf("device '%s': %w", target, rifferrors.ErrDeviceNotFound)
+	if len(errs) > 0 {
+		err = fmt.Errorf("%w (%w)", err, errors.Join(errs...))
+	}
+	return nil, nil, err
+}
+
+// PlayerFor returns a player for target. An empty target selects the default
+// player: the first backend whose default target is playing, or failing that
+// the first backend able to provide a player at all.
+func (r *Registry) PlayerFor(ctx context.Context, target string) (Player, *Device, error) {
+	_, p, d, err := r.Select(ctx, target)
+	return p, d, err
+}
+
+// Select is PlayerFor, also returning the backend that provided the player.
+func (r *Registry) Select(ctx context.Context, target string) (Backend, Player, *Device, error) {
+	if target != "" {
+		b, d, err := r.Resolve(ctx, target)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		p, err := b.PlayerFor(ctx, d)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return b, p, d, nil
+	}
+
+	var fallback Player
+	var fallbackBackend Backend
+	var errs []error
+	for _, b := range r.backends {
+		p, err := b.PlayerFor(ctx, nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", b.Platform(), err))
+			continue
+		}
+		state, err := p.GetState(ctx)
+		if err == nil && state != nil && state.IsPlaying {
+			return b, p, state.Device, nil
+		}
+		if fallback == nil {
+			fallback, fallbackBackend = p, b
+		}
+	}
+	if fallback != nil {
+		return fallbackBackend, fallback, nil, nil
+	}
+	if len(errs) == 0 {
+		return nil, nil, nil, errors.New("no player available: no backends configured")
+	}
+	return nil, nil, nil, fmt.Errorf("no player available: %w", errors.Join(errs...))
+}
+
+// Search queries each backend in turn and returns the first non-empty
+// results. Backends that cannot search are skipped.
+func (r *Registry) Search(ctx context.Context, query string, kinds []SearchKind, limit int) ([]SearchResult, error) {
+	var errs []error
+	for _, b := range r.backends {
+		results, err := b.Search(ctx, query, kinds, limit)
+		if err != nil {
+			if !errors.Is(err, errors.ErrUnsupported) {
+				errs = append(errs, fmt.Errorf("%s: %w", b.Platform(), err))
+			}
+			continue
+		}
+		if len(results) > 0 {
+			return results, nil
+		}
+	}
+	return nil, errors.Join(errs...)
+}
@@ -0,0 +1,203 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	rifferrors "github.com/tessro/riff/internal/errors"
+)
+
+// fakePlayer is a Player whose state reports playing or paused.
+type fakePlayer struct {
+	device  *Device
+	playing bool
+}
+
+func (p *fakePlayer) Play(ctx context.Context) error                 { return nil }
+func (p *fakePlayer) Pause(ctx context.Context) error                { return nil }
+func (p *fakePlayer) Next(ctx context.Context) error                 { return nil }
+func (p *fakePlayer) Prev(ctx context.Context) error                 { return nil }
+func (p *fakePlayer) Seek(ctx context.Context, positionMs int) error { return nil }
+func (p *fakePlayer) Volume(ctx context.Context, percent int) error  { return nil }
+func (p *fakePlayer) GetQueue(ctx context.Context) (*Queue, error)   { return &Queue{}, nil }
+func (p *fakePlayer) AddToQueue(ctx context.Context, trackURI string) error {
+	return nil
+}
+func (p *fakePlayer) GetRecentlyPlayed(ctx context.Context, limit int) ([]HistoryEntry, error) {
+	return nil, nil
+}
+func (p *fakePlayer) Capabilities() Capability { return CapSeek }
+func (p *fakePlayer) GetState(ctx context.Context) (*PlaybackState, error) {
+	return &PlaybackState{Device: p.device, IsPlaying: p.playing}, nil
+}
+
+// fakeBackend serves a fixed device list.
+type fakeBackend struct {
+	platform Platform
+	devices  []Device
+	playing  bool
+	err      error
+	results  []SearchResult
+}
+
+func (b *fakeBackend) Platform() Platform { return b.platform }
+
+func (b *fakeBackend) Devices(ctx context.Context) ([]Device, error) {
+	return b.devices, b.err
+}
+
+func (b *fakeBackend) PlayerFor(ctx context.Context, device *Device) (Player, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if device == nil && len(b.devices) > 0 {
+		device = &b.devices[0]
+	}
+	return &fakePlayer{device: device, playing: b.playing}, nil
+}
+
+func (b *fakeBackend) Search(ctx context.Context, query string, kinds []SearchKind, limit int) ([]SearchResult, error) {
+	if b.results == nil {
+		return nil, errors.ErrUnsupported
+	}
+	return b.results, nil
+}
+
+func newTestRegistry() (*Registry, *fakeBackend, *fakeBackend) {
+	spotify := &fakeBackend{
+		platform: PlatformSpotify,
+		devices: []Device{
+			{ID: "abc123", Name: "MacBook Pro", Platform: PlatformSpotify},
+			{ID: "def456", Name: "Kitchen Speaker", Platform: PlatformSpotify},
+		},
+	}
+	sonos := &fakeBackend{
+		platform: PlatformSonos,
+		devices: []Device{
+			{ID: "RINCON_1", Name: "Kitchen", Platform: PlatformSonos},
+			{ID: "RINCON_2", Name: "Living Room", Platform: PlatformSonos},
+		},
+	}
+	return NewRegistry(spotify, sonos), spotify, sonos
+}
+
+func TestRegistryResolve(t *testing.T) {
+	r, _, _ := newTestRegistry()
+
+	tests := []struct {
+		target   string
+		wantID   string
+		platform Platform
+	}{
+		{"def456", "def456", PlatformSpotify},
+		{"RINCON_2", "RINCON_2", PlatformSonos},
+		{"kitchen", "RINCON_1", PlatformSonos}, // exact name beats substring
+		{"macbook", "abc123", PlatformSpotify},
+		{"living", "RINCON_2", PlatformSonos},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			b, d, err := r.Resolve(context.Background(), tt.target)
+			if err != nil {
+				t.Fatalf("Resolve(%q) error = %v", tt.target, err)
+			}
+			if d.ID != tt.wantID {
+				t.Errorf("Resolve(%q) device = %q, want %q", tt.target, d.ID, tt.wantID)
+			}
+			if b.Platform() != tt.platform {
+				t.Errorf("Resolve(%q) platform = %q, want %q", tt.target, b.Platform(), tt.platform)
+			}
+		})
+	}
+}
+
+func TestRegistryResolveNotFound(t *testing.T) {
+	r, _, sonos := newTestRegistry()
+	sonos.err = errors.New("discovery failed")
+
+	_, _, err := r.Resolve(context.Background(), "Bathroom")
+	if !errors.Is(err, rifferrors.ErrDeviceNotFound) {
+		t.Errorf("Resolve() error = %v, want ErrDeviceNotFound", err)
+	}
+
+	// A failing backend must not hide devices from the others
+	if _, d, err := r.Resolve(context.Background(), "MacBook Pro"); err != nil || d.ID != "abc123" {
+		t.Errorf("Resolve() = %v, %v; want abc123", d, err)
+	}
+}
+
+func TestRegistryPlayerForDefault(t *testing.T) {
+	r, spotify, sonos := newTestRegistry()
+	ctx := context.Background()
+
+	// Nothing playing: first backend wins
+	p, _, err := r.PlayerFor(ctx, "")
+	if err != nil {
+		t.Fatalf("PlayerFor() error = %v", err)
+	}
+	if got := p.(*fakePlayer).device.ID; got != "abc123" {
+		t.Errorf("default device = %q, want abc123", got)
+	}
+
+	// A playing backend wins over registration order
+	sonos.playing = true
+	_, d, err := r.PlayerFor(ctx, "")
+	if err != nil {
+		t.Fatalf("PlayerFor() error = %v", err)
+	}
+	if d == nil || d.ID != "RINCON_1" {
+		t.Errorf("default device = %v, want RINCON_1", d)
+	}
+
+	// Failing backends are skipped
+	spotify.err = errors.New("not authenticated")
+	sonos.playing = false
+	if _, _, err := r.PlayerFor(ctx, ""); err != nil {
+		t.Errorf("PlayerFor() error = %v, want Sonos fallback", err)
+	}
+
+	sonos.err = errors.New("no sonos devices found")
+	if _, _, err := r.PlayerFor(ctx, ""); err == nil {
+		t.Error("PlayerFor() error = nil, want error when every backend fails")
+	}
+}
+
+func TestRegistrySelectReturnsBackend(t *testing.T) {
+	r, _, sonos := newTestRegistry()
+	ctx := context.Background()
+
+	b, _, _, err := r.Select(ctx, "living")
+	if err != nil || b.Platform() != PlatformSonos {
+		t.Errorf("Select(living) backend = %v, %v; want sonos", b, err)
+	}
+
+	// The fallback player's backend is reported too
+	b, _, d, err := r.Select(ctx, "")
+	if err != nil || b.Platform() != PlatformSpotify || d != nil {
+		t.Errorf("Select() = %v, %v, %v; want spotify fallback", b, d, err)
+	}
+
+	sonos.playing = true
+	if b, _, _, _ := r.Select(ctx, ""); b.Platform() != PlatformSonos {
+		t.Errorf("Select() backend = %s, want playing sonos", b.Platform())
+	}
+}
+
+func TestRegistrySearchSkipsUnsupported(t *testing.T) {
+	sonos := &fakeBackend{platform: PlatformSonos}
+	spotify := &fakeBackend{
+		platform: PlatformSpotify,
+		results:  []SearchResult{{URI: "spotify:track:1", Title: "Song"}},
+	}
+	r := NewRegistry(sonos, spotify)
+
+	results, err := r.Search(context.Background(), "song", []SearchKind{SearchKindTrack}, 1)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 1 || results[0].URI != "spotify:track:1" {
+		t.Errorf("Search() = %+v, want spotify:track:1", results)
+	}
+}
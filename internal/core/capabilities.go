@@ -0,0 +1,146 @@
+package core
+
+import (
+	"context"
+	"strings"
+
+	rifferrors "github.com/tessro/riff/internal/errors"
+)
+
+// Capability is a set of optional operations a player supports.
+type Capability uint32
+
+const (
+	CapQueueRead   Capability = 1 << iota // GetQueue returns the real queue
+	CapQueueAdd                           // AddToQueue
+	CapQueueEdit                          // QueueEditor: remove, move, clear
+	CapHistory                            // GetRecentlyPlayed
+	CapShuffle                            // PlayModeController.Shuffle
+	CapRepeat                             // PlayModeController.Repeat
+	CapSeek                               // Seek
+	CapGroupVolume                        // GroupVolumeController
+	CapInputs                             // InputSelector
+	CapStream                             // StreamPlayer
+)
+
+// capabilityInfo names each capability and suggests what to do without it.
+var capabilityInfo = []struct {
+	cap        Capability
+	name       string
+	operation  string
+	suggestion string
+}{
+	{CapQueueRead, "queue-read", "reading the queue", "Spotify devices expose their queue; try --to with one"},
+	{CapQueueAdd, "queue-add", "adding to the queue", "Use 'riff play --to' to start the track instead"},
+	{CapQueueEdit, "queue-edit", "editing the queue", "Spotify's API cannot remove or reorder queued tracks; Sonos rooms can"},
+	{CapHistory, "history", "playback history", "Recently played tracks are only available from Spotify"},
+	{CapShuffle, "shuffle", "shuffle", "Toggle shuffle from the device's own app"},
+	{CapRepeat, "repeat", "repeat", "Toggle repeat from the device's own app"},
+	{CapSeek, "seek", "seeking", "Use 'riff restart' or 'riff next' instead"},
+	{CapGroupVolume, "group-volume", "group volume", "Use 'riff volume' without --group to set this device's volume"},
+	{CapInputs, "inputs", "switching inputs", "Only devices with line-in or TV inputs can switch them"},
+	{CapStream, "stream", "playing stream URLs", "Use --to with a Sonos room, MPD host or UPnP renderer"},
+}
+
+// Has reports whether c includes every capability in other.
+func (c Capability) Has(other Capability) bool {
+	return c&other == other
+}
+
+// Names returns the names of the capabilities in c, in declaration order.
+func (c Capability) Names() []string {
+	var names []string
+	for _, info := range capabilityInfo {
+		if c.Has(info.cap) {
+			names = append(names, info.name)
+		}
+	}
+	return names
+}
+
+// String returns the capability names joined with commas.
+func (c Capability) String() string {
+	return strings.Join(c.Names(), ",")
+}
+
+// Require returns nil if p supports every capability in c, otherwise an
+// error wrapping errors.ErrUnsupported with a suggestion for the first
+// missing one.
+func Require(p Player, c Capability) error {
+	missing := c &^ p.Capabilities()
+	if missing == 0 {
+		return nil
+	}
+	for _, info := range capabilityInfo {
+		if missing.Has(info.cap) {
+			return rifferrors.Unsupported(info.operation, describePlayer(p), info.suggestion)
+		}
+	}
+	return rifferrors.Unsupported(missing.String(), describePlayer(p), "")
+}
+
+// describePlayer names a player for error messages, using its Describe
+// method when it has one.
+func describePlayer(p Player) string {
+	if d, ok := p.(interface{ Describe() string }); ok {
+		return d.Describe()
+	}
+	return "this device"
+}
+
+// QueueEditor is implemented by players with CapQueueEdit. Positions are
+// 1-based, matching 'riff queue' output.
+type QueueEditor interface {
+	RemoveFromQueue(ctx context.Context, position int) error
+	MoveInQueue(ctx context.Context, from, to int) error
+	ClearQueue(ctx context.Context) error
+}
+
+// PlayModeController is implemented by players with CapShuffle or CapRepeat.
+// Repeat modes are "off", "track" and "context".
+type PlayModeController interface {
+	Shuffle(ctx context.Context, state bool) error
+	Repeat(ctx context.Context, mode string) error
+}
+
+// GroupVolumeController is implemented by players with CapGroupVolume.
+type GroupVolumeController interface {
+	GroupVolume(ctx context.Context) (int, error)
+	SetGroupVolume(ctx context.Context, percent int) error
+	// CoordinatesGroup reports whether the player is the coordinator of a
+	// group with other rooms in it, whose volumes move together.
+	CoordinatesGroup(ctx context.Context) (bool, error)
+}
+
+// StreamPlayer is implemented by players with CapStream. uri is an HTTP(S)
+// URL the device fetches itself.
+type StreamPlayer interface {
+	PlayURI(ctx context.Context, uri string) error
+}
+
+// MediaPlayer is implemented by stream players that can also pass along a
+// DIDL-Lite description of uri, such as an item from a UPnP MediaServer, so
+// the device shows a title and artist.
+type MediaPlayer interface {
+	PlayMedia(ctx context.Context, uri, metadata string) error
+}
+
+// MediaQueuer is the queueing counterpart to MediaPlayer.
+type MediaQueuer interface {
+	QueueMedia(ctx context.Context, uri, metadata string) error
+}
+
+// ChangeNotifier is implemented by players that can tell when their state
+// changes, such as MPD through its idle command, so a watcher needn't wait
+// for its next poll.
+type ChangeNotifier interface {
+	// WaitForChange blocks until the playback state may have changed, or
+	// ctx is done.
+	WaitForChange(ctx context.Context) error
+}
+
+// InputSelector is implemented by players with CapInputs.
+type InputSelector interface {
+	Inputs(ctx context.Context) ([]string, error)
+	SelectInput(ctx context.Context, name string) error
+}
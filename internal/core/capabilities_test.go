@@ -0,0 +1,37 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	rifferrors "github.com/tessro/riff/internal/errors"
+)
+
+func TestCapabilityNames(t *testing.T) {
+	c := CapQueueRead | CapSeek | CapGroupVolume
+	if got, want := c.String(), "queue-read,seek,group-volume"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if !c.Has(CapSeek | CapQueueRead) {
+		t.Error("Has() = false for a subset")
+	}
+	if c.Has(CapSeek | CapHistory) {
+		t.Error("Has() = true with a missing capability")
+	}
+}
+
+func TestRequire(t *testing.T) {
+	p := &fakePlayer{}
+
+	if err := Require(p, CapSeek); err != nil {
+		t.Errorf("Require(CapSeek) error = %v", err)
+	}
+
+	err := Require(p, CapSeek|CapQueueEdit)
+	if !errors.Is(err, rifferrors.ErrUnsupported) {
+		t.Fatalf("Require(CapQueueEdit) error = %v, want ErrUnsupported", err)
+	}
+	if rifferrors.GetSuggestion(err) == "" {
+		t.Error("Require() error has no suggestion")
+	}
+}
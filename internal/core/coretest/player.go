@@ -0,0 +1,156 @@
+// Package coretest provides a fake core.Player for tests.
+//
+// The fake keeps a playback state that commands change the way a real
+// player would, and records every command, so a test can drive code that
+// takes a core.Player and then assert on both:
+//
+//	p := coretest.NewPlayer(device, core.CapSeek)
+//	_ = p.Volume(ctx, 30)
+//	calls := p.Calls() // ["volume 30"]
+package coretest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+)
+
+// Ensure Player implements core.Player
+var _ core.Player = (*Player)(nil)
+
+// Player is a fake core.Player. It is safe for concurrent use.
+type Player struct {
+	caps core.Capability
+
+	mu    sync.Mutex
+	state core.PlaybackState
+	queue []string
+	calls []string
+	err   error
+}
+
+// NewPlayer creates a paused player on device, which may be nil, that
+// reports caps.
+func NewPlayer(device *core.Device, caps core.Capability) *Player {
+	return &Player{caps: caps, state: core.PlaybackState{Device: device}}
+}
+
+// Record records a call and returns the error set with SetErr. Tests use it
+// to add optional interfaces, such as core.StreamPlayer, on top of Player.
+func (p *Player) Record(call string) error {
+	return p.do(call, nil)
+}
+
+// do records call and, unless an error is set, applies fn to the state.
+func (p *Player) do(call string, fn func(s *core.PlaybackState)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, call)
+	if p.err != nil {
+		return p.err
+	}
+	if fn != nil {
+		fn(&p.state)
+	}
+	return nil
+}
+
+// Calls returns the commands received so far, like "play" or "volume 30".
+func (p *Player) Calls() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.calls...)
+}
+
+// State returns the current playback state.
+func (p *Player) State() core.PlaybackState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state
+}
+
+// Update changes the playback state without recording a call.
+func (p *Player) Update(fn func(s *core.PlaybackState)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fn(&p.state)
+}
+
+// Queue returns the URIs added with AddToQueue.
+func (p *Player) Queue() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.queue...)
+}
+
+// SetErr makes every command fail with err, as when the device goes away.
+// A nil err makes them succeed again.
+func (p *Player) SetErr(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+func (p *Player) Play(ctx context.Context) error {
+	return p.do("play", func(s *core.PlaybackState) { s.IsPlaying = true })
+}
+
+func (p *Player) Pause(ctx context.Context) error {
+	return p.do("pause", func(s *core.PlaybackState) { s.IsPlaying = false })
+}
+
+func (p *Player) Next(ctx context.Context) error { return p.do("next", nil) }
+func (p *Player) Prev(ctx context.Context) error { return p.do("prev", nil) }
+
+func (p *Player) Seek(ctx context.Context, positionMs int) error {
+	return p.do(fmt.Sprintf("seek %d", positionMs), func(s *core.PlaybackState) {
+		s.Progress = time.Duration(positionMs) * time.Millisecond
+	})
+}
+
+func (p *Player) Volume(ctx context.Context, percent int) error {
+	return p.do(fmt.Sprintf("volume %d", percent), func(s *core.PlaybackState) { s.Volume = percent })
+}
+
+func (p *Player) GetState(ctx context.Context) (*core.PlaybackState, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return nil, p.err
+	}
+	state := p.state
+	return &state, nil
+}
+
+func (p *Player) GetQueue(ctx context.Context) (*core.Queue, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	q := &core.Queue{}
+	for _, uri := range p.queue {
+		q.Tracks = append(q.Tracks, core.Track{URI: uri})
+	}
+	return q, nil
+}
+
+func (p *Player) GetRecentlyPlayed(ctx context.Context, limit int) ([]core.HistoryEntry, error) {
+	return nil, nil
+}
+
+func (p *Player) AddToQueue(ctx context.Context, trackURI string) error {
+	return p.do("add "+trackURI, func(s *core.PlaybackState) { p.queue = append(p.queue, trackURI) })
+}
+
+func (p *Player) Capabilities() core.Capability { return p.caps }
+
+// Describe names the player after its device, for unsupported errors.
+func (p *Player) Describe() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state.Device == nil {
+		return "fake player"
+	}
+	return p.state.Device.Name
+}
@@ -17,6 +17,8 @@ type Platform string
 const (
 	PlatformSpotify Platform = "spotify"
 	PlatformSonos   Platform = "sonos"
+	PlatformMPD     Platform = "mpd"
+	PlatformUPnP    Platform = "upnp"
 )
 
 // Device represents a playback device.
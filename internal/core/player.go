@@ -24,6 +24,10 @@ type Player interface {
 
 	// Queue manipulation
 	AddToQueue(ctx context.Context, trackURI string) error
+
+	// Capabilities reports which optional operations the player supports.
+	// Unsupported operations return an error wrapping errors.ErrUnsupported.
+	Capabilities() Capability
 }
 
 // HistoryEntry represents a recently played track.
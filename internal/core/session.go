@@ -0,0 +1,23 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// Session is what a player is playing, described so that a player on
+// another platform can pick it up where it is. URIs are Spotify URIs.
+type Session struct {
+	Context  string        `json:"context,omitempty"` // album, playlist or artist, if any
+	Track    string        `json:"track"`
+	Tracks   []string      `json:"tracks,omitempty"` // Track and what follows, for players that can't use Context
+	Progress time.Duration `json:"progress"`
+	Volume   int           `json:"volume"`
+}
+
+// SessionPlayer is implemented by players that can hand what they are
+// playing to another player, or pick up a session from one.
+type SessionPlayer interface {
+	Session(ctx context.Context) (*Session, error)
+	ResumeSession(ctx context.Context, s *Session) error
+}
@@ -10,6 +10,8 @@ type PlaybackState struct {
 	IsPlaying bool          `json:"is_playing"`
 	Progress  time.Duration `json:"progress"`
 	Volume    int           `json:"volume"`
+	Shuffle   bool          `json:"shuffle"`
+	Repeat    string        `json:"repeat"` // off, track, context
 }
 
 // HasTrack returns true if there is an active track.
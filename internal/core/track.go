@@ -8,6 +8,8 @@ type Source string
 const (
 	SourceSpotify Source = "spotify"
 	SourceSonos   Source = "sonos"
+	SourceMPD     Source = "mpd"
+	SourceUPnP    Source = "upnp"
 )
 
 // Track represents a playable audio track.
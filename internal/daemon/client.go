@@ -0,0 +1,137 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+)
+
+// dialTimeout bounds connecting to the socket. The daemon is local, so a
+// slow connect means it is wedged and the CLI is better off without it.
+const dialTimeout = 250 * time.Millisecond
+
+// Client calls a running daemon. It is safe for concurrent use; calls are
+// serialized over one connection.
+type Client struct {
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	nextID int64
+}
+
+// Dial connects to the daemon listening on path.
+func Dial(path string) (*Client, error) {
+	conn, err := net.DialTimeout("unix", path, dialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to riffd: %w", err)
+	}
+	return &Client{
+		conn:   conn,
+		reader: bufio.NewReaderSize(conn, 64*1024),
+	}, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Call invokes method with params and decodes the result into result, which
+// may be nil. The call is abandoned when ctx is done.
+func (c *Client) Call(ctx context.Context, method string, params, result any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextID++
+	req := request{JSONRPC: "2.0", ID: c.nextID, Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("failed to encode params: %w", err)
+		}
+		req.Params = data
+	}
+	line, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	deadline, _ := ctx.Deadline()
+	_ = c.conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() {
+		_ = c.conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	if _, err := c.conn.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("riffd request failed: %w", err)
+	}
+	data, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("riffd request failed: %w", err)
+	}
+
+	var resp response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("invalid riffd response: %w", err)
+	}
+	if resp.ID != req.ID {
+		return fmt.Errorf("invalid riffd response: id %d, want %d", resp.ID, req.ID)
+	}
+	if resp.Error != nil {
+		return decodeError(resp.Error)
+	}
+	if result != nil && len(resp.Result) > 0 {
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("invalid riffd response: %w", err)
+		}
+	}
+	return nil
+}
+
+// Status returns the daemon's status.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	var status Status
+	if err := c.Call(ctx, "daemon.status", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Shutdown asks the daemon to exit.
+func (c *Client) Shutdown(ctx context.Context) error {
+	return c.Call(ctx, "daemon.shutdown", nil, nil)
+}
+
+// Backends returns a proxy for each backend the daemon serves, in its
+// priority order.
+func (c *Client) Backends(ctx context.Context) ([]core.Backend, error) {
+	status, err := c.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	backends := make([]core.Backend, len(status.Platforms))
+	for i, platform := range status.Platforms {
+		backends[i] = &Backend{client: c, platform: platform}
+	}
+	return backends, nil
+}
+
+// PlayerFor resolves target across every backend in one round trip, as
+// core.Registry.PlayerFor does locally.
+func (c *Client) PlayerFor(ctx context.Context, target string) (core.Player, *core.Device, error) {
+	var info playerInfo
+	if err := c.Call(ctx, "player.select", selectParams{Target: target}, &info); err != nil {
+		return nil, nil, err
+	}
+	return newPlayer(c, &info), info.Device, nil
+}
@@ -0,0 +1,213 @@
+// Package daemon implements riffd, a long-running process that keeps
+// backends, discovery caches and clients warm, and serves them to the CLI
+// over JSON-RPC 2.0 on a Unix socket.
+//
+// Requests and responses are single JSON objects, one per line. Backend and
+// player methods are addressed by platform and device, so the protocol is
+// stateless: a client never holds a handle the daemon could forget.
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+	rifferrors "github.com/tessro/riff/internal/errors"
+)
+
+// SocketPath returns the default socket location:
+// $XDG_RUNTIME_DIR/riff/riffd.sock, or a per-user directory under the
+// system temp dir when XDG_RUNTIME_DIR is unset.
+func SocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "riff", "riffd.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("riff-%d", os.Getuid()), "riffd.sock")
+}
+
+// Status describes a running daemon.
+type Status struct {
+	PID       int             `json:"pid"`
+	Version   string          `json:"version"`
+	StartedAt time.Time       `json:"started_at"`
+	Platforms []core.Platform `json:"platforms"`
+	Players   int             `json:"players"`
+}
+
+// JSON-RPC 2.0 error codes. Errors from backends use codeServer, with the
+// sentinel they wrap recorded in the error data.
+const (
+	codeParse          = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeServer         = -32000
+)
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int64           `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int64           `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int        `json:"code"`
+	Message string     `json:"message"`
+	Data    *errorData `json:"data,omitempty"`
+}
+
+// errorData carries what the CLI needs to rebuild an error: the sentinel it
+// wraps, so errors.Is still works, and any suggestion.
+type errorData struct {
+	Kind       string `json:"kind,omitempty"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// errorKinds names the sentinels that survive the trip over the socket.
+var errorKinds = []struct {
+	kind string
+	err  error
+}{
+	{"unsupported", rifferrors.ErrUnsupported},
+	{"device_not_found", rifferrors.ErrDeviceNotFound},
+	{"not_authenticated", rifferrors.ErrNotAuthenticated},
+	{"no_active_device", rifferrors.ErrNoActiveDevice},
+	{"premium_required", rifferrors.ErrPremiumRequired},
+	{"rate_limited", rifferrors.ErrRateLimited},
+}
+
+// encodeError converts a backend error for a response.
+func encodeError(err error) *rpcError {
+	e := &rpcError{Code: codeServer, Message: err.Error()}
+	data := &errorData{}
+	for _, k := range errorKinds {
+		if errors.Is(err, k.err) {
+			data.Kind = k.kind
+			break
+		}
+	}
+	var riffErr *rifferrors.RiffError
+	if errors.As(err, &riffErr) {
+		data.Suggestion = riffErr.Suggestion
+	}
+	if *data != (errorData{}) {
+		e.Data = data
+	}
+	return e
+}
+
+// remoteError is an error returned by the daemon.
+type remoteError struct {
+	msg  string
+	kind error
+}
+
+func (e *remoteError) Error() string { return e.msg }
+func (e *remoteError) Unwrap() error { return e.kind }
+
+// decodeError rebuilds an error from a response.
+func decodeError(e *rpcError) error {
+	err := &remoteError{msg: e.Message}
+	if e.Data == nil {
+		return err
+	}
+	for _, k := range errorKinds {
+		if k.kind == e.Data.Kind {
+			err.kind = k.err
+		}
+	}
+	if e.Data.Suggestion != "" {
+		return rifferrors.WithSuggestion(err, e.Data.Suggestion)
+	}
+	return err
+}
+
+// target addresses a player: the backend's platform and, optionally, a
+// device. A nil device means the backend's default target.
+type target struct {
+	Platform core.Platform `json:"platform"`
+	Device   *core.Device  `json:"device,omitempty"`
+}
+
+// key identifies the player for t in the server's cache.
+func (t target) key() string {
+	if t.Device == nil {
+		return string(t.Platform) + "/"
+	}
+	return string(t.Platform) + "/" + t.Device.ID
+}
+
+// playerInfo describes a player opened by the daemon. Device is the device
+// the player resolved to, which for a default player is the playing device
+// rather than part of Target.
+type playerInfo struct {
+	Target       target          `json:"target"`
+	Device       *core.Device    `json:"device,omitempty"`
+	Capabilities core.Capability `json:"capabilities"`
+	Description  string          `json:"description"`
+}
+
+// Parameter types for methods that take more than a target.
+type (
+	selectParams struct {
+		Target string `json:"target"`
+	}
+	platformParams struct {
+		Platform core.Platform `json:"platform"`
+	}
+	searchParams struct {
+		Platform core.Platform     `json:"platform"`
+		Query    string            `json:"query"`
+		Kinds    []core.SearchKind `json:"kinds"`
+		Limit    int               `json:"limit"`
+	}
+	seekParams struct {
+		PositionMs int `json:"position_ms"`
+	}
+	percentParams struct {
+		Percent int `json:"percent"`
+	}
+	limitParams struct {
+		Limit int `json:"limit"`
+	}
+	uriParams struct {
+		URI      string `json:"uri"`
+		Metadata string `json:"metadata,omitempty"`
+	}
+	contextParams struct {
+		URI    string `json:"uri"`
+		Offset int    `json:"offset"`
+	}
+	positionParams struct {
+		Position int `json:"position"`
+	}
+	moveParams struct {
+		From int `json:"from"`
+		To   int `json:"to"`
+	}
+	stateParams struct {
+		State bool `json:"state"`
+	}
+	modeParams struct {
+		Mode string `json:"mode"`
+	}
+	nameParams struct {
+		Name string `json:"name"`
+	}
+	transferParams struct {
+		DeviceID string `json:"device_id"`
+		Play     bool   `json:"play"`
+	}
+)
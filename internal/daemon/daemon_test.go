@@ -0,0 +1,241 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/core/coretest"
+	rifferrors "github.com/tessro/riff/internal/errors"
+)
+
+// fakeBackend serves a fixed device list, with one player per device.
+type fakeBackend struct {
+	platform core.Platform
+	devices  []core.Device
+	players  map[string]*coretest.Player
+	opened   int
+}
+
+func newFakeBackend(platform core.Platform, devices ...core.Device) *fakeBackend {
+	b := &fakeBackend{platform: platform, devices: devices, players: make(map[string]*coretest.Player)}
+	for i := range devices {
+		b.players[devices[i].ID] = coretest.NewPlayer(&b.devices[i], core.CapQueueRead|core.CapQueueAdd)
+	}
+	return b
+}
+
+func (b *fakeBackend) Platform() core.Platform { return b.platform }
+
+func (b *fakeBackend) Devices(ctx context.Context) ([]core.Device, error) {
+	return b.devices, nil
+}
+
+func (b *fakeBackend) PlayerFor(ctx context.Context, device *core.Device) (core.Player, error) {
+	b.opened++
+	if device == nil {
+		device = &b.devices[0]
+	}
+	return b.players[device.ID], nil
+}
+
+func (b *fakeBackend) Search(ctx context.Context, query string, kinds []core.SearchKind, limit int) ([]core.SearchResult, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// startServer serves registry on a socket in a temp dir and returns a
+// connected client.
+func startServer(t *testing.T, registry *core.Registry) (*Server, *Client, string, <-chan error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "riffd.sock")
+	ln, err := Listen(path)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	s := NewServer(registry, "test")
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, ln) }()
+
+	c, err := Dial(path)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return s, c, path, done
+}
+
+func TestClientServer(t *testing.T) {
+	spotify := newFakeBackend(core.PlatformSpotify,
+		core.Device{ID: "abc123", Name: "MacBook Pro", Platform: core.PlatformSpotify})
+	sonos := newFakeBackend(core.PlatformSonos,
+		core.Device{ID: "RINCON_1", Name: "Kitchen", Platform: core.PlatformSonos},
+		core.Device{ID: "RINCON_2", Name: "Living Room", Platform: core.PlatformSonos})
+	_, c, _, _ := startServer(t, core.NewRegistry(spotify, sonos))
+	ctx := context.Background()
+
+	status, err := c.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status.Version != "test" || len(status.Platforms) != 2 || status.Platforms[0] != core.PlatformSpotify {
+		t.Errorf("Status() = %+v", status)
+	}
+
+	// Remote backends work with a local registry, in the daemon's order
+	backends, err := c.Backends(ctx)
+	if err != nil {
+		t.Fatalf("Backends() error = %v", err)
+	}
+	registry := core.NewRegistry(backends...)
+	devices, err := registry.Devices(ctx)
+	if err != nil || len(devices) != 3 {
+		t.Fatalf("Devices() = %v, %v; want 3 devices", devices, err)
+	}
+	_, d, err := registry.Resolve(ctx, "living")
+	if err != nil || d.ID != "RINCON_2" {
+		t.Errorf("Resolve(living) = %v, %v; want RINCON_2", d, err)
+	}
+
+	p, d, err := c.PlayerFor(ctx, "Kitchen")
+	if err != nil {
+		t.Fatalf("PlayerFor() error = %v", err)
+	}
+	if d == nil || d.ID != "RINCON_1" {
+		t.Errorf("PlayerFor() device = %v, want RINCON_1", d)
+	}
+	if p.(*Player).Platform() != core.PlatformSonos || p.Capabilities() != core.CapQueueRead|core.CapQueueAdd {
+		t.Errorf("player = %+v", p)
+	}
+
+	if err := p.Play(ctx); err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+	if err := p.Volume(ctx, 35); err != nil {
+		t.Fatalf("Volume() error = %v", err)
+	}
+	if err := p.AddToQueue(ctx, "x-file:1"); err != nil {
+		t.Fatalf("AddToQueue() error = %v", err)
+	}
+	state, err := p.GetState(ctx)
+	if err != nil {
+		t.Fatalf("GetState() error = %v", err)
+	}
+	if !state.IsPlaying || state.Volume != 35 || state.Device.Name != "Kitchen" {
+		t.Errorf("GetState() = %+v", state)
+	}
+	if q, err := p.GetQueue(ctx); err != nil || q.Len() != 1 || q.Tracks[0].URI != "x-file:1" {
+		t.Errorf("GetQueue() = %+v, %v", q, err)
+	}
+
+	// Players stay warm between calls
+	if sonos.opened != 1 {
+		t.Errorf("sonos players opened = %d, want 1", sonos.opened)
+	}
+
+	// The default player is the playing one
+	_, d, err = c.PlayerFor(ctx, "")
+	if err != nil || d == nil || d.ID != "RINCON_1" {
+		t.Errorf("PlayerFor(\"\") = %v, %v; want playing Kitchen", d, err)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	sonos := newFakeBackend(core.PlatformSonos,
+		core.Device{ID: "RINCON_1", Name: "Kitchen", Platform: core.PlatformSonos})
+	_, c, _, _ := startServer(t, core.NewRegistry(sonos))
+	ctx := context.Background()
+
+	_, _, err := c.PlayerFor(ctx, "Bathroom")
+	if !errors.Is(err, rifferrors.ErrDeviceNotFound) {
+		t.Errorf("PlayerFor(Bathroom) error = %v, want ErrDeviceNotFound", err)
+	}
+
+	p, _, err := c.PlayerFor(ctx, "Kitchen")
+	if err != nil {
+		t.Fatalf("PlayerFor() error = %v", err)
+	}
+	err = p.(core.PlayModeController).Shuffle(ctx, true)
+	if !errors.Is(err, rifferrors.ErrUnsupported) {
+		t.Errorf("Shuffle() error = %v, want ErrUnsupported", err)
+	}
+	if err == nil || err.Error() != "shuffle is not supported by Kitchen: unsupported operation" {
+		t.Errorf("Shuffle() error = %q", err)
+	}
+
+	if err := c.Call(ctx, "player.dance", nil, nil); err == nil {
+		t.Error("Call(unknown method) error = nil")
+	}
+}
+
+func TestListenAndShutdown(t *testing.T) {
+	_, c, path, done := startServer(t, core.NewRegistry())
+
+	if _, err := Listen(path); err == nil {
+		t.Error("second Listen() succeeded, want already running")
+	}
+
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve() did not return after Shutdown")
+	}
+
+	// A stale socket is replaced
+	ln, err := Listen(path)
+	if err != nil {
+		t.Fatalf("Listen() over stale socket error = %v", err)
+	}
+	_ = ln.Close()
+}
+
+func TestServerReopensPlayers(t *testing.T) {
+	sonos := newFakeBackend(core.PlatformSonos,
+		core.Device{ID: "RINCON_1", Name: "Kitchen", Platform: core.PlatformSonos})
+	s, c, _, _ := startServer(t, core.NewRegistry(sonos))
+
+	ctx := context.Background()
+	p, _, err := c.PlayerFor(ctx, "Kitchen")
+	if err != nil {
+		t.Fatalf("PlayerFor() error = %v", err)
+	}
+	if err := p.Play(ctx); err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+	opened := sonos.opened
+
+	// A failed command looks the device up again, in case its group moved
+	sonos.players["RINCON_1"].SetErr(errors.New("group coordinator changed"))
+	if err := p.Pause(ctx); err == nil {
+		t.Fatal("Pause() error = nil, want the player's error")
+	}
+	sonos.players["RINCON_1"].SetErr(nil)
+	if err := p.Pause(ctx); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if sonos.opened != opened+1 {
+		t.Errorf("players opened = %d, want %d after an error", sonos.opened, opened+1)
+	}
+
+	// Players expire so grouping changes are picked up even without errors
+	s.mu.Lock()
+	s.playerTTL = 0
+	s.mu.Unlock()
+	if err := p.Play(ctx); err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+	if sonos.opened != opened+2 {
+		t.Errorf("players opened = %d, want %d after the TTL", sonos.opened, opened+2)
+	}
+}
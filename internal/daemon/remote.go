@@ -0,0 +1,250 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/tessro/riff/internal/core"
+)
+
+// Backend is a core.Backend served by the daemon.
+type Backend struct {
+	client   *Client
+	platform core.Platform
+}
+
+// Ensure Backend implements core.Backend
+var _ core.Backend = (*Backend)(nil)
+
+// Platform returns the platform the daemon's backend serves.
+func (b *Backend) Platform() core.Platform {
+	return b.platform
+}
+
+// Devices lists the backend's devices from the daemon's warm cache.
+func (b *Backend) Devices(ctx context.Context) ([]core.Device, error) {
+	var devices []core.Device
+	if err := b.client.Call(ctx, "backend.devices", platformParams{Platform: b.platform}, &devices); err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// Refresh asks the daemon to rediscover the backend's devices.
+func (b *Backend) Refresh(ctx context.Context) error {
+	return b.client.Call(ctx, "backend.refresh", platformParams{Platform: b.platform}, nil)
+}
+
+// PlayerFor opens a player for device in the daemon.
+func (b *Backend) PlayerFor(ctx context.Context, device *core.Device) (core.Player, error) {
+	var info playerInfo
+	t := target{Platform: b.platform, Device: device}
+	if err := b.client.Call(ctx, "player.open", t, &info); err != nil {
+		return nil, err
+	}
+	return newPlayer(b.client, &info), nil
+}
+
+// Search runs the backend's search in the daemon.
+func (b *Backend) Search(ctx context.Context, query string, kinds []core.SearchKind, limit int) ([]core.SearchResult, error) {
+	var results []core.SearchResult
+	params := searchParams{Platform: b.platform, Query: query, Kinds: kinds, Limit: limit}
+	if err := b.client.Call(ctx, "backend.search", params, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Player is a core.Player served by the daemon. It implements every
+// optional player interface; Capabilities reports what the daemon's player
+// actually supports, and calls it can't serve return an unsupported error.
+type Player struct {
+	client *Client
+	target target
+	caps   core.Capability
+	desc   string
+}
+
+// Ensure Player implements core.Player and the optional interfaces
+var (
+	_ core.Player                = (*Player)(nil)
+	_ core.QueueEditor           = (*Player)(nil)
+	_ core.PlayModeController    = (*Player)(nil)
+	_ core.GroupVolumeController = (*Player)(nil)
+	_ core.StreamPlayer          = (*Player)(nil)
+	_ core.MediaPlayer           = (*Player)(nil)
+	_ core.MediaQueuer           = (*Player)(nil)
+	_ core.InputSelector         = (*Player)(nil)
+)
+
+func newPlayer(c *Client, info *playerInfo) *Player {
+	return &Player{
+		client: c,
+		target: info.Target,
+		caps:   info.Capabilities,
+		desc:   info.Description,
+	}
+}
+
+// Platform returns the platform of the daemon's player.
+func (p *Player) Platform() core.Platform {
+	return p.target.Platform
+}
+
+// Describe names the daemon's player, for error messages.
+func (p *Player) Describe() string {
+	return p.desc
+}
+
+// Capabilities returns what the daemon's player supports.
+func (p *Player) Capabilities() core.Capability {
+	return p.caps
+}
+
+// call invokes a player method, adding the target to args.
+func (p *Player) call(ctx context.Context, method string, args, result any) error {
+	params := map[string]json.RawMessage{}
+	if args != nil {
+		data, err := json.Marshal(args)
+		if err != nil {
+			return fmt.Errorf("failed to encode params: %w", err)
+		}
+		if err := json.Unmarshal(data, &params); err != nil {
+			return fmt.Errorf("failed to encode params: %w", err)
+		}
+	}
+	t, err := json.Marshal(p.target)
+	if err != nil {
+		return fmt.Errorf("failed to encode params: %w", err)
+	}
+	params["target"] = t
+	return p.client.Call(ctx, method, params, result)
+}
+
+func (p *Player) Play(ctx context.Context) error {
+	return p.call(ctx, "player.play", nil, nil)
+}
+
+func (p *Player) Pause(ctx context.Context) error {
+	return p.call(ctx, "player.pause", nil, nil)
+}
+
+func (p *Player) Next(ctx context.Context) error {
+	return p.call(ctx, "player.next", nil, nil)
+}
+
+func (p *Player) Prev(ctx context.Context) error {
+	return p.call(ctx, "player.prev", nil, nil)
+}
+
+func (p *Player) Seek(ctx context.Context, positionMs int) error {
+	return p.call(ctx, "player.seek", seekParams{PositionMs: positionMs}, nil)
+}
+
+func (p *Player) Volume(ctx context.Context, percent int) error {
+	return p.call(ctx, "player.volume", percentParams{Percent: percent}, nil)
+}
+
+func (p *Player) GetState(ctx context.Context) (*core.PlaybackState, error) {
+	var state *core.PlaybackState
+	if err := p.call(ctx, "player.state", nil, &state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (p *Player) GetQueue(ctx context.Context) (*core.Queue, error) {
+	var queue core.Queue
+	if err := p.call(ctx, "player.queue", nil, &queue); err != nil {
+		return nil, err
+	}
+	return &queue, nil
+}
+
+func (p *Player) GetRecentlyPlayed(ctx context.Context, limit int) ([]core.HistoryEntry, error) {
+	var history []core.HistoryEntry
+	if err := p.call(ctx, "player.recent", limitParams{Limit: limit}, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func (p *Player) AddToQueue(ctx context.Context, trackURI string) error {
+	return p.call(ctx, "player.add_to_queue", uriParams{URI: trackURI}, nil)
+}
+
+func (p *Player) RemoveFromQueue(ctx context.Context, position int) error {
+	return p.call(ctx, "player.remove_from_queue", positionParams{Position: position}, nil)
+}
+
+func (p *Player) MoveInQueue(ctx context.Context, from, to int) error {
+	return p.call(ctx, "player.move_in_queue", moveParams{From: from, To: to}, nil)
+}
+
+func (p *Player) ClearQueue(ctx context.Context) error {
+	return p.call(ctx, "player.clear_queue", nil, nil)
+}
+
+func (p *Player) Shuffle(ctx context.Context, state bool) error {
+	return p.call(ctx, "player.shuffle", stateParams{State: state}, nil)
+}
+
+func (p *Player) Repeat(ctx context.Context, mode string) error {
+	return p.call(ctx, "player.repeat", modeParams{Mode: mode}, nil)
+}
+
+func (p *Player) GroupVolume(ctx context.Context) (int, error) {
+	var volume int
+	if err := p.call(ctx, "player.group_volume", nil, &volume); err != nil {
+		return 0, err
+	}
+	return volume, nil
+}
+
+func (p *Player) SetGroupVolume(ctx context.Context, percent int) error {
+	return p.call(ctx, "player.set_group_volume", percentParams{Percent: percent}, nil)
+}
+
+func (p *Player) CoordinatesGroup(ctx context.Context) (bool, error) {
+	var coordinates bool
+	if err := p.call(ctx, "player.coordinates_group", nil, &coordinates); err != nil {
+		return false, err
+	}
+	return coordinates, nil
+}
+
+func (p *Player) Inputs(ctx context.Context) ([]string, error) {
+	var inputs []string
+	if err := p.call(ctx, "player.inputs", nil, &inputs); err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
+
+func (p *Player) SelectInput(ctx context.Context, name string) error {
+	return p.call(ctx, "player.select_input", nameParams{Name: name}, nil)
+}
+
+func (p *Player) PlayURI(ctx context.Context, uri string) error {
+	return p.call(ctx, "player.play_uri", uriParams{URI: uri}, nil)
+}
+
+func (p *Player) PlayMedia(ctx context.Context, uri, metadata string) error {
+	return p.call(ctx, "player.play_media", uriParams{URI: uri, Metadata: metadata}, nil)
+}
+
+func (p *Player) QueueMedia(ctx context.Context, uri, metadata string) error {
+	return p.call(ctx, "player.queue_media", uriParams{URI: uri, Metadata: metadata}, nil)
+}
+
+// PlayContext starts an album, artist or playlist on players that support
+// it, such as Spotify's.
+func (p *Player) PlayContext(ctx context.Context, contextURI string, offset int) error {
+	return p.call(ctx, "player.play_context", contextParams{URI: contextURI, Offset: offset}, nil)
+}
+
+// TransferPlayback moves playback to deviceID on players that support it.
+func (p *Player) TransferPlayback(ctx context.Context, deviceID string, play bool) error {
+	return p.call(ctx, "player.transfer", transferParams{DeviceID: deviceID, Play: play}, nil)
+}
@@ -0,0 +1,614 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+	rifferrors "github.com/tessro/riff/internal/errors"
+)
+
+// maxRequestSize bounds a single request line.
+const maxRequestSize = 1 << 20
+
+// playerTTL is how long an opened player is reused. Sonos players are bound
+// to their group's coordinator at the time, so they are reopened now and
+// then to pick up grouping changes.
+const playerTTL = time.Minute
+
+// cachedPlayer is an opened player and when it was opened.
+type cachedPlayer struct {
+	player core.Player
+	opened time.Time
+}
+
+// handler answers one method. params is the raw "params" member.
+type handler func(ctx context.Context, params json.RawMessage) (any, error)
+
+// Server serves a registry's backends and players over JSON-RPC.
+type Server struct {
+	registry *core.Registry
+	version  string
+	started  time.Time
+	methods  map[string]handler
+
+	mu        sync.Mutex
+	players   map[string]cachedPlayer
+	playerTTL time.Duration
+
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// NewServer creates a server for registry. version is reported by
+// daemon.status.
+func NewServer(registry *core.Registry, version string) *Server {
+	s := &Server{
+		registry:  registry,
+		version:   version,
+		started:   time.Now(),
+		players:   make(map[string]cachedPlayer),
+		playerTTL: playerTTL,
+		done:      make(chan struct{}),
+	}
+	s.methods = map[string]handler{
+		"daemon.status":   s.status,
+		"daemon.shutdown": s.shutdown,
+
+		"backend.devices": s.devices,
+		"backend.refresh": s.refresh,
+		"backend.search":  s.search,
+
+		"player.select": s.selectPlayer,
+		"player.open":   s.openPlayer,
+
+		"player.play": playerMethod(s, func(ctx context.Context, p core.Player, _ struct{}) (any, error) {
+			return nil, p.Play(ctx)
+		}),
+		"player.pause": playerMethod(s, func(ctx context.Context, p core.Player, _ struct{}) (any, error) {
+			return nil, p.Pause(ctx)
+		}),
+		"player.next": playerMethod(s, func(ctx context.Context, p core.Player, _ struct{}) (any, error) {
+			return nil, p.Next(ctx)
+		}),
+		"player.prev": playerMethod(s, func(ctx context.Context, p core.Player, _ struct{}) (any, error) {
+			return nil, p.Prev(ctx)
+		}),
+		"player.seek": playerMethod(s, func(ctx context.Context, p core.Player, a seekParams) (any, error) {
+			return nil, p.Seek(ctx, a.PositionMs)
+		}),
+		"player.volume": playerMethod(s, func(ctx context.Context, p core.Player, a percentParams) (any, error) {
+			return nil, p.Volume(ctx, a.Percent)
+		}),
+		"player.state": playerMethod(s, func(ctx context.Context, p core.Player, _ struct{}) (any, error) {
+			return p.GetState(ctx)
+		}),
+		"player.queue": playerMethod(s, func(ctx context.Context, p core.Player, _ struct{}) (any, error) {
+			return p.GetQueue(ctx)
+		}),
+		"player.recent": playerMethod(s, func(ctx context.Context, p core.Player, a limitParams) (any, error) {
+			return p.GetRecentlyPlayed(ctx, a.Limit)
+		}),
+		"player.add_to_queue": playerMethod(s, func(ctx context.Context, p core.Player, a uriParams) (any, error) {
+			return nil, p.AddToQueue(ctx, a.URI)
+		}),
+
+		"player.remove_from_queue": playerMethod(s, func(ctx context.Context, p core.Player, a positionParams) (any, error) {
+			q, err := optional[core.QueueEditor](p, "editing the queue")
+			if err != nil {
+				return nil, err
+			}
+			return nil, q.RemoveFromQueue(ctx, a.Position)
+		}),
+		"player.move_in_queue": playerMethod(s, func(ctx context.Context, p core.Player, a moveParams) (any, error) {
+			q, err := optional[core.QueueEditor](p, "editing the queue")
+			if err != nil {
+				return nil, err
+			}
+			return nil, q.MoveInQueue(ctx, a.From, a.To)
+		}),
+		"player.clear_queue": playerMethod(s, func(ctx context.Context, p core.Player, _ struct{}) (any, error) {
+			q, err := optional[core.QueueEditor](p, "editing the queue")
+			if err != nil {
+				return nil, err
+			}
+			return nil, q.ClearQueue(ctx)
+		}),
+		"player.shuffle": playerMethod(s, func(ctx context.Context, p core.Player, a stateParams) (any, error) {
+			c, err := optional[core.PlayModeController](p, "shuffle")
+			if err != nil {
+				return nil, err
+			}
+			return nil, c.Shuffle(ctx, a.State)
+		}),
+		"player.repeat": playerMethod(s, func(ctx context.Context, p core.Player, a modeParams) (any, error) {
+			c, err := optional[core.PlayModeController](p, "repeat")
+			if err != nil {
+				return nil, err
+			}
+			return nil, c.Repeat(ctx, a.Mode)
+		}),
+		"player.group_volume": playerMethod(s, func(ctx context.Context, p core.Player, _ struct{}) (any, error) {
+			g, err := optional[core.GroupVolumeController](p, "group volume")
+			if err != nil {
+				return nil, err
+			}
+			return g.GroupVolume(ctx)
+		}),
+		"player.set_group_volume": playerMethod(s, func(ctx context.Context, p core.Player, a percentParams) (any, error) {
+			g, err := optional[core.GroupVolumeController](p, "group volume")
+			if err != nil {
+				return nil, err
+			}
+			return nil, g.SetGroupVolume(ctx, a.Percent)
+		}),
+		"player.coordinates_group": playerMethod(s, func(ctx context.Context, p core.Player, _ struct{}) (any, error) {
+			g, err := optional[core.GroupVolumeController](p, "group volume")
+			if err != nil {
+				return nil, err
+			}
+			return g.CoordinatesGroup(ctx)
+		}),
+		"player.inputs": playerMethod(s, func(ctx context.Context, p core.Player, _ struct{}) (any, error) {
+			i, err := optional[core.InputSelector](p, "switching inputs")
+			if err != nil {
+				return nil, err
+			}
+			return i.Inputs(ctx)
+		}),
+		"player.select_input": playerMethod(s, func(ctx context.Context, p core.Player, a nameParams) (any, error) {
+			i, err := optional[core.InputSelector](p, "switching inputs")
+			if err != nil {
+				return nil, err
+			}
+			return nil, i.SelectInput(ctx, a.Name)
+		}),
+		"player.play_uri": playerMethod(s, func(ctx context.Context, p core.Player, a uriParams) (any, error) {
+			sp, err := optional[core.StreamPlayer](p, "playing URIs")
+			if err != nil {
+				return nil, err
+			}
+			return nil, sp.PlayURI(ctx, a.URI)
+		}),
+		"player.play_media": playerMethod(s, func(ctx context.Context, p core.Player, a uriParams) (any, error) {
+			// Players without metadata support still take the bare URL, as
+			// 'riff media play' does in direct mode
+			if mp, ok := p.(core.MediaPlayer); ok {
+				return nil, mp.PlayMedia(ctx, a.URI, a.Metadata)
+			}
+			if err := core.Require(p, core.CapStream); err != nil {
+				return nil, err
+			}
+			return nil, p.(core.StreamPlayer).PlayURI(ctx, a.URI)
+		}),
+		"player.queue_media": playerMethod(s, func(ctx context.Context, p core.Player, a uriParams) (any, error) {
+			if mq, ok := p.(core.MediaQueuer); ok {
+				return nil, mq.QueueMedia(ctx, a.URI, a.Metadata)
+			}
+			if err := core.Require(p, core.CapQueueAdd|core.CapStream); err != nil {
+				return nil, err
+			}
+			return nil, p.AddToQueue(ctx, a.URI)
+		}),
+		"player.play_context": playerMethod(s, func(ctx context.Context, p core.Player, a contextParams) (any, error) {
+			c, err := optional[contextPlayer](p, "playing albums and playlists")
+			if err != nil {
+				return nil, err
+			}
+			return nil, c.PlayContext(ctx, a.URI, a.Offset)
+		}),
+		"player.transfer": playerMethod(s, func(ctx context.Context, p core.Player, a transferParams) (any, error) {
+			t, err := optional[playbackTransferer](p, "transferring playback")
+			if err != nil {
+				return nil, err
+			}
+			return nil, t.TransferPlayback(ctx, a.DeviceID, a.Play)
+		}),
+	}
+	return s
+}
+
+// contextPlayer is implemented by players that can start an album, artist
+// or playlist, such as Spotify's.
+type contextPlayer interface {
+	PlayContext(ctx context.Context, contextURI string, offset int) error
+}
+
+// playbackTransferer is implemented by players that can move playback
+// between devices.
+type playbackTransferer interface {
+	TransferPlayback(ctx context.Context, deviceID string, play bool) error
+}
+
+// Listen creates the socket at path, replacing a stale one left by a
+// daemon that exited without cleaning up. It fails if a daemon is already
+// listening there.
+func Listen(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	if conn, err := net.DialTimeout("unix", path, dialTimeout); err == nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("riffd is already running on %s", path)
+	}
+	_ = os.Remove(path)
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+	return ln, nil
+}
+
+// Serve accepts connections on ln until ctx is cancelled or a client calls
+// daemon.shutdown. It closes ln before returning.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	// Cancelling closes open connections, so it must run before the wait
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-s.done:
+		}
+		_ = ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return nil
+			default:
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveConn(ctx, conn)
+		}()
+	}
+}
+
+// Done is closed when a client has asked the daemon to shut down.
+func (s *Server) Done() <-chan struct{} {
+	return s.done
+}
+
+// KeepWarm lists every backend's devices once, loading their caches, then
+// refreshes the backends that support it each interval, so commands never
+// wait on discovery. It returns when ctx is cancelled or the server stops.
+func (s *Server) KeepWarm(ctx context.Context, interval time.Duration) {
+	for _, b := range s.registry.Backends() {
+		_, _ = b.Devices(ctx)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.done:
+			return
+		case <-ticker.C:
+			for _, b := range s.registry.Backends() {
+				if r, ok := b.(interface{ Refresh(context.Context) error }); ok {
+					_ = r.Refresh(ctx)
+				}
+			}
+		}
+	}
+}
+
+// serveConn answers requests on conn, one per line, until it closes.
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRequestSize)
+	enc := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var req request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			_ = enc.Encode(&response{JSONRPC: "2.0", Error: &rpcError{Code: codeParse, Message: err.Error()}})
+			continue
+		}
+		if err := enc.Encode(s.handle(ctx, &req)); err != nil {
+			return
+		}
+		// Shut down only once the reply is out, since stopping closes conn
+		if req.Method == "daemon.shutdown" {
+			s.doneOnce.Do(func() { close(s.done) })
+		}
+	}
+}
+
+// handle dispatches one request.
+func (s *Server) handle(ctx context.Context, req *request) *response {
+	resp := &response{JSONRPC: "2.0", ID: req.ID}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		resp.Error = &rpcError{Code: codeInvalidRequest, Message: "invalid request"}
+		return resp
+	}
+
+	h, ok := s.methods[req.Method]
+	if !ok {
+		resp.Error = &rpcError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+		return resp
+	}
+
+	result, err := h(ctx, req.Params)
+	if err != nil {
+		var pe *paramsError
+		if errors.As(err, &pe) {
+			resp.Error = &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		} else {
+			resp.Error = encodeError(err)
+		}
+		return resp
+	}
+	if result != nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			resp.Error = encodeError(fmt.Errorf("failed to encode result: %w", err))
+			return resp
+		}
+		resp.Result = data
+	}
+	return resp
+}
+
+// paramsError reports params that don't match the method.
+type paramsError struct {
+	err error
+}
+
+func (e *paramsError) Error() string { return "invalid params: " + e.err.Error() }
+func (e *paramsError) Unwrap() error { return e.err }
+
+// decodeParams unmarshals params into v. Missing params leave v zero.
+func decodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &paramsError{err}
+	}
+	return nil
+}
+
+// playerMethod adapts fn into a handler that resolves the request's target
+// to a player, then decodes the remaining params as A.
+func playerMethod[A any](s *Server, fn func(ctx context.Context, p core.Player, args A) (any, error)) handler {
+	return func(ctx context.Context, params json.RawMessage) (any, error) {
+		var t struct {
+			Target target `json:"target"`
+		}
+		var args A
+		if err := decodeParams(params, &t); err != nil {
+			return nil, err
+		}
+		if err := decodeParams(params, &args); err != nil {
+			return nil, err
+		}
+		p, err := s.player(ctx, t.Target)
+		if err != nil {
+			return nil, err
+		}
+		result, err := fn(ctx, p, args)
+		if err != nil && !errors.Is(err, rifferrors.ErrUnsupported) {
+			// The device may have moved, as when a Sonos room joins
+			// another group, so look it up again next time
+			s.forget(t.Target, p)
+		}
+		return result, err
+	}
+}
+
+// optional returns p as T, or an unsupported error naming operation.
+func optional[T any](p core.Player, operation string) (T, error) {
+	v, ok := p.(T)
+	if !ok {
+		return v, rifferrors.Unsupported(operation, describe(p), "")
+	}
+	return v, nil
+}
+
+// describe names a player for errors and playerInfo.
+func describe(p core.Player) string {
+	if d, ok := p.(interface{ Describe() string }); ok {
+		return d.Describe()
+	}
+	return "this device"
+}
+
+// backend returns the registered backend for platform.
+func (s *Server) backend(platform core.Platform) (core.Backend, error) {
+	b := s.registry.Backend(platform)
+	if b == nil {
+		return nil, fmt.Errorf("%s is not available in riffd", platform)
+	}
+	return b, nil
+}
+
+// player returns the cached player for t, opening one if there is none or
+// it has been in use for longer than the server's player TTL.
+func (s *Server) player(ctx context.Context, t target) (core.Player, error) {
+	s.mu.Lock()
+	c, ok := s.players[t.key()]
+	fresh := ok && time.Since(c.opened) < s.playerTTL
+	s.mu.Unlock()
+	if fresh {
+		return c.player, nil
+	}
+	return s.open(ctx, t)
+}
+
+// open creates a player for t and caches it, replacing any earlier one so
+// a reopened Sonos player picks up group changes.
+func (s *Server) open(ctx context.Context, t target) (core.Player, error) {
+	b, err := s.backend(t.Platform)
+	if err != nil {
+		return nil, err
+	}
+	p, err := b.PlayerFor(ctx, t.Device)
+	if err != nil {
+		return nil, err
+	}
+	s.remember(t, p)
+	return p, nil
+}
+
+func (s *Server) remember(t target, p core.Player) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.players[t.key()] = cachedPlayer{player: p, opened: time.Now()}
+}
+
+// forget drops the cached player for t if it is still p.
+func (s *Server) forget(t target, p core.Player) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.players[t.key()]; ok && c.player == p {
+		delete(s.players, t.key())
+	}
+}
+
+func info(t target, p core.Player, d *core.Device) *playerInfo {
+	return &playerInfo{
+		Target:       t,
+		Device:       d,
+		Capabilities: p.Capabilities(),
+		Description:  describe(p),
+	}
+}
+
+func (s *Server) status(ctx context.Context, params json.RawMessage) (any, error) {
+	platforms := []core.Platform{}
+	for _, b := range s.registry.Backends() {
+		platforms = append(platforms, b.Platform())
+	}
+	s.mu.Lock()
+	players := len(s.players)
+	s.mu.Unlock()
+	return &Status{
+		PID:       os.Getpid(),
+		Version:   s.version,
+		StartedAt: s.started,
+		Platforms: platforms,
+		Players:   players,
+	}, nil
+}
+
+// shutdown acknowledges daemon.shutdown; serveConn stops the server after
+// replying.
+func (s *Server) shutdown(ctx context.Context, params json.RawMessage) (any, error) {
+	return nil, nil
+}
+
+func (s *Server) devices(ctx context.Context, params json.RawMessage) (any, error) {
+	var p platformParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	b, err := s.backend(p.Platform)
+	if err != nil {
+		return nil, err
+	}
+	devices, err := b.Devices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if devices == nil {
+		devices = []core.Device{}
+	}
+	return devices, nil
+}
+
+func (s *Server) refresh(ctx context.Context, params json.RawMessage) (any, error) {
+	var p platformParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	b, err := s.backend(p.Platform)
+	if err != nil {
+		return nil, err
+	}
+	r, ok := b.(interface{ Refresh(context.Context) error })
+	if !ok {
+		return nil, nil
+	}
+	return nil, r.Refresh(ctx)
+}
+
+func (s *Server) search(ctx context.Context, params json.RawMessage) (any, error) {
+	var p searchParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	b, err := s.backend(p.Platform)
+	if err != nil {
+		return nil, err
+	}
+	return b.Search(ctx, p.Query, p.Kinds, p.Limit)
+}
+
+// selectPlayer resolves a --to target, or the default player, across every
+// backend.
+func (s *Server) selectPlayer(ctx context.Context, params json.RawMessage) (any, error) {
+	var p selectParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	b, player, d, err := s.registry.Select(ctx, p.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	// The default player was created without a device, so it stays
+	// addressed that way even when its state names the playing device
+	t := target{Platform: b.Platform()}
+	if p.Target != "" {
+		t.Device = d
+	}
+	s.remember(t, player)
+
+	return info(t, player, d), nil
+}
+
+// openPlayer creates a player for a device from one backend.
+func (s *Server) openPlayer(ctx context.Context, params json.RawMessage) (any, error) {
+	var t target
+	if err := decodeParams(params, &t); err != nil {
+		return nil, err
+	}
+	p, err := s.open(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	return info(t, p, t.Device), nil
+}
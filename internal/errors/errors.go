@@ -18,6 +18,10 @@ var (
 	ErrTimeout          = errors.New("request timeout")
 	ErrConfigNotFound   = errors.New("config file not found")
 	ErrInvalidConfig    = errors.New("invalid configuration")
+
+	// ErrUnsupported is the standard library's errors.ErrUnsupported, so
+	// errors.Is matches either.
+	ErrUnsupported = errors.ErrUnsupported
 )
 
 // RiffError wraps an error with a user-friendly suggestion.
@@ -42,6 +46,16 @@ func WithSuggestion(err error, suggestion string) error {
 	}
 }
 
+// Unsupported reports that operation is not available on the target device.
+// The error wraps ErrUnsupported and carries suggestion, if any.
+func Unsupported(operation, target, suggestion string) error {
+	err := fmt.Errorf("%s is not supported by %s: %w", operation, target, ErrUnsupported)
+	if suggestion == "" {
+		return err
+	}
+	return WithSuggestion(err, suggestion)
+}
+
 // GetSuggestion returns a suggestion for the given error.
 func GetSuggestion(err error) string {
 	if err == nil {
@@ -72,6 +86,10 @@ func GetSuggestion(err error) string {
 		return "Run 'riff devices' to see available devices"
 	}
 
+	if errors.Is(err, ErrUnsupported) {
+		return "Use --to to pick a device that supports this; run 'riff devices' to list them"
+	}
+
 	// Premium errors
 	if errors.Is(err, ErrPremiumRequired) || strings.Contains(errStr, "premium required") ||
 		strings.Contains(errStr, "restricted device") {
@@ -0,0 +1,66 @@
+package fade
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Claim marks a fade as the owner of a device's volume, in a file shared by
+// every riff process. Claiming the same device again, or calling Supersede,
+// cancels the older fade at its next step.
+type Claim struct {
+	path  string
+	token string
+}
+
+// claimPath returns the file for key in dir.
+func claimPath(dir, key string) string {
+	safe := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, key)
+	return filepath.Join(dir, "fade-"+safe)
+}
+
+// NewClaim claims key's volume, superseding any fade already running on it.
+func NewClaim(dir, key string) (*Claim, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	c := &Claim{
+		path:  claimPath(dir, key),
+		token: fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano()),
+	}
+	if err := os.WriteFile(c.path, []byte(c.token), 0600); err != nil {
+		return nil, fmt.Errorf("failed to claim volume: %w", err)
+	}
+	return c, nil
+}
+
+// Superseded reports whether another process has claimed the volume since.
+func (c *Claim) Superseded() bool {
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return true
+	}
+	return err == nil && string(data) != c.token
+}
+
+// Release gives up the claim, if it is still held.
+func (c *Claim) Release() {
+	if !c.Superseded() {
+		_ = os.Remove(c.path)
+	}
+}
+
+// Supersede cancels any fade running on key, e.g. because the volume was
+// just set directly.
+func Supersede(dir, key string) {
+	_ = os.Remove(claimPath(dir, key))
+}
@@ -0,0 +1,140 @@
+// Package fade moves a player's volume gradually.
+package fade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	rifferrors "github.com/tessro/riff/internal/errors"
+)
+
+// Curve shapes how the volume moves over a fade.
+type Curve string
+
+const (
+	// Linear changes the volume by the same amount each second.
+	Linear Curve = "linear"
+	// Log changes the volume by the same ratio each second, so loudness
+	// moves evenly: fade-ins start gently and fade-outs linger at the end.
+	Log Curve = "log"
+)
+
+// ParseCurve parses a curve name. Empty means Linear.
+func ParseCurve(s string) (Curve, error) {
+	switch Curve(s) {
+	case "", Linear:
+		return Linear, nil
+	case Log:
+		return Log, nil
+	default:
+		return "", fmt.Errorf("invalid fade curve %q (want linear or log)", s)
+	}
+}
+
+// level returns the volume a fraction frac of the way from from to to.
+func (c Curve) level(from, to int, frac float64) int {
+	if frac >= 1 {
+		return to
+	}
+	if c == Log {
+		// Offset by one so fades to and from zero stay finite
+		a, b := float64(from+1), float64(to+1)
+		return int(math.Round(a*math.Pow(b/a, frac))) - 1
+	}
+	return from + int(math.Round(float64(to-from)*frac))
+}
+
+// Intervals between volume changes. Spotify's Web API rate limits per
+// account, so its steps are further apart.
+const (
+	DefaultInterval = 200 * time.Millisecond
+	SpotifyInterval = time.Second
+
+	maxInterval = 10 * time.Second
+)
+
+// ErrSuperseded is returned when a newer volume change cancels a fade.
+var ErrSuperseded = errors.New("fade cancelled by a newer volume change")
+
+// Setter sets a volume. core.Player satisfies it.
+type Setter interface {
+	Volume(ctx context.Context, percent int) error
+}
+
+// Options configures a fade.
+type Options struct {
+	Curve Curve
+
+	// Interval is the shortest time between volume changes (default
+	// DefaultInterval). Rate-limited fades back off from it and take
+	// bigger steps.
+	Interval time.Duration
+
+	// Superseded reports whether something else has taken over the
+	// volume, which stops the fade with ErrSuperseded. It may be nil.
+	Superseded func() bool
+}
+
+// Run moves the volume from from to to over d, ending at exactly to. A zero
+// d sets to at once.
+func Run(ctx context.Context, s Setter, from, to int, d time.Duration, opts Options) error {
+	if d <= 0 || from == to {
+		return s.Volume(ctx, to)
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	// No point stepping more often than the volume can change
+	interval = max(interval, d/time.Duration(abs(to-from)))
+
+	start := time.Now()
+	last := from
+	timer := time.NewTimer(min(interval, d))
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if opts.Superseded != nil && opts.Superseded() {
+			return ErrSuperseded
+		}
+
+		frac := float64(time.Since(start)) / float64(d)
+		v := opts.Curve.level(from, to, frac)
+		if v != last {
+			err := s.Volume(ctx, v)
+			switch {
+			case errors.Is(err, rifferrors.ErrRateLimited):
+				// Wait longer, and catch up with a bigger step
+				interval = min(2*interval, maxInterval)
+			case err != nil:
+				return err
+			default:
+				last = v
+			}
+		}
+		if frac >= 1 && last == to {
+			return nil
+		}
+
+		// Land the last step on time
+		wait := interval
+		if rest := d - time.Since(start); rest > 0 {
+			wait = min(wait, rest)
+		}
+		timer.Reset(wait)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,154 @@
+package fade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	rifferrors "github.com/tessro/riff/internal/errors"
+)
+
+func TestCurveLevel(t *testing.T) {
+	tests := []struct {
+		curve    Curve
+		from, to int
+		frac     float64
+		want     int
+	}{
+		{Linear, 0, 100, 0, 0},
+		{Linear, 0, 100, 0.5, 50},
+		{Linear, 60, 20, 0.25, 50},
+		{Linear, 60, 20, 1.5, 20},
+		{Log, 0, 99, 0.5, 9},
+		{Log, 99, 0, 0.5, 9},
+		{Log, 99, 0, 1, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.curve.level(tt.from, tt.to, tt.frac); got != tt.want {
+			t.Errorf("%s level(%d, %d, %v) = %d, want %d", tt.curve, tt.from, tt.to, tt.frac, got, tt.want)
+		}
+	}
+
+	if _, err := ParseCurve("exponential"); err == nil {
+		t.Error("ParseCurve() accepted an unknown curve")
+	}
+}
+
+// fakeSetter records volumes and can fail with a rate limit.
+type fakeSetter struct {
+	mu          sync.Mutex
+	volumes     []int
+	rateLimited int // calls to fail
+}
+
+func (s *fakeSetter) Volume(ctx context.Context, percent int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.rateLimited > 0 {
+		s.rateLimited--
+		return fmt.Errorf("API error: status 429: %w", rifferrors.ErrRateLimited)
+	}
+	s.volumes = append(s.volumes, percent)
+	return nil
+}
+
+func (s *fakeSetter) Volumes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int(nil), s.volumes...)
+}
+
+func TestRun(t *testing.T) {
+	s := &fakeSetter{}
+	start := time.Now()
+	if err := Run(context.Background(), s, 10, 0, 100*time.Millisecond, Options{Interval: 10 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("fade took %v, want at least 100ms", elapsed)
+	}
+
+	vols := s.Volumes()
+	if len(vols) < 2 || vols[len(vols)-1] != 0 {
+		t.Fatalf("volumes = %v, want a fade ending at 0", vols)
+	}
+	for i := 1; i < len(vols); i++ {
+		if vols[i] >= vols[i-1] {
+			t.Errorf("volumes = %v, want strictly decreasing", vols)
+			break
+		}
+	}
+}
+
+func TestRunRateLimited(t *testing.T) {
+	s := &fakeSetter{rateLimited: 2}
+	err := Run(context.Background(), s, 0, 100, 200*time.Millisecond, Options{Interval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Backing off means fewer, bigger steps, still ending on target
+	vols := s.Volumes()
+	if len(vols) == 0 || vols[len(vols)-1] != 100 {
+		t.Fatalf("volumes = %v, want a fade ending at 100", vols)
+	}
+	if len(vols) > 8 {
+		t.Errorf("got %d steps after rate limiting, want fewer", len(vols))
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	s := &fakeSetter{}
+	superseded := func() bool { return len(s.Volumes()) >= 2 }
+	err := Run(context.Background(), s, 0, 50, time.Second, Options{Interval: 5 * time.Millisecond, Superseded: superseded})
+	if !errors.Is(err, ErrSuperseded) {
+		t.Errorf("Run() = %v, want ErrSuperseded", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := Run(ctx, &fakeSetter{}, 0, 50, time.Second, Options{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run() = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestClaim(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := NewClaim(dir, "RINCON_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Superseded() {
+		t.Fatal("new claim is already superseded")
+	}
+
+	// Another device doesn't interfere
+	other, err := NewClaim(dir, "spotify/abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Release()
+	if first.Superseded() {
+		t.Error("claim on another device superseded the first")
+	}
+
+	second, err := NewClaim(dir, "RINCON_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.Superseded() {
+		t.Error("newer claim didn't supersede the first")
+	}
+	first.Release() // must not release the newer claim
+	if second.Superseded() {
+		t.Error("releasing a stale claim removed the newer one")
+	}
+
+	Supersede(dir, "RINCON_1")
+	if !second.Superseded() {
+		t.Error("Supersede() didn't cancel the claim")
+	}
+}
@@ -0,0 +1,152 @@
+// Package handoff moves what is playing from one device to another, across
+// Spotify Connect devices and Sonos rooms, keeping the position and volume.
+package handoff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/tessro/riff/internal/core"
+	rifferrors "github.com/tessro/riff/internal/errors"
+)
+
+// Groups changes Sonos groups. sonos.Backend satisfies it.
+type Groups interface {
+	Join(ctx context.Context, speaker, target string) error
+	Leave(ctx context.Context, speaker string) (string, error)
+	CoordinatorOf(ctx context.Context, speaker string) (string, error)
+}
+
+// Options configures Handoff.
+type Options struct {
+	// Logger reports each step at debug level. Nil means slog.Default().
+	Logger *slog.Logger
+}
+
+// Result describes a completed handoff.
+type Result struct {
+	From *core.Device
+	To   *core.Device
+	// Session is what was handed over. It is nil when Sonos rooms were
+	// regrouped instead.
+	Session *core.Session
+}
+
+// Handoff moves playback from the device named from, or whatever is
+// playing when from is empty, to the device named to.
+//
+// Between Sonos rooms the target joins the source's group and the source
+// leaves it, which works for any content. Otherwise the source's session
+// (the album or playlist, the track and the position) is started on the
+// target. Either way the target gets the source's volume and starts before
+// the source is paused, so if it fails the source keeps playing.
+func Handoff(ctx context.Context, b core.PlayerResolver, g Groups, from, to string, opts Options) (*Result, error) {
+	log := opts.Logger
+	if log == nil {
+		log = slog.Default()
+	}
+
+	src, srcDevice, err := b.PlayerFor(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	if srcDevice == nil {
+		return nil, errors.New("nothing is playing; name the device to hand off from with --from")
+	}
+	dst, dstDevice, err := b.PlayerFor(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+	if dstDevice.ID == srcDevice.ID {
+		return nil, fmt.Errorf("%s is already playing", dstDevice.Name)
+	}
+	res := &Result{From: srcDevice, To: dstDevice}
+
+	if g != nil && srcDevice.Platform == core.PlatformSonos && dstDevice.Platform == core.PlatformSonos {
+		return res, regroup(ctx, src, dst, srcDevice, dstDevice, g, log)
+	}
+
+	source, ok := src.(core.SessionPlayer)
+	if !ok {
+		return nil, rifferrors.Unsupported("handoff", srcDevice.Name, "hand off between Spotify Connect devices and Sonos rooms")
+	}
+	target, ok := dst.(core.SessionPlayer)
+	if !ok {
+		return nil, rifferrors.Unsupported("handoff", dstDevice.Name, "hand off between Spotify Connect devices and Sonos rooms")
+	}
+
+	s, err := source.Session(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", srcDevice.Name, err)
+	}
+	res.Session = s
+	log.DebugContext(ctx, "handing off", "track", s.Track, "progress", s.Progress, "from", srcDevice.Name, "to", dstDevice.Name)
+
+	// Spotify only takes a volume for the device that is playing, so it is
+	// set once the target has started. Elsewhere it is set first, so the
+	// target doesn't start loud.
+	volumeFirst := dstDevice.Platform != core.PlatformSpotify
+	if volumeFirst {
+		matchVolume(ctx, dst, dstDevice, s.Volume, log)
+	}
+	if err := target.ResumeSession(ctx, s); err != nil {
+		return nil, fmt.Errorf("start on %s: %w", dstDevice.Name, err)
+	}
+	if !volumeFirst {
+		matchVolume(ctx, dst, dstDevice, s.Volume, log)
+	}
+
+	// One Spotify account plays on one device at a time, so starting the
+	// target already stopped the source.
+	if srcDevice.Platform == core.PlatformSpotify && dstDevice.Platform == core.PlatformSpotify {
+		return res, nil
+	}
+	if err := src.Pause(ctx); err != nil {
+		return res, fmt.Errorf("playing on %s, but pausing %s failed: %w", dstDevice.Name, srcDevice.Name, err)
+	}
+	return res, nil
+}
+
+// regroup hands off between Sonos rooms by joining the target to the
+// source's group, then taking the source out of it.
+func regroup(ctx context.Context, src, dst core.Player, from, to *core.Device, g Groups, log *slog.Logger) error {
+	state, err := src.GetState(ctx)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", from.Name, err)
+	}
+	matchVolume(ctx, dst, to, state.Volume, log)
+
+	leader, err := g.CoordinatorOf(ctx, from.Name)
+	if err != nil {
+		return err
+	}
+	current, err := g.CoordinatorOf(ctx, to.Name)
+	if err != nil {
+		return err
+	}
+	if current != leader {
+		log.DebugContext(ctx, "joining group", "device", to.Name, "coordinator", leader)
+		if err := g.Join(ctx, to.Name, leader); err != nil {
+			return fmt.Errorf("start on %s: %w", to.Name, err)
+		}
+	}
+	log.DebugContext(ctx, "leaving group", "device", from.Name)
+	if _, err := g.Leave(ctx, from.Name); err != nil {
+		return fmt.Errorf("playing on %s, but removing %s from the group failed: %w", to.Name, from.Name, err)
+	}
+	return nil
+}
+
+// matchVolume sets the target to the source's volume. It is best effort:
+// some devices don't allow volume control, and that shouldn't stop the
+// music moving.
+func matchVolume(ctx context.Context, p core.Player, d *core.Device, volume int, log *slog.Logger) {
+	if volume <= 0 {
+		return
+	}
+	if err := p.Volume(ctx, volume); err != nil {
+		log.WarnContext(ctx, "handoff failed to set the volume", "device", d.Name, "error", err)
+	}
+}
@@ -0,0 +1,247 @@
+package handoff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+)
+
+// home is a fake pair of Spotify devices and three Sonos rooms. Every call
+// that changes something is recorded in order.
+type home struct {
+	calls        []string
+	volumes      map[string]int
+	coordinators map[string]string
+	sessions     map[string]*core.Session
+	failResume   string
+}
+
+func newHome() *home {
+	return &home{
+		volumes:      map[string]int{"Laptop": 60, "Phone": 40, "Office": 20, "Kitchen": 50, "Den": 10},
+		coordinators: map[string]string{"Office": "Office", "Kitchen": "Kitchen", "Den": "Den"},
+		sessions:     map[string]*core.Session{},
+	}
+}
+
+func (h *home) record(format string, args ...any) {
+	h.calls = append(h.calls, fmt.Sprintf(format, args...))
+}
+
+func (h *home) PlayerFor(ctx context.Context, target string) (core.Player, *core.Device, error) {
+	if target == "" {
+		for name := range h.sessions {
+			target = name
+		}
+		if target == "" {
+			return &player{h: h}, nil, nil
+		}
+	}
+	if _, ok := h.volumes[target]; !ok {
+		return nil, nil, fmt.Errorf("device '%s' not found", target)
+	}
+	platform := core.PlatformSonos
+	if target == "Laptop" || target == "Phone" {
+		platform = core.PlatformSpotify
+	}
+	return &player{h: h, name: target}, &core.Device{ID: target, Name: target, Platform: platform}, nil
+}
+
+func (h *home) Join(ctx context.Context, speaker, target string) error {
+	h.record("join %s %s", speaker, target)
+	h.coordinators[speaker] = h.coordinators[target]
+	return nil
+}
+
+func (h *home) Leave(ctx context.Context, speaker string) (string, error) {
+	h.record("leave %s", speaker)
+	group := h.coordinators[speaker]
+	h.coordinators[speaker] = speaker
+	return group, nil
+}
+
+func (h *home) CoordinatorOf(ctx context.Context, speaker string) (string, error) {
+	return h.coordinators[speaker], nil
+}
+
+// player is a device in the home. What it plays is its session.
+type player struct {
+	h    *home
+	name string
+}
+
+func (p *player) Play(ctx context.Context) error { return nil }
+
+func (p *player) Pause(ctx context.Context) error {
+	p.h.record("pause %s", p.name)
+	delete(p.h.sessions, p.name)
+	return nil
+}
+
+func (p *player) Next(ctx context.Context) error                   { return nil }
+func (p *player) Prev(ctx context.Context) error                   { return nil }
+func (p *player) Seek(ctx context.Context, positionMs int) error   { return nil }
+func (p *player) AddToQueue(ctx context.Context, uri string) error { return nil }
+
+func (p *player) Volume(ctx context.Context, percent int) error {
+	p.h.record("volume %s %d", p.name, percent)
+	p.h.volumes[p.name] = percent
+	return nil
+}
+
+func (p *player) GetState(ctx context.Context) (*core.PlaybackState, error) {
+	return &core.PlaybackState{IsPlaying: p.h.sessions[p.name] != nil, Volume: p.h.volumes[p.name]}, nil
+}
+
+func (p *player) GetQueue(ctx context.Context) (*core.Queue, error) { return &core.Queue{}, nil }
+func (p *player) GetRecentlyPlayed(ctx context.Context, limit int) ([]core.HistoryEntry, error) {
+	return nil, nil
+}
+func (p *player) Capabilities() core.Capability { return 0 }
+
+func (p *player) Session(ctx context.Context) (*core.Session, error) {
+	s, ok := p.h.sessions[p.name]
+	if !ok {
+		return nil, errors.New("nothing is playing")
+	}
+	s.Volume = p.h.volumes[p.name]
+	return s, nil
+}
+
+func (p *player) ResumeSession(ctx context.Context, s *core.Session) error {
+	if p.name == p.h.failResume {
+		return errors.New("playlist unavailable")
+	}
+	p.h.record("resume %s %s %s", p.name, s.Context, s.Progress)
+	if p.name == "Laptop" || p.name == "Phone" {
+		// Starting one Spotify device stops the other
+		delete(p.h.sessions, "Laptop")
+		delete(p.h.sessions, "Phone")
+	}
+	p.h.sessions[p.name] = s
+	return nil
+}
+
+func mix() *core.Session {
+	return &core.Session{
+		Context:  "spotify:playlist:mix",
+		Track:    "spotify:track:2",
+		Progress: 90 * time.Second,
+	}
+}
+
+func TestHandoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		want     []string
+	}{
+		{
+			name: "Spotify to Sonos",
+			from: "Laptop", to: "Kitchen",
+			want: []string{"volume Kitchen 60", "resume Kitchen spotify:playlist:mix 1m30s", "pause Laptop"},
+		},
+		{
+			name: "Sonos to Spotify",
+			from: "Kitchen", to: "Laptop",
+			want: []string{"resume Laptop spotify:playlist:mix 1m30s", "volume Laptop 50", "pause Kitchen"},
+		},
+		{
+			name: "Spotify to Spotify",
+			from: "Laptop", to: "Phone",
+			want: []string{"resume Phone spotify:playlist:mix 1m30s", "volume Phone 60"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHome()
+			h.sessions[tt.from] = mix()
+
+			res, err := Handoff(context.Background(), h, h, "", tt.to, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.From.Name != tt.from || res.To.Name != tt.to || res.Session == nil {
+				t.Errorf("Handoff() = %+v", res)
+			}
+			if !slices.Equal(h.calls, tt.want) {
+				t.Errorf("calls = %q\nwant %q", h.calls, tt.want)
+			}
+			if _, ok := h.sessions[tt.from]; ok {
+				t.Errorf("%s is still playing", tt.from)
+			}
+		})
+	}
+}
+
+func TestHandoffRegroupsSonos(t *testing.T) {
+	h := newHome()
+	h.sessions["Office"] = mix()
+
+	res, err := Handoff(context.Background(), h, h, "Office", "Kitchen", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Session != nil {
+		t.Errorf("Session = %+v, want none when regrouping", res.Session)
+	}
+	want := []string{"volume Kitchen 20", "join Kitchen Office", "leave Office"}
+	if !slices.Equal(h.calls, want) {
+		t.Errorf("calls = %q\nwant %q", h.calls, want)
+	}
+
+	// A room already in the group just stays
+	h = newHome()
+	h.coordinators["Kitchen"] = "Office"
+	if _, err := Handoff(context.Background(), h, h, "Office", "Kitchen", Options{}); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"volume Kitchen 20", "leave Office"}
+	if !slices.Equal(h.calls, want) {
+		t.Errorf("calls = %q\nwant %q", h.calls, want)
+	}
+}
+
+func TestHandoffFailureKeepsSource(t *testing.T) {
+	h := newHome()
+	h.sessions["Laptop"] = mix()
+	h.failResume = "Kitchen"
+
+	if _, err := Handoff(context.Background(), h, h, "Laptop", "Kitchen", Options{}); err == nil {
+		t.Fatal("Handoff() succeeded, want the resume failure")
+	}
+	if _, ok := h.sessions["Laptop"]; !ok || slices.Contains(h.calls, "pause Laptop") {
+		t.Errorf("Laptop was paused after the handoff failed: %q", h.calls)
+	}
+}
+
+func TestHandoffErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+	}{
+		{"nothing playing", "", "Kitchen"},
+		{"same device", "Kitchen", "Kitchen"},
+		{"unknown target", "Laptop", "Attic"},
+		{"no session", "Phone", "Kitchen"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHome()
+			if tt.from == "Laptop" {
+				h.sessions["Laptop"] = mix()
+			}
+			if _, err := Handoff(context.Background(), h, h, tt.from, tt.to, Options{}); err == nil {
+				t.Fatal("Handoff() succeeded, want an error")
+			}
+			if len(h.calls) != 0 {
+				t.Errorf("Handoff() changed things before failing: %q", h.calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+// Package logging builds riff's structured logger from the [log] config:
+// a level, a text or JSON handler, and stderr or a rotating file. Secrets
+// are redacted from every record.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Options configures New.
+type Options struct {
+	// Level is "debug", "info", "warn" or "error". Empty means info.
+	Level string
+	// Format is "text" or "json". Empty means text.
+	Format string
+	// File is the path to log to. Empty logs to Stderr.
+	File string
+	// MaxSize is how many megabytes File grows to before it is rotated,
+	// keeping MaxBackups old files. Zero disables rotation.
+	MaxSize    int
+	MaxBackups int
+	// Stderr receives records when File is empty. Defaults to os.Stderr.
+	Stderr io.Writer
+}
+
+// New returns a logger configured by opts, and a Closer for its file. The
+// Closer is a no-op when logging to Stderr.
+func New(opts Options) (*slog.Logger, io.Closer, error) {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var w io.Writer = opts.Stderr
+	if w == nil {
+		w = os.Stderr
+	}
+	var closer io.Closer = nopCloser{}
+	if opts.File != "" {
+		f, err := OpenRotatingFile(opts.File, int64(opts.MaxSize)<<20, opts.MaxBackups)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open log file: %w", err)
+		}
+		w, closer = f, f
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr}
+	var h slog.Handler
+	switch opts.Format {
+	case "", "text":
+		h = slog.NewTextHandler(w, handlerOpts)
+	case "json":
+		h = slog.NewJSONHandler(w, handlerOpts)
+	default:
+		_ = closer.Close()
+		return nil, nil, fmt.Errorf("invalid log format: %s (must be text or json)", opts.Format)
+	}
+	return slog.New(h), closer, nil
+}
+
+// ParseLevel converts a config level name to a slog.Level. Empty means
+// info.
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("invalid log level: %s (must be debug, info, warn, or error)", name)
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
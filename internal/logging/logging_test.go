@@ -0,0 +1,135 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Authorization: Bearer abc.def-123", "Authorization: Bearer [REDACTED]"},
+		{`{"access_token":"abc","expires_in":3600,"refresh_token": "xyz"}`, `{"access_token":"[REDACTED]","expires_in":3600,"refresh_token": "[REDACTED]"}`},
+		{"/v1/status?access_token=abc&device=Kitchen", "/v1/status?access_token=[REDACTED]&device=Kitchen"},
+		{"grant_type=authorization_code&code=abc&redirect_uri=x", "grant_type=authorization_code&code=[REDACTED]&redirect_uri=x"},
+		{"https://api.spotify.com/v1/me/player?device_id=abc", "https://api.spotify.com/v1/me/player?device_id=abc"},
+	}
+	for _, tt := range tests {
+		if got := Redact(tt.in); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRedactErrors(t *testing.T) {
+	var buf bytes.Buffer
+	logger, closer, err := New(Options{Level: "debug", Format: "json", Stderr: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+
+	reqErr := fmt.Errorf("request failed: %w", errors.New("401 for header Authorization: Bearer abc.def"))
+	logger.Error("spotify request failed", "error", reqErr,
+		slog.Group("request", "url", stringer("/v1/me?access_token=abc"), "status", 401))
+	out := buf.String()
+	if strings.Contains(out, "abc") {
+		t.Errorf("record = %q, want the token redacted", out)
+	}
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("record %q: %v", out, err)
+	}
+	if record["error"] != "request failed: 401 for header Authorization: Bearer [REDACTED]" {
+		t.Errorf("error = %v", record["error"])
+	}
+	if req, _ := record["request"].(map[string]any); req["url"] != "/v1/me?access_token=[REDACTED]" || req["status"] != 401.0 {
+		t.Errorf("request = %v", record["request"])
+	}
+}
+
+// stringer is a fmt.Stringer, logged as a KindAny value.
+type stringer string
+
+func (s stringer) String() string { return string(s) }
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, closer, err := New(Options{Level: "debug", Format: "json", Stderr: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+
+	logger.Debug("spotify request", "url", "/v1/me?access_token=abc", "token", "xyz",
+		slog.Group("args", "Authorization", "Bearer abc"))
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("record %q: %v", buf.String(), err)
+	}
+	if record["url"] != "/v1/me?access_token=[REDACTED]" || record["token"] != "[REDACTED]" {
+		t.Errorf("record = %v, want secrets redacted", record)
+	}
+	if args, _ := record["args"].(map[string]any); args["Authorization"] != "[REDACTED]" {
+		t.Errorf("args = %v, want secrets redacted in groups", record["args"])
+	}
+
+	buf.Reset()
+	logger, _, err = New(Options{Level: "warn", Stderr: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("hidden")
+	logger.Warn("shown")
+	if out := buf.String(); strings.Contains(out, "hidden") || !strings.Contains(out, "level=WARN msg=shown") {
+		t.Errorf("text output = %q", out)
+	}
+
+	if _, _, err := New(Options{Level: "loud"}); err == nil {
+		t.Error("New() accepted an invalid level")
+	}
+	if _, _, err := New(Options{Format: "xml"}); err == nil {
+		t.Error("New() accepted an invalid format")
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "riff.log")
+	f, err := OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != content {
+			t.Errorf("%s = %q, want %q", filepath.Base(name), got, content)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("kept more than 2 backups")
+	}
+}
@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// redacted replaces secret values.
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values are always redacted.
+var sensitiveKeys = map[string]bool{
+	"authorization": true,
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"client_secret": true,
+	"password":      true,
+	"passphrase":    true,
+	"secret":        true,
+}
+
+// secretPatterns find tokens inside longer strings: bearer credentials,
+// JSON fields, and query or form parameters.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(bearer\s+)[^\s"',]+`),
+	regexp.MustCompile(`(?i)("(?:access_token|refresh_token|client_secret|token|code_verifier)"\s*:\s*")[^"]*`),
+	regexp.MustCompile(`(?i)((?:^|[?&\s])(?:access_token|refresh_token|client_secret|token|code|code_verifier)=)[^&\s"]*`),
+}
+
+// Redact masks tokens and secrets in s, leaving the rest readable.
+func Redact(s string) string {
+	for _, re := range secretPatterns {
+		s = re.ReplaceAllString(s, "${1}"+redacted)
+	}
+	return s
+}
+
+// redactAttr is a slog.HandlerOptions.ReplaceAttr that hides secrets.
+// Strings are redacted in place; errors, Stringers and other values are
+// replaced by their redacted string form when it hides anything, and
+// groups are redacted member by member.
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, redacted)
+	}
+	a.Value = a.Value.Resolve()
+	switch a.Value.Kind() {
+	case slog.KindString:
+		if s := a.Value.String(); s != "" {
+			a.Value = slog.StringValue(Redact(s))
+		}
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		members := make([]slog.Attr, len(attrs))
+		for i, attr := range attrs {
+			members[i] = redactAttr(append(groups, a.Key), attr)
+		}
+		a.Value = slog.GroupValue(members...)
+	case slog.KindAny:
+		var s string
+		switch v := a.Value.Any().(type) {
+		case nil:
+			return a
+		case error:
+			s = v.Error()
+		case fmt.Stringer:
+			s = v.String()
+		default:
+			s = fmt.Sprint(v)
+		}
+		if r := Redact(s); r != s {
+			a.Value = slog.StringValue(r)
+		}
+	}
+	return a
+}
@@ -0,0 +1,188 @@
+// Package metrics defines riff's Prometheus metrics and serves them for
+// long-running modes such as tail, the TUI and the daemon.
+//
+// Instrumented packages update the collectors below directly. Nothing is
+// exported over HTTP unless Serve is called, so short commands pay only for
+// a few counter increments.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every riff metric plus the Go runtime and process
+// collectors.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// SpotifyRequests counts Spotify Web API attempts by method, endpoint
+	// and status code ("error" for network failures).
+	SpotifyRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "riff_spotify_requests_total",
+		Help: "Spotify Web API request attempts by method, endpoint and status.",
+	}, []string{"method", "endpoint", "status"})
+
+	// SpotifyRequestDuration observes the latency of each attempt.
+	SpotifyRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "riff_spotify_request_duration_seconds",
+		Help:    "Latency of Spotify Web API request attempts.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "endpoint"})
+
+	// SpotifyRetries counts retried Spotify requests by reason.
+	SpotifyRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "riff_spotify_retries_total",
+		Help: "Spotify Web API retries by reason (network, server_error, unauthorized).",
+	}, []string{"reason"})
+
+	// SpotifyRateLimited counts 429 Too Many Requests responses.
+	SpotifyRateLimited = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "riff_spotify_rate_limited_total",
+		Help: "Spotify Web API responses with status 429.",
+	})
+
+	// SOAPCallDuration observes UPnP SOAP call latency per device and action.
+	SOAPCallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "riff_soap_call_duration_seconds",
+		Help:    "Latency of UPnP SOAP calls by device and action.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"device", "action"})
+
+	// SOAPCallErrors counts failed SOAP calls per device and action.
+	SOAPCallErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "riff_soap_call_errors_total",
+		Help: "Failed UPnP SOAP calls by device and action.",
+	}, []string{"device", "action"})
+
+	// DiscoveryLookups counts device discovery lookups by platform and
+	// whether the cache answered them ("hit" or "miss").
+	DiscoveryLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "riff_discovery_lookups_total",
+		Help: "Device discovery lookups by platform and cache result.",
+	}, []string{"platform", "cache"})
+
+	// DiscoveryDuration observes SSDP searches that missed the cache.
+	DiscoveryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "riff_discovery_duration_seconds",
+		Help:    "Duration of SSDP discovery by platform.",
+		Buckets: []float64{0.1, 0.5, 1, 2, 3, 5, 10},
+	}, []string{"platform"})
+
+	// WatcherEvents counts playback events emitted by tail watchers.
+	WatcherEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "riff_watcher_events_total",
+		Help: "Playback events emitted by watchers, by type.",
+	}, []string{"type"})
+
+	// WatcherEventsDropped counts events dropped because a watcher's
+	// consumer fell behind.
+	WatcherEventsDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "riff_watcher_events_dropped_total",
+		Help: "Playback events dropped because the consumer fell behind, by type.",
+	}, []string{"type"})
+
+	// WebhookDeliveries counts webhook delivery outcomes ("ok", "retry" or
+	// "dead_letter").
+	WebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "riff_webhook_deliveries_total",
+		Help: "Webhook delivery attempts by result.",
+	}, []string{"result"})
+
+	// WatcherPollErrors counts failed state polls.
+	WatcherPollErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "riff_watcher_poll_errors_total",
+		Help: "Failed playback state polls by watchers.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		SpotifyRequests,
+		SpotifyRequestDuration,
+		SpotifyRetries,
+		SpotifyRateLimited,
+		SOAPCallDuration,
+		SOAPCallErrors,
+		DiscoveryLookups,
+		DiscoveryDuration,
+		WatcherEvents,
+		WatcherEventsDropped,
+		WatcherPollErrors,
+		WebhookDeliveries,
+	)
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Listen binds addr for Serve, so address errors surface before a
+// long-running mode starts.
+func Listen(addr string) (net.Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	return ln, nil
+}
+
+// Serve serves /metrics on ln until ctx is done.
+func Serve(ctx context.Context, ln net.Listener) error {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", Handler())
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	stop := context.AfterFunc(ctx, func() { _ = srv.Close() })
+	defer stop()
+
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Since returns the seconds elapsed since start, for histograms.
+func Since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
+
+// Endpoint reduces an API path to a low-cardinality label by dropping the
+// query string and replacing IDs with ":id", so "/albums/4aawyAB9vmqN3uQ7FjRGTy/tracks?limit=50"
+// becomes "/albums/:id/tracks".
+func Endpoint(path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if looksLikeID(s) {
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// looksLikeID reports whether s is a Spotify base-62 ID or a user ID with
+// digits, as opposed to a fixed path segment like "player".
+func looksLikeID(s string) bool {
+	if len(s) < 16 {
+		return false
+	}
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			return true
+		}
+	}
+	return len(s) == 22
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestEndpoint(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/me/player", "/me/player"},
+		{"/me/player/volume?volume_percent=50", "/me/player/volume"},
+		{"/albums/4aawyAB9vmqN3uQ7FjRGTy/tracks?limit=50", "/albums/:id/tracks"},
+		{"/users/smedjan2024abcdef/playlists", "/users/:id/playlists"},
+		{"/me/player/recently-played", "/me/player/recently-played"},
+	}
+	for _, tt := range tests {
+		if got := Endpoint(tt.path); got != tt.want {
+			t.Errorf("Endpoint(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestServe(t *testing.T) {
+	ln, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Serve(ctx, ln) }()
+
+	SpotifyRateLimited.Inc()
+	DiscoveryLookups.WithLabelValues("sonos", "hit").Inc()
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	for _, want := range []string{
+		"riff_spotify_rate_limited_total",
+		`riff_discovery_lookups_total{cache="hit",platform="sonos"}`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics output missing %s", want)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Serve() = %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package mpd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tessro/riff/internal/core"
+	rifferrors "github.com/tessro/riff/internal/errors"
+)
+
+// Host is a named MPD server.
+type Host struct {
+	Name   string
+	Client *Client
+}
+
+// Backend implements core.Backend for a set of configured MPD servers.
+type Backend struct {
+	hosts []Host
+}
+
+// Ensure Backend implements core.Backend
+var _ core.Backend = (*Backend)(nil)
+
+// NewBackend creates an MPD backend. The first host is the default target
+// when nothing is playing.
+func NewBackend(hosts ...Host) *Backend {
+	return &Backend{hosts: hosts}
+}
+
+// Platform returns core.PlatformMPD.
+func (b *Backend) Platform() core.Platform {
+	return core.PlatformMPD
+}
+
+// Devices lists the audio outputs of every reachable host. Enabled outputs
+// are reported as active. Unreachable hosts are skipped unless none answer.
+func (b *Backend) Devices(ctx context.Context) ([]core.Device, error) {
+	var devices []core.Device
+	var errs []error
+	for _, h := range b.hosts {
+		outputs, err := h.Client.Outputs(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", h.Name, err))
+			continue
+		}
+		for _, o := range outputs {
+			devices = append(devices, core.Device{
+				ID:       outputID(h.Name, o.ID),
+				Name:     fmt.Sprintf("%s (%s)", h.Name, o.Name),
+				Type:     core.DeviceTypeSpeaker,
+				Platform: core.PlatformMPD,
+				IsActive: o.Enabled,
+			})
+		}
+	}
+	if len(devices) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return devices, nil
+}
+
+// PlayerFor returns the player for the host owning device. MPD plays to all
+// enabled outputs at once, so any output of a host selects that host. A nil
+// device selects a playing host, then the first one.
+func (b *Backend) PlayerFor(ctx context.Context, device *core.Device) (core.Player, error) {
+	if len(b.hosts) == 0 {
+		return nil, errors.New("no mpd hosts configured")
+	}
+
+	if device != nil {
+		for _, h := range b.hosts {
+			id := hostID(h.Name)
+			if device.ID == id || strings.HasPrefix(device.ID, id+"/") {
+				return NewPlayer(h.Client, h.Name), nil
+			}
+		}
+		return nil, fmt.Errorf("device '%s': %w", device.Name, rifferrors.ErrDeviceNotFound)
+	}
+
+	for _, h := range b.hosts {
+		if status, err := h.Client.Status(ctx); err == nil && status.State == "play" {
+			return NewPlayer(h.Client, h.Name), nil
+		}
+	}
+	return NewPlayer(b.hosts[0].Client, b.hosts[0].Name), nil
+}
+
+// Search is not supported; MPD plays files and streams by path or URL.
+func (b *Backend) Search(ctx context.Context, query string, kinds []core.SearchKind, limit int) ([]core.SearchResult, error) {
+	return nil, fmt.Errorf("mpd search: %w", errors.ErrUnsupported)
+}
+
+// hostID is the device ID prefix for a host.
+func hostID(name string) string {
+	return "mpd:" + name
+}
+
+// outputID is the device ID of one of a host's outputs.
+func outputID(host string, id int) string {
+	return hostID(host) + "/" + strconv.Itoa(id)
+}
@@ -0,0 +1,305 @@
+// Package mpd implements a Music Player Daemon protocol client and a
+// core.Player on top of it.
+//
+// The client keeps one command connection per server, reconnecting when MPD
+// drops it after its idle timeout. Change notification uses the protocol's
+// idle command on a dedicated connection, so a watcher never blocks
+// commands.
+package mpd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds each command when no timeout is configured.
+const DefaultTimeout = 5 * time.Second
+
+// Error is an ACK response from the server.
+type Error struct {
+	Code    int    // MPD error code, e.g. 50 for "no such file"
+	Index   int    // Index of the failing command in a command list
+	Command string // Command that failed
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Command == "" {
+		return "mpd: " + e.Message
+	}
+	return fmt.Sprintf("mpd: %s: %s", e.Command, e.Message)
+}
+
+// pair is a single "key: value" response line.
+type pair struct {
+	key, value string
+}
+
+// Client talks to a single MPD server.
+type Client struct {
+	addr     string
+	password string
+	timeout  time.Duration
+
+	mu   sync.Mutex
+	conn *conn
+}
+
+// NewClient creates a client for the server at addr, which is either a
+// host:port or a Unix socket path. An empty password skips authentication;
+// a zero timeout uses DefaultTimeout.
+func NewClient(addr, password string, timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Client{
+		addr:     addr,
+		password: password,
+		timeout:  timeout,
+	}
+}
+
+// Addr returns the server address.
+func (c *Client) Addr() string {
+	return c.addr
+}
+
+// Close closes the command connection, if open.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// command runs a command on the shared connection. MPD closes clients that
+// stay idle too long, so a connection the server has closed is redialled
+// before use, and a command whose write fails on a reused connection is
+// sent once more on a fresh one. Once the command may have reached MPD it
+// is never resent, since commands like add and next are not idempotent.
+func (c *Client) command(ctx context.Context, name string, args ...string) ([]pair, error) {
+	line := formatCommand(name, args...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn != nil && c.conn.closedByServer() {
+		_ = c.conn.Close()
+		c.conn = nil
+	}
+
+	for attempt := 0; ; attempt++ {
+		reused := c.conn != nil
+		if !reused {
+			cn, err := c.dial(ctx)
+			if err != nil {
+				return nil, err
+			}
+			c.conn = cn
+		}
+
+		pairs, err := c.conn.exec(ctx, line, c.timeout)
+		if err == nil {
+			return pairs, nil
+		}
+
+		var ackErr *Error
+		if errors.As(err, &ackErr) {
+			return nil, err
+		}
+		_ = c.conn.Close()
+		c.conn = nil
+
+		var writeErr *writeError
+		if !errors.As(err, &writeErr) || !reused || attempt > 0 || ctx.Err() != nil {
+			return nil, fmt.Errorf("mpd %s: %w", name, err)
+		}
+	}
+}
+
+// Idle blocks until one of the given subsystems (all of them when none are
+// given) changes, and returns the names of those that did. It uses its own
+// connection and returns ctx.Err() when ctx is cancelled.
+func (c *Client) Idle(ctx context.Context, subsystems ...string) ([]string, error) {
+	cn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cn.Close()
+
+	// Unblock the read when the caller gives up
+	stop := context.AfterFunc(ctx, func() { _ = cn.Close() })
+	defer stop()
+
+	pairs, err := cn.exec(context.Background(), formatCommand("idle", subsystems...), 0)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("mpd idle: %w", err)
+	}
+
+	var changed []string
+	for _, p := range pairs {
+		if p.key == "changed" {
+			changed = append(changed, p.value)
+		}
+	}
+	return changed, nil
+}
+
+// dial opens and authenticates a new connection.
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	network := "tcp"
+	if strings.HasPrefix(c.addr, "/") || strings.HasPrefix(c.addr, "@") {
+		network = "unix"
+	}
+
+	d := net.Dialer{Timeout: c.timeout}
+	nc, err := d.DialContext(ctx, network, c.addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to mpd at %s: %w", c.addr, err)
+	}
+
+	cn := &conn{nc: nc, r: bufio.NewReader(nc)}
+	_ = nc.SetReadDeadline(time.Now().Add(c.timeout))
+	greeting, err := cn.r.ReadString('\n')
+	if err != nil {
+		_ = nc.Close()
+		return nil, fmt.Errorf("failed to read mpd greeting: %w", err)
+	}
+	version, ok := strings.CutPrefix(strings.TrimRight(greeting, "\n"), "OK MPD ")
+	if !ok {
+		_ = nc.Close()
+		return nil, fmt.Errorf("unexpected mpd greeting: %q", greeting)
+	}
+	cn.version = version
+
+	if c.password != "" {
+		if _, err := cn.exec(ctx, formatCommand("password", c.password), c.timeout); err != nil {
+			_ = nc.Close()
+			return nil, fmt.Errorf("mpd authentication failed: %w", err)
+		}
+	}
+	return cn, nil
+}
+
+// conn is a single protocol connection.
+type conn struct {
+	nc      net.Conn
+	r       *bufio.Reader
+	version string
+}
+
+func (cn *conn) Close() error {
+	return cn.nc.Close()
+}
+
+// closedByServer reports whether the server has closed the connection
+// while it sat unused. MPD sends nothing between commands, so anything
+// other than a timeout on a short read means the connection is gone.
+func (cn *conn) closedByServer() bool {
+	if cn.r.Buffered() > 0 {
+		return true
+	}
+	_ = cn.nc.SetReadDeadline(time.Now().Add(time.Millisecond))
+	_, err := cn.r.Peek(1)
+	var netErr net.Error
+	return !errors.As(err, &netErr) || !netErr.Timeout()
+}
+
+// writeError is a failure to send a command, which therefore never reached
+// the server.
+type writeError struct {
+	err error
+}
+
+func (e *writeError) Error() string { return e.err.Error() }
+func (e *writeError) Unwrap() error { return e.err }
+
+// exec sends one command line and reads its response. A zero timeout waits
+// indefinitely, unless ctx has a deadline.
+func (cn *conn) exec(ctx context.Context, line string, timeout time.Duration) ([]pair, error) {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	if d, ok := ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+	if err := cn.nc.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if _, err := cn.nc.Write([]byte(line + "\n")); err != nil {
+		return nil, &writeError{err}
+	}
+
+	var pairs []pair
+	for {
+		text, err := cn.r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		text = strings.TrimRight(text, "\n")
+
+		switch {
+		case text == "OK":
+			return pairs, nil
+		case strings.HasPrefix(text, "ACK "):
+			return nil, parseAck(text)
+		}
+
+		key, value, ok := strings.Cut(text, ": ")
+		if !ok {
+			return nil, fmt.Errorf("malformed response line: %q", text)
+		}
+		pairs = append(pairs, pair{key: key, value: value})
+	}
+}
+
+// parseAck parses "ACK [code@index] {command} message".
+func parseAck(line string) *Error {
+	e := &Error{Message: strings.TrimPrefix(line, "ACK ")}
+
+	rest, ok := strings.CutPrefix(e.Message, "[")
+	if !ok {
+		return e
+	}
+	codes, rest, ok := strings.Cut(rest, "] ")
+	if !ok {
+		return e
+	}
+	code, index, _ := strings.Cut(codes, "@")
+	e.Code, _ = strconv.Atoi(code)
+	e.Index, _ = strconv.Atoi(index)
+
+	if rest, ok = strings.CutPrefix(rest, "{"); ok {
+		e.Command, rest, _ = strings.Cut(rest, "} ")
+	}
+	e.Message = rest
+	return e
+}
+
+// formatCommand builds a command line, quoting every argument.
+func formatCommand(name string, args ...string) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, arg := range args {
+		b.WriteString(` "`)
+		b.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg))
+		b.WriteString(`"`)
+	}
+	return b.String()
+}
@@ -0,0 +1,232 @@
+package mpd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tessro/riff/internal/mpd/mpdtest"
+)
+
+func TestParseAck(t *testing.T) {
+	e := parseAck(`ACK [50@1] {add} No such directory`)
+	if e.Code != 50 || e.Index != 1 || e.Command != "add" || e.Message != "No such directory" {
+		t.Errorf("parseAck() = %+v", e)
+	}
+}
+
+func TestFormatCommand(t *testing.T) {
+	got := formatCommand("add", `Artist/Say "Hi" \ Bye.flac`)
+	want := `add "Artist/Say \"Hi\" \\ Bye.flac"`
+	if got != want {
+		t.Errorf("formatCommand() = %s, want %s", got, want)
+	}
+}
+
+func TestClientCommands(t *testing.T) {
+	srv := mpdtest.NewServer()
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := NewClient(srv.Addr(), "", 0)
+	defer c.Close()
+
+	for _, song := range mpdtest.Library[:3] {
+		if err := c.Add(ctx, song.File); err != nil {
+			t.Fatalf("Add(%q) error = %v", song.File, err)
+		}
+	}
+
+	err := c.Add(ctx, "missing.flac")
+	var ackErr *Error
+	if !errors.As(err, &ackErr) || ackErr.Code != 50 {
+		t.Errorf("Add(missing) error = %v, want ACK 50", err)
+	}
+
+	if err := c.Play(ctx); err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+	if err := c.SeekCur(ctx, 90*time.Second); err != nil {
+		t.Fatalf("SeekCur() error = %v", err)
+	}
+
+	status, err := c.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status.State != "play" || status.Song != 0 || status.Elapsed != 90*time.Second || status.PlaylistLength != 3 {
+		t.Errorf("Status() = %+v", status)
+	}
+	if status.Duration != 201500*time.Millisecond {
+		t.Errorf("Duration = %v, want 3m21.5s", status.Duration)
+	}
+
+	song, err := c.CurrentSong(ctx)
+	if err != nil {
+		t.Fatalf("CurrentSong() error = %v", err)
+	}
+	if song == nil || song.Title != "Neon" || song.Artist != "The Testers" {
+		t.Errorf("CurrentSong() = %+v, want Neon", song)
+	}
+
+	if err := c.Move(ctx, 2, 0); err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	if err := c.Delete(ctx, 1); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	songs, err := c.PlaylistInfo(ctx)
+	if err != nil {
+		t.Fatalf("PlaylistInfo() error = %v", err)
+	}
+	var titles []string
+	for _, s := range songs {
+		titles = append(titles, s.Title)
+	}
+	if !slices.Equal(titles, []string{"Last Exit", "Overpass"}) {
+		t.Errorf("queue = %v, want [Last Exit Overpass]", titles)
+	}
+
+	outputs, err := c.Outputs(ctx)
+	if err != nil {
+		t.Fatalf("Outputs() error = %v", err)
+	}
+	if len(outputs) != 2 || outputs[0].Name != "Speakers" || !outputs[0].Enabled || outputs[1].Enabled {
+		t.Errorf("Outputs() = %+v", outputs)
+	}
+}
+
+func TestClientReconnects(t *testing.T) {
+	srv := mpdtest.NewServer()
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := NewClient(srv.Addr(), "", 0)
+	defer c.Close()
+
+	if err := c.SetVol(ctx, 20); err != nil {
+		t.Fatalf("SetVol() error = %v", err)
+	}
+
+	// MPD drops clients that stay idle past its connection timeout
+	srv.DropConnections()
+
+	if err := c.SetVol(ctx, 30); err != nil {
+		t.Fatalf("SetVol() after drop error = %v", err)
+	}
+	if got := srv.Status().Volume; got != 30 {
+		t.Errorf("volume = %d, want 30", got)
+	}
+}
+
+func TestClientPassword(t *testing.T) {
+	srv := mpdtest.NewServer()
+	defer srv.Close()
+	srv.SetPassword("hunter2")
+
+	ctx := context.Background()
+
+	if _, err := NewClient(srv.Addr(), "", 0).Status(ctx); err == nil {
+		t.Error("Status() without password succeeded")
+	}
+	if _, err := NewClient(srv.Addr(), "wrong", 0).Status(ctx); err == nil {
+		t.Error("Status() with wrong password succeeded")
+	}
+
+	c := NewClient(srv.Addr(), "hunter2", 0)
+	defer c.Close()
+	if _, err := c.Status(ctx); err != nil {
+		t.Errorf("Status() with password error = %v", err)
+	}
+}
+
+func TestIdle(t *testing.T) {
+	srv := mpdtest.NewServer()
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c := NewClient(srv.Addr(), "", 0)
+	defer c.Close()
+
+	changed := make(chan []string, 1)
+	go func() {
+		subs, err := c.Idle(ctx, "mixer")
+		if err != nil {
+			t.Errorf("Idle() error = %v", err)
+		}
+		changed <- subs
+	}()
+
+	// Wait for the idle connection before changing anything
+	for !slices.Contains(srv.Commands(), "idle") {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// A change outside the subscription must not wake the watcher
+	if err := c.Random(ctx, true); err != nil {
+		t.Fatalf("Random() error = %v", err)
+	}
+	if err := c.SetVol(ctx, 70); err != nil {
+		t.Fatalf("SetVol() error = %v", err)
+	}
+
+	if subs := <-changed; !slices.Equal(subs, []string{"mixer"}) {
+		t.Errorf("Idle() = %v, want [mixer]", subs)
+	}
+
+	// Cancelling the context unblocks Idle
+	idleCtx, idleCancel := context.WithCancel(ctx)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		idleCancel()
+	}()
+	if _, err := c.Idle(idleCtx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Idle() after cancel error = %v, want context.Canceled", err)
+	}
+}
+
+func TestClientDoesNotResend(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// A server that takes the command and then drops the connection
+	// without replying, as when it dies mid-command
+	received := make(chan string, 4)
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(nc, "OK MPD %s\n", mpdtest.Version)
+			line, _ := bufio.NewReader(nc).ReadString('\n')
+			received <- strings.TrimSpace(line)
+			_ = nc.Close()
+		}
+	}()
+
+	c := NewClient(ln.Addr().String(), "", 0)
+	defer c.Close()
+	if err := c.Next(context.Background()); err == nil {
+		t.Fatal("Next() error = nil, want the dropped connection")
+	}
+	if got := <-received; got != "next" {
+		t.Errorf("command = %q, want next", got)
+	}
+	select {
+	case line := <-received:
+		t.Errorf("command resent: %q", line)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
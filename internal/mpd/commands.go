@@ -0,0 +1,295 @@
+package mpd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Status is the response to the status command.
+type Status struct {
+	State          string // play, pause or stop
+	Volume         int    // -1 when the server has no mixer
+	Repeat         bool
+	Random         bool
+	Single         bool
+	Song           int // Queue position of the current song, -1 if none
+	SongID         int
+	Elapsed        time.Duration
+	Duration       time.Duration
+	PlaylistLength int
+}
+
+// Song is a queue entry or the current song.
+type Song struct {
+	File     string
+	Title    string
+	Artist   string
+	Album    string
+	Duration time.Duration
+	Pos      int
+	ID       int
+}
+
+// Output is an audio output configured on the server.
+type Output struct {
+	ID      int
+	Name    string
+	Plugin  string
+	Enabled bool
+}
+
+// Status returns the player status.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	pairs, err := c.command(ctx, "status")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Status{Volume: -1, Song: -1, SongID: -1}
+	for _, p := range pairs {
+		switch p.key {
+		case "state":
+			s.State = p.value
+		case "volume":
+			s.Volume = atoi(p.value, -1)
+		case "repeat":
+			s.Repeat = p.value == "1"
+		case "random":
+			s.Random = p.value == "1"
+		case "single":
+			s.Single = p.value == "1"
+		case "song":
+			s.Song = atoi(p.value, -1)
+		case "songid":
+			s.SongID = atoi(p.value, -1)
+		case "elapsed":
+			s.Elapsed = parseSeconds(p.value)
+		case "duration":
+			s.Duration = parseSeconds(p.value)
+		case "playlistlength":
+			s.PlaylistLength = atoi(p.value, 0)
+		}
+	}
+	return s, nil
+}
+
+// CurrentSong returns the current song, or nil when there is none.
+func (c *Client) CurrentSong(ctx context.Context) (*Song, error) {
+	pairs, err := c.command(ctx, "currentsong")
+	if err != nil {
+		return nil, err
+	}
+	songs := parseSongs(pairs)
+	if len(songs) == 0 {
+		return nil, nil
+	}
+	return &songs[0], nil
+}
+
+// PlaylistInfo returns every song in the queue.
+func (c *Client) PlaylistInfo(ctx context.Context) ([]Song, error) {
+	pairs, err := c.command(ctx, "playlistinfo")
+	if err != nil {
+		return nil, err
+	}
+	return parseSongs(pairs), nil
+}
+
+// Outputs lists the server's audio outputs.
+func (c *Client) Outputs(ctx context.Context) ([]Output, error) {
+	pairs, err := c.command(ctx, "outputs")
+	if err != nil {
+		return nil, err
+	}
+
+	var outputs []Output
+	for _, p := range pairs {
+		if p.key == "outputid" {
+			outputs = append(outputs, Output{ID: atoi(p.value, 0)})
+			continue
+		}
+		if len(outputs) == 0 {
+			continue
+		}
+		o := &outputs[len(outputs)-1]
+		switch p.key {
+		case "outputname":
+			o.Name = p.value
+		case "plugin":
+			o.Plugin = p.value
+		case "outputenabled":
+			o.Enabled = p.value == "1"
+		}
+	}
+	return outputs, nil
+}
+
+// Play starts playback, resuming from pause.
+func (c *Client) Play(ctx context.Context) error {
+	_, err := c.command(ctx, "play")
+	return err
+}
+
+// Pause pauses playback.
+func (c *Client) Pause(ctx context.Context) error {
+	_, err := c.command(ctx, "pause", "1")
+	return err
+}
+
+// Next skips to the next song in the queue.
+func (c *Client) Next(ctx context.Context) error {
+	_, err := c.command(ctx, "next")
+	return err
+}
+
+// Previous goes back to the previous song in the queue.
+func (c *Client) Previous(ctx context.Context) error {
+	_, err := c.command(ctx, "previous")
+	return err
+}
+
+// SeekCur seeks within the current song.
+func (c *Client) SeekCur(ctx context.Context, position time.Duration) error {
+	_, err := c.command(ctx, "seekcur", strconv.FormatFloat(position.Seconds(), 'f', 3, 64))
+	return err
+}
+
+// SetVol sets the volume (0-100).
+func (c *Client) SetVol(ctx context.Context, volume int) error {
+	if volume < 0 {
+		volume = 0
+	}
+	if volume > 100 {
+		volume = 100
+	}
+	_, err := c.command(ctx, "setvol", strconv.Itoa(volume))
+	return err
+}
+
+// Add appends a file, directory or stream URL to the queue.
+func (c *Client) Add(ctx context.Context, uri string) error {
+	_, err := c.command(ctx, "add", uri)
+	return err
+}
+
+// AddID appends a file or stream URL to the queue and returns its song ID.
+func (c *Client) AddID(ctx context.Context, uri string) (int, error) {
+	pairs, err := c.command(ctx, "addid", uri)
+	if err != nil {
+		return 0, err
+	}
+	for _, p := range pairs {
+		if p.key == "Id" {
+			return atoi(p.value, 0), nil
+		}
+	}
+	return 0, fmt.Errorf("mpd addid: no Id in response")
+}
+
+// PlayID starts playing the song with the given ID.
+func (c *Client) PlayID(ctx context.Context, id int) error {
+	_, err := c.command(ctx, "playid", strconv.Itoa(id))
+	return err
+}
+
+// Delete removes the song at a 0-based queue position.
+func (c *Client) Delete(ctx context.Context, pos int) error {
+	_, err := c.command(ctx, "delete", strconv.Itoa(pos))
+	return err
+}
+
+// Move moves the song at 0-based position from to position to.
+func (c *Client) Move(ctx context.Context, from, to int) error {
+	_, err := c.command(ctx, "move", strconv.Itoa(from), strconv.Itoa(to))
+	return err
+}
+
+// Clear empties the queue.
+func (c *Client) Clear(ctx context.Context) error {
+	_, err := c.command(ctx, "clear")
+	return err
+}
+
+// Random turns random playback on or off.
+func (c *Client) Random(ctx context.Context, on bool) error {
+	_, err := c.command(ctx, "random", boolArg(on))
+	return err
+}
+
+// Repeat turns repeat on or off.
+func (c *Client) Repeat(ctx context.Context, on bool) error {
+	_, err := c.command(ctx, "repeat", boolArg(on))
+	return err
+}
+
+// Single turns single mode on or off. With repeat on, single repeats the
+// current song.
+func (c *Client) Single(ctx context.Context, on bool) error {
+	_, err := c.command(ctx, "single", boolArg(on))
+	return err
+}
+
+// parseSongs splits a song listing into songs; each starts with a "file" key.
+func parseSongs(pairs []pair) []Song {
+	var songs []Song
+	for _, p := range pairs {
+		if p.key == "file" {
+			songs = append(songs, Song{File: p.value, Pos: -1, ID: -1})
+			continue
+		}
+		if len(songs) == 0 {
+			continue
+		}
+		s := &songs[len(songs)-1]
+		switch p.key {
+		case "Title":
+			s.Title = p.value
+		case "Artist":
+			// Multi-artist tracks repeat the tag
+			if s.Artist != "" {
+				s.Artist += ", " + p.value
+			} else {
+				s.Artist = p.value
+			}
+		case "Album":
+			s.Album = p.value
+		case "duration":
+			s.Duration = parseSeconds(p.value)
+		case "Time":
+			if s.Duration == 0 {
+				s.Duration = parseSeconds(p.value)
+			}
+		case "Pos":
+			s.Pos = atoi(p.value, -1)
+		case "Id":
+			s.ID = atoi(p.value, -1)
+		}
+	}
+	return songs
+}
+
+// parseSeconds parses a fractional seconds value such as "12.345".
+func parseSeconds(s string) time.Duration {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(f * float64(time.Second))
+}
+
+func atoi(s string, fallback int) int {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return fallback
+	}
+	return i
+}
+
+func boolArg(on bool) string {
+	if on {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,271 @@
+package mpdtest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// exec runs one command against the model. It returns the response body, or
+// an ACK as "code message".
+func (s *Server) exec(c *client, name string, args []string) (string, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.commands = append(s.commands, name)
+
+	if name == "password" {
+		if len(args) != 1 || args[0] != s.password {
+			return "", "3 incorrect password"
+		}
+		c.authed = true
+		return "", ""
+	}
+	if !c.authed && name != "ping" {
+		return "", fmt.Sprintf("4 you don't have permission for %q", name)
+	}
+
+	var b strings.Builder
+	switch name {
+	case "ping":
+
+	case "status":
+		st := s.status
+		fmt.Fprintf(&b, "volume: %d\n", st.Volume)
+		fmt.Fprintf(&b, "repeat: %s\n", flag(st.Repeat))
+		fmt.Fprintf(&b, "random: %s\n", flag(st.Random))
+		fmt.Fprintf(&b, "single: %s\n", flag(st.Single))
+		fmt.Fprintf(&b, "playlistlength: %d\n", len(s.queue))
+		fmt.Fprintf(&b, "state: %s\n", st.State)
+		if st.Current >= 0 {
+			cur := s.queue[st.Current]
+			fmt.Fprintf(&b, "song: %d\nsongid: %d\n", st.Current, cur.id)
+			fmt.Fprintf(&b, "elapsed: %s\nduration: %s\n", formatSeconds(st.Elapsed), formatSeconds(cur.Duration))
+		}
+
+	case "currentsong":
+		if s.status.Current >= 0 {
+			writeSong(&b, s.queue[s.status.Current], s.status.Current)
+		}
+
+	case "playlistinfo":
+		for i, q := range s.queue {
+			writeSong(&b, q, i)
+		}
+
+	case "outputs":
+		for _, o := range s.outputs {
+			fmt.Fprintf(&b, "outputid: %d\noutputname: %s\nplugin: %s\noutputenabled: %s\n", o.ID, o.Name, o.Plugin, flag(o.Enabled))
+		}
+
+	case "play":
+		if len(s.queue) == 0 {
+			break
+		}
+		if len(args) > 0 {
+			pos, err := strconv.Atoi(args[0])
+			if err != nil || pos < 0 || pos >= len(s.queue) {
+				return "", "2 Bad song index"
+			}
+			s.status.Current, s.status.Elapsed = pos, 0
+		}
+		if s.status.Current < 0 {
+			s.status.Current = 0
+		}
+		s.status.State = "play"
+		s.notifyLocked("player")
+
+	case "pause":
+		if s.status.State == "stop" {
+			break
+		}
+		pause := s.status.State == "play"
+		if len(args) > 0 {
+			pause = args[0] == "1"
+		}
+		if pause {
+			s.status.State = "pause"
+		} else {
+			s.status.State = "play"
+		}
+		s.notifyLocked("player")
+
+	case "stop":
+		s.status.State, s.status.Elapsed = "stop", 0
+		s.notifyLocked("player")
+
+	case "next":
+		if s.status.Current < 0 {
+			break
+		}
+		switch {
+		case s.status.Current+1 < len(s.queue):
+			s.status.Current++
+		case s.status.Repeat:
+			s.status.Current = 0
+		default:
+			s.status.Current, s.status.State = -1, "stop"
+		}
+		s.status.Elapsed = 0
+		s.notifyLocked("player")
+
+	case "previous":
+		if s.status.Current > 0 {
+			s.status.Current--
+		}
+		s.status.Elapsed = 0
+		s.notifyLocked("player")
+
+	case "seekcur":
+		if s.status.Current < 0 {
+			return "", "55 Not playing"
+		}
+		if len(args) != 1 {
+			return "", "2 wrong number of arguments"
+		}
+		secs, err := strconv.ParseFloat(strings.TrimLeft(args[0], "+"), 64)
+		if err != nil {
+			return "", "2 Number expected"
+		}
+		s.status.Elapsed = secs
+		s.notifyLocked("player")
+
+	case "setvol":
+		vol, err := intArg(args)
+		if err != nil || vol < 0 || vol > 100 {
+			return "", "2 Invalid volume value"
+		}
+		s.status.Volume = vol
+		s.notifyLocked("mixer")
+
+	case "add":
+		if len(args) != 1 {
+			return "", "2 wrong number of arguments"
+		}
+		song, ok := lookup(args[0])
+		if !ok {
+			return "", "50 No such directory"
+		}
+		s.queue = append(s.queue, queued{Song: song, id: s.nextID})
+		s.nextID++
+		s.notifyLocked("playlist")
+
+	case "addid":
+		if len(args) != 1 {
+			return "", "2 wrong number of arguments"
+		}
+		song, ok := lookup(args[0])
+		if !ok {
+			return "", "50 No such directory"
+		}
+		s.queue = append(s.queue, queued{Song: song, id: s.nextID})
+		fmt.Fprintf(&b, "Id: %d\n", s.nextID)
+		s.nextID++
+		s.notifyLocked("playlist")
+
+	case "playid":
+		id, err := intArg(args)
+		if err != nil {
+			return "", "2 Integer expected"
+		}
+		pos := -1
+		for i, q := range s.queue {
+			if q.id == id {
+				pos = i
+			}
+		}
+		if pos < 0 {
+			return "", "50 No such song"
+		}
+		s.status.Current, s.status.State, s.status.Elapsed = pos, "play", 0
+		s.notifyLocked("player")
+
+	case "delete":
+		pos, err := intArg(args)
+		if err != nil || pos < 0 || pos >= len(s.queue) {
+			return "", "2 Bad song index"
+		}
+		s.queue = append(s.queue[:pos], s.queue[pos+1:]...)
+		switch {
+		case pos == s.status.Current:
+			if pos >= len(s.queue) {
+				s.status.Current, s.status.State = -1, "stop"
+			}
+			s.status.Elapsed = 0
+		case pos < s.status.Current:
+			s.status.Current--
+		}
+		s.notifyLocked("playlist")
+
+	case "move":
+		if len(args) != 2 {
+			return "", "2 wrong number of arguments"
+		}
+		from, err1 := strconv.Atoi(args[0])
+		to, err2 := strconv.Atoi(args[1])
+		if err1 != nil || err2 != nil || from < 0 || from >= len(s.queue) || to < 0 || to >= len(s.queue) {
+			return "", "2 Bad song index"
+		}
+		q := s.queue[from]
+		s.queue = append(s.queue[:from], s.queue[from+1:]...)
+		s.queue = append(s.queue[:to], append([]queued{q}, s.queue[to:]...)...)
+		switch cur := s.status.Current; {
+		case cur == from:
+			s.status.Current = to
+		case from < cur && to >= cur:
+			s.status.Current--
+		case from > cur && to <= cur:
+			s.status.Current++
+		}
+		s.notifyLocked("playlist")
+
+	case "clear":
+		s.queue = nil
+		s.status.Current, s.status.State, s.status.Elapsed = -1, "stop", 0
+		s.notifyLocked("playlist", "player")
+
+	case "random", "repeat", "single":
+		on, err := intArg(args)
+		if err != nil || (on != 0 && on != 1) {
+			return "", "2 Boolean (0/1) expected"
+		}
+		switch name {
+		case "random":
+			s.status.Random = on == 1
+		case "repeat":
+			s.status.Repeat = on == 1
+		case "single":
+			s.status.Single = on == 1
+		}
+		s.notifyLocked("options")
+
+	default:
+		return "", fmt.Sprintf("5 unknown command %q", name)
+	}
+	return b.String(), ""
+}
+
+func writeSong(b *strings.Builder, q queued, pos int) {
+	fmt.Fprintf(b, "file: %s\n", q.File)
+	if q.Title != "" {
+		fmt.Fprintf(b, "Title: %s\nArtist: %s\nAlbum: %s\n", q.Title, q.Artist, q.Album)
+	}
+	if q.Duration > 0 {
+		fmt.Fprintf(b, "Time: %d\nduration: %s\n", int(q.Duration), formatSeconds(q.Duration))
+	}
+	fmt.Fprintf(b, "Pos: %d\nId: %d\n", pos, q.id)
+}
+
+func intArg(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("wrong number of arguments")
+	}
+	return strconv.Atoi(args[0])
+}
+
+func flag(on bool) string {
+	if on {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,414 @@
+// Package mpdtest provides a fake MPD server for tests.
+//
+// The fake speaks the MPD text protocol over TCP and keeps a small model of
+// the queue, playback, mixer and outputs. Commands mutate that model and
+// wake idle clients the way MPD does, so a test can drive a real client and
+// then assert on the server state:
+//
+//	srv := mpdtest.NewServer()
+//	defer srv.Close()
+//
+//	c := mpd.NewClient(srv.Addr(), "", 0)
+//	_ = c.Add(ctx, mpdtest.Library[0].File)
+//	queue := srv.Queue()
+package mpdtest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Version is the protocol version announced in the greeting.
+const Version = "0.23.5"
+
+// Song is a file in the fake music library.
+type Song struct {
+	File     string
+	Title    string
+	Artist   string
+	Album    string
+	Duration float64 // Seconds
+}
+
+// Library is the set of files the server knows about.
+var Library = []Song{
+	{File: "Night Drive/01 Neon.flac", Title: "Neon", Artist: "The Testers", Album: "Night Drive", Duration: 201.5},
+	{File: "Night Drive/02 Overpass.flac", Title: "Overpass", Artist: "The Testers", Album: "Night Drive", Duration: 187},
+	{File: "Night Drive/03 Last Exit.flac", Title: "Last Exit", Artist: "The Testers", Album: "Night Drive", Duration: 243.25},
+	{File: "Morning Songs/01 Coffee.mp3", Title: "Coffee", Artist: "Fixture", Album: "Morning Songs", Duration: 165},
+}
+
+// Output is an audio output on the fake server.
+type Output struct {
+	ID      int
+	Name    string
+	Plugin  string
+	Enabled bool
+}
+
+// Status is a snapshot of the server's playback model.
+type Status struct {
+	State   string // play, pause or stop
+	Volume  int
+	Repeat  bool
+	Random  bool
+	Single  bool
+	Current int // Queue position of the current song, -1 if none
+	Elapsed float64
+}
+
+// queued is a queue entry.
+type queued struct {
+	Song
+	id int
+}
+
+// Server is a fake MPD server.
+type Server struct {
+	ln net.Listener
+	wg sync.WaitGroup
+
+	mu       sync.Mutex
+	password string
+	queue    []queued
+	nextID   int
+	status   Status
+	outputs  []Output
+	conns    map[*client]bool
+	commands []string
+	closed   bool
+}
+
+// NewServer starts a fake MPD server on a loopback port with an empty
+// queue and two outputs, only the first enabled. Call Close when done.
+func NewServer() *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("mpdtest: failed to listen: %v", err))
+	}
+
+	s := &Server{
+		ln:     ln,
+		nextID: 1,
+		status: Status{State: "stop", Volume: 50, Current: -1},
+		outputs: []Output{
+			{ID: 0, Name: "Speakers", Plugin: "alsa", Enabled: true},
+			{ID: 1, Name: "HDMI", Plugin: "pulse", Enabled: false},
+		},
+		conns: make(map[*client]bool),
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// Addr returns the host:port the server listens on.
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Close stops the server and drops every connection.
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	for c := range s.conns {
+		_ = c.conn.Close()
+	}
+	s.mu.Unlock()
+
+	_ = s.ln.Close()
+	s.wg.Wait()
+}
+
+// SetPassword requires clients to authenticate with password. An empty
+// password disables authentication.
+func (s *Server) SetPassword(password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.password = password
+}
+
+// DropConnections closes every client connection, as MPD does when a client
+// exceeds its connection timeout.
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		_ = c.conn.Close()
+	}
+}
+
+// Status returns a snapshot of the playback model.
+func (s *Server) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Queue returns the files in the queue, in order.
+func (s *Server) Queue() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files := make([]string, len(s.queue))
+	for i, q := range s.queue {
+		files[i] = q.File
+	}
+	return files
+}
+
+// Commands returns the command names received so far, in order.
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.commands)
+}
+
+// Enqueue appends library files to the queue without going through a client.
+func (s *Server) Enqueue(files ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f := range files {
+		if song, ok := lookup(f); ok {
+			s.queue = append(s.queue, queued{Song: song, id: s.nextID})
+			s.nextID++
+		}
+	}
+	s.notifyLocked("playlist")
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		nc, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		c := &client{
+			conn:    nc,
+			pending: make(map[string]bool),
+			wake:    make(chan struct{}, 1),
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = nc.Close()
+			return
+		}
+		c.authed = s.password == ""
+		s.conns[c] = true
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(c)
+		}()
+	}
+}
+
+// client is one connection's protocol state.
+type client struct {
+	conn    net.Conn
+	authed  bool
+	pending map[string]bool // Subsystems changed since the last idle
+	wake    chan struct{}
+}
+
+func (s *Server) handle(c *client) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		_ = c.conn.Close()
+	}()
+
+	// Read lines on their own goroutine so idle can wait for either a
+	// change or the client's noidle.
+	lines := make(chan string)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(lines)
+		r := bufio.NewReader(c.conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			select {
+			case lines <- strings.TrimRight(line, "\r\n"):
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	w := bufio.NewWriter(c.conn)
+	fmt.Fprintf(w, "OK MPD %s\n", Version)
+	_ = w.Flush()
+
+	for line := range lines {
+		name, args, err := parseLine(line)
+		if err != nil {
+			fmt.Fprintf(w, "ACK [5@0] {} %v\n", err)
+			_ = w.Flush()
+			continue
+		}
+		if name == "close" {
+			return
+		}
+		if name == "idle" {
+			if !s.idle(c, w, args, lines) {
+				return
+			}
+			continue
+		}
+
+		out, ack := s.exec(c, name, args)
+		if ack != "" {
+			fmt.Fprintf(w, "ACK [%s] {%s} %s\n", ackCode(ack), name, ackMessage(ack))
+		} else {
+			w.WriteString(out)
+			w.WriteString("OK\n")
+		}
+		_ = w.Flush()
+	}
+}
+
+// idle waits for a change in one of subsystems (any when empty) or for
+// noidle. It returns false if the connection went away.
+func (s *Server) idle(c *client, w *bufio.Writer, subsystems []string, lines <-chan string) bool {
+	s.mu.Lock()
+	s.commands = append(s.commands, "idle")
+	s.mu.Unlock()
+
+	for {
+		if changed := s.takePending(c, subsystems); len(changed) > 0 {
+			for _, sub := range changed {
+				fmt.Fprintf(w, "changed: %s\n", sub)
+			}
+			w.WriteString("OK\n")
+			return w.Flush() == nil
+		}
+
+		select {
+		case <-c.wake:
+		case line, ok := <-lines:
+			if !ok {
+				return false
+			}
+			if line != "noidle" {
+				// Anything else during idle is a protocol error; MPD drops the client
+				return false
+			}
+			for _, sub := range s.takePending(c, subsystems) {
+				fmt.Fprintf(w, "changed: %s\n", sub)
+			}
+			w.WriteString("OK\n")
+			return w.Flush() == nil
+		}
+	}
+}
+
+// takePending returns and clears c's pending changes that match subsystems.
+func (s *Server) takePending(c *client, subsystems []string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var changed []string
+	for sub := range c.pending {
+		if len(subsystems) == 0 || slices.Contains(subsystems, sub) {
+			changed = append(changed, sub)
+			delete(c.pending, sub)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+// notifyLocked records a change for every connected client.
+func (s *Server) notifyLocked(subsystems ...string) {
+	for c := range s.conns {
+		for _, sub := range subsystems {
+			c.pending[sub] = true
+		}
+		select {
+		case c.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// parseLine splits a command line into its name and arguments, honouring
+// double quotes and backslash escapes.
+func parseLine(line string) (string, []string, error) {
+	var fields []string
+	var b strings.Builder
+	inQuotes, escaped, started := false, false, false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			b.WriteRune(r)
+			escaped = false
+		case r == '\\' && inQuotes:
+			escaped = true
+		case r == '"':
+			inQuotes = !inQuotes
+			started = true
+		case r == ' ' && !inQuotes:
+			if started {
+				fields = append(fields, b.String())
+				b.Reset()
+				started = false
+			}
+		default:
+			b.WriteRune(r)
+			started = true
+		}
+	}
+	if inQuotes {
+		return "", nil, fmt.Errorf("unterminated quote")
+	}
+	if started {
+		fields = append(fields, b.String())
+	}
+	if len(fields) == 0 {
+		return "", nil, fmt.Errorf("no command given")
+	}
+	return fields[0], fields[1:], nil
+}
+
+// ackCode and ackMessage split an internal "code message" ACK string.
+func ackCode(ack string) string {
+	code, _, _ := strings.Cut(ack, " ")
+	return code + "@0"
+}
+
+func ackMessage(ack string) string {
+	_, msg, _ := strings.Cut(ack, " ")
+	return msg
+}
+
+func lookup(file string) (Song, bool) {
+	for _, song := range Library {
+		if song.File == file {
+			return song, true
+		}
+	}
+	if strings.Contains(file, "://") {
+		return Song{File: file}, true
+	}
+	return Song{}, false
+}
+
+func formatSeconds(f float64) string {
+	return strconv.FormatFloat(f, 'f', 3, 64)
+}
@@ -0,0 +1,240 @@
+package mpd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+)
+
+// Player implements core.Player for an MPD server.
+type Player struct {
+	client *Client
+	name   string
+}
+
+// Ensure Player implements core.Player
+var _ core.Player = (*Player)(nil)
+
+// Ensure Player implements core.QueueEditor
+var _ core.QueueEditor = (*Player)(nil)
+
+// Ensure Player implements core.PlayModeController
+var _ core.PlayModeController = (*Player)(nil)
+
+// Ensure Player implements core.StreamPlayer
+var _ core.StreamPlayer = (*Player)(nil)
+
+// capabilities are the optional operations MPD supports. MPD keeps no play
+// history.
+const capabilities = core.CapQueueRead | core.CapQueueAdd | core.CapQueueEdit |
+	core.CapShuffle | core.CapRepeat | core.CapSeek | core.CapStream
+
+// NewPlayer creates a player for the server behind client. name is the
+// host name from the config.
+func NewPlayer(client *Client, name string) *Player {
+	return &Player{
+		client: client,
+		name:   name,
+	}
+}
+
+// Client returns the underlying MPD client.
+func (p *Player) Client() *Client {
+	return p.client
+}
+
+// Play starts or resumes playback.
+func (p *Player) Play(ctx context.Context) error {
+	return p.client.Play(ctx)
+}
+
+// Pause pauses playback.
+func (p *Player) Pause(ctx context.Context) error {
+	return p.client.Pause(ctx)
+}
+
+// Next skips to the next track.
+func (p *Player) Next(ctx context.Context) error {
+	return p.client.Next(ctx)
+}
+
+// Prev skips to the previous track.
+func (p *Player) Prev(ctx context.Context) error {
+	return p.client.Previous(ctx)
+}
+
+// Seek seeks to the specified position in milliseconds.
+func (p *Player) Seek(ctx context.Context, positionMs int) error {
+	return p.client.SeekCur(ctx, time.Duration(positionMs)*time.Millisecond)
+}
+
+// Volume sets the volume level (0-100).
+func (p *Player) Volume(ctx context.Context, percent int) error {
+	return p.client.SetVol(ctx, percent)
+}
+
+// GetState returns the current playback state.
+func (p *Player) GetState(ctx context.Context) (*core.PlaybackState, error) {
+	status, err := p.client.Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get status: %w", err)
+	}
+	song, err := p.client.CurrentSong(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get current song: %w", err)
+	}
+
+	state := &core.PlaybackState{
+		Device:    p.coreDevice(),
+		IsPlaying: status.State == "play",
+		Progress:  status.Elapsed,
+		Volume:    max(status.Volume, 0),
+		Shuffle:   status.Random,
+		Repeat:    repeatMode(status),
+	}
+	if song != nil {
+		state.Track = songTrack(*song)
+		if state.Track.Duration == 0 {
+			state.Track.Duration = status.Duration
+		}
+	}
+	return state, nil
+}
+
+// GetQueue returns the current queue.
+func (p *Player) GetQueue(ctx context.Context) (*core.Queue, error) {
+	songs, err := p.client.PlaylistInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get queue: %w", err)
+	}
+
+	queue := &core.Queue{Tracks: make([]core.Track, len(songs))}
+	for i, s := range songs {
+		queue.Tracks[i] = *songTrack(s)
+	}
+	if status, err := p.client.Status(ctx); err == nil && status.Song >= 0 && status.Song < len(songs) {
+		queue.CurrentIndex = status.Song
+	}
+	return queue, nil
+}
+
+// GetRecentlyPlayed is unsupported; MPD keeps no play history.
+func (p *Player) GetRecentlyPlayed(ctx context.Context, limit int) ([]core.HistoryEntry, error) {
+	return nil, core.Require(p, core.CapHistory)
+}
+
+// AddToQueue appends a file, directory or stream URL to the queue.
+func (p *Player) AddToQueue(ctx context.Context, trackURI string) error {
+	return p.client.Add(ctx, trackURI)
+}
+
+// PlayURI appends a stream URL to the queue and plays it.
+func (p *Player) PlayURI(ctx context.Context, uri string) error {
+	id, err := p.client.AddID(ctx, uri)
+	if err != nil {
+		return err
+	}
+	return p.client.PlayID(ctx, id)
+}
+
+// RemoveFromQueue removes the track at a 1-based queue position.
+func (p *Player) RemoveFromQueue(ctx context.Context, position int) error {
+	return p.client.Delete(ctx, position-1)
+}
+
+// MoveInQueue moves the track at 1-based position from to position to.
+func (p *Player) MoveInQueue(ctx context.Context, from, to int) error {
+	return p.client.Move(ctx, from-1, to-1)
+}
+
+// ClearQueue removes every track from the queue.
+func (p *Player) ClearQueue(ctx context.Context) error {
+	return p.client.Clear(ctx)
+}
+
+// Shuffle turns random playback on or off.
+func (p *Player) Shuffle(ctx context.Context, state bool) error {
+	return p.client.Random(ctx, state)
+}
+
+// Repeat sets the repeat mode: "off", "track" or "context". MPD repeats a
+// single track with repeat and single mode both on.
+func (p *Player) Repeat(ctx context.Context, mode string) error {
+	var repeat, single bool
+	switch mode {
+	case "off":
+	case "track":
+		repeat, single = true, true
+	case "context":
+		repeat = true
+	default:
+		return fmt.Errorf("invalid repeat mode: %s (must be off, track, or context)", mode)
+	}
+
+	if err := p.client.Repeat(ctx, repeat); err != nil {
+		return err
+	}
+	return p.client.Single(ctx, single)
+}
+
+// WaitForChange blocks until MPD reports a change to playback, the volume,
+// the queue or the play modes. See core.ChangeNotifier.
+func (p *Player) WaitForChange(ctx context.Context) error {
+	_, err := p.client.Idle(ctx, "player", "mixer", "playlist", "options")
+	return err
+}
+
+// Capabilities returns the operations MPD supports.
+func (p *Player) Capabilities() core.Capability {
+	return capabilities
+}
+
+// Describe names the player for error messages.
+func (p *Player) Describe() string {
+	return fmt.Sprintf("MPD (%s)", p.name)
+}
+
+func (p *Player) coreDevice() *core.Device {
+	return &core.Device{
+		ID:       hostID(p.name),
+		Name:     p.name,
+		Type:     core.DeviceTypeComputer,
+		Platform: core.PlatformMPD,
+		IsActive: true,
+	}
+}
+
+// repeatMode maps MPD's repeat and single flags to a core repeat mode.
+func repeatMode(s *Status) string {
+	switch {
+	case s.Repeat && s.Single:
+		return "track"
+	case s.Repeat:
+		return "context"
+	default:
+		return "off"
+	}
+}
+
+// songTrack converts an MPD song to a core.Track.
+func songTrack(s Song) *core.Track {
+	title := s.Title
+	if title == "" {
+		title = s.File
+	}
+	t := &core.Track{
+		ID:       s.File,
+		URI:      s.File,
+		Title:    title,
+		Artist:   s.Artist,
+		Album:    s.Album,
+		Duration: s.Duration,
+		Source:   core.SourceMPD,
+	}
+	if s.Artist != "" {
+		t.Artists = []string{s.Artist}
+	}
+	return t
+}
@@ -0,0 +1,205 @@
+package mpd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/mpd/mpdtest"
+	"github.com/tessro/riff/internal/tail"
+)
+
+func newTestPlayer(t *testing.T) (*Player, *mpdtest.Server) {
+	t.Helper()
+	srv := mpdtest.NewServer()
+	t.Cleanup(srv.Close)
+
+	c := NewClient(srv.Addr(), "", 0)
+	t.Cleanup(func() { _ = c.Close() })
+
+	srv.Enqueue(
+		mpdtest.Library[0].File,
+		mpdtest.Library[1].File,
+		mpdtest.Library[2].File,
+	)
+	return NewPlayer(c, "Office"), srv
+}
+
+func TestPlayerState(t *testing.T) {
+	p, srv := newTestPlayer(t)
+	ctx := context.Background()
+
+	if err := p.Play(ctx); err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+	if err := p.Next(ctx); err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if err := p.Seek(ctx, 65000); err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+	if err := p.Volume(ctx, 35); err != nil {
+		t.Fatalf("Volume() error = %v", err)
+	}
+
+	state, err := p.GetState(ctx)
+	if err != nil {
+		t.Fatalf("GetState() error = %v", err)
+	}
+	if !state.IsPlaying || state.Progress != 65*time.Second || state.Volume != 35 {
+		t.Errorf("GetState() = %+v", state)
+	}
+	if state.Track == nil || state.Track.Title != "Overpass" || state.Track.Source != core.SourceMPD {
+		t.Errorf("Track = %+v, want Overpass from mpd", state.Track)
+	}
+	if state.Device == nil || state.Device.Platform != core.PlatformMPD || state.Device.Name != "Office" {
+		t.Errorf("Device = %+v, want Office", state.Device)
+	}
+
+	if err := p.Pause(ctx); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if got := srv.Status().State; got != "pause" {
+		t.Errorf("state = %q, want pause", got)
+	}
+}
+
+func TestPlayerQueueEditing(t *testing.T) {
+	p, _ := newTestPlayer(t)
+	ctx := context.Background()
+
+	if err := p.Play(ctx); err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+	// Positions are 1-based, as in 'riff queue'
+	if err := p.MoveInQueue(ctx, 3, 1); err != nil {
+		t.Fatalf("MoveInQueue() error = %v", err)
+	}
+	if err := p.RemoveFromQueue(ctx, 3); err != nil {
+		t.Fatalf("RemoveFromQueue() error = %v", err)
+	}
+
+	queue, err := p.GetQueue(ctx)
+	if err != nil {
+		t.Fatalf("GetQueue() error = %v", err)
+	}
+	if len(queue.Tracks) != 2 || queue.Tracks[0].Title != "Last Exit" || queue.Tracks[1].Title != "Neon" {
+		t.Errorf("queue = %+v, want [Last Exit Neon]", queue.Tracks)
+	}
+	if queue.CurrentIndex != 1 {
+		t.Errorf("CurrentIndex = %d, want 1 (Neon)", queue.CurrentIndex)
+	}
+
+	if err := p.ClearQueue(ctx); err != nil {
+		t.Fatalf("ClearQueue() error = %v", err)
+	}
+	if queue, _ := p.GetQueue(ctx); len(queue.Tracks) != 0 {
+		t.Errorf("queue after clear = %+v, want empty", queue.Tracks)
+	}
+}
+
+func TestPlayerPlayModes(t *testing.T) {
+	p, srv := newTestPlayer(t)
+	ctx := context.Background()
+
+	for _, mode := range []string{"track", "context", "off"} {
+		if err := p.Repeat(ctx, mode); err != nil {
+			t.Fatalf("Repeat(%q) error = %v", mode, err)
+		}
+		state, err := p.GetState(ctx)
+		if err != nil {
+			t.Fatalf("GetState() error = %v", err)
+		}
+		if state.Repeat != mode {
+			t.Errorf("Repeat = %q after Repeat(%q)", state.Repeat, mode)
+		}
+	}
+
+	if err := p.Shuffle(ctx, true); err != nil {
+		t.Fatalf("Shuffle() error = %v", err)
+	}
+	if !srv.Status().Random {
+		t.Error("random = false after Shuffle(true)")
+	}
+
+	if _, err := p.GetRecentlyPlayed(ctx, 10); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("GetRecentlyPlayed() error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestBackend(t *testing.T) {
+	office := mpdtest.NewServer()
+	defer office.Close()
+	den := mpdtest.NewServer()
+	defer den.Close()
+
+	b := NewBackend(
+		Host{Name: "Office", Client: NewClient(office.Addr(), "", 0)},
+		Host{Name: "Den", Client: NewClient(den.Addr(), "", 0)},
+	)
+	ctx := context.Background()
+
+	devices, err := b.Devices(ctx)
+	if err != nil {
+		t.Fatalf("Devices() error = %v", err)
+	}
+	if len(devices) != 4 {
+		t.Fatalf("Devices() = %+v, want two outputs per host", devices)
+	}
+	if d := devices[2]; d.ID != "mpd:Den/0" || d.Name != "Den (Speakers)" || !d.IsActive {
+		t.Errorf("devices[2] = %+v, want active Den (Speakers)", d)
+	}
+
+	// Any output selects its host
+	p, err := b.PlayerFor(ctx, &devices[3])
+	if err != nil {
+		t.Fatalf("PlayerFor() error = %v", err)
+	}
+	if got := p.(*Player).Describe(); got != "MPD (Den)" {
+		t.Errorf("PlayerFor(Den HDMI) = %s, want MPD (Den)", got)
+	}
+
+	// With no device, a playing host wins over the first one
+	den.Enqueue(mpdtest.Library[0].File)
+	if err := p.Play(ctx); err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+	p, err = b.PlayerFor(ctx, nil)
+	if err != nil {
+		t.Fatalf("PlayerFor(nil) error = %v", err)
+	}
+	if got := p.(*Player).Describe(); got != "MPD (Den)" {
+		t.Errorf("PlayerFor(nil) = %s, want the playing host", got)
+	}
+}
+
+func TestPlayerWatcherUsesIdle(t *testing.T) {
+	p, _ := newTestPlayer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Poll only hourly, so the event can only come from idle
+	w := tail.NewWatcher(p, time.Hour)
+	go func() { _ = w.Start(ctx) }()
+
+	// Changes are retried until the idle connection is up to see one
+	volume := 40
+	for {
+		volume++
+		if err := p.Volume(ctx, volume); err != nil {
+			t.Fatalf("Volume() error = %v", err)
+		}
+		select {
+		case e := <-w.Events():
+			if e.Type != tail.EventVolumeChange || e.Current.Volume == 0 {
+				t.Errorf("event = %v, want a volume change", e.Type)
+			}
+			return
+		case <-time.After(50 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatal("no event from idle")
+		}
+	}
+}
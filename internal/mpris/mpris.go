@@ -0,0 +1,317 @@
+// Package mpris exposes a core.Player on D-Bus as an MPRIS media player, so
+// desktop media keys, shell widgets and playerctl can control it.
+//
+// The server owns org.mpris.MediaPlayer2.riff and serves the
+// org.mpris.MediaPlayer2 and org.mpris.MediaPlayer2.Player interfaces at
+// /org/mpris/MediaPlayer2. Method calls go straight to the player; property
+// values come from the last known state, which Run keeps current from
+// tail.Watcher events and announces with PropertiesChanged.
+package mpris
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/tail"
+)
+
+const (
+	// BusName is the well-known name riff claims on the session bus.
+	BusName = "org.mpris.MediaPlayer2.riff"
+
+	// ObjectPath is where MPRIS clients look for the player.
+	ObjectPath dbus.ObjectPath = "/org/mpris/MediaPlayer2"
+
+	RootInterface   = "org.mpris.MediaPlayer2"
+	PlayerInterface = "org.mpris.MediaPlayer2.Player"
+
+	propertiesInterface = "org.freedesktop.DBus.Properties"
+
+	// noTrack is the track ID MPRIS reserves for "nothing loaded".
+	noTrack dbus.ObjectPath = "/org/mpris/MediaPlayer2/TrackList/NoTrack"
+
+	// callTimeout bounds each player call made for a D-Bus method.
+	callTimeout = 10 * time.Second
+)
+
+// Server is an MPRIS player backed by a core.Player.
+type Server struct {
+	conn   *dbus.Conn
+	player core.Player
+
+	mu      sync.Mutex
+	state   *core.PlaybackState
+	updated time.Time // When state was fetched, to extrapolate Position
+	quit    chan struct{}
+}
+
+// NewServer creates a server that will export player on conn.
+func NewServer(conn *dbus.Conn, player core.Player) *Server {
+	return &Server{
+		conn:   conn,
+		player: player,
+		quit:   make(chan struct{}),
+	}
+}
+
+// Start exports the MPRIS interfaces and claims BusName. It fails if
+// another riff instance already owns the name.
+func (s *Server) Start(ctx context.Context) error {
+	if state, err := s.player.GetState(ctx); err == nil {
+		s.setState(state)
+	}
+
+	exports := []struct {
+		v       any
+		iface   string
+		mapping map[string]string
+	}{
+		{&rootObject{s}, RootInterface, nil},
+		{&playerObject{s}, PlayerInterface, map[string]string{"SeekBy": "Seek"}},
+		{&propertiesObject{s}, propertiesInterface, nil},
+	}
+	for _, e := range exports {
+		if err := s.conn.ExportWithMap(e.v, e.mapping, ObjectPath, e.iface); err != nil {
+			return fmt.Errorf("failed to export %s: %w", e.iface, err)
+		}
+	}
+
+	reply, err := s.conn.RequestName(BusName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return fmt.Errorf("failed to request %s: %w", BusName, err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return fmt.Errorf("%s is already owned; is another riff mpris running?", BusName)
+	}
+	return nil
+}
+
+// Run applies watcher events until ctx is cancelled, events closes or a
+// client calls Quit.
+func (s *Server) Run(ctx context.Context, events <-chan tail.Event) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.quit:
+			return nil
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			s.update(e.Current)
+		}
+	}
+}
+
+// Close releases BusName.
+func (s *Server) Close() error {
+	_, err := s.conn.ReleaseName(BusName)
+	return err
+}
+
+// refresh fetches the player state after a command so clients see the
+// result without waiting for the next poll.
+func (s *Server) refresh(ctx context.Context) {
+	if state, err := s.player.GetState(ctx); err == nil {
+		s.update(state)
+	}
+}
+
+// update stores state and emits PropertiesChanged for whatever changed.
+func (s *Server) update(state *core.PlaybackState) {
+	if state == nil {
+		return
+	}
+	s.mu.Lock()
+	before := s.playerProperties()
+	s.setStateLocked(state)
+	after := s.playerProperties()
+	s.mu.Unlock()
+
+	changed := make(map[string]dbus.Variant)
+	for name, v := range after {
+		if emitsChange(name) && v.String() != before[name].String() {
+			changed[name] = v
+		}
+	}
+	if len(changed) > 0 {
+		_ = s.conn.Emit(ObjectPath, propertiesInterface+".PropertiesChanged", PlayerInterface, changed, []string{})
+	}
+}
+
+func (s *Server) setState(state *core.PlaybackState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setStateLocked(state)
+}
+
+func (s *Server) setStateLocked(state *core.PlaybackState) {
+	s.state = state
+	s.updated = time.Now()
+}
+
+// position extrapolates the playback position from the last state, since
+// clients poll Position rather than waiting for a change signal.
+func (s *Server) position() time.Duration {
+	if s.state == nil {
+		return 0
+	}
+	pos := s.state.Progress
+	if s.state.IsPlaying {
+		pos += time.Since(s.updated)
+	}
+	if s.state.Track != nil && s.state.Track.Duration > 0 {
+		pos = min(pos, s.state.Track.Duration)
+	}
+	return pos
+}
+
+// emitsChange reports whether a Player property is announced through
+// PropertiesChanged. Position changes continuously and is only polled.
+func emitsChange(name string) bool {
+	return name != "Position"
+}
+
+// rootProperties returns the org.mpris.MediaPlayer2 properties.
+func (s *Server) rootProperties() map[string]dbus.Variant {
+	return map[string]dbus.Variant{
+		"CanQuit":             dbus.MakeVariant(true),
+		"CanRaise":            dbus.MakeVariant(false),
+		"HasTrackList":        dbus.MakeVariant(false),
+		"Identity":            dbus.MakeVariant("riff"),
+		"SupportedUriSchemes": dbus.MakeVariant(s.uriSchemes()),
+		"SupportedMimeTypes":  dbus.MakeVariant([]string{}),
+	}
+}
+
+// uriSchemes lists what OpenUri accepts on this player.
+func (s *Server) uriSchemes() []string {
+	if s.player.Capabilities().Has(core.CapStream) {
+		return []string{"http", "https"}
+	}
+	return []string{}
+}
+
+// playerProperties returns the org.mpris.MediaPlayer2.Player properties.
+// The caller must hold s.mu.
+func (s *Server) playerProperties() map[string]dbus.Variant {
+	caps := s.player.Capabilities()
+	state := s.state
+	if state == nil {
+		state = &core.PlaybackState{}
+	}
+
+	return map[string]dbus.Variant{
+		"PlaybackStatus": dbus.MakeVariant(playbackStatus(state)),
+		"LoopStatus":     dbus.MakeVariant(loopStatus(state.Repeat)),
+		"Rate":           dbus.MakeVariant(1.0),
+		"Shuffle":        dbus.MakeVariant(state.Shuffle),
+		"Metadata":       dbus.MakeVariant(metadata(state.Track)),
+		"Volume":         dbus.MakeVariant(float64(state.Volume) / 100),
+		"Position":       dbus.MakeVariant(s.position().Microseconds()),
+		"MinimumRate":    dbus.MakeVariant(1.0),
+		"MaximumRate":    dbus.MakeVariant(1.0),
+		"CanGoNext":      dbus.MakeVariant(true),
+		"CanGoPrevious":  dbus.MakeVariant(true),
+		"CanPlay":        dbus.MakeVariant(true),
+		"CanPause":       dbus.MakeVariant(true),
+		"CanSeek":        dbus.MakeVariant(caps.Has(core.CapSeek) && state.HasTrack()),
+		"CanControl":     dbus.MakeVariant(true),
+	}
+}
+
+// playbackStatus maps a state to Playing, Paused or Stopped.
+func playbackStatus(state *core.PlaybackState) string {
+	switch {
+	case state.IsPlaying:
+		return "Playing"
+	case state.HasTrack():
+		return "Paused"
+	default:
+		return "Stopped"
+	}
+}
+
+// loopStatus maps a core repeat mode to an MPRIS loop status.
+func loopStatus(repeat string) string {
+	switch repeat {
+	case "track":
+		return "Track"
+	case "context":
+		return "Playlist"
+	default:
+		return "None"
+	}
+}
+
+// repeatMode maps an MPRIS loop status to a core repeat mode.
+func repeatMode(loop string) (string, bool) {
+	switch loop {
+	case "None":
+		return "off", true
+	case "Track":
+		return "track", true
+	case "Playlist":
+		return "context", true
+	}
+	return "", false
+}
+
+// metadata builds the MPRIS Metadata map for a track.
+func metadata(t *core.Track) map[string]dbus.Variant {
+	if t == nil {
+		return map[string]dbus.Variant{"mpris:trackid": dbus.MakeVariant(noTrack)}
+	}
+
+	m := map[string]dbus.Variant{
+		"mpris:trackid": dbus.MakeVariant(trackID(t)),
+		"mpris:length":  dbus.MakeVariant(t.Duration.Microseconds()),
+		"xesam:title":   dbus.MakeVariant(t.Title),
+	}
+	artists := t.Artists
+	if len(artists) == 0 && t.Artist != "" {
+		artists = []string{t.Artist}
+	}
+	if len(artists) > 0 {
+		m["xesam:artist"] = dbus.MakeVariant(artists)
+	}
+	if t.Album != "" {
+		m["xesam:album"] = dbus.MakeVariant(t.Album)
+	}
+	if t.URI != "" {
+		m["xesam:url"] = dbus.MakeVariant(t.URI)
+	}
+	return m
+}
+
+// trackID derives a stable D-Bus object path for a track. Track URIs
+// contain characters paths can't, so they are hashed.
+func trackID(t *core.Track) dbus.ObjectPath {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(t.URI + "\x00" + t.Title))
+	return dbus.ObjectPath(fmt.Sprintf("/org/mpris/MediaPlayer2/riff/track/%x", h.Sum64()))
+}
+
+// callContext returns a context for one player call.
+func callContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), callTimeout)
+}
+
+// dbusError converts a player error for a D-Bus reply.
+func dbusError(err error) *dbus.Error {
+	if err == nil {
+		return nil
+	}
+	name := "org.mpris.MediaPlayer2.riff.Error"
+	if errors.Is(err, errors.ErrUnsupported) {
+		name = "org.mpris.MediaPlayer2.riff.NotSupported"
+	}
+	return dbus.NewError(name, []any{err.Error()})
+}
@@ -0,0 +1,278 @@
+package mpris
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/core/coretest"
+	"github.com/tessro/riff/internal/tail"
+)
+
+// startBus runs a private dbus-daemon for the test and returns its address.
+func startBus(t *testing.T) string {
+	t.Helper()
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not installed")
+	}
+
+	dir := t.TempDir()
+	config := filepath.Join(dir, "bus.conf")
+	err = os.WriteFile(config, []byte(`<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:dir=`+dir+`</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(daemon, "--config-file="+config, "--print-address", "--nofork")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("failed to start dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	addr, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("failed to read bus address: %v", err)
+	}
+	return strings.TrimSpace(addr)
+}
+
+func connect(t *testing.T, addr string) *dbus.Conn {
+	t.Helper()
+	conn, err := dbus.Connect(addr)
+	if err != nil {
+		t.Fatalf("failed to connect to bus: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestServer(t *testing.T) {
+	addr := startBus(t)
+
+	player := coretest.NewPlayer(nil, core.CapSeek)
+	player.Update(func(s *core.PlaybackState) {
+		s.Track = &core.Track{
+			URI:      "spotify:track:1",
+			Title:    "Neon",
+			Artist:   "The Testers",
+			Album:    "Night Drive",
+			Duration: 3 * time.Minute,
+		}
+		s.Progress = 30 * time.Second
+		s.Volume = 40
+	})
+
+	server := NewServer(connect(t, addr), player)
+	if err := server.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	client := connect(t, addr)
+	obj := client.Object(BusName, ObjectPath)
+
+	get := func(name string) dbus.Variant {
+		t.Helper()
+		v, err := obj.GetProperty(PlayerInterface + "." + name)
+		if err != nil {
+			t.Fatalf("Get(%s) error = %v", name, err)
+		}
+		return v
+	}
+
+	if got := get("PlaybackStatus").Value(); got != "Paused" {
+		t.Errorf("PlaybackStatus = %v, want Paused", got)
+	}
+	if got := get("Volume").Value(); got != 0.4 {
+		t.Errorf("Volume = %v, want 0.4", got)
+	}
+	meta := get("Metadata").Value().(map[string]dbus.Variant)
+	if got := meta["xesam:title"].Value(); got != "Neon" {
+		t.Errorf("xesam:title = %v, want Neon", got)
+	}
+	if got := meta["xesam:artist"].Value(); !equal(got, []string{"The Testers"}) {
+		t.Errorf("xesam:artist = %v, want [The Testers]", got)
+	}
+	if got := meta["mpris:length"].Value(); got != (3 * time.Minute).Microseconds() {
+		t.Errorf("mpris:length = %v, want %d", got, (3 * time.Minute).Microseconds())
+	}
+
+	identity, err := obj.GetProperty(RootInterface + ".Identity")
+	if err != nil || identity.Value() != "riff" {
+		t.Errorf("Identity = %v, %v; want riff", identity.Value(), err)
+	}
+
+	signals := make(chan *dbus.Signal, 16)
+	client.Signal(signals)
+	if err := client.AddMatchSignal(
+		dbus.WithMatchObjectPath(ObjectPath),
+		dbus.WithMatchInterface(propertiesInterface),
+		dbus.WithMatchMember("PropertiesChanged"),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	// Method calls reach the player and announce the new state
+	for _, method := range []string{"PlayPause", "Next"} {
+		if err := obj.Call(PlayerInterface+"."+method, 0).Err; err != nil {
+			t.Fatalf("%s() error = %v", method, err)
+		}
+	}
+	if err := obj.Call(PlayerInterface+".Seek", 0, int64(15*time.Second/time.Microsecond)).Err; err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+	if err := obj.SetProperty(PlayerInterface+".Volume", dbus.MakeVariant(0.75)); err != nil {
+		t.Fatalf("Set(Volume) error = %v", err)
+	}
+
+	var calls []string
+	for _, call := range player.Calls() {
+		name, _, _ := strings.Cut(call, " ")
+		calls = append(calls, name)
+	}
+	want := []string{"play", "next", "seek", "volume"}
+	if !equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	state := player.State()
+	if state.Volume != 75 {
+		t.Errorf("volume = %d, want 75", state.Volume)
+	}
+	if p := state.Progress; p < 45*time.Second || p > 46*time.Second {
+		t.Errorf("progress = %v, want about 45s", p)
+	}
+
+	changed := waitChanged(t, signals, "PlaybackStatus")
+	if got := changed["PlaybackStatus"].Value(); got != "Playing" {
+		t.Errorf("PlaybackStatus changed to %v, want Playing", got)
+	}
+
+	// Watcher events update properties too
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan tail.Event, 1)
+	done := make(chan error, 1)
+	go func() { done <- server.Run(ctx, events) }()
+
+	events <- tail.Event{Type: tail.EventTrackChange, Current: &core.PlaybackState{
+		Track:     &core.Track{URI: "spotify:track:2", Title: "Overpass", Artist: "The Testers"},
+		IsPlaying: true,
+		Volume:    75,
+	}}
+	changed = waitChanged(t, signals, "Metadata")
+	meta = changed["Metadata"].Value().(map[string]dbus.Variant)
+	if got := meta["xesam:title"].Value(); got != "Overpass" {
+		t.Errorf("xesam:title changed to %v, want Overpass", got)
+	}
+
+	// Quit stops Run
+	if err := obj.Call(RootInterface+".Quit", 0).Err; err != nil {
+		t.Fatalf("Quit() error = %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return after Quit")
+	}
+}
+
+func TestServerUnsupported(t *testing.T) {
+	addr := startBus(t)
+
+	server := NewServer(connect(t, addr), coretest.NewPlayer(nil, core.CapSeek))
+	if err := server.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	obj := connect(t, addr).Object(BusName, ObjectPath)
+	err := obj.SetProperty(PlayerInterface+".Shuffle", dbus.MakeVariant(true))
+	dbusErr, ok := err.(dbus.Error)
+	if !ok || dbusErr.Name != "org.mpris.MediaPlayer2.riff.NotSupported" {
+		t.Errorf("Set(Shuffle) error = %v, want NotSupported", err)
+	}
+
+	// A second server can't claim the name
+	other := NewServer(connect(t, addr), coretest.NewPlayer(nil, core.CapSeek))
+	if err := other.Start(context.Background()); err == nil {
+		t.Error("second Start() succeeded, want error")
+	}
+}
+
+// waitChanged returns the next PropertiesChanged payload that includes name.
+func waitChanged(t *testing.T, signals <-chan *dbus.Signal, name string) map[string]dbus.Variant {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case sig := <-signals:
+			if len(sig.Body) < 2 || sig.Body[0] != PlayerInterface {
+				continue
+			}
+			changed := sig.Body[1].(map[string]dbus.Variant)
+			if _, ok := changed[name]; ok {
+				return changed
+			}
+		case <-timeout:
+			t.Fatalf("no PropertiesChanged for %s", name)
+			return nil
+		}
+	}
+}
+
+func equal(got any, want []string) bool {
+	s, ok := got.([]string)
+	if !ok || len(s) != len(want) {
+		return false
+	}
+	for i := range s {
+		if s[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMetadataNoTrack(t *testing.T) {
+	m := metadata(nil)
+	if got := m["mpris:trackid"].Value(); got != noTrack {
+		t.Errorf("mpris:trackid = %v, want %v", got, noTrack)
+	}
+}
+
+func TestLoopStatus(t *testing.T) {
+	for _, mode := range []string{"off", "track", "context"} {
+		got, ok := repeatMode(loopStatus(mode))
+		if !ok || got != mode {
+			t.Errorf("repeatMode(loopStatus(%q)) = %q, %v", mode, got, ok)
+		}
+	}
+}
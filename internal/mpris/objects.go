@@ -0,0 +1,200 @@
+package mpris
+
+import (
+	"context"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/tessro/riff/internal/core"
+)
+
+// rootObject implements org.mpris.MediaPlayer2.
+type rootObject struct {
+	s *Server
+}
+
+// Raise is a no-op; riff has no window.
+func (o *rootObject) Raise() *dbus.Error {
+	return nil
+}
+
+// Quit stops the server's Run loop.
+func (o *rootObject) Quit() *dbus.Error {
+	select {
+	case <-o.s.quit:
+	default:
+		close(o.s.quit)
+	}
+	return nil
+}
+
+// playerObject implements org.mpris.MediaPlayer2.Player.
+type playerObject struct {
+	s *Server
+}
+
+// do runs a player command, then refreshes the exported state.
+func (o *playerObject) do(fn func(ctx context.Context, p core.Player) error) *dbus.Error {
+	ctx, cancel := callContext()
+	defer cancel()
+	if err := fn(ctx, o.s.player); err != nil {
+		return dbusError(err)
+	}
+	o.s.refresh(ctx)
+	return nil
+}
+
+func (o *playerObject) Next() *dbus.Error {
+	return o.do(func(ctx context.Context, p core.Player) error { return p.Next(ctx) })
+}
+
+func (o *playerObject) Previous() *dbus.Error {
+	return o.do(func(ctx context.Context, p core.Player) error { return p.Prev(ctx) })
+}
+
+func (o *playerObject) Pause() *dbus.Error {
+	return o.do(func(ctx context.Context, p core.Player) error { return p.Pause(ctx) })
+}
+
+func (o *playerObject) Play() *dbus.Error {
+	return o.do(func(ctx context.Context, p core.Player) error { return p.Play(ctx) })
+}
+
+// Stop pauses; none of the backends distinguish stopping from pausing.
+func (o *playerObject) Stop() *dbus.Error {
+	return o.Pause()
+}
+
+func (o *playerObject) PlayPause() *dbus.Error {
+	o.s.mu.Lock()
+	playing := o.s.state != nil && o.s.state.IsPlaying
+	o.s.mu.Unlock()
+	if playing {
+		return o.Pause()
+	}
+	return o.Play()
+}
+
+// SeekBy implements Seek, moving the position by offset microseconds. It
+// is renamed on export, since Go reserves Seek for io.Seeker.
+func (o *playerObject) SeekBy(offset int64) *dbus.Error {
+	o.s.mu.Lock()
+	target := o.s.position() + time.Duration(offset)*time.Microsecond
+	o.s.mu.Unlock()
+	return o.seekTo(max(target, 0))
+}
+
+// SetPosition seeks to position microseconds, if trackID is still the
+// current track.
+func (o *playerObject) SetPosition(id dbus.ObjectPath, position int64) *dbus.Error {
+	o.s.mu.Lock()
+	current := o.s.state
+	o.s.mu.Unlock()
+	if current == nil || current.Track == nil || trackID(current.Track) != id || position < 0 {
+		return nil
+	}
+	return o.seekTo(time.Duration(position) * time.Microsecond)
+}
+
+func (o *playerObject) seekTo(target time.Duration) *dbus.Error {
+	if err := core.Require(o.s.player, core.CapSeek); err != nil {
+		return dbusError(err)
+	}
+	if err := o.do(func(ctx context.Context, p core.Player) error { return p.Seek(ctx, int(target.Milliseconds())) }); err != nil {
+		return err
+	}
+	_ = o.s.conn.Emit(ObjectPath, PlayerInterface+".Seeked", target.Microseconds())
+	return nil
+}
+
+// OpenUri plays a stream URL on players that can fetch one.
+func (o *playerObject) OpenUri(uri string) *dbus.Error {
+	if err := core.Require(o.s.player, core.CapStream); err != nil {
+		return dbusError(err)
+	}
+	return o.do(func(ctx context.Context, p core.Player) error {
+		return p.(core.StreamPlayer).PlayURI(ctx, uri)
+	})
+}
+
+// propertiesObject implements org.freedesktop.DBus.Properties for both
+// MPRIS interfaces.
+type propertiesObject struct {
+	s *Server
+}
+
+func (o *propertiesObject) Get(iface, name string) (dbus.Variant, *dbus.Error) {
+	props, err := o.GetAll(iface)
+	if err != nil {
+		return dbus.Variant{}, err
+	}
+	v, ok := props[name]
+	if !ok {
+		return dbus.Variant{}, dbus.NewError("org.freedesktop.DBus.Error.UnknownProperty", []any{name})
+	}
+	return v, nil
+}
+
+func (o *propertiesObject) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	switch iface {
+	case RootInterface:
+		return o.s.rootProperties(), nil
+	case PlayerInterface:
+		o.s.mu.Lock()
+		defer o.s.mu.Unlock()
+		return o.s.playerProperties(), nil
+	}
+	return nil, dbus.NewError("org.freedesktop.DBus.Error.UnknownInterface", []any{iface})
+}
+
+// Set handles the writable Player properties: Volume, Shuffle and
+// LoopStatus.
+func (o *propertiesObject) Set(iface, name string, value dbus.Variant) *dbus.Error {
+	if iface != PlayerInterface {
+		return dbus.NewError("org.freedesktop.DBus.Error.PropertyReadOnly", []any{name})
+	}
+	player := &playerObject{o.s}
+
+	switch name {
+	case "Volume":
+		vol, ok := value.Value().(float64)
+		if !ok {
+			return invalidArgs(name)
+		}
+		percent := int(min(max(vol, 0), 1)*100 + 0.5)
+		return player.do(func(ctx context.Context, p core.Player) error { return p.Volume(ctx, percent) })
+
+	case "Shuffle":
+		on, ok := value.Value().(bool)
+		if !ok {
+			return invalidArgs(name)
+		}
+		if err := core.Require(o.s.player, core.CapShuffle); err != nil {
+			return dbusError(err)
+		}
+		return player.do(func(ctx context.Context, p core.Player) error {
+			return p.(core.PlayModeController).Shuffle(ctx, on)
+		})
+
+	case "LoopStatus":
+		loop, ok := value.Value().(string)
+		if !ok {
+			return invalidArgs(name)
+		}
+		mode, ok := repeatMode(loop)
+		if !ok {
+			return invalidArgs(name)
+		}
+		if err := core.Require(o.s.player, core.CapRepeat); err != nil {
+			return dbusError(err)
+		}
+		return player.do(func(ctx context.Context, p core.Player) error {
+			return p.(core.PlayModeController).Repeat(ctx, mode)
+		})
+	}
+	return dbus.NewError("org.freedesktop.DBus.Error.PropertyReadOnly", []any{name})
+}
+
+func invalidArgs(name string) *dbus.Error {
+	return dbus.NewError("org.freedesktop.DBus.Error.InvalidArgs", []any{"invalid value for " + name})
+}
@@ -0,0 +1,389 @@
+// Package mqtt publishes riff's players to an MQTT broker and accepts
+// commands from it, announcing each device to Home Assistant through MQTT
+// discovery.
+//
+// Each device gets a topic tree under the prefix, named by a topic-safe
+// form of its ID:
+//
+//	riff/status                      online/offline (retained, LWT)
+//	riff/<id>/state                  playing, paused or idle (retained)
+//	riff/<id>/title, artist, album   current track (retained)
+//	riff/<id>/volume                 0-1 (retained)
+//	riff/<id>/position, duration     seconds (retained)
+//	riff/<id>/attributes             full playback state as JSON (retained)
+//	riff/<id>/set/<command>          play, pause, playpause, next,
+//	                                 previous, volume, seek, playmedia
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/tail"
+)
+
+// Options configures a Bridge.
+type Options struct {
+	// Broker is the broker URL, such as tcp://localhost:1883.
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+
+	// TopicPrefix roots every state and command topic (default: riff).
+	TopicPrefix string
+
+	// DiscoveryPrefix is Home Assistant's discovery prefix (default:
+	// homeassistant). Discovery is skipped when DisableDiscovery is set.
+	DiscoveryPrefix  string
+	DisableDiscovery bool
+
+	// Interval is how often each player is polled for changes.
+	Interval time.Duration
+
+	// RefreshInterval is how often the device list is refreshed, so new
+	// devices appear without a restart.
+	RefreshInterval time.Duration
+
+	// Logger reports connection problems and failed commands. Nil means
+	// slog.Default().
+	Logger *slog.Logger
+}
+
+// Bridge connects players to an MQTT broker.
+type Bridge struct {
+	opts     Options
+	backends core.DeviceResolver
+
+	// Reconnect backoff bounds
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu       sync.Mutex
+	client   paho.Client
+	entities map[string]*entity
+	lost     chan error
+}
+
+// entity is one device's player and its last published state.
+type entity struct {
+	id     string
+	device core.Device
+	player core.Player
+
+	mu    sync.Mutex
+	state *core.PlaybackState
+}
+
+// New creates a bridge. Call Run to connect it.
+func New(backends core.DeviceResolver, opts Options) *Bridge {
+	if opts.ClientID == "" {
+		opts.ClientID = "riff"
+	}
+	if opts.TopicPrefix == "" {
+		opts.TopicPrefix = "riff"
+	}
+	if opts.DiscoveryPrefix == "" {
+		opts.DiscoveryPrefix = "homeassistant"
+	}
+	if opts.Interval == 0 {
+		opts.Interval = time.Second
+	}
+	if opts.RefreshInterval == 0 {
+		opts.RefreshInterval = 5 * time.Minute
+	}
+	return &Bridge{
+		opts:       opts,
+		backends:   backends,
+		minBackoff: time.Second,
+		maxBackoff: time.Minute,
+		entities:   make(map[string]*entity),
+		lost:       make(chan error, 1),
+	}
+}
+
+func (b *Bridge) log() *slog.Logger {
+	if b.opts.Logger != nil {
+		return b.opts.Logger
+	}
+	return slog.Default()
+}
+
+// availabilityTopic is the bridge's online/offline topic.
+func (b *Bridge) availabilityTopic() string {
+	return b.opts.TopicPrefix + "/status"
+}
+
+// topic returns a topic under an entity's tree.
+func (b *Bridge) topic(e *entity, name string) string {
+	return b.opts.TopicPrefix + "/" + e.id + "/" + name
+}
+
+// Run connects to the broker and bridges players until ctx is done,
+// reconnecting with exponential backoff whenever the connection fails.
+func (b *Bridge) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		b.watchDevices(ctx, &wg)
+	}()
+
+	backoff := b.minBackoff
+	for {
+		err := b.connect()
+		if err == nil {
+			backoff = b.minBackoff
+			select {
+			case <-ctx.Done():
+				b.disconnect()
+				return nil
+			case err = <-b.lost:
+			}
+		}
+
+		b.log().Warn("mqtt connection lost, reconnecting", "error", err, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, b.maxBackoff)
+	}
+}
+
+// connect opens a session, marks the bridge online and announces every
+// known entity.
+func (b *Bridge) connect() error {
+	opts := paho.NewClientOptions().
+		AddBroker(b.opts.Broker).
+		SetClientID(b.opts.ClientID).
+		SetUsername(b.opts.Username).
+		SetPassword(b.opts.Password).
+		SetCleanSession(true).
+		SetAutoReconnect(false).
+		SetConnectTimeout(10*time.Second).
+		SetWill(b.availabilityTopic(), "offline", 1, true).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			select {
+			case b.lost <- fmt.Errorf("connection lost: %w", err):
+			default:
+			}
+		})
+
+	client := paho.NewClient(opts)
+	if err := wait(client.Connect()); err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", b.opts.Broker, err)
+	}
+
+	// Drop a loss reported by a previous session
+	select {
+	case <-b.lost:
+	default:
+	}
+
+	commands := b.opts.TopicPrefix + "/+/set/+"
+	if err := wait(client.Subscribe(commands, 1, b.onCommand)); err != nil {
+		client.Disconnect(0)
+		return fmt.Errorf("failed to subscribe to %s: %w", commands, err)
+	}
+
+	b.mu.Lock()
+	b.client = client
+	entities := make([]*entity, 0, len(b.entities))
+	for _, e := range b.entities {
+		entities = append(entities, e)
+	}
+	b.mu.Unlock()
+
+	b.publish(b.availabilityTopic(), "online")
+	for _, e := range entities {
+		b.announce(e)
+	}
+	return nil
+}
+
+// disconnect marks the bridge offline and closes the session.
+func (b *Bridge) disconnect() {
+	b.mu.Lock()
+	client := b.client
+	b.client = nil
+	b.mu.Unlock()
+	if client == nil {
+		return
+	}
+	_ = wait(client.Publish(b.availabilityTopic(), 1, true, "offline"))
+	client.Disconnect(250)
+}
+
+// wait waits for t to complete and returns its error.
+func wait(t paho.Token) error {
+	if !t.WaitTimeout(10 * time.Second) {
+		return fmt.Errorf("timed out")
+	}
+	return t.Error()
+}
+
+// publish sends a retained message at QoS 1. While disconnected it does
+// nothing; state is republished on reconnect.
+func (b *Bridge) publish(topic, payload string) {
+	b.mu.Lock()
+	client := b.client
+	b.mu.Unlock()
+	if client == nil || !client.IsConnectionOpen() {
+		return
+	}
+	if err := wait(client.Publish(topic, 1, true, payload)); err != nil {
+		b.log().Warn("mqtt publish failed", "topic", topic, "error", err)
+	}
+}
+
+// announce publishes an entity's discovery config and last state.
+func (b *Bridge) announce(e *entity) {
+	if !b.opts.DisableDiscovery {
+		b.publishDiscovery(e)
+	}
+	e.mu.Lock()
+	state := e.state
+	e.mu.Unlock()
+	if state != nil {
+		b.publishState(e, state)
+	}
+}
+
+// watchDevices adds an entity for every device, now and on each refresh.
+func (b *Bridge) watchDevices(ctx context.Context, wg *sync.WaitGroup) {
+	ticker := time.NewTicker(b.opts.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		devices, err := b.backends.Devices(ctx)
+		if err != nil && len(devices) == 0 {
+			b.log().Warn("mqtt failed to list devices", "error", err)
+		}
+		for _, d := range devices {
+			b.addDevice(ctx, wg, d)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// addDevice starts watching d unless it is already known.
+func (b *Bridge) addDevice(ctx context.Context, wg *sync.WaitGroup, d core.Device) {
+	id := objectID(d)
+	b.mu.Lock()
+	_, known := b.entities[id]
+	b.mu.Unlock()
+	if known {
+		return
+	}
+
+	player, _, err := b.backends.PlayerFor(ctx, d.ID)
+	if err != nil {
+		b.log().Warn("mqtt failed to open device", "device", d.Name, "error", err)
+		return
+	}
+	e := &entity{id: id, device: d, player: player}
+
+	b.mu.Lock()
+	b.entities[id] = e
+	b.mu.Unlock()
+
+	if !b.opts.DisableDiscovery {
+		b.publishDiscovery(e)
+	}
+	if state, err := player.GetState(ctx); err == nil {
+		b.update(e, state)
+	}
+
+	w := tail.NewWatcher(player, b.opts.Interval)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_ = w.Start(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		for ev := range w.Events() {
+			b.update(e, ev.Current)
+		}
+	}()
+}
+
+// update records and publishes an entity's state.
+func (b *Bridge) update(e *entity, state *core.PlaybackState) {
+	if state == nil {
+		return
+	}
+	e.mu.Lock()
+	e.state = state
+	e.mu.Unlock()
+	b.publishState(e, state)
+}
+
+// publishState publishes each field of state to its own retained topic.
+// Spotify players report the account's playback, so a device that isn't
+// the one playing is shown as idle.
+func (b *Bridge) publishState(e *entity, state *core.PlaybackState) {
+	status := "idle"
+	var title, artist, album string
+	var duration, position float64
+	active := state.Device == nil || state.Device.ID == e.device.ID
+	if active && state.HasTrack() {
+		status = "paused"
+		if state.IsPlaying {
+			status = "playing"
+		}
+		title, artist, album = state.Track.Title, state.Track.Artist, state.Track.Album
+		duration = state.Track.Duration.Seconds()
+		position = state.Progress.Seconds()
+	}
+
+	b.publish(b.topic(e, "state"), status)
+	b.publish(b.topic(e, "title"), title)
+	b.publish(b.topic(e, "artist"), artist)
+	b.publish(b.topic(e, "album"), album)
+	b.publish(b.topic(e, "volume"), formatFloat(float64(state.Volume)/100))
+	b.publish(b.topic(e, "duration"), formatFloat(duration))
+	b.publish(b.topic(e, "position"), formatFloat(position))
+	if data, err := json.Marshal(state); err == nil {
+		b.publish(b.topic(e, "attributes"), string(data))
+	}
+}
+
+// entityByID returns the entity with the given object ID.
+func (b *Bridge) entityByID(id string) *entity {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.entities[id]
+}
+
+// objectID returns a topic- and Home Assistant-safe ID for d.
+func objectID(d core.Device) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(d.ID) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteRune('_')
+		}
+	}
+	return sb.String()
+}
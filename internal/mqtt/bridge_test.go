@@ -0,0 +1,225 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/core/coretest"
+	rifferrors "github.com/tessro/riff/internal/errors"
+	"github.com/tessro/riff/internal/mqtt/mqtttest"
+)
+
+const waitTimeout = 5 * time.Second
+
+// fakeBackends serves one player per device, by ID.
+type fakeBackends struct {
+	devices []core.Device
+	players map[string]*coretest.Player
+}
+
+func newFakeBackends() *fakeBackends {
+	b := &fakeBackends{
+		devices: []core.Device{
+			{ID: "RINCON_1", Name: "Kitchen", Type: core.DeviceTypeSpeaker, Platform: core.PlatformSonos},
+			{ID: "abc123", Name: "MacBook Pro", Type: core.DeviceTypeComputer, Platform: core.PlatformSpotify},
+		},
+		players: map[string]*coretest.Player{},
+	}
+	for i := range b.devices {
+		d := &b.devices[i]
+		p := coretest.NewPlayer(d, core.CapSeek)
+		p.Update(func(s *core.PlaybackState) {
+			s.Track = &core.Track{Title: "Neon", Artist: "The Testers", Album: "Night Drive", Duration: 200 * time.Second}
+			s.Volume = 40
+		})
+		b.players[d.ID] = p
+	}
+	return b
+}
+
+func (b *fakeBackends) Devices(ctx context.Context) ([]core.Device, error) {
+	return b.devices, nil
+}
+
+func (b *fakeBackends) PlayerFor(ctx context.Context, target string) (core.Player, *core.Device, error) {
+	p, ok := b.players[target]
+	if !ok {
+		return nil, nil, fmt.Errorf("'%s': %w", target, rifferrors.ErrDeviceNotFound)
+	}
+	return p, p.State().Device, nil
+}
+
+// newBroker starts an embedded broker that outlives any bridge started
+// after it.
+func newBroker(t *testing.T) *mqtttest.Broker {
+	t.Helper()
+	broker := mqtttest.NewBroker()
+	t.Cleanup(broker.Close)
+	return broker
+}
+
+// startBridge runs a bridge against broker until the test ends, and
+// returns a function that stops it and waits for Run to return.
+func startBridge(t *testing.T, broker *mqtttest.Broker, backends core.DeviceResolver) func() {
+	t.Helper()
+	b := New(backends, Options{
+		Broker:   broker.URL(),
+		ClientID: "riff-test",
+		Interval: 20 * time.Millisecond,
+		Logger:   slog.New(slog.NewTextHandler(t.Output(), nil)),
+	})
+	b.minBackoff = 20 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- b.Run(ctx) }()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			cancel()
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("Run() error = %v", err)
+				}
+			case <-time.After(waitTimeout):
+				t.Error("Run() did not return after cancel")
+			}
+		})
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+func wantRetained(t *testing.T, broker *mqtttest.Broker, topic, want string) {
+	t.Helper()
+	got, ok := broker.WaitRetained(topic, waitTimeout, func(s string) bool { return s == want })
+	if !ok {
+		t.Errorf("%s = %q, want %q", topic, got, want)
+	}
+}
+
+func TestDiscoveryAndState(t *testing.T) {
+	broker := newBroker(t)
+	startBridge(t, broker, newFakeBackends())
+
+	wantRetained(t, broker, "riff/status", "online")
+
+	payload, ok := broker.WaitRetained("homeassistant/media_player/riff_rincon_1/config", waitTimeout, nil)
+	if !ok {
+		t.Fatal("no discovery config for Kitchen")
+	}
+	var config map[string]any
+	if err := json.Unmarshal([]byte(payload), &config); err != nil {
+		t.Fatalf("invalid discovery config: %v", err)
+	}
+	want := map[string]string{
+		"name":                    "Kitchen",
+		"unique_id":               "riff_rincon_1",
+		"state_state_topic":       "riff/rincon_1/state",
+		"state_volume_topic":      "riff/rincon_1/volume",
+		"command_play_topic":      "riff/rincon_1/set/play",
+		"command_playmedia_topic": "riff/rincon_1/set/playmedia",
+	}
+	for key, value := range want {
+		if config[key] != value {
+			t.Errorf("discovery %s = %v, want %q", key, config[key], value)
+		}
+	}
+	if avail, _ := config["availability"].(map[string]any); avail["topic"] != "riff/status" {
+		t.Errorf("discovery availability = %v", config["availability"])
+	}
+	if _, ok := broker.WaitRetained("homeassistant/media_player/riff_abc123/config", waitTimeout, nil); !ok {
+		t.Error("no discovery config for MacBook Pro")
+	}
+
+	wantRetained(t, broker, "riff/rincon_1/state", "paused")
+	wantRetained(t, broker, "riff/rincon_1/title", "Neon")
+	wantRetained(t, broker, "riff/rincon_1/artist", "The Testers")
+	wantRetained(t, broker, "riff/rincon_1/volume", "0.4")
+	wantRetained(t, broker, "riff/rincon_1/duration", "200")
+}
+
+func TestCommands(t *testing.T) {
+	broker := newBroker(t)
+	backends := newFakeBackends()
+	startBridge(t, broker, backends)
+	kitchen := backends.players["RINCON_1"]
+
+	wantRetained(t, broker, "riff/rincon_1/state", "paused")
+
+	broker.Publish("riff/rincon_1/set/play", "", false)
+	wantRetained(t, broker, "riff/rincon_1/state", "playing")
+
+	broker.Publish("riff/rincon_1/set/playpause", "", false)
+	wantRetained(t, broker, "riff/rincon_1/state", "paused")
+
+	broker.Publish("riff/rincon_1/set/volume", "0.25", false)
+	wantRetained(t, broker, "riff/rincon_1/volume", "0.25")
+	if v := kitchen.State().Volume; v != 25 {
+		t.Errorf("volume = %d, want 25", v)
+	}
+
+	broker.Publish("riff/rincon_1/set/seek", "90", false)
+	wantRetained(t, broker, "riff/rincon_1/position", "90")
+
+	// Commands for other devices leave Kitchen alone
+	broker.Publish("riff/abc123/set/volume", "70", false)
+	wantRetained(t, broker, "riff/abc123/volume", "0.7")
+	if v := kitchen.State().Volume; v != 25 {
+		t.Errorf("Kitchen volume = %d after MacBook command, want 25", v)
+	}
+}
+
+func TestAvailabilityAndReconnect(t *testing.T) {
+	broker := newBroker(t)
+	stop := startBridge(t, broker, newFakeBackends())
+
+	wantRetained(t, broker, "riff/status", "online")
+	connects := broker.Connects()
+
+	// A dropped connection publishes the will, then the bridge reconnects
+	// and comes back online with its state
+	broker.DropConnections()
+	wantRetained(t, broker, "riff/status", "offline")
+	wantRetained(t, broker, "riff/status", "online")
+	if broker.Connects() <= connects {
+		t.Errorf("connects = %d, want more than %d", broker.Connects(), connects)
+	}
+
+	broker.Publish("riff/rincon_1/set/play", "", false)
+	wantRetained(t, broker, "riff/rincon_1/state", "playing")
+
+	// A clean shutdown marks the bridge offline
+	stop()
+	wantRetained(t, broker, "riff/status", "offline")
+}
+
+func TestParseVolume(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+		err  bool
+	}{
+		{"0.5", 50, false},
+		{"1", 100, false},
+		{"0", 0, false},
+		{"35", 35, false},
+		{"0.333", 33, false},
+		{"loud", 0, true},
+		{"150", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseVolume(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("parseVolume(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
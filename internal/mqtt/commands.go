@@ -0,0 +1,108 @@
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/tessro/riff/internal/core"
+)
+
+// commandTimeout bounds one command, including the state refresh after it.
+const commandTimeout = 15 * time.Second
+
+// onCommand handles a message on <prefix>/<id>/set/<command>. Commands run
+// off the client's goroutine so a slow device can't stall the connection.
+func (b *Bridge) onCommand(_ paho.Client, msg paho.Message) {
+	rest, ok := strings.CutPrefix(msg.Topic(), b.opts.TopicPrefix+"/")
+	if !ok {
+		return
+	}
+	id, command, ok := strings.Cut(rest, "/set/")
+	if !ok {
+		return
+	}
+	e := b.entityByID(id)
+	if e == nil {
+		return
+	}
+	payload := strings.TrimSpace(string(msg.Payload()))
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		defer cancel()
+		if err := b.runCommand(ctx, e, command, payload); err != nil {
+			b.log().Warn("mqtt command failed", "command", command, "device", e.device.Name, "error", err)
+			return
+		}
+		// Publish the result now rather than on the next poll
+		if state, err := e.player.GetState(ctx); err == nil {
+			b.update(e, state)
+		}
+	}()
+}
+
+// runCommand applies one command to e's player.
+func (b *Bridge) runCommand(ctx context.Context, e *entity, command, payload string) error {
+	p := e.player
+	switch command {
+	case "play":
+		return p.Play(ctx)
+	case "pause":
+		return p.Pause(ctx)
+	case "playpause":
+		state, err := p.GetState(ctx)
+		if err != nil {
+			return err
+		}
+		if state.IsPlaying {
+			return p.Pause(ctx)
+		}
+		return p.Play(ctx)
+	case "next":
+		return p.Next(ctx)
+	case "previous":
+		return p.Prev(ctx)
+	case "volume":
+		percent, err := parseVolume(payload)
+		if err != nil {
+			return err
+		}
+		return p.Volume(ctx, percent)
+	case "seek":
+		seconds, err := strconv.ParseFloat(payload, 64)
+		if err != nil || seconds < 0 {
+			return fmt.Errorf("invalid seek position: %q", payload)
+		}
+		if err := core.Require(p, core.CapSeek); err != nil {
+			return err
+		}
+		return p.Seek(ctx, int(seconds*1000))
+	case "playmedia":
+		if payload == "" {
+			return fmt.Errorf("playmedia needs a URL")
+		}
+		if err := core.Require(p, core.CapStream); err != nil {
+			return err
+		}
+		return p.(core.StreamPlayer).PlayURI(ctx, payload)
+	default:
+		return fmt.Errorf("unknown command: %s", command)
+	}
+}
+
+// parseVolume reads a volume as Home Assistant sends it, a fraction from 0
+// to 1, or as a percentage above 1.
+func parseVolume(payload string) (int, error) {
+	v, err := strconv.ParseFloat(payload, 64)
+	if err != nil || v < 0 || v > 100 {
+		return 0, fmt.Errorf("invalid volume: %q", payload)
+	}
+	if v <= 1 {
+		v *= 100
+	}
+	return int(v + 0.5), nil
+}
@@ -0,0 +1,72 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// commands lists the command topics under <prefix>/<id>/set/.
+var commands = []string{"play", "pause", "playpause", "next", "previous", "volume", "seek", "playmedia"}
+
+// discoveryTopic returns the Home Assistant discovery config topic for e.
+func (b *Bridge) discoveryTopic(e *entity) string {
+	return b.opts.DiscoveryPrefix + "/media_player/riff_" + e.id + "/config"
+}
+
+// discoveryConfig builds e's media_player discovery payload. Keys follow
+// the MQTT Media Player integration: state_<field>_topic for each state
+// topic and command_<command>_topic for each command.
+func (b *Bridge) discoveryConfig(e *entity) map[string]any {
+	config := map[string]any{
+		"name":      e.device.Name,
+		"unique_id": "riff_" + e.id,
+		"availability": map[string]any{
+			"topic":                 b.availabilityTopic(),
+			"payload_available":     "online",
+			"payload_not_available": "offline",
+		},
+		"json_attributes_topic": b.topic(e, "attributes"),
+		"device": map[string]any{
+			"identifiers":  []string{"riff_" + e.id},
+			"name":         e.device.Name,
+			"manufacturer": platformName(string(e.device.Platform)),
+			"model":        string(e.device.Type),
+		},
+		"origin": map[string]any{"name": "riff"},
+	}
+	for _, field := range []string{"state", "title", "artist", "album", "volume", "duration", "position"} {
+		config["state_"+field+"_topic"] = b.topic(e, field)
+	}
+	for _, cmd := range commands {
+		config["command_"+cmd+"_topic"] = b.topic(e, "set/"+cmd)
+	}
+	return config
+}
+
+// publishDiscovery announces e to Home Assistant.
+func (b *Bridge) publishDiscovery(e *entity) {
+	data, err := json.Marshal(b.discoveryConfig(e))
+	if err != nil {
+		b.log().Error("mqtt failed to encode discovery config", "error", err)
+		return
+	}
+	b.publish(b.discoveryTopic(e), string(data))
+}
+
+// platformName capitalizes a platform for display, such as "Sonos".
+func platformName(platform string) string {
+	switch platform {
+	case "mpd", "upnp":
+		return strings.ToUpper(platform)
+	case "":
+		return "riff"
+	default:
+		return strings.ToUpper(platform[:1]) + platform[1:]
+	}
+}
+
+// formatFloat formats v without trailing zeros.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
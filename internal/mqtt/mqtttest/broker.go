@@ -0,0 +1,492 @@
+// Package mqtttest provides an embedded MQTT broker for tests.
+//
+// The broker speaks enough of MQTT 3.1.1 for a real client to connect,
+// publish, subscribe and receive retained messages, and it publishes a
+// client's will when the connection drops without a DISCONNECT. Messages
+// are delivered at QoS 0. Tests drive a client against it and assert on
+// what was published:
+//
+//	b := mqtttest.NewBroker()
+//	defer b.Close()
+//
+//	// ... connect a client to b.URL() ...
+//	state, ok := b.WaitRetained("riff/kitchen/state", time.Second, nil)
+package mqtttest
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Packet types.
+const (
+	typeConnect     = 1
+	typeConnack     = 2
+	typePublish     = 3
+	typePuback      = 4
+	typeSubscribe   = 8
+	typeSuback      = 9
+	typeUnsubscribe = 10
+	typeUnsuback    = 11
+	typePingreq     = 12
+	typePingresp    = 13
+	typeDisconnect  = 14
+)
+
+// Message is a published message.
+type Message struct {
+	Topic   string
+	Payload string
+	Retain  bool
+}
+
+// Broker is an embedded MQTT broker.
+type Broker struct {
+	ln net.Listener
+	wg sync.WaitGroup
+
+	mu       sync.Mutex
+	conns    map[*client]bool
+	retained map[string]string
+	messages []Message
+	connects int
+	changed  chan struct{}
+}
+
+// client is a connected session.
+type client struct {
+	conn net.Conn
+	id   string
+	will *Message
+
+	wmu  sync.Mutex
+	subs []string
+}
+
+// NewBroker starts a broker on a loopback port. Call Close when done.
+func NewBroker() *Broker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("mqtttest: failed to listen: %v", err))
+	}
+	b := &Broker{
+		ln:       ln,
+		conns:    make(map[*client]bool),
+		retained: make(map[string]string),
+		changed:  make(chan struct{}),
+	}
+	b.wg.Add(1)
+	go b.serve()
+	return b
+}
+
+// URL returns the broker URL, such as tcp://127.0.0.1:1883.
+func (b *Broker) URL() string {
+	return "tcp://" + b.ln.Addr().String()
+}
+
+// Close stops the broker and drops every connection without publishing
+// wills.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	for c := range b.conns {
+		c.will = nil
+		_ = c.conn.Close()
+	}
+	b.mu.Unlock()
+
+	_ = b.ln.Close()
+	b.wg.Wait()
+}
+
+// DropConnections closes every client connection abruptly, publishing
+// their wills, as a broker restart or network failure would.
+func (b *Broker) DropConnections() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.conns {
+		_ = c.conn.Close()
+	}
+}
+
+// Connects returns how many CONNECT packets the broker has accepted.
+func (b *Broker) Connects() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.connects
+}
+
+// Retained returns the retained message on topic.
+func (b *Broker) Retained(topic string) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	payload, ok := b.retained[topic]
+	return payload, ok
+}
+
+// Messages returns every message published so far, in order.
+func (b *Broker) Messages() []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Message(nil), b.messages...)
+}
+
+// WaitRetained waits up to timeout for a retained message on topic for
+// which match returns true. A nil match accepts any message.
+func (b *Broker) WaitRetained(topic string, timeout time.Duration, match func(string) bool) (string, bool) {
+	deadline := time.After(timeout)
+	for {
+		b.mu.Lock()
+		payload, ok := b.retained[topic]
+		changed := b.changed
+		b.mu.Unlock()
+		if ok && (match == nil || match(payload)) {
+			return payload, true
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			return payload, false
+		}
+	}
+}
+
+// Publish publishes a message as if from a client.
+func (b *Broker) Publish(topic, payload string, retain bool) {
+	b.publish(Message{Topic: topic, Payload: payload, Retain: retain})
+}
+
+func (b *Broker) serve() {
+	defer b.wg.Done()
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		c := &client{conn: conn}
+		b.mu.Lock()
+		b.conns[c] = true
+		b.mu.Unlock()
+
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			b.handle(c)
+		}()
+	}
+}
+
+// handle reads packets from c until it disconnects.
+func (b *Broker) handle(c *client) {
+	defer func() {
+		_ = c.conn.Close()
+		b.mu.Lock()
+		delete(b.conns, c)
+		will := c.will
+		b.mu.Unlock()
+		if will != nil {
+			b.publish(*will)
+		}
+	}()
+
+	r := bufio.NewReader(c.conn)
+	for {
+		header, body, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		switch header >> 4 {
+		case typeConnect:
+			if err := b.connect(c, body); err != nil {
+				return
+			}
+		case typePublish:
+			msg, id, qos, err := parsePublish(header, body)
+			if err != nil {
+				return
+			}
+			b.publish(msg)
+			if qos > 0 {
+				c.write(typePuback<<4, binary.BigEndian.AppendUint16(nil, id))
+			}
+		case typeSubscribe:
+			b.subscribe(c, body)
+		case typeUnsubscribe:
+			b.unsubscribe(c, body)
+		case typePingreq:
+			c.write(typePingresp<<4, nil)
+		case typeDisconnect:
+			b.mu.Lock()
+			c.will = nil
+			b.mu.Unlock()
+			return
+		default:
+			return
+		}
+	}
+}
+
+func (b *Broker) connect(c *client, body []byte) error {
+	p := parser{data: body}
+	if proto := p.string(); proto != "MQTT" {
+		return fmt.Errorf("unsupported protocol %q", proto)
+	}
+	_ = p.byte() // protocol level
+	flags := p.byte()
+	_ = p.uint16() // keep alive
+	c.id = p.string()
+	var will *Message
+	if flags&0x04 != 0 {
+		will = &Message{Topic: p.string(), Payload: p.string(), Retain: flags&0x20 != 0}
+	}
+	if p.err != nil {
+		return p.err
+	}
+
+	b.mu.Lock()
+	c.will = will
+	b.connects++
+	b.mu.Unlock()
+	c.write(typeConnack<<4, []byte{0, 0})
+	return nil
+}
+
+func (b *Broker) subscribe(c *client, body []byte) {
+	p := parser{data: body}
+	id := p.uint16()
+	var filters []string
+	for p.remaining() > 0 && p.err == nil {
+		filters = append(filters, p.string())
+		_ = p.byte() // requested QoS
+	}
+
+	c.wmu.Lock()
+	c.subs = append(c.subs, filters...)
+	c.wmu.Unlock()
+
+	ack := binary.BigEndian.AppendUint16(nil, id)
+	for range filters {
+		ack = append(ack, 0) // granted QoS 0
+	}
+	c.write(typeSuback<<4, ack)
+
+	b.mu.Lock()
+	var retained []Message
+	for topic, payload := range b.retained {
+		for _, f := range filters {
+			if Match(f, topic) {
+				retained = append(retained, Message{Topic: topic, Payload: payload, Retain: true})
+				break
+			}
+		}
+	}
+	b.mu.Unlock()
+	for _, m := range retained {
+		c.deliver(m)
+	}
+}
+
+func (b *Broker) unsubscribe(c *client, body []byte) {
+	p := parser{data: body}
+	id := p.uint16()
+	c.wmu.Lock()
+	for p.remaining() > 0 && p.err == nil {
+		filter := p.string()
+		for i, s := range c.subs {
+			if s == filter {
+				c.subs = append(c.subs[:i], c.subs[i+1:]...)
+				break
+			}
+		}
+	}
+	c.wmu.Unlock()
+	c.write(typeUnsuback<<4, binary.BigEndian.AppendUint16(nil, id))
+}
+
+// publish records m, updates the retained store and delivers m to every
+// matching subscriber.
+func (b *Broker) publish(m Message) {
+	b.mu.Lock()
+	b.messages = append(b.messages, m)
+	if m.Retain {
+		if m.Payload == "" {
+			delete(b.retained, m.Topic)
+		} else {
+			b.retained[m.Topic] = m.Payload
+		}
+	}
+	close(b.changed)
+	b.changed = make(chan struct{})
+	conns := make([]*client, 0, len(b.conns))
+	for c := range b.conns {
+		conns = append(conns, c)
+	}
+	b.mu.Unlock()
+
+	// Retain is only set on delivery for messages sent at subscribe time
+	m.Retain = false
+	for _, c := range conns {
+		c.wmu.Lock()
+		subscribed := false
+		for _, f := range c.subs {
+			if Match(f, m.Topic) {
+				subscribed = true
+				break
+			}
+		}
+		c.wmu.Unlock()
+		if subscribed {
+			c.deliver(m)
+		}
+	}
+}
+
+// deliver sends m to c at QoS 0.
+func (c *client) deliver(m Message) {
+	var flags byte
+	if m.Retain {
+		flags = 0x01
+	}
+	body := appendString(nil, m.Topic)
+	body = append(body, m.Payload...)
+	c.write(typePublish<<4|flags, body)
+}
+
+// write sends one packet, ignoring errors; a broken connection is noticed
+// by the read loop.
+func (c *client) write(header byte, body []byte) {
+	packet := []byte{header}
+	n := len(body)
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if n == 0 {
+			break
+		}
+	}
+	packet = append(packet, body...)
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_, _ = c.conn.Write(packet)
+}
+
+// Match reports whether topic matches filter, which may contain the + and #
+// wildcards.
+func Match(filter, topic string) bool {
+	fs := strings.Split(filter, "/")
+	ts := strings.Split(topic, "/")
+	for i, f := range fs {
+		if f == "#" {
+			return true
+		}
+		if i >= len(ts) {
+			return false
+		}
+		if f != "+" && f != ts[i] {
+			return false
+		}
+	}
+	return len(fs) == len(ts)
+}
+
+// readPacket reads one packet's fixed header byte and body.
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	var length, shift int
+	for {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length |= int(digit&0x7f) << shift
+		if digit&0x80 == 0 {
+			break
+		}
+		shift += 7
+		if shift > 21 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+func parsePublish(header byte, body []byte) (Message, uint16, byte, error) {
+	p := parser{data: body}
+	qos := (header >> 1) & 0x03
+	m := Message{Topic: p.string(), Retain: header&0x01 != 0}
+	var id uint16
+	if qos > 0 {
+		id = p.uint16()
+	}
+	if p.err != nil {
+		return Message{}, 0, 0, p.err
+	}
+	m.Payload = string(p.data[p.pos:])
+	return m, id, qos, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// parser reads MQTT fields from a packet body, recording the first error.
+type parser struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (p *parser) remaining() int {
+	return len(p.data) - p.pos
+}
+
+func (p *parser) byte() byte {
+	if p.err != nil || p.remaining() < 1 {
+		p.err = errors.New("short packet")
+		return 0
+	}
+	v := p.data[p.pos]
+	p.pos++
+	return v
+}
+
+func (p *parser) uint16() uint16 {
+	if p.err != nil || p.remaining() < 2 {
+		p.err = errors.New("short packet")
+		return 0
+	}
+	v := binary.BigEndian.Uint16(p.data[p.pos:])
+	p.pos += 2
+	return v
+}
+
+func (p *parser) string() string {
+	n := int(p.uint16())
+	if p.err != nil || p.remaining() < n {
+		p.err = errors.New("short packet")
+		return ""
+	}
+	s := string(p.data[p.pos : p.pos+n])
+	p.pos += n
+	return s
+}
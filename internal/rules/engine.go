@@ -0,0 +1,352 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/tail"
+)
+
+// Groups changes Sonos groups. sonos.Backend satisfies it.
+type Groups interface {
+	Join(ctx context.Context, speaker, target string) error
+	Leave(ctx context.Context, speaker string) (string, error)
+}
+
+// Options configures an Engine.
+type Options struct {
+	// Interval is how often each player is polled for changes.
+	Interval time.Duration
+
+	// RefreshInterval is how often the device list is refreshed, so new
+	// devices are watched without a restart.
+	RefreshInterval time.Duration
+
+	// Logger reports fired rules and failed actions. Nil means
+	// slog.Default().
+	Logger *slog.Logger
+}
+
+// Engine evaluates rules against events and runs their actions.
+type Engine struct {
+	rules    []*rule
+	backends core.DeviceResolver
+	groups   Groups
+	opts     Options
+
+	mu      sync.Mutex
+	pending map[string][]*time.Timer
+	watched map[string]bool
+}
+
+// New compiles rules. backends and groups may be nil when the engine is
+// only used for Check.
+func New(rules []Rule, backends core.DeviceResolver, groups Groups, opts Options) (*Engine, error) {
+	if opts.Interval == 0 {
+		opts.Interval = time.Second
+	}
+	if opts.RefreshInterval == 0 {
+		opts.RefreshInterval = 5 * time.Minute
+	}
+
+	e := &Engine{
+		backends: backends,
+		groups:   groups,
+		opts:     opts,
+		pending:  make(map[string][]*time.Timer),
+		watched:  make(map[string]bool),
+	}
+	names := make(map[string]bool)
+	for _, r := range rules {
+		c, err := compile(r)
+		if err != nil {
+			return nil, err
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("duplicate rule name: %s", r.Name)
+		}
+		names[r.Name] = true
+		e.rules = append(e.rules, c)
+	}
+	return e, nil
+}
+
+func (e *Engine) log() *slog.Logger {
+	if e.opts.Logger != nil {
+		return e.opts.Logger
+	}
+	return slog.Default()
+}
+
+// Rules returns the engine's rules in order.
+func (e *Engine) Rules() []Rule {
+	rules := make([]Rule, len(e.rules))
+	for i, r := range e.rules {
+		rules[i] = r.Rule
+	}
+	return rules
+}
+
+// Match returns the rules whose conditions hold for ev, ignoring For.
+func (e *Engine) Match(ev tail.Event) []Rule {
+	var matched []Rule
+	for _, r := range e.rules {
+		if r.matches(ev) {
+			matched = append(matched, r.Rule)
+		}
+	}
+	return matched
+}
+
+// Run watches every device and handles its events until ctx is done.
+func (e *Engine) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer e.cancelPending()
+
+	ticker := time.NewTicker(e.opts.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		devices, err := e.backends.Devices(ctx)
+		if err != nil && len(devices) == 0 {
+			e.log().WarnContext(ctx, "rules failed to list devices", "error", err)
+		}
+		for _, d := range devices {
+			e.watch(ctx, &wg, d)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// watch starts a watcher for d unless it already has one.
+func (e *Engine) watch(ctx context.Context, wg *sync.WaitGroup, d core.Device) {
+	key := string(d.Platform) + ":" + d.ID
+	e.mu.Lock()
+	known := e.watched[key]
+	e.watched[key] = true
+	e.mu.Unlock()
+	if known {
+		return
+	}
+
+	player, _, err := e.backends.PlayerFor(ctx, d.ID)
+	if err != nil {
+		e.log().WarnContext(ctx, "rules failed to open device", "device", d.Name, "error", err)
+		e.mu.Lock()
+		delete(e.watched, key)
+		e.mu.Unlock()
+		return
+	}
+
+	w := tail.NewWatcher(player, e.opts.Interval)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_ = w.Start(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		for ev := range w.Events() {
+			// Spotify players all report the account's playback; handle
+			// each event once, from the device it happened on
+			if ed := eventDevice(ev); ed != nil && ed.ID != d.ID {
+				continue
+			}
+			e.Handle(ctx, ev)
+		}
+	}()
+}
+
+// Handle evaluates ev and runs the actions of every matching rule, after
+// its For delay if it has one. Any event on a device cancels the delayed
+// rules it started earlier.
+func (e *Engine) Handle(ctx context.Context, ev tail.Event) {
+	key := deviceKey(ev)
+
+	e.mu.Lock()
+	for _, t := range e.pending[key] {
+		t.Stop()
+	}
+	delete(e.pending, key)
+	e.mu.Unlock()
+
+	for _, r := range e.rules {
+		if !r.matches(ev) {
+			continue
+		}
+		if r.For == 0 {
+			e.fire(ctx, r, ev)
+			continue
+		}
+
+		e.mu.Lock()
+		e.pending[key] = append(e.pending[key], time.AfterFunc(r.For, func() {
+			if ctx.Err() == nil {
+				e.fire(ctx, r, ev)
+			}
+		}))
+		e.mu.Unlock()
+	}
+}
+
+// cancelPending stops every delayed rule.
+func (e *Engine) cancelPending() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for key, timers := range e.pending {
+		for _, t := range timers {
+			t.Stop()
+		}
+		delete(e.pending, key)
+	}
+}
+
+// fire runs r's actions in order, stopping at the first failure.
+func (e *Engine) fire(ctx context.Context, r *rule, ev tail.Event) {
+	e.log().InfoContext(ctx, "rule fired", "rule", r.Name, "event", ev.Type.String())
+	for _, a := range r.Actions {
+		if err := e.do(ctx, a, ev); err != nil {
+			e.log().ErrorContext(ctx, "rule action failed", "rule", r.Name, "action", a.String(), "error", err)
+			return
+		}
+	}
+}
+
+// do runs one action.
+func (e *Engine) do(ctx context.Context, a Action, ev tail.Event) error {
+	target := a.Target
+	if target == "" {
+		d := eventDevice(ev)
+		if d == nil {
+			return errors.New("event has no device")
+		}
+		target = d.ID
+		if a.Do == "group" || a.Do == "ungroup" {
+			target = d.Name
+		}
+	}
+
+	switch a.Do {
+	case "group", "ungroup":
+		if e.groups == nil {
+			return errors.New("sonos is not available")
+		}
+		if a.Do == "group" {
+			return e.groups.Join(ctx, target, a.With)
+		}
+		_, err := e.groups.Leave(ctx, target)
+		return err
+	}
+
+	player, _, err := e.backends.PlayerFor(ctx, target)
+	if err != nil {
+		return err
+	}
+	switch a.Do {
+	case "play":
+		return player.Play(ctx)
+	case "pause":
+		return player.Pause(ctx)
+	case "next":
+		return player.Next(ctx)
+	case "previous":
+		return player.Prev(ctx)
+	case "volume":
+		return player.Volume(ctx, a.Volume)
+	case "volume_cap":
+		state, err := player.GetState(ctx)
+		if err != nil {
+			return err
+		}
+		if state.Volume > a.Volume {
+			return player.Volume(ctx, a.Volume)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown action: %s", a.Do)
+	}
+}
+
+// Firing is a rule that fired, or would fire, during Check.
+type Firing struct {
+	Rule    string     `json:"rule"`
+	Event   tail.Event `json:"event"`
+	At      time.Time  `json:"at"`
+	Actions []Action   `json:"actions"`
+
+	// Pending is set when the recording ended during the rule's For delay,
+	// so it fires only if nothing else happens on the device.
+	Pending bool `json:"pending,omitempty"`
+}
+
+// Check replays recorded events through the rules without running any
+// actions, and returns what would have fired, in order. For delays are
+// measured between event timestamps.
+func (e *Engine) Check(events []tail.Event) []Firing {
+	var fired []Firing
+	pending := make(map[string][]Firing)
+
+	// flush moves delayed firings due by now into fired
+	flush := func(now time.Time) {
+		for key, fs := range pending {
+			var rest []Firing
+			for _, f := range fs {
+				if !f.At.After(now) {
+					fired = append(fired, f)
+				} else {
+					rest = append(rest, f)
+				}
+			}
+			pending[key] = rest
+		}
+	}
+
+	for _, ev := range events {
+		flush(ev.Timestamp)
+		key := deviceKey(ev)
+		delete(pending, key)
+
+		for _, r := range e.rules {
+			if !r.matches(ev) {
+				continue
+			}
+			f := Firing{Rule: r.Name, Event: ev, At: ev.Timestamp.Add(r.For), Actions: r.Actions}
+			if r.For == 0 {
+				fired = append(fired, f)
+			} else {
+				pending[key] = append(pending[key], f)
+			}
+		}
+	}
+
+	var open []Firing
+	for _, fs := range pending {
+		for _, f := range fs {
+			f.Pending = true
+			open = append(open, f)
+		}
+	}
+	fired = append(fired, open...)
+	sort.SliceStable(fired, func(i, j int) bool {
+		if fired[i].Pending != fired[j].Pending {
+			return !fired[i].Pending
+		}
+		return fired[i].At.Before(fired[j].At)
+	})
+	return fired
+}
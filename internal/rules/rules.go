@@ -0,0 +1,261 @@
+// Package rules runs declarative automations on playback events, such as
+// "when the Office Spotify device starts playing after 18:00, cap volume at
+// 30" or "when the Kitchen pauses for 10 minutes, ungroup Patio".
+//
+// A rule matches a tail.Event when every condition it sets holds: the event
+// type, the device's name, ID or platform, whether it is playing, and the
+// local time of day and weekday of the event. A rule with a For duration
+// waits that long and fires only if nothing else happens on the device in
+// the meantime.
+package rules
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/tail"
+)
+
+// Rule is one automation.
+type Rule struct {
+	Name string
+
+	// Events are the event types that trigger the rule, such as "resume"
+	// or "pause". Empty matches every event.
+	Events []string
+
+	// Device is the name or ID of the device the event must come from.
+	// Empty matches every device.
+	Device string
+
+	// Platform limits the rule to devices on one platform, such as
+	// "spotify" or "sonos".
+	Platform string
+
+	// After and Before bound the local time of day ("18:00"). A window
+	// whose Before is earlier than its After wraps past midnight.
+	After  string
+	Before string
+
+	// Days limits the rule to these weekdays ("mon" ... "sun").
+	Days []string
+
+	// Playing, when set, requires the device to be playing (true) or not
+	// (false) after the event.
+	Playing *bool
+
+	// For delays the actions; they run only if the device has no further
+	// events for this long.
+	For time.Duration
+
+	Actions []Action
+}
+
+// Action is one thing a rule does.
+type Action struct {
+	// Do is play, pause, next, previous, volume, volume_cap, group or
+	// ungroup.
+	Do string `json:"do"`
+
+	// Target is the device to act on. Empty means the event's device.
+	Target string `json:"target,omitempty"`
+
+	// Volume is the level for volume, and the ceiling for volume_cap.
+	Volume int `json:"volume,omitempty"`
+
+	// With is the group coordinator that group joins Target to.
+	With string `json:"with,omitempty"`
+}
+
+// rule is a Rule with its conditions parsed.
+type rule struct {
+	Rule
+	events map[tail.EventType]bool
+	days   map[time.Weekday]bool
+	after  int // minutes past midnight, or -1
+	before int // minutes past midnight, or -1
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// compile parses r's conditions and checks its actions.
+func compile(r Rule) (*rule, error) {
+	if r.Name == "" {
+		return nil, fmt.Errorf("rule is missing a name")
+	}
+	if len(r.Actions) == 0 {
+		return nil, fmt.Errorf("rule %q: no actions", r.Name)
+	}
+
+	c := &rule{Rule: r}
+	if len(r.Events) > 0 {
+		c.events = make(map[tail.EventType]bool, len(r.Events))
+		for _, name := range r.Events {
+			t, err := tail.ParseEventType(name)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: %w", r.Name, err)
+			}
+			c.events[t] = true
+		}
+	}
+	if len(r.Days) > 0 {
+		c.days = make(map[time.Weekday]bool, len(r.Days))
+		for _, name := range r.Days {
+			key := strings.ToLower(name)
+			if len(key) > 3 {
+				key = key[:3] // "monday" -> "mon"
+			}
+			day, ok := weekdays[key]
+			if !ok {
+				return nil, fmt.Errorf("rule %q: unknown day: %s", r.Name, name)
+			}
+			c.days[day] = true
+		}
+	}
+
+	var err error
+	if c.after, err = parseClock(r.After); err != nil {
+		return nil, fmt.Errorf("rule %q: invalid after: %w", r.Name, err)
+	}
+	if c.before, err = parseClock(r.Before); err != nil {
+		return nil, fmt.Errorf("rule %q: invalid before: %w", r.Name, err)
+	}
+	if r.For < 0 {
+		return nil, fmt.Errorf("rule %q: for must be non-negative", r.Name)
+	}
+
+	for _, a := range r.Actions {
+		if err := a.validate(); err != nil {
+			return nil, fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+	}
+	return c, nil
+}
+
+// validate checks that a is a known action with the fields it needs.
+func (a Action) validate() error {
+	switch a.Do {
+	case "play", "pause", "next", "previous", "ungroup":
+		return nil
+	case "volume", "volume_cap":
+		if a.Volume < 0 || a.Volume > 100 {
+			return fmt.Errorf("%s: volume must be 0-100", a.Do)
+		}
+		return nil
+	case "group":
+		if a.With == "" {
+			return fmt.Errorf("group: missing with")
+		}
+		return nil
+	case "":
+		return fmt.Errorf("action is missing do")
+	default:
+		return fmt.Errorf("unknown action: %s", a.Do)
+	}
+}
+
+// String describes a, as in "volume_cap 30 on Office".
+func (a Action) String() string {
+	s := a.Do
+	switch a.Do {
+	case "volume", "volume_cap":
+		s += fmt.Sprintf(" %d", a.Volume)
+	}
+	target := a.Target
+	if target == "" {
+		target = "event device"
+	}
+	s += " on " + target
+	if a.Do == "group" {
+		s += " with " + a.With
+	}
+	return s
+}
+
+// parseClock parses "HH:MM" into minutes past midnight. Empty returns -1.
+func parseClock(s string) (int, error) {
+	if s == "" {
+		return -1, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// matches reports whether every condition of r holds for ev.
+func (r *rule) matches(ev tail.Event) bool {
+	if r.events != nil && !r.events[ev.Type] {
+		return false
+	}
+
+	d := eventDevice(ev)
+	if r.Device != "" {
+		if d == nil || (d.ID != r.Device && !strings.EqualFold(d.Name, r.Device)) {
+			return false
+		}
+	}
+	if r.Platform != "" {
+		if d == nil || !strings.EqualFold(string(d.Platform), r.Platform) {
+			return false
+		}
+	}
+	if r.Playing != nil {
+		if ev.Current == nil || ev.Current.IsPlaying != *r.Playing {
+			return false
+		}
+	}
+
+	at := ev.Timestamp.Local()
+	if r.days != nil && !r.days[at.Weekday()] {
+		return false
+	}
+	return r.inWindow(at.Hour()*60 + at.Minute())
+}
+
+// inWindow reports whether minute falls between After and Before.
+func (r *rule) inWindow(minute int) bool {
+	switch {
+	case r.after < 0 && r.before < 0:
+		return true
+	case r.before < 0:
+		return minute >= r.after
+	case r.after < 0:
+		return minute < r.before
+	case r.after <= r.before:
+		return minute >= r.after && minute < r.before
+	default:
+		return minute >= r.after || minute < r.before
+	}
+}
+
+// eventDevice returns the device an event happened on.
+func eventDevice(ev tail.Event) *core.Device {
+	if ev.Current != nil && ev.Current.Device != nil {
+		return ev.Current.Device
+	}
+	if ev.Previous != nil {
+		return ev.Previous.Device
+	}
+	return nil
+}
+
+// deviceKey identifies an event's device for delayed rules.
+func deviceKey(ev tail.Event) string {
+	d := eventDevice(ev)
+	if d == nil {
+		return ""
+	}
+	return string(d.Platform) + ":" + d.ID
+}
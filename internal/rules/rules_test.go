@@ -0,0 +1,235 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/core/coretest"
+	"github.com/tessro/riff/internal/tail"
+)
+
+var (
+	office  = &core.Device{ID: "spot-1", Name: "Office", Platform: core.PlatformSpotify}
+	kitchen = &core.Device{ID: "RINCON_1", Name: "Kitchen", Platform: core.PlatformSonos}
+)
+
+func at(hour, minute int) time.Time {
+	// 2026-01-05 is a Monday
+	return time.Date(2026, 1, 5, hour, minute, 0, 0, time.Local)
+}
+
+func event(t tail.EventType, d *core.Device, ts time.Time, playing bool) tail.Event {
+	return tail.Event{
+		Type:      t,
+		Timestamp: ts,
+		Current:   &core.PlaybackState{Device: d, IsPlaying: playing, Volume: 60},
+	}
+}
+
+func yes() *bool { b := true; return &b }
+
+func TestMatch(t *testing.T) {
+	evening := Rule{
+		Name:     "evening cap",
+		Events:   []string{"resume", "track_change"},
+		Device:   "office",
+		Platform: "spotify",
+		After:    "18:00",
+		Playing:  yes(),
+		Actions:  []Action{{Do: "volume_cap", Volume: 30}},
+	}
+	overnight := Rule{
+		Name:    "overnight",
+		After:   "22:00",
+		Before:  "06:00",
+		Days:    []string{"Monday"},
+		Actions: []Action{{Do: "pause"}},
+	}
+	e, err := New([]Rule{evening, overnight}, nil, nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		event tail.Event
+		want  []string
+	}{
+		{"office resumes in the evening", event(tail.EventResume, office, at(19, 0), true), []string{"evening cap"}},
+		{"office resumes in the afternoon", event(tail.EventResume, office, at(17, 59), true), nil},
+		{"office pauses in the evening", event(tail.EventPause, office, at(19, 0), false), nil},
+		{"kitchen resumes in the evening", event(tail.EventResume, kitchen, at(19, 0), true), nil},
+		{"late monday", event(tail.EventVolumeChange, kitchen, at(23, 0), true), []string{"overnight"}},
+		{"early monday", event(tail.EventVolumeChange, kitchen, at(5, 59), true), []string{"overnight"}},
+		{"monday morning", event(tail.EventVolumeChange, kitchen, at(6, 0), true), nil},
+		{"late tuesday", event(tail.EventVolumeChange, kitchen, at(23, 0).AddDate(0, 0, 1), true), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range e.Match(tt.event) {
+				got = append(got, r.Name)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRejectsBadRules(t *testing.T) {
+	pause := []Action{{Do: "pause"}}
+	tests := []Rule{
+		{Actions: pause},
+		{Name: "no actions"},
+		{Name: "bad event", Events: []string{"explode"}, Actions: pause},
+		{Name: "bad day", Days: []string{"someday"}, Actions: pause},
+		{Name: "bad time", After: "6pm", Actions: pause},
+		{Name: "bad action", Actions: []Action{{Do: "dance"}}},
+		{Name: "bad volume", Actions: []Action{{Do: "volume", Volume: 150}}},
+		{Name: "group without with", Actions: []Action{{Do: "group", Target: "Patio"}}},
+	}
+	for _, r := range tests {
+		if _, err := New([]Rule{r}, nil, nil, Options{}); err == nil {
+			t.Errorf("New(%q) succeeded, want error", r.Name)
+		}
+	}
+
+	if _, err := New([]Rule{{Name: "a", Actions: pause}, {Name: "a", Actions: pause}}, nil, nil, Options{}); err == nil {
+		t.Error("New() accepted duplicate names")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	idle := Rule{
+		Name:    "split patio",
+		Events:  []string{"pause"},
+		Device:  "Kitchen",
+		For:     10 * time.Minute,
+		Actions: []Action{{Do: "ungroup", Target: "Patio"}},
+	}
+	e, err := New([]Rule{idle}, nil, nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	firings := e.Check([]tail.Event{
+		event(tail.EventPause, kitchen, at(12, 0), false),
+		event(tail.EventResume, kitchen, at(12, 5), true), // cancels the first pause
+		event(tail.EventPause, kitchen, at(12, 10), false),
+		event(tail.EventResume, office, at(12, 15), true), // another device
+		event(tail.EventResume, kitchen, at(12, 30), true),
+		event(tail.EventPause, kitchen, at(12, 40), false),
+	})
+
+	if len(firings) != 2 {
+		t.Fatalf("got %d firings, want 2: %+v", len(firings), firings)
+	}
+	if !firings[0].At.Equal(at(12, 20)) || firings[0].Pending {
+		t.Errorf("first firing at %v (pending %v), want 12:20", firings[0].At, firings[0].Pending)
+	}
+	if !firings[1].At.Equal(at(12, 50)) || !firings[1].Pending {
+		t.Errorf("second firing at %v (pending %v), want pending at 12:50", firings[1].At, firings[1].Pending)
+	}
+}
+
+// fakeBackends resolves targets to players by ID.
+type fakeBackends struct {
+	players map[string]*coretest.Player
+}
+
+func (b *fakeBackends) Devices(ctx context.Context) ([]core.Device, error) { return nil, nil }
+
+func (b *fakeBackends) PlayerFor(ctx context.Context, target string) (core.Player, *core.Device, error) {
+	p, ok := b.players[target]
+	if !ok {
+		return nil, nil, fmt.Errorf("no device %s", target)
+	}
+	return p, nil, nil
+}
+
+// fakeGroups records group changes.
+type fakeGroups struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (g *fakeGroups) Join(ctx context.Context, speaker, target string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.calls = append(g.calls, "join "+speaker+" "+target)
+	return nil
+}
+
+func (g *fakeGroups) Leave(ctx context.Context, speaker string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.calls = append(g.calls, "leave "+speaker)
+	return "Kitchen", nil
+}
+
+func (g *fakeGroups) Calls() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return strings.Join(g.calls, "; ")
+}
+
+func TestHandleRunsActions(t *testing.T) {
+	player := coretest.NewPlayer(nil, 0)
+	player.Update(func(s *core.PlaybackState) { s.Volume = 60 })
+	backends := &fakeBackends{players: map[string]*coretest.Player{office.ID: player}}
+	e, err := New([]Rule{{
+		Name:    "cap",
+		Device:  "Office",
+		Actions: []Action{{Do: "volume_cap", Volume: 30}, {Do: "next"}},
+	}}, backends, nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e.Handle(context.Background(), event(tail.EventResume, office, time.Now(), true))
+	e.Handle(context.Background(), event(tail.EventVolumeChange, office, time.Now(), true))
+
+	// The cap applies once; at 30 the second event only skips
+	want := "[volume 30 next next]"
+	if got := fmt.Sprint(player.Calls()); got != want {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+}
+
+func TestHandleDelayedRule(t *testing.T) {
+	groups := &fakeGroups{}
+	e, err := New([]Rule{{
+		Name:    "split patio",
+		Events:  []string{"pause"},
+		Device:  "Kitchen",
+		For:     50 * time.Millisecond,
+		Actions: []Action{{Do: "ungroup", Target: "Patio"}},
+	}}, &fakeBackends{}, groups, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// A resume within the delay cancels it
+	e.Handle(ctx, event(tail.EventPause, kitchen, time.Now(), false))
+	e.Handle(ctx, event(tail.EventResume, kitchen, time.Now(), true))
+	time.Sleep(100 * time.Millisecond)
+	if got := groups.Calls(); got != "" {
+		t.Fatalf("cancelled rule fired: %s", got)
+	}
+
+	e.Handle(ctx, event(tail.EventPause, kitchen, time.Now(), false))
+	deadline := time.Now().Add(5 * time.Second)
+	for groups.Calls() == "" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := groups.Calls(); got != "leave Patio" {
+		t.Errorf("group calls = %q, want leave Patio", got)
+	}
+}
@@ -0,0 +1,475 @@
+// Package scene applies named multi-room setups: which rooms play, how
+// they are grouped, their volume and tone, and what they play.
+package scene
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/sonos"
+)
+
+// rollbackTimeout bounds undoing a scene that failed part way, which runs
+// even when the apply's context was cancelled.
+const rollbackTimeout = 30 * time.Second
+
+// Scene is a named setup of one or more rooms.
+type Scene struct {
+	Name string
+	// Rooms are the devices in the scene. The first one leads the group
+	// and is where the source starts.
+	Rooms []Room
+	// Group joins every room to the first one's group. Only Sonos rooms
+	// can be grouped.
+	Group bool
+	// Source is what to play, as written in the config. Apply plays it
+	// through Options.Play.
+	Source string
+	// Shuffle and Repeat ("off", "track" or "context") set the play mode
+	// when given.
+	Shuffle *bool
+	Repeat  string
+}
+
+// Room is one device in a scene. Unset fields are left alone.
+type Room struct {
+	Name     string
+	Volume   *int
+	Bass     *int
+	Treble   *int
+	Loudness *bool
+}
+
+func (r *Room) hasEQ() bool {
+	return r.Bass != nil || r.Treble != nil || r.Loudness != nil
+}
+
+// Groups changes Sonos groups. sonos.Backend satisfies it.
+type Groups interface {
+	Join(ctx context.Context, speaker, target string) error
+	Leave(ctx context.Context, speaker string) (string, error)
+	CoordinatorOf(ctx context.Context, speaker string) (string, error)
+}
+
+// EQController is implemented by players with tone controls, such as
+// sonos.Player.
+type EQController interface {
+	EQ(ctx context.Context) (*sonos.EQ, error)
+	SetEQ(ctx context.Context, eq sonos.EQ) error
+}
+
+// playModeReader is implemented by players that report their play mode
+// outside GetState, such as sonos.Player.
+type playModeReader interface {
+	PlayMode(ctx context.Context) (shuffle bool, repeat string, err error)
+}
+
+// Options configures Apply.
+type Options struct {
+	// Play starts the scene's source on a player. It runs on the first
+	// room, or on every room when the scene isn't grouped. nil leaves
+	// playback alone.
+	Play func(ctx context.Context, p core.Player) error
+
+	// Logger reports each step and any rollback at debug level. Nil means
+	// slog.Default().
+	Logger *slog.Logger
+}
+
+// room is a scene room resolved to its player, with what it was doing
+// before the scene was applied.
+type room struct {
+	Room
+	player  core.Player
+	device  *core.Device
+	state   *core.PlaybackState
+	eq      *sonos.EQ
+	shuffle bool
+	repeat  string
+	// coordinator is the room leading the group the room was in.
+	coordinator string
+}
+
+// applier runs one Apply, keeping the steps to undo should a later one
+// fail.
+type applier struct {
+	scene  *Scene
+	groups Groups
+	opts   Options
+
+	mu   sync.Mutex
+	undo []undoStep
+}
+
+type undoStep struct {
+	what string
+	fn   func(ctx context.Context) error
+}
+
+// Apply sets up s. Every room is resolved and its current settings read
+// before anything changes. Grouping, volume and tone are then set
+// concurrently, followed by the source and play mode. If any step fails,
+// the steps that succeeded are undone in reverse order: groups, volumes,
+// tone and play mode are restored and rooms that weren't playing are
+// paused again. The previous source isn't restored.
+func Apply(ctx context.Context, s *Scene, b core.PlayerResolver, g Groups, opts Options) error {
+	if len(s.Rooms) == 0 {
+		return fmt.Errorf("scene %s has no rooms", s.Name)
+	}
+	a := &applier{scene: s, groups: g, opts: opts}
+
+	rooms, err := a.resolve(ctx, b)
+	if err != nil {
+		return err
+	}
+
+	var steps []func(context.Context) error
+	if s.Group && len(rooms) > 1 {
+		steps = append(steps, func(ctx context.Context) error { return a.group(ctx, rooms) })
+	}
+	for _, r := range rooms {
+		if r.Volume != nil {
+			steps = append(steps, func(ctx context.Context) error { return a.volume(ctx, r) })
+		}
+		if r.hasEQ() {
+			steps = append(steps, func(ctx context.Context) error { return a.setEQ(ctx, r) })
+		}
+	}
+	if err := a.rollbackOnError(ctx, concurrently(ctx, steps)); err != nil {
+		return err
+	}
+
+	// Grouping moves rooms to new coordinators, so play on fresh players
+	targets := rooms
+	if s.Group || len(rooms) == 1 {
+		targets = rooms[:1]
+	}
+	steps = nil
+	for _, r := range targets {
+		steps = append(steps, func(ctx context.Context) error { return a.play(ctx, b, r) })
+	}
+	return a.rollbackOnError(ctx, concurrently(ctx, steps))
+}
+
+func (a *applier) log() *slog.Logger {
+	if a.opts.Logger != nil {
+		return a.opts.Logger
+	}
+	return slog.Default()
+}
+
+// done records how to undo a step that succeeded.
+func (a *applier) done(what string, undo func(ctx context.Context) error) {
+	a.log().Debug("scene step done", "scene", a.scene.Name, "step", what)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.undo = append(a.undo, undoStep{what: what, fn: undo})
+}
+
+// rollbackOnError undoes every completed step when err is non-nil, and
+// returns err along with any step that couldn't be undone.
+func (a *applier) rollbackOnError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	err = fmt.Errorf("scene %s: %w", a.scene.Name, err)
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+
+	errs := []error{err}
+	for _, u := range slices.Backward(a.undo) {
+		a.log().DebugContext(ctx, "undoing scene step", "scene", a.scene.Name, "step", u.what)
+		if uerr := u.fn(ctx); uerr != nil {
+			errs = append(errs, fmt.Errorf("failed to undo %s: %w", u.what, uerr))
+		}
+	}
+	a.undo = nil
+	return errors.Join(errs...)
+}
+
+// resolve finds every room's player and reads what it is doing now.
+func (a *applier) resolve(ctx context.Context, b core.PlayerResolver) ([]*room, error) {
+	rooms := make([]*room, len(a.scene.Rooms))
+	steps := make([]func(context.Context) error, len(rooms))
+	for i, cfg := range a.scene.Rooms {
+		steps[i] = func(ctx context.Context) error {
+			r, err := a.read(ctx, b, cfg)
+			rooms[i] = r
+			return err
+		}
+	}
+	if err := concurrently(ctx, steps); err != nil {
+		return nil, fmt.Errorf("scene %s: %w", a.scene.Name, err)
+	}
+	return rooms, nil
+}
+
+func (a *applier) read(ctx context.Context, b core.PlayerResolver, cfg Room) (*room, error) {
+	p, d, err := b.PlayerFor(ctx, cfg.Name)
+	if err != nil {
+		return nil, err
+	}
+	r := &room{Room: cfg, player: p, device: d}
+	if r.state, err = p.GetState(ctx); err != nil {
+		return nil, fmt.Errorf("%s: failed to get playback state: %w", cfg.Name, err)
+	}
+	r.shuffle, r.repeat = r.state.Shuffle, r.state.Repeat
+	if m, ok := p.(playModeReader); ok && a.setsPlayMode() {
+		if r.shuffle, r.repeat, err = m.PlayMode(ctx); err != nil {
+			return nil, fmt.Errorf("%s: failed to get play mode: %w", cfg.Name, err)
+		}
+	}
+
+	if cfg.hasEQ() {
+		eqc, ok := p.(EQController)
+		if !ok {
+			return nil, fmt.Errorf("%s: only Sonos speakers have bass, treble and loudness", cfg.Name)
+		}
+		if r.eq, err = eqc.EQ(ctx); err != nil {
+			return nil, fmt.Errorf("%s: failed to get tone controls: %w", cfg.Name, err)
+		}
+	}
+
+	if a.scene.Group && len(a.scene.Rooms) > 1 {
+		if a.groups == nil || d == nil || d.Platform != core.PlatformSonos {
+			return nil, fmt.Errorf("%s: only Sonos rooms can be grouped", cfg.Name)
+		}
+		if r.coordinator, err = a.groups.CoordinatorOf(ctx, cfg.Name); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (a *applier) setsPlayMode() bool {
+	return a.scene.Shuffle != nil || a.scene.Repeat != ""
+}
+
+// group makes the first room lead a group and joins the others to it.
+func (a *applier) group(ctx context.Context, rooms []*room) error {
+	leader := rooms[0]
+	if !strings.EqualFold(leader.coordinator, leader.Name) {
+		if _, err := a.groups.Leave(ctx, leader.Name); err != nil {
+			return err
+		}
+		a.done("ungrouped "+leader.Name, func(ctx context.Context) error {
+			return a.groups.Join(ctx, leader.Name, leader.coordinator)
+		})
+	}
+
+	var steps []func(context.Context) error
+	for _, r := range rooms[1:] {
+		if strings.EqualFold(r.coordinator, leader.Name) {
+			continue
+		}
+		steps = append(steps, func(ctx context.Context) error {
+			if err := a.groups.Join(ctx, r.Name, leader.Name); err != nil {
+				return err
+			}
+			a.done(fmt.Sprintf("grouped %s with %s", r.Name, leader.Name), func(ctx context.Context) error {
+				if strings.EqualFold(r.coordinator, r.Name) {
+					_, err := a.groups.Leave(ctx, r.Name)
+					return err
+				}
+				return a.groups.Join(ctx, r.Name, r.coordinator)
+			})
+			return nil
+		})
+	}
+	return concurrently(ctx, steps)
+}
+
+func (a *applier) volume(ctx context.Context, r *room) error {
+	if err := r.player.Volume(ctx, *r.Volume); err != nil {
+		return fmt.Errorf("%s: failed to set volume: %w", r.Name, err)
+	}
+	a.done(fmt.Sprintf("set %s to %d%%", r.Name, *r.Volume), func(ctx context.Context) error {
+		return r.player.Volume(ctx, r.state.Volume)
+	})
+	return nil
+}
+
+func (a *applier) setEQ(ctx context.Context, r *room) error {
+	eq := *r.eq
+	if r.Bass != nil {
+		eq.Bass = *r.Bass
+	}
+	if r.Treble != nil {
+		eq.Treble = *r.Treble
+	}
+	if r.Loudness != nil {
+		eq.Loudness = *r.Loudness
+	}
+	eqc := r.player.(EQController)
+	if err := eqc.SetEQ(ctx, eq); err != nil {
+		return fmt.Errorf("%s: failed to set tone controls: %w", r.Name, err)
+	}
+	a.done("set tone controls on "+r.Name, func(ctx context.Context) error {
+		return eqc.SetEQ(ctx, *r.eq)
+	})
+	return nil
+}
+
+// play starts the source on r and sets the play mode.
+func (a *applier) play(ctx context.Context, b core.PlayerResolver, r *room) error {
+	p, _, err := b.PlayerFor(ctx, r.Name)
+	if err != nil {
+		return err
+	}
+
+	if a.opts.Play != nil {
+		if err := a.opts.Play(ctx, p); err != nil {
+			return fmt.Errorf("%s: failed to play: %w", r.Name, err)
+		}
+		a.done("started playback on "+r.Name, func(ctx context.Context) error {
+			if r.state.IsPlaying {
+				return nil
+			}
+			return p.Pause(ctx)
+		})
+	}
+
+	if !a.setsPlayMode() {
+		return nil
+	}
+	var caps core.Capability
+	if a.scene.Shuffle != nil {
+		caps |= core.CapShuffle
+	}
+	if a.scene.Repeat != "" {
+		caps |= core.CapRepeat
+	}
+	if err := core.Require(p, caps); err != nil {
+		return err
+	}
+	c := p.(core.PlayModeController)
+	if a.scene.Shuffle != nil {
+		if err := c.Shuffle(ctx, *a.scene.Shuffle); err != nil {
+			return fmt.Errorf("%s: failed to set shuffle: %w", r.Name, err)
+		}
+		a.done("set shuffle on "+r.Name, func(ctx context.Context) error {
+			return c.Shuffle(ctx, r.shuffle)
+		})
+	}
+	if a.scene.Repeat != "" {
+		if err := c.Repeat(ctx, a.scene.Repeat); err != nil {
+			return fmt.Errorf("%s: failed to set repeat: %w", r.Name, err)
+		}
+		a.done("set repeat on "+r.Name, func(ctx context.Context) error {
+			return c.Repeat(ctx, cmp.Or(r.repeat, "off"))
+		})
+	}
+	return nil
+}
+
+// concurrently runs steps in parallel and joins their errors.
+func concurrently(ctx context.Context, steps []func(context.Context) error) error {
+	errs := make([]error, len(steps))
+	var wg sync.WaitGroup
+	for i, step := range steps {
+		wg.Go(func() { errs[i] = step(ctx) })
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Capture reads the current setup of the named rooms into a scene. Rooms
+// that share a Sonos group are captured as grouped, led by the group's
+// coordinator. g may be nil when Sonos isn't available. The source is only
+// captured for stream URLs, since other sources can't be read back as
+// something to play later.
+func Capture(ctx context.Context, name string, names []string, b core.PlayerResolver, g Groups) (*Scene, error) {
+	if len(names) == 0 {
+		return nil, errors.New("no rooms to capture")
+	}
+	s := &Scene{Name: name}
+
+	rooms := make([]*room, len(names))
+	steps := make([]func(context.Context) error, len(names))
+	for i, n := range names {
+		steps[i] = func(ctx context.Context) error {
+			r, err := capture(ctx, b, g, n)
+			rooms[i] = r
+			return err
+		}
+	}
+	if err := concurrently(ctx, steps); err != nil {
+		return nil, fmt.Errorf("scene %s: %w", name, err)
+	}
+
+	// One shared coordinator means one group, led by it
+	leader := rooms[0].coordinator
+	s.Group = len(rooms) > 1 && leader != ""
+	for _, r := range rooms {
+		if !strings.EqualFold(r.coordinator, leader) {
+			s.Group = false
+		}
+	}
+	if i := slices.IndexFunc(rooms, func(r *room) bool { return strings.EqualFold(r.Name, leader) }); s.Group && i > 0 {
+		lead := rooms[i]
+		rooms = append([]*room{lead}, slices.Delete(rooms, i, i+1)...)
+	}
+
+	for _, r := range rooms {
+		s.Rooms = append(s.Rooms, r.Room)
+	}
+	lead := rooms[0]
+	if lead.player.Capabilities().Has(core.CapShuffle) {
+		s.Shuffle = &lead.shuffle
+	}
+	if lead.player.Capabilities().Has(core.CapRepeat) {
+		s.Repeat = cmp.Or(lead.repeat, "off")
+	}
+	if lead.state.HasTrack() {
+		if uri := lead.state.Track.URI; strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://") {
+			s.Source = uri
+		}
+	}
+	return s, nil
+}
+
+// capture reads one room's volume, tone, play mode and group.
+func capture(ctx context.Context, b core.PlayerResolver, g Groups, name string) (*room, error) {
+	p, d, err := b.PlayerFor(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	r := &room{player: p, device: d}
+	if d != nil {
+		name = d.Name
+	}
+	if r.state, err = p.GetState(ctx); err != nil {
+		return nil, fmt.Errorf("%s: failed to get playback state: %w", name, err)
+	}
+	volume := r.state.Volume
+	r.Room = Room{Name: name, Volume: &volume}
+
+	r.shuffle, r.repeat = r.state.Shuffle, r.state.Repeat
+	if m, ok := p.(playModeReader); ok {
+		if r.shuffle, r.repeat, err = m.PlayMode(ctx); err != nil {
+			return nil, fmt.Errorf("%s: failed to get play mode: %w", name, err)
+		}
+	}
+	if eqc, ok := p.(EQController); ok {
+		eq, err := eqc.EQ(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to get tone controls: %w", name, err)
+		}
+		r.Bass, r.Treble, r.Loudness = &eq.Bass, &eq.Treble, &eq.Loudness
+	}
+	if g != nil && d != nil && d.Platform == core.PlatformSonos {
+		if r.coordinator, err = g.CoordinatorOf(ctx, name); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
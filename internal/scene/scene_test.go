@@ -0,0 +1,336 @@
+package scene
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/sonos"
+)
+
+// household is a fake set of Sonos rooms and one Spotify device. Every
+// call that changes something is recorded.
+type household struct {
+	mu           sync.Mutex
+	calls        []string
+	volumes      map[string]int
+	eqs          map[string]sonos.EQ
+	coordinators map[string]string
+	playing      map[string]bool
+	shuffle      map[string]bool
+	failJoin     string
+}
+
+func newHousehold() *household {
+	return &household{
+		volumes:      map[string]int{"Dining Room": 10, "Kitchen": 20, "Patio": 30, "Laptop": 40},
+		eqs:          map[string]sonos.EQ{"Dining Room": {}, "Kitchen": {}, "Patio": {}},
+		coordinators: map[string]string{"Dining Room": "Dining Room", "Kitchen": "Kitchen", "Patio": "Patio"},
+		playing:      map[string]bool{},
+		shuffle:      map[string]bool{},
+	}
+}
+
+func (h *household) record(format string, args ...any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.calls = append(h.calls, fmt.Sprintf(format, args...))
+}
+
+func (h *household) Calls() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	calls := slices.Clone(h.calls)
+	slices.Sort(calls) // steps run concurrently
+	return calls
+}
+
+func (h *household) PlayerFor(ctx context.Context, target string) (core.Player, *core.Device, error) {
+	platform := core.PlatformSonos
+	if target == "Laptop" {
+		platform = core.PlatformSpotify
+	}
+	if _, ok := h.volumes[target]; !ok {
+		return nil, nil, fmt.Errorf("device '%s' not found", target)
+	}
+	return &player{h: h, name: target}, &core.Device{ID: target, Name: target, Platform: platform}, nil
+}
+
+func (h *household) Join(ctx context.Context, speaker, target string) error {
+	if speaker == h.failJoin {
+		return fmt.Errorf("failed to add %s to group", speaker)
+	}
+	h.record("join %s %s", speaker, target)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.coordinators[speaker] = target
+	return nil
+}
+
+func (h *household) Leave(ctx context.Context, speaker string) (string, error) {
+	h.record("leave %s", speaker)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.coordinators[speaker] = speaker
+	return speaker, nil
+}
+
+func (h *household) CoordinatorOf(ctx context.Context, speaker string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.coordinators[speaker], nil
+}
+
+// player is a room in the household. Sonos rooms have tone controls and
+// play modes; the Spotify device only has volume.
+type player struct {
+	h    *household
+	name string
+}
+
+func (p *player) Play(ctx context.Context) error { return nil }
+
+func (p *player) Pause(ctx context.Context) error {
+	p.h.record("pause %s", p.name)
+	return nil
+}
+
+func (p *player) Next(ctx context.Context) error                   { return nil }
+func (p *player) Prev(ctx context.Context) error                   { return nil }
+func (p *player) Seek(ctx context.Context, positionMs int) error   { return nil }
+func (p *player) AddToQueue(ctx context.Context, uri string) error { return nil }
+
+func (p *player) Volume(ctx context.Context, percent int) error {
+	p.h.record("volume %s %d", p.name, percent)
+	p.h.mu.Lock()
+	defer p.h.mu.Unlock()
+	p.h.volumes[p.name] = percent
+	return nil
+}
+
+func (p *player) GetState(ctx context.Context) (*core.PlaybackState, error) {
+	p.h.mu.Lock()
+	defer p.h.mu.Unlock()
+	return &core.PlaybackState{
+		Device:    &core.Device{ID: p.name, Name: p.name},
+		IsPlaying: p.h.playing[p.name],
+		Volume:    p.h.volumes[p.name],
+	}, nil
+}
+
+func (p *player) GetQueue(ctx context.Context) (*core.Queue, error) { return &core.Queue{}, nil }
+func (p *player) GetRecentlyPlayed(ctx context.Context, limit int) ([]core.HistoryEntry, error) {
+	return nil, nil
+}
+
+func (p *player) Capabilities() core.Capability {
+	if p.name == "Laptop" {
+		return 0
+	}
+	return core.CapShuffle | core.CapRepeat
+}
+
+func (p *player) Shuffle(ctx context.Context, state bool) error {
+	p.h.record("shuffle %s %v", p.name, state)
+	p.h.mu.Lock()
+	defer p.h.mu.Unlock()
+	p.h.shuffle[p.name] = state
+	return nil
+}
+
+func (p *player) Repeat(ctx context.Context, mode string) error { return nil }
+
+func (p *player) PlayMode(ctx context.Context) (bool, string, error) {
+	p.h.mu.Lock()
+	defer p.h.mu.Unlock()
+	return p.h.shuffle[p.name], "off", nil
+}
+
+func (p *player) EQ(ctx context.Context) (*sonos.EQ, error) {
+	p.h.mu.Lock()
+	defer p.h.mu.Unlock()
+	eq, ok := p.h.eqs[p.name]
+	if !ok {
+		return nil, errors.New("no tone controls")
+	}
+	return &eq, nil
+}
+
+func (p *player) SetEQ(ctx context.Context, eq sonos.EQ) error {
+	p.h.record("eq %s %+v", p.name, eq)
+	p.h.mu.Lock()
+	defer p.h.mu.Unlock()
+	p.h.eqs[p.name] = eq
+	return nil
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func dinner() *Scene {
+	return &Scene{
+		Name: "dinner",
+		Rooms: []Room{
+			{Name: "Dining Room", Volume: ptr(25), Bass: ptr(3)},
+			{Name: "Kitchen", Volume: ptr(15)},
+			{Name: "Patio"},
+		},
+		Group:   true,
+		Shuffle: ptr(true),
+	}
+}
+
+func TestApply(t *testing.T) {
+	h := newHousehold()
+	var played []string
+	opts := Options{Play: func(ctx context.Context, p core.Player) error {
+		played = append(played, p.(*player).name)
+		return nil
+	}}
+
+	if err := Apply(context.Background(), dinner(), h, h, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"eq Dining Room {Bass:3 Treble:0 Loudness:false}",
+		"join Kitchen Dining Room",
+		"join Patio Dining Room",
+		"shuffle Dining Room true",
+		"volume Dining Room 25",
+		"volume Kitchen 15",
+	}
+	if got := h.Calls(); !slices.Equal(got, want) {
+		t.Errorf("calls = %q\nwant %q", got, want)
+	}
+	if !slices.Equal(played, []string{"Dining Room"}) {
+		t.Errorf("played on %v, want only the leader", played)
+	}
+}
+
+func TestApplyUngrouped(t *testing.T) {
+	h := newHousehold()
+	s := &Scene{Name: "quiet", Rooms: []Room{{Name: "Kitchen"}, {Name: "Laptop", Volume: ptr(5)}}}
+	var played []string
+	var mu sync.Mutex
+	opts := Options{Play: func(ctx context.Context, p core.Player) error {
+		mu.Lock()
+		defer mu.Unlock()
+		played = append(played, p.(*player).name)
+		return nil
+	}}
+
+	if err := Apply(context.Background(), s, h, h, opts); err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(played)
+	if !slices.Equal(played, []string{"Kitchen", "Laptop"}) {
+		t.Errorf("played on %v, want every room", played)
+	}
+}
+
+func TestApplyRollback(t *testing.T) {
+	h := newHousehold()
+	h.failJoin = "Patio"
+	played := false
+	opts := Options{Play: func(ctx context.Context, p core.Player) error {
+		played = true
+		return nil
+	}}
+
+	err := Apply(context.Background(), dinner(), h, h, opts)
+	if err == nil || !strings.Contains(err.Error(), "failed to add Patio to group") {
+		t.Fatalf("Apply() = %v, want the join failure", err)
+	}
+	if played {
+		t.Error("source played after a failed step")
+	}
+
+	// Everything that worked was put back
+	if h.volumes["Dining Room"] != 10 || h.volumes["Kitchen"] != 20 {
+		t.Errorf("volumes = %v, want them restored", h.volumes)
+	}
+	if h.eqs["Dining Room"].Bass != 0 {
+		t.Errorf("Dining Room bass = %d, want it restored", h.eqs["Dining Room"].Bass)
+	}
+	if h.coordinators["Kitchen"] != "Kitchen" {
+		t.Errorf("Kitchen is still grouped with %s", h.coordinators["Kitchen"])
+	}
+}
+
+func TestApplyPlayFailure(t *testing.T) {
+	h := newHousehold()
+	opts := Options{Play: func(ctx context.Context, p core.Player) error {
+		return errors.New("no such playlist")
+	}}
+
+	s := dinner()
+	s.Group = false
+	s.Rooms = s.Rooms[:1]
+	if err := Apply(context.Background(), s, h, h, opts); err == nil {
+		t.Fatal("Apply() succeeded, want the play failure")
+	}
+	if h.volumes["Dining Room"] != 10 || h.eqs["Dining Room"].Bass != 0 {
+		t.Errorf("Dining Room wasn't restored: volume %d, eq %+v", h.volumes["Dining Room"], h.eqs["Dining Room"])
+	}
+	if h.shuffle["Dining Room"] {
+		t.Error("shuffle was set after the source failed")
+	}
+}
+
+func TestApplyChecksFirst(t *testing.T) {
+	tests := []struct {
+		name  string
+		scene *Scene
+	}{
+		{"grouping Spotify", &Scene{Name: "x", Group: true, Rooms: []Room{{Name: "Kitchen", Volume: ptr(50)}, {Name: "Laptop"}}}},
+		{"tone on Spotify", &Scene{Name: "x", Rooms: []Room{{Name: "Kitchen", Volume: ptr(50)}, {Name: "Laptop", Treble: ptr(2)}}}},
+		{"unknown room", &Scene{Name: "x", Rooms: []Room{{Name: "Kitchen", Volume: ptr(50)}, {Name: "Attic"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHousehold()
+			if err := Apply(context.Background(), tt.scene, h, h, Options{}); err == nil {
+				t.Fatal("Apply() succeeded, want an error")
+			}
+			if calls := h.Calls(); len(calls) != 0 {
+				t.Errorf("Apply() changed things before failing: %v", calls)
+			}
+		})
+	}
+}
+
+func TestCapture(t *testing.T) {
+	h := newHousehold()
+	h.coordinators["Kitchen"] = "Dining Room"
+	h.shuffle["Dining Room"] = true
+	h.eqs["Kitchen"] = sonos.EQ{Bass: -2, Loudness: true}
+
+	s, err := Capture(context.Background(), "dinner", []string{"Kitchen", "Dining Room"}, h, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Group || len(s.Rooms) != 2 || s.Rooms[0].Name != "Dining Room" {
+		t.Fatalf("Capture() = %+v, want Dining Room leading a group", s)
+	}
+	kitchen := s.Rooms[1]
+	if *kitchen.Volume != 20 || *kitchen.Bass != -2 || !*kitchen.Loudness {
+		t.Errorf("Kitchen = volume %d, bass %d, loudness %v", *kitchen.Volume, *kitchen.Bass, *kitchen.Loudness)
+	}
+	if s.Shuffle == nil || !*s.Shuffle || s.Repeat != "off" {
+		t.Errorf("play mode = %v, %q; want shuffle on, repeat off", s.Shuffle, s.Repeat)
+	}
+
+	// Rooms in different groups aren't grouped
+	s, err = Capture(context.Background(), "apart", []string{"Patio", "Kitchen"}, h, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Group || s.Rooms[0].Name != "Patio" {
+		t.Errorf("Capture() = %+v, want ungrouped rooms in order", s)
+	}
+}
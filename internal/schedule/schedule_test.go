@@ -0,0 +1,267 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/core/coretest"
+)
+
+func TestParseSpec(t *testing.T) {
+	valid := []string{
+		"07:00",
+		"07:00 daily",
+		"07:00 weekdays",
+		"22:30 fri,sat",
+		"22:30 Friday Saturday",
+		"06:15 mon-wed",
+		"30 6 * * 1-5",
+		"*/15 * * * *",
+		"0 9 1,15 * sun",
+		"0 9 * * 7",
+	}
+	for _, s := range valid {
+		if _, err := ParseSpec(s); err != nil {
+			t.Errorf("ParseSpec(%q) error: %v", s, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"7am",
+		"25:00",
+		"07:00 someday",
+		"60 * * * *",
+		"* * * *  * *",
+		"0 9 * * fri-mon",
+		"*/0 * * * *",
+	}
+	for _, s := range invalid {
+		if _, err := ParseSpec(s); err == nil {
+			t.Errorf("ParseSpec(%q) succeeded, want error", s)
+		}
+	}
+}
+
+func TestSpecNext(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone data unavailable")
+	}
+
+	// 2026-01-09 is a Friday
+	friday := time.Date(2026, 1, 9, 8, 0, 0, 0, ny)
+	tests := []struct {
+		spec  string
+		after time.Time
+		want  time.Time
+	}{
+		{"07:00 weekdays", friday, time.Date(2026, 1, 12, 7, 0, 0, 0, ny)},
+		{"07:00 weekdays", friday.Add(-2 * time.Hour), time.Date(2026, 1, 9, 7, 0, 0, 0, ny)},
+		{"09:00 weekends", friday, time.Date(2026, 1, 10, 9, 0, 0, 0, ny)},
+		{"08:00", friday, time.Date(2026, 1, 10, 8, 0, 0, 0, ny)},
+		{"*/20 8 * * *", friday, time.Date(2026, 1, 9, 8, 20, 0, 0, ny)},
+		{"0 12 1 * *", friday, time.Date(2026, 2, 1, 12, 0, 0, 0, ny)},
+		// Day of month or day of week when both are restricted
+		{"0 12 13 * mon", friday, time.Date(2026, 1, 12, 12, 0, 0, 0, ny)},
+		// 02:30 doesn't exist on 2026-03-08 and normalizes past the gap
+		{"30 2 8 3 *", friday, time.Date(2026, 3, 8, 3, 30, 0, 0, ny)},
+		{"0 0 31 2 *", friday, time.Time{}},
+	}
+	for _, tt := range tests {
+		spec, err := ParseSpec(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseSpec(%q) error: %v", tt.spec, err)
+		}
+		if got := spec.Next(tt.after, ny); !got.Equal(tt.want) {
+			t.Errorf("%q after %v = %v, want %v", tt.spec, tt.after, got, tt.want)
+		}
+	}
+
+	// The same instant is read in the job's zone, not the caller's
+	spec, _ := ParseSpec("07:00")
+	got := spec.Next(friday.UTC(), ny)
+	if want := time.Date(2026, 1, 10, 7, 0, 0, 0, ny); !got.Equal(want) {
+		t.Errorf("Next() in UTC = %v, want %v", got, want)
+	}
+}
+
+func TestStore(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "riff", "schedule.json"))
+
+	jobs, err := store.Load()
+	if err != nil || len(jobs) != 0 {
+		t.Fatalf("Load() on missing file = %v, %v", jobs, err)
+	}
+
+	for _, spec := range []string{"07:00 weekdays", "09:00 weekends", "22:00"} {
+		if _, err := store.Add(Job{Spec: spec}); err != nil {
+			t.Fatalf("Add(%q) error: %v", spec, err)
+		}
+	}
+	if _, err := store.Add(Job{Spec: "7am"}); err == nil {
+		t.Error("Add() accepted an invalid spec")
+	}
+	if _, err := store.Add(Job{Spec: "07:00", Missed: "panic"}); err == nil {
+		t.Error("Add() accepted an invalid policy")
+	}
+	if _, err := store.Add(Job{Spec: "07:00", TZ: "Mars/Olympus_Mons"}); err == nil {
+		t.Error("Add() accepted an invalid time zone")
+	}
+
+	if err := store.Remove(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Remove(2); err == nil {
+		t.Error("Remove() of a missing job succeeded")
+	}
+	job, err := store.Add(Job{Spec: "12:00"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ID != 4 {
+		t.Errorf("new job ID = %d, want 4", job.ID)
+	}
+
+	jobs, err = store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, j := range jobs {
+		ids = append(ids, j.ID)
+	}
+	if fmt.Sprint(ids) != "[1 3 4]" {
+		t.Errorf("IDs = %v, want [1 3 4]", ids)
+	}
+}
+
+func TestDue(t *testing.T) {
+	created := time.Date(2026, 1, 5, 0, 0, 0, 0, time.Local)
+	skip := Job{ID: 1, Spec: "07:00", Created: created}
+	run := Job{ID: 2, Spec: "07:00", Missed: MissedRun, Created: created}
+	jobs := []Job{skip, run}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{"before the run", time.Date(2026, 1, 5, 6, 59, 0, 0, time.Local), "[]"},
+		{"on time", time.Date(2026, 1, 5, 7, 0, 5, 0, time.Local), "[1 2]"},
+		{"within grace", time.Date(2026, 1, 5, 7, 9, 0, 0, time.Local), "[1 2]"},
+		{"missed", time.Date(2026, 1, 5, 9, 0, 0, 0, time.Local), "[2]"},
+		{"missed for days", time.Date(2026, 1, 8, 9, 0, 0, 0, time.Local), "[2]"},
+		// The last of several missed runs is recent enough to fire normally
+		{"woke just after a run", time.Date(2026, 1, 8, 7, 1, 0, 0, time.Local), "[1 2]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(nil, nil, Options{})
+			var ids []int
+			for _, j := range s.due(jobs, tt.now) {
+				ids = append(ids, j.ID)
+			}
+			if got := fmt.Sprint(ids); got != tt.want {
+				t.Errorf("due() = %v, want %v", got, tt.want)
+			}
+
+			// Once checked, nothing is due again until the next run
+			if again := s.due(jobs, tt.now.Add(30*time.Second)); len(again) != 0 {
+				t.Errorf("due() fired %d jobs twice", len(again))
+			}
+		})
+	}
+}
+
+// streamPlayer adds stream playback to the fake player.
+type streamPlayer struct {
+	*coretest.Player
+}
+
+func (p streamPlayer) PlayURI(ctx context.Context, uri string) error { return p.Record("play " + uri) }
+
+type fakeBackends struct {
+	player   streamPlayer
+	platform core.Platform
+}
+
+func (b *fakeBackends) PlayerFor(ctx context.Context, target string) (core.Player, *core.Device, error) {
+	return b.player, &core.Device{Name: target, Platform: b.platform}, nil
+}
+
+func TestFireVolumeOrder(t *testing.T) {
+	tests := []struct {
+		platform core.Platform
+		want     string
+	}{
+		// Spotify ignores the volume of a device that isn't playing yet
+		{core.PlatformSpotify, "[play volume 10]"},
+		{core.PlatformSonos, "[volume 10 play]"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.platform), func(t *testing.T) {
+			player := coretest.NewPlayer(nil, 0)
+			s := New(NewStore(filepath.Join(t.TempDir(), "schedule.json")),
+				&fakeBackends{player: streamPlayer{player}, platform: tt.platform}, Options{})
+
+			s.fire(t.Context(), Job{ID: 1, Target: "Bedroom", Volume: &VolumeRange{From: 10, To: 10}})
+			if got := fmt.Sprint(player.Calls()); got != tt.want {
+				t.Errorf("calls = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunFiresJobs(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(filepath.Join(dir, "schedule.json"))
+	created := time.Date(2026, 1, 5, 6, 0, 0, 0, time.Local)
+	if _, err := store.Add(Job{
+		Spec:    "07:00",
+		URI:     "http://radio.example/live.mp3",
+		Target:  "Bedroom",
+		Volume:  &VolumeRange{From: 10, To: 10},
+		Created: created,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	player := coretest.NewPlayer(nil, core.CapStream)
+	s := New(store, &fakeBackends{player: streamPlayer{player}}, Options{StatePath: filepath.Join(dir, "state.json")})
+
+	// Step the clock past 07:00, then stop
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clock := []time.Time{
+		time.Date(2026, 1, 5, 6, 59, 0, 0, time.Local),
+		time.Date(2026, 1, 5, 7, 0, 1, 0, time.Local),
+		time.Date(2026, 1, 5, 7, 0, 31, 0, time.Local),
+	}
+	s.now = func() time.Time { return clock[0] }
+	s.sleep = func(ctx context.Context, d time.Duration) {
+		if clock = clock[1:]; len(clock) == 0 {
+			cancel()
+		}
+	}
+	if err := s.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "[volume 10 play http://radio.example/live.mp3]"
+	if got := fmt.Sprint(player.Calls()); got != want {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+
+	// A restarted scheduler remembers the run
+	s = New(store, &fakeBackends{player: streamPlayer{player}}, Options{StatePath: filepath.Join(dir, "state.json")})
+	s.loadState()
+	jobs, _ := store.Load()
+	if due := s.due(jobs, time.Date(2026, 1, 5, 7, 5, 0, 0, time.Local)); len(due) != 0 {
+		t.Errorf("restarted scheduler fired %d jobs again", len(due))
+	}
+}
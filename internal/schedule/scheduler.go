@@ -0,0 +1,309 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/fade"
+)
+
+// maxSleep bounds how long the scheduler sleeps between checks, so it
+// notices new jobs and wall-clock jumps after a suspend.
+const maxSleep = 30 * time.Second
+
+// Options configures a Scheduler.
+type Options struct {
+	// StatePath is where the time each job was last checked is kept, so
+	// runs missed while the scheduler was down can be detected. Empty
+	// keeps it in memory only.
+	StatePath string
+
+	// ClaimDir is where ramps claim their device's volume, so a newer
+	// volume command cancels them (see fade.Claim). Empty disables it.
+	ClaimDir string
+
+	// Logger reports runs and failures. Nil means slog.Default().
+	Logger *slog.Logger
+}
+
+// Scheduler fires jobs from a store.
+type Scheduler struct {
+	store    *Store
+	backends core.PlayerResolver
+	opts     Options
+
+	// now and sleep are replaced in tests
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration)
+
+	mu      sync.Mutex
+	checked map[int]time.Time
+}
+
+// New creates a scheduler for the jobs in store.
+func New(store *Store, backends core.PlayerResolver, opts Options) *Scheduler {
+	return &Scheduler{
+		store:    store,
+		backends: backends,
+		opts:     opts,
+		now:      time.Now,
+		sleep:    sleepContext,
+		checked:  make(map[int]time.Time),
+	}
+}
+
+func (s *Scheduler) log() *slog.Logger {
+	if s.opts.Logger != nil {
+		return s.opts.Logger
+	}
+	return slog.Default()
+}
+
+// Run fires jobs as they come due until ctx is done. The store is reread
+// on every check, so jobs added or removed while it runs take effect.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.loadState()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		jobs, err := s.store.Load()
+		if err != nil {
+			s.log().Error("schedule failed to load jobs", "error", err)
+		}
+
+		// Compare wall-clock times; after a suspend the monotonic clock
+		// lags behind
+		now := s.now().Round(0)
+		for _, j := range s.due(jobs, now) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.fire(ctx, j)
+			}()
+		}
+		s.saveState()
+
+		wait := maxSleep
+		for _, j := range jobs {
+			if next := j.Next(s.since(j)); !next.IsZero() {
+				wait = min(wait, max(next.Sub(now), time.Second))
+			}
+		}
+		s.sleep(ctx, wait)
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// due returns the jobs to fire at now, and records that every job has been
+// checked up to now. A run more than the job's grace period late was
+// missed, and fires only under MissedRun; several missed runs fire once.
+func (s *Scheduler) due(jobs []Job, now time.Time) []Job {
+	var fire []Job
+	seen := make(map[int]bool, len(jobs))
+	for _, j := range jobs {
+		seen[j.ID] = true
+		next := j.Next(s.since(j))
+		if next.IsZero() || next.After(now) {
+			continue
+		}
+
+		// Find the latest run at or before now
+		latest := next
+		for {
+			n := j.Next(latest)
+			if n.IsZero() || n.After(now) {
+				break
+			}
+			latest = n
+		}
+
+		switch {
+		case now.Sub(latest) <= j.grace():
+			fire = append(fire, j)
+		case j.Missed == MissedRun:
+			s.log().Info("schedule job missed a run, running it now", "job", j.ID, "missed", latest)
+			fire = append(fire, j)
+		default:
+			s.log().Info("schedule job missed a run, skipping it", "job", j.ID, "missed", latest)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id := range s.checked {
+		if !seen[id] {
+			delete(s.checked, id)
+		}
+	}
+	for _, j := range jobs {
+		s.checked[j.ID] = now
+	}
+	return fire
+}
+
+// since returns when j was last checked, or when it was created.
+func (s *Scheduler) since(j Job) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.checked[j.ID]; ok {
+		return t
+	}
+	return j.Created
+}
+
+// fire plays a job: it starts playback at the starting volume and then
+// ramps to the final volume.
+func (s *Scheduler) fire(ctx context.Context, j Job) {
+	p, d, err := s.backends.PlayerFor(ctx, j.Target)
+	if err != nil {
+		s.log().Error("schedule job failed", "job", j.ID, "error", err)
+		return
+	}
+	name := j.Target
+	if d != nil {
+		name = d.Name
+	}
+	s.log().Info("schedule job playing", "job", j.ID, "device", name)
+
+	// Spotify only takes a volume for the device that is playing, so it is
+	// set once playback has started. Elsewhere it is set first, so the
+	// device doesn't start loud.
+	volumeFirst := d == nil || d.Platform != core.PlatformSpotify
+	if volumeFirst {
+		s.startVolume(ctx, p, j)
+	}
+	if j.URI != "" {
+		err = PlayURI(ctx, p, j.URI)
+	} else {
+		err = p.Play(ctx)
+	}
+	if err != nil {
+		s.log().Error("schedule job failed to play", "job", j.ID, "error", err)
+		return
+	}
+	if !volumeFirst {
+		s.startVolume(ctx, p, j)
+	}
+
+	if j.Volume != nil && j.Volume.To != j.Volume.From {
+		if err := s.ramp(ctx, p, d, j); err != nil && ctx.Err() == nil {
+			s.log().Warn("schedule job volume ramp stopped", "job", j.ID, "error", err)
+		}
+	}
+}
+
+// startVolume sets a job's starting volume, if it has one.
+func (s *Scheduler) startVolume(ctx context.Context, p core.Player, j Job) {
+	if j.Volume == nil {
+		return
+	}
+	if err := p.Volume(ctx, j.Volume.From); err != nil {
+		s.log().Warn("schedule job failed to set volume", "job", j.ID, "error", err)
+	}
+}
+
+// ramp fades a job's volume from its start to its end.
+func (s *Scheduler) ramp(ctx context.Context, p core.Player, d *core.Device, j Job) error {
+	var opts fade.Options
+	if d != nil && d.Platform == core.PlatformSpotify {
+		opts.Interval = fade.SpotifyInterval
+	}
+	if s.opts.ClaimDir != "" && d != nil {
+		claim, err := fade.NewClaim(s.opts.ClaimDir, d.ID)
+		if err != nil {
+			return err
+		}
+		defer claim.Release()
+		opts.Superseded = claim.Superseded
+	}
+	return fade.Run(ctx, p, j.Volume.From, j.Volume.To, j.Ramp, opts)
+}
+
+// PlayURI starts uri on p. Spotify albums, playlists and artists play as a
+// context; anything else goes to the player's PlayURI.
+func PlayURI(ctx context.Context, p core.Player, uri string) error {
+	type contextPlayer interface {
+		PlayContextNoOffset(ctx context.Context, contextURI string) error
+	}
+	if cp, ok := p.(contextPlayer); ok && isSpotifyContext(uri) {
+		return cp.PlayContextNoOffset(ctx, uri)
+	}
+	if sp, ok := p.(core.StreamPlayer); ok {
+		return sp.PlayURI(ctx, uri)
+	}
+	return core.Require(p, core.CapStream)
+}
+
+// isSpotifyContext reports whether uri is a Spotify album, playlist or
+// artist.
+func isSpotifyContext(uri string) bool {
+	for _, kind := range []string{"album", "playlist", "artist"} {
+		if strings.HasPrefix(uri, "spotify:"+kind+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// loadState restores when each job was last checked.
+func (s *Scheduler) loadState() {
+	if s.opts.StatePath == "" {
+		return
+	}
+	data, err := os.ReadFile(s.opts.StatePath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			s.log().Warn("schedule failed to read state", "path", s.opts.StatePath, "error", err)
+		}
+		return
+	}
+	var state struct {
+		Checked map[int]time.Time `json:"checked"`
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		s.log().Warn("schedule failed to parse state", "path", s.opts.StatePath, "error", err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, t := range state.Checked {
+		s.checked[id] = t
+	}
+}
+
+// saveState records when each job was last checked.
+func (s *Scheduler) saveState() {
+	if s.opts.StatePath == "" {
+		return
+	}
+	s.mu.Lock()
+	data, err := json.Marshal(map[string]map[int]time.Time{"checked": s.checked})
+	s.mu.Unlock()
+	if err != nil {
+		return
+	}
+	if err := writeFileAtomic(s.opts.StatePath, data); err != nil {
+		s.log().Warn("schedule failed to save state", "path", s.opts.StatePath, "error", err)
+	}
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
@@ -0,0 +1,227 @@
+package schedule
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Spec is a recurrence: either a five-field cron expression
+// ("30 6 * * 1-5") or a time of day with optional days ("07:00 weekdays",
+// "22:30 fri,sat", "09:00 daily").
+type Spec struct {
+	text string
+
+	minutes uint64 // bit n set when minute n matches
+	hours   uint64
+	doms    uint64 // days of month, 1-31
+	months  uint64 // 1-12
+	dows    uint64 // 0-6, Sunday first
+
+	// Cron matches a day when either day field matches if both are
+	// restricted, and when both match otherwise
+	domAny bool
+	dowAny bool
+}
+
+var dayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// ParseSpec parses a recurrence.
+func ParseSpec(s string) (*Spec, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 5 {
+		return parseCron(s, fields)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty schedule")
+	}
+	return parseFriendly(s, fields)
+}
+
+// parseFriendly parses "HH:MM [days]".
+func parseFriendly(s string, fields []string) (*Spec, error) {
+	t, err := time.Parse("15:04", fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: expected HH:MM [days] or a cron expression", s)
+	}
+	spec := &Spec{
+		text:    s,
+		minutes: 1 << t.Minute(),
+		hours:   1 << t.Hour(),
+		doms:    span(1, 31),
+		months:  span(1, 12),
+		domAny:  true,
+		dowAny:  true,
+		dows:    span(0, 6),
+	}
+
+	days := strings.ToLower(strings.Join(fields[1:], ","))
+	switch days {
+	case "", "daily", "everyday":
+		return spec, nil
+	case "weekdays":
+		spec.dows = span(1, 5)
+	case "weekends":
+		spec.dows = 1<<0 | 1<<6
+	default:
+		spec.dows, err = parseField(days, 0, 6, dayNames)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", s, err)
+		}
+	}
+	spec.dowAny = false
+	return spec, nil
+}
+
+// parseCron parses "minute hour day-of-month month day-of-week".
+func parseCron(s string, fields []string) (*Spec, error) {
+	spec := &Spec{text: s}
+	var err error
+	parse := func(i, lo, hi int, names map[string]int) uint64 {
+		if err != nil {
+			return 0
+		}
+		var set uint64
+		set, err = parseField(strings.ToLower(fields[i]), lo, hi, names)
+		return set
+	}
+	spec.minutes = parse(0, 0, 59, nil)
+	spec.hours = parse(1, 0, 23, nil)
+	spec.doms = parse(2, 1, 31, nil)
+	spec.months = parse(3, 1, 12, nil)
+	spec.dows = parse(4, 0, 7, dayNames)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", s, err)
+	}
+
+	// 7 is Sunday too
+	if spec.dows&(1<<7) != 0 {
+		spec.dows = spec.dows&^(1<<7) | 1
+	}
+	spec.domAny = fields[2] == "*"
+	spec.dowAny = fields[4] == "*"
+	return spec, nil
+}
+
+// parseField parses a comma-separated list of values, ranges ("1-5") and
+// steps ("*/15", "0-30/10") into a bit set.
+func parseField(field string, lo, hi int, names map[string]int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+			step = n
+		}
+
+		from, to := lo, hi
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if from, err = parseValue(a, lo, hi, names); err != nil {
+				return 0, err
+			}
+			to = from
+			if isRange {
+				if to, err = parseValue(b, lo, hi, names); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				to = hi
+			}
+			if to < from {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		}
+		for v := from; v <= to; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// parseValue parses a number or name within [lo, hi].
+func parseValue(s string, lo, hi int, names map[string]int) (int, error) {
+	if len(s) > 3 {
+		if v, ok := names[s[:3]]; ok {
+			return v, nil // "monday" -> "mon"
+		}
+	}
+	if v, ok := names[s]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < lo || v > hi {
+		return 0, fmt.Errorf("invalid value %q (want %d-%d)", s, lo, hi)
+	}
+	return v, nil
+}
+
+// span returns a bit set of lo through hi.
+func span(lo, hi int) uint64 {
+	var set uint64
+	for v := lo; v <= hi; v++ {
+		set |= 1 << v
+	}
+	return set
+}
+
+// String returns the recurrence as written.
+func (s *Spec) String() string {
+	return s.text
+}
+
+// Next returns the first time after after that matches, in loc. It returns
+// the zero time if nothing matches within five years, e.g. for "0 0 31 2 *".
+func (s *Spec) Next(after time.Time, loc *time.Location) time.Time {
+	after = after.In(loc)
+	day := time.Date(after.Year(), after.Month(), after.Day(), 0, 0, 0, 0, loc)
+	for i := 0; i < 5*366; i++ {
+		d := day.AddDate(0, 0, i)
+		if !s.matchesDay(d) {
+			continue
+		}
+		for h := range bits.Len64(s.hours) {
+			if s.hours&(1<<h) == 0 {
+				continue
+			}
+			for m := range bits.Len64(s.minutes) {
+				if s.minutes&(1<<m) == 0 {
+					continue
+				}
+				t := time.Date(d.Year(), d.Month(), d.Day(), h, m, 0, 0, loc)
+				if t.Hour() != h || t.Minute() != m {
+					// Skipped by a DST change; run as the clocks go forward
+					_, before := t.Zone()
+					_, after := t.Add(3 * time.Hour).Zone()
+					t = t.Add(time.Duration(after-before) * time.Second)
+				}
+				if t.After(after) {
+					return t
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// matchesDay reports whether d's date matches the day and month fields.
+func (s *Spec) matchesDay(d time.Time) bool {
+	if s.months&(1<<int(d.Month())) == 0 {
+		return false
+	}
+	dom := s.doms&(1<<d.Day()) != 0
+	dow := s.dows&(1<<int(d.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,223 @@
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Missed-run policies.
+const (
+	// MissedSkip drops runs that were missed by more than the grace period,
+	// e.g. while the machine was asleep.
+	MissedSkip = "skip"
+	// MissedRun fires a missed run once, as soon as the scheduler notices.
+	MissedRun = "run"
+)
+
+// DefaultGrace is how late a run may fire before it counts as missed.
+const DefaultGrace = 10 * time.Minute
+
+// VolumeRange is a job's starting volume and, for a ramp, its final volume.
+type VolumeRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// Job is a scheduled playback.
+type Job struct {
+	ID   int    `json:"id"`
+	Spec string `json:"spec"`
+
+	// TZ is the IANA time zone the spec is read in. Empty means local.
+	TZ string `json:"tz,omitempty"`
+
+	// URI is what to play. Empty resumes playback.
+	URI string `json:"uri,omitempty"`
+
+	// Target is the device to play on. Empty means the default player.
+	Target string `json:"target,omitempty"`
+
+	Volume *VolumeRange `json:"volume,omitempty"`
+
+	// Ramp is how long the volume takes to go from Volume.From to
+	// Volume.To.
+	Ramp time.Duration `json:"ramp,omitempty"`
+
+	// Missed is MissedSkip or MissedRun.
+	Missed string `json:"missed,omitempty"`
+
+	// Grace is how late a run may fire before Missed applies.
+	Grace time.Duration `json:"grace,omitempty"`
+
+	Created time.Time `json:"created"`
+}
+
+// Validate checks the job's spec, time zone, volume and policy.
+func (j *Job) Validate() error {
+	if _, err := ParseSpec(j.Spec); err != nil {
+		return err
+	}
+	if _, err := j.Location(); err != nil {
+		return err
+	}
+	if v := j.Volume; v != nil && (v.From < 0 || v.From > 100 || v.To < 0 || v.To > 100) {
+		return errors.New("volume must be between 0 and 100")
+	}
+	if j.Ramp < 0 || j.Grace < 0 {
+		return errors.New("ramp and grace must be non-negative")
+	}
+	switch j.Missed {
+	case "", MissedSkip, MissedRun:
+		return nil
+	default:
+		return fmt.Errorf("invalid missed-run policy %q (want %s or %s)", j.Missed, MissedSkip, MissedRun)
+	}
+}
+
+// Location returns the job's time zone.
+func (j *Job) Location() (*time.Location, error) {
+	if j.TZ == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(j.TZ)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", j.TZ, err)
+	}
+	return loc, nil
+}
+
+// Next returns the job's first run after t, or the zero time if its spec
+// or time zone is invalid or never matches.
+func (j *Job) Next(t time.Time) time.Time {
+	spec, err := ParseSpec(j.Spec)
+	if err != nil {
+		return time.Time{}
+	}
+	loc, err := j.Location()
+	if err != nil {
+		return time.Time{}
+	}
+	return spec.Next(t, loc)
+}
+
+// grace returns the job's grace period, or the default.
+func (j *Job) grace() time.Duration {
+	if j.Grace > 0 {
+		return j.Grace
+	}
+	return DefaultGrace
+}
+
+// Store keeps jobs in a JSON file.
+type Store struct {
+	path string
+}
+
+// DefaultPath returns schedule.json in riff's config directory.
+func DefaultPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return filepath.Join(configDir, "riff", "schedule.json"), nil
+}
+
+// NewStore returns a store backed by path.
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Path returns the store's file.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Load returns every job, ordered by ID. A missing file has no jobs.
+func (s *Store) Load() ([]Job, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedule: %w", err)
+	}
+	var file struct {
+		Jobs []Job `json:"jobs"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+	return file.Jobs, nil
+}
+
+// Add validates j, assigns it the next ID and saves it.
+func (s *Store) Add(j Job) (Job, error) {
+	if err := j.Validate(); err != nil {
+		return Job{}, err
+	}
+	jobs, err := s.Load()
+	if err != nil {
+		return Job{}, err
+	}
+	j.ID = 1
+	for _, existing := range jobs {
+		j.ID = max(j.ID, existing.ID+1)
+	}
+	if j.Created.IsZero() {
+		j.Created = time.Now()
+	}
+	return j, s.save(append(jobs, j))
+}
+
+// Remove deletes the job with the given ID.
+func (s *Store) Remove(id int) error {
+	jobs, err := s.Load()
+	if err != nil {
+		return err
+	}
+	for i, j := range jobs {
+		if j.ID == id {
+			return s.save(append(jobs[:i], jobs[i+1:]...))
+		}
+	}
+	return fmt.Errorf("no scheduled job %d", id)
+}
+
+// save writes jobs, replacing the file atomically.
+func (s *Store) save(jobs []Job) error {
+	if jobs == nil {
+		jobs = []Job{}
+	}
+	data, err := json.MarshalIndent(map[string][]Job{"jobs": jobs}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }() // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
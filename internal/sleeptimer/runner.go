@@ -0,0 +1,255 @@
+package sleeptimer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/fade"
+)
+
+// DefaultPoll is how often a running timer rereads the store and the
+// device's state.
+const DefaultPoll = 2 * time.Second
+
+// stale is how long past its deadline a timer is dropped instead of fired,
+// e.g. when riffd was down when it was due.
+const stale = time.Minute
+
+// Options configures a Runner.
+type Options struct {
+	// Owner selects the timers Run handles (OwnerDaemon or OwnerWorker).
+	Owner string
+
+	// Poll is how often timers check for cancellation and track changes
+	// (default DefaultPoll).
+	Poll time.Duration
+
+	// ClaimDir is where fade-outs claim their device's volume, so that a
+	// volume command or another fade on the device cancels them (see
+	// fade.Claim). Empty disables it.
+	ClaimDir string
+
+	// Logger reports pauses and failures. Nil means slog.Default().
+	Logger *slog.Logger
+}
+
+// Runner runs sleep timers from a store.
+type Runner struct {
+	store    *Store
+	backends core.PlayerResolver
+	opts     Options
+
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+// NewRunner creates a runner for the timers in store.
+func NewRunner(store *Store, backends core.PlayerResolver, opts Options) *Runner {
+	if opts.Poll <= 0 {
+		opts.Poll = DefaultPoll
+	}
+	return &Runner{
+		store:    store,
+		backends: backends,
+		opts:     opts,
+		running:  make(map[string]bool),
+	}
+}
+
+func (r *Runner) log() *slog.Logger {
+	if r.opts.Logger != nil {
+		return r.opts.Logger
+	}
+	return slog.Default()
+}
+
+// Run runs every timer with the runner's owner, picking up new ones as they
+// are set, until ctx is done.
+func (r *Runner) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ticker := time.NewTicker(r.opts.Poll)
+	defer ticker.Stop()
+	for {
+		timers, err := r.store.Load()
+		if err != nil {
+			r.log().ErrorContext(ctx, "sleep failed to load timers", "error", err)
+		}
+		for _, t := range timers {
+			if t.Owner != r.opts.Owner || !r.start(t.ID) {
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer r.done(t.ID)
+				_ = r.RunTimer(ctx, t.ID)
+			}()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Runner) start(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running[id] {
+		return false
+	}
+	r.running[id] = true
+	return true
+}
+
+func (r *Runner) done(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.running, id)
+}
+
+// RunTimer waits for the timer with the given ID to go off, then fades out
+// and pauses. It returns early when the timer is cancelled or replaced.
+func (r *Runner) RunTimer(ctx context.Context, id string) error {
+	for {
+		t, err := r.store.Get(id)
+		if err != nil {
+			return err
+		}
+		if t == nil {
+			return nil // cancelled
+		}
+		if t.Mode == ModeDuration && time.Since(t.Deadline) > stale {
+			r.log().InfoContext(ctx, "sleep timer expired while not running, dropping it", "timer", t.Name, "deadline", t.Deadline)
+			_, err := r.store.Remove(t.ID)
+			return err
+		}
+
+		left, known, err := r.check(ctx, t)
+		wait := left - t.Fade
+		switch {
+		case err != nil:
+			r.log().WarnContext(ctx, "sleep timer failed to get state", "timer", t.Name, "error", err)
+			wait = r.opts.Poll
+		case !known:
+			// Wait for the album to change
+			wait = r.opts.Poll
+		}
+		if wait <= 0 {
+			r.fire(ctx, t, min(left, t.Fade))
+			_, err := r.store.Remove(t.ID)
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(min(wait, r.opts.Poll)):
+		}
+	}
+}
+
+// check returns how long is left on t; see Timer.Remaining.
+func (r *Runner) check(ctx context.Context, t *Timer) (left time.Duration, known bool, err error) {
+	if t.Mode == ModeDuration {
+		left, known = t.Remaining(nil, nil, time.Now())
+		return left, known, nil
+	}
+
+	p, _, err := r.backends.PlayerFor(ctx, t.Device)
+	if err != nil {
+		return 0, false, err
+	}
+	state, err := p.GetState(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	var queue *core.Queue
+	if t.Mode == ModeAlbum && p.Capabilities().Has(core.CapQueueRead) {
+		if queue, err = p.GetQueue(ctx); err != nil {
+			queue = nil
+		}
+	}
+
+	left, known = t.Remaining(state, queue, time.Now())
+	return left, known, nil
+}
+
+// fire fades t's device out over fadeFor, pauses it and restores the
+// volume. Cancelling the timer mid-fade puts the volume back and leaves
+// playback alone; a newer volume change on the device leaves both alone.
+func (r *Runner) fire(ctx context.Context, t *Timer, fadeFor time.Duration) {
+	p, d, err := r.backends.PlayerFor(ctx, t.Device)
+	if err != nil {
+		r.log().ErrorContext(ctx, "sleep timer failed to open device", "timer", t.Name, "error", err)
+		return
+	}
+	state, err := p.GetState(ctx)
+	if err != nil {
+		r.log().ErrorContext(ctx, "sleep timer failed to get state", "timer", t.Name, "error", err)
+		return
+	}
+	if !state.IsPlaying {
+		return
+	}
+
+	volume := state.Volume
+	if fadeFor > 0 {
+		curve, _ := fade.ParseCurve(t.Curve)
+		cancelled := func() bool {
+			cur, err := r.store.Get(t.ID)
+			return err == nil && cur == nil
+		}
+		opts := fade.Options{Curve: curve, Superseded: cancelled}
+		if d != nil && d.Platform == core.PlatformSpotify {
+			opts.Interval = fade.SpotifyInterval
+		}
+		if r.opts.ClaimDir != "" {
+			key := t.Device
+			if d != nil && d.ID != "" {
+				key = d.ID
+			}
+			claim, err := fade.NewClaim(r.opts.ClaimDir, key)
+			if err != nil {
+				r.log().ErrorContext(ctx, "sleep timer failed to claim the volume", "timer", t.Name, "error", err)
+				return
+			}
+			defer claim.Release()
+			opts.Superseded = func() bool { return claim.Superseded() || cancelled() }
+		}
+
+		err := fade.Run(ctx, p, volume, 0, fadeFor, opts)
+		if errors.Is(err, fade.ErrSuperseded) {
+			if !cancelled() {
+				r.log().InfoContext(ctx, "sleep timer volume changed during the fade, not pausing", "timer", t.Name)
+				return
+			}
+			if err := p.Volume(ctx, volume); err != nil {
+				r.log().WarnContext(ctx, "sleep timer failed to restore volume", "timer", t.Name, "error", err)
+			}
+			return
+		}
+		if err != nil {
+			r.log().WarnContext(ctx, "sleep timer fade failed", "timer", t.Name, "error", err)
+		}
+	}
+
+	if err := p.Pause(ctx); err != nil {
+		r.log().ErrorContext(ctx, "sleep timer failed to pause", "timer", t.Name, "error", err)
+	} else {
+		r.log().InfoContext(ctx, "sleep timer paused playback", "timer", t.Name)
+	}
+	if fadeFor > 0 {
+		if err := p.Volume(ctx, volume); err != nil {
+			r.log().WarnContext(ctx, "sleep timer failed to restore volume", "timer", t.Name, "error", err)
+		}
+	}
+}
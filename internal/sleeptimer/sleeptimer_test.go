@@ -0,0 +1,234 @@
+package sleeptimer
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/core/coretest"
+	"github.com/tessro/riff/internal/fade"
+)
+
+var bedroom = &core.Device{ID: "RINCON_1", Name: "Bedroom", Platform: core.PlatformSonos}
+
+func track(uri, album string, d time.Duration) *core.Track {
+	return &core.Track{URI: uri, Title: uri, Album: album, Duration: d}
+}
+
+func TestNew(t *testing.T) {
+	now := time.Date(2026, 1, 5, 22, 0, 0, 0, time.UTC)
+	state := &core.PlaybackState{Device: bedroom, Track: track("a1", "Album", 3*time.Minute), IsPlaying: true}
+
+	timer, err := New("45m", state, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timer.Mode != ModeDuration || !timer.Deadline.Equal(now.Add(45*time.Minute)) || timer.Device != bedroom.ID {
+		t.Errorf("New(45m) = %+v", timer)
+	}
+
+	timer, err = New(ModeAlbum, state, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timer.Album != "Album" || timer.Track != "a1" {
+		t.Errorf("New(end-of-album) = %+v", timer)
+	}
+
+	for _, mode := range []string{"tonight", "-5m", "0s"} {
+		if _, err := New(mode, state, now); err == nil {
+			t.Errorf("New(%q) succeeded, want error", mode)
+		}
+	}
+	if _, err := New(ModeTrack, &core.PlaybackState{Device: bedroom}, now); err == nil {
+		t.Error("New(end-of-track) succeeded with nothing playing")
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	now := time.Now()
+	state := &core.PlaybackState{
+		Device:   bedroom,
+		Track:    track("a2", "Album", 4*time.Minute),
+		Progress: time.Minute,
+	}
+	queue := &core.Queue{
+		Tracks: []core.Track{
+			*track("a1", "Album", time.Minute),
+			*track("a2", "Album", 4*time.Minute),
+			*track("a3", "Album", 2*time.Minute),
+			*track("b1", "Other", 5*time.Minute),
+			*track("a4", "Album", 2*time.Minute),
+		},
+		CurrentIndex: 1,
+	}
+
+	tests := []struct {
+		name      string
+		timer     Timer
+		state     *core.PlaybackState
+		queue     *core.Queue
+		wantLeft  time.Duration
+		wantKnown bool
+	}{
+		{"duration", Timer{Mode: ModeDuration, Deadline: now.Add(time.Hour)}, nil, nil, time.Hour, true},
+		{"expired", Timer{Mode: ModeDuration, Deadline: now.Add(-time.Hour)}, nil, nil, 0, true},
+		{"track", Timer{Mode: ModeTrack, Track: "a2"}, state, nil, 3 * time.Minute, true},
+		{"track ended", Timer{Mode: ModeTrack, Track: "a1"}, state, nil, 0, true},
+		{"album", Timer{Mode: ModeAlbum, Album: "Album"}, state, queue, 5 * time.Minute, true},
+		{"album without queue", Timer{Mode: ModeAlbum, Album: "Album"}, state, nil, 3 * time.Minute, false},
+		{"album ended", Timer{Mode: ModeAlbum, Album: "Other"}, state, queue, 0, true},
+		{"stopped", Timer{Mode: ModeTrack, Track: "a2"}, &core.PlaybackState{}, nil, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			left, known := tt.timer.Remaining(tt.state, tt.queue, now)
+			if left != tt.wantLeft || known != tt.wantKnown {
+				t.Errorf("Remaining() = %v, %v; want %v, %v", left, known, tt.wantLeft, tt.wantKnown)
+			}
+		})
+	}
+}
+
+func TestStore(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "riff", "sleep-timers.json"))
+
+	first := Timer{ID: "1", Device: "RINCON_1", Mode: ModeTrack}
+	other := Timer{ID: "2", Device: "spot-1", Mode: ModeTrack}
+	replacement := Timer{ID: "3", Device: "RINCON_1", Mode: ModeAlbum}
+	for _, timer := range []Timer{first, other, replacement} {
+		if err := store.Put(timer); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, _ := store.Get("1"); got != nil {
+		t.Error("replaced timer is still set")
+	}
+	if got, _ := store.For("RINCON_1"); got == nil || got.ID != "3" {
+		t.Errorf("For(RINCON_1) = %+v, want timer 3", got)
+	}
+
+	if removed, err := store.Remove("2"); err != nil || !removed {
+		t.Errorf("Remove(2) = %v, %v", removed, err)
+	}
+	if removed, _ := store.Remove("2"); removed {
+		t.Error("Remove(2) removed a timer twice")
+	}
+	timers, _ := store.Load()
+	if len(timers) != 1 {
+		t.Errorf("got %d timers, want 1", len(timers))
+	}
+}
+
+type fakeBackends struct {
+	player *coretest.Player
+}
+
+func (b *fakeBackends) PlayerFor(ctx context.Context, target string) (core.Player, *core.Device, error) {
+	return b.player, bedroom, nil
+}
+
+// playingPlayer returns a player on the bedroom speaker, playing at volume.
+func playingPlayer(volume int) *coretest.Player {
+	p := coretest.NewPlayer(bedroom, 0)
+	p.Update(func(s *core.PlaybackState) {
+		s.IsPlaying = true
+		s.Volume = volume
+	})
+	return p
+}
+
+func TestRunTimer(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "sleep-timers.json"))
+	player := playingPlayer(2)
+	timer := Timer{
+		ID:       "1",
+		Device:   bedroom.ID,
+		Name:     bedroom.Name,
+		Mode:     ModeDuration,
+		Deadline: time.Now().Add(100 * time.Millisecond),
+		Fade:     50 * time.Millisecond,
+		Owner:    OwnerWorker,
+	}
+	if err := store.Put(timer); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRunner(store, &fakeBackends{player: player}, Options{Poll: 10 * time.Millisecond})
+	if err := r.RunTimer(context.Background(), "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Faded out, paused, and back to where it was for next time
+	calls := player.Calls()
+	if got := fmt.Sprint(calls[max(len(calls)-3, 0):]); got != "[volume 0 pause volume 2]" {
+		t.Errorf("calls = %v, want a fade ending in [volume 0 pause volume 2]", calls)
+	}
+	if got, _ := store.Get("1"); got != nil {
+		t.Error("timer is still set after firing")
+	}
+}
+
+func TestRunTimerCancelled(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "sleep-timers.json"))
+	player := playingPlayer(40)
+	if err := store.Put(Timer{ID: "1", Device: bedroom.ID, Mode: ModeDuration, Deadline: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRunner(store, &fakeBackends{player: player}, Options{Poll: 10 * time.Millisecond})
+	done := make(chan error, 1)
+	go func() { done <- r.RunTimer(context.Background(), "1") }()
+
+	if _, err := store.Remove("1"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunTimer() didn't notice the timer was cancelled")
+	}
+	if calls := player.Calls(); len(calls) != 0 {
+		t.Errorf("cancelled timer made calls: %v", calls)
+	}
+}
+
+func TestRunTimerYieldsToVolumeChange(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(filepath.Join(dir, "sleep-timers.json"))
+	player := playingPlayer(40)
+	if err := store.Put(Timer{ID: "1", Device: bedroom.ID, Mode: ModeDuration, Deadline: time.Now().Add(2 * time.Second), Fade: 2 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRunner(store, &fakeBackends{player: player}, Options{Poll: 10 * time.Millisecond, ClaimDir: dir})
+	done := make(chan error, 1)
+	go func() { done <- r.RunTimer(context.Background(), "1") }()
+
+	// A riff volume on the device takes its volume over from the fade
+	deadline := time.Now().Add(5 * time.Second)
+	for len(player.Calls()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	fade.Supersede(dir, bedroom.ID)
+	_ = player.Volume(context.Background(), 25)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunTimer() kept fading after the volume was set")
+	}
+	if state := player.State(); !state.IsPlaying || state.Volume != 25 {
+		t.Errorf("state = playing %v at %d, want still playing at 25", state.IsPlaying, state.Volume)
+	}
+}
@@ -0,0 +1,287 @@
+// Package sleeptimer pauses playback after a while, at the end of the
+// current track or at the end of the current album, on any player.
+package sleeptimer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+)
+
+// Timer modes.
+const (
+	// ModeDuration pauses at Deadline.
+	ModeDuration = "duration"
+	// ModeTrack pauses when the track playing when the timer was set ends.
+	ModeTrack = "end-of-track"
+	// ModeAlbum pauses when playback moves on from the album playing when
+	// the timer was set.
+	ModeAlbum = "end-of-album"
+)
+
+// Owners. A timer runs in exactly one process, which removes it when done.
+const (
+	OwnerDaemon = "riffd"
+	OwnerWorker = "worker"
+)
+
+// Timer is a pending pause.
+type Timer struct {
+	ID string `json:"id"`
+
+	// Device is the ID of the device to pause, and Name its display name.
+	Device string `json:"device"`
+	Name   string `json:"name"`
+
+	Mode     string    `json:"mode"`
+	Deadline time.Time `json:"deadline,omitzero"`
+
+	// Track and Album are what was playing when the timer was set.
+	Track string `json:"track,omitempty"`
+	Album string `json:"album,omitempty"`
+
+	// Fade is how long the volume takes to fade out before the pause,
+	// following Curve ("linear" or "log").
+	Fade  time.Duration `json:"fade,omitempty"`
+	Curve string        `json:"curve,omitempty"`
+
+	Owner   string    `json:"owner"`
+	Created time.Time `json:"created"`
+}
+
+// New returns a timer for the device playing state. mode is a duration
+// such as "45m", "end-of-track" or "end-of-album".
+func New(mode string, state *core.PlaybackState, now time.Time) (*Timer, error) {
+	if state == nil || state.Device == nil {
+		return nil, errors.New("no device to set a sleep timer on")
+	}
+	t := &Timer{
+		ID:      newID(),
+		Device:  state.Device.ID,
+		Name:    state.Device.Name,
+		Mode:    mode,
+		Created: now,
+	}
+
+	switch mode {
+	case ModeTrack, ModeAlbum:
+		if !state.HasTrack() {
+			return nil, fmt.Errorf("nothing is playing on %s", state.Device.Name)
+		}
+		t.Track = state.Track.URI
+		if mode == ModeAlbum {
+			if state.Track.Album == "" {
+				return nil, errors.New("the current track has no album")
+			}
+			t.Album = state.Track.Album
+		}
+	default:
+		d, err := time.ParseDuration(mode)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid sleep timer %q (want a duration such as 45m, %s or %s)", mode, ModeTrack, ModeAlbum)
+		}
+		t.Mode = ModeDuration
+		t.Deadline = now.Add(d)
+	}
+	return t, nil
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Remaining returns how long until the timer goes off, given the device's
+// state and, for ModeAlbum, its queue. known is false when the end can't be
+// worked out yet, e.g. an album timer without a readable queue; left is
+// then the least time remaining. A timer whose track or album has already
+// ended has no time left.
+func (t *Timer) Remaining(state *core.PlaybackState, queue *core.Queue, now time.Time) (left time.Duration, known bool) {
+	if t.Mode == ModeDuration {
+		return max(t.Deadline.Sub(now), 0), true
+	}
+	if !state.HasTrack() {
+		return 0, true
+	}
+
+	left = max(state.Track.Duration-state.Progress, 0)
+	switch t.Mode {
+	case ModeTrack:
+		if state.Track.URI != t.Track {
+			return 0, true
+		}
+		return left, true
+	case ModeAlbum:
+		if state.Track.Album != t.Album {
+			return 0, true
+		}
+		if queue == nil || queue.Current() == nil {
+			return left, false
+		}
+		for _, track := range queue.Tracks[queue.CurrentIndex+1:] {
+			if track.Album != t.Album {
+				break
+			}
+			left += track.Duration
+		}
+		return left, true
+	}
+	return 0, true
+}
+
+// Describe summarizes when the timer goes off, e.g. "in 12:30" or "at the
+// end of the album". state may be nil.
+func (t *Timer) Describe(state *core.PlaybackState, now time.Time) string {
+	left, known := t.Remaining(state, nil, now)
+	switch {
+	case t.Mode == ModeAlbum && (state == nil || !known):
+		return "at the end of the album"
+	case t.Mode == ModeTrack && state == nil:
+		return "at the end of the track"
+	case t.Mode == ModeTrack:
+		return "at the end of the track (" + formatLeft(left) + ")"
+	default:
+		return "in " + formatLeft(left)
+	}
+}
+
+// formatLeft formats a countdown as m:ss or h:mm:ss.
+func formatLeft(d time.Duration) string {
+	d = d.Round(time.Second)
+	h, m, s := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+// Store keeps timers in a JSON file, at most one per device.
+type Store struct {
+	path string
+}
+
+// NewStore returns a store backed by path.
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Load returns every timer. A missing file has none.
+func (s *Store) Load() ([]Timer, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sleep timers: %w", err)
+	}
+	var file struct {
+		Timers []Timer `json:"timers"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+	return file.Timers, nil
+}
+
+// For returns the timer on device, if any.
+func (s *Store) For(device string) (*Timer, error) {
+	timers, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range timers {
+		if t.Device == device {
+			return &t, nil
+		}
+	}
+	return nil, nil
+}
+
+// Get returns the timer with the given ID, if it is still set.
+func (s *Store) Get(id string) (*Timer, error) {
+	timers, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range timers {
+		if t.ID == id {
+			return &t, nil
+		}
+	}
+	return nil, nil
+}
+
+// Put saves t, replacing any timer already on its device.
+func (s *Store) Put(t Timer) error {
+	timers, err := s.Load()
+	if err != nil {
+		return err
+	}
+	kept := timers[:0]
+	for _, existing := range timers {
+		if existing.Device != t.Device {
+			kept = append(kept, existing)
+		}
+	}
+	return s.save(append(kept, t))
+}
+
+// Remove deletes the timer with the given ID. It reports whether it was set.
+func (s *Store) Remove(id string) (bool, error) {
+	return s.removeIf(func(t Timer) bool { return t.ID == id })
+}
+
+func (s *Store) removeIf(match func(Timer) bool) (bool, error) {
+	timers, err := s.Load()
+	if err != nil {
+		return false, err
+	}
+	kept := timers[:0]
+	for _, t := range timers {
+		if !match(t) {
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) == len(timers) {
+		return false, nil
+	}
+	return true, s.save(kept)
+}
+
+// save writes timers, replacing the file atomically.
+func (s *Store) save(timers []Timer) error {
+	if timers == nil {
+		timers = []Timer{}
+	}
+	data, err := json.MarshalIndent(map[string][]Timer{"timers": timers}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", s.path, err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }() // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", s.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", s.path, err)
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,264 @@
+package sonos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tessro/riff/internal/core"
+	rifferrors "github.com/tessro/riff/internal/errors"
+)
+
+// Backend implements core.Backend for Sonos households.
+type Backend struct {
+	client      *Client
+	defaultRoom string
+}
+
+// Ensure Backend implements core.Backend
+var _ core.Backend = (*Backend)(nil)
+
+// NewBackend creates a Sonos backend. defaultRoom, if set, is the room used
+// when no device is specified and nothing is playing.
+func NewBackend(client *Client, defaultRoom string) *Backend {
+	return &Backend{
+		client:      client,
+		defaultRoom: defaultRoom,
+	}
+}
+
+// Client returns the underlying Sonos client.
+func (b *Backend) Client() *Client {
+	return b.client
+}
+
+// Platform returns core.PlatformSonos.
+func (b *Backend) Platform() core.Platform {
+	return core.PlatformSonos
+}
+
+// Refresh bypasses the discovery cache and re-reads the group topology.
+func (b *Backend) Refresh(ctx context.Context) error {
+	b.client.InvalidateGroupCache()
+	_, err := b.client.DiscoverFresh(ctx)
+	return err
+}
+
+// Devices returns every speaker in the household. Group coordinators are
+// reported as active.
+func (b *Backend) Devices(ctx context.Context) ([]core.Device, error) {
+	devices, groups, err := b.topology(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if groups == nil {
+		// Fall back to basic device info
+		result := make([]core.Device, len(devices))
+		for i, d := range devices {
+			result[i] = *speakerDevice(d, false)
+		}
+		return result, nil
+	}
+
+	var result []core.Device
+	for _, g := range groups {
+		for _, m := range g.Members {
+			isCoordinator := g.Coordinator != nil && m.UUID == g.Coordinator.UUID
+			result = append(result, *speakerDevice(m, isCoordinator))
+		}
+	}
+	return result, nil
+}
+
+// PlayerFor returns a player for device. Transport commands go to the
+// coordinator of the device's group; volume stays with the speaker itself.
+// A nil device selects a playing group, then the configured default room,
+// then the first coordinator.
+func (b *Backend) PlayerFor(ctx context.Context, device *core.Device) (core.Player, error) {
+	devices, groups, err := b.topology(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if device != nil {
+		for _, g := range groups {
+			for _, m := range g.Members {
+				if m.UUID == device.ID {
+					coordinator := g.Coordinator
+					if coordinator == nil {
+						coordinator = m
+					}
+					return NewGroupPlayer(b.client, coordinator, m), nil
+				}
+			}
+		}
+		for _, d := range devices {
+			if d.UUID == device.ID {
+				return NewPlayer(b.client, d), nil
+			}
+		}
+		return nil, fmt.Errorf("device '%s': %w", device.Name, rifferrors.ErrDeviceNotFound)
+	}
+
+	var coordinators []*Device
+	for _, g := range groups {
+		if g.Coordinator != nil {
+			coordinators = append(coordinators, g.Coordinator)
+		}
+	}
+	if len(coordinators) == 0 {
+		return NewPlayer(b.client, devices[0]), nil
+	}
+
+	for _, c := range coordinators {
+		if playing, err := b.client.IsPlaying(ctx, c); err == nil && playing {
+			return NewPlayer(b.client, c), nil
+		}
+	}
+	if b.defaultRoom != "" {
+		for _, c := range coordinators {
+			if strings.EqualFold(c.Name, b.defaultRoom) {
+				return NewPlayer(b.client, c), nil
+			}
+		}
+	}
+	return NewPlayer(b.client, coordinators[0]), nil
+}
+
+// Search is not supported; Sonos plays content found through other services.
+func (b *Backend) Search(ctx context.Context, query string, kinds []core.SearchKind, limit int) ([]core.SearchResult, error) {
+	return nil, fmt.Errorf("sonos search: %w", errors.ErrUnsupported)
+}
+
+// Groups returns the household's speaker groups.
+func (b *Backend) Groups(ctx context.Context) ([]Group, error) {
+	devices, err := b.client.Discover(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("discovery failed: %w", err)
+	}
+	if len(devices) == 0 {
+		return nil, nil
+	}
+	groups, err := b.client.ListGroups(ctx, devices[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get groups: %w", err)
+	}
+	return groups, nil
+}
+
+// Join adds the speaker named speaker to the group named target, which may
+// also be the name of any speaker in that group.
+func (b *Backend) Join(ctx context.Context, speaker, target string) error {
+	groups, err := b.Groups(ctx)
+	if err != nil {
+		return err
+	}
+
+	var device *Device
+	var coordinatorUUID string
+	for _, g := range groups {
+		for _, m := range g.Members {
+			if strings.EqualFold(m.Name, speaker) {
+				device = m
+			}
+			if (strings.EqualFold(m.Name, target) || strings.EqualFold(g.Name, target)) && g.Coordinator != nil {
+				coordinatorUUID = g.Coordinator.UUID
+			}
+		}
+	}
+	if device == nil {
+		return fmt.Errorf("speaker '%s': %w", speaker, rifferrors.ErrDeviceNotFound)
+	}
+	if coordinatorUUID == "" {
+		return fmt.Errorf("group '%s': %w", target, rifferrors.ErrDeviceNotFound)
+	}
+
+	if err := b.client.AddToGroup(ctx, device, coordinatorUUID); err != nil {
+		return fmt.Errorf("failed to add speaker to group: %w", err)
+	}
+	return nil
+}
+
+// Leave makes the speaker named speaker standalone and returns the name of
+// the group it left.
+func (b *Backend) Leave(ctx context.Context, speaker string) (string, error) {
+	groups, err := b.Groups(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	for _, g := range groups {
+		for _, m := range g.Members {
+			if !strings.EqualFold(m.Name, speaker) {
+				continue
+			}
+			if err := b.client.RemoveFromGroup(ctx, m); err != nil {
+				return "", fmt.Errorf("failed to remove speaker from group: %w", err)
+			}
+			return g.Name, nil
+		}
+	}
+	return "", fmt.Errorf("speaker '%s': %w", speaker, rifferrors.ErrDeviceNotFound)
+}
+
+// CoordinatorOf returns the name of the coordinator of the group the
+// speaker named speaker is in, which is speaker itself when it is
+// standalone or leads its group.
+func (b *Backend) CoordinatorOf(ctx context.Context, speaker string) (string, error) {
+	groups, err := b.Groups(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, g := range groups {
+		for _, m := range g.Members {
+			if strings.EqualFold(m.Name, speaker) && g.Coordinator != nil {
+				return g.Coordinator.Name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("speaker '%s': %w", speaker, rifferrors.ErrDeviceNotFound)
+}
+
+// Favorites returns the household's Sonos Favorites.
+func (b *Backend) Favorites(ctx context.Context) ([]Favorite, error) {
+	devices, _, err := b.topology(ctx)
+	if err != nil {
+		return nil, err
+	}
+	favorites, err := b.client.GetFavorites(ctx, devices[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get favorites: %w", err)
+	}
+	return favorites, nil
+}
+
+// topology returns discovered devices and, if available, their groups.
+// groups is nil when the zone group state could not be read.
+func (b *Backend) topology(ctx context.Context) ([]*Device, []Group, error) {
+	devices, err := b.client.Discover(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(devices) == 0 {
+		return nil, nil, errors.New("no sonos devices found")
+	}
+
+	groups, err := b.client.ListGroups(ctx, devices[0])
+	if err != nil {
+		return devices, nil, nil
+	}
+	return devices, groups, nil
+}
+
+// speakerDevice converts a Sonos device to a core.Device.
+func speakerDevice(d *Device, active bool) *core.Device {
+	return &core.Device{
+		ID:       d.UUID,
+		Name:     d.Name,
+		Type:     core.DeviceTypeSpeaker,
+		Platform: core.PlatformSonos,
+		IsActive: active,
+	}
+}
@@ -289,9 +289,15 @@ func (c *Client) Previous(ctx context.Context, device *Device) error {
 
 // Seek seeks to a position in the current track.
 func (c *Client) Seek(ctx context.Context, device *Device, target string) error {
+	return c.seek(ctx, device, "REL_TIME", target)
+}
+
+// seek moves the transport to target, a time or, with unit TRACK_NR, a
+// 1-based queue position.
+func (c *Client) seek(ctx context.Context, device *Device, unit, target string) error {
 	args := map[string]string{
 		"InstanceID": "0",
-		"Unit":       "REL_TIME",
+		"Unit":       unit,
 		"Target":     target,
 	}
 	_, err := c.soap.Call(ctx, device.IP, device.Port, AVTransportEndpoint, AVTransportService, "Seek", args)
@@ -342,7 +348,29 @@ func (c *Client) ClearQueue(ctx context.Context, device *Device) error {
 
 // PlayFromQueue starts playback from the queue.
 func (c *Client) PlayFromQueue(ctx context.Context, device *Device) error {
-	// Get the device's queue URI
+	if err := c.useQueue(ctx, device); err != nil {
+		return err
+	}
+	return c.Play(ctx, device)
+}
+
+// PlayFromQueueAt starts playback from the queue at a 1-based track number
+// and a position (H:MM:SS) within that track.
+func (c *Client) PlayFromQueueAt(ctx context.Context, device *Device, track int, position string) error {
+	if err := c.useQueue(ctx, device); err != nil {
+		return err
+	}
+	if err := c.seek(ctx, device, "TRACK_NR", strconv.Itoa(track)); err != nil {
+		return fmt.Errorf("seek to track %d: %w", track, err)
+	}
+	if err := c.Seek(ctx, device, position); err != nil {
+		return fmt.Errorf("seek to %s: %w", position, err)
+	}
+	return c.Play(ctx, device)
+}
+
+// useQueue makes the device's queue its transport source.
+func (c *Client) useQueue(ctx context.Context, device *Device) error {
 	queueURI := "x-rincon-queue:" + device.UUID + "#0"
 	args := map[string]string{
 		"InstanceID":         "0",
@@ -352,7 +380,7 @@ func (c *Client) PlayFromQueue(ctx context.Context, device *Device) error {
 	if _, err := c.soap.Call(ctx, device.IP, device.Port, AVTransportEndpoint, AVTransportService, "SetAVTransportURI", args); err != nil {
 		return fmt.Errorf("set queue URI: %w", err)
 	}
-	return c.Play(ctx, device)
+	return nil
 }
 
 // MusicService represents a configured music service on the Sonos.
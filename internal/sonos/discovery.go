@@ -1,35 +1,25 @@
 package sonos
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
-	"net"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/tessro/riff/internal/metrics"
+	"github.com/tessro/riff/internal/upnp"
 )
 
 const (
-	ssdpAddr      = "239.255.255.250:1900"
 	sonosURN      = "urn:schemas-upnp-org:device:ZonePlayer:1"
 	defaultTTL    = 5 * time.Minute
 	fileCacheTTL  = 5 * time.Minute
 )
 
-var mSearchRequest = []byte(
-	"M-SEARCH * HTTP/1.1\r\n" +
-		"HOST: 239.255.255.250:1900\r\n" +
-		"MAN: \"ssdp:discover\"\r\n" +
-		"MX: 2\r\n" +
-		"ST: " + sonosURN + "\r\n" +
-		"\r\n",
-)
-
 // Device represents a discovered Sonos device.
 type Device struct {
 	IP       string    `json:"ip"`
@@ -64,18 +54,10 @@ func NewDiscovery(timeout time.Duration) *Discovery {
 		timeout = 3 * time.Second
 	}
 
-	// Determine cache directory
-	cacheDir := os.Getenv("XDG_CACHE_HOME")
-	if cacheDir == "" {
-		home, _ := os.UserHomeDir()
-		cacheDir = filepath.Join(home, ".cache")
-	}
-	cacheDir = filepath.Join(cacheDir, "riff")
-
 	return &Discovery{
 		timeout:  timeout,
 		ttl:      defaultTTL,
-		cacheDir: cacheDir,
+		cacheDir: upnp.CacheDir(),
 		devices:  make(map[string]*Device),
 		aliases:  make(map[string]string),
 	}
@@ -113,6 +95,20 @@ func (d *Discovery) loadCache() ([]*Device, bool) {
 	return cache.Devices, true
 }
 
+// CachedSpeakers returns the speakers in the on-disk discovery cache,
+// however old, without searching the network.
+func CachedSpeakers() []*Device {
+	data, err := os.ReadFile(filepath.Join(upnp.CacheDir(), "sonos-devices.json"))
+	if err != nil {
+		return nil
+	}
+	var cache deviceCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil
+	}
+	return cache.Devices
+}
+
 // saveCache writes devices to the file cache.
 func (d *Discovery) saveCache(devices []*Device) {
 	cache := deviceCache{
@@ -145,9 +141,11 @@ func (d *Discovery) SetAlias(alias, target string) {
 func (d *Discovery) Discover(ctx context.Context) ([]*Device, error) {
 	// Check file cache first
 	if devices, ok := d.loadCache(); ok {
+		metrics.DiscoveryLookups.WithLabelValues("sonos", "hit").Inc()
 		return devices, nil
 	}
 
+	metrics.DiscoveryLookups.WithLabelValues("sonos", "miss").Inc()
 	return d.discoverSSDP(ctx)
 }
 
@@ -158,53 +156,18 @@ func (d *Discovery) DiscoverFresh(ctx context.Context) ([]*Device, error) {
 
 // discoverSSDP performs the actual SSDP discovery.
 func (d *Discovery) discoverSSDP(ctx context.Context) ([]*Device, error) {
-	addr, err := net.ResolveUDPAddr("udp4", ssdpAddr)
-	if err != nil {
-		return nil, fmt.Errorf("resolve ssdp addr: %w", err)
-	}
-
-	conn, err := net.ListenUDP("udp4", nil)
-	if err != nil {
-		return nil, fmt.Errorf("listen udp: %w", err)
-	}
-	defer func() { _ = conn.Close() }()
-
-	// Set read deadline
-	deadline := time.Now().Add(d.timeout)
-	_ = conn.SetReadDeadline(deadline)
-
-	// Send M-SEARCH
-	if _, err := conn.WriteToUDP(mSearchRequest, addr); err != nil {
-		return nil, fmt.Errorf("send m-search: %w", err)
+	start := time.Now()
+	responses, searchErr := upnp.Search(ctx, sonosURN, d.timeout)
+	metrics.DiscoveryDuration.WithLabelValues("sonos").Observe(metrics.Since(start))
+	if searchErr != nil && len(responses) == 0 {
+		return nil, searchErr
 	}
 
-	// Collect responses
 	var devices []*Device
 	seen := make(map[string]bool)
-	buf := make([]byte, 2048)
-
-	for {
-		select {
-		case <-ctx.Done():
-			d.saveCache(devices)
-			return devices, ctx.Err()
-		default:
-		}
-
-		n, remoteAddr, err := conn.ReadFromUDP(buf)
-		if err != nil {
-			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-				break // Discovery complete
-			}
-			continue
-		}
-
-		device, err := parseResponse(buf[:n], remoteAddr)
-		if err != nil || device == nil {
-			continue
-		}
-
-		if seen[device.UUID] {
+	for _, resp := range responses {
+		device := parseResponse(resp)
+		if device == nil || seen[device.UUID] {
 			continue
 		}
 		seen[device.UUID] = true
@@ -221,7 +184,7 @@ func (d *Discovery) discoverSSDP(ctx context.Context) ([]*Device, error) {
 	// Save to file cache
 	d.saveCache(devices)
 
-	return devices, nil
+	return devices, searchErr
 }
 
 // GetDevice returns a cached device by UUID, name, or alias.
@@ -271,27 +234,15 @@ func (d *Discovery) CachedDevices() []*Device {
 	return devices
 }
 
-// parseResponse parses an SSDP response into a Device.
-func parseResponse(data []byte, addr *net.UDPAddr) (*Device, error) {
-	resp, err := http.ReadResponse(bufio.NewReader(strings.NewReader(string(data))), nil)
-	if err != nil {
-		return nil, err
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	// Verify it's a Sonos device
-	st := resp.Header.Get("ST")
-	if st != sonosURN {
-		return nil, nil
-	}
-
-	location := resp.Header.Get("Location")
-	usn := resp.Header.Get("USN")
+// parseResponse converts an SSDP response into a Device.
+func parseResponse(resp upnp.SearchResponse) *Device {
+	location := resp.Location
+	usn := resp.USN
 
 	// Extract UUID from USN (format: uuid:RINCON_xxx::urn:...)
 	uuid := extractUUID(usn)
 	if uuid == "" {
-		return nil, nil
+		return nil
 	}
 
 	// Extract port from location URL
@@ -308,11 +259,11 @@ func parseResponse(data []byte, addr *net.UDPAddr) (*Device, error) {
 	}
 
 	return &Device{
-		IP:       addr.IP.String(),
+		IP:       resp.IP.String(),
 		Port:     port,
 		UUID:     uuid,
 		Location: location,
-	}, nil
+	}
 }
 
 // extractUUID extracts the UUID from a USN header.
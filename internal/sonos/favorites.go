@@ -0,0 +1,77 @@
+package sonos
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Favorite is an entry in the household's Sonos Favorites.
+type Favorite struct {
+	Title string `json:"title"`
+	URI   string `json:"uri"`
+	// Metadata is the DIDL-Lite description Sonos plays the URI with.
+	Metadata string `json:"-"`
+}
+
+// IsContainer reports whether the favorite is a playlist or album, which
+// plays through the queue rather than as a single URI.
+func (f *Favorite) IsContainer() bool {
+	return strings.HasPrefix(f.URI, "x-rincon-cpcontainer:") ||
+		strings.Contains(f.Metadata, "object.container")
+}
+
+// GetFavorites lists the household's Sonos Favorites.
+func (c *Client) GetFavorites(ctx context.Context, device *Device) ([]Favorite, error) {
+	args := map[string]string{
+		"ObjectID":       "FV:2",
+		"BrowseFlag":     "BrowseDirectChildren",
+		"Filter":         "dc:title,res,r:resMD",
+		"StartingIndex":  "0",
+		"RequestedCount": "100",
+		"SortCriteria":   "",
+	}
+	resp, err := c.soap.Call(ctx, device.IP, device.Port, ContentDirectoryEndpoint, ContentDirectoryService, "Browse", args)
+	if err != nil {
+		return nil, err
+	}
+
+	var envelope struct {
+		Body struct {
+			Response struct {
+				Result string `xml:"Result"`
+			} `xml:"BrowseResponse"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(resp, &envelope); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+
+	return parseFavorites(envelope.Body.Response.Result)
+}
+
+// parseFavorites converts a DIDL-Lite favorites listing. Unlike the queue
+// listing it is not unescaped first: each item's resMD is escaped DIDL-Lite.
+func parseFavorites(result string) ([]Favorite, error) {
+	if result == "" {
+		return nil, nil
+	}
+
+	var didl struct {
+		Items []struct {
+			Title string `xml:"http://purl.org/dc/elements/1.1/ title"`
+			Res   string `xml:"res"`
+			ResMD string `xml:"urn:schemas-rinconnetworks-com:metadata-1-0/ resMD"`
+		} `xml:"item"`
+	}
+	if err := xml.Unmarshal([]byte(result), &didl); err != nil {
+		return nil, fmt.Errorf("parse favorites: %w", err)
+	}
+
+	favorites := make([]Favorite, len(didl.Items))
+	for i, item := range didl.Items {
+		favorites[i] = Favorite{Title: item.Title, URI: item.Res, Metadata: item.ResMD}
+	}
+	return favorites, nil
+}
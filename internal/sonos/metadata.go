@@ -3,6 +3,7 @@ package sonos
 import (
 	"encoding/xml"
 	"html"
+	"net/url"
 	"regexp"
 	"strings"
 
@@ -126,7 +127,11 @@ func splitArtists(creator string) []string {
 
 // ExtractSpotifyTrackID extracts a Spotify track ID from a Sonos URI.
 func ExtractSpotifyTrackID(uri string) string {
-	// Format: x-sonos-spotify:spotify:track:TRACKID?...
+	// Format: x-sonos-spotify:spotify:track:TRACKID?..., often with the
+	// colons escaped as %3a
+	if unescaped, err := url.PathUnescape(uri); err == nil {
+		uri = unescaped
+	}
 	uri = strings.TrimPrefix(uri, "x-sonos-spotify:")
 
 	if strings.HasPrefix(uri, "spotify:track:") {
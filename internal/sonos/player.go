@@ -2,6 +2,7 @@ package sonos
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -14,13 +15,54 @@ import (
 type Player struct {
 	client *Client
 	device *Device
+	member *Device // speaker whose volume is controlled; defaults to device
 }
 
+// Ensure Player implements core.Player
+var _ core.Player = (*Player)(nil)
+
+// Ensure Player implements core.QueueEditor
+var _ core.QueueEditor = (*Player)(nil)
+
+// Ensure Player implements core.GroupVolumeController
+var _ core.GroupVolumeController = (*Player)(nil)
+
+// Ensure Player implements core.StreamPlayer
+var _ core.StreamPlayer = (*Player)(nil)
+
+// Ensure Player implements core.MediaPlayer
+var _ core.MediaPlayer = (*Player)(nil)
+
+// Ensure Player implements core.MediaQueuer
+var _ core.MediaQueuer = (*Player)(nil)
+
+// Ensure Player implements core.PlayModeController
+var _ core.PlayModeController = (*Player)(nil)
+
+// Ensure Player implements core.SessionPlayer
+var _ core.SessionPlayer = (*Player)(nil)
+
+// capabilities are the optional operations Sonos supports. There is no
+// play history.
+const capabilities = core.CapQueueRead | core.CapQueueAdd | core.CapQueueEdit |
+	core.CapShuffle | core.CapRepeat | core.CapSeek | core.CapGroupVolume | core.CapStream
+
 // NewPlayer creates a new Sonos player for the given device.
 func NewPlayer(client *Client, device *Device) *Player {
 	return &Player{
 		client: client,
 		device: device,
+		member: device,
+	}
+}
+
+// NewGroupPlayer creates a player that sends transport commands to the
+// group coordinator while reading and setting the volume of member.
+func NewGroupPlayer(client *Client, coordinator, member *Device) *Player {
+	return &Player{
+		client: client,
+		device: coordinator,
+		member: member,
 	}
 }
 
@@ -52,7 +94,7 @@ func (p *Player) Seek(ctx context.Context, positionMs int) error {
 
 // Volume sets the volume level (0-100).
 func (p *Player) Volume(ctx context.Context, percent int) error {
-	return p.client.SetVolume(ctx, p.device, percent)
+	return p.client.SetVolume(ctx, p.member, percent)
 }
 
 // GetState returns the current playback state.
@@ -97,7 +139,7 @@ func (p *Player) GetState(ctx context.Context) (*core.PlaybackState, error) {
 
 		go func() {
 			defer wg.Done()
-			v, err := p.client.GetVolume(ctx, p.device)
+			v, err := p.client.GetVolume(ctx, p.member)
 			mu.Lock()
 			if err != nil && r.err == nil {
 				r.err = fmt.Errorf("get volume: %w", err)
@@ -131,19 +173,240 @@ func (p *Player) GetState(ctx context.Context) (*core.PlaybackState, error) {
 
 // GetQueue returns the current queue.
 func (p *Player) GetQueue(ctx context.Context) (*core.Queue, error) {
-	// Sonos queue retrieval is more complex, returning empty for now
-	return &core.Queue{}, nil
+	tracks, err := p.client.GetQueue(ctx, p.device)
+	if err != nil {
+		return nil, fmt.Errorf("get queue: %w", err)
+	}
+
+	queue := &core.Queue{Tracks: tracks}
+	if pos, err := p.client.GetPositionInfo(ctx, p.device); err == nil && pos.Track > 0 && pos.Track <= len(tracks) {
+		queue.CurrentIndex = pos.Track - 1
+	}
+	return queue, nil
 }
 
-// GetRecentlyPlayed returns recently played tracks.
-// Sonos doesn't have a recently played API, so this returns empty.
+// GetRecentlyPlayed is unsupported; Sonos keeps no play history.
 func (p *Player) GetRecentlyPlayed(ctx context.Context, limit int) ([]core.HistoryEntry, error) {
-	return nil, nil
+	return nil, core.Require(p, core.CapHistory)
 }
 
 // AddToQueue adds a track to the queue.
 func (p *Player) AddToQueue(ctx context.Context, trackURI string) error {
-	return p.client.AddURIToQueue(ctx, p.device, trackURI, "")
+	sonosURI, metadata := ConvertSpotifyURIWithMetadata(trackURI)
+	return p.client.AddURIToQueue(ctx, p.device, sonosURI, metadata)
+}
+
+// RemoveFromQueue removes the track at a 1-based queue position.
+func (p *Player) RemoveFromQueue(ctx context.Context, position int) error {
+	return p.client.RemoveFromQueue(ctx, p.device, position)
+}
+
+// MoveInQueue moves the track at 1-based position from to position to.
+func (p *Player) MoveInQueue(ctx context.Context, from, to int) error {
+	return p.client.MoveInQueue(ctx, p.device, from, to)
+}
+
+// ClearQueue removes every track from the queue.
+func (p *Player) ClearQueue(ctx context.Context) error {
+	return p.client.ClearQueue(ctx, p.device)
+}
+
+// GroupVolume returns the volume of the player's group.
+func (p *Player) GroupVolume(ctx context.Context) (int, error) {
+	return p.client.GetGroupVolume(ctx, p.device)
+}
+
+// SetGroupVolume sets the volume of the player's group.
+func (p *Player) SetGroupVolume(ctx context.Context, percent int) error {
+	return p.client.SetGroupVolume(ctx, p.device, percent)
+}
+
+// CoordinatesGroup reports whether the player targets the coordinator of a
+// group with other rooms in it. A player for one room of a group, which
+// controls only that room's volume, does not.
+func (p *Player) CoordinatesGroup(ctx context.Context) (bool, error) {
+	if p.member.UUID != p.device.UUID {
+		return false, nil
+	}
+	groups, err := p.client.ListGroups(ctx, p.device)
+	if err != nil {
+		return false, err
+	}
+	for _, g := range groups {
+		if g.Coordinator != nil && g.Coordinator.UUID == p.device.UUID {
+			return len(g.Members) > 1, nil
+		}
+	}
+	return false, nil
+}
+
+// PlayMode returns the group's shuffle and repeat settings.
+func (p *Player) PlayMode(ctx context.Context) (shuffle bool, repeat string, err error) {
+	return p.client.GetPlayMode(ctx, p.device)
+}
+
+// Shuffle turns shuffle on or off for the group, keeping its repeat mode.
+func (p *Player) Shuffle(ctx context.Context, state bool) error {
+	_, repeat, err := p.client.GetPlayMode(ctx, p.device)
+	if err != nil {
+		return err
+	}
+	return p.client.SetPlayMode(ctx, p.device, state, repeat)
+}
+
+// Repeat sets the group's repeat mode (off, track, context), keeping its
+// shuffle setting.
+func (p *Player) Repeat(ctx context.Context, mode string) error {
+	shuffle, _, err := p.client.GetPlayMode(ctx, p.device)
+	if err != nil {
+		return err
+	}
+	return p.client.SetPlayMode(ctx, p.device, shuffle, mode)
+}
+
+// EQ returns the speaker's tone controls.
+func (p *Player) EQ(ctx context.Context) (*EQ, error) {
+	return p.client.GetEQ(ctx, p.member)
+}
+
+// SetEQ sets the speaker's tone controls.
+func (p *Player) SetEQ(ctx context.Context, eq EQ) error {
+	return p.client.SetEQ(ctx, p.member, eq)
+}
+
+// PlayFavorite plays an entry from Sonos Favorites. Playlists and albums
+// replace the queue; stations and tracks play directly.
+func (p *Player) PlayFavorite(ctx context.Context, f Favorite) error {
+	if !f.IsContainer() {
+		return p.client.PlayURI(ctx, p.device, f.URI, f.Metadata)
+	}
+	// Clear queue errors are non-fatal
+	_ = p.client.ClearQueue(ctx, p.device)
+	if err := p.client.AddURIToQueue(ctx, p.device, f.URI, f.Metadata); err != nil {
+		return fmt.Errorf("add to queue: %w", err)
+	}
+	return p.client.PlayFromQueue(ctx, p.device)
+}
+
+// sessionTracks caps how much of the queue a session carries.
+const sessionTracks = 100
+
+// Session returns the Spotify track playing and, when it comes from the
+// queue, the Spotify tracks queued after it. Sonos doesn't know which album
+// or playlist queued them, so the session has no context.
+func (p *Player) Session(ctx context.Context) (*core.Session, error) {
+	pos, err := p.client.GetPositionInfo(ctx, p.device)
+	if err != nil {
+		return nil, fmt.Errorf("get position info: %w", err)
+	}
+	id := ExtractSpotifyTrackID(pos.TrackURI)
+	if id == "" {
+		return nil, fmt.Errorf("%s isn't playing Spotify content: %w", p.Describe(), errors.ErrUnsupported)
+	}
+	volume, err := p.client.GetVolume(ctx, p.member)
+	if err != nil {
+		return nil, fmt.Errorf("get volume: %w", err)
+	}
+
+	s := &core.Session{
+		Track:    "spotify:track:" + id,
+		Tracks:   []string{"spotify:track:" + id},
+		Progress: parseDuration(pos.RelTime),
+		Volume:   volume,
+	}
+	media, err := p.client.GetMediaInfo(ctx, p.device)
+	if err != nil || !strings.HasPrefix(media.CurrentURI, "x-rincon-queue:") {
+		return s, nil
+	}
+	if pos.Track < 1 {
+		return s, nil
+	}
+	// Browse from the track after the current one, however deep it is
+	next, _, err := p.client.BrowseQueue(ctx, p.device, pos.Track, sessionTracks)
+	if err != nil {
+		return s, nil
+	}
+	for _, t := range next {
+		if len(s.Tracks) == sessionTracks {
+			break
+		}
+		if id := ExtractSpotifyTrackID(t.URI); id != "" {
+			s.Tracks = append(s.Tracks, "spotify:track:"+id)
+		}
+	}
+	return s, nil
+}
+
+// ResumeSession replaces the queue with s and plays it from the same
+// position. The whole album or playlist is queued when there is one, so the
+// room carries on through it; otherwise s.Tracks are.
+func (p *Player) ResumeSession(ctx context.Context, s *core.Session) error {
+	// Clear queue errors are non-fatal
+	_ = p.client.ClearQueue(ctx, p.device)
+	track := p.queueContext(ctx, s)
+	if track == 0 {
+		tracks := s.Tracks
+		if len(tracks) == 0 {
+			tracks = []string{s.Track}
+		}
+		for _, uri := range tracks {
+			sonosURI, metadata := ConvertSpotifyURIWithMetadata(uri)
+			if err := p.client.AddURIToQueue(ctx, p.device, sonosURI, metadata); err != nil {
+				return fmt.Errorf("add to queue: %w", err)
+			}
+		}
+		track = 1
+	}
+	return p.client.PlayFromQueueAt(ctx, p.device, track, formatDuration(s.Progress))
+}
+
+// queueContext queues the session's album, playlist or artist and returns
+// the queue position of its track. It returns 0, leaving the queue empty,
+// if the context couldn't be queued or doesn't include the track.
+func (p *Player) queueContext(ctx context.Context, s *core.Session) int {
+	kind := strings.Split(s.Context, ":")
+	if len(kind) < 3 || kind[0] != "spotify" || (kind[1] != "album" && kind[1] != "playlist" && kind[1] != "artist") {
+		return 0
+	}
+	sonosURI, metadata := ConvertSpotifyURIWithMetadata(s.Context)
+	if err := p.client.AddURIToQueue(ctx, p.device, sonosURI, metadata); err != nil {
+		return 0
+	}
+	if track := p.findInQueue(ctx, strings.TrimPrefix(s.Track, "spotify:track:")); track > 0 {
+		return track
+	}
+	_ = p.client.ClearQueue(ctx, p.device)
+	return 0
+}
+
+// findInQueue returns the queue position of the Spotify track id, paging
+// through the whole queue, or 0 if it isn't queued.
+func (p *Player) findInQueue(ctx context.Context, id string) int {
+	for start := 0; ; {
+		page, total, err := p.client.BrowseQueue(ctx, p.device, start, queueBrowseLimit)
+		if err != nil {
+			return 0
+		}
+		for i, t := range page {
+			if ExtractSpotifyTrackID(t.URI) == id {
+				return start + i + 1
+			}
+		}
+		start += len(page)
+		if len(page) == 0 || start >= total {
+			return 0
+		}
+	}
+}
+
+// Capabilities returns the operations Sonos supports.
+func (p *Player) Capabilities() core.Capability {
+	return capabilities
+}
+
+// Describe names the player for error messages.
+func (p *Player) Describe() string {
+	return fmt.Sprintf("Sonos (%s)", p.member.Name)
 }
 
 // PlayURI plays a specific URI on the device.
@@ -169,6 +432,18 @@ func (p *Player) PlayURI(ctx context.Context, uri string) error {
 	return p.client.PlayURI(ctx, p.device, sonosURI, "")
 }
 
+// PlayMedia plays uri, described by DIDL-Lite metadata, replacing whatever
+// is playing.
+func (p *Player) PlayMedia(ctx context.Context, uri, metadata string) error {
+	return p.client.PlayURI(ctx, p.device, uri, metadata)
+}
+
+// QueueMedia adds uri, described by DIDL-Lite metadata, to the end of the
+// queue.
+func (p *Player) QueueMedia(ctx context.Context, uri, metadata string) error {
+	return p.client.AddURIToQueue(ctx, p.device, uri, metadata)
+}
+
 // ConvertSpotifyURIWithMetadata converts a Spotify URI to Sonos format with DIDL-Lite metadata.
 func ConvertSpotifyURIWithMetadata(uri string) (sonosURI, metadata string) {
 	if !strings.HasPrefix(uri, "spotify:") {
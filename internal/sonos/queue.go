@@ -0,0 +1,182 @@
+package sonos
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"strconv"
+
+	"github.com/tessro/riff/internal/core"
+)
+
+// queueBrowseLimit caps how many queue entries are fetched at once.
+const queueBrowseLimit = 500
+
+// queueDIDL is the DIDL-Lite listing returned when browsing the queue.
+type queueDIDL struct {
+	Items []struct {
+		ID      string `xml:"id,attr"`
+		Title   string `xml:"http://purl.org/dc/elements/1.1/ title"`
+		Creator string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+		Album   string `xml:"urn:schemas-upnp-org:metadata-1-0/upnp/ album"`
+		Res     struct {
+			URI      string `xml:",chardata"`
+			Duration string `xml:"duration,attr"`
+		} `xml:"res"`
+	} `xml:"item"`
+}
+
+// GetQueue lists the tracks in the device's queue, up to queueBrowseLimit.
+func (c *Client) GetQueue(ctx context.Context, device *Device) ([]core.Track, error) {
+	tracks, _, err := c.BrowseQueue(ctx, device, 0, queueBrowseLimit)
+	return tracks, err
+}
+
+// BrowseQueue lists up to count tracks of the device's queue starting at
+// the 0-based index start, and returns how many tracks the queue holds.
+func (c *Client) BrowseQueue(ctx context.Context, device *Device, start, count int) ([]core.Track, int, error) {
+	args := map[string]string{
+		"ObjectID":       "Q:0",
+		"BrowseFlag":     "BrowseDirectChildren",
+		"Filter":         "dc:title,dc:creator,upnp:album,res",
+		"StartingIndex":  strconv.Itoa(start),
+		"RequestedCount": strconv.Itoa(count),
+		"SortCriteria":   "",
+	}
+	resp, err := c.soap.Call(ctx, device.IP, device.Port, ContentDirectoryEndpoint, ContentDirectoryService, "Browse", args)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var envelope struct {
+		Body struct {
+			Response struct {
+				Result       string `xml:"Result"`
+				TotalMatches int    `xml:"TotalMatches"`
+			} `xml:"BrowseResponse"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(resp, &envelope); err != nil {
+		return nil, 0, fmt.Errorf("parse response: %w", err)
+	}
+
+	tracks, err := parseQueue(envelope.Body.Response.Result)
+	if err != nil {
+		return nil, 0, err
+	}
+	return tracks, envelope.Body.Response.TotalMatches, nil
+}
+
+// parseQueue converts a DIDL-Lite queue listing into tracks.
+func parseQueue(result string) ([]core.Track, error) {
+	if result == "" {
+		return nil, nil
+	}
+
+	var didl queueDIDL
+	if err := xml.Unmarshal([]byte(html.UnescapeString(result)), &didl); err != nil {
+		return nil, fmt.Errorf("parse queue: %w", err)
+	}
+
+	tracks := make([]core.Track, len(didl.Items))
+	for i, item := range didl.Items {
+		tracks[i] = core.Track{
+			ID:       item.ID,
+			URI:      item.Res.URI,
+			Title:    item.Title,
+			Artist:   item.Creator,
+			Artists:  splitArtists(item.Creator),
+			Album:    item.Album,
+			Duration: parseDuration(item.Res.Duration),
+			Source:   detectSource(item.Res.URI),
+		}
+	}
+	return tracks, nil
+}
+
+// RemoveFromQueue removes the track at a 1-based queue position.
+func (c *Client) RemoveFromQueue(ctx context.Context, device *Device, position int) error {
+	args := map[string]string{
+		"InstanceID": "0",
+		"ObjectID":   "Q:0/" + strconv.Itoa(position),
+		"UpdateID":   "0",
+	}
+	_, err := c.soap.Call(ctx, device.IP, device.Port, AVTransportEndpoint, AVTransportService, "RemoveTrackFromQueue", args)
+	return err
+}
+
+// MoveInQueue moves the track at 1-based position from so that it ends up
+// at position to.
+func (c *Client) MoveInQueue(ctx context.Context, device *Device, from, to int) error {
+	// InsertBefore refers to positions before the move, so moving a track
+	// down the queue has to skip past the destination.
+	insertBefore := to
+	if to > from {
+		insertBefore = to + 1
+	}
+	args := map[string]string{
+		"InstanceID":     "0",
+		"StartingIndex":  strconv.Itoa(from),
+		"NumberOfTracks": "1",
+		"InsertBefore":   strconv.Itoa(insertBefore),
+		"UpdateID":       "0",
+	}
+	_, err := c.soap.Call(ctx, device.IP, device.Port, AVTransportEndpoint, AVTransportService, "ReorderTracksInQueue", args)
+	return err
+}
+
+// GetGroupVolume returns the volume of the group coordinated by device.
+func (c *Client) GetGroupVolume(ctx context.Context, device *Device) (int, error) {
+	args := map[string]string{"InstanceID": "0"}
+	resp, err := c.soap.Call(ctx, device.IP, device.Port, GroupRenderingControlEndpoint, GroupRenderingControlService, "GetGroupVolume", args)
+	if err != nil {
+		return 0, err
+	}
+
+	var envelope struct {
+		Body struct {
+			Response struct {
+				CurrentVolume string `xml:"CurrentVolume"`
+			} `xml:"GetGroupVolumeResponse"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(resp, &envelope); err != nil {
+		return 0, fmt.Errorf("parse response: %w", err)
+	}
+
+	vol, _ := strconv.Atoi(envelope.Body.Response.CurrentVolume)
+	return vol, nil
+}
+
+// SetGroupVolume sets the volume of the group coordinated by device,
+// keeping the relative levels of its members.
+func (c *Client) SetGroupVolume(ctx context.Context, device *Device, volume int) error {
+	if volume < 0 {
+		volume = 0
+	}
+	if volume > 100 {
+		volume = 100
+	}
+
+	// Sonos requires a snapshot of member volumes before a group change
+	args := map[string]string{"InstanceID": "0"}
+	if _, err := c.soap.Call(ctx, device.IP, device.Port, GroupRenderingControlEndpoint, GroupRenderingControlService, "SnapshotGroupVolume", args); err != nil {
+		return fmt.Errorf("snapshot group volume: %w", err)
+	}
+
+	args = map[string]string{
+		"InstanceID":    "0",
+		"DesiredVolume": strconv.Itoa(volume),
+	}
+	if _, err := c.soap.Call(ctx, device.IP, device.Port, GroupRenderingControlEndpoint, GroupRenderingControlService, "SetGroupVolume", args); err != nil {
+		return err
+	}
+
+	// Member volumes have all moved
+	c.mu.Lock()
+	c.volumeCache = make(map[string]*volumeCache)
+	c.mu.Unlock()
+
+	return nil
+}
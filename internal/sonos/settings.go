@@ -0,0 +1,144 @@
+package sonos
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+)
+
+// playModes maps shuffle and repeat ("off", "track", "context") to Sonos
+// play modes.
+var playModes = map[bool]map[string]string{
+	false: {"off": "NORMAL", "context": "REPEAT_ALL", "track": "REPEAT_ONE"},
+	true:  {"off": "SHUFFLE_NOREPEAT", "context": "SHUFFLE", "track": "SHUFFLE_REPEAT_ONE"},
+}
+
+// GetPlayMode returns the shuffle and repeat settings of a group
+// coordinator. repeat is "off", "track" or "context".
+func (c *Client) GetPlayMode(ctx context.Context, device *Device) (shuffle bool, repeat string, err error) {
+	args := map[string]string{"InstanceID": "0"}
+	resp, err := c.soap.Call(ctx, device.IP, device.Port, AVTransportEndpoint, AVTransportService, "GetTransportSettings", args)
+	if err != nil {
+		return false, "", err
+	}
+
+	var envelope struct {
+		Body struct {
+			Response struct {
+				PlayMode string `xml:"PlayMode"`
+			} `xml:"GetTransportSettingsResponse"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(resp, &envelope); err != nil {
+		return false, "", fmt.Errorf("parse response: %w", err)
+	}
+
+	for s, modes := range playModes {
+		for r, mode := range modes {
+			if mode == envelope.Body.Response.PlayMode {
+				return s, r, nil
+			}
+		}
+	}
+	return false, "off", nil
+}
+
+// SetPlayMode sets the shuffle and repeat settings of a group coordinator.
+func (c *Client) SetPlayMode(ctx context.Context, device *Device, shuffle bool, repeat string) error {
+	mode, ok := playModes[shuffle][repeat]
+	if !ok {
+		return fmt.Errorf("invalid repeat mode %q", repeat)
+	}
+	args := map[string]string{
+		"InstanceID":  "0",
+		"NewPlayMode": mode,
+	}
+	_, err := c.soap.Call(ctx, device.IP, device.Port, AVTransportEndpoint, AVTransportService, "SetPlayMode", args)
+	return err
+}
+
+// EQ holds a speaker's tone controls. Bass and Treble range from -10 to 10.
+type EQ struct {
+	Bass     int  `json:"bass"`
+	Treble   int  `json:"treble"`
+	Loudness bool `json:"loudness"`
+}
+
+// GetEQ reads a speaker's tone controls.
+func (c *Client) GetEQ(ctx context.Context, device *Device) (*EQ, error) {
+	bass, err := c.renderingValue(ctx, device, "GetBass", "CurrentBass", nil)
+	if err != nil {
+		return nil, err
+	}
+	treble, err := c.renderingValue(ctx, device, "GetTreble", "CurrentTreble", nil)
+	if err != nil {
+		return nil, err
+	}
+	loudness, err := c.renderingValue(ctx, device, "GetLoudness", "CurrentLoudness", map[string]string{"Channel": "Master"})
+	if err != nil {
+		return nil, err
+	}
+	return &EQ{Bass: bass, Treble: treble, Loudness: loudness == 1}, nil
+}
+
+// SetEQ sets a speaker's tone controls.
+func (c *Client) SetEQ(ctx context.Context, device *Device, eq EQ) error {
+	loudness := "0"
+	if eq.Loudness {
+		loudness = "1"
+	}
+	calls := []struct {
+		action string
+		args   map[string]string
+	}{
+		{"SetBass", map[string]string{"DesiredBass": strconv.Itoa(clampEQ(eq.Bass))}},
+		{"SetTreble", map[string]string{"DesiredTreble": strconv.Itoa(clampEQ(eq.Treble))}},
+		{"SetLoudness", map[string]string{"Channel": "Master", "DesiredLoudness": loudness}},
+	}
+	for _, call := range calls {
+		call.args["InstanceID"] = "0"
+		if _, err := c.soap.Call(ctx, device.IP, device.Port, RenderingControlEndpoint, RenderingControlService, call.action, call.args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderingValue calls a RenderingControl getter and returns the integer
+// in its response element named field.
+func (c *Client) renderingValue(ctx context.Context, device *Device, action, field string, extra map[string]string) (int, error) {
+	args := map[string]string{"InstanceID": "0"}
+	for k, v := range extra {
+		args[k] = v
+	}
+	resp, err := c.soap.Call(ctx, device.IP, device.Port, RenderingControlEndpoint, RenderingControlService, action, args)
+	if err != nil {
+		return 0, err
+	}
+
+	var envelope struct {
+		Body struct {
+			Response struct {
+				Values []struct {
+					XMLName xml.Name
+					Value   string `xml:",chardata"`
+				} `xml:",any"`
+			} `xml:",any"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(resp, &envelope); err != nil {
+		return 0, fmt.Errorf("parse response: %w", err)
+	}
+	for _, v := range envelope.Body.Response.Values {
+		if v.XMLName.Local == field {
+			n, _ := strconv.Atoi(v.Value)
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("%s response has no %s", action, field)
+}
+
+func clampEQ(v int) int {
+	return min(max(v, -10), 10)
+}
@@ -1,14 +1,11 @@
 package sonos
 
 import (
-	"bytes"
 	"context"
-	"encoding/xml"
 	"fmt"
-	"io"
-	"net/http"
-	"strings"
-	"time"
+	"sort"
+
+	"github.com/tessro/riff/internal/upnp"
 )
 
 const (
@@ -17,100 +14,47 @@ const (
 	RenderingControlEndpoint = "/MediaRenderer/RenderingControl/Control"
 	ZoneGroupTopologyEndpoint = "/ZoneGroupTopology/Control"
 	DevicePropertiesEndpoint = "/DeviceProperties/Control"
+	GroupRenderingControlEndpoint = "/MediaRenderer/GroupRenderingControl/Control"
+	ContentDirectoryEndpoint = "/MediaServer/ContentDirectory/Control"
 
 	// UPnP service URNs
 	AVTransportService      = "urn:schemas-upnp-org:service:AVTransport:1"
 	RenderingControlService = "urn:schemas-upnp-org:service:RenderingControl:1"
 	ZoneGroupTopologyService = "urn:upnp-org:serviceId:ZoneGroupTopology"
 	DevicePropertiesService = "urn:upnp-org:serviceId:DeviceProperties"
+	GroupRenderingControlService = "urn:schemas-upnp-org:service:GroupRenderingControl:1"
+	ContentDirectoryService = "urn:schemas-upnp-org:service:ContentDirectory:1"
 )
 
 // SOAPClient makes SOAP requests to Sonos devices.
 type SOAPClient struct {
-	httpClient *http.Client
+	*upnp.SOAPClient
 }
 
 // NewSOAPClient creates a new SOAP client.
 func NewSOAPClient() *SOAPClient {
-	return &SOAPClient{
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-	}
-}
-
-// SOAPEnvelope wraps a SOAP request/response.
-type SOAPEnvelope struct {
-	XMLName xml.Name `xml:"s:Envelope"`
-	NS      string   `xml:"xmlns:s,attr"`
-	Body    SOAPBody `xml:"s:Body"`
-}
-
-// SOAPBody contains the SOAP body content.
-type SOAPBody struct {
-	Content []byte `xml:",innerxml"`
+	return &SOAPClient{SOAPClient: upnp.NewSOAPClient()}
 }
 
-// Call makes a SOAP request to a Sonos device.
+// Call makes a SOAP request to a Sonos device. Arguments are sent in name
+// order with InstanceID first; Sonos does not care about order otherwise.
 func (c *SOAPClient) Call(ctx context.Context, host string, port int, endpoint, service, action string, args map[string]string) ([]byte, error) {
 	url := fmt.Sprintf("http://%s:%d%s", host, port, endpoint)
 
-	// Build SOAP body
-	body := c.buildSOAPBody(service, action, args)
-
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
-	req.Header.Set("SOAPAction", fmt.Sprintf("\"%s#%s\"", service, action))
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("soap request: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("read response: %w", err)
+	names := make([]string, 0, len(args))
+	for k := range args {
+		names = append(names, k)
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("soap error (status %d): %s", resp.StatusCode, string(respBody))
-	}
-
-	return respBody, nil
-}
-
-// buildSOAPBody constructs the SOAP envelope.
-func (c *SOAPClient) buildSOAPBody(service, action string, args map[string]string) []byte {
-	var buf bytes.Buffer
-	buf.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
-	buf.WriteString(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">`)
-	buf.WriteString(`<s:Body>`)
-	buf.WriteString(fmt.Sprintf(`<u:%s xmlns:u="%s">`, action, service))
-
-	for k, v := range args {
-		// Metadata fields contain XML that should not be escaped
-		if strings.HasSuffix(k, "MetaData") || strings.HasSuffix(k, "Metadata") {
-			buf.WriteString(fmt.Sprintf("<%s>%s</%s>", k, v, k))
-		} else {
-			buf.WriteString(fmt.Sprintf("<%s>%s</%s>", k, xmlEscape(v), k))
+	sort.Slice(names, func(i, j int) bool {
+		if (names[i] == "InstanceID") != (names[j] == "InstanceID") {
+			return names[i] == "InstanceID"
 		}
-	}
-
-	buf.WriteString(fmt.Sprintf(`</u:%s>`, action))
-	buf.WriteString(`</s:Body>`)
-	buf.WriteString(`</s:Envelope>`)
+		return names[i] < names[j]
+	})
 
-	return buf.Bytes()
-}
-
-// xmlEscape escapes special XML characters.
-func xmlEscape(s string) string {
-	var buf bytes.Buffer
-	_ = xml.EscapeText(&buf, []byte(s))
-	return buf.String()
+	ordered := make([]upnp.Arg, len(names))
+	for i, k := range names {
+		ordered[i] = upnp.Arg{Name: k, Value: args[k]}
+	}
+	return c.SOAPClient.Call(ctx, url, service, action, ordered...)
 }
@@ -2,9 +2,13 @@ package auth
 
 import (
 	"net/url"
+	"strings"
 )
 
 const (
+	// SpotifyAccountsURL is the base URL of the Spotify accounts service.
+	SpotifyAccountsURL = "https://accounts.spotify.com"
+
 	// SpotifyAuthURL is the Spotify authorization endpoint.
 	SpotifyAuthURL = "https://accounts.spotify.com/authorize"
 
@@ -31,6 +35,8 @@ type Config struct {
 	ClientID    string
 	RedirectURI string
 	Scopes      []string
+	// AccountsURL overrides SpotifyAccountsURL for the authorize endpoint.
+	AccountsURL string
 }
 
 // AuthURLParams contains the parameters for building an authorization URL.
@@ -40,11 +46,16 @@ type AuthURLParams struct {
 	Scopes       []string
 	CodeVerifier string
 	State        string
+	AccountsURL  string // Optional: defaults to SpotifyAccountsURL
 }
 
 // BuildAuthURL constructs the Spotify authorization URL with PKCE parameters.
 func BuildAuthURL(params AuthURLParams, pkce *PKCE) string {
-	u, _ := url.Parse(SpotifyAuthURL)
+	authURL := SpotifyAuthURL
+	if params.AccountsURL != "" {
+		authURL = strings.TrimSuffix(params.AccountsURL, "/") + "/authorize"
+	}
+	u, _ := url.Parse(authURL)
 
 	q := u.Query()
 	q.Set("client_id", params.ClientID)
@@ -84,5 +95,6 @@ func (c *Config) BuildAuthURL(pkce *PKCE) string {
 		ClientID:    c.ClientID,
 		RedirectURI: c.RedirectURI,
 		Scopes:      c.Scopes,
+		AccountsURL: c.AccountsURL,
 	}, pkce)
 }
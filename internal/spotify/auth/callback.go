@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
@@ -24,9 +26,15 @@ type CallbackServer struct {
 
 // NewCallbackServer creates a new callback server on the specified port.
 func NewCallbackServer(port int) (*CallbackServer, error) {
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	return NewCallbackServerAddr(fmt.Sprintf(":%d", port))
+}
+
+// NewCallbackServerAddr creates a new callback server listening on addr,
+// e.g. "127.0.0.1:8888" or "0.0.0.0:8888".
+func NewCallbackServerAddr(addr string) (*CallbackServer, error) {
+	listener, err := net.Listen("tcp", addr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to listen on port %d: %w", port, err)
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
 	}
 
 	cs := &CallbackServer{
@@ -69,11 +77,80 @@ func (cs *CallbackServer) Shutdown(ctx context.Context) error {
 	return cs.server.Shutdown(ctx)
 }
 
+// Addr returns the address the server is listening on.
+func (cs *CallbackServer) Addr() string {
+	return cs.listener.Addr().String()
+}
+
 // Port returns the port the server is listening on.
 func (cs *CallbackServer) Port() int {
 	return cs.listener.Addr().(*net.TCPAddr).Port
 }
 
+// ParseCallbackInput parses what a user pasted after authorizing in a browser
+// on another machine. It accepts the full redirect URL, its query string, or
+// just the bare authorization code, and reports which of these it was. A
+// bare code carries no state, so the caller can only rely on the PKCE
+// verifier to bind it to this session; a URL or query string must carry
+// the state.
+func ParseCallbackInput(input string) (CallbackResult, bool, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return CallbackResult{}, false, fmt.Errorf("no redirect URL or code provided")
+	}
+
+	// A bare code is URL-safe base64 with no query syntax
+	if !strings.ContainsAny(input, "?=&/:") {
+		return CallbackResult{Code: input}, true, nil
+	}
+
+	rawQuery := input
+	if i := strings.Index(input, "?"); i >= 0 {
+		u, err := url.Parse(input)
+		if err != nil {
+			return CallbackResult{}, false, fmt.Errorf("invalid redirect URL: %w", err)
+		}
+		rawQuery = u.RawQuery
+		if rawQuery == "" {
+			rawQuery = input[i+1:]
+		}
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return CallbackResult{}, false, fmt.Errorf("invalid redirect URL: %w", err)
+	}
+
+	result := CallbackResult{
+		Code:  query.Get("code"),
+		State: query.Get("state"),
+		Error: query.Get("error"),
+	}
+	if result.Code == "" && result.Error == "" {
+		return CallbackResult{}, false, fmt.Errorf("redirect URL has no code parameter")
+	}
+	return result, false, nil
+}
+
+// Validate checks a callback result against the PKCE session that started
+// the flow. An empty state is accepted only when allowMissingState is true,
+// which is the case for a pasted bare code.
+func (r CallbackResult) Validate(pkce *PKCE, allowMissingState bool) error {
+	if r.Error != "" {
+		return fmt.Errorf("authentication failed: %s", r.Error)
+	}
+	if r.Code == "" {
+		return fmt.Errorf("authentication failed: no code received")
+	}
+	if r.State == "" && allowMissingState {
+		return nil
+	}
+	if r.State != pkce.State {
+		return fmt.Errorf("state mismatch: possible CSRF attack")
+	}
+	return nil
+}
+
 func (cs *CallbackServer) handleCallback(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 
@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"testing"
 	"time"
@@ -110,3 +111,134 @@ func TestCallbackServerTimeout(t *testing.T) {
 		t.Errorf("Wait() error = %v, want %v", err, context.DeadlineExceeded)
 	}
 }
+
+func TestCallbackServerAddr(t *testing.T) {
+	server, err := NewCallbackServerAddr("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("NewCallbackServerAddr() error = %v", err)
+	}
+	defer func() { _ = server.Shutdown(context.Background()) }()
+
+	host, _, err := net.SplitHostPort(server.Addr())
+	if err != nil {
+		t.Fatalf("SplitHostPort() error = %v", err)
+	}
+	if host != "127.0.0.1" {
+		t.Errorf("host = %q, want %q", host, "127.0.0.1")
+	}
+	if server.Port() == 0 {
+		t.Error("Server port should not be 0")
+	}
+}
+
+func TestParseCallbackInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		want     CallbackResult
+		wantBare bool
+		wantErr  bool
+	}{
+		{
+			name:  "full redirect URL",
+			input: "http://127.0.0.1:8888/callback?code=abc123&state=xyz",
+			want:  CallbackResult{Code: "abc123", State: "xyz"},
+		},
+		{
+			name:  "redirect URL with whitespace",
+			input: "  http://localhost:8888/callback?code=abc&state=s\n",
+			want:  CallbackResult{Code: "abc", State: "s"},
+		},
+		{
+			name:  "query string only",
+			input: "?code=abc&state=s",
+			want:  CallbackResult{Code: "abc", State: "s"},
+		},
+		{
+			name:  "query string without question mark",
+			input: "code=abc&state=s",
+			want:  CallbackResult{Code: "abc", State: "s"},
+		},
+		{
+			name:     "bare code",
+			input:    "AQBx-y_z0123",
+			want:     CallbackResult{Code: "AQBx-y_z0123"},
+			wantBare: true,
+		},
+		{
+			name:  "error redirect",
+			input: "http://127.0.0.1:8888/callback?error=access_denied&state=s",
+			want:  CallbackResult{State: "s", Error: "access_denied"},
+		},
+		{
+			name:    "empty input",
+			input:   "   ",
+			wantErr: true,
+		},
+		{
+			name:    "URL without code",
+			input:   "http://127.0.0.1:8888/callback?state=s",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, bare, err := ParseCallbackInput(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCallbackInput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want || bare != tt.wantBare {
+				t.Errorf("ParseCallbackInput() = %+v, %v, want %+v, %v", got, bare, tt.want, tt.wantBare)
+			}
+		})
+	}
+}
+
+func TestCallbackResultValidate(t *testing.T) {
+	pkce := &PKCE{State: "expected"}
+
+	tests := []struct {
+		name              string
+		result            CallbackResult
+		allowMissingState bool
+		wantErr           bool
+	}{
+		{"matching state", CallbackResult{Code: "c", State: "expected"}, false, false},
+		{"mismatched state", CallbackResult{Code: "c", State: "other"}, true, true},
+		{"missing state rejected", CallbackResult{Code: "c"}, false, true},
+		{"missing state allowed", CallbackResult{Code: "c"}, true, false},
+		{"error result", CallbackResult{Error: "access_denied", State: "expected"}, false, true},
+		{"no code", CallbackResult{State: "expected"}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.result.Validate(pkce, tt.allowMissingState)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPastedURLWithoutStateRejected(t *testing.T) {
+	pkce := &PKCE{State: "expected"}
+
+	// Only a bare code may skip the state check
+	result, bare, err := ParseCallbackInput("http://127.0.0.1:8888/callback?code=abc123")
+	if err != nil {
+		t.Fatalf("ParseCallbackInput() error = %v", err)
+	}
+	if err := result.Validate(pkce, bare); err == nil {
+		t.Error("Validate() accepted a pasted URL without state")
+	}
+
+	result, bare, err = ParseCallbackInput("abc123")
+	if err != nil {
+		t.Fatalf("ParseCallbackInput() error = %v", err)
+	}
+	if err := result.Validate(pkce, bare); err != nil {
+		t.Errorf("Validate() rejected a bare code: %v", err)
+	}
+}
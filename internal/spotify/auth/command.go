@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// CommandTokenStorage delegates token persistence to an external helper,
+// in the style of git credential helpers. The helper is run through the
+// shell with one extra argument naming the operation:
+//
+//	<command> get    print the stored token JSON on stdout (nothing if none)
+//	<command> store  read the token JSON from stdin and persist it
+//	<command> erase  remove the stored token
+//
+// A non-zero exit status is treated as an error, and anything the helper
+// writes to stderr is included in the error message.
+type CommandTokenStorage struct {
+	command string
+}
+
+// NewCommandTokenStorage creates a store backed by the given helper command.
+func NewCommandTokenStorage(command string) (*CommandTokenStorage, error) {
+	if strings.TrimSpace(command) == "" {
+		return nil, errors.New("token helper command must not be empty")
+	}
+	return &CommandTokenStorage{command: command}, nil
+}
+
+// Save passes the token to the helper's store operation.
+func (s *CommandTokenStorage) Save(token *Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token: %w", err)
+	}
+	if _, err := s.run("store", data); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Load reads the token from the helper's get operation.
+func (s *CommandTokenStorage) Load() (*Token, error) {
+	out, err := s.run("get", nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil // No token stored yet
+	}
+
+	var token Token
+	if err := json.Unmarshal(out, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse token from helper: %w", err)
+	}
+	return &token, nil
+}
+
+// Delete asks the helper to erase the stored token.
+func (s *CommandTokenStorage) Delete() error {
+	_, err := s.run("erase", nil)
+	return err
+}
+
+// Command returns the helper command line.
+func (s *CommandTokenStorage) Command() string {
+	return s.command
+}
+
+// run executes the helper with the given operation and optional stdin.
+func (s *CommandTokenStorage) run(op string, stdin []byte) ([]byte, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", s.command+" "+op)
+	} else {
+		// Same trick as git: let the shell parse the command, then append
+		// the operation as a positional argument.
+		cmd = exec.Command("sh", "-c", s.command+` "$@"`, s.command, op)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg != "" {
+			return nil, fmt.Errorf("token helper %s failed: %w: %s", op, err, msg)
+		}
+		return nil, fmt.Errorf("token helper %s failed: %w", op, err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// Ensure CommandTokenStorage implements TokenStore
+var _ TokenStore = (*CommandTokenStorage)(nil)
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCommandTokenStorage(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("helper script requires a POSIX shell")
+	}
+
+	// A minimal helper that keeps the token in a file
+	tokenPath := filepath.Join(t.TempDir(), "helper-token")
+	helper := `f=` + tokenPath + `; case "$1" in get) cat "$f" 2>/dev/null || true ;; store) cat > "$f" ;; erase) rm -f "$f" ;; esac; :`
+
+	storage, err := NewCommandTokenStorage(helper)
+	if err != nil {
+		t.Fatalf("NewCommandTokenStorage() error = %v", err)
+	}
+
+	token, err := storage.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if token != nil {
+		t.Error("Load() should return nil when the helper prints nothing")
+	}
+
+	if err := storage.Save(&Token{AccessToken: "access", RefreshToken: "refresh"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := storage.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded == nil || loaded.RefreshToken != "refresh" {
+		t.Errorf("Load() = %+v, want refresh token %q", loaded, "refresh")
+	}
+
+	if err := storage.Delete(); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if token, _ := storage.Load(); token != nil {
+		t.Error("Load() after Delete() should return nil")
+	}
+}
+
+func TestCommandTokenStorageFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("helper script requires a POSIX shell")
+	}
+
+	storage, _ := NewCommandTokenStorage(`echo "vault sealed" >&2; exit 1; :`)
+
+	_, err := storage.Load()
+	if err == nil {
+		t.Fatal("Load() should fail when the helper exits non-zero")
+	}
+	if got := err.Error(); !strings.Contains(got, "vault sealed") {
+		t.Errorf("error = %q, want it to include helper stderr", got)
+	}
+}
+
+func TestCommandTokenStorageEmpty(t *testing.T) {
+	if _, err := NewCommandTokenStorage("  "); err == nil {
+		t.Error("NewCommandTokenStorage() with empty command should fail")
+	}
+}
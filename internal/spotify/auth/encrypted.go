@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// DefaultEncryptedTokenFileName is the default name for the encrypted token file.
+	DefaultEncryptedTokenFileName = "spotify_token.enc"
+
+	// pbkdf2Iterations follows the OWASP recommendation for PBKDF2-HMAC-SHA256.
+	pbkdf2Iterations = 600000
+
+	encryptedFormatVersion = 1
+	saltLength             = 16
+	keyLength              = 32 // AES-256
+)
+
+// Key derivation functions recorded in the encrypted file.
+const (
+	kdfPBKDF2 = "pbkdf2-sha256"
+	kdfHKDF   = "hkdf-sha256"
+)
+
+// ErrDecrypt is returned when a token file cannot be decrypted, usually
+// because the passphrase or key file is wrong.
+var ErrDecrypt = errors.New("failed to decrypt token file (wrong passphrase or key?)")
+
+// encryptedFile is the on-disk format of an encrypted token.
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations,omitempty"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// EncryptedTokenStorage persists tokens to disk encrypted with AES-256-GCM.
+// The key is derived either from a passphrase (PBKDF2) or from the contents
+// of a key file (HKDF), using a random salt stored alongside the ciphertext.
+type EncryptedTokenStorage struct {
+	path   string
+	secret []byte
+	kdf    string
+}
+
+// NewEncryptedTokenStorage creates an encrypted store keyed by a passphrase.
+// If path is empty, uses ~/.config/riff/spotify_token.enc.
+func NewEncryptedTokenStorage(path, passphrase string) (*EncryptedTokenStorage, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase must not be empty")
+	}
+	return newEncryptedTokenStorage(path, []byte(passphrase), kdfPBKDF2)
+}
+
+// NewKeyFileTokenStorage creates an encrypted store keyed by the contents of
+// keyFile. The key file should contain at least 32 bytes of random data, e.g.
+// the output of `openssl rand -base64 32`.
+func NewKeyFileTokenStorage(path, keyFile string) (*EncryptedTokenStorage, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	secret := []byte(strings.TrimSpace(string(data)))
+	if len(secret) < keyLength {
+		return nil, fmt.Errorf("key file %s is too short (need at least %d bytes)", keyFile, keyLength)
+	}
+	return newEncryptedTokenStorage(path, secret, kdfHKDF)
+}
+
+func newEncryptedTokenStorage(path string, secret []byte, kdf string) (*EncryptedTokenStorage, error) {
+	if path == "" {
+		var err error
+		path, err = defaultTokenPath(DefaultEncryptedTokenFileName)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &EncryptedTokenStorage{path: path, secret: secret, kdf: kdf}, nil
+}
+
+// Save encrypts a token and persists it to disk under the token lock.
+func (s *EncryptedTokenStorage) Save(token *Token) error {
+	return withFileLock(s.path, func() error {
+		return s.write(token)
+	})
+}
+
+// Update loads, modifies and saves the token under the token lock.
+// See TokenUpdater.
+func (s *EncryptedTokenStorage) Update(fn func(current *Token) (*Token, error)) (*Token, error) {
+	var result *Token
+	err := withFileLock(s.path, func() error {
+		current, err := s.Load()
+		if err != nil {
+			return err
+		}
+
+		updated, err := fn(current)
+		if err != nil {
+			return err
+		}
+
+		result = updated
+		if updated == current {
+			return nil
+		}
+		return s.write(updated)
+	})
+	return result, err
+}
+
+// write encrypts and atomically replaces the token file. Callers must hold
+// the token lock.
+func (s *EncryptedTokenStorage) write(token *Token) error {
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token: %w", err)
+	}
+
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	file := encryptedFile{
+		Version: encryptedFormatVersion,
+		KDF:     s.kdf,
+		Salt:    salt,
+	}
+	if s.kdf == kdfPBKDF2 {
+		file.Iterations = pbkdf2Iterations
+	}
+
+	aead, err := s.cipher(&file)
+	if err != nil {
+		return err
+	}
+
+	file.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	file.Ciphertext = aead.Seal(nil, file.Nonce, plaintext, s.additionalData(&file))
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal encrypted token: %w", err)
+	}
+
+	return writeFileAtomic(s.path, data, 0600)
+}
+
+// Load reads and decrypts a token from disk.
+func (s *EncryptedTokenStorage) Load() (*Token, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil // No token stored yet
+		}
+		return nil, fmt.Errorf("failed to read token file: %w", err)
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse token file: %w", err)
+	}
+	if file.Version != encryptedFormatVersion {
+		return nil, fmt.Errorf("unsupported token file version: %d", file.Version)
+	}
+	if file.KDF != s.kdf {
+		return nil, fmt.Errorf("token file was encrypted with %s, but a %s key was provided", file.KDF, s.kdf)
+	}
+
+	aead, err := s.cipher(&file)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Nonce) != aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+
+	plaintext, err := aead.Open(nil, file.Nonce, file.Ciphertext, s.additionalData(&file))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	var token Token
+	if err := json.Unmarshal(plaintext, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// Delete removes the stored token.
+func (s *EncryptedTokenStorage) Delete() error {
+	err := os.Remove(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete token file: %w", err)
+	}
+	return nil
+}
+
+// Path returns the path to the token file.
+func (s *EncryptedTokenStorage) Path() string {
+	return s.path
+}
+
+// cipher derives the file key and returns an AES-GCM AEAD for it.
+func (s *EncryptedTokenStorage) cipher(file *encryptedFile) (cipher.AEAD, error) {
+	var key []byte
+	var err error
+	switch file.KDF {
+	case kdfPBKDF2:
+		if file.Iterations <= 0 {
+			return nil, fmt.Errorf("invalid iteration count: %d", file.Iterations)
+		}
+		key, err = pbkdf2.Key(sha256.New, string(s.secret), file.Salt, file.Iterations, keyLength)
+	case kdfHKDF:
+		key, err = hkdf.Key(sha256.New, s.secret, file.Salt, "riff token storage", keyLength)
+	default:
+		return nil, fmt.Errorf("unsupported key derivation: %s", file.KDF)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// additionalData binds the header fields to the ciphertext so they cannot be
+// tampered with independently.
+func (s *EncryptedTokenStorage) additionalData(file *encryptedFile) []byte {
+	return fmt.Appendf(nil, "riff-token:v%d:%s:%d", file.Version, file.KDF, file.Iterations)
+}
+
+// Ensure EncryptedTokenStorage implements TokenStore and TokenUpdater
+var (
+	_ TokenStore   = (*EncryptedTokenStorage)(nil)
+	_ TokenUpdater = (*EncryptedTokenStorage)(nil)
+)
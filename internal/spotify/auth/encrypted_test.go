@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncryptedTokenStorage(t *testing.T) {
+	tokenPath := filepath.Join(t.TempDir(), "token.enc")
+
+	storage, err := NewEncryptedTokenStorage(tokenPath, "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("NewEncryptedTokenStorage() error = %v", err)
+	}
+
+	// Load should return nil for non-existent token
+	token, err := storage.Load()
+	if err != nil {
+		t.Errorf("Load() error = %v", err)
+	}
+	if token != nil {
+		t.Error("Load() should return nil for non-existent token")
+	}
+
+	testToken := &Token{
+		AccessToken:  "access_123",
+		TokenType:    "Bearer",
+		RefreshToken: "refresh_456",
+		ExpiresAt:    time.Now().Add(1 * time.Hour),
+	}
+
+	if err := storage.Save(testToken); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// The refresh token must not appear in plaintext
+	data, err := os.ReadFile(tokenPath)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if strings.Contains(string(data), "refresh_456") {
+		t.Error("token file contains the refresh token in plaintext")
+	}
+
+	info, err := os.Stat(tokenPath)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("File permissions = %o, want 0600", mode)
+	}
+
+	loaded, err := storage.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.AccessToken != testToken.AccessToken {
+		t.Errorf("AccessToken = %q, want %q", loaded.AccessToken, testToken.AccessToken)
+	}
+	if loaded.RefreshToken != testToken.RefreshToken {
+		t.Errorf("RefreshToken = %q, want %q", loaded.RefreshToken, testToken.RefreshToken)
+	}
+
+	if err := storage.Delete(); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if token, _ := storage.Load(); token != nil {
+		t.Error("Load() after Delete() should return nil")
+	}
+}
+
+func TestEncryptedTokenStorageWrongPassphrase(t *testing.T) {
+	tokenPath := filepath.Join(t.TempDir(), "token.enc")
+
+	storage, _ := NewEncryptedTokenStorage(tokenPath, "right")
+	if err := storage.Save(&Token{AccessToken: "secret"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	wrong, _ := NewEncryptedTokenStorage(tokenPath, "wrong")
+	if _, err := wrong.Load(); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Load() with wrong passphrase error = %v, want %v", err, ErrDecrypt)
+	}
+}
+
+func TestEncryptedTokenStorageTampered(t *testing.T) {
+	tokenPath := filepath.Join(t.TempDir(), "token.enc")
+
+	storage, _ := NewEncryptedTokenStorage(tokenPath, "passphrase")
+	if err := storage.Save(&Token{AccessToken: "secret"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Lowering the iteration count must invalidate the file
+	data, _ := os.ReadFile(tokenPath)
+	data = []byte(strings.Replace(string(data), `"iterations": 600000`, `"iterations": 1`, 1))
+	if err := os.WriteFile(tokenPath, data, 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if _, err := storage.Load(); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Load() of tampered file error = %v, want %v", err, ErrDecrypt)
+	}
+}
+
+func TestKeyFileTokenStorage(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "token.key")
+	if err := os.WriteFile(keyFile, []byte("0123456789abcdef0123456789abcdef\n"), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	storage, err := NewKeyFileTokenStorage(filepath.Join(dir, "token.enc"), keyFile)
+	if err != nil {
+		t.Fatalf("NewKeyFileTokenStorage() error = %v", err)
+	}
+
+	if err := storage.Save(&Token{RefreshToken: "refresh"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := storage.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.RefreshToken != "refresh" {
+		t.Errorf("RefreshToken = %q, want %q", loaded.RefreshToken, "refresh")
+	}
+
+	// A passphrase store must refuse a key-file encrypted token
+	passphrase, _ := NewEncryptedTokenStorage(filepath.Join(dir, "token.enc"), "passphrase")
+	if _, err := passphrase.Load(); err == nil {
+		t.Error("Load() with mismatched key type should fail")
+	}
+}
+
+func TestKeyFileTooShort(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "token.key")
+	if err := os.WriteFile(keyFile, []byte("short"), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if _, err := NewKeyFileTokenStorage("", keyFile); err == nil {
+		t.Error("NewKeyFileTokenStorage() with short key should fail")
+	}
+}
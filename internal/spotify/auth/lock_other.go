@@ -0,0 +1,11 @@
+//go:build !unix && !windows
+
+package auth
+
+import "os"
+
+// Platforms without advisory locking fall back to atomic writes alone.
+
+func lockFile(f *os.File) error { return nil }
+
+func unlockFile(f *os.File) error { return nil }
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	lockHelperEnv     = "RIFF_TEST_LOCK_HELPER"
+	lockHelperPathEnv = "RIFF_TEST_LOCK_PATH"
+	lockIterations    = 25
+	lockProcesses     = 6
+)
+
+// TestLockHelperProcess is not a real test. It is run as a subprocess by
+// TestTokenStorageConcurrentProcesses to simulate independent riff processes
+// rotating the same token.
+func TestLockHelperProcess(t *testing.T) {
+	if os.Getenv(lockHelperEnv) != "1" {
+		t.Skip("helper process")
+	}
+
+	storage, err := NewTokenStorage(os.Getenv(lockHelperPathEnv))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	for i := 0; i < lockIterations; i++ {
+		if _, err := storage.Update(rotateToken); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	os.Exit(0)
+}
+
+// rotateToken simulates a refresh with rotating refresh tokens: each refresh
+// consumes "rt-N" and issues "rt-N+1". Using a stale refresh token (one that
+// another process already consumed) would lose an increment.
+func rotateToken(current *Token) (*Token, error) {
+	n := 0
+	if current != nil {
+		var err error
+		n, err = strconv.Atoi(current.RefreshToken[len("rt-"):])
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Widen the race window so unlocked updates would reliably collide
+	time.Sleep(time.Millisecond)
+	return &Token{
+		AccessToken:  fmt.Sprintf("at-%d", n+1),
+		RefreshToken: fmt.Sprintf("rt-%d", n+1),
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}, nil
+}
+
+func TestTokenStorageConcurrentProcesses(t *testing.T) {
+	if testing.Short() {
+		t.Skip("spawns subprocesses")
+	}
+
+	tokenPath := filepath.Join(t.TempDir(), "token.json")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, lockProcesses)
+	for i := 0; i < lockProcesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := exec.Command(os.Args[0], "-test.run=^TestLockHelperProcess$")
+			cmd.Env = append(os.Environ(), lockHelperEnv+"=1", lockHelperPathEnv+"="+tokenPath)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				errs <- fmt.Errorf("helper process failed: %v: %s", err, out)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	storage, _ := NewTokenStorage(tokenPath)
+	token, err := storage.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	want := fmt.Sprintf("rt-%d", lockProcesses*lockIterations)
+	if token.RefreshToken != want {
+		t.Errorf("RefreshToken = %q, want %q (updates were lost)", token.RefreshToken, want)
+	}
+
+	// No temp files should be left behind
+	entries, _ := os.ReadDir(filepath.Dir(tokenPath))
+	for _, e := range entries {
+		if name := e.Name(); name != "token.json" && name != "token.json.lock" {
+			t.Errorf("unexpected file left in token directory: %s", name)
+		}
+	}
+}
+
+func TestTokenStorageUpdateNoChange(t *testing.T) {
+	tokenPath := filepath.Join(t.TempDir(), "token.json")
+	storage, _ := NewTokenStorage(tokenPath)
+
+	if err := storage.Save(&Token{AccessToken: "a", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	before, _ := os.Stat(tokenPath)
+
+	// Returning the current token must not rewrite the file
+	got, err := storage.Update(func(current *Token) (*Token, error) {
+		return current, nil
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got.AccessToken != "a" {
+		t.Errorf("AccessToken = %q, want %q", got.AccessToken, "a")
+	}
+
+	after, _ := os.Stat(tokenPath)
+	if !os.SameFile(before, after) {
+		t.Error("Update() rewrote the token file although nothing changed")
+	}
+}
+
+func TestTokenStorageUpdateError(t *testing.T) {
+	tokenPath := filepath.Join(t.TempDir(), "token.json")
+	storage, _ := NewTokenStorage(tokenPath)
+
+	if err := storage.Save(&Token{AccessToken: "a"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	_, err := storage.Update(func(current *Token) (*Token, error) {
+		return nil, fmt.Errorf("refresh failed")
+	})
+	if err == nil {
+		t.Fatal("Update() should return the callback error")
+	}
+
+	token, _ := storage.Load()
+	if token.AccessToken != "a" {
+		t.Errorf("AccessToken = %q, want unchanged %q", token.AccessToken, "a")
+	}
+}
@@ -12,7 +12,15 @@ const (
 	DefaultTokenFileName = "spotify_token.json"
 )
 
-// TokenStorage handles persisting tokens to disk.
+// TokenStore persists OAuth tokens between runs.
+// Load returns nil (and no error) when no token has been stored yet.
+type TokenStore interface {
+	Load() (*Token, error)
+	Save(token *Token) error
+	Delete() error
+}
+
+// TokenStorage handles persisting tokens to disk as plaintext JSON.
 type TokenStorage struct {
 	path string
 }
@@ -21,35 +29,66 @@ type TokenStorage struct {
 // If path is empty, uses the default location (~/.config/riff/spotify_token.json).
 func NewTokenStorage(path string) (*TokenStorage, error) {
 	if path == "" {
-		configDir, err := os.UserConfigDir()
+		var err error
+		path, err = defaultTokenPath(DefaultTokenFileName)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get config directory: %w", err)
+			return nil, err
 		}
-		path = filepath.Join(configDir, "riff", DefaultTokenFileName)
 	}
 
 	return &TokenStorage{path: path}, nil
 }
 
-// Save persists a token to disk.
-func (s *TokenStorage) Save(token *Token) error {
-	// Ensure directory exists
-	dir := filepath.Dir(s.path)
-	if err := os.MkdirAll(dir, 0700); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
+// defaultTokenPath returns the path of name inside riff's config directory.
+func defaultTokenPath(name string) (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
 	}
+	return filepath.Join(configDir, "riff", name), nil
+}
+
+// Save persists a token to disk. The file is replaced atomically while
+// holding the token lock, so concurrent riff processes never interleave writes.
+func (s *TokenStorage) Save(token *Token) error {
+	return withFileLock(s.path, func() error {
+		return s.write(token)
+	})
+}
+
+// Update loads, modifies and saves the token under the token lock.
+// See TokenUpdater.
+func (s *TokenStorage) Update(fn func(current *Token) (*Token, error)) (*Token, error) {
+	var result *Token
+	err := withFileLock(s.path, func() error {
+		current, err := s.Load()
+		if err != nil {
+			return err
+		}
 
+		updated, err := fn(current)
+		if err != nil {
+			return err
+		}
+
+		result = updated
+		if updated == current {
+			return nil
+		}
+		return s.write(updated)
+	})
+	return result, err
+}
+
+// write atomically replaces the token file. Callers must hold the token lock.
+func (s *TokenStorage) write(token *Token) error {
 	data, err := json.MarshalIndent(token, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal token: %w", err)
 	}
 
 	// Write with restricted permissions (owner only)
-	if err := os.WriteFile(s.path, data, 0600); err != nil {
-		return fmt.Errorf("failed to write token file: %w", err)
-	}
-
-	return nil
+	return writeFileAtomic(s.path, data, 0600)
 }
 
 // Load reads a token from disk.
@@ -89,3 +128,9 @@ func (s *TokenStorage) Exists() bool {
 func (s *TokenStorage) Path() string {
 	return s.path
 }
+
+// Ensure TokenStorage implements TokenStore and TokenUpdater
+var (
+	_ TokenStore   = (*TokenStorage)(nil)
+	_ TokenUpdater = (*TokenStorage)(nil)
+)
@@ -38,8 +38,32 @@ type tokenResponse struct {
 	ErrorDesc    string `json:"error_description"`
 }
 
+// TokenOption configures a request to the token endpoint.
+type TokenOption func(*tokenOptions)
+
+type tokenOptions struct {
+	tokenURL   string
+	httpClient *http.Client
+}
+
+// WithAccountsURL sends token requests to the accounts service at baseURL
+// instead of SpotifyAccountsURL, e.g. a test server.
+func WithAccountsURL(baseURL string) TokenOption {
+	return func(o *tokenOptions) {
+		o.tokenURL = strings.TrimSuffix(baseURL, "/") + "/api/token"
+	}
+}
+
+// WithHTTPClient sends token requests with httpClient, e.g. one configured
+// with a proxy or custom CA bundle.
+func WithHTTPClient(httpClient *http.Client) TokenOption {
+	return func(o *tokenOptions) {
+		o.httpClient = httpClient
+	}
+}
+
 // ExchangeCode exchanges an authorization code for tokens.
-func ExchangeCode(ctx context.Context, clientID, code, redirectURI, codeVerifier string) (*Token, error) {
+func ExchangeCode(ctx context.Context, clientID, code, redirectURI, codeVerifier string, opts ...TokenOption) (*Token, error) {
 	data := url.Values{}
 	data.Set("grant_type", "authorization_code")
 	data.Set("code", code)
@@ -47,29 +71,36 @@ func ExchangeCode(ctx context.Context, clientID, code, redirectURI, codeVerifier
 	data.Set("client_id", clientID)
 	data.Set("code_verifier", codeVerifier)
 
-	return requestToken(ctx, data)
+	return requestToken(ctx, data, opts)
 }
 
 // RefreshAccessToken uses a refresh token to get a new access token.
-func RefreshAccessToken(ctx context.Context, clientID, refreshToken string) (*Token, error) {
+func RefreshAccessToken(ctx context.Context, clientID, refreshToken string, opts ...TokenOption) (*Token, error) {
 	data := url.Values{}
 	data.Set("grant_type", "refresh_token")
 	data.Set("refresh_token", refreshToken)
 	data.Set("client_id", clientID)
 
-	return requestToken(ctx, data)
+	return requestToken(ctx, data, opts)
 }
 
-func requestToken(ctx context.Context, data url.Values) (*Token, error) {
-	req, err := http.NewRequestWithContext(ctx, "POST", SpotifyTokenURL, strings.NewReader(data.Encode()))
+func requestToken(ctx context.Context, data url.Values, opts []TokenOption) (*Token, error) {
+	o := tokenOptions{
+		tokenURL:   SpotifyTokenURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", o.tokenURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := o.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("token request failed: %w", err)
 	}
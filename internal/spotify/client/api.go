@@ -89,6 +89,23 @@ func (c *Client) Search(ctx context.Context, opts SearchOptions) (*SearchRespons
 	return &resp, nil
 }
 
+// GetMyPlaylists returns a page of the playlists the user owns or follows.
+func (c *Client) GetMyPlaylists(ctx context.Context, limit, offset int) (*PlaylistsResponse, error) {
+	params := make(map[string]string)
+	if limit > 0 {
+		params["limit"] = strconv.Itoa(limit)
+	}
+	if offset > 0 {
+		params["offset"] = strconv.Itoa(offset)
+	}
+
+	var resp PlaylistsResponse
+	if err := c.Get(ctx, BuildURL("/me/playlists", params), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
 // GetRecentlyPlayed returns the user's recently played tracks.
 func (c *Client) GetRecentlyPlayed(ctx context.Context, limit int) (*RecentlyPlayedResponse, error) {
 	params := make(map[string]string)
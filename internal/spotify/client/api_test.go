@@ -0,0 +1,184 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/tessro/riff/internal/spotify/auth"
+	"github.com/tessro/riff/internal/spotify/client"
+	"github.com/tessro/riff/internal/spotify/spotifytest"
+)
+
+func TestGetDevices(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+
+	devices, err := srv.Client().GetDevices(context.Background())
+	if err != nil {
+		t.Fatalf("GetDevices() error = %v", err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("len(devices) = %d, want 2", len(devices))
+	}
+	if devices[0].Name != "Test Computer" {
+		t.Errorf("devices[0].Name = %q, want %q", devices[0].Name, "Test Computer")
+	}
+}
+
+func TestGetPlaybackStateIdle(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+
+	// Spotify answers 204 when nothing is active
+	state, err := srv.Client().GetPlaybackState(context.Background())
+	if err != nil {
+		t.Fatalf("GetPlaybackState() error = %v", err)
+	}
+	if state.Item != nil || state.IsPlaying {
+		t.Errorf("GetPlaybackState() = %+v, want empty state", state)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+
+	resp, err := srv.Client().Search(context.Background(), client.SearchOptions{
+		Query: "night",
+		Types: []client.SearchType{client.SearchTypeTrack, client.SearchTypeAlbum},
+		Limit: 2,
+	})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if resp.Tracks == nil || len(resp.Tracks.Items) != 2 {
+		t.Errorf("Tracks = %+v, want 2 items", resp.Tracks)
+	}
+	if resp.Albums == nil || len(resp.Albums.Items) != 1 || resp.Albums.Items[0].Name != "Night Drive" {
+		t.Errorf("Albums = %+v, want Night Drive", resp.Albums)
+	}
+	if resp.Artists != nil {
+		t.Error("Artists should be nil when not requested")
+	}
+}
+
+func TestGetQueue(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := srv.Client()
+
+	if err := c.Play(ctx, "device-computer", &client.PlayOptions{URIs: []string{"spotify:track:night-drive-1"}}); err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+	if err := c.AddToQueue(ctx, "spotify:track:morning-songs-1", ""); err != nil {
+		t.Fatalf("AddToQueue() error = %v", err)
+	}
+
+	queue, err := c.GetQueue(ctx)
+	if err != nil {
+		t.Fatalf("GetQueue() error = %v", err)
+	}
+	if queue.CurrentlyPlaying == nil || queue.CurrentlyPlaying.Name != "Neon Lights" {
+		t.Errorf("CurrentlyPlaying = %+v, want Neon Lights", queue.CurrentlyPlaying)
+	}
+	if len(queue.Queue) != 1 || queue.Queue[0].Name != "Sunrise" {
+		t.Errorf("Queue = %+v, want [Sunrise]", queue.Queue)
+	}
+}
+
+func TestRefreshAfterUnauthorized(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+
+	store := spotifytest.NewMemoryTokenStore(srv.Token())
+	c := client.New(spotifytest.ClientID, store, srv.ClientOptions()...)
+	if err := c.LoadToken(); err != nil {
+		t.Fatalf("LoadToken() error = %v", err)
+	}
+
+	srv.ExpireAccessTokens()
+
+	if _, err := c.GetCurrentUser(context.Background()); err != nil {
+		t.Fatalf("GetCurrentUser() error = %v", err)
+	}
+
+	// The rotated refresh token must be persisted
+	token, _ := store.Load()
+	if token.RefreshToken != srv.RefreshToken() {
+		t.Errorf("stored RefreshToken = %q, want rotated %q", token.RefreshToken, srv.RefreshToken())
+	}
+}
+
+func TestRefreshAfterUnauthorizedWithLockedStore(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+
+	// The stored token is unexpired but Spotify rejects it; re-reading the
+	// file under the lock must not hand it straight back
+	store, err := auth.NewTokenStorage(filepath.Join(t.TempDir(), "token.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := srv.Token()
+	if err := store.Save(stale); err != nil {
+		t.Fatal(err)
+	}
+	c := client.New(spotifytest.ClientID, store, srv.ClientOptions()...)
+	if err := c.LoadToken(); err != nil {
+		t.Fatalf("LoadToken() error = %v", err)
+	}
+
+	srv.ExpireAccessTokens()
+
+	if _, err := c.GetCurrentUser(context.Background()); err != nil {
+		t.Fatalf("GetCurrentUser() error = %v", err)
+	}
+	token, _ := store.Load()
+	if token.AccessToken == stale.AccessToken || token.RefreshToken != srv.RefreshToken() {
+		t.Errorf("stored token = %+v, want a freshly refreshed one", token)
+	}
+}
+
+func TestRetryOnServerError(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+
+	fault := spotifytest.ServerError("/me", http.StatusBadGateway)
+	fault.Times = 1
+	srv.InjectFault(fault)
+
+	user, err := srv.Client().GetCurrentUser(context.Background())
+	if err != nil {
+		t.Fatalf("GetCurrentUser() error = %v", err)
+	}
+	if user.ID != "testuser" {
+		t.Errorf("ID = %q, want %q", user.ID, "testuser")
+	}
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	srv := spotifytest.NewServer()
+	defer srv.Close()
+
+	srv.InjectFault(spotifytest.PremiumRequired("/me/player/play"))
+
+	err := srv.Client().Play(context.Background(), "device-computer", nil)
+	apiErr, ok := err.(*client.APIError)
+	if !ok || apiErr.ErrorInfo.Status != http.StatusForbidden {
+		t.Fatalf("Play() error = %v, want 403 APIError", err)
+	}
+
+	var plays int
+	for _, r := range srv.Requests() {
+		if r.Path == "/me/player/play" {
+			plays++
+		}
+	}
+	if plays != 1 {
+		t.Errorf("play requests = %d, want 1 (4xx must not be retried)", plays)
+	}
+}
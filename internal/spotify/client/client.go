@@ -5,12 +5,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	rifferrors "github.com/tessro/riff/internal/errors"
+	"github.com/tessro/riff/internal/metrics"
 	"github.com/tessro/riff/internal/spotify/auth"
 )
 
@@ -25,34 +29,52 @@ const (
 
 // Client is a Spotify API client.
 type Client struct {
-	httpClient *http.Client
-	clientID   string
-	storage    *auth.TokenStorage
-	token      *auth.Token
-	mu         sync.RWMutex
-	verbose    bool
-	logFunc    func(format string, args ...interface{})
+	httpClient  *http.Client
+	baseURL     string
+	accountsURL string
+	clientID    string
+	storage     auth.TokenStore
+	token       *auth.Token
+	mu          sync.RWMutex
 }
 
-// New creates a new Spotify client.
-func New(clientID string, storage *auth.TokenStorage) *Client {
-	return &Client{
-		httpClient: &http.Client{Timeout: 30 * time.Second},
-		clientID:   clientID,
-		storage:    storage,
+// Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL sets the Web API base URL. Defaults to BaseURL.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimSuffix(baseURL, "/")
 	}
 }
 
-// SetVerbose enables verbose logging.
-func (c *Client) SetVerbose(verbose bool, logFunc func(format string, args ...interface{})) {
-	c.verbose = verbose
-	c.logFunc = logFunc
+// WithAccountsURL sets the accounts service base URL used to refresh tokens.
+// Defaults to auth.SpotifyAccountsURL.
+func WithAccountsURL(accountsURL string) Option {
+	return func(c *Client) {
+		c.accountsURL = accountsURL
+	}
 }
 
-func (c *Client) log(format string, args ...interface{}) {
-	if c.verbose && c.logFunc != nil {
-		c.logFunc(format, args...)
+// WithHTTPClient sets the HTTP client used for API and token requests.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// New creates a new Spotify client.
+func New(clientID string, storage auth.TokenStore, opts ...Option) *Client {
+	c := &Client{
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+		baseURL:    BaseURL,
+		clientID:   clientID,
+		storage:    storage,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
 
 // LoadToken loads the token from storage.
@@ -102,18 +124,56 @@ func (c *Client) RefreshToken(ctx context.Context) error {
 		return nil // Token is still valid
 	}
 
-	newToken, err := auth.RefreshAccessToken(ctx, c.clientID, c.token.RefreshToken)
+	// Other riff processes share the token. When the store supports it,
+	// refresh under its lock and re-read first: another process may already
+	// have refreshed, and reusing our stale refresh token could invalidate it.
+	if updater, ok := c.storage.(auth.TokenUpdater); ok {
+		token, err := updater.Update(func(current *auth.Token) (*auth.Token, error) {
+			// A stored token we already hold may have been rejected; only
+			// one another process refreshed can be reused
+			if current != nil && !current.IsExpired() && current.AccessToken != c.token.AccessToken {
+				return current, nil
+			}
+			if current == nil {
+				current = c.token
+			}
+			return c.refresh(ctx, current)
+		})
+		if err != nil {
+			return err
+		}
+		c.token = token
+		return nil
+	}
+
+	newToken, err := c.refresh(ctx, c.token)
 	if err != nil {
-		return fmt.Errorf("failed to refresh token: %w", err)
+		return err
+	}
+
+	c.token = newToken
+	return c.storage.Save(newToken)
+}
+
+// refresh exchanges the refresh token in token for a new access token.
+func (c *Client) refresh(ctx context.Context, token *auth.Token) (*auth.Token, error) {
+	opts := []auth.TokenOption{auth.WithHTTPClient(c.httpClient)}
+	if c.accountsURL != "" {
+		opts = append(opts, auth.WithAccountsURL(c.accountsURL))
+	}
+
+	newToken, err := auth.RefreshAccessToken(ctx, c.clientID, token.RefreshToken, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh token: %w", err)
 	}
 
 	// Preserve refresh token if not returned
 	if newToken.RefreshToken == "" {
-		newToken.RefreshToken = c.token.RefreshToken
+		newToken.RefreshToken = token.RefreshToken
 	}
+	slog.DebugContext(ctx, "spotify token refreshed", "expires_at", newToken.ExpiresAt)
 
-	c.token = newToken
-	return c.storage.Save(newToken)
+	return newToken, nil
 }
 
 // getToken returns the current access token, refreshing if needed.
@@ -166,12 +226,13 @@ func (c *Client) request(ctx context.Context, method, path string, body interfac
 		}
 	}
 
-	fullURL := BaseURL + path
+	fullURL := c.baseURL + path
+	endpoint := metrics.Endpoint(path)
 
 	if jsonBody != nil {
-		c.log("[spotify] %s %s\n  body: %s", method, fullURL, string(jsonBody))
+		slog.DebugContext(ctx, "spotify request", "method", method, "url", fullURL, "body", string(jsonBody))
 	} else {
-		c.log("[spotify] %s %s", method, fullURL)
+		slog.DebugContext(ctx, "spotify request", "method", method, "url", fullURL)
 	}
 
 	var lastErr error
@@ -179,7 +240,8 @@ func (c *Client) request(ctx context.Context, method, path string, body interfac
 		// Wait before retry (skip on first attempt)
 		if attempt > 0 {
 			wait := baseRetryWait * time.Duration(1<<(attempt-1)) // exponential backoff
-			c.log("[spotify] retry %d/%d after %v (last error: %v)", attempt, maxRetries, wait, lastErr)
+			slog.WarnContext(ctx, "spotify retry", "method", method, "url", fullURL,
+				"attempt", attempt, "wait", wait, "error", lastErr)
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
@@ -202,25 +264,35 @@ func (c *Client) request(ctx context.Context, method, path string, body interfac
 			req.Header.Set("Content-Type", "application/json")
 		}
 
+		start := time.Now()
 		resp, err := c.httpClient.Do(req)
+		metrics.SpotifyRequestDuration.WithLabelValues(method, endpoint).Observe(metrics.Since(start))
 		if err != nil {
+			metrics.SpotifyRequests.WithLabelValues(method, endpoint, "error").Inc()
 			lastErr = fmt.Errorf("request failed: %w", err)
-			c.log("[spotify] network error: %v", err)
+			slog.DebugContext(ctx, "spotify network error", "method", method, "url", fullURL, "error", err)
+			metrics.SpotifyRetries.WithLabelValues("network").Inc()
 			continue // Retry on network error
 		}
+		metrics.SpotifyRequests.WithLabelValues(method, endpoint, strconv.Itoa(resp.StatusCode)).Inc()
+		if resp.StatusCode == http.StatusTooManyRequests {
+			metrics.SpotifyRateLimited.Inc()
+		}
 
 		respBody, err := io.ReadAll(resp.Body)
 		_ = resp.Body.Close()
 		if err != nil {
 			lastErr = fmt.Errorf("failed to read response: %w", err)
-			c.log("[spotify] read error: %v", err)
+			slog.DebugContext(ctx, "spotify read error", "method", method, "url", fullURL, "error", err)
+			metrics.SpotifyRetries.WithLabelValues("network").Inc()
 			continue
 		}
 
-		c.log("[spotify] response: %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+		attrs := []any{"method", method, "url", fullURL, "status", resp.StatusCode, "duration", time.Since(start)}
 		if resp.StatusCode >= 400 {
-			c.log("[spotify] response body: %s", string(respBody))
+			attrs = append(attrs, "body", string(respBody))
 		}
+		slog.DebugContext(ctx, "spotify response", attrs...)
 
 		if resp.StatusCode == http.StatusNoContent {
 			return nil
@@ -234,13 +306,13 @@ func (c *Client) request(ctx context.Context, method, path string, body interfac
 			} else {
 				lastErr = fmt.Errorf("API error: status %d, body: %s", resp.StatusCode, string(respBody))
 			}
-			c.log("[spotify] server error, will retry: %v", lastErr)
+			metrics.SpotifyRetries.WithLabelValues("server_error").Inc()
 			continue // Retry
 		}
 
 		// Retry 401 with a fresh token (token may have expired during request)
 		if resp.StatusCode == http.StatusUnauthorized && attempt < maxRetries {
-			c.log("[spotify] 401 unauthorized, forcing token refresh")
+			slog.DebugContext(ctx, "spotify token rejected, refreshing", "url", fullURL)
 			c.mu.Lock()
 			if c.token != nil {
 				// Force token refresh by marking as expired
@@ -253,6 +325,7 @@ func (c *Client) request(ctx context.Context, method, path string, body interfac
 				return fmt.Errorf("failed to refresh token after 401: %w", err)
 			}
 			lastErr = fmt.Errorf("401 unauthorized")
+			metrics.SpotifyRetries.WithLabelValues("unauthorized").Inc()
 			continue
 		}
 
@@ -262,6 +335,9 @@ func (c *Client) request(ctx context.Context, method, path string, body interfac
 			if err := json.Unmarshal(respBody, &apiErr); err == nil && apiErr.ErrorInfo.Message != "" {
 				return &apiErr
 			}
+			if resp.StatusCode == http.StatusTooManyRequests {
+				return fmt.Errorf("API error: status %d: %w", resp.StatusCode, rifferrors.ErrRateLimited)
+			}
 			return fmt.Errorf("API error: status %d, body: %s", resp.StatusCode, string(respBody))
 		}
 
@@ -289,6 +365,11 @@ func (e *APIError) Error() string {
 	return fmt.Sprintf("Spotify API error %d: %s", e.ErrorInfo.Status, e.ErrorInfo.Message)
 }
 
+// Is reports rate limiting (429) as rifferrors.ErrRateLimited.
+func (e *APIError) Is(target error) bool {
+	return target == rifferrors.ErrRateLimited && e.ErrorInfo.Status == http.StatusTooManyRequests
+}
+
 // IsNoActiveDevice returns true if the error indicates no active device.
 func (e *APIError) IsNoActiveDevice() bool {
 	return e.ErrorInfo.Status == 404
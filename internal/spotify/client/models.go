@@ -157,6 +157,15 @@ type SearchAlbums struct {
 	Next   string  `json:"next"`
 }
 
+// PlaylistsResponse is a page of the current user's playlists.
+type PlaylistsResponse struct {
+	Items  []Playlist `json:"items"`
+	Total  int        `json:"total"`
+	Limit  int        `json:"limit"`
+	Offset int        `json:"offset"`
+	Next   string     `json:"next"`
+}
+
 // SearchPlaylists contains playlist search results.
 type SearchPlaylists struct {
 	Items  []Playlist `json:"items"`
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// DefaultTimeout is the request timeout used when none is configured.
+const DefaultTimeout = 30 * time.Second
+
+// HTTPConfig configures the HTTP client used to reach Spotify.
+type HTTPConfig struct {
+	// Proxy is an HTTP(S) proxy URL. When empty, the standard proxy
+	// environment variables (HTTPS_PROXY, NO_PROXY, ...) are honored.
+	Proxy string
+	// CAFile is a PEM bundle of CA certificates trusted in addition to the
+	// system roots, e.g. for a TLS-intercepting corporate proxy.
+	CAFile string
+	// Timeout bounds each request, including reading the response.
+	Timeout time.Duration
+	// ConnectTimeout bounds establishing the TCP connection.
+	ConnectTimeout time.Duration
+}
+
+// NewHTTPClient builds an http.Client from cfg. Zero values keep the
+// defaults of http.DefaultTransport.
+func NewHTTPClient(cfg HTTPConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", cfg.CAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    pool,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	if cfg.ConnectTimeout > 0 {
+		dialer := &net.Dialer{
+			Timeout:   cfg.ConnectTimeout,
+			KeepAlive: 30 * time.Second,
+		}
+		transport.DialContext = dialer.DialContext
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}, nil
+}
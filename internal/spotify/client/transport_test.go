@@ -0,0 +1,90 @@
+package client
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewHTTPClientDefaults(t *testing.T) {
+	c, err := NewHTTPClient(HTTPConfig{})
+	if err != nil {
+		t.Fatalf("NewHTTPClient() error = %v", err)
+	}
+	if c.Timeout != DefaultTimeout {
+		t.Errorf("Timeout = %v, want %v", c.Timeout, DefaultTimeout)
+	}
+}
+
+func TestNewHTTPClientProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A forward proxy receives the absolute URL
+		proxied = r.URL.String()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxy.Close()
+
+	c, err := NewHTTPClient(HTTPConfig{Proxy: proxy.URL, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("NewHTTPClient() error = %v", err)
+	}
+
+	resp, err := c.Get("http://api.spotify.invalid/v1/me")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if proxied != "http://api.spotify.invalid/v1/me" {
+		t.Errorf("proxy saw %q, want the target URL", proxied)
+	}
+}
+
+func TestNewHTTPClientCAFile(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	// Without the CA bundle the self-signed certificate is rejected
+	plain, _ := NewHTTPClient(HTTPConfig{})
+	if resp, err := plain.Get(srv.URL); err == nil {
+		_ = resp.Body.Close()
+		t.Fatal("Get() should fail without the CA bundle")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	c, err := NewHTTPClient(HTTPConfig{CAFile: caFile})
+	if err != nil {
+		t.Fatalf("NewHTTPClient() error = %v", err)
+	}
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get() with CA bundle error = %v", err)
+	}
+	_ = resp.Body.Close()
+}
+
+func TestNewHTTPClientBadCAFile(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, []byte("not a certificate"), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if _, err := NewHTTPClient(HTTPConfig{CAFile: caFile}); err == nil {
+		t.Error("NewHTTPClient() with invalid CA bundle should fail")
+	}
+	if _, err := NewHTTPClient(HTTPConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("NewHTTPClient() with missing CA bundle should fail")
+	}
+}
@@ -0,0 +1,110 @@
+package player
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/spotify/client"
+)
+
+// Backend implements core.Backend for Spotify Connect.
+type Backend struct {
+	client *client.Client
+}
+
+// Ensure Backend implements core.Backend
+var _ core.Backend = (*Backend)(nil)
+
+// NewBackend creates a Spotify backend using an authenticated client.
+func NewBackend(c *client.Client) *Backend {
+	return &Backend{client: c}
+}
+
+// Client returns the underlying Spotify API client.
+func (b *Backend) Client() *client.Client {
+	return b.client
+}
+
+// Platform returns core.PlatformSpotify.
+func (b *Backend) Platform() core.Platform {
+	return core.PlatformSpotify
+}
+
+// Devices returns the user's Spotify Connect devices.
+func (b *Backend) Devices(ctx context.Context) ([]core.Device, error) {
+	return New(b.client).GetDevices(ctx)
+}
+
+// PlayerFor returns a player targeting device, or the active device if nil.
+func (b *Backend) PlayerFor(ctx context.Context, device *core.Device) (core.Player, error) {
+	p := New(b.client)
+	if device != nil {
+		if device.Platform != "" && device.Platform != core.PlatformSpotify {
+			return nil, fmt.Errorf("device '%s' is not a Spotify device", device.Name)
+		}
+		p.SetDevice(device.ID)
+	}
+	return p, nil
+}
+
+// Search searches the Spotify catalog.
+func (b *Backend) Search(ctx context.Context, query string, kinds []core.SearchKind, limit int) ([]core.SearchResult, error) {
+	types := make([]client.SearchType, len(kinds))
+	for i, k := range kinds {
+		types[i] = client.SearchType(k)
+	}
+
+	resp, err := b.client.Search(ctx, client.SearchOptions{
+		Query: query,
+		Types: types,
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var results []core.SearchResult
+	add := func(kind core.SearchKind, uri, title, subtitle string) {
+		results = append(results, core.SearchResult{
+			URI:      uri,
+			Kind:     kind,
+			Title:    title,
+			Subtitle: subtitle,
+			Platform: core.PlatformSpotify,
+		})
+	}
+
+	if resp.Tracks != nil {
+		for _, t := range resp.Tracks.Items {
+			add(core.SearchKindTrack, t.URI, t.Name, artistNames(t.Artists))
+		}
+	}
+	if resp.Albums != nil {
+		for _, a := range resp.Albums.Items {
+			add(core.SearchKindAlbum, a.URI, a.Name, artistNames(a.Artists))
+		}
+	}
+	if resp.Artists != nil {
+		for _, a := range resp.Artists.Items {
+			add(core.SearchKindArtist, a.URI, a.Name, "")
+		}
+	}
+	if resp.Playlists != nil {
+		for _, p := range resp.Playlists.Items {
+			add(core.SearchKindPlaylist, p.URI, p.Name, p.Owner.DisplayName)
+		}
+	}
+
+	return results, nil
+}
+
+// artistNames joins artist names with commas.
+func artistNames(artists []client.Artist) string {
+	names := make([]string, len(artists))
+	for i, a := range artists {
+		names[i] = a.Name
+	}
+	return strings.Join(names, ", ")
+}
@@ -2,9 +2,12 @@ package player
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/tessro/riff/internal/core"
+	rifferrors "github.com/tessro/riff/internal/errors"
 	"github.com/tessro/riff/internal/spotify/client"
 )
 
@@ -14,6 +17,20 @@ type Player struct {
 	deviceID string // Optional: target device ID
 }
 
+// Ensure Player implements core.Player
+var _ core.Player = (*Player)(nil)
+
+// Ensure Player implements core.PlayModeController
+var _ core.PlayModeController = (*Player)(nil)
+
+// Ensure Player implements core.SessionPlayer
+var _ core.SessionPlayer = (*Player)(nil)
+
+// capabilities are the optional operations the Spotify Web API supports.
+// It has no endpoints to remove, reorder or clear queued tracks.
+const capabilities = core.CapQueueRead | core.CapQueueAdd | core.CapHistory |
+	core.CapShuffle | core.CapRepeat | core.CapSeek
+
 // New creates a new Spotify player.
 func New(c *client.Client) *Player {
 	return &Player{client: c}
@@ -100,6 +117,8 @@ func (p *Player) GetState(ctx context.Context) (*core.PlaybackState, error) {
 	coreState := &core.PlaybackState{
 		IsPlaying: state.IsPlaying,
 		Progress:  time.Duration(state.ProgressMS) * time.Millisecond,
+		Shuffle:   state.ShuffleState,
+		Repeat:    state.RepeatState,
 	}
 
 	if state.Device.VolumePercent != nil {
@@ -169,11 +188,67 @@ func (p *Player) Shuffle(ctx context.Context, state bool) error {
 	return p.client.SetShuffle(ctx, state, p.deviceID)
 }
 
+// Repeat sets the repeat mode (off, track, context).
+func (p *Player) Repeat(ctx context.Context, mode string) error {
+	return p.client.SetRepeat(ctx, mode, p.deviceID)
+}
+
+// Capabilities returns the operations Spotify supports.
+func (p *Player) Capabilities() core.Capability {
+	return capabilities
+}
+
+// Describe names the player for error messages.
+func (p *Player) Describe() string {
+	return "Spotify"
+}
+
 // TransferPlayback transfers playback to a different device.
 func (p *Player) TransferPlayback(ctx context.Context, deviceID string, play bool) error {
 	return p.client.TransferPlayback(ctx, deviceID, play)
 }
 
+// Session returns what is playing on the player's device, with its
+// context so another player can carry on through the album or playlist.
+func (p *Player) Session(ctx context.Context) (*core.Session, error) {
+	state, err := p.client.GetPlaybackState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil || state.Item == nil || (p.deviceID != "" && state.Device.ID != p.deviceID) {
+		return nil, fmt.Errorf("nothing is playing on this Spotify device: %w", rifferrors.ErrNoActiveDevice)
+	}
+
+	s := &core.Session{
+		Track:    state.Item.URI,
+		Tracks:   []string{state.Item.URI},
+		Progress: time.Duration(state.ProgressMS) * time.Millisecond,
+	}
+	if state.Context != nil {
+		s.Context = state.Context.URI
+	}
+	if state.Device.VolumePercent != nil {
+		s.Volume = *state.Device.VolumePercent
+	}
+	return s, nil
+}
+
+// ResumeSession plays s on the player's device from the same position.
+// Artist contexts can't start at a given track, so they play s.Tracks.
+func (p *Player) ResumeSession(ctx context.Context, s *core.Session) error {
+	opts := &client.PlayOptions{PositionMS: int(s.Progress.Milliseconds())}
+	switch {
+	case s.Context != "" && !strings.HasPrefix(s.Context, "spotify:artist:"):
+		opts.ContextURI = s.Context
+		opts.Offset = &client.PlayOffset{URI: s.Track}
+	case len(s.Tracks) > 0:
+		opts.URIs = s.Tracks
+	default:
+		opts.URIs = []string{s.Track}
+	}
+	return p.client.Play(ctx, p.deviceID, opts)
+}
+
 // GetDevices returns the user's available playback devices.
 func (p *Player) GetDevices(ctx context.Context) ([]core.Device, error) {
 	devices, err := p.client.GetDevices(ctx)
@@ -0,0 +1,186 @@
+package spotifytest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tessro/riff/internal/spotify/client"
+)
+
+// catalog is the fake's music library. Albums and playlists are contexts
+// whose tracks are listed in order.
+type catalog struct {
+	tracks    []client.Track
+	artists   []client.Artist
+	albums    []client.Album
+	playlists []client.Playlist
+
+	// contexts maps album and playlist URIs to their track URIs.
+	contexts map[string][]string
+}
+
+// defaultCatalog returns two albums by two artists and a playlist mixing them.
+func defaultCatalog() catalog {
+	c := catalog{contexts: make(map[string][]string)}
+
+	c.addAlbum("Night Drive", "The Examples", []string{"Neon Lights", "Overpass", "Last Exit"})
+	c.addAlbum("Morning Songs", "Test Pattern", []string{"Sunrise", "Coffee", "Commute", "Desk Lamp"})
+
+	playlist := client.Playlist{
+		ID:   "playlist-mix",
+		Name: "Test Mix",
+		URI:  "spotify:playlist:playlist-mix",
+	}
+	playlist.Tracks.Total = 3
+	c.playlists = append(c.playlists, playlist)
+	c.contexts[playlist.URI] = []string{
+		c.tracks[1].URI, // Overpass
+		c.tracks[3].URI, // Sunrise
+		c.tracks[6].URI, // Desk Lamp
+	}
+
+	return c
+}
+
+// addAlbum adds an album, its artist and its tracks (three minutes each).
+func (c *catalog) addAlbum(name, artistName string, titles []string) {
+	artistID := slug(artistName)
+	artist := client.Artist{
+		ID:   artistID,
+		Name: artistName,
+		URI:  "spotify:artist:" + artistID,
+		Type: "artist",
+	}
+	c.artists = append(c.artists, artist)
+
+	albumID := slug(name)
+	album := client.Album{
+		ID:          albumID,
+		Name:        name,
+		URI:         "spotify:album:" + albumID,
+		AlbumType:   "album",
+		TotalTracks: len(titles),
+		Artists:     []client.Artist{artist},
+	}
+	c.albums = append(c.albums, album)
+
+	var uris []string
+	for i, title := range titles {
+		id := fmt.Sprintf("%s-%d", albumID, i+1)
+		track := client.Track{
+			ID:          id,
+			Name:        title,
+			URI:         "spotify:track:" + id,
+			DurationMS:  180000,
+			IsPlayable:  true,
+			TrackNumber: i + 1,
+			DiscNumber:  1,
+			Artists:     []client.Artist{artist},
+			Album:       album,
+		}
+		c.tracks = append(c.tracks, track)
+		uris = append(uris, track.URI)
+	}
+	c.contexts[album.URI] = uris
+}
+
+// track looks up a track by URI.
+func (c *catalog) track(uri string) (client.Track, bool) {
+	for _, t := range c.tracks {
+		if t.URI == uri {
+			return t, true
+		}
+	}
+	return client.Track{}, false
+}
+
+// context returns the tracks of an album, playlist or artist context.
+// Artist contexts play every track by that artist.
+func (c *catalog) context(uri string) ([]client.Track, bool) {
+	if uris, ok := c.contexts[uri]; ok {
+		tracks := make([]client.Track, 0, len(uris))
+		for _, u := range uris {
+			if t, ok := c.track(u); ok {
+				tracks = append(tracks, t)
+			}
+		}
+		return tracks, true
+	}
+
+	if uriType(uri) == "artist" {
+		var tracks []client.Track
+		for _, t := range c.tracks {
+			for _, a := range t.Artists {
+				if a.URI == uri {
+					tracks = append(tracks, t)
+					break
+				}
+			}
+		}
+		return tracks, len(tracks) > 0
+	}
+
+	return nil, false
+}
+
+func (c *catalog) searchTracks(query string) []client.Track {
+	var out []client.Track
+	for _, t := range c.tracks {
+		names := []string{t.Name, t.Album.Name}
+		for _, a := range t.Artists {
+			names = append(names, a.Name)
+		}
+		if matches(query, names...) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func (c *catalog) searchArtists(query string) []client.Artist {
+	var out []client.Artist
+	for _, a := range c.artists {
+		if matches(query, a.Name) {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+func (c *catalog) searchAlbums(query string) []client.Album {
+	var out []client.Album
+	for _, a := range c.albums {
+		names := []string{a.Name}
+		for _, ar := range a.Artists {
+			names = append(names, ar.Name)
+		}
+		if matches(query, names...) {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+func (c *catalog) searchPlaylists(query string) []client.Playlist {
+	var out []client.Playlist
+	for _, p := range c.playlists {
+		if matches(query, p.Name) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// matches reports whether any of names contains the (lowercase) query.
+func matches(query string, names ...string) bool {
+	for _, n := range names {
+		if strings.Contains(strings.ToLower(n), query) {
+			return true
+		}
+	}
+	return false
+}
+
+func slug(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), " ", "-")
+}
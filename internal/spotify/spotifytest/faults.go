@@ -0,0 +1,99 @@
+package spotifytest
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Fault describes an error the server returns instead of handling a request.
+type Fault struct {
+	// Method and Path select the requests to fail. Path is relative to the
+	// API base (e.g. "/me/player/play"), or "/api/token" for the token
+	// endpoint. Empty values match anything.
+	Method string
+	Path   string
+
+	// Status is the HTTP status code to return.
+	Status int
+	// Reason is the Spotify error reason, e.g. "NO_ACTIVE_DEVICE".
+	Reason string
+	// Message is the error message; defaults to the status text.
+	Message string
+	// RetryAfter sets the Retry-After header, for 429 responses.
+	RetryAfter time.Duration
+
+	// Times is how many matching requests fail before the fault clears.
+	// Zero means the fault persists until ClearFaults.
+	Times int
+}
+
+// RateLimited returns a 429 fault for path with the given Retry-After.
+func RateLimited(path string, retryAfter time.Duration) Fault {
+	return Fault{Path: path, Status: http.StatusTooManyRequests, Message: "API rate limit exceeded", RetryAfter: retryAfter}
+}
+
+// ServerError returns a 5xx fault for path.
+func ServerError(path string, status int) Fault {
+	return Fault{Path: path, Status: status}
+}
+
+// NoActiveDevice returns the 404 Spotify sends for player commands when no
+// device is active.
+func NoActiveDevice(path string) Fault {
+	return Fault{Path: path, Status: http.StatusNotFound, Reason: "NO_ACTIVE_DEVICE", Message: "Player command failed: No active device found"}
+}
+
+// PremiumRequired returns the 403 Spotify sends for player commands from
+// free accounts.
+func PremiumRequired(path string) Fault {
+	return Fault{Path: path, Status: http.StatusForbidden, Reason: "PREMIUM_REQUIRED", Message: "Player command failed: Premium required"}
+}
+
+// InjectFault makes matching requests fail. Faults are checked in the order
+// they were injected, after authentication.
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes all injected faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// matchFault returns the first fault matching the request, consuming one of
+// its uses. Callers must hold s.mu.
+func (s *Server) matchFault(method, path string) *Fault {
+	for i, f := range s.faults {
+		if f.Method != "" && f.Method != method {
+			continue
+		}
+		if f.Path != "" && f.Path != path {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+func (f *Fault) write(w http.ResponseWriter) {
+	if f.RetryAfter > 0 {
+		secs := int((f.RetryAfter + time.Second - 1) / time.Second)
+		w.Header().Set("Retry-After", strconv.Itoa(secs))
+	}
+	msg := f.Message
+	if msg == "" {
+		msg = http.StatusText(f.Status)
+	}
+	writeError(w, f.Status, f.Reason, msg)
+}
@@ -0,0 +1,877 @@
+// Package spotifytest provides a fake Spotify Web API for tests.
+//
+// The fake keeps a small stateful model of devices, playback, the queue and
+// a track catalog, and serves the endpoints riff uses from an
+// httptest.Server. Player commands mutate that model the way Spotify does,
+// so a test can run a command and then assert on the resulting state:
+//
+//	srv := spotifytest.NewServer()
+//	defer srv.Close()
+//
+//	c := srv.Client()
+//	_ = c.Play(ctx, srv.Devices()[0].ID, nil)
+//	state := srv.State()
+//
+// Errors can be injected per endpoint with InjectFault.
+package spotifytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tessro/riff/internal/spotify/auth"
+	"github.com/tessro/riff/internal/spotify/client"
+)
+
+const (
+	// ClientID is the OAuth client ID the fake accepts.
+	ClientID = "spotifytest-client"
+
+	// tokenLifetime is how long issued access tokens are valid.
+	tokenLifetime = time.Hour
+)
+
+// Request records a request received by the server.
+type Request struct {
+	Method string
+	Path   string // Path relative to the API base, e.g. "/me/player/play"
+	Query  map[string]string
+	Body   string
+}
+
+// Server is a fake Spotify Web API and accounts service.
+type Server struct {
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	user     client.User
+	devices  []client.Device
+	catalog  catalog
+	playback playback
+	queue    []client.Track
+	history  []client.PlayHistory
+	faults   []*Fault
+	requests []Request
+
+	accessTokens map[string]bool
+	refreshToken string
+	tokenSeq     int
+}
+
+// playback is the server's model of the current playback.
+type playback struct {
+	deviceID   string // Active device; empty when nothing is active
+	isPlaying  bool
+	progressMS int
+	item       *client.Track
+	context    *client.Context
+	contextPos int // Index of item within the context's tracks
+	shuffle    bool
+	repeat     string
+}
+
+// NewServer starts a fake Spotify server with a premium user, two inactive
+// devices and a small catalog. Call Close when done.
+func NewServer() *Server {
+	s := &Server{
+		user: client.User{
+			ID:          "testuser",
+			DisplayName: "Test User",
+			Email:       "test@example.com",
+			Country:     "US",
+			Product:     "premium",
+			Type:        "user",
+			URI:         "spotify:user:testuser",
+		},
+		devices: []client.Device{
+			{ID: "device-computer", Name: "Test Computer", Type: "Computer", VolumePercent: intPtr(50), SupportsVolume: true},
+			{ID: "device-speaker", Name: "Test Speaker", Type: "Speaker", VolumePercent: intPtr(30), SupportsVolume: true},
+		},
+		catalog:      defaultCatalog(),
+		playback:     playback{repeat: "off"},
+		accessTokens: make(map[string]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/", s.handleAPI)
+	mux.HandleFunc("/api/token", s.handleToken)
+	s.srv = httptest.NewServer(mux)
+
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// URL returns the root URL of the server.
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// APIURL returns the Web API base URL, for client.WithBaseURL.
+func (s *Server) APIURL() string {
+	return s.srv.URL + "/v1"
+}
+
+// AccountsURL returns the accounts service base URL, for client.WithAccountsURL.
+func (s *Server) AccountsURL() string {
+	return s.srv.URL
+}
+
+// ClientOptions returns the options that point a client.Client at this server.
+func (s *Server) ClientOptions() []client.Option {
+	return []client.Option{
+		client.WithBaseURL(s.APIURL()),
+		client.WithAccountsURL(s.AccountsURL()),
+	}
+}
+
+// Token issues a fresh, valid token. Store it in a TokenStore to authenticate
+// a client against this server.
+func (s *Server) Token() *auth.Token {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.issueToken()
+}
+
+// Client returns a client authenticated against this server, backed by an
+// in-memory token store.
+func (s *Server) Client() *client.Client {
+	store := NewMemoryTokenStore(s.Token())
+	c := client.New(ClientID, store, s.ClientOptions()...)
+	_ = c.LoadToken()
+	return c
+}
+
+// ExpireAccessTokens invalidates every issued access token, so the next API
+// request gets a 401 and the client has to refresh.
+func (s *Server) ExpireAccessTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accessTokens = make(map[string]bool)
+}
+
+// RefreshToken returns the currently valid refresh token. Refresh tokens
+// rotate on every refresh, as Spotify's do for PKCE clients.
+func (s *Server) RefreshToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refreshToken
+}
+
+// SetProduct sets the user's subscription ("premium", "free"). Player
+// commands fail with PREMIUM_REQUIRED for non-premium users.
+func (s *Server) SetProduct(product string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user.Product = product
+}
+
+// SetDevices replaces the available devices.
+func (s *Server) SetDevices(devices ...client.Device) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.devices = append([]client.Device(nil), devices...)
+	if s.deviceIndex(s.playback.deviceID) < 0 {
+		s.playback.deviceID = ""
+		s.playback.isPlaying = false
+	}
+}
+
+// Devices returns a snapshot of the available devices.
+func (s *Server) Devices() []client.Device {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshotDevices()
+}
+
+// AddTracks adds tracks to the catalog, making them playable and searchable.
+func (s *Server) AddTracks(tracks ...client.Track) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.catalog.tracks = append(s.catalog.tracks, tracks...)
+}
+
+// Tracks returns the tracks in the catalog.
+func (s *Server) Tracks() []client.Track {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]client.Track(nil), s.catalog.tracks...)
+}
+
+// State returns the current playback state, or nil if no device is active.
+func (s *Server) State() *client.PlaybackState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.playbackState()
+}
+
+// Queue returns the tracks queued after the current item.
+func (s *Server) Queue() []client.Track {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]client.Track(nil), s.queue...)
+}
+
+// Advance moves the playback position forward by d if playing, rolling over
+// to the next track when the current one ends. Progress never advances on
+// its own, which keeps tests deterministic.
+func (s *Server) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.playback.isPlaying || s.playback.item == nil {
+		return
+	}
+	s.playback.progressMS += int(d / time.Millisecond)
+	for s.playback.isPlaying && s.playback.progressMS >= s.playback.item.DurationMS {
+		rest := s.playback.progressMS - s.playback.item.DurationMS
+		s.skipNext()
+		if s.playback.isPlaying {
+			s.playback.progressMS = rest
+		}
+	}
+}
+
+// Requests returns every API request received so far, oldest first.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// handleToken implements the accounts service token endpoint for the
+// refresh_token and authorization_code grants.
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeTokenError(w, "invalid_request", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  flatten(r.PostForm),
+	})
+
+	if fault := s.matchFault(r.Method, r.URL.Path); fault != nil {
+		fault.write(w)
+		return
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case "refresh_token":
+		if s.refreshToken == "" || r.PostForm.Get("refresh_token") != s.refreshToken {
+			writeTokenError(w, "invalid_grant", "Invalid refresh token")
+			return
+		}
+	case "authorization_code":
+		if r.PostForm.Get("code") == "" || r.PostForm.Get("code_verifier") == "" {
+			writeTokenError(w, "invalid_grant", "Invalid authorization code")
+			return
+		}
+	default:
+		writeTokenError(w, "unsupported_grant_type", "grant_type must be refresh_token or authorization_code")
+		return
+	}
+
+	token := s.issueToken()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":  token.AccessToken,
+		"token_type":    token.TokenType,
+		"scope":         token.Scope,
+		"expires_in":    token.ExpiresIn,
+		"refresh_token": token.RefreshToken,
+	})
+}
+
+// issueToken mints a new access token and rotates the refresh token.
+// Callers must hold s.mu.
+func (s *Server) issueToken() *auth.Token {
+	s.tokenSeq++
+	access := fmt.Sprintf("access-%d", s.tokenSeq)
+	s.refreshToken = fmt.Sprintf("refresh-%d", s.tokenSeq)
+	s.accessTokens[access] = true
+
+	return &auth.Token{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		Scope:        strings.Join(auth.DefaultScopes, " "),
+		ExpiresIn:    int(tokenLifetime / time.Second),
+		RefreshToken: s.refreshToken,
+		ExpiresAt:    time.Now().Add(tokenLifetime),
+	}
+}
+
+func writeTokenError(w http.ResponseWriter, code, desc string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{
+		"error":             code,
+		"error_description": desc,
+	})
+}
+
+// handleAPI authenticates, records and dispatches a Web API request.
+func (s *Server) handleAPI(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v1")
+
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, Request{
+		Method: r.Method,
+		Path:   path,
+		Query:  flatten(r.URL.Query()),
+		Body:   string(body),
+	})
+
+	bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !s.accessTokens[bearer] {
+		writeError(w, http.StatusUnauthorized, "", "The access token expired")
+		return
+	}
+
+	if fault := s.matchFault(r.Method, path); fault != nil {
+		fault.write(w)
+		return
+	}
+
+	route := r.Method + " " + path
+	switch route {
+	case "GET /me":
+		writeJSON(w, http.StatusOK, s.user)
+	case "GET /me/player":
+		s.getPlayer(w)
+	case "PUT /me/player":
+		s.transfer(w, body)
+	case "GET /me/player/devices":
+		writeJSON(w, http.StatusOK, client.DevicesResponse{Devices: s.snapshotDevices()})
+	case "PUT /me/player/play":
+		s.play(w, r, body)
+	case "PUT /me/player/pause":
+		s.pause(w, r)
+	case "POST /me/player/next":
+		s.next(w, r)
+	case "POST /me/player/previous":
+		s.previous(w, r)
+	case "PUT /me/player/seek":
+		s.seek(w, r)
+	case "PUT /me/player/volume":
+		s.volume(w, r)
+	case "PUT /me/player/repeat":
+		s.setRepeat(w, r)
+	case "PUT /me/player/shuffle":
+		s.setShuffle(w, r)
+	case "GET /me/player/queue":
+		s.getQueue(w)
+	case "POST /me/player/queue":
+		s.addToQueue(w, r)
+	case "GET /me/player/recently-played":
+		s.recentlyPlayed(w, r)
+	case "GET /me/playlists":
+		s.myPlaylists(w, r)
+	case "GET /search":
+		s.search(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "", "Service not found")
+	}
+}
+
+func (s *Server) getPlayer(w http.ResponseWriter) {
+	state := s.playbackState()
+	if state == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, state)
+}
+
+func (s *Server) transfer(w http.ResponseWriter, body []byte) {
+	var req struct {
+		DeviceIDs []string `json:"device_ids"`
+		Play      *bool    `json:"play"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil || len(req.DeviceIDs) != 1 {
+		writeError(w, http.StatusBadRequest, "", "Exactly one device_id is required")
+		return
+	}
+	if !s.requirePremium(w) {
+		return
+	}
+	if s.deviceIndex(req.DeviceIDs[0]) < 0 {
+		writeError(w, http.StatusNotFound, "", "Device not found")
+		return
+	}
+
+	s.playback.deviceID = req.DeviceIDs[0]
+	if req.Play != nil && *req.Play {
+		s.playback.isPlaying = s.playback.item != nil
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) play(w http.ResponseWriter, r *http.Request, body []byte) {
+	if !s.requirePremium(w) || !s.targetDevice(w, r) {
+		return
+	}
+
+	var opts client.PlayOptions
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &opts); err != nil {
+			writeError(w, http.StatusBadRequest, "", "Malformed json")
+			return
+		}
+	}
+
+	switch {
+	case opts.ContextURI != "":
+		tracks, ok := s.catalog.context(opts.ContextURI)
+		if !ok || len(tracks) == 0 {
+			writeError(w, http.StatusNotFound, "", "Context not found")
+			return
+		}
+		pos := 0
+		if opts.Offset != nil {
+			if opts.Offset.URI != "" {
+				pos = -1
+				for i, t := range tracks {
+					if t.URI == opts.Offset.URI {
+						pos = i
+					}
+				}
+				if pos < 0 {
+					writeError(w, http.StatusNotFound, "", "Offset track not in context")
+					return
+				}
+			} else {
+				pos = opts.Offset.Position
+			}
+		}
+		if pos < 0 || pos >= len(tracks) {
+			writeError(w, http.StatusBadRequest, "", "Invalid offset")
+			return
+		}
+		s.startItem(&tracks[pos], &client.Context{Type: uriType(opts.ContextURI), URI: opts.ContextURI}, pos)
+	case len(opts.URIs) > 0:
+		track, ok := s.catalog.track(opts.URIs[0])
+		if !ok {
+			writeError(w, http.StatusBadRequest, "", "Invalid track uri: "+opts.URIs[0])
+			return
+		}
+		s.startItem(&track, nil, 0)
+	default:
+		// Resume: Spotify rejects resuming something already playing
+		if s.playback.isPlaying {
+			writeError(w, http.StatusForbidden, "UNKNOWN", "Player command failed: Restriction violated")
+			return
+		}
+		if s.playback.item == nil {
+			if len(s.catalog.tracks) == 0 {
+				writeError(w, http.StatusNotFound, "", "Nothing to play")
+				return
+			}
+			s.startItem(&s.catalog.tracks[0], nil, 0)
+		}
+	}
+
+	s.playback.progressMS += opts.PositionMS
+	s.playback.isPlaying = true
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) pause(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePremium(w) || !s.targetDevice(w, r) {
+		return
+	}
+	if !s.playback.isPlaying {
+		writeError(w, http.StatusForbidden, "UNKNOWN", "Player command failed: Restriction violated")
+		return
+	}
+	s.playback.isPlaying = false
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) next(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePremium(w) || !s.targetDevice(w, r) {
+		return
+	}
+	s.skipNext()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) previous(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePremium(w) || !s.targetDevice(w, r) {
+		return
+	}
+
+	// Like Spotify, restart the track unless it has only just started
+	if s.playback.progressMS < 3000 && s.playback.context != nil && s.playback.contextPos > 0 {
+		tracks, _ := s.catalog.context(s.playback.context.URI)
+		s.playback.contextPos--
+		prev := tracks[s.playback.contextPos]
+		s.playback.item = &prev
+	}
+	s.playback.progressMS = 0
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) seek(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePremium(w) || !s.targetDevice(w, r) {
+		return
+	}
+	pos, err := strconv.Atoi(r.URL.Query().Get("position_ms"))
+	if err != nil || pos < 0 {
+		writeError(w, http.StatusBadRequest, "", "Invalid position_ms")
+		return
+	}
+	if s.playback.item != nil && pos >= s.playback.item.DurationMS {
+		s.skipNext()
+	} else {
+		s.playback.progressMS = pos
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) volume(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePremium(w) || !s.targetDevice(w, r) {
+		return
+	}
+	percent, err := strconv.Atoi(r.URL.Query().Get("volume_percent"))
+	if err != nil || percent < 0 || percent > 100 {
+		writeError(w, http.StatusBadRequest, "", "Invalid volume_percent")
+		return
+	}
+
+	dev := &s.devices[s.deviceIndex(s.playback.deviceID)]
+	if !dev.SupportsVolume {
+		writeError(w, http.StatusForbidden, "VOLUME_CONTROL_DISALLOW", "Player command failed: Cannot control device volume")
+		return
+	}
+	dev.VolumePercent = intPtr(percent)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) setRepeat(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePremium(w) || !s.targetDevice(w, r) {
+		return
+	}
+	state := r.URL.Query().Get("state")
+	switch state {
+	case "off", "track", "context":
+		s.playback.repeat = state
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusBadRequest, "", "Invalid repeat state")
+	}
+}
+
+func (s *Server) setShuffle(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePremium(w) || !s.targetDevice(w, r) {
+		return
+	}
+	state, err := strconv.ParseBool(r.URL.Query().Get("state"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "", "Invalid shuffle state")
+		return
+	}
+	s.playback.shuffle = state
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getQueue(w http.ResponseWriter) {
+	queue := client.Queue{
+		CurrentlyPlaying: s.playback.item,
+		Queue:            append([]client.Track{}, s.queue...),
+	}
+	// Spotify lists upcoming context tracks after explicitly queued ones
+	if s.playback.context != nil {
+		tracks, _ := s.catalog.context(s.playback.context.URI)
+		queue.Queue = append(queue.Queue, tracks[s.playback.contextPos+1:]...)
+	}
+	writeJSON(w, http.StatusOK, queue)
+}
+
+func (s *Server) addToQueue(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePremium(w) || !s.targetDevice(w, r) {
+		return
+	}
+	uri := r.URL.Query().Get("uri")
+	track, ok := s.catalog.track(uri)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "", "Invalid track uri: "+uri)
+		return
+	}
+	s.queue = append(s.queue, track)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) recentlyPlayed(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 50 {
+			writeError(w, http.StatusBadRequest, "", "Invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	items := s.history
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	writeJSON(w, http.StatusOK, client.RecentlyPlayedResponse{
+		Items: append([]client.PlayHistory{}, items...),
+		Limit: limit,
+	})
+}
+
+// myPlaylists lists every playlist in the catalog as the user's own.
+func (s *Server) myPlaylists(w http.ResponseWriter, r *http.Request) {
+	limit, offset := 20, 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, _ = strconv.Atoi(v)
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		offset, _ = strconv.Atoi(v)
+	}
+	items := page(s.catalog.playlists, offset, limit)
+	writeJSON(w, http.StatusOK, client.PlaylistsResponse{
+		Items:  items,
+		Total:  len(s.catalog.playlists),
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query := strings.ToLower(q.Get("q"))
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "", "No search query")
+		return
+	}
+
+	limit, offset := 20, 0
+	if v := q.Get("limit"); v != "" {
+		limit, _ = strconv.Atoi(v)
+	}
+	if v := q.Get("offset"); v != "" {
+		offset, _ = strconv.Atoi(v)
+	}
+
+	var resp client.SearchResponse
+	for _, typ := range strings.Split(q.Get("type"), ",") {
+		switch typ {
+		case "track":
+			items := page(s.catalog.searchTracks(query), offset, limit)
+			resp.Tracks = &client.SearchTracks{Items: items, Total: len(items), Limit: limit, Offset: offset}
+		case "artist":
+			items := page(s.catalog.searchArtists(query), offset, limit)
+			resp.Artists = &client.SearchArtists{Items: items, Total: len(items), Limit: limit, Offset: offset}
+		case "album":
+			items := page(s.catalog.searchAlbums(query), offset, limit)
+			resp.Albums = &client.SearchAlbums{Items: items, Total: len(items), Limit: limit, Offset: offset}
+		case "playlist":
+			items := page(s.catalog.searchPlaylists(query), offset, limit)
+			resp.Playlists = &client.SearchPlaylists{Items: items, Total: len(items), Limit: limit, Offset: offset}
+		default:
+			writeError(w, http.StatusBadRequest, "", "Bad search type field "+typ)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// requirePremium rejects player commands for non-premium users.
+func (s *Server) requirePremium(w http.ResponseWriter) bool {
+	if s.user.Product != "premium" {
+		writeError(w, http.StatusForbidden, "PREMIUM_REQUIRED", "Player command failed: Premium required")
+		return false
+	}
+	return true
+}
+
+// targetDevice resolves the device a player command applies to: the
+// device_id parameter (which becomes active) or the active device.
+func (s *Server) targetDevice(w http.ResponseWriter, r *http.Request) bool {
+	if id := r.URL.Query().Get("device_id"); id != "" {
+		if s.deviceIndex(id) < 0 {
+			writeError(w, http.StatusNotFound, "", "Device not found")
+			return false
+		}
+		s.playback.deviceID = id
+		return true
+	}
+	if s.playback.deviceID == "" {
+		writeError(w, http.StatusNotFound, "NO_ACTIVE_DEVICE", "Player command failed: No active device found")
+		return false
+	}
+	return true
+}
+
+// startItem begins playing track, recording the previous item in history.
+func (s *Server) startItem(track *client.Track, ctx *client.Context, pos int) {
+	s.recordHistory()
+	t := *track
+	s.playback.item = &t
+	s.playback.context = ctx
+	s.playback.contextPos = pos
+	s.playback.progressMS = 0
+}
+
+// skipNext moves to the next queued track, then the next context track.
+// Playback stops at the end of the context.
+func (s *Server) skipNext() {
+	if len(s.queue) > 0 {
+		next := s.queue[0]
+		s.queue = s.queue[1:]
+		s.recordHistory()
+		s.playback.item = &next
+		s.playback.progressMS = 0
+		return
+	}
+
+	if s.playback.context != nil {
+		tracks, _ := s.catalog.context(s.playback.context.URI)
+		if s.playback.contextPos+1 < len(tracks) {
+			s.startItem(&tracks[s.playback.contextPos+1], s.playback.context, s.playback.contextPos+1)
+			return
+		}
+	}
+
+	s.recordHistory()
+	s.playback.isPlaying = false
+	s.playback.progressMS = 0
+}
+
+func (s *Server) recordHistory() {
+	if s.playback.item == nil {
+		return
+	}
+	entry := client.PlayHistory{
+		Track:    *s.playback.item,
+		PlayedAt: time.Now().UTC().Format(time.RFC3339),
+		Context:  s.playback.context,
+	}
+	s.history = append([]client.PlayHistory{entry}, s.history...)
+}
+
+// playbackState builds the API view of playback. Callers must hold s.mu.
+func (s *Server) playbackState() *client.PlaybackState {
+	i := s.deviceIndex(s.playback.deviceID)
+	if i < 0 {
+		return nil
+	}
+
+	devices := s.snapshotDevices()
+	state := &client.PlaybackState{
+		Device:       devices[i],
+		ShuffleState: s.playback.shuffle,
+		RepeatState:  s.playback.repeat,
+		Timestamp:    time.Now().UnixMilli(),
+		ProgressMS:   s.playback.progressMS,
+		IsPlaying:    s.playback.isPlaying,
+		Context:      s.playback.context,
+	}
+	if s.playback.item != nil {
+		item := *s.playback.item
+		state.Item = &item
+		state.CurrentlyPlayingType = "track"
+	}
+	return state
+}
+
+// snapshotDevices copies the devices, marking the active one.
+// Callers must hold s.mu.
+func (s *Server) snapshotDevices() []client.Device {
+	devices := make([]client.Device, len(s.devices))
+	for i, d := range s.devices {
+		d.IsActive = d.ID == s.playback.deviceID
+		if d.VolumePercent != nil {
+			d.VolumePercent = intPtr(*d.VolumePercent)
+		}
+		devices[i] = d
+	}
+	return devices
+}
+
+func (s *Server) deviceIndex(id string) int {
+	if id == "" {
+		return -1
+	}
+	for i, d := range s.devices {
+		if d.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// writeError writes a Spotify-style error body.
+func writeError(w http.ResponseWriter, status int, reason, message string) {
+	info := map[string]interface{}{
+		"status":  status,
+		"message": message,
+	}
+	if reason != "" {
+		info["reason"] = reason
+	}
+	writeJSON(w, status, map[string]interface{}{"error": info})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func flatten(values map[string][]string) map[string]string {
+	out := make(map[string]string, len(values))
+	for k, v := range values {
+		if len(v) > 0 {
+			out[k] = v[0]
+		}
+	}
+	return out
+}
+
+func page[T any](items []T, offset, limit int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	items = items[offset:]
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
+
+// uriType returns the type component of a Spotify URI, e.g. "album".
+func uriType(uri string) string {
+	parts := strings.Split(uri, ":")
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[1]
+}
+
+func intPtr(v int) *int {
+	return &v
+}
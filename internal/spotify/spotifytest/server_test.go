@@ -0,0 +1,205 @@
+package spotifytest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tessro/riff/internal/spotify/client"
+)
+
+func TestPlaybackModel(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := srv.Client()
+
+	if state := srv.State(); state != nil {
+		t.Fatalf("State() = %+v, want nil before any device is active", state)
+	}
+
+	album := "spotify:album:night-drive"
+	err := c.Play(ctx, "device-speaker", &client.PlayOptions{
+		ContextURI: album,
+		Offset:     &client.PlayOffset{Position: 1},
+	})
+	if err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+
+	state := srv.State()
+	if !state.IsPlaying {
+		t.Error("IsPlaying = false after Play()")
+	}
+	if state.Device.ID != "device-speaker" || !state.Device.IsActive {
+		t.Errorf("Device = %+v, want active device-speaker", state.Device)
+	}
+	if state.Item.Name != "Overpass" {
+		t.Errorf("Item = %q, want %q", state.Item.Name, "Overpass")
+	}
+	if state.Context == nil || state.Context.URI != album {
+		t.Errorf("Context = %+v, want %s", state.Context, album)
+	}
+
+	if err := c.AddToQueue(ctx, "spotify:track:morning-songs-2", ""); err != nil {
+		t.Fatalf("AddToQueue() error = %v", err)
+	}
+	if err := c.Next(ctx, ""); err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if got := srv.State().Item.Name; got != "Coffee" {
+		t.Errorf("after Next() Item = %q, want queued %q", got, "Coffee")
+	}
+	if err := c.Next(ctx, ""); err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if got := srv.State().Item.Name; got != "Last Exit" {
+		t.Errorf("after Next() Item = %q, want context track %q", got, "Last Exit")
+	}
+
+	if err := c.Seek(ctx, 90000, ""); err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+	if got := srv.State().ProgressMS; got != 90000 {
+		t.Errorf("ProgressMS = %d, want 90000", got)
+	}
+
+	if err := c.SetVolume(ctx, 70, ""); err != nil {
+		t.Fatalf("SetVolume() error = %v", err)
+	}
+	if got := *srv.State().Device.VolumePercent; got != 70 {
+		t.Errorf("VolumePercent = %d, want 70", got)
+	}
+
+	if err := c.Pause(ctx, ""); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if srv.State().IsPlaying {
+		t.Error("IsPlaying = true after Pause()")
+	}
+
+	// Pausing twice is rejected, like the real API
+	if err := c.Pause(ctx, ""); !client.IsAlreadyPlayingError(err) {
+		t.Errorf("second Pause() error = %v, want 403", err)
+	}
+
+	if err := c.TransferPlayback(ctx, "device-computer", true); err != nil {
+		t.Fatalf("TransferPlayback() error = %v", err)
+	}
+	state = srv.State()
+	if state.Device.ID != "device-computer" || !state.IsPlaying {
+		t.Errorf("after transfer Device = %s, IsPlaying = %v", state.Device.ID, state.IsPlaying)
+	}
+
+	recent, err := c.GetRecentlyPlayed(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetRecentlyPlayed() error = %v", err)
+	}
+	if len(recent.Items) != 2 || recent.Items[0].Track.Name != "Coffee" {
+		t.Errorf("RecentlyPlayed = %+v, want Coffee then Overpass", recent.Items)
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := srv.Client()
+
+	if err := c.Play(ctx, "device-computer", &client.PlayOptions{ContextURI: "spotify:album:night-drive"}); err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+
+	srv.Advance(4 * time.Minute)
+	state := srv.State()
+	if state.Item.Name != "Overpass" || state.ProgressMS != 60000 {
+		t.Errorf("after 4m Item = %q at %dms, want Overpass at 60000ms", state.Item.Name, state.ProgressMS)
+	}
+
+	// Running off the end of the context stops playback
+	srv.Advance(time.Hour)
+	if srv.State().IsPlaying {
+		t.Error("IsPlaying = true after the context ended")
+	}
+}
+
+func TestPlayerErrors(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := srv.Client()
+
+	err := c.Pause(ctx, "")
+	if !client.IsNoActiveDeviceError(err) {
+		t.Errorf("Pause() with no active device error = %v, want 404", err)
+	}
+
+	if err := c.Play(ctx, "no-such-device", nil); err == nil {
+		t.Error("Play() on unknown device should fail")
+	}
+
+	srv.SetProduct("free")
+	err = c.Play(ctx, "device-computer", nil)
+	apiErr, ok := err.(*client.APIError)
+	if !ok || apiErr.ErrorInfo.Status != http.StatusForbidden {
+		t.Errorf("Play() for free user error = %v, want 403", err)
+	}
+}
+
+func TestFaultInjection(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := srv.Client()
+
+	srv.InjectFault(NoActiveDevice("/me/player/devices"))
+	if _, err := c.GetDevices(ctx); !client.IsNoActiveDeviceError(err) {
+		t.Errorf("GetDevices() error = %v, want injected 404", err)
+	}
+
+	// Other endpoints are unaffected
+	if _, err := c.GetCurrentUser(ctx); err != nil {
+		t.Errorf("GetCurrentUser() error = %v", err)
+	}
+
+	srv.ClearFaults()
+	if _, err := c.GetDevices(ctx); err != nil {
+		t.Errorf("GetDevices() after ClearFaults() error = %v", err)
+	}
+
+	// A counted fault clears itself
+	srv.InjectFault(Fault{Method: http.MethodGet, Path: "/me", Status: http.StatusTooManyRequests, Times: 1})
+	if _, err := c.GetCurrentUser(ctx); err == nil {
+		t.Error("GetCurrentUser() should fail while the fault is active")
+	}
+	if _, err := c.GetCurrentUser(ctx); err != nil {
+		t.Errorf("GetCurrentUser() after fault expired error = %v", err)
+	}
+}
+
+func TestRateLimitedHeader(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	srv.InjectFault(RateLimited("/me", 1500*time.Millisecond))
+
+	req, _ := http.NewRequest(http.MethodGet, srv.APIURL()+"/me", nil)
+	req.Header.Set("Authorization", "Bearer "+srv.Token().AccessToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error = %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("StatusCode = %d, want 429", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want %q", got, "2")
+	}
+}
@@ -0,0 +1,49 @@
+package spotifytest
+
+import (
+	"sync"
+
+	"github.com/tessro/riff/internal/spotify/auth"
+)
+
+// MemoryTokenStore is an in-memory auth.TokenStore.
+type MemoryTokenStore struct {
+	mu    sync.Mutex
+	token *auth.Token
+}
+
+// NewMemoryTokenStore creates a store holding token, which may be nil.
+func NewMemoryTokenStore(token *auth.Token) *MemoryTokenStore {
+	return &MemoryTokenStore{token: token}
+}
+
+// Load returns a copy of the stored token.
+func (m *MemoryTokenStore) Load() (*auth.Token, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.token == nil {
+		return nil, nil
+	}
+	t := *m.token
+	return &t, nil
+}
+
+// Save stores a copy of token.
+func (m *MemoryTokenStore) Save(token *auth.Token) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := *token
+	m.token = &t
+	return nil
+}
+
+// Delete removes the stored token.
+func (m *MemoryTokenStore) Delete() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.token = nil
+	return nil
+}
+
+// Ensure MemoryTokenStore implements auth.TokenStore
+var _ auth.TokenStore = (*MemoryTokenStore)(nil)
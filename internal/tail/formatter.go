@@ -219,3 +219,14 @@ func eventTypeName(t EventType) string {
 		return "unknown"
 	}
 }
+
+// ParseEventType returns the event type with the given name, such as
+// "track_change".
+func ParseEventType(name string) (EventType, error) {
+	for t := EventTrackChange; t <= EventDeviceChange; t++ {
+		if eventTypeName(t) == name {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown event type: %s", name)
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/metrics"
 )
 
 // EventType represents the type of playback event.
@@ -20,15 +21,38 @@ const (
 	EventDeviceChange
 )
 
+// String returns the event type's name, such as "track_change".
+func (t EventType) String() string {
+	return eventTypeName(t)
+}
+
+// MarshalText encodes the event type by name, so events serialize readably.
+func (t EventType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText decodes an event type by name, so recorded events can be
+// read back.
+func (t *EventType) UnmarshalText(text []byte) error {
+	parsed, err := ParseEventType(string(text))
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
 // Event represents a playback state change.
 type Event struct {
-	Type      EventType
-	Timestamp time.Time
-	Previous  *core.PlaybackState
-	Current   *core.PlaybackState
+	Type      EventType           `json:"type"`
+	Timestamp time.Time           `json:"timestamp"`
+	Previous  *core.PlaybackState `json:"previous,omitempty"`
+	Current   *core.PlaybackState `json:"current"`
 }
 
-// Watcher polls a player for state changes and emits events.
+// Watcher polls a player for state changes and emits events. Players that
+// implement core.ChangeNotifier are also polled as soon as they report a
+// change.
 type Watcher struct {
 	player   core.Player
 	interval time.Duration
@@ -56,10 +80,18 @@ func (w *Watcher) Events() <-chan Event {
 
 // Start begins polling for state changes.
 func (w *Watcher) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	ticker := time.NewTicker(w.interval)
 	defer ticker.Stop()
 	defer close(w.events)
 
+	changed := make(chan struct{}, 1)
+	if n, ok := w.player.(core.ChangeNotifier); ok {
+		go w.notify(ctx, n, changed)
+	}
+
 	var prev *core.PlaybackState
 
 	// Get initial state
@@ -75,21 +107,46 @@ func (w *Watcher) Start(ctx context.Context) error {
 		case <-w.done:
 			return nil
 		case <-ticker.C:
-			curr, err := w.player.GetState(ctx)
-			if err != nil {
-				continue
-			}
+		case <-changed:
+		}
+
+		curr, err := w.player.GetState(ctx)
+		if err != nil {
+			metrics.WatcherPollErrors.Inc()
+			continue
+		}
 
-			events := diffStates(prev, curr)
-			for _, e := range events {
-				select {
-				case w.events <- e:
-				default:
-					// Drop event if channel is full
-				}
+		events := diffStates(prev, curr)
+		for _, e := range events {
+			select {
+			case w.events <- e:
+				metrics.WatcherEvents.WithLabelValues(e.Type.String()).Inc()
+			default:
+				// Drop event if channel is full
+				metrics.WatcherEventsDropped.WithLabelValues(e.Type.String()).Inc()
 			}
+		}
 
-			prev = curr
+		prev = curr
+	}
+}
+
+// notify signals changed whenever n reports a change, until ctx is done.
+func (w *Watcher) notify(ctx context.Context, n core.ChangeNotifier, changed chan<- struct{}) {
+	for {
+		if err := n.WaitForChange(ctx); err != nil {
+			// Leave it to the ticker until the player is reachable again
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(w.interval):
+			}
+			continue
+		}
+		select {
+		case changed <- struct{}{}:
+		default:
+			// A poll is already pending
 		}
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/BurntSushi/toml"
@@ -13,9 +14,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/tessro/riff/internal/core"
-	"github.com/tessro/riff/internal/spotify/auth"
-	"github.com/tessro/riff/internal/spotify/client"
-	"github.com/tessro/riff/internal/spotify/player"
+	"github.com/tessro/riff/internal/sleeptimer"
 	"github.com/tessro/riff/internal/tui/components"
 	"github.com/tessro/riff/internal/tui/styles"
 )
@@ -43,41 +42,64 @@ const (
 
 // searchResult represents a search result item
 type searchResult struct {
-	URI       string
-	ArtistURI string // For starting radio (artist context)
-	Title     string
-	Subtitle  string
-	Type      SearchType
+	URI      string
+	Title    string
+	Subtitle string
+	Type     SearchType
 }
 
 const searchDebounce = 300 * time.Millisecond
 
 // App holds the TUI application state
 type App struct {
-	spotifyClient *client.Client
-	player        *player.Player
+	registry      *core.Registry
 	refreshRate   time.Duration
-	defaultDevice string // Device name from config
-}
-
-// NewApp creates a new TUI application
-func NewApp(clientID string, refreshRate time.Duration, defaultDevice string) (*App, error) {
-	storage, err := auth.NewTokenStorage("")
-	if err != nil {
-		return nil, err
-	}
+	defaultDevice string            // Device name from config
+	sleepTimers   *sleeptimer.Store // May be nil
 
-	spotifyClient := client.New(clientID, storage)
-	if err := spotifyClient.LoadToken(); err != nil {
-		return nil, err
-	}
+	mu     sync.RWMutex
+	player core.Player // Player currently under control
+}
 
+// NewApp creates a new TUI application controlling p, with registry
+// supplying devices and search
+func NewApp(registry *core.Registry, p core.Player, refreshRate time.Duration, defaultDevice string, sleepTimers *sleeptimer.Store) *App {
 	return &App{
-		spotifyClient: spotifyClient,
-		player:        player.New(spotifyClient),
+		registry:      registry,
+		player:        p,
 		refreshRate:   refreshRate,
 		defaultDevice: defaultDevice,
-	}, nil
+		sleepTimers:   sleepTimers,
+	}
+}
+
+// current returns the player under control.
+func (a *App) current() core.Player {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.player
+}
+
+// setPlayer switches control to p.
+func (a *App) setPlayer(p core.Player) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.player = p
+}
+
+// uriPlayer is implemented by players that can start a specific URI.
+type uriPlayer interface {
+	PlayURI(ctx context.Context, uri string) error
+}
+
+// contextPlayer is implemented by players that can start an album, artist or playlist.
+type contextPlayer interface {
+	PlayContext(ctx context.Context, contextURI string, offset int) error
+}
+
+// playbackTransferer is implemented by players that can move playback between devices.
+type playbackTransferer interface {
+	TransferPlayback(ctx context.Context, deviceID string, play bool) error
 }
 
 // Model is the main TUI model
@@ -92,6 +114,7 @@ type Model struct {
 	queue    *core.Queue
 	devices  []core.Device
 	history  []components.HistoryEntry
+	sleep    *sleeptimer.Timer
 
 	// Components
 	nowPlaying *components.NowPlaying
@@ -145,6 +168,7 @@ type stateMsg *core.PlaybackState
 type queueMsg *core.Queue
 type devicesMsg []core.Device
 type historyMsg []core.HistoryEntry
+type sleepMsg *sleeptimer.Timer
 type errMsg error
 type defaultDeviceSetMsg string // Device name that was set as default
 
@@ -167,7 +191,7 @@ func (m Model) fetchState() tea.Cmd {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		state, err := m.app.player.GetState(ctx)
+		state, err := m.app.current().GetState(ctx)
 		if err != nil {
 			return errMsg(err)
 		}
@@ -175,12 +199,30 @@ func (m Model) fetchState() tea.Cmd {
 	}
 }
 
+// fetchSleep looks up the sleep timer on the device being shown.
+func (m Model) fetchSleep() tea.Cmd {
+	if m.app.sleepTimers == nil || m.state == nil || m.state.Device == nil {
+		return func() tea.Msg { return sleepMsg(nil) }
+	}
+	device := m.state.Device.ID
+	return func() tea.Msg {
+		t, err := m.app.sleepTimers.For(device)
+		if err != nil {
+			return sleepMsg(nil)
+		}
+		return sleepMsg(t)
+	}
+}
+
 func (m Model) fetchQueue() tea.Cmd {
+	if !m.caps().Has(core.CapQueueRead) {
+		return func() tea.Msg { return queueMsg(&core.Queue{}) }
+	}
 	return func() tea.Msg {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		queue, err := m.app.player.GetQueue(ctx)
+		queue, err := m.app.current().GetQueue(ctx)
 		if err != nil {
 			return errMsg(err)
 		}
@@ -193,8 +235,8 @@ func (m Model) fetchDevices() tea.Cmd {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		devices, err := m.app.player.GetDevices(ctx)
-		if err != nil {
+		devices, err := m.app.registry.Devices(ctx)
+		if err != nil && len(devices) == 0 {
 			return errMsg(err)
 		}
 		return devicesMsg(devices)
@@ -202,11 +244,14 @@ func (m Model) fetchDevices() tea.Cmd {
 }
 
 func (m Model) fetchHistory() tea.Cmd {
+	if !m.caps().Has(core.CapHistory) {
+		return nil
+	}
 	return func() tea.Msg {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		history, err := m.app.player.GetRecentlyPlayed(ctx, 20)
+		history, err := m.app.current().GetRecentlyPlayed(ctx, 20)
 		if err != nil {
 			return errMsg(err)
 		}
@@ -224,96 +269,49 @@ func (m Model) doSearch(query string) tea.Cmd {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		// Determine which types to search based on searchType
-		var types []client.SearchType
+		// Determine which kinds to search based on searchType
+		var kinds []core.SearchKind
 		switch searchType {
 		case SearchTracks:
-			types = []client.SearchType{client.SearchTypeTrack}
+			kinds = []core.SearchKind{core.SearchKindTrack}
 		case SearchAlbums:
-			types = []client.SearchType{client.SearchTypeAlbum}
+			kinds = []core.SearchKind{core.SearchKindAlbum}
 		case SearchArtists:
-			types = []client.SearchType{client.SearchTypeArtist}
+			kinds = []core.SearchKind{core.SearchKindArtist}
 		case SearchPlaylists:
-			types = []client.SearchType{client.SearchTypePlaylist}
+			kinds = []core.SearchKind{core.SearchKindPlaylist}
 		default:
-			types = []client.SearchType{
-				client.SearchTypeTrack,
-				client.SearchTypeAlbum,
-				client.SearchTypeArtist,
-				client.SearchTypePlaylist,
+			kinds = []core.SearchKind{
+				core.SearchKindTrack,
+				core.SearchKindAlbum,
+				core.SearchKindArtist,
+				core.SearchKindPlaylist,
 			}
 		}
 
-		resp, err := m.app.spotifyClient.Search(ctx, client.SearchOptions{
-			Query: query,
-			Types: types,
-			Limit: 10,
-		})
+		found, err := m.app.registry.Search(ctx, query, kinds, 10)
 		if err != nil {
 			return searchResultsMsg{err: err}
 		}
 
 		// Convert to searchResult slice
-		var results []searchResult
-
-		if resp.Tracks != nil {
-			for _, t := range resp.Tracks.Items {
-				artists := make([]string, len(t.Artists))
-				for i, a := range t.Artists {
-					artists[i] = a.Name
-				}
-				artistURI := ""
-				if len(t.Artists) > 0 {
-					artistURI = t.Artists[0].URI
-				}
-				results = append(results, searchResult{
-					URI:       t.URI,
-					ArtistURI: artistURI,
-					Title:     t.Name,
-					Subtitle:  strings.Join(artists, ", "),
-					Type:      SearchTracks,
-				})
-			}
-		}
-		if resp.Albums != nil {
-			for _, a := range resp.Albums.Items {
-				artists := make([]string, len(a.Artists))
-				for i, art := range a.Artists {
-					artists[i] = art.Name
-				}
-				artistURI := ""
-				if len(a.Artists) > 0 {
-					artistURI = a.Artists[0].URI
-				}
-				results = append(results, searchResult{
-					URI:       a.URI,
-					ArtistURI: artistURI,
-					Title:     a.Name,
-					Subtitle:  strings.Join(artists, ", ") + " (Album)",
-					Type:      SearchAlbums,
-				})
-			}
-		}
-		if resp.Artists != nil {
-			for _, a := range resp.Artists.Items {
-				results = append(results, searchResult{
-					URI:       a.URI,
-					ArtistURI: a.URI, // Artist's own URI for radio
-					Title:     a.Name,
-					Subtitle:  "(Artist)",
-					Type:      SearchArtists,
-				})
-			}
-		}
-		if resp.Playlists != nil {
-			for _, p := range resp.Playlists.Items {
-				results = append(results, searchResult{
-					URI:      p.URI,
-					Title:    p.Name,
-					Subtitle: "by " + p.Owner.DisplayName + " (Playlist)",
-					Type:     SearchPlaylists,
-				})
+		results := make([]searchResult, 0, len(found))
+		for _, r := range found {
+			result := searchResult{URI: r.URI, Title: r.Title, Subtitle: r.Subtitle}
+			switch r.Kind {
+			case core.SearchKindTrack:
+				result.Type = SearchTracks
+			case core.SearchKindAlbum:
+				result.Type = SearchAlbums
+				result.Subtitle += " (Album)"
+			case core.SearchKindArtist:
+				result.Type = SearchArtists
+				result.Subtitle = "(Artist)"
+			case core.SearchKindPlaylist:
+				result.Type = SearchPlaylists
+				result.Subtitle = "by " + r.Subtitle + " (Playlist)"
 			}
+			results = append(results, result)
 		}
 
 		return searchResultsMsg{results: results}
@@ -323,11 +321,11 @@ func (m Model) doSearch(query string) tea.Cmd {
 func (m Model) playSearchResult(result searchResult) tea.Cmd {
 	return func() tea.Msg {
 		ctx := context.Background()
-		switch result.Type {
-		case SearchTracks:
-			_ = m.app.player.PlayURI(ctx, result.URI)
-		case SearchAlbums, SearchArtists, SearchPlaylists:
-			_ = m.app.player.PlayContext(ctx, result.URI, 0)
+		p := m.app.current()
+		if cp, ok := p.(contextPlayer); ok && result.Type != SearchTracks {
+			_ = cp.PlayContext(ctx, result.URI, 0)
+		} else if up, ok := p.(uriPlayer); ok {
+			_ = up.PlayURI(ctx, result.URI)
 		}
 		time.Sleep(200 * time.Millisecond)
 		return refreshAfterActionMsg{}
@@ -337,7 +335,7 @@ func (m Model) playSearchResult(result searchResult) tea.Cmd {
 func (m Model) queueSearchResult(result searchResult) tea.Cmd {
 	return func() tea.Msg {
 		ctx := context.Background()
-		_ = m.app.player.AddToQueue(ctx, result.URI)
+		_ = m.app.current().AddToQueue(ctx, result.URI)
 		time.Sleep(200 * time.Millisecond)
 		return refreshAfterActionMsg{}
 	}
@@ -366,7 +364,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tickMsg:
-		return m, tea.Batch(m.tick(), m.fetchState())
+		return m, tea.Batch(m.tick(), m.fetchState(), m.fetchSleep())
+
+	case sleepMsg:
+		m.sleep = msg
+		return m, nil
 
 	case stateMsg:
 		if time.Now().After(m.errorExpiry) {
@@ -529,6 +531,27 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, tea.Batch(m.fetchState(), m.fetchQueue(), m.fetchDevices())
 	}
 
+	// Keys the current device may not support
+	caps := m.caps()
+	switch msg.String() {
+	case "left":
+		if caps.Has(core.CapSeek) {
+			return m, m.seekBy(-seekStep)
+		}
+	case "right":
+		if caps.Has(core.CapSeek) {
+			return m, m.seekBy(seekStep)
+		}
+	case "s":
+		if caps.Has(core.CapShuffle) {
+			return m, m.toggleShuffle()
+		}
+	case "R":
+		if caps.Has(core.CapRepeat) {
+			return m, m.cycleRepeat()
+		}
+	}
+
 	// Panel-specific keys
 	switch m.focusedPanel {
 	case PanelQueue:
@@ -599,7 +622,7 @@ func (m Model) handleSearchKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		// Add to queue (tracks only)
 		if len(m.searchResults) > 0 && m.searchCursor < len(m.searchResults) {
 			result := m.searchResults[m.searchCursor]
-			if result.Type == SearchTracks {
+			if result.Type == SearchTracks && m.caps().Has(core.CapQueueAdd) {
 				m.showSearch = false
 				m.searchInput.Blur()
 				return m, m.queueSearchResult(result)
@@ -627,9 +650,9 @@ func (m Model) togglePlayPause() tea.Cmd {
 	return func() tea.Msg {
 		ctx := context.Background()
 		if m.state != nil && m.state.IsPlaying {
-			_ = m.app.player.Pause(ctx)
+			_ = m.app.current().Pause(ctx)
 		} else {
-			_ = m.app.player.Play(ctx)
+			_ = m.app.current().Play(ctx)
 		}
 		return nil
 	}
@@ -639,7 +662,7 @@ type refreshAfterActionMsg struct{}
 
 func (m Model) nextTrack() tea.Cmd {
 	return func() tea.Msg {
-		_ = m.app.player.Next(context.Background())
+		_ = m.app.current().Next(context.Background())
 		// Small delay to let Spotify update state
 		time.Sleep(200 * time.Millisecond)
 		return refreshAfterActionMsg{}
@@ -648,7 +671,7 @@ func (m Model) nextTrack() tea.Cmd {
 
 func (m Model) prevTrack() tea.Cmd {
 	return func() tea.Msg {
-		_ = m.app.player.Prev(context.Background())
+		_ = m.app.current().Prev(context.Background())
 		// Small delay to let Spotify update state
 		time.Sleep(200 * time.Millisecond)
 		return refreshAfterActionMsg{}
@@ -662,7 +685,7 @@ func (m Model) volumeUp() tea.Cmd {
 			if newVol > 100 {
 				newVol = 100
 			}
-			_ = m.app.player.Volume(context.Background(), newVol)
+			_ = m.app.current().Volume(context.Background(), newVol)
 		}
 		return nil
 	}
@@ -675,18 +698,75 @@ func (m Model) volumeDown() tea.Cmd {
 			if newVol < 0 {
 				newVol = 0
 			}
-			_ = m.app.player.Volume(context.Background(), newVol)
+			_ = m.app.current().Volume(context.Background(), newVol)
 		}
 		return nil
 	}
 }
 
+// seekStep is how far the left and right arrow keys seek.
+const seekStep = 10 * time.Second
+
+func (m Model) seekBy(d time.Duration) tea.Cmd {
+	return func() tea.Msg {
+		if m.state == nil {
+			return nil
+		}
+		pos := m.state.Progress + d
+		if pos < 0 {
+			pos = 0
+		}
+		_ = m.app.current().Seek(context.Background(), int(pos.Milliseconds()))
+		return refreshAfterActionMsg{}
+	}
+}
+
+func (m Model) toggleShuffle() tea.Cmd {
+	return func() tea.Msg {
+		c, ok := m.app.current().(core.PlayModeController)
+		if !ok || m.state == nil {
+			return nil
+		}
+		_ = c.Shuffle(context.Background(), !m.state.Shuffle)
+		return refreshAfterActionMsg{}
+	}
+}
+
+func (m Model) cycleRepeat() tea.Cmd {
+	return func() tea.Msg {
+		c, ok := m.app.current().(core.PlayModeController)
+		if !ok || m.state == nil {
+			return nil
+		}
+		next := map[string]string{"off": "context", "context": "track", "track": "off"}[m.state.Repeat]
+		if next == "" {
+			next = "context"
+		}
+		_ = c.Repeat(context.Background(), next)
+		return refreshAfterActionMsg{}
+	}
+}
+
 func (m Model) transferToDevice() tea.Cmd {
 	return func() tea.Msg {
 		selected := m.devicesView.Selected()
 		if selected >= 0 && selected < len(m.devices) {
 			device := m.devices[selected]
-			_ = m.app.player.TransferPlayback(context.Background(), device.ID, true)
+			ctx := context.Background()
+			b := m.app.registry.Backend(device.Platform)
+			if b == nil {
+				return nil
+			}
+			p, err := b.PlayerFor(ctx, &device)
+			if err != nil {
+				return errMsg(err)
+			}
+			if t, ok := p.(playbackTransferer); ok {
+				_ = t.TransferPlayback(ctx, device.ID, true)
+			}
+			m.app.setPlayer(p)
+			time.Sleep(200 * time.Millisecond)
+			return refreshAfterActionMsg{}
 		}
 		return nil
 	}
@@ -822,7 +902,18 @@ func (m Model) View() string {
 }
 
 func (m Model) renderStatusBar() string {
-	status := styles.Dim.Render("q:quit  ?:help  /:search  space:play/pause  n:next  p:prev  +/-:volume  tab:switch panel")
+	hints := []string{"q:quit", "?:help", "/:search"}
+	caps := m.caps()
+	for _, k := range playbackKeys {
+		if caps.Has(k.requires) {
+			hints = append(hints, k.short+":"+k.label)
+		}
+	}
+	hints = append(hints, "tab:switch panel")
+	status := styles.Dim.Render(strings.Join(hints, "  "))
+	if m.sleep != nil {
+		status = styles.Highlight.Render("💤 Pausing "+m.sleep.Describe(m.state, time.Now())) + "  " + status
+	}
 
 	if m.lastError != nil {
 		status = styles.Paused.Render("Error: " + m.lastError.Error())
@@ -853,11 +944,7 @@ func (m Model) renderHelp() string {
 
   Playback
   ────────
-  Space        Play/Pause
-  n            Next track
-  p            Previous track
-  +/=          Volume up
-  -            Volume down
+` + m.renderPlaybackHelp() + `
 
   Queue Panel
   ───────────
@@ -882,6 +969,49 @@ func (m Model) renderHelp() string {
 		Render(styles.BorderStyle.Render(help))
 }
 
+// keyHint describes a playback key and the capability it needs.
+type keyHint struct {
+	short, label string // status bar
+	long, desc   string // help overlay
+	requires     core.Capability
+}
+
+var playbackKeys = []keyHint{
+	{"space", "play/pause", "Space", "Play/Pause", 0},
+	{"n", "next", "n", "Next track", 0},
+	{"p", "prev", "p", "Previous track", 0},
+	{"+/-", "volume", "+/=, -", "Volume up/down", 0},
+	{"←/→", "seek", "←/→", "Seek 10s", core.CapSeek},
+	{"s", "shuffle", "s", "Toggle shuffle", core.CapShuffle},
+	{"R", "repeat", "R", "Cycle repeat", core.CapRepeat},
+}
+
+// searchHints lists the search overlay keys, omitting queueing when the
+// current device cannot add to its queue.
+func (m Model) searchHints() string {
+	if m.caps().Has(core.CapQueueAdd) {
+		return "Ctrl+t:filter  ↑/↓:nav  Enter:play  Ctrl+q:queue  Esc:close"
+	}
+	return "Ctrl+t:filter  ↑/↓:nav  Enter:play  Esc:close"
+}
+
+// caps returns the capabilities of the current player.
+func (m Model) caps() core.Capability {
+	return m.app.current().Capabilities()
+}
+
+// renderPlaybackHelp lists the playback keys the current device supports.
+func (m Model) renderPlaybackHelp() string {
+	caps := m.caps()
+	var lines []string
+	for _, k := range playbackKeys {
+		if caps.Has(k.requires) {
+			lines = append(lines, fmt.Sprintf("  %-12s %s", k.long, k.desc))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
 func (m Model) renderSearch() string {
 	var b strings.Builder
 
@@ -942,7 +1072,7 @@ func (m Model) renderSearch() string {
 
 	// Help
 	b.WriteString("\n")
-	b.WriteString(subtitleStyle.Render("Ctrl+t:filter  ↑/↓:nav  Enter:play  Ctrl+q:queue  Esc:close"))
+	b.WriteString(subtitleStyle.Render(m.searchHints()))
 
 	content := lipgloss.NewStyle().
 		Width(60).
@@ -957,15 +1087,10 @@ func (m Model) renderSearch() string {
 }
 
 // Run starts the TUI application
-func Run(clientID string, refreshRate time.Duration, defaultDevice string) error {
-	app, err := NewApp(clientID, refreshRate, defaultDevice)
-	if err != nil {
-		return err
-	}
-
-	model := NewModel(app)
+func Run(registry *core.Registry, player core.Player, refreshRate time.Duration, defaultDevice string, sleepTimers *sleeptimer.Store) error {
+	model := NewModel(NewApp(registry, player, refreshRate, defaultDevice, sleepTimers))
 	p := tea.NewProgram(model, tea.WithAltScreen())
 
-	_, err = p.Run()
+	_, err := p.Run()
 	return err
 }
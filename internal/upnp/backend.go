@@ -0,0 +1,169 @@
+package upnp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+	rifferrors "github.com/tessro/riff/internal/errors"
+	"github.com/tessro/riff/internal/metrics"
+)
+
+const (
+	defaultDiscoveryTimeout = 3 * time.Second
+	rendererCacheFile       = "upnp-renderers.json"
+)
+
+// Backend implements core.Backend for standard MediaRenderers found by SSDP.
+// Sonos speakers also announce themselves as MediaRenderers; they are left
+// to the Sonos backend.
+type Backend struct {
+	soap     *SOAPClient
+	control  *Control
+	timeout  time.Duration
+	cacheDir string
+
+	mu        sync.Mutex
+	renderers []*Renderer
+}
+
+// Ensure Backend implements core.Backend
+var _ core.Backend = (*Backend)(nil)
+
+// NewBackend creates a renderer backend. A zero timeout uses a 3 second
+// discovery window.
+func NewBackend(timeout time.Duration) *Backend {
+	if timeout == 0 {
+		timeout = defaultDiscoveryTimeout
+	}
+	soap := NewSOAPClient()
+	return &Backend{
+		soap:     soap,
+		control:  NewControl(soap),
+		timeout:  timeout,
+		cacheDir: CacheDir(),
+	}
+}
+
+// Platform returns core.PlatformUPnP.
+func (b *Backend) Platform() core.Platform {
+	return core.PlatformUPnP
+}
+
+// Control returns the action client used for renderers.
+func (b *Backend) Control() *Control {
+	return b.control
+}
+
+// Refresh bypasses the discovery cache and searches the network again.
+func (b *Backend) Refresh(ctx context.Context) error {
+	_, err := b.discover(ctx)
+	return err
+}
+
+// Renderers returns the known renderers, from cache when fresh.
+func (b *Backend) Renderers(ctx context.Context) ([]*Renderer, error) {
+	b.mu.Lock()
+	renderers := b.renderers
+	b.mu.Unlock()
+	if renderers != nil {
+		metrics.DiscoveryLookups.WithLabelValues("upnp", "hit").Inc()
+		return renderers, nil
+	}
+
+	if renderers, ok := loadCache[*Renderer](b.cacheDir, rendererCacheFile, "renderers"); ok {
+		metrics.DiscoveryLookups.WithLabelValues("upnp", "hit").Inc()
+		b.mu.Lock()
+		b.renderers = renderers
+		b.mu.Unlock()
+		return renderers, nil
+	}
+	metrics.DiscoveryLookups.WithLabelValues("upnp", "miss").Inc()
+	return b.discover(ctx)
+}
+
+// CachedRenderers returns the renderers in the on-disk discovery cache,
+// however old, without searching the network.
+func CachedRenderers() []*Renderer {
+	renderers, _ := readCache[*Renderer](CacheDir(), rendererCacheFile, "renderers", 0)
+	return renderers
+}
+
+// Devices lists discovered renderers.
+func (b *Backend) Devices(ctx context.Context) ([]core.Device, error) {
+	renderers, err := b.Renderers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	devices := make([]core.Device, len(renderers))
+	for i, r := range renderers {
+		devices[i] = *rendererDevice(r, false)
+	}
+	return devices, nil
+}
+
+// PlayerFor returns a player for device. Renderers are never picked
+// implicitly, so a nil device is an error.
+func (b *Backend) PlayerFor(ctx context.Context, device *core.Device) (core.Player, error) {
+	if device == nil {
+		return nil, errors.New("renderers must be selected with --to")
+	}
+	renderers, err := b.Renderers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range renderers {
+		if r.UUID == device.ID {
+			return NewPlayer(b.control, r), nil
+		}
+	}
+	return nil, fmt.Errorf("device '%s': %w", device.Name, rifferrors.ErrDeviceNotFound)
+}
+
+// Search is not supported; renderers only play what they are sent.
+func (b *Backend) Search(ctx context.Context, query string, kinds []core.SearchKind, limit int) ([]core.SearchResult, error) {
+	return nil, fmt.Errorf("upnp search: %w", errors.ErrUnsupported)
+}
+
+// discover searches for renderers, fetches their descriptions and caches
+// the result.
+func (b *Backend) discover(ctx context.Context) ([]*Renderer, error) {
+	start := time.Now()
+	responses, err := Search(ctx, MediaRendererDevice, b.timeout)
+	metrics.DiscoveryDuration.WithLabelValues("upnp").Observe(metrics.Since(start))
+	if err != nil && len(responses) == 0 {
+		return nil, err
+	}
+
+	renderers := make([]*Renderer, 0, len(responses))
+	for _, resp := range responses {
+		if resp.Location == "" || isSonos(resp) {
+			continue
+		}
+		desc, err := FetchDescription(ctx, b.soap.HTTPClient(), resp.Location)
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(desc.Manufacturer), "sonos") {
+			continue
+		}
+		if r := NewRenderer(desc, resp.Location); r != nil {
+			renderers = append(renderers, r)
+		}
+	}
+
+	b.mu.Lock()
+	b.renderers = renderers
+	b.mu.Unlock()
+	saveCache(b.cacheDir, rendererCacheFile, "renderers", renderers)
+	return renderers, nil
+}
+
+// isSonos reports whether an SSDP reply came from a Sonos speaker.
+func isSonos(resp SearchResponse) bool {
+	return strings.Contains(resp.USN, "RINCON_") || strings.Contains(strings.ToLower(resp.Server), "sonos")
+}
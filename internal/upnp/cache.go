@@ -0,0 +1,75 @@
+package upnp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// discoveryCacheTTL is how long discovered devices are trusted before SSDP
+// is run again.
+const discoveryCacheTTL = 5 * time.Minute
+
+// CacheDir returns the directory riff caches discovery results in.
+func CacheDir() string {
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(dir, "riff")
+}
+
+// loadCache reads the list stored under key in dir/file if the file is
+// fresh. A fresh cache of nothing returns an empty, non-nil slice.
+func loadCache[T any](dir, file, key string) ([]T, bool) {
+	return readCache[T](dir, file, key, discoveryCacheTTL)
+}
+
+// readCache is loadCache with a maximum age; zero accepts any age.
+func readCache[T any](dir, file, key string, maxAge time.Duration) ([]T, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		return nil, false
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, false
+	}
+	var cachedAt time.Time
+	if err := json.Unmarshal(raw["cached_at"], &cachedAt); err != nil {
+		return nil, false
+	}
+	if maxAge > 0 && time.Since(cachedAt) > maxAge {
+		return nil, false
+	}
+
+	items := []T{}
+	if list, ok := raw[key]; ok {
+		if err := json.Unmarshal(list, &items); err != nil {
+			return nil, false
+		}
+	}
+	if items == nil {
+		items = []T{}
+	}
+	return items, true
+}
+
+// saveCache writes items under key to dir/file. Failures are ignored; the
+// cache only saves a discovery round trip.
+func saveCache[T any](dir, file, key string, items []T) {
+	data, err := json.MarshalIndent(map[string]any{
+		"cached_at": time.Now(),
+		key:         items,
+	}, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	_ = os.WriteFile(filepath.Join(dir, file), data, 0644)
+}
@@ -0,0 +1,171 @@
+package upnp
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Description is a device from a UPnP device description document. Service
+// URLs are resolved to absolute URLs.
+type Description struct {
+	DeviceType   string
+	FriendlyName string
+	Manufacturer string
+	ModelName    string
+	UDN          string
+	Services     []Service
+	Devices      []Description // Embedded devices
+}
+
+// Service is a service offered by a device.
+type Service struct {
+	ServiceType string
+	ServiceID   string
+	ControlURL  string
+	EventSubURL string
+	SCPDURL     string
+}
+
+// UUID returns the UDN without its "uuid:" prefix.
+func (d *Description) UUID() string {
+	return strings.TrimPrefix(d.UDN, "uuid:")
+}
+
+// Service returns the first service whose type starts with serviceType,
+// ignoring the version suffix, or nil.
+func (d *Description) Service(serviceType string) *Service {
+	prefix := trimVersion(serviceType)
+	for i := range d.Services {
+		if trimVersion(d.Services[i].ServiceType) == prefix {
+			return &d.Services[i]
+		}
+	}
+	return nil
+}
+
+// Find returns the first device, this one or an embedded one, whose type
+// matches deviceType ignoring the version suffix, or nil.
+func (d *Description) Find(deviceType string) *Description {
+	if trimVersion(d.DeviceType) == trimVersion(deviceType) {
+		return d
+	}
+	for i := range d.Devices {
+		if found := d.Devices[i].Find(deviceType); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// FetchDescription downloads and parses the device description at location.
+func FetchDescription(ctx context.Context, client *http.Client, location string) (*Description, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", location, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch description: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch description: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read description: %w", err)
+	}
+	return ParseDescription(data, location)
+}
+
+// xmlDevice mirrors the <device> element of a description document.
+type xmlDevice struct {
+	DeviceType   string `xml:"deviceType"`
+	FriendlyName string `xml:"friendlyName"`
+	Manufacturer string `xml:"manufacturer"`
+	ModelName    string `xml:"modelName"`
+	UDN          string `xml:"UDN"`
+	Services     []struct {
+		ServiceType string `xml:"serviceType"`
+		ServiceID   string `xml:"serviceId"`
+		ControlURL  string `xml:"controlURL"`
+		EventSubURL string `xml:"eventSubURL"`
+		SCPDURL     string `xml:"SCPDURL"`
+	} `xml:"serviceList>service"`
+	Devices []xmlDevice `xml:"deviceList>device"`
+}
+
+// ParseDescription parses a device description document. Relative URLs are
+// resolved against URLBase, or location when there is none.
+func ParseDescription(data []byte, location string) (*Description, error) {
+	var doc struct {
+		URLBase string    `xml:"URLBase"`
+		Device  xmlDevice `xml:"device"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse description: %w", err)
+	}
+
+	base, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid location %q: %w", location, err)
+	}
+	if doc.URLBase != "" {
+		if u, err := url.Parse(strings.TrimSpace(doc.URLBase)); err == nil {
+			base = u
+		}
+	}
+
+	d := convertDevice(doc.Device, base)
+	return &d, nil
+}
+
+func convertDevice(x xmlDevice, base *url.URL) Description {
+	d := Description{
+		DeviceType:   strings.TrimSpace(x.DeviceType),
+		FriendlyName: strings.TrimSpace(x.FriendlyName),
+		Manufacturer: strings.TrimSpace(x.Manufacturer),
+		ModelName:    strings.TrimSpace(x.ModelName),
+		UDN:          strings.TrimSpace(x.UDN),
+	}
+	for _, s := range x.Services {
+		d.Services = append(d.Services, Service{
+			ServiceType: strings.TrimSpace(s.ServiceType),
+			ServiceID:   strings.TrimSpace(s.ServiceID),
+			ControlURL:  resolveURL(base, s.ControlURL),
+			EventSubURL: resolveURL(base, s.EventSubURL),
+			SCPDURL:     resolveURL(base, s.SCPDURL),
+		})
+	}
+	for _, child := range x.Devices {
+		d.Devices = append(d.Devices, convertDevice(child, base))
+	}
+	return d
+}
+
+func resolveURL(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+// trimVersion drops the ":N" version from a device or service type URN.
+func trimVersion(urn string) string {
+	if i := strings.LastIndex(urn, ":"); i >= 0 {
+		return urn[:i]
+	}
+	return urn
+}
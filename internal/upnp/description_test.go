@@ -0,0 +1,68 @@
+package upnp
+
+import "testing"
+
+// A TV exposing its renderer as an embedded device, with URLs relative to
+// URLBase.
+const tvDescription = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <URLBase>http://192.168.1.50:9197/</URLBase>
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:Basic:1</deviceType>
+    <friendlyName>Living Room</friendlyName>
+    <UDN>uuid:root-device</UDN>
+    <deviceList>
+      <device>
+        <deviceType>urn:schemas-upnp-org:device:MediaRenderer:1</deviceType>
+        <friendlyName>[TV] Living Room</friendlyName>
+        <manufacturer>Example Corp</manufacturer>
+        <modelName>UE55 Series</modelName>
+        <UDN>uuid:renderer-1234</UDN>
+        <serviceList>
+          <service>
+            <serviceType>urn:schemas-upnp-org:service:AVTransport:1</serviceType>
+            <serviceId>urn:upnp-org:serviceId:AVTransport</serviceId>
+            <controlURL>dmr/upnp/control/AVTransport1</controlURL>
+          </service>
+          <service>
+            <serviceType>urn:schemas-upnp-org:service:RenderingControl:2</serviceType>
+            <serviceId>urn:upnp-org:serviceId:RenderingControl</serviceId>
+            <controlURL>/dmr/upnp/control/RenderingControl1</controlURL>
+          </service>
+        </serviceList>
+      </device>
+    </deviceList>
+  </device>
+</root>`
+
+func TestParseDescription(t *testing.T) {
+	desc, err := ParseDescription([]byte(tvDescription), "http://192.168.1.50:9197/dmr")
+	if err != nil {
+		t.Fatalf("ParseDescription() error = %v", err)
+	}
+
+	r := NewRenderer(desc, "http://192.168.1.50:9197/dmr")
+	if r == nil {
+		t.Fatal("NewRenderer() = nil, want the embedded MediaRenderer")
+	}
+	if r.UUID != "renderer-1234" || r.Name != "[TV] Living Room" || r.Manufacturer != "Example Corp" {
+		t.Errorf("NewRenderer() = %+v", r)
+	}
+	if got, want := r.AVTransportURL, "http://192.168.1.50:9197/dmr/upnp/control/AVTransport1"; got != want {
+		t.Errorf("AVTransportURL = %q, want %q", got, want)
+	}
+	// RenderingControl:2 still matches the version-less service lookup
+	if got, want := r.RenderingControlURL, "http://192.168.1.50:9197/dmr/upnp/control/RenderingControl1"; got != want {
+		t.Errorf("RenderingControlURL = %q, want %q", got, want)
+	}
+}
+
+func TestNewRendererWithoutAVTransport(t *testing.T) {
+	desc := &Description{
+		DeviceType: "urn:schemas-upnp-org:device:MediaRenderer:1",
+		UDN:        "uuid:no-transport",
+	}
+	if r := NewRenderer(desc, "http://example.invalid/"); r != nil {
+		t.Errorf("NewRenderer() = %+v, want nil", r)
+	}
+}
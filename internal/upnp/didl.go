@@ -0,0 +1,187 @@
+package upnp
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+// Object is an item or container from a DIDL-Lite document.
+type Object struct {
+	ID          string
+	ParentID    string
+	Container   bool
+	Title       string
+	Creator     string
+	Artist      string
+	Album       string
+	Class       string // e.g. object.item.audioItem.musicTrack
+	AlbumArtURI string
+	Resources   []Resource
+}
+
+// Resource is a <res> element: a URL the object can be fetched from.
+type Resource struct {
+	URL          string
+	ProtocolInfo string // e.g. http-get:*:audio/flac:*
+	Duration     time.Duration
+}
+
+// URL returns the first resource URL, or "" if there is none.
+func (o *Object) URL() string {
+	if len(o.Resources) == 0 {
+		return ""
+	}
+	return o.Resources[0].URL
+}
+
+// Duration returns the first resource's duration.
+func (o *Object) Duration() time.Duration {
+	if len(o.Resources) == 0 {
+		return 0
+	}
+	return o.Resources[0].Duration
+}
+
+// ArtistName returns upnp:artist, falling back to dc:creator.
+func (o *Object) ArtistName() string {
+	if o.Artist != "" {
+		return o.Artist
+	}
+	return o.Creator
+}
+
+// xmlObject mirrors an <item> or <container> element.
+type xmlObject struct {
+	XMLName     xml.Name
+	ID          string `xml:"id,attr"`
+	ParentID    string `xml:"parentID,attr"`
+	Title       string `xml:"http://purl.org/dc/elements/1.1/ title"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Artist      string `xml:"urn:schemas-upnp-org:metadata-1-0/upnp/ artist"`
+	Album       string `xml:"urn:schemas-upnp-org:metadata-1-0/upnp/ album"`
+	Class       string `xml:"urn:schemas-upnp-org:metadata-1-0/upnp/ class"`
+	AlbumArtURI string `xml:"urn:schemas-upnp-org:metadata-1-0/upnp/ albumArtURI"`
+	Res         []struct {
+		URL          string `xml:",chardata"`
+		ProtocolInfo string `xml:"protocolInfo,attr"`
+		Duration     string `xml:"duration,attr"`
+	} `xml:"res"`
+}
+
+// ParseDIDL parses a DIDL-Lite document, such as a Browse result or
+// transport metadata. Entity-escaped input is accepted.
+func ParseDIDL(s string) ([]Object, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "NOT_IMPLEMENTED" {
+		return nil, nil
+	}
+	if strings.HasPrefix(s, "&lt;") {
+		s = html.UnescapeString(s)
+	}
+
+	var doc struct {
+		Objects []xmlObject `xml:",any"`
+	}
+	if err := xml.Unmarshal([]byte(s), &doc); err != nil {
+		return nil, fmt.Errorf("parse didl: %w", err)
+	}
+
+	var objects []Object
+	for _, x := range doc.Objects {
+		if x.XMLName.Local != "item" && x.XMLName.Local != "container" {
+			continue
+		}
+		o := Object{
+			ID:          x.ID,
+			ParentID:    x.ParentID,
+			Container:   x.XMLName.Local == "container",
+			Title:       strings.TrimSpace(x.Title),
+			Creator:     strings.TrimSpace(x.Creator),
+			Artist:      strings.TrimSpace(x.Artist),
+			Album:       strings.TrimSpace(x.Album),
+			Class:       strings.TrimSpace(x.Class),
+			AlbumArtURI: strings.TrimSpace(x.AlbumArtURI),
+		}
+		for _, r := range x.Res {
+			o.Resources = append(o.Resources, Resource{
+				URL:          strings.TrimSpace(r.URL),
+				ProtocolInfo: r.ProtocolInfo,
+				Duration:     ParseDuration(r.Duration),
+			})
+		}
+		objects = append(objects, o)
+	}
+	return objects, nil
+}
+
+// BuildDIDL renders a single-item DIDL-Lite document describing o, suitable
+// for SetAVTransportURI and AddURIToQueue metadata.
+func BuildDIDL(o Object) string {
+	class := o.Class
+	if class == "" {
+		class = "object.item.audioItem.musicTrack"
+	}
+	id := o.ID
+	if id == "" {
+		id = "0"
+	}
+	parent := o.ParentID
+	if parent == "" {
+		parent = "-1"
+	}
+
+	var b strings.Builder
+	b.WriteString(`<DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/"`)
+	b.WriteString(` xmlns:dc="http://purl.org/dc/elements/1.1/"`)
+	b.WriteString(` xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/">`)
+	fmt.Fprintf(&b, `<item id="%s" parentID="%s" restricted="1">`, EscapeXML(id), EscapeXML(parent))
+	writeElement(&b, "dc:title", o.Title)
+	writeElement(&b, "dc:creator", o.Creator)
+	writeElement(&b, "upnp:artist", o.Artist)
+	writeElement(&b, "upnp:album", o.Album)
+	writeElement(&b, "upnp:albumArtURI", o.AlbumArtURI)
+	writeElement(&b, "upnp:class", class)
+	for _, r := range o.Resources {
+		protocolInfo := r.ProtocolInfo
+		if protocolInfo == "" {
+			protocolInfo = "http-get:*:*:*"
+		}
+		fmt.Fprintf(&b, `<res protocolInfo="%s"`, EscapeXML(protocolInfo))
+		if r.Duration > 0 {
+			fmt.Fprintf(&b, ` duration="%s"`, FormatDuration(r.Duration))
+		}
+		fmt.Fprintf(&b, `>%s</res>`, EscapeXML(r.URL))
+	}
+	b.WriteString(`</item></DIDL-Lite>`)
+	return b.String()
+}
+
+func writeElement(b *strings.Builder, name, value string) {
+	if value == "" {
+		return
+	}
+	fmt.Fprintf(b, "<%s>%s</%s>", name, EscapeXML(value), name)
+}
+
+// ParseDuration parses a UPnP duration such as "0:03:25" or "0:03:25.500".
+// Unknown values like "NOT_IMPLEMENTED" parse as zero.
+func ParseDuration(s string) time.Duration {
+	var h, m int
+	var sec float64
+	if _, err := fmt.Sscanf(s, "%d:%d:%f", &h, &m, &sec); err != nil {
+		return 0
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute +
+		time.Duration(sec*float64(time.Second))
+}
+
+// FormatDuration formats d as H:MM:SS, the form Seek and DIDL expect.
+func FormatDuration(d time.Duration) string {
+	h := int(d.Hours())
+	m := int(d.Minutes()) % 60
+	s := int(d.Seconds()) % 60
+	return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+}
@@ -0,0 +1,90 @@
+package upnp
+
+import (
+	"testing"
+	"time"
+)
+
+const browseResult = `&lt;DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/"&gt;` +
+	`&lt;container id="1$4" parentID="1" childCount="3"&gt;&lt;dc:title&gt;Night Drive&lt;/dc:title&gt;&lt;upnp:class&gt;object.container.album.musicAlbum&lt;/upnp:class&gt;&lt;/container&gt;` +
+	`&lt;item id="1$4$1" parentID="1$4"&gt;&lt;dc:title&gt;Neon &amp;amp; Rain&lt;/dc:title&gt;&lt;dc:creator&gt;The Testers&lt;/dc:creator&gt;&lt;upnp:album&gt;Night Drive&lt;/upnp:album&gt;&lt;upnp:class&gt;object.item.audioItem.musicTrack&lt;/upnp:class&gt;` +
+	`&lt;res protocolInfo="http-get:*:audio/flac:*" duration="0:03:21.500"&gt;http://nas:8200/MediaItems/1.flac&lt;/res&gt;&lt;/item&gt;` +
+	`&lt;/DIDL-Lite&gt;`
+
+func TestParseDIDL(t *testing.T) {
+	objects, err := ParseDIDL(browseResult)
+	if err != nil {
+		t.Fatalf("ParseDIDL() error = %v", err)
+	}
+	if len(objects) != 2 {
+		t.Fatalf("ParseDIDL() returned %d objects, want 2", len(objects))
+	}
+
+	album := objects[0]
+	if !album.Container || album.ID != "1$4" || album.Title != "Night Drive" {
+		t.Errorf("objects[0] = %+v, want the Night Drive container", album)
+	}
+
+	track := objects[1]
+	if track.Container || track.Title != "Neon & Rain" || track.ArtistName() != "The Testers" || track.Album != "Night Drive" {
+		t.Errorf("objects[1] = %+v, want Neon & Rain by The Testers", track)
+	}
+	if got, want := track.URL(), "http://nas:8200/MediaItems/1.flac"; got != want {
+		t.Errorf("URL() = %q, want %q", got, want)
+	}
+	if got, want := track.Duration(), 201500*time.Millisecond; got != want {
+		t.Errorf("Duration() = %v, want %v", got, want)
+	}
+}
+
+func TestBuildDIDLRoundTrip(t *testing.T) {
+	in := Object{
+		ID:     "1$4$1",
+		Title:  "Neon & Rain",
+		Artist: "The Testers",
+		Album:  "Night Drive",
+		Resources: []Resource{{
+			URL:          "http://nas:8200/MediaItems/1.flac?a=1&b=2",
+			ProtocolInfo: "http-get:*:audio/flac:*",
+			Duration:     201 * time.Second,
+		}},
+	}
+
+	objects, err := ParseDIDL(BuildDIDL(in))
+	if err != nil {
+		t.Fatalf("ParseDIDL(BuildDIDL()) error = %v", err)
+	}
+	if len(objects) != 1 {
+		t.Fatalf("got %d objects, want 1", len(objects))
+	}
+	out := objects[0]
+	if out.ID != in.ID || out.Title != in.Title || out.Artist != in.Artist || out.Album != in.Album {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+	if out.Class != "object.item.audioItem.musicTrack" {
+		t.Errorf("Class = %q, want the musicTrack default", out.Class)
+	}
+	if out.URL() != in.URL() || out.Duration() != in.Duration() {
+		t.Errorf("resource = %+v, want %+v", out.Resources, in.Resources)
+	}
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"0:03:25", 205 * time.Second},
+		{"1:00:00.250", time.Hour + 250*time.Millisecond},
+		{"NOT_IMPLEMENTED", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := ParseDuration(tt.in); got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+	if got, want := FormatDuration(time.Hour+2*time.Minute+3*time.Second), "1:02:03"; got != want {
+		t.Errorf("FormatDuration() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,142 @@
+package upnp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	rifferrors "github.com/tessro/riff/internal/errors"
+)
+
+const mediaServerCacheFile = "upnp-servers.json"
+
+// Library finds MediaServers by SSDP and browses them. Sonos speakers also
+// announce a MediaServer for their indexed music share; it is skipped, as
+// with renderers.
+type Library struct {
+	soap     *SOAPClient
+	cd       *ContentDirectory
+	timeout  time.Duration
+	cacheDir string
+
+	mu      sync.Mutex
+	servers []*MediaServer
+}
+
+// NewLibrary creates a Library. A zero timeout uses a 3 second discovery
+// window.
+func NewLibrary(timeout time.Duration) *Library {
+	if timeout == 0 {
+		timeout = defaultDiscoveryTimeout
+	}
+	soap := NewSOAPClient()
+	return &Library{
+		soap:     soap,
+		cd:       NewContentDirectory(soap),
+		timeout:  timeout,
+		cacheDir: CacheDir(),
+	}
+}
+
+// ContentDirectory returns the action client used for servers.
+func (l *Library) ContentDirectory() *ContentDirectory {
+	return l.cd
+}
+
+// Refresh bypasses the discovery cache and searches the network again.
+func (l *Library) Refresh(ctx context.Context) error {
+	_, err := l.discover(ctx)
+	return err
+}
+
+// Servers returns the known media servers, from cache when fresh.
+func (l *Library) Servers(ctx context.Context) ([]*MediaServer, error) {
+	l.mu.Lock()
+	servers := l.servers
+	l.mu.Unlock()
+	if servers != nil {
+		return servers, nil
+	}
+
+	if servers, ok := loadCache[*MediaServer](l.cacheDir, mediaServerCacheFile, "servers"); ok {
+		l.mu.Lock()
+		l.servers = servers
+		l.mu.Unlock()
+		return servers, nil
+	}
+	return l.discover(ctx)
+}
+
+// Server finds a media server by UUID, then exact name, then name
+// substring. An empty target selects the only server on the network.
+func (l *Library) Server(ctx context.Context, target string) (*MediaServer, error) {
+	servers, err := l.Servers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if target == "" {
+		switch len(servers) {
+		case 0:
+			return nil, fmt.Errorf("no media servers found")
+		case 1:
+			return servers[0], nil
+		}
+		names := make([]string, len(servers))
+		for i, s := range servers {
+			names[i] = s.Name
+		}
+		return nil, rifferrors.WithSuggestion(
+			fmt.Errorf("found %d media servers", len(servers)),
+			"Choose one with --server: "+strings.Join(names, ", "))
+	}
+
+	targetLower := strings.ToLower(target)
+	matchers := []func(s *MediaServer) bool{
+		func(s *MediaServer) bool { return s.UUID == target },
+		func(s *MediaServer) bool { return strings.ToLower(s.Name) == targetLower },
+		func(s *MediaServer) bool { return strings.Contains(strings.ToLower(s.Name), targetLower) },
+	}
+	for _, match := range matchers {
+		for _, s := range servers {
+			if match(s) {
+				return s, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("media server '%s': %w", target, rifferrors.ErrDeviceNotFound)
+}
+
+// discover searches for media servers, fetches their descriptions and
+// caches the result.
+func (l *Library) discover(ctx context.Context) ([]*MediaServer, error) {
+	responses, err := Search(ctx, MediaServerDevice, l.timeout)
+	if err != nil && len(responses) == 0 {
+		return nil, err
+	}
+
+	servers := make([]*MediaServer, 0, len(responses))
+	for _, resp := range responses {
+		if resp.Location == "" || isSonos(resp) {
+			continue
+		}
+		desc, err := FetchDescription(ctx, l.soap.HTTPClient(), resp.Location)
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(desc.Manufacturer), "sonos") {
+			continue
+		}
+		if s := NewMediaServer(desc, resp.Location); s != nil {
+			servers = append(servers, s)
+		}
+	}
+
+	l.mu.Lock()
+	l.servers = servers
+	l.mu.Unlock()
+	saveCache(l.cacheDir, mediaServerCacheFile, "servers", servers)
+	return servers, nil
+}
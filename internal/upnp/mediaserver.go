@@ -0,0 +1,202 @@
+package upnp
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MediaServer is a MediaServer device and the control URL of its
+// ContentDirectory service.
+type MediaServer struct {
+	UUID                string    `json:"uuid"`
+	Name                string    `json:"name"`
+	Manufacturer        string    `json:"manufacturer"`
+	Model               string    `json:"model"`
+	Location            string    `json:"location"`
+	ContentDirectoryURL string    `json:"content_directory_url"`
+	LastSeen            time.Time `json:"last_seen"`
+}
+
+// NewMediaServer builds a MediaServer from a device description. It returns
+// nil if the description has no MediaServer with a ContentDirectory service.
+func NewMediaServer(desc *Description, location string) *MediaServer {
+	d := desc.Find(MediaServerDevice)
+	if d == nil {
+		return nil
+	}
+	cd := d.Service(ContentDirectoryService)
+	if cd == nil || cd.ControlURL == "" {
+		return nil
+	}
+
+	s := &MediaServer{
+		UUID:                d.UUID(),
+		Name:                d.FriendlyName,
+		Manufacturer:        d.Manufacturer,
+		Model:               d.ModelName,
+		Location:            location,
+		ContentDirectoryURL: cd.ControlURL,
+		LastSeen:            time.Now(),
+	}
+	if s.Name == "" {
+		s.Name = desc.FriendlyName
+	}
+	return s
+}
+
+// RootID is the object ID of a ContentDirectory's top-level container.
+const RootID = "0"
+
+// BrowseResult is a page of objects from Browse or Search.
+type BrowseResult struct {
+	Objects        []Object
+	NumberReturned int
+	TotalMatches   int
+}
+
+// ContentDirectory sends ContentDirectory actions to media servers.
+type ContentDirectory struct {
+	soap *SOAPClient
+}
+
+// NewContentDirectory creates a ContentDirectory that calls servers through
+// soap.
+func NewContentDirectory(soap *SOAPClient) *ContentDirectory {
+	return &ContentDirectory{soap: soap}
+}
+
+// Browse lists the children of the container objectID, starting at the
+// 0-based index start. A count of 0 asks the server for everything.
+func (c *ContentDirectory) Browse(ctx context.Context, s *MediaServer, objectID string, start, count int) (*BrowseResult, error) {
+	return c.browse(ctx, s, objectID, "BrowseDirectChildren", start, count)
+}
+
+// Metadata returns the object objectID itself.
+func (c *ContentDirectory) Metadata(ctx context.Context, s *MediaServer, objectID string) (*Object, error) {
+	result, err := c.browse(ctx, s, objectID, "BrowseMetadata", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Objects) == 0 {
+		return nil, fmt.Errorf("object %q not found on %s", objectID, s.Name)
+	}
+	return &result.Objects[0], nil
+}
+
+func (c *ContentDirectory) browse(ctx context.Context, s *MediaServer, objectID, flag string, start, count int) (*BrowseResult, error) {
+	resp, err := c.soap.Call(ctx, s.ContentDirectoryURL, ContentDirectoryService, "Browse",
+		Arg{"ObjectID", objectID},
+		Arg{"BrowseFlag", flag},
+		Arg{"Filter", "*"},
+		Arg{"StartingIndex", strconv.Itoa(start)},
+		Arg{"RequestedCount", strconv.Itoa(count)},
+		Arg{"SortCriteria", ""})
+	if err != nil {
+		return nil, err
+	}
+	return parseBrowseResponse(resp, "BrowseResponse")
+}
+
+// Search finds objects under containerID matching a UPnP search criteria
+// string, such as one built by SearchCriteria.
+func (c *ContentDirectory) Search(ctx context.Context, s *MediaServer, containerID, criteria string, start, count int) (*BrowseResult, error) {
+	resp, err := c.soap.Call(ctx, s.ContentDirectoryURL, ContentDirectoryService, "Search",
+		Arg{"ContainerID", containerID},
+		Arg{"SearchCriteria", criteria},
+		Arg{"Filter", "*"},
+		Arg{"StartingIndex", strconv.Itoa(start)},
+		Arg{"RequestedCount", strconv.Itoa(count)},
+		Arg{"SortCriteria", ""})
+	if err != nil {
+		return nil, err
+	}
+	return parseBrowseResponse(resp, "SearchResponse")
+}
+
+// SearchCapabilities returns the properties the server can search on. An
+// empty list means the server does not support Search.
+func (c *ContentDirectory) SearchCapabilities(ctx context.Context, s *MediaServer) ([]string, error) {
+	resp, err := c.soap.Call(ctx, s.ContentDirectoryURL, ContentDirectoryService, "GetSearchCapabilities")
+	if err != nil {
+		return nil, err
+	}
+
+	var envelope struct {
+		Body struct {
+			Response struct {
+				SearchCaps string `xml:"SearchCaps"`
+			} `xml:"GetSearchCapabilitiesResponse"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(resp, &envelope); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+
+	var caps []string
+	for _, c := range strings.Split(envelope.Body.Response.SearchCaps, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			caps = append(caps, c)
+		}
+	}
+	return caps, nil
+}
+
+// searchProperties are the properties a free-text query is matched against,
+// when the server can search them.
+var searchProperties = []string{"dc:title", "upnp:artist", "dc:creator", "upnp:album"}
+
+// SearchCriteria builds a criteria string matching query against the
+// title, artist and album properties in caps. "*" in caps means every
+// property is searchable. It returns "" if none of them are.
+func SearchCriteria(query string, caps []string) string {
+	quoted := `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(query) + `"`
+
+	var terms []string
+	for _, prop := range searchProperties {
+		if slices.Contains(caps, "*") || slices.Contains(caps, prop) {
+			terms = append(terms, prop+" contains "+quoted)
+		}
+	}
+	if len(terms) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(terms, " or ") + ")"
+}
+
+// parseBrowseResponse decodes a Browse or Search response envelope.
+func parseBrowseResponse(resp []byte, element string) (*BrowseResult, error) {
+	var envelope struct {
+		Body struct {
+			Responses []struct {
+				XMLName        xml.Name
+				Result         string `xml:"Result"`
+				NumberReturned int    `xml:"NumberReturned"`
+				TotalMatches   int    `xml:"TotalMatches"`
+			} `xml:",any"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(resp, &envelope); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+
+	for _, r := range envelope.Body.Responses {
+		if r.XMLName.Local != element {
+			continue
+		}
+		objects, err := ParseDIDL(r.Result)
+		if err != nil {
+			return nil, err
+		}
+		return &BrowseResult{
+			Objects:        objects,
+			NumberReturned: r.NumberReturned,
+			TotalMatches:   r.TotalMatches,
+		}, nil
+	}
+	return nil, fmt.Errorf("parse response: no %s element", element)
+}
@@ -0,0 +1,125 @@
+package upnp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	rifferrors "github.com/tessro/riff/internal/errors"
+	"github.com/tessro/riff/internal/upnp/upnptest"
+)
+
+func newTestMediaServer(t *testing.T) (*MediaServer, *upnptest.MediaServer) {
+	t.Helper()
+	srv := upnptest.NewMediaServer("NAS")
+	t.Cleanup(srv.Close)
+
+	desc, err := FetchDescription(context.Background(), NewSOAPClient().HTTPClient(), srv.Location())
+	if err != nil {
+		t.Fatalf("FetchDescription() error = %v", err)
+	}
+	s := NewMediaServer(desc, srv.Location())
+	if s == nil {
+		t.Fatal("NewMediaServer() = nil")
+	}
+	return s, srv
+}
+
+func TestContentDirectoryBrowse(t *testing.T) {
+	s, srv := newTestMediaServer(t)
+	cd := NewContentDirectory(NewSOAPClient())
+	ctx := context.Background()
+
+	root, err := cd.Browse(ctx, s, RootID, 0, 0)
+	if err != nil {
+		t.Fatalf("Browse(root) error = %v", err)
+	}
+	if len(root.Objects) != 1 || !root.Objects[0].Container || root.Objects[0].Title != "Music" {
+		t.Fatalf("Browse(root) = %+v, want the Music folder", root.Objects)
+	}
+
+	page, err := cd.Browse(ctx, s, upnptest.AlbumID, 1, 1)
+	if err != nil {
+		t.Fatalf("Browse(album) error = %v", err)
+	}
+	if page.TotalMatches != 3 || page.NumberReturned != 1 || page.Objects[0].Title != "Overpass" {
+		t.Errorf("Browse(album, 1, 1) = %+v, want Overpass of 3", page)
+	}
+	if got, want := page.Objects[0].URL(), srv.URL(upnptest.Tracks[1]); got != want {
+		t.Errorf("URL() = %q, want %q", got, want)
+	}
+
+	o, err := cd.Metadata(ctx, s, upnptest.Tracks[2].ID)
+	if err != nil {
+		t.Fatalf("Metadata() error = %v", err)
+	}
+	if o.Title != "Last Exit" || o.ArtistName() != "The Testers" {
+		t.Errorf("Metadata() = %+v, want Last Exit", o)
+	}
+
+	var soapErr *SOAPError
+	if _, err := cd.Metadata(ctx, s, "missing"); !errors.As(err, &soapErr) || soapErr.Code != 701 {
+		t.Errorf("Metadata(missing) error = %v, want UPnP error 701", err)
+	}
+}
+
+func TestContentDirectorySearch(t *testing.T) {
+	s, _ := newTestMediaServer(t)
+	cd := NewContentDirectory(NewSOAPClient())
+	ctx := context.Background()
+
+	caps, err := cd.SearchCapabilities(ctx, s)
+	if err != nil {
+		t.Fatalf("SearchCapabilities() error = %v", err)
+	}
+	criteria := SearchCriteria("overpass", caps)
+	result, err := cd.Search(ctx, s, RootID, criteria, 0, 10)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(result.Objects) != 1 || result.Objects[0].Title != "Overpass" {
+		t.Errorf("Search(%s) = %+v, want Overpass", criteria, result.Objects)
+	}
+}
+
+func TestSearchCriteria(t *testing.T) {
+	tests := []struct {
+		caps []string
+		want string
+	}{
+		{[]string{"dc:title"}, `(dc:title contains "say \"hi\"")`},
+		{[]string{"*"}, `(dc:title contains "say \"hi\"" or upnp:artist contains "say \"hi\"" or dc:creator contains "say \"hi\"" or upnp:album contains "say \"hi\"")`},
+		{[]string{"upnp:genre"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := SearchCriteria(`say "hi"`, tt.caps); got != tt.want {
+			t.Errorf("SearchCriteria(%v) = %s, want %s", tt.caps, got, tt.want)
+		}
+	}
+}
+
+func TestLibraryServer(t *testing.T) {
+	s, _ := newTestMediaServer(t)
+	other := *s
+	other.UUID, other.Name = "other", "Office PC"
+
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	saveCache(CacheDir(), mediaServerCacheFile, "servers", []*MediaServer{s, &other})
+
+	l := NewLibrary(0)
+	ctx := context.Background()
+
+	if got, err := l.Server(ctx, "nas"); err != nil || got.UUID != s.UUID {
+		t.Errorf("Server(nas) = %v, %v, want the NAS", got, err)
+	}
+	if got, err := l.Server(ctx, "office"); err != nil || got.UUID != "other" {
+		t.Errorf("Server(office) = %v, %v, want Office PC", got, err)
+	}
+	if _, err := l.Server(ctx, "kitchen"); !errors.Is(err, rifferrors.ErrDeviceNotFound) {
+		t.Errorf("Server(kitchen) error = %v, want ErrDeviceNotFound", err)
+	}
+	if _, err := l.Server(ctx, ""); err == nil || rifferrors.GetSuggestion(err) == "" {
+		t.Errorf("Server(\"\") error = %v, want a suggestion to pick one", err)
+	}
+}
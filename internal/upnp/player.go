@@ -0,0 +1,201 @@
+package upnp
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+)
+
+// Player implements core.Player for a standard MediaRenderer. Renderers
+// play one URI at a time, so there is no queue or history.
+type Player struct {
+	control  *Control
+	renderer *Renderer
+}
+
+// Ensure Player implements core.Player
+var _ core.Player = (*Player)(nil)
+
+// Ensure Player implements core.StreamPlayer
+var _ core.StreamPlayer = (*Player)(nil)
+
+// Ensure Player implements core.MediaPlayer
+var _ core.MediaPlayer = (*Player)(nil)
+
+// capabilities are the optional operations standard renderers support.
+const capabilities = core.CapSeek | core.CapStream
+
+// NewPlayer creates a player for renderer.
+func NewPlayer(control *Control, renderer *Renderer) *Player {
+	return &Player{
+		control:  control,
+		renderer: renderer,
+	}
+}
+
+// Renderer returns the renderer the player controls.
+func (p *Player) Renderer() *Renderer {
+	return p.renderer
+}
+
+// Play resumes playback.
+func (p *Player) Play(ctx context.Context) error {
+	return p.control.Play(ctx, p.renderer)
+}
+
+// Pause pauses playback.
+func (p *Player) Pause(ctx context.Context) error {
+	return p.control.Pause(ctx, p.renderer)
+}
+
+// Next skips to the next track. Most renderers only support this while
+// playing a playlist URI.
+func (p *Player) Next(ctx context.Context) error {
+	return p.control.Next(ctx, p.renderer)
+}
+
+// Prev skips to the previous track.
+func (p *Player) Prev(ctx context.Context) error {
+	return p.control.Previous(ctx, p.renderer)
+}
+
+// Seek seeks to the specified position in milliseconds.
+func (p *Player) Seek(ctx context.Context, positionMs int) error {
+	return p.control.Seek(ctx, p.renderer, time.Duration(positionMs)*time.Millisecond)
+}
+
+// Volume sets the volume level (0-100).
+func (p *Player) Volume(ctx context.Context, percent int) error {
+	return p.control.SetVolume(ctx, p.renderer, percent)
+}
+
+// GetState returns the current playback state.
+func (p *Player) GetState(ctx context.Context) (*core.PlaybackState, error) {
+	transport, err := p.control.GetTransportInfo(ctx, p.renderer)
+	if err != nil {
+		return nil, fmt.Errorf("get transport info: %w", err)
+	}
+	position, err := p.control.GetPositionInfo(ctx, p.renderer)
+	if err != nil {
+		return nil, fmt.Errorf("get position info: %w", err)
+	}
+
+	state := &core.PlaybackState{
+		Track:     positionTrack(position),
+		Device:    p.coreDevice(),
+		IsPlaying: transport.CurrentTransportState == "PLAYING",
+		Progress:  ParseDuration(position.RelTime),
+		Repeat:    "off",
+	}
+	// Volume is optional; renderers without RenderingControl report 0
+	if vol, err := p.control.GetVolume(ctx, p.renderer); err == nil {
+		state.Volume = vol
+	}
+	return state, nil
+}
+
+// GetQueue is unsupported; renderers have no queue.
+func (p *Player) GetQueue(ctx context.Context) (*core.Queue, error) {
+	return nil, core.Require(p, core.CapQueueRead)
+}
+
+// GetRecentlyPlayed is unsupported; renderers keep no history.
+func (p *Player) GetRecentlyPlayed(ctx context.Context, limit int) ([]core.HistoryEntry, error) {
+	return nil, core.Require(p, core.CapHistory)
+}
+
+// AddToQueue is unsupported; renderers have no queue.
+func (p *Player) AddToQueue(ctx context.Context, trackURI string) error {
+	return core.Require(p, core.CapQueueAdd)
+}
+
+// PlayURI plays an HTTP URL, such as a stream, describing it with minimal
+// metadata since many renderers refuse a URI without any.
+func (p *Player) PlayURI(ctx context.Context, uri string) error {
+	metadata := BuildDIDL(Object{
+		Title:     titleFromURL(uri),
+		Class:     "object.item.audioItem.musicTrack",
+		Resources: []Resource{{URL: uri}},
+	})
+	return p.control.PlayURI(ctx, p.renderer, uri, metadata)
+}
+
+// PlayMedia plays uri, described by DIDL-Lite metadata.
+func (p *Player) PlayMedia(ctx context.Context, uri, metadata string) error {
+	return p.control.PlayURI(ctx, p.renderer, uri, metadata)
+}
+
+// Capabilities returns the operations renderers support.
+func (p *Player) Capabilities() core.Capability {
+	return capabilities
+}
+
+// Describe names the player for error messages.
+func (p *Player) Describe() string {
+	return fmt.Sprintf("UPnP renderer (%s)", p.renderer.Name)
+}
+
+func (p *Player) coreDevice() *core.Device {
+	return rendererDevice(p.renderer, true)
+}
+
+// rendererDevice converts a renderer to a core.Device.
+func rendererDevice(r *Renderer, active bool) *core.Device {
+	return &core.Device{
+		ID:       r.UUID,
+		Name:     r.Name,
+		Type:     rendererType(r),
+		Platform: core.PlatformUPnP,
+		IsActive: active,
+	}
+}
+
+// rendererType guesses the device type from its model name.
+func rendererType(r *Renderer) core.DeviceType {
+	model := strings.ToLower(r.Model + " " + r.Name)
+	if strings.Contains(model, "tv") {
+		return core.DeviceTypeTV
+	}
+	return core.DeviceTypeSpeaker
+}
+
+// positionTrack builds the current track from position info, or returns
+// nil when nothing is loaded.
+func positionTrack(pos *PositionInfo) *core.Track {
+	if pos.TrackURI == "" && pos.TrackMetaData == "" {
+		return nil
+	}
+
+	t := &core.Track{
+		ID:       pos.TrackURI,
+		URI:      pos.TrackURI,
+		Title:    titleFromURL(pos.TrackURI),
+		Duration: ParseDuration(pos.TrackDuration),
+		Source:   core.SourceUPnP,
+	}
+	if objects, err := ParseDIDL(pos.TrackMetaData); err == nil && len(objects) > 0 {
+		o := objects[0]
+		if o.Title != "" {
+			t.Title = o.Title
+		}
+		t.Artist = o.ArtistName()
+		if t.Artist != "" {
+			t.Artists = []string{t.Artist}
+		}
+		t.Album = o.Album
+	}
+	return t
+}
+
+// titleFromURL derives a display title from the last path element.
+func titleFromURL(uri string) string {
+	base := path.Base(strings.SplitN(uri, "?", 2)[0])
+	if base == "." || base == "/" {
+		return uri
+	}
+	return base
+}
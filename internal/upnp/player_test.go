@@ -0,0 +1,127 @@
+package upnp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+	rifferrors "github.com/tessro/riff/internal/errors"
+	"github.com/tessro/riff/internal/upnp/upnptest"
+)
+
+func newTestRenderer(t *testing.T) (*Renderer, *upnptest.Renderer) {
+	t.Helper()
+	srv := upnptest.NewRenderer("Living Room TV")
+	t.Cleanup(srv.Close)
+
+	soap := NewSOAPClient()
+	desc, err := FetchDescription(context.Background(), soap.HTTPClient(), srv.Location())
+	if err != nil {
+		t.Fatalf("FetchDescription() error = %v", err)
+	}
+	r := NewRenderer(desc, srv.Location())
+	if r == nil {
+		t.Fatal("NewRenderer() = nil")
+	}
+	return r, srv
+}
+
+func TestPlayerStream(t *testing.T) {
+	r, srv := newTestRenderer(t)
+	p := NewPlayer(NewControl(NewSOAPClient()), r)
+	ctx := context.Background()
+
+	const stream = "http://radio.example/live/jazz.mp3?token=a&b"
+	if err := p.PlayURI(ctx, stream); err != nil {
+		t.Fatalf("PlayURI() error = %v", err)
+	}
+	if err := p.Seek(ctx, 65000); err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+	if err := p.Volume(ctx, 35); err != nil {
+		t.Fatalf("Volume() error = %v", err)
+	}
+
+	st := srv.State()
+	if st.URI != stream || st.Transport != "PLAYING" || st.Position != "0:01:05" || st.Volume != 35 {
+		t.Errorf("renderer state = %+v", st)
+	}
+	if !strings.Contains(st.Metadata, "<dc:title>jazz.mp3</dc:title>") {
+		t.Errorf("metadata = %q, want a title derived from the URL", st.Metadata)
+	}
+
+	state, err := p.GetState(ctx)
+	if err != nil {
+		t.Fatalf("GetState() error = %v", err)
+	}
+	if !state.IsPlaying || state.Progress != 65*time.Second || state.Volume != 35 {
+		t.Errorf("GetState() = %+v", state)
+	}
+	if state.Track == nil || state.Track.URI != stream || state.Track.Title != "jazz.mp3" || state.Track.Source != core.SourceUPnP {
+		t.Errorf("Track = %+v", state.Track)
+	}
+	if state.Device == nil || state.Device.Type != core.DeviceTypeTV || state.Device.Platform != core.PlatformUPnP {
+		t.Errorf("Device = %+v, want a UPnP TV", state.Device)
+	}
+
+	if err := p.Pause(ctx); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if got := srv.State().Transport; got != "PAUSED_PLAYBACK" {
+		t.Errorf("Transport = %q after Pause(), want PAUSED_PLAYBACK", got)
+	}
+
+	var soapErr *SOAPError
+	if err := p.Next(ctx); !errors.As(err, &soapErr) || soapErr.Code != 711 {
+		t.Errorf("Next() error = %v, want UPnP error 711", err)
+	}
+}
+
+func TestPlayerUnsupported(t *testing.T) {
+	r, _ := newTestRenderer(t)
+	p := NewPlayer(NewControl(NewSOAPClient()), r)
+	ctx := context.Background()
+
+	if _, err := p.GetQueue(ctx); !errors.Is(err, rifferrors.ErrUnsupported) {
+		t.Errorf("GetQueue() error = %v, want ErrUnsupported", err)
+	}
+	if err := p.AddToQueue(ctx, "http://example.invalid/a.mp3"); !errors.Is(err, rifferrors.ErrUnsupported) {
+		t.Errorf("AddToQueue() error = %v, want ErrUnsupported", err)
+	}
+	if !p.Capabilities().Has(core.CapStream | core.CapSeek) {
+		t.Errorf("Capabilities() = %v, want stream and seek", p.Capabilities())
+	}
+}
+
+func TestBackendFromCache(t *testing.T) {
+	r, srv := newTestRenderer(t)
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	saveCache(CacheDir(), rendererCacheFile, "renderers", []*Renderer{r})
+
+	reg := core.NewRegistry(NewBackend(0))
+	ctx := context.Background()
+
+	p, d, err := reg.PlayerFor(ctx, "living room")
+	if err != nil {
+		t.Fatalf("PlayerFor() error = %v", err)
+	}
+	if d.ID != "5f1b6a6e-riff-test-renderer" || d.Platform != core.PlatformUPnP {
+		t.Errorf("device = %+v", d)
+	}
+	if err := p.(core.StreamPlayer).PlayURI(ctx, "http://radio.example/live.mp3"); err != nil {
+		t.Fatalf("PlayURI() error = %v", err)
+	}
+	if got := srv.State().Transport; got != "PLAYING" {
+		t.Errorf("Transport = %q, want PLAYING", got)
+	}
+
+	// Renderers are never chosen implicitly
+	if _, _, err := reg.PlayerFor(ctx, ""); err == nil {
+		t.Error("PlayerFor(\"\") error = nil, want an error")
+	}
+}
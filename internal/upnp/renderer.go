@@ -0,0 +1,213 @@
+package upnp
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Renderer is a MediaRenderer device and the control URLs of its
+// AVTransport and RenderingControl services.
+type Renderer struct {
+	UUID                string    `json:"uuid"`
+	Name                string    `json:"name"`
+	Manufacturer        string    `json:"manufacturer"`
+	Model               string    `json:"model"`
+	Location            string    `json:"location"`
+	AVTransportURL      string    `json:"av_transport_url"`
+	RenderingControlURL string    `json:"rendering_control_url,omitempty"`
+	LastSeen            time.Time `json:"last_seen"`
+}
+
+// NewRenderer builds a Renderer from a device description. It returns nil
+// if the description has no MediaRenderer with an AVTransport service.
+func NewRenderer(desc *Description, location string) *Renderer {
+	d := desc.Find(MediaRendererDevice)
+	if d == nil {
+		return nil
+	}
+	av := d.Service(AVTransportService)
+	if av == nil || av.ControlURL == "" {
+		return nil
+	}
+
+	r := &Renderer{
+		UUID:           d.UUID(),
+		Name:           d.FriendlyName,
+		Manufacturer:   d.Manufacturer,
+		Model:          d.ModelName,
+		Location:       location,
+		AVTransportURL: av.ControlURL,
+		LastSeen:       time.Now(),
+	}
+	if r.Name == "" {
+		r.Name = desc.FriendlyName
+	}
+	if rc := d.Service(RenderingControlService); rc != nil {
+		r.RenderingControlURL = rc.ControlURL
+	}
+	return r
+}
+
+// TransportInfo is the response to GetTransportInfo.
+type TransportInfo struct {
+	CurrentTransportState  string `xml:"CurrentTransportState"`
+	CurrentTransportStatus string `xml:"CurrentTransportStatus"`
+	CurrentSpeed           string `xml:"CurrentSpeed"`
+}
+
+// PositionInfo is the response to GetPositionInfo.
+type PositionInfo struct {
+	Track         int    `xml:"Track"`
+	TrackDuration string `xml:"TrackDuration"`
+	TrackMetaData string `xml:"TrackMetaData"`
+	TrackURI      string `xml:"TrackURI"`
+	RelTime       string `xml:"RelTime"`
+}
+
+// Control sends standard AVTransport and RenderingControl actions to
+// renderers.
+type Control struct {
+	soap *SOAPClient
+}
+
+// NewControl creates a Control that calls renderers through soap.
+func NewControl(soap *SOAPClient) *Control {
+	return &Control{soap: soap}
+}
+
+var instance0 = Arg{"InstanceID", "0"}
+
+// SetAVTransportURI loads uri, described by DIDL-Lite metadata, without
+// starting playback.
+func (c *Control) SetAVTransportURI(ctx context.Context, r *Renderer, uri, metadata string) error {
+	_, err := c.soap.Call(ctx, r.AVTransportURL, AVTransportService, "SetAVTransportURI",
+		instance0, Arg{"CurrentURI", uri}, Arg{"CurrentURIMetaData", metadata})
+	return err
+}
+
+// PlayURI loads uri and starts playback.
+func (c *Control) PlayURI(ctx context.Context, r *Renderer, uri, metadata string) error {
+	if err := c.SetAVTransportURI(ctx, r, uri, metadata); err != nil {
+		return fmt.Errorf("set transport URI: %w", err)
+	}
+	return c.Play(ctx, r)
+}
+
+// Play starts or resumes playback.
+func (c *Control) Play(ctx context.Context, r *Renderer) error {
+	_, err := c.soap.Call(ctx, r.AVTransportURL, AVTransportService, "Play", instance0, Arg{"Speed", "1"})
+	return err
+}
+
+// Pause pauses playback.
+func (c *Control) Pause(ctx context.Context, r *Renderer) error {
+	_, err := c.soap.Call(ctx, r.AVTransportURL, AVTransportService, "Pause", instance0)
+	return err
+}
+
+// Stop stops playback.
+func (c *Control) Stop(ctx context.Context, r *Renderer) error {
+	_, err := c.soap.Call(ctx, r.AVTransportURL, AVTransportService, "Stop", instance0)
+	return err
+}
+
+// Next skips to the next track, for renderers playing a playlist.
+func (c *Control) Next(ctx context.Context, r *Renderer) error {
+	_, err := c.soap.Call(ctx, r.AVTransportURL, AVTransportService, "Next", instance0)
+	return err
+}
+
+// Previous goes back a track, for renderers playing a playlist.
+func (c *Control) Previous(ctx context.Context, r *Renderer) error {
+	_, err := c.soap.Call(ctx, r.AVTransportURL, AVTransportService, "Previous", instance0)
+	return err
+}
+
+// Seek seeks to position within the current track.
+func (c *Control) Seek(ctx context.Context, r *Renderer, position time.Duration) error {
+	_, err := c.soap.Call(ctx, r.AVTransportURL, AVTransportService, "Seek",
+		instance0, Arg{"Unit", "REL_TIME"}, Arg{"Target", FormatDuration(position)})
+	return err
+}
+
+// GetTransportInfo returns the transport state.
+func (c *Control) GetTransportInfo(ctx context.Context, r *Renderer) (*TransportInfo, error) {
+	resp, err := c.soap.Call(ctx, r.AVTransportURL, AVTransportService, "GetTransportInfo", instance0)
+	if err != nil {
+		return nil, err
+	}
+
+	var envelope struct {
+		Body struct {
+			Response TransportInfo `xml:"GetTransportInfoResponse"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(resp, &envelope); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+	return &envelope.Body.Response, nil
+}
+
+// GetPositionInfo returns the current track and position.
+func (c *Control) GetPositionInfo(ctx context.Context, r *Renderer) (*PositionInfo, error) {
+	resp, err := c.soap.Call(ctx, r.AVTransportURL, AVTransportService, "GetPositionInfo", instance0)
+	if err != nil {
+		return nil, err
+	}
+
+	var envelope struct {
+		Body struct {
+			Response PositionInfo `xml:"GetPositionInfoResponse"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(resp, &envelope); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+	return &envelope.Body.Response, nil
+}
+
+// GetVolume returns the master volume (0-100).
+func (c *Control) GetVolume(ctx context.Context, r *Renderer) (int, error) {
+	if r.RenderingControlURL == "" {
+		return 0, fmt.Errorf("%s has no volume control", r.Name)
+	}
+	resp, err := c.soap.Call(ctx, r.RenderingControlURL, RenderingControlService, "GetVolume",
+		instance0, Arg{"Channel", "Master"})
+	if err != nil {
+		return 0, err
+	}
+
+	var envelope struct {
+		Body struct {
+			Response struct {
+				CurrentVolume string `xml:"CurrentVolume"`
+			} `xml:"GetVolumeResponse"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(resp, &envelope); err != nil {
+		return 0, fmt.Errorf("parse response: %w", err)
+	}
+
+	vol, _ := strconv.Atoi(strings.TrimSpace(envelope.Body.Response.CurrentVolume))
+	return vol, nil
+}
+
+// SetVolume sets the master volume (0-100).
+func (c *Control) SetVolume(ctx context.Context, r *Renderer, volume int) error {
+	if r.RenderingControlURL == "" {
+		return fmt.Errorf("%s has no volume control", r.Name)
+	}
+	if volume < 0 {
+		volume = 0
+	}
+	if volume > 100 {
+		volume = 100
+	}
+	_, err := c.soap.Call(ctx, r.RenderingControlURL, RenderingControlService, "SetVolume",
+		instance0, Arg{"Channel", "Master"}, Arg{"DesiredVolume", strconv.Itoa(volume)})
+	return err
+}
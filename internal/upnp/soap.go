@@ -0,0 +1,190 @@
+package upnp
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/tessro/riff/internal/metrics"
+)
+
+// Standard service types.
+const (
+	AVTransportService      = "urn:schemas-upnp-org:service:AVTransport:1"
+	RenderingControlService = "urn:schemas-upnp-org:service:RenderingControl:1"
+	ContentDirectoryService = "urn:schemas-upnp-org:service:ContentDirectory:1"
+)
+
+// Arg is a named SOAP action argument. Some devices reject arguments that
+// are not in the order the service description lists them, so actions
+// take an ordered list rather than a map.
+type Arg struct {
+	Name  string
+	Value string
+}
+
+// SOAPError is a failed SOAP call. Code and Description come from the
+// UPnPError detail when the device sent one.
+type SOAPError struct {
+	StatusCode  int
+	Code        int
+	Description string
+	Body        string
+}
+
+func (e *SOAPError) Error() string {
+	if e.Code != 0 {
+		return fmt.Sprintf("soap error (status %d): upnp error %d: %s", e.StatusCode, e.Code, e.Description)
+	}
+	return fmt.Sprintf("soap error (status %d): %s", e.StatusCode, e.Body)
+}
+
+// SOAPClient invokes actions on UPnP service control URLs.
+type SOAPClient struct {
+	httpClient *http.Client
+}
+
+// NewSOAPClient creates a new SOAP client.
+func NewSOAPClient() *SOAPClient {
+	return &SOAPClient{
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// HTTPClient returns the HTTP client used for calls, for fetching device
+// descriptions with the same settings.
+func (c *SOAPClient) HTTPClient() *http.Client {
+	return c.httpClient
+}
+
+// Call invokes action on the service at controlURL and returns the raw
+// response envelope.
+func (c *SOAPClient) Call(ctx context.Context, controlURL, service, action string, args ...Arg) ([]byte, error) {
+	device := controlURL
+	if u, err := url.Parse(controlURL); err == nil {
+		device = u.Host
+	}
+	start := time.Now()
+	resp, err := c.call(ctx, controlURL, service, action, args)
+	metrics.SOAPCallDuration.WithLabelValues(device, action).Observe(metrics.Since(start))
+	if err != nil {
+		metrics.SOAPCallErrors.WithLabelValues(device, action).Inc()
+	}
+	logCall(ctx, controlURL, action, args, resp, time.Since(start), err)
+	return resp, err
+}
+
+// logBodyLimit caps how much of a response is logged; browsing a queue or
+// a media server returns large DIDL-Lite documents.
+const logBodyLimit = 1024
+
+// logCall logs a SOAP call and its response at debug level.
+func logCall(ctx context.Context, controlURL, action string, args []Arg, resp []byte, d time.Duration, err error) {
+	if !slog.Default().Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	argAttrs := make([]any, len(args))
+	for i, a := range args {
+		argAttrs[i] = slog.String(a.Name, a.Value)
+	}
+	attrs := []any{"url", controlURL, "action", action, slog.Group("args", argAttrs...), "duration", d}
+	if err != nil {
+		attrs = append(attrs, "error", err)
+	} else {
+		body := string(resp)
+		if len(body) > logBodyLimit {
+			body = body[:logBodyLimit] + "..."
+		}
+		attrs = append(attrs, "response", body)
+	}
+	slog.DebugContext(ctx, "soap call", attrs...)
+}
+
+func (c *SOAPClient) call(ctx context.Context, controlURL, service, action string, args []Arg) ([]byte, error) {
+	body := buildSOAPBody(service, action, args)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", controlURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", fmt.Sprintf("\"%s#%s\"", service, action))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("soap request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, parseSOAPError(resp.StatusCode, respBody)
+	}
+
+	return respBody, nil
+}
+
+// buildSOAPBody constructs the SOAP envelope. Argument values are escaped,
+// so DIDL-Lite metadata travels as text as the UPnP spec requires.
+func buildSOAPBody(service, action string, args []Arg) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	buf.WriteString(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">`)
+	buf.WriteString(`<s:Body>`)
+	buf.WriteString(fmt.Sprintf(`<u:%s xmlns:u="%s">`, action, service))
+
+	for _, a := range args {
+		buf.WriteString(fmt.Sprintf("<%s>%s</%s>", a.Name, EscapeXML(a.Value), a.Name))
+	}
+
+	buf.WriteString(fmt.Sprintf(`</u:%s>`, action))
+	buf.WriteString(`</s:Body>`)
+	buf.WriteString(`</s:Envelope>`)
+
+	return buf.Bytes()
+}
+
+// parseSOAPError extracts the UPnPError from a fault response.
+func parseSOAPError(status int, body []byte) error {
+	var fault struct {
+		Body struct {
+			Fault struct {
+				Detail struct {
+					UPnPError struct {
+						Code        int    `xml:"errorCode"`
+						Description string `xml:"errorDescription"`
+					} `xml:"UPnPError"`
+				} `xml:"detail"`
+			} `xml:"Fault"`
+		} `xml:"Body"`
+	}
+	_ = xml.Unmarshal(body, &fault)
+
+	e := fault.Body.Fault.Detail.UPnPError
+	return &SOAPError{
+		StatusCode:  status,
+		Code:        e.Code,
+		Description: e.Description,
+		Body:        string(body),
+	}
+}
+
+// EscapeXML escapes special XML characters.
+func EscapeXML(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,106 @@
+// Package upnp implements the parts of UPnP AV that riff needs: SSDP
+// search, device descriptions, SOAP actions, DIDL-Lite metadata, and a
+// core.Player for standard MediaRenderer devices such as DLNA receivers and
+// smart TVs.
+package upnp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+const ssdpAddr = "239.255.255.250:1900"
+
+// Device types riff searches for.
+const (
+	MediaRendererDevice = "urn:schemas-upnp-org:device:MediaRenderer:1"
+	MediaServerDevice   = "urn:schemas-upnp-org:device:MediaServer:1"
+)
+
+// SearchResponse is a reply to an SSDP M-SEARCH.
+type SearchResponse struct {
+	ST       string
+	USN      string
+	Location string
+	Server   string
+	IP       net.IP
+}
+
+// Search sends an M-SEARCH for st and collects replies until timeout or ctx
+// is done. Replies are deduplicated by USN. When ctx ends early, the replies
+// gathered so far are returned along with ctx.Err().
+func Search(ctx context.Context, st string, timeout time.Duration) ([]SearchResponse, error) {
+	addr, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return nil, fmt.Errorf("resolve ssdp addr: %w", err)
+	}
+
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, fmt.Errorf("listen udp: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+
+	request := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: " + ssdpAddr + "\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 2\r\n" +
+		"ST: " + st + "\r\n" +
+		"\r\n"
+	if _, err := conn.WriteToUDP([]byte(request), addr); err != nil {
+		return nil, fmt.Errorf("send m-search: %w", err)
+	}
+
+	var responses []SearchResponse
+	seen := make(map[string]bool)
+	buf := make([]byte, 2048)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return responses, ctx.Err()
+		default:
+		}
+
+		n, remoteAddr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return responses, nil // Search complete
+			}
+			continue
+		}
+
+		resp, ok := parseSearchResponse(buf[:n], remoteAddr)
+		if !ok || resp.ST != st || seen[resp.USN] {
+			continue
+		}
+		seen[resp.USN] = true
+		responses = append(responses, resp)
+	}
+}
+
+// parseSearchResponse parses an SSDP reply datagram.
+func parseSearchResponse(data []byte, addr *net.UDPAddr) (SearchResponse, bool) {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), nil)
+	if err != nil {
+		return SearchResponse{}, false
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	return SearchResponse{
+		ST:       resp.Header.Get("ST"),
+		USN:      resp.Header.Get("USN"),
+		Location: resp.Header.Get("Location"),
+		Server:   resp.Header.Get("Server"),
+		IP:       addr.IP,
+	}, true
+}
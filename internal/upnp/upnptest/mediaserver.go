@@ -0,0 +1,217 @@
+package upnptest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+)
+
+// MediaServerUDN is the unique device name of the fake media server.
+const MediaServerUDN = "uuid:9d3c2e1a-riff-test-server"
+
+const contentDirectoryService = "urn:schemas-upnp-org:service:ContentDirectory:1"
+
+// Track is an item in the fake server's library.
+type Track struct {
+	ID     string
+	Title  string
+	Artist string
+	Album  string
+	Path   string // Served under the server's URL
+}
+
+// Tracks is the fake library: one album inside a Music folder.
+var Tracks = []Track{
+	{ID: "1$1$1", Title: "Neon", Artist: "The Testers", Album: "Night Drive", Path: "/media/1.flac"},
+	{ID: "1$1$2", Title: "Overpass", Artist: "The Testers", Album: "Night Drive", Path: "/media/2.flac"},
+	{ID: "1$1$3", Title: "Last Exit", Artist: "The Testers", Album: "Night Drive", Path: "/media/3.flac"},
+}
+
+// Container IDs in the fake library.
+const (
+	MusicID = "1"
+	AlbumID = "1$1"
+)
+
+// MediaServer is a fake MediaServer with a ContentDirectory.
+type MediaServer struct {
+	name string
+	srv  *httptest.Server
+}
+
+// NewMediaServer starts a media server with the given friendly name.
+func NewMediaServer(name string) *MediaServer {
+	s := &MediaServer{name: name}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /description.xml", s.handleDescription)
+	mux.HandleFunc("POST /ContentDirectory/control", soapHandler(s.exec))
+	s.srv = httptest.NewServer(mux)
+	return s
+}
+
+// Location returns the description URL, as SSDP would announce it.
+func (s *MediaServer) Location() string {
+	return s.srv.URL + "/description.xml"
+}
+
+// URL returns the resource URL of a track.
+func (s *MediaServer) URL(t Track) string {
+	return s.srv.URL + t.Path
+}
+
+// Close shuts the server down.
+func (s *MediaServer) Close() {
+	s.srv.Close()
+}
+
+func (s *MediaServer) handleDescription(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprintf(w, `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:MediaServer:1</deviceType>
+    <friendlyName>%s</friendlyName>
+    <manufacturer>Riff Test</manufacturer>
+    <modelName>Fake Media Server</modelName>
+    <UDN>%s</UDN>
+    <serviceList>
+      <service>
+        <serviceType>%s</serviceType>
+        <serviceId>urn:upnp-org:serviceId:ContentDirectory</serviceId>
+        <controlURL>/ContentDirectory/control</controlURL>
+        <eventSubURL>/ContentDirectory/event</eventSubURL>
+        <SCPDURL>/ContentDirectory/scpd.xml</SCPDURL>
+      </service>
+    </serviceList>
+  </device>
+</root>`, escape(s.name), MediaServerUDN, contentDirectoryService)
+}
+
+// exec answers ContentDirectory actions.
+func (s *MediaServer) exec(action string, args map[string]string) ([][2]string, int) {
+	switch action {
+	case "GetSearchCapabilities":
+		return [][2]string{{"SearchCaps", "dc:title,upnp:artist,upnp:album"}}, 0
+
+	case "Browse":
+		var entries []string
+		id := args["ObjectID"]
+		switch args["BrowseFlag"] {
+		case "BrowseMetadata":
+			entry, ok := s.entry(id)
+			if !ok {
+				return nil, 701 // No such object
+			}
+			entries = []string{entry}
+		case "BrowseDirectChildren":
+			children, ok := s.children(id)
+			if !ok {
+				return nil, 701
+			}
+			entries = children
+		default:
+			return nil, 402
+		}
+		return page(entries, args), 0
+
+	case "Search":
+		query := strings.ToLower(quoted(args["SearchCriteria"]))
+		var entries []string
+		for _, t := range Tracks {
+			if strings.Contains(strings.ToLower(t.Title+"\n"+t.Artist+"\n"+t.Album), query) {
+				entries = append(entries, s.item(t))
+			}
+		}
+		return page(entries, args), 0
+
+	default:
+		return nil, 401
+	}
+}
+
+// entry returns the DIDL-Lite element for id.
+func (s *MediaServer) entry(id string) (string, bool) {
+	switch id {
+	case "0":
+		return container("0", "-1", "Root", "object.container", 1), true
+	case MusicID:
+		return container(MusicID, "0", "Music", "object.container.storageFolder", 1), true
+	case AlbumID:
+		return container(AlbumID, MusicID, "Night Drive", "object.container.album.musicAlbum", len(Tracks)), true
+	}
+	for _, t := range Tracks {
+		if t.ID == id {
+			return s.item(t), true
+		}
+	}
+	return "", false
+}
+
+// children returns the DIDL-Lite elements inside container id.
+func (s *MediaServer) children(id string) ([]string, bool) {
+	switch id {
+	case "0":
+		e, _ := s.entry(MusicID)
+		return []string{e}, true
+	case MusicID:
+		e, _ := s.entry(AlbumID)
+		return []string{e}, true
+	case AlbumID:
+		items := make([]string, len(Tracks))
+		for i, t := range Tracks {
+			items[i] = s.item(t)
+		}
+		return items, true
+	}
+	if _, ok := s.entry(id); ok {
+		return nil, true
+	}
+	return nil, false
+}
+
+func (s *MediaServer) item(t Track) string {
+	return fmt.Sprintf(`<item id="%s" parentID="%s" restricted="1"><dc:title>%s</dc:title><dc:creator>%s</dc:creator><upnp:artist>%s</upnp:artist><upnp:album>%s</upnp:album><upnp:class>object.item.audioItem.musicTrack</upnp:class><res protocolInfo="http-get:*:audio/flac:*" duration="0:03:00.000">%s</res></item>`,
+		escape(t.ID), AlbumID, escape(t.Title), escape(t.Artist), escape(t.Artist), escape(t.Album), escape(s.URL(t)))
+}
+
+func container(id, parent, title, class string, children int) string {
+	return fmt.Sprintf(`<container id="%s" parentID="%s" childCount="%d" restricted="1"><dc:title>%s</dc:title><upnp:class>%s</upnp:class></container>`,
+		escape(id), escape(parent), children, escape(title), class)
+}
+
+// page applies StartingIndex and RequestedCount to entries and wraps them in
+// a Browse or Search result.
+func page(entries []string, args map[string]string) [][2]string {
+	total := len(entries)
+	start, _ := strconv.Atoi(args["StartingIndex"])
+	count, _ := strconv.Atoi(args["RequestedCount"])
+	start = min(max(start, 0), total)
+	end := total
+	if count > 0 {
+		end = min(start+count, total)
+	}
+	entries = entries[start:end]
+
+	didl := `<DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/">` +
+		strings.Join(entries, "") + `</DIDL-Lite>`
+	return [][2]string{
+		{"Result", didl},
+		{"NumberReturned", strconv.Itoa(len(entries))},
+		{"TotalMatches", strconv.Itoa(total)},
+		{"UpdateID", "1"},
+	}
+}
+
+// quoted returns the first double-quoted string in a search criteria.
+func quoted(criteria string) string {
+	_, rest, ok := strings.Cut(criteria, `"`)
+	if !ok {
+		return ""
+	}
+	value, _, _ := strings.Cut(rest, `"`)
+	return value
+}
@@ -0,0 +1,183 @@
+package upnptest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+)
+
+// RendererUDN is the unique device name of the fake renderer.
+const RendererUDN = "uuid:5f1b6a6e-riff-test-renderer"
+
+const (
+	avTransportService      = "urn:schemas-upnp-org:service:AVTransport:1"
+	renderingControlService = "urn:schemas-upnp-org:service:RenderingControl:1"
+)
+
+// State is a snapshot of the renderer's transport model.
+type State struct {
+	Transport string // PLAYING, PAUSED_PLAYBACK, STOPPED or NO_MEDIA_PRESENT
+	URI       string
+	Metadata  string // DIDL-Lite, unescaped
+	Position  string // H:MM:SS
+	Volume    int
+}
+
+// Renderer is a fake MediaRenderer.
+type Renderer struct {
+	name string
+	srv  *httptest.Server
+
+	mu      sync.Mutex
+	state   State
+	actions []string
+}
+
+// NewRenderer starts a renderer with the given friendly name.
+func NewRenderer(name string) *Renderer {
+	s := &Renderer{
+		name: name,
+		state: State{
+			Transport: "NO_MEDIA_PRESENT",
+			Position:  "0:00:00",
+			Volume:    20,
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /description.xml", s.handleDescription)
+	mux.HandleFunc("POST /AVTransport/control", soapHandler(s.exec))
+	mux.HandleFunc("POST /RenderingControl/control", soapHandler(s.exec))
+	s.srv = httptest.NewServer(mux)
+	return s
+}
+
+// Location returns the description URL, as SSDP would announce it.
+func (s *Renderer) Location() string {
+	return s.srv.URL + "/description.xml"
+}
+
+// Close shuts the server down.
+func (s *Renderer) Close() {
+	s.srv.Close()
+}
+
+// State returns a snapshot of the transport.
+func (s *Renderer) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// Actions returns the names of every action received, in order.
+func (s *Renderer) Actions() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.actions)
+}
+
+func (s *Renderer) handleDescription(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprintf(w, `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:MediaRenderer:1</deviceType>
+    <friendlyName>%s</friendlyName>
+    <manufacturer>Riff Test</manufacturer>
+    <modelName>Fake Renderer</modelName>
+    <UDN>%s</UDN>
+    <serviceList>
+      <service>
+        <serviceType>%s</serviceType>
+        <serviceId>urn:upnp-org:serviceId:AVTransport</serviceId>
+        <controlURL>/AVTransport/control</controlURL>
+        <eventSubURL>/AVTransport/event</eventSubURL>
+        <SCPDURL>/AVTransport/scpd.xml</SCPDURL>
+      </service>
+      <service>
+        <serviceType>%s</serviceType>
+        <serviceId>urn:upnp-org:serviceId:RenderingControl</serviceId>
+        <controlURL>/RenderingControl/control</controlURL>
+        <eventSubURL>/RenderingControl/event</eventSubURL>
+        <SCPDURL>/RenderingControl/scpd.xml</SCPDURL>
+      </service>
+    </serviceList>
+  </device>
+</root>`, escape(s.name), RendererUDN, avTransportService, renderingControlService)
+}
+
+// exec runs one action against the model. It returns the output arguments
+// in order, or a non-zero UPnP error code.
+func (s *Renderer) exec(action string, args map[string]string) ([][2]string, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.actions = append(s.actions, action)
+
+	switch action {
+	case "SetAVTransportURI":
+		s.state.URI = args["CurrentURI"]
+		s.state.Metadata = args["CurrentURIMetaData"]
+		s.state.Transport = "STOPPED"
+		s.state.Position = "0:00:00"
+
+	case "Play":
+		if s.state.URI == "" {
+			return nil, 701 // Transition not available
+		}
+		s.state.Transport = "PLAYING"
+
+	case "Pause":
+		if s.state.Transport != "PLAYING" {
+			return nil, 701
+		}
+		s.state.Transport = "PAUSED_PLAYBACK"
+
+	case "Stop":
+		if s.state.URI != "" {
+			s.state.Transport = "STOPPED"
+		}
+
+	case "Seek":
+		if args["Unit"] != "REL_TIME" {
+			return nil, 710 // Seek mode not supported
+		}
+		s.state.Position = args["Target"]
+
+	case "Next", "Previous":
+		return nil, 711 // Illegal seek target
+
+	case "GetTransportInfo":
+		return [][2]string{
+			{"CurrentTransportState", s.state.Transport},
+			{"CurrentTransportStatus", "OK"},
+			{"CurrentSpeed", "1"},
+		}, 0
+
+	case "GetPositionInfo":
+		return [][2]string{
+			{"Track", "1"},
+			{"TrackDuration", "0:03:30"},
+			{"TrackMetaData", s.state.Metadata},
+			{"TrackURI", s.state.URI},
+			{"RelTime", s.state.Position},
+		}, 0
+
+	case "GetVolume":
+		return [][2]string{{"CurrentVolume", fmt.Sprint(s.state.Volume)}}, 0
+
+	case "SetVolume":
+		var vol int
+		if _, err := fmt.Sscan(args["DesiredVolume"], &vol); err != nil || vol < 0 || vol > 100 {
+			return nil, 402
+		}
+		s.state.Volume = vol
+
+	default:
+		return nil, 401 // Invalid Action
+	}
+	return nil, 0
+}
@@ -0,0 +1,98 @@
+// Package upnptest provides fake UPnP devices for tests.
+//
+// Renderer is a MediaRenderer answering AVTransport and RenderingControl
+// actions, and MediaServer is a MediaServer with a small ContentDirectory.
+// Both serve a device description over HTTP and keep a model a test can
+// assert on after driving a real client:
+//
+//	srv := upnptest.NewRenderer("Living Room TV")
+//	defer srv.Close()
+//
+//	desc, _ := upnp.FetchDescription(ctx, http.DefaultClient, srv.Location())
+//	r := upnp.NewRenderer(desc, srv.Location())
+//	_ = upnp.NewControl(upnp.NewSOAPClient()).PlayURI(ctx, r, uri, "")
+//	state := srv.State()
+package upnptest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// execFunc runs one action. It returns the output arguments in order, or a
+// non-zero UPnP error code.
+type execFunc func(action string, args map[string]string) ([][2]string, int)
+
+// soapHandler decodes SOAP requests and answers them with exec.
+func soapHandler(exec execFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		header := strings.Trim(r.Header.Get("SOAPAction"), `"`)
+		service, action, ok := strings.Cut(header, "#")
+		if !ok {
+			http.Error(w, "missing SOAPAction", http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		args, err := parseArgs(body)
+		if err != nil {
+			writeFault(w, 402, "Invalid Args")
+			return
+		}
+
+		out, code := exec(action, args)
+		if code != 0 {
+			writeFault(w, code, "Action Failed")
+			return
+		}
+
+		w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+		fmt.Fprintf(w, `<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:%sResponse xmlns:u="%s">`, action, service)
+		for _, kv := range out {
+			fmt.Fprintf(w, "<%s>%s</%s>", kv[0], escape(kv[1]), kv[0])
+		}
+		fmt.Fprintf(w, `</u:%sResponse></s:Body></s:Envelope>`, action)
+	}
+}
+
+// parseArgs returns the arguments of the action element in a SOAP body.
+func parseArgs(body []byte) (map[string]string, error) {
+	var envelope struct {
+		Body struct {
+			Action struct {
+				Args []struct {
+					XMLName xml.Name
+					Value   string `xml:",chardata"`
+				} `xml:",any"`
+			} `xml:",any"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(body, &envelope); err != nil {
+		return nil, err
+	}
+
+	args := make(map[string]string)
+	for _, a := range envelope.Body.Action.Args {
+		args[a.XMLName.Local] = a.Value
+	}
+	return args, nil
+}
+
+func writeFault(w http.ResponseWriter, code int, description string) {
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.WriteHeader(http.StatusInternalServerError)
+	fmt.Fprintf(w, `<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><s:Fault><faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring><detail><UPnPError xmlns="urn:schemas-upnp-org:control-1-0"><errorCode>%d</errorCode><errorDescription>%s</errorDescription></UPnPError></detail></s:Fault></s:Body></s:Envelope>`, code, description)
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,417 @@
+// Package webhook POSTs playback events to HTTP endpoints.
+//
+// Each hook has its own queue and delivery goroutine, so a slow or failing
+// endpoint never holds up the others. Failed deliveries are retried with
+// exponential backoff; events that still can't be delivered, or that arrive
+// while a hook's queue is full, are appended to a dead-letter log.
+//
+// Requests carry the event type in X-Riff-Event and, when the hook has a
+// secret, an HMAC-SHA256 of the body in X-Riff-Signature:
+//
+//	X-Riff-Signature: sha256=<hex digest>
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/metrics"
+	"github.com/tessro/riff/internal/tail"
+)
+
+const (
+	defaultMaxAttempts = 3
+	defaultTimeout     = 10 * time.Second
+	defaultQueueSize   = 64
+)
+
+// Hook is one endpoint and the events it receives.
+type Hook struct {
+	// URL receives a POST for each matching event.
+	URL string
+
+	// Events limits the hook to these event types, such as "track_change"
+	// or "pause". Empty matches every event.
+	Events []string
+
+	// Devices limits the hook to events from devices with these names or
+	// IDs. Empty matches every device.
+	Devices []string
+
+	// Secret signs each body with HMAC-SHA256. Empty sends no signature.
+	Secret string
+
+	// Template renders the JSON body with text/template; see Payload for
+	// its fields. Empty sends the Payload itself.
+	Template string
+
+	// MaxAttempts bounds delivery attempts per event (default: 3).
+	MaxAttempts int
+
+	// Timeout bounds each attempt (default: 10s).
+	Timeout time.Duration
+}
+
+// Options configures a Runner.
+type Options struct {
+	// Client sends requests. It defaults to http.DefaultClient.
+	Client *http.Client
+
+	// DeadLetter is a file that undeliverable events are appended to as
+	// JSON lines. Empty discards them.
+	DeadLetter string
+
+	// QueueSize is how many events each hook buffers (default: 64).
+	QueueSize int
+
+	// Logger reports failed deliveries. Nil means slog.Default().
+	Logger *slog.Logger
+}
+
+// Payload is the default request body, and the data passed to templates.
+type Payload struct {
+	Event     string              `json:"event"`
+	Timestamp time.Time           `json:"timestamp"`
+	Device    *core.Device        `json:"device,omitempty"`
+	Track     *core.Track         `json:"track,omitempty"`
+	Current   *core.PlaybackState `json:"current,omitempty"`
+	Previous  *core.PlaybackState `json:"previous,omitempty"`
+}
+
+// Runner delivers events to hooks.
+type Runner struct {
+	opts  Options
+	hooks []*hook
+
+	// Retry backoff bounds
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	deadMu sync.Mutex
+}
+
+// hook is a Hook with its parsed filters and queue.
+type hook struct {
+	Hook
+	events   map[tail.EventType]bool
+	template *template.Template
+	queue    chan delivery
+}
+
+// delivery is one event queued for a hook.
+type delivery struct {
+	event tail.Event
+	body  []byte
+}
+
+// New creates a runner for hooks. Call Run to start delivering, and Send
+// to queue events.
+func New(hooks []Hook, opts Options) (*Runner, error) {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.QueueSize == 0 {
+		opts.QueueSize = defaultQueueSize
+	}
+
+	r := &Runner{
+		opts:       opts,
+		minBackoff: time.Second,
+		maxBackoff: 30 * time.Second,
+	}
+	for i, h := range hooks {
+		if h.URL == "" {
+			return nil, fmt.Errorf("webhook %d: missing url", i+1)
+		}
+		if h.MaxAttempts == 0 {
+			h.MaxAttempts = defaultMaxAttempts
+		}
+		if h.Timeout == 0 {
+			h.Timeout = defaultTimeout
+		}
+
+		wh := &hook{Hook: h, queue: make(chan delivery, opts.QueueSize)}
+		if len(h.Events) > 0 {
+			wh.events = make(map[tail.EventType]bool, len(h.Events))
+			for _, name := range h.Events {
+				t, err := tail.ParseEventType(name)
+				if err != nil {
+					return nil, fmt.Errorf("webhook %s: %w", h.URL, err)
+				}
+				wh.events[t] = true
+			}
+		}
+		if h.Template != "" {
+			t, err := template.New("webhook").Funcs(template.FuncMap{"json": toJSON}).Parse(h.Template)
+			if err != nil {
+				return nil, fmt.Errorf("webhook %s: invalid template: %w", h.URL, err)
+			}
+			wh.template = t
+		}
+		r.hooks = append(r.hooks, wh)
+	}
+	return r, nil
+}
+
+func (r *Runner) log() *slog.Logger {
+	if r.opts.Logger != nil {
+		return r.opts.Logger
+	}
+	return slog.Default()
+}
+
+// Run delivers queued events until ctx is done.
+func (r *Runner) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, h := range r.hooks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.deliverAll(ctx, h)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// Send queues e for every hook it matches. It never blocks: when a hook's
+// queue is full the event goes to the dead-letter log instead.
+func (r *Runner) Send(e tail.Event) {
+	for _, h := range r.hooks {
+		if !h.matches(e) {
+			continue
+		}
+
+		body, err := h.render(e)
+		if err != nil {
+			r.deadLetter(h, e, nil, 0, err)
+			continue
+		}
+
+		select {
+		case h.queue <- delivery{event: e, body: body}:
+		default:
+			r.deadLetter(h, e, body, 0, errors.New("queue full"))
+		}
+	}
+}
+
+// deliverAll sends h's queued events in order until ctx is done, then
+// dead-letters whatever is left.
+func (r *Runner) deliverAll(ctx context.Context, h *hook) {
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case d := <-h.queue:
+					r.deadLetter(h, d.event, d.body, 0, errors.New("shutting down"))
+				default:
+					return
+				}
+			}
+		case d := <-h.queue:
+			r.deliver(ctx, h, d)
+		}
+	}
+}
+
+// deliver POSTs one event, retrying with backoff, and dead-letters it if
+// every attempt fails.
+func (r *Runner) deliver(ctx context.Context, h *hook, d delivery) {
+	backoff := r.minBackoff
+	var err error
+	for attempt := 1; attempt <= h.MaxAttempts; attempt++ {
+		var retry bool
+		retry, err = r.post(ctx, h, d)
+		if err == nil {
+			metrics.WebhookDeliveries.WithLabelValues("ok").Inc()
+			return
+		}
+		if !retry || attempt == h.MaxAttempts || ctx.Err() != nil {
+			r.deadLetter(h, d.event, d.body, attempt, err)
+			return
+		}
+
+		r.log().Warn("webhook delivery failed, retrying", "url", h.URL, "error", err, "backoff", backoff)
+		metrics.WebhookDeliveries.WithLabelValues("retry").Inc()
+		select {
+		case <-ctx.Done():
+			r.deadLetter(h, d.event, d.body, attempt, err)
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, r.maxBackoff)
+	}
+}
+
+// post makes one delivery attempt. It reports whether a failure is worth
+// retrying: network errors, 429s and server errors are; other statuses
+// mean the endpoint rejected the event.
+func (r *Runner) post(ctx context.Context, h *hook, d delivery) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(d.body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "riff-webhook")
+	req.Header.Set("X-Riff-Event", d.event.Type.String())
+	if h.Secret != "" {
+		req.Header.Set("X-Riff-Signature", Sign(h.Secret, d.body))
+	}
+
+	resp, err := r.opts.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("status %d", resp.StatusCode)
+	}
+}
+
+// deadLetter records an undeliverable event.
+func (r *Runner) deadLetter(h *hook, e tail.Event, body []byte, attempts int, cause error) {
+	metrics.WebhookDeliveries.WithLabelValues("dead_letter").Inc()
+	r.log().Error("webhook delivery failed, giving up", "url", h.URL, "event", e.Type.String(), "error", cause)
+	if r.opts.DeadLetter == "" {
+		return
+	}
+
+	entry := struct {
+		Time     time.Time       `json:"time"`
+		URL      string          `json:"url"`
+		Event    string          `json:"event"`
+		Attempts int             `json:"attempts"`
+		Error    string          `json:"error"`
+		Body     json.RawMessage `json:"body,omitempty"`
+	}{
+		Time:     time.Now(),
+		URL:      h.URL,
+		Event:    e.Type.String(),
+		Attempts: attempts,
+		Error:    cause.Error(),
+		Body:     body,
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	r.deadMu.Lock()
+	defer r.deadMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(r.opts.DeadLetter), 0700); err != nil {
+		r.log().Error("failed to write webhook dead-letter log", "path", r.opts.DeadLetter, "error", err)
+		return
+	}
+	f, err := os.OpenFile(r.opts.DeadLetter, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		r.log().Error("failed to write webhook dead-letter log", "path", r.opts.DeadLetter, "error", err)
+		return
+	}
+	defer f.Close()
+	_, _ = f.Write(append(line, '\n'))
+}
+
+// matches reports whether e passes h's event and device filters.
+func (h *hook) matches(e tail.Event) bool {
+	if h.events != nil && !h.events[e.Type] {
+		return false
+	}
+	if len(h.Devices) == 0 {
+		return true
+	}
+	for _, s := range []*core.PlaybackState{e.Current, e.Previous} {
+		if s == nil || s.Device == nil {
+			continue
+		}
+		for _, want := range h.Devices {
+			if strings.EqualFold(want, s.Device.Name) || want == s.Device.ID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// render builds the request body for e.
+func (h *hook) render(e tail.Event) ([]byte, error) {
+	p := NewPayload(e)
+	if h.template == nil {
+		return json.Marshal(p)
+	}
+
+	var buf bytes.Buffer
+	if err := h.template.Execute(&buf, p); err != nil {
+		return nil, fmt.Errorf("template: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, errors.New("template did not produce valid JSON")
+	}
+	return buf.Bytes(), nil
+}
+
+// NewPayload describes e for a request body.
+func NewPayload(e tail.Event) Payload {
+	p := Payload{
+		Event:     e.Type.String(),
+		Timestamp: e.Timestamp,
+		Current:   e.Current,
+		Previous:  e.Previous,
+	}
+	if p.Timestamp.IsZero() {
+		p.Timestamp = time.Now()
+	}
+
+	// Completions and skips are about the track that just ended
+	state := e.Current
+	if (e.Type == tail.EventTrackComplete || e.Type == tail.EventTrackSkip) && e.Previous != nil {
+		state = e.Previous
+	}
+	if state != nil {
+		p.Track = state.Track
+	}
+	if e.Current != nil {
+		p.Device = e.Current.Device
+	}
+	return p
+}
+
+// Sign returns the X-Riff-Signature value for body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// toJSON is the "json" template function, for embedding values safely.
+func toJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
@@ -0,0 +1,276 @@
+package webhook
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tessro/riff/internal/core"
+	"github.com/tessro/riff/internal/tail"
+)
+
+const waitTimeout = 5 * time.Second
+
+// request is one request received by the test endpoint.
+type request struct {
+	header http.Header
+	body   []byte
+}
+
+// endpoint records requests and answers with the next status in statuses,
+// then 200.
+type endpoint struct {
+	mu       sync.Mutex
+	statuses []int
+	requests chan request
+}
+
+func newEndpoint(t *testing.T, statuses ...int) (*endpoint, *httptest.Server) {
+	t.Helper()
+	e := &endpoint{statuses: statuses, requests: make(chan request, 16)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		e.requests <- request{header: r.Header, body: body}
+
+		e.mu.Lock()
+		status := http.StatusOK
+		if len(e.statuses) > 0 {
+			status, e.statuses = e.statuses[0], e.statuses[1:]
+		}
+		e.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return e, srv
+}
+
+func (e *endpoint) next(t *testing.T) request {
+	t.Helper()
+	select {
+	case r := <-e.requests:
+		return r
+	case <-time.After(waitTimeout):
+		t.Fatal("timed out waiting for request")
+		return request{}
+	}
+}
+
+func (e *endpoint) none(t *testing.T) {
+	t.Helper()
+	select {
+	case r := <-e.requests:
+		t.Fatalf("unexpected request: %s", r.body)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// start runs a runner for hooks until the test ends.
+func start(t *testing.T, hooks []Hook, opts Options) *Runner {
+	t.Helper()
+	r, err := New(hooks, opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	r.minBackoff = time.Millisecond
+	r.maxBackoff = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = r.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return r
+}
+
+func event(t tail.EventType, device string) tail.Event {
+	return tail.Event{
+		Type:      t,
+		Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Current: &core.PlaybackState{
+			Track:     &core.Track{Title: "Harvest Moon", Artist: "Neil Young"},
+			Device:    &core.Device{ID: "dev-" + device, Name: device},
+			IsPlaying: true,
+			Volume:    40,
+		},
+	}
+}
+
+func TestSendPostsSignedPayload(t *testing.T) {
+	ep, srv := newEndpoint(t)
+	r := start(t, []Hook{{URL: srv.URL, Secret: "s3cret"}}, Options{})
+
+	r.Send(event(tail.EventTrackChange, "Kitchen"))
+	req := ep.next(t)
+
+	if got := req.header.Get("X-Riff-Event"); got != "track_change" {
+		t.Errorf("X-Riff-Event = %q, want track_change", got)
+	}
+	if got, want := req.header.Get("X-Riff-Signature"), Sign("s3cret", req.body); got != want {
+		t.Errorf("X-Riff-Signature = %q, want %q", got, want)
+	}
+
+	var p Payload
+	if err := json.Unmarshal(req.body, &p); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+	if p.Event != "track_change" || p.Track == nil || p.Track.Title != "Harvest Moon" {
+		t.Errorf("payload = %+v", p)
+	}
+	if p.Device == nil || p.Device.Name != "Kitchen" {
+		t.Errorf("payload device = %+v, want Kitchen", p.Device)
+	}
+}
+
+func TestSendFilters(t *testing.T) {
+	ep, srv := newEndpoint(t)
+	r := start(t, []Hook{{
+		URL:     srv.URL,
+		Events:  []string{"pause", "device_change"},
+		Devices: []string{"kitchen"},
+	}}, Options{})
+
+	r.Send(event(tail.EventTrackChange, "Kitchen"))
+	r.Send(event(tail.EventPause, "Office"))
+	ep.none(t)
+
+	r.Send(event(tail.EventPause, "Kitchen"))
+	if got := ep.next(t).header.Get("X-Riff-Event"); got != "pause" {
+		t.Errorf("X-Riff-Event = %q, want pause", got)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	ep, srv := newEndpoint(t)
+	r := start(t, []Hook{{
+		URL:      srv.URL,
+		Template: `{"text": {{json (printf "%s - %s" .Track.Artist .Track.Title)}}, "room": {{json .Device.Name}}}`,
+	}}, Options{})
+
+	r.Send(event(tail.EventTrackChange, "Kitchen"))
+	var got map[string]string
+	if err := json.Unmarshal(ep.next(t).body, &got); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+	if got["text"] != "Neil Young - Harvest Moon" || got["room"] != "Kitchen" {
+		t.Errorf("body = %v", got)
+	}
+}
+
+func TestRetriesThenDeadLetters(t *testing.T) {
+	ep, srv := newEndpoint(t, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusBadGateway)
+	dead := filepath.Join(t.TempDir(), "dead.jsonl")
+	r := start(t, []Hook{{URL: srv.URL, MaxAttempts: 3}}, Options{DeadLetter: dead})
+
+	r.Send(event(tail.EventResume, "Kitchen"))
+	for range 3 {
+		ep.next(t)
+	}
+	ep.none(t)
+
+	entries := readDeadLetters(t, dead)
+	if len(entries) != 1 {
+		t.Fatalf("got %d dead letters, want 1", len(entries))
+	}
+	if entries[0]["attempts"] != float64(3) || entries[0]["event"] != "resume" {
+		t.Errorf("dead letter = %v", entries[0])
+	}
+}
+
+func TestRetrySucceeds(t *testing.T) {
+	ep, srv := newEndpoint(t, http.StatusInternalServerError)
+	dead := filepath.Join(t.TempDir(), "dead.jsonl")
+	r := start(t, []Hook{{URL: srv.URL}}, Options{DeadLetter: dead})
+
+	r.Send(event(tail.EventPause, "Kitchen"))
+	first, second := ep.next(t), ep.next(t)
+	if string(first.body) != string(second.body) {
+		t.Errorf("retry body differs: %s vs %s", first.body, second.body)
+	}
+	if _, err := os.Stat(dead); !os.IsNotExist(err) {
+		t.Errorf("dead-letter log written after a successful retry")
+	}
+}
+
+func TestClientErrorIsNotRetried(t *testing.T) {
+	ep, srv := newEndpoint(t, http.StatusBadRequest)
+	dead := filepath.Join(t.TempDir(), "dead.jsonl")
+	r := start(t, []Hook{{URL: srv.URL}}, Options{DeadLetter: dead})
+
+	r.Send(event(tail.EventPause, "Kitchen"))
+	ep.next(t)
+	ep.none(t)
+
+	if entries := readDeadLetters(t, dead); len(entries) != 1 {
+		t.Fatalf("got %d dead letters, want 1", len(entries))
+	}
+}
+
+func TestSlowHookDoesNotBlockOthers(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(slow.Close)
+	t.Cleanup(func() { close(release) })
+
+	ep, fast := newEndpoint(t)
+	r := start(t, []Hook{{URL: slow.URL}, {URL: fast.URL}}, Options{})
+
+	r.Send(event(tail.EventPause, "Kitchen"))
+	r.Send(event(tail.EventResume, "Kitchen"))
+	ep.next(t)
+	ep.next(t)
+}
+
+func TestNewRejectsBadHooks(t *testing.T) {
+	tests := []Hook{
+		{},
+		{URL: "http://example.com", Events: []string{"explode"}},
+		{URL: "http://example.com", Template: "{{"},
+	}
+	for _, h := range tests {
+		if _, err := New([]Hook{h}, Options{}); err == nil {
+			t.Errorf("New(%+v) succeeded, want error", h)
+		}
+	}
+}
+
+func readDeadLetters(t *testing.T, path string) []map[string]any {
+	t.Helper()
+	deadline := time.Now().Add(waitTimeout)
+	for {
+		f, err := os.Open(path)
+		if err == nil {
+			var entries []map[string]any
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				var entry map[string]any
+				if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+					t.Fatalf("bad dead-letter line %q: %v", scanner.Text(), err)
+				}
+				entries = append(entries, entry)
+			}
+			_ = f.Close()
+			if len(entries) > 0 {
+				return entries
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no dead letters in %s", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}